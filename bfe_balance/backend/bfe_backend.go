@@ -26,6 +26,13 @@ import (
 	"github.com/bfenetworks/bfe/bfe_route/bfe_cluster"
 )
 
+// admin mode of backend, set by runtime override
+const (
+	ModeNormal      = ""            // serve requests as usual
+	ModeDraining    = "draining"    // no new requests, existing sessions allowed to finish
+	ModeMaintenance = "maintenance" // out of service
+)
+
 // BfeBackend is a backend server.
 type BfeBackend struct {
 	// immutable
@@ -35,12 +42,16 @@ type BfeBackend struct {
 	AddrInfo   string // backend's address and port, e.g., "10.1.1.1:8080"
 	SubCluster string // name of sub-cluster
 
-	sync.RWMutex      // guards following fields
-	avail        bool // whether the backend is usable
-	restarted    bool // indicate if this backend is new bring-up by health-check
-	connNum      int  // number of connections backend hold
-	failNum      int  // number of consecutive failures of normal requests
-	succNum      int  // number of consecutive successes of health-check request
+	sync.RWMutex          // guards following fields
+	avail        bool     // whether the backend is usable
+	restarted    bool     // indicate if this backend is new bring-up by health-check
+	connNum      int      // number of connections backend hold
+	failNum      int      // number of consecutive failures of normal requests
+	succNum      int      // number of consecutive successes of health-check request
+	ewma         peakEwma // moving average of latency
+	mode         string   // admin mode, e.g. draining or maintenance
+
+	outlier outlierStat // state of outlier detection, not guarded by lock of backend
 
 	closeChan chan bool // tell health-check to stop
 
@@ -100,9 +111,10 @@ func (back *BfeBackend) GetAddrInfo() string {
 	return back.AddrInfo
 }
 
+// Avail checks whether backend is usable, i.e. healthy and not ejected.
 func (back *BfeBackend) Avail() bool {
 	back.RLock()
-	avail := back.avail
+	avail := back.avail && !back.outlier.ejected.Load()
 	back.RUnlock()
 
 	return avail
@@ -122,6 +134,28 @@ func (back *BfeBackend) setAvail(avail bool) {
 	}
 }
 
+// SetMode sets admin mode of backend.
+func (back *BfeBackend) SetMode(mode string) {
+	back.Lock()
+	back.mode = mode
+	back.Unlock()
+}
+
+// Mode returns admin mode of backend.
+func (back *BfeBackend) Mode() string {
+	back.RLock()
+	mode := back.mode
+	back.RUnlock()
+
+	return mode
+}
+
+// InService checks whether backend could accept new requests, i.e. not
+// draining or in maintenance.
+func (back *BfeBackend) InService() bool {
+	return back.Mode() == ModeNormal
+}
+
 func (back *BfeBackend) SetRestart(restart bool) {
 	back.Lock()
 	back.restarted = restart
@@ -255,4 +289,4 @@ func (back *BfeBackend) OnFailByCluster(cluster *bfe_cluster.BfeCluster) {
 		return
 	}
 	back.OnFail(cluster.Name)
-}
\ No newline at end of file
+}
@@ -17,9 +17,13 @@
 package backend
 
 import (
+	"bufio"
+	"bytes"
 	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"net/url"
@@ -29,6 +33,7 @@ import (
 	"time"
 
 	"github.com/bfenetworks/go-lib/log"
+	"github.com/tidwall/gjson"
 
 	"github.com/bfenetworks/bfe/bfe_config/bfe_cluster_conf/cluster_conf"
 	"github.com/bfenetworks/bfe/bfe_debug"
@@ -145,7 +150,8 @@ func checkTCPConnect(backend *BfeBackend, checkConf *cluster_conf.BackendCheck)
 	return true, nil
 }
 
-func doHTTPHealthCheck(request *http.Request, timeout time.Duration) (int, error) {
+func doHTTPHealthCheck(request *http.Request, timeout time.Duration,
+	checkConf *cluster_conf.BackendCheck) (int, error) {
 	client := &http.Client{
 		// Note: disable following an HTTP redirect
 		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
@@ -159,9 +165,82 @@ func doHTTPHealthCheck(request *http.Request, timeout time.Duration) (int, error
 	}
 	defer response.Body.Close()
 
+	if err := matchCheckResponse(checkConf, response); err != nil {
+		return response.StatusCode, err
+	}
+
 	return response.StatusCode, nil
 }
 
+// matchCheckResponse checks response header and body of health check.
+func matchCheckResponse(checkConf *cluster_conf.BackendCheck, response *http.Response) error {
+	if checkConf == nil || !checkConf.NeedMatchResponse() {
+		return nil
+	}
+
+	// check response headers
+	if checkConf.RequiredHeaders != nil {
+		for key, value := range *checkConf.RequiredHeaders {
+			values, ok := response.Header[http.CanonicalHeaderKey(key)]
+			if !ok {
+				return fmt.Errorf("response header %s not found", key)
+			}
+			if value != "" && !containsString(values, value) {
+				return fmt.Errorf("response header %s: %v, while expect[%s]", key, values, value)
+			}
+		}
+	}
+
+	// check response body, read at most MaxCheckBodySize bytes
+	body, err := io.ReadAll(io.LimitReader(response.Body, cluster_conf.MaxCheckBodySize))
+	if err != nil {
+		return err
+	}
+	return matchCheckBody(checkConf, body)
+}
+
+// matchCheckBody checks response body of health check.
+func matchCheckBody(checkConf *cluster_conf.BackendCheck, body []byte) error {
+	if checkConf.BodyContains != nil && *checkConf.BodyContains != "" {
+		if !bytes.Contains(body, []byte(*checkConf.BodyContains)) {
+			return fmt.Errorf("response body not contains [%s]", *checkConf.BodyContains)
+		}
+	}
+
+	re, err := checkConf.BodyRegexp()
+	if err != nil {
+		return fmt.Errorf("invalid BodyRegex: %s", err.Error())
+	}
+	if re != nil && !re.Match(body) {
+		return fmt.Errorf("response body not match [%s]", re.String())
+	}
+
+	if checkConf.BodyJSONPath != nil && *checkConf.BodyJSONPath != "" {
+		if !gjson.ValidBytes(body) {
+			return errors.New("response body is not valid JSON")
+		}
+		result := gjson.GetBytes(body, *checkConf.BodyJSONPath)
+		if !result.Exists() {
+			return fmt.Errorf("JSON path [%s] not found in response body", *checkConf.BodyJSONPath)
+		}
+		if checkConf.BodyJSONValue != nil && result.String() != *checkConf.BodyJSONValue {
+			return fmt.Errorf("JSON path [%s] is [%s], while expect[%s]", *checkConf.BodyJSONPath,
+				result.String(), *checkConf.BodyJSONValue)
+		}
+	}
+
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
 // extractIP extract ip address
 func extractIP(rsAddr string) string {
 	if strings.HasPrefix(rsAddr, "[") {
@@ -267,6 +346,9 @@ func checkHTTPSConnect(backend *BfeBackend, checkConf *cluster_conf.BackendCheck
 				log.Logger.Debug("debug_https err=%s", err.Error())
 				return checkRtn{false, err}
 			}
+			if checkConf.NeedMatchResponse() {
+				return checkHTTPSResponse(conn, checkConf)
+			}
 			var (
 				response = ""
 				ok       bool
@@ -393,6 +475,33 @@ func checkHTTPSConnect(backend *BfeBackend, checkConf *cluster_conf.BackendCheck
 	return rtn.ok, rtn.err
 }
 
+// checkHTTPSResponse reads and checks response of https health check,
+// including response header and body.
+func checkHTTPSResponse(conn net.Conn, checkConf *cluster_conf.BackendCheck) checkRtn {
+	response, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		return checkRtn{false, err}
+	}
+	defer response.Body.Close()
+
+	if checkConf.StatusCodeRange != nil && *checkConf.StatusCodeRange != "" {
+		ok, err := cluster_conf.MatchStatusCodeRange(fmt.Sprintf("%d", response.StatusCode), *checkConf.StatusCodeRange)
+		if !ok {
+			return checkRtn{ok, err}
+		}
+	} else if checkConf.StatusCode != nil {
+		ok, err := cluster_conf.MatchStatusCode(response.StatusCode, *checkConf.StatusCode)
+		if !ok {
+			return checkRtn{ok, err}
+		}
+	}
+
+	if err := matchCheckResponse(checkConf, response); err != nil {
+		return checkRtn{false, err}
+	}
+	return checkRtn{true, nil}
+}
+
 func checkHTTPConnect(backend *BfeBackend, checkConf *cluster_conf.BackendCheck) (bool, error) {
 	// prepare health check request
 	addrInfo := getHealthCheckAddrInfo(backend, checkConf)
@@ -416,7 +525,7 @@ func checkHTTPConnect(backend *BfeBackend, checkConf *cluster_conf.BackendCheck)
 		checkTimeout = time.Duration(*checkConf.CheckTimeout) * time.Millisecond
 	}
 
-	statusCode, err := doHTTPHealthCheck(request, checkTimeout)
+	statusCode, err := doHTTPHealthCheck(request, checkTimeout, checkConf)
 	if err != nil {
 		return false, err
 	}
@@ -433,6 +542,10 @@ func CheckConnect(backend *BfeBackend, checkConf *cluster_conf.BackendCheck, htt
 		return checkTCPConnect(backend, checkConf)
 	case "https", "tls":
 		return checkHTTPSConnect(backend, checkConf, httpsConf)
+	case "h2c", "h2":
+		return checkH2Connect(backend, checkConf, httpsConf)
+	case "grpc":
+		return checkGRPCConnect(backend, checkConf, httpsConf)
 	default:
 		// never come here
 		return checkHTTPConnect(backend, checkConf)
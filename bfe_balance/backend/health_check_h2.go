@@ -0,0 +1,239 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// health check over http/2: h2c, h2 and grpc
+
+package backend
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/http2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/bfenetworks/bfe/bfe_config/bfe_cluster_conf/cluster_conf"
+	"github.com/bfenetworks/bfe/bfe_tls"
+)
+
+// getH2CheckTimeout returns timeout for h2/grpc health check. If
+// CheckTimeout is not configured, check interval is used, so that a stalled
+// backend never blocks subsequent checks.
+func getH2CheckTimeout(checkConf *cluster_conf.BackendCheck) time.Duration {
+	if checkConf.CheckTimeout != nil && *checkConf.CheckTimeout > 0 {
+		return time.Duration(*checkConf.CheckTimeout) * time.Millisecond
+	}
+	return time.Duration(*checkConf.CheckInterval) * time.Millisecond
+}
+
+// isTLSBackend checks whether backends of cluster are served over TLS.
+func isTLSBackend(httpsConf *cluster_conf.BackendHTTPS) bool {
+	if httpsConf == nil {
+		return false
+	}
+	protocol := httpsConf.GetProtocol()
+	return protocol == "https" || protocol == "h2"
+}
+
+// newH2CheckTLSConfig creates tls config for h2/grpc health check. Server
+// certificate is verified in the same way as https health check.
+func newH2CheckTLSConfig(serverName string, httpsConf *cluster_conf.BackendHTTPS) (*tls.Config, error) {
+	var (
+		rootCAs  *x509.CertPool
+		insecure = false
+		certs    []tls.Certificate
+		err      error
+	)
+
+	if httpsConf != nil {
+		if httpsConf.RSHost != nil && *httpsConf.RSHost != "" {
+			serverName = *httpsConf.RSHost
+		}
+		if rootCAs, err = httpsConf.GetRSCAList(); err != nil {
+			return nil, err
+		}
+		if httpsConf.RSInsecureSkipVerify != nil {
+			insecure = *httpsConf.RSInsecureSkipVerify
+		}
+		if cert, err := httpsConf.GetBFECert(); err == nil {
+			certs = []tls.Certificate{{
+				Certificate: cert.Certificate,
+				PrivateKey:  cert.PrivateKey,
+			}}
+		}
+	}
+
+	// Note: verification of crypto/tls is replaced by bfe_tls hooks, which
+	// also respect RSInsecureSkipVerify
+	return &tls.Config{
+		Certificates:          certs,
+		ServerName:            serverName,
+		RootCAs:               rootCAs,
+		InsecureSkipVerify:    true,
+		VerifyPeerCertificate: bfe_tls.NewVerifyPeerCertHooks(insecure, serverName, rootCAs).Ready(),
+	}, nil
+}
+
+// newH2CheckTransport creates http/2 transport for h2c (prior knowledge) or
+// h2 (TLS with ALPN) health check.
+func newH2CheckTransport(schem string, serverName string, httpsConf *cluster_conf.BackendHTTPS) (*http2.Transport, error) {
+	if schem == "h2c" {
+		return &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, addr)
+			},
+		}, nil
+	}
+
+	tlsConf, err := newH2CheckTLSConfig(serverName, httpsConf)
+	if err != nil {
+		return nil, err
+	}
+	tlsConf.NextProtos = []string{http2.NextProtoTLS}
+	return &http2.Transport{TLSClientConfig: tlsConf}, nil
+}
+
+// matchH2StatusCode checks status code of response, as https health check.
+func matchH2StatusCode(statusCode int, checkConf *cluster_conf.BackendCheck) (bool, error) {
+	if checkConf.StatusCodeRange != nil && *checkConf.StatusCodeRange != "" {
+		return cluster_conf.MatchStatusCodeRange(fmt.Sprintf("%d", statusCode), *checkConf.StatusCodeRange)
+	}
+	if checkConf.StatusCode != nil {
+		return cluster_conf.MatchStatusCode(statusCode, *checkConf.StatusCode)
+	}
+	return true, nil
+}
+
+func checkH2Connect(backend *BfeBackend, checkConf *cluster_conf.BackendCheck, httpsConf *cluster_conf.BackendHTTPS) (bool, error) {
+	// prepare health check request
+	addrInfo := getHealthCheckAddrInfo(backend, checkConf)
+	scheme := "https"
+	if *checkConf.Schem == "h2c" {
+		scheme = "http"
+	}
+	urlStr := fmt.Sprintf("%s://%s%s", scheme, addrInfo, *checkConf.Uri)
+	request, err := http.NewRequest("GET", urlStr, nil)
+	if err != nil {
+		return false, err
+	}
+
+	// modify http host header if needed
+	host := getHostByType(checkConf.Host, &addrInfo, checkConf.HostType, "")
+	if host != "" {
+		request.Host = host
+	}
+	request.Header.Set("Accept", "*/*")
+	request.Header.Set("User-Agent", "BFE-Health-Check")
+
+	serverName := request.URL.Hostname()
+	if host != "" {
+		serverName = host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			serverName = h
+		}
+	}
+	transport, err := newH2CheckTransport(*checkConf.Schem, serverName, httpsConf)
+	if err != nil {
+		return false, err
+	}
+	defer transport.CloseIdleConnections()
+
+	// do http/2 health check
+	client := &http.Client{
+		Transport: transport,
+		// Note: disable following an HTTP redirect
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		Timeout:       getH2CheckTimeout(checkConf),
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return false, err
+	}
+	defer response.Body.Close()
+
+	if ok, err := matchH2StatusCode(response.StatusCode, checkConf); !ok {
+		return false, err
+	}
+	if err := matchCheckResponse(checkConf, response); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// checkGRPCConnect checks backend using grpc health checking protocol
+// (grpc.health.v1.Health/Check). Backend is healthy only if it reports
+// SERVING for the configured service. Check is sent over TLS if backends
+// of cluster are served over TLS (https/h2).
+func checkGRPCConnect(backend *BfeBackend, checkConf *cluster_conf.BackendCheck, httpsConf *cluster_conf.BackendHTTPS) (bool, error) {
+	addrInfo := getHealthCheckAddrInfo(backend, checkConf)
+	host := getHostByType(checkConf.Host, &addrInfo, checkConf.HostType, "")
+
+	creds := insecure.NewCredentials()
+	if isTLSBackend(httpsConf) {
+		serverName := host
+		if serverName == "" {
+			serverName = addrInfo
+		}
+		if h, _, err := net.SplitHostPort(serverName); err == nil {
+			serverName = h
+		}
+		tlsConf, err := newH2CheckTLSConfig(serverName, httpsConf)
+		if err != nil {
+			return false, err
+		}
+		creds = credentials.NewTLS(tlsConf)
+	}
+
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithUserAgent("BFE-Health-Check"),
+	}
+	if host != "" {
+		opts = append(opts, grpc.WithAuthority(host))
+	}
+
+	conn, err := grpc.NewClient("passthrough:///"+addrInfo, opts...)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), getH2CheckTimeout(checkConf))
+	defer cancel()
+
+	service := ""
+	if checkConf.GRPCServiceName != nil {
+		service = *checkConf.GRPCServiceName
+	}
+	resp, err := grpc_health_v1.NewHealthClient(conn).Check(ctx,
+		&grpc_health_v1.HealthCheckRequest{Service: service})
+	if err != nil {
+		return false, err
+	}
+
+	if resp.GetStatus() != grpc_health_v1.HealthCheckResponse_SERVING {
+		return false, fmt.Errorf("grpc health status of service[%s] is %s", service, resp.GetStatus())
+	}
+	return true, nil
+}
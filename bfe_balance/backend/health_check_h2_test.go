@@ -0,0 +1,197 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/bfenetworks/bfe/bfe_config/bfe_cluster_conf/cluster_conf"
+)
+
+func newH2CheckConf(t *testing.T, schem string) *cluster_conf.BackendCheck {
+	checkTimeout := 1000
+	checkConf := &cluster_conf.BackendCheck{
+		Schem:        &schem,
+		CheckTimeout: &checkTimeout,
+	}
+	if err := cluster_conf.BackendCheckCheck(checkConf); err != nil {
+		t.Fatalf("BackendCheckCheck() error: %v", err)
+	}
+	return checkConf
+}
+
+func TestCheckConnect_h2c(t *testing.T) {
+	// mock h2c backend, accepts http/2 requests only
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 {
+			w.WriteHeader(http.StatusHTTPVersionNotSupported)
+			return
+		}
+		if r.URL.Path != "/health_check" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	ts := httptest.NewServer(h2c.NewHandler(handler, &http2.Server{}))
+	defer ts.Close()
+
+	backend := &BfeBackend{AddrInfo: strings.TrimPrefix(ts.URL, "http://")}
+	checkConf := newH2CheckConf(t, "h2c")
+	statusCode := 200
+	checkConf.StatusCode = &statusCode
+
+	if ok, err := CheckConnect(backend, checkConf, nil); !ok {
+		t.Errorf("backend should be healthy: %v", err)
+	}
+
+	// StatusCodeRange takes precedence over StatusCode
+	statusCodeRange := "4xx"
+	checkConf.StatusCodeRange = &statusCodeRange
+	if ok, _ := CheckConnect(backend, checkConf, nil); ok {
+		t.Errorf("backend should be unhealthy for unexpected status code range")
+	}
+	checkConf.StatusCodeRange = nil
+
+	uri := "/not_exist"
+	checkConf.Uri = &uri
+	if ok, _ := CheckConnect(backend, checkConf, nil); ok {
+		t.Errorf("backend should be unhealthy")
+	}
+}
+
+func TestCheckConnect_h2(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 {
+			w.WriteHeader(http.StatusHTTPVersionNotSupported)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	backend := &BfeBackend{AddrInfo: strings.TrimPrefix(ts.URL, "https://")}
+	checkConf := newH2CheckConf(t, "h2")
+	statusCode := 200
+	checkConf.StatusCode = &statusCode
+
+	// certificate of test server is not trusted
+	if ok, _ := CheckConnect(backend, checkConf, &cluster_conf.BackendHTTPS{}); ok {
+		t.Errorf("backend should be unhealthy")
+	}
+
+	insecure := true
+	httpsConf := &cluster_conf.BackendHTTPS{RSInsecureSkipVerify: &insecure}
+	if ok, err := CheckConnect(backend, checkConf, httpsConf); !ok {
+		t.Errorf("backend should be healthy: %v", err)
+	}
+}
+
+func TestCheckConnect_grpc(t *testing.T) {
+	// mock grpc backend with health service
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error: %v", err)
+	}
+	healthServer := health.NewServer()
+	server := grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(server, healthServer)
+	go server.Serve(ln)
+	defer server.Stop()
+
+	backend := &BfeBackend{AddrInfo: ln.Addr().String()}
+	checkConf := newH2CheckConf(t, "grpc")
+	service := "example.Service"
+	checkConf.GRPCServiceName = &service
+
+	// unknown service
+	if ok, _ := CheckConnect(backend, checkConf, nil); ok {
+		t.Errorf("backend should be unhealthy for unknown service")
+	}
+
+	// port is open, but service is not serving
+	healthServer.SetServingStatus(service, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	if ok, _ := CheckConnect(backend, checkConf, nil); ok {
+		t.Errorf("backend should be unhealthy for NOT_SERVING service")
+	}
+
+	healthServer.SetServingStatus(service, grpc_health_v1.HealthCheckResponse_SERVING)
+	if ok, err := CheckConnect(backend, checkConf, nil); !ok {
+		t.Errorf("backend should be healthy: %v", err)
+	}
+
+	// overall health status of server
+	service = ""
+	if ok, err := CheckConnect(backend, checkConf, nil); !ok {
+		t.Errorf("backend should be healthy: %v", err)
+	}
+}
+
+func TestCheckConnect_grpcTLS(t *testing.T) {
+	// borrow certificate of httptest server for grpc backend over TLS
+	ts := httptest.NewUnstartedServer(nil)
+	ts.StartTLS()
+	cert := ts.TLS.Certificates[0]
+	ts.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error: %v", err)
+	}
+	server := grpc.NewServer(grpc.Creds(credentials.NewServerTLSFromCert(&cert)))
+	grpc_health_v1.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(ln)
+	defer server.Stop()
+
+	backend := &BfeBackend{AddrInfo: ln.Addr().String()}
+	checkConf := newH2CheckConf(t, "grpc")
+
+	// plaintext check fails for TLS backend
+	if ok, _ := CheckConnect(backend, checkConf, nil); ok {
+		t.Errorf("backend should be unhealthy for plaintext check")
+	}
+
+	// certificate of test server is not trusted
+	protocol := "h2"
+	httpsConf := &cluster_conf.BackendHTTPS{}
+	if err := cluster_conf.BackendHTTPSCheck(&protocol, httpsConf); err != nil {
+		t.Fatalf("BackendHTTPSCheck() error: %v", err)
+	}
+	if ok, _ := CheckConnect(backend, checkConf, httpsConf); ok {
+		t.Errorf("backend should be unhealthy for untrusted certificate")
+	}
+
+	insecure := true
+	httpsConf = &cluster_conf.BackendHTTPS{RSInsecureSkipVerify: &insecure}
+	if err := cluster_conf.BackendHTTPSCheck(&protocol, httpsConf); err != nil {
+		t.Fatalf("BackendHTTPSCheck() error: %v", err)
+	}
+	if ok, err := CheckConnect(backend, checkConf, httpsConf); !ok {
+		t.Errorf("backend should be healthy: %v", err)
+	}
+}
@@ -447,6 +447,89 @@ func TestCheck_1(t *testing.T) {
 	}
 }
 
+// test CheckConnect, response header and body matching
+func TestCheckConnect_matchResponse(t *testing.T) {
+	// mock backend, returns 200 even if degraded
+	status := "ok"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Health", status)
+		fmt.Fprintf(w, `{"status":"%s","data":{"version":"1.0"}}`, status)
+	}))
+	defer ts.Close()
+
+	backend := BfeBackend{
+		AddrInfo: strings.TrimPrefix(ts.URL, "http://"),
+	}
+	schem := "http"
+	bodyContains := `"version"`
+	bodyRegex := `"status":"(ok|good)"`
+	jsonPath := "status"
+	jsonValue := "ok"
+	headers := map[string]string{"x-health": "", "Content-Type": "text/plain; charset=utf-8"}
+	checkConf := cluster_conf.BackendCheck{
+		Schem:           &schem,
+		BodyContains:    &bodyContains,
+		BodyRegex:       &bodyRegex,
+		BodyJSONPath:    &jsonPath,
+		BodyJSONValue:   &jsonValue,
+		RequiredHeaders: &headers,
+	}
+	if err := cluster_conf.BackendCheckCheck(&checkConf); err != nil {
+		t.Fatalf("BackendCheckCheck() error: %v", err)
+	}
+
+	if ok, err := CheckConnect(&backend, &checkConf, nil); !ok {
+		t.Errorf("backend should be healthy: %v", err)
+	}
+
+	// degraded backend should be unhealthy
+	status = "degraded"
+	if ok, _ := CheckConnect(&backend, &checkConf, nil); ok {
+		t.Errorf("backend should be unhealthy")
+	}
+
+	// required header not found
+	status = "ok"
+	headers["X-Not-Exist"] = ""
+	if ok, _ := CheckConnect(&backend, &checkConf, nil); ok {
+		t.Errorf("backend should be unhealthy")
+	}
+}
+
+func TestMatchCheckBody(t *testing.T) {
+	body := []byte(`{"status":"degraded","checks":[{"name":"db","ok":true}]}`)
+	cases := []struct {
+		path  string
+		value *string
+		ok    bool
+	}{
+		{"status", nil, true},
+		{"checks.0.ok", nil, true},
+		{"checks.0.ok", strPtr("true"), true},
+		{"status", strPtr("ok"), false},
+		{"not_exist", nil, false},
+	}
+
+	for _, c := range cases {
+		path := c.path
+		checkConf := cluster_conf.BackendCheck{BodyJSONPath: &path, BodyJSONValue: c.value}
+		if err := matchCheckBody(&checkConf, body); (err == nil) != c.ok {
+			t.Errorf("matchCheckBody(%s) err: %v", c.path, err)
+		}
+	}
+
+	// invalid json
+	path := "status"
+	checkConf := cluster_conf.BackendCheck{BodyJSONPath: &path}
+	if err := matchCheckBody(&checkConf, []byte("status")); err == nil {
+		t.Errorf("matchCheckBody() should fail for invalid JSON")
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
 // test CheckConnect->checkHTTPSConnect >>>>>>>>>>>>>>>>>>>>
 // test CheckConnect->checkHTTPSConnect >>>>>>>>>>>>>>>>>>>>
 func TestCheckConnect_checkHTTPSConnect(t *testing.T) {
@@ -0,0 +1,493 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// passive outlier detection for backends of a cluster
+//
+// Detectors:
+//   - consecutive 5xx: backend is ejected after Consecutive5xx consecutive
+//     5xx responses (gateway failures are counted as 5xx too).
+//   - consecutive gateway failure: backend is ejected after
+//     ConsecutiveGatewayFailure consecutive 502/503/504 responses or
+//     connect/read failures.
+//   - success rate: every Interval, if at least SuccessRateMinimumHosts
+//     backends have SuccessRateRequestVolume requests or more, a backend is
+//     ejected if its success rate is less than
+//     mean - stdev * SuccessRateStdevFactor / 1000.
+//
+// An ejected backend is excluded from balance for
+// min(BaseEjectionTime * 2^(n-1), MaxEjectionTime), where n is the number of
+// consecutive ejections. n decreases by one for each interval in which the
+// backend is not ejected. At most MaxEjectionPercent of backends in a cluster
+// are ejected at the same time, while at least one backend can be ejected
+// regardless of MaxEjectionPercent (as envoy does).
+//
+// Ejection expiry and success rate are checked by a background goroutine
+// every Interval, so balance of requests is never blocked by the check.
+//
+// Results of requests are recorded by per-backend atomic counters. The lock
+// of detector is only taken by the periodic check, and when a backend reaches
+// a consecutive failure threshold.
+
+package backend
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bfenetworks/go-lib/log"
+
+	"github.com/bfenetworks/bfe/bfe_config/bfe_cluster_conf/cluster_conf"
+)
+
+// Results of backend request for outlier detection.
+const (
+	OutlierSuccess        = iota // non-5xx response
+	OutlierError5xx              // 5xx response, except gateway failure
+	OutlierGatewayFailure        // 502/503/504 response, or connect/read failure
+)
+
+// Reasons of ejection.
+const (
+	EjectConsecutive5xx            = "consecutive_5xx"
+	EjectConsecutiveGatewayFailure = "consecutive_gateway_failure"
+	EjectSuccessRate               = "success_rate"
+)
+
+// outlierStat is outlier detection state of backend.
+type outlierStat struct {
+	// updated atomically on each request
+	consecutive5xx            atomic.Int64 // number of consecutive 5xx responses
+	consecutiveGatewayFailure atomic.Int64 // number of consecutive gateway failures
+	succNum                   atomic.Int64 // number of successful requests in current interval
+	totalNum                  atomic.Int64 // number of requests in current interval
+	ejected                   atomic.Bool  // whether backend is ejected
+
+	// guarded by lock of OutlierDetector
+	ejectReason   string        // reason of last ejection
+	ejectTime     time.Time     // time of last ejection
+	ejectDuration time.Duration // duration of last ejection
+	ejectNum      int           // number of consecutive ejections
+}
+
+// ejectionDuration returns ejection duration for the n-th consecutive ejection.
+func ejectionDuration(n int, base, max time.Duration) time.Duration {
+	d := base
+	for i := 1; i < n && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+// Ejected returns whether backend is ejected by outlier detection.
+func (back *BfeBackend) Ejected() bool {
+	return back.outlier.ejected.Load()
+}
+
+// recordOutlierResult records result of request, and returns reason of
+// ejection if any consecutive failure threshold is reached.
+func (back *BfeBackend) recordOutlierResult(result int, conf *cluster_conf.OutlierDetectionConf) string {
+	stat := &back.outlier
+	stat.totalNum.Add(1)
+
+	var consecutive5xx, consecutiveGatewayFailure int64
+	switch result {
+	case OutlierSuccess:
+		stat.succNum.Add(1)
+		// Note: avoid writing shared counters if not needed
+		if stat.consecutive5xx.Load() != 0 {
+			stat.consecutive5xx.Store(0)
+		}
+		if stat.consecutiveGatewayFailure.Load() != 0 {
+			stat.consecutiveGatewayFailure.Store(0)
+		}
+		return ""
+	case OutlierError5xx:
+		consecutive5xx = stat.consecutive5xx.Add(1)
+		stat.consecutiveGatewayFailure.Store(0)
+	case OutlierGatewayFailure:
+		consecutive5xx = stat.consecutive5xx.Add(1)
+		consecutiveGatewayFailure = stat.consecutiveGatewayFailure.Add(1)
+	}
+
+	if stat.ejected.Load() {
+		return ""
+	}
+	if *conf.ConsecutiveGatewayFailure > 0 && consecutiveGatewayFailure >= int64(*conf.ConsecutiveGatewayFailure) {
+		return EjectConsecutiveGatewayFailure
+	}
+	if *conf.Consecutive5xx > 0 && consecutive5xx >= int64(*conf.Consecutive5xx) {
+		return EjectConsecutive5xx
+	}
+	return ""
+}
+
+// eject ejects backend, returns false if backend is already ejected.
+// Note: lock of OutlierDetector should be held
+func (back *BfeBackend) eject(reason string, now time.Time, base, max time.Duration) bool {
+	stat := &back.outlier
+	if stat.ejected.Load() {
+		return false
+	}
+
+	stat.ejectNum++
+	stat.ejectReason = reason
+	stat.ejectTime = now
+	stat.ejectDuration = ejectionDuration(stat.ejectNum, base, max)
+	stat.consecutive5xx.Store(0)
+	stat.consecutiveGatewayFailure.Store(0)
+	stat.ejected.Store(true)
+	return true
+}
+
+// unejectIfExpired brings backend back if its ejection is expired, returns
+// true if backend is brought back.
+// Note: lock of OutlierDetector should be held
+func (back *BfeBackend) unejectIfExpired(now time.Time) bool {
+	stat := &back.outlier
+	if !stat.ejected.Load() {
+		// backend is healthy for an interval
+		if stat.ejectNum > 0 {
+			stat.ejectNum--
+		}
+		return false
+	}
+
+	if now.Sub(stat.ejectTime) < stat.ejectDuration {
+		return false
+	}
+	stat.ejected.Store(false)
+	return true
+}
+
+// successRate returns success rate (in percent) of current interval, and
+// whether the backend takes part in success rate analysis.
+func (back *BfeBackend) successRate(volume int) (float64, bool) {
+	stat := &back.outlier
+	totalNum := stat.totalNum.Load()
+	succNum := stat.succNum.Load()
+	if stat.ejected.Load() || totalNum < int64(volume) || totalNum == 0 {
+		return 0, false
+	}
+	if succNum > totalNum {
+		// counters may be updated between loads
+		succNum = totalNum
+	}
+	return float64(succNum) * 100 / float64(totalNum), true
+}
+
+// resetOutlier resets outlier detection state of backend. If all is false,
+// only counters of current interval are reset.
+// Note: lock of OutlierDetector should be held
+func (back *BfeBackend) resetOutlier(all bool) {
+	stat := &back.outlier
+	stat.succNum.Store(0)
+	stat.totalNum.Store(0)
+	if !all {
+		return
+	}
+
+	stat.consecutive5xx.Store(0)
+	stat.consecutiveGatewayFailure.Store(0)
+	stat.ejected.Store(false)
+	stat.ejectReason = ""
+	stat.ejectTime = time.Time{}
+	stat.ejectDuration = 0
+	stat.ejectNum = 0
+}
+
+// OutlierBackendState is state of an ejected backend.
+type OutlierBackendState struct {
+	SubCluster string // name of sub cluster
+	Name       string // name of backend
+	AddrInfo   string // address of backend
+	Reason     string // reason of ejection
+	EjectNum   int    // number of consecutive ejections
+	EjectTime  string // time of ejection
+	Duration   string // duration of ejection
+}
+
+// OutlierState is state of outlier detector.
+type OutlierState struct {
+	Enabled     bool                   // whether outlier detection is enabled
+	BackendNum  int                    // number of backends in cluster
+	EjectedNum  int                    // number of ejected backends
+	Ejections   map[string]int64       // number of ejections by reason
+	OverflowNum int64                  // number of ejections skipped due to MaxEjectionPercent
+	Ejected     []*OutlierBackendState // ejected backends
+}
+
+// OutlierDetector detects and ejects outlier backends of a cluster.
+type OutlierDetector struct {
+	lock sync.Mutex
+
+	cluster   string                                            // name of cluster
+	conf      atomic.Pointer[cluster_conf.OutlierDetectionConf] // conf of outlier detection, nil if disabled
+	backends  []*BfeBackend                                     // backends of cluster
+	lastCheck time.Time                                         // time of last interval check
+
+	ejections   map[string]int64 // number of ejections by reason
+	overflowNum int64            // number of ejections skipped due to MaxEjectionPercent
+
+	stopCh chan struct{} // stop channel of check loop, nil if not running
+}
+
+func NewOutlierDetector(cluster string) *OutlierDetector {
+	d := new(OutlierDetector)
+	d.cluster = cluster
+	d.ejections = make(map[string]int64)
+	return d
+}
+
+// outlierEnabled checks whether any detector is enabled in conf.
+func outlierEnabled(conf *cluster_conf.OutlierDetectionConf) bool {
+	if conf == nil {
+		return false
+	}
+	return *conf.Consecutive5xx > 0 || *conf.ConsecutiveGatewayFailure > 0 ||
+		*conf.SuccessRateStdevFactor > 0
+}
+
+// SetConf sets conf of outlier detection. Ejected backends are brought back
+// if outlier detection is disabled.
+func (d *OutlierDetector) SetConf(conf *cluster_conf.OutlierDetectionConf) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if !outlierEnabled(conf) {
+		conf = nil
+		if d.conf.Load() != nil {
+			for _, back := range d.backends {
+				back.resetOutlier(true)
+			}
+		}
+	}
+	d.conf.Store(conf)
+
+	// start or stop check loop
+	if conf != nil && d.stopCh == nil {
+		d.stopCh = make(chan struct{})
+		go d.checkLoop(d.stopCh)
+	}
+	if conf == nil {
+		d.stopUnlocked()
+	}
+}
+
+// Stop stops background check of detector.
+func (d *OutlierDetector) Stop() {
+	d.lock.Lock()
+	d.stopUnlocked()
+	d.lock.Unlock()
+}
+
+func (d *OutlierDetector) stopUnlocked() {
+	if d.stopCh != nil {
+		close(d.stopCh)
+		d.stopCh = nil
+	}
+}
+
+// checkInterval returns interval of check loop.
+func (d *OutlierDetector) checkInterval() time.Duration {
+	conf := d.conf.Load()
+	if conf == nil {
+		return time.Second
+	}
+	return time.Duration(*conf.Interval) * time.Millisecond
+}
+
+// checkLoop checks ejection expiry and success rate every interval, even if
+// there is no request result.
+func (d *OutlierDetector) checkLoop(stopCh chan struct{}) {
+	timer := time.NewTimer(d.checkInterval())
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case now := <-timer.C:
+			d.check(now)
+			timer.Reset(d.checkInterval())
+		}
+	}
+}
+
+// SetBackends sets backends of cluster.
+func (d *OutlierDetector) SetBackends(backends []*BfeBackend) {
+	d.lock.Lock()
+	d.backends = backends
+	d.lock.Unlock()
+}
+
+// OnResult records result of request to backend, and ejects the backend if
+// it becomes an outlier.
+func (d *OutlierDetector) OnResult(back *BfeBackend, result int) {
+	d.onResult(back, result, time.Now())
+}
+
+func (d *OutlierDetector) onResult(back *BfeBackend, result int, now time.Time) {
+	conf := d.conf.Load()
+	if conf == nil {
+		return
+	}
+
+	reason := back.recordOutlierResult(result, conf)
+	if reason == "" {
+		return
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if conf = d.conf.Load(); conf != nil {
+		d.ejectUnlocked(conf, back, reason, now)
+	}
+}
+
+func (d *OutlierDetector) check(now time.Time) {
+	d.lock.Lock()
+	if conf := d.conf.Load(); conf != nil {
+		d.checkUnlocked(conf, now)
+	}
+	d.lock.Unlock()
+}
+
+func (d *OutlierDetector) checkUnlocked(conf *cluster_conf.OutlierDetectionConf, now time.Time) {
+	if d.lastCheck.IsZero() {
+		d.lastCheck = now
+		return
+	}
+	if now.Sub(d.lastCheck) < time.Duration(*conf.Interval)*time.Millisecond {
+		return
+	}
+	d.lastCheck = now
+
+	// bring back backends with expired ejection
+	for _, back := range d.backends {
+		if back.unejectIfExpired(now) {
+			log.Logger.Info("outlier: backend %s of cluster %s is brought back",
+				back.AddrInfo, d.cluster)
+		}
+	}
+
+	if *conf.SuccessRateStdevFactor > 0 {
+		d.successRateEjectUnlocked(conf, now)
+	}
+
+	// start a new interval
+	for _, back := range d.backends {
+		back.resetOutlier(false)
+	}
+}
+
+func (d *OutlierDetector) successRateEjectUnlocked(conf *cluster_conf.OutlierDetectionConf, now time.Time) {
+	var backs []*BfeBackend
+	var rates []float64
+	for _, back := range d.backends {
+		if rate, ok := back.successRate(*conf.SuccessRateRequestVolume); ok {
+			backs = append(backs, back)
+			rates = append(rates, rate)
+		}
+	}
+	if len(backs) == 0 || len(backs) < *conf.SuccessRateMinimumHosts {
+		return
+	}
+
+	// calculate mean and standard deviation of success rates
+	sum := 0.0
+	for _, rate := range rates {
+		sum += rate
+	}
+	mean := sum / float64(len(rates))
+	variance := 0.0
+	for _, rate := range rates {
+		variance += (rate - mean) * (rate - mean)
+	}
+	stdev := math.Sqrt(variance / float64(len(rates)))
+
+	threshold := mean - stdev*float64(*conf.SuccessRateStdevFactor)/1000
+	for i, back := range backs {
+		if rates[i] < threshold {
+			d.ejectUnlocked(conf, back, EjectSuccessRate, now)
+		}
+	}
+}
+
+// ejectedNumUnlocked returns number of ejected backends.
+func (d *OutlierDetector) ejectedNumUnlocked() int {
+	num := 0
+	for _, back := range d.backends {
+		if back.Ejected() {
+			num++
+		}
+	}
+	return num
+}
+
+func (d *OutlierDetector) ejectUnlocked(conf *cluster_conf.OutlierDetectionConf, back *BfeBackend,
+	reason string, now time.Time) {
+	// check MaxEjectionPercent, while at least one backend can be ejected
+	ejectedNum := d.ejectedNumUnlocked()
+	if ejectedNum > 0 && ejectedNum*100 >= *conf.MaxEjectionPercent*len(d.backends) {
+		d.overflowNum++
+		return
+	}
+
+	base := time.Duration(*conf.BaseEjectionTime) * time.Millisecond
+	max := time.Duration(*conf.MaxEjectionTime) * time.Millisecond
+	if back.eject(reason, now, base, max) {
+		d.ejections[reason]++
+		log.Logger.Info("outlier: backend %s of cluster %s is ejected for %s",
+			back.AddrInfo, d.cluster, reason)
+	}
+}
+
+// State returns state of outlier detector.
+func (d *OutlierDetector) State() *OutlierState {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	state := new(OutlierState)
+	state.Enabled = d.conf.Load() != nil
+	state.BackendNum = len(d.backends)
+	state.OverflowNum = d.overflowNum
+	state.Ejections = make(map[string]int64, len(d.ejections))
+	for reason, num := range d.ejections {
+		state.Ejections[reason] = num
+	}
+
+	for _, back := range d.backends {
+		stat := &back.outlier
+		if !stat.ejected.Load() {
+			continue
+		}
+		state.EjectedNum++
+		state.Ejected = append(state.Ejected, &OutlierBackendState{
+			SubCluster: back.SubCluster,
+			Name:       back.Name,
+			AddrInfo:   back.AddrInfo,
+			Reason:     stat.ejectReason,
+			EjectNum:   stat.ejectNum,
+			EjectTime:  stat.ejectTime.Format(time.RFC3339),
+			Duration:   stat.ejectDuration.String(),
+		})
+	}
+
+	return state
+}
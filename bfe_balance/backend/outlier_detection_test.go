@@ -0,0 +1,277 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_config/bfe_cluster_conf/cluster_conf"
+)
+
+func prepareOutlierDetector(t *testing.T, num int, conf cluster_conf.OutlierDetectionConf) (
+	*OutlierDetector, []*BfeBackend) {
+	if err := cluster_conf.OutlierDetectionConfCheck(&conf); err != nil {
+		t.Fatalf("OutlierDetectionConfCheck() error: %v", err)
+	}
+
+	var backends []*BfeBackend
+	for i := 0; i < num; i++ {
+		back := NewBfeBackendByAddrinfo("sub", fmt.Sprintf("b%d", i), fmt.Sprintf("10.0.0.%d:80", i))
+		backends = append(backends, back)
+	}
+
+	d := NewOutlierDetector("cluster")
+	d.SetConf(&conf)
+	d.SetBackends(backends)
+	t.Cleanup(d.Stop)
+	return d, backends
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestEjectionDuration(t *testing.T) {
+	base, max := 10*time.Second, 60*time.Second
+	expects := []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, 60 * time.Second, 60 * time.Second}
+	for i, expect := range expects {
+		if d := ejectionDuration(i+1, base, max); d != expect {
+			t.Errorf("ejectionDuration(%d) should be %s, not %s", i+1, expect, d)
+		}
+	}
+}
+
+func TestOutlierConsecutiveFailure(t *testing.T) {
+	d, backends := prepareOutlierDetector(t, 10, cluster_conf.OutlierDetectionConf{
+		Consecutive5xx:            intPtr(5),
+		ConsecutiveGatewayFailure: intPtr(3),
+		BaseEjectionTime:          intPtr(10000),
+	})
+	now := time.Now()
+	d.check(now)
+
+	// success resets consecutive counter
+	back := backends[0]
+	for i := 0; i < 4; i++ {
+		d.onResult(back, OutlierError5xx, now)
+	}
+	d.onResult(back, OutlierSuccess, now)
+	d.onResult(back, OutlierError5xx, now)
+	if back.Ejected() || !back.Avail() {
+		t.Fatalf("backend should not be ejected")
+	}
+
+	// ejected by consecutive 5xx
+	for i := 0; i < 4; i++ {
+		d.onResult(back, OutlierError5xx, now)
+	}
+	if !back.Ejected() || back.Avail() {
+		t.Fatalf("backend should be ejected")
+	}
+
+	// brought back after ejection time
+	d.check(now.Add(11 * time.Second))
+	if back.Ejected() {
+		t.Errorf("backend should be brought back")
+	}
+
+	// ejected by consecutive gateway failure, for a doubled time
+	now = now.Add(11 * time.Second)
+	for i := 0; i < 3; i++ {
+		d.onResult(back, OutlierGatewayFailure, now)
+	}
+	if !back.Ejected() {
+		t.Fatalf("backend should be ejected")
+	}
+	state := d.State()
+	if state.EjectedNum != 1 || state.Ejected[0].Reason != EjectConsecutiveGatewayFailure ||
+		state.Ejected[0].Duration != (20*time.Second).String() {
+		t.Errorf("unexpected state: %+v %+v", state, state.Ejected[0])
+	}
+	if state.Ejections[EjectConsecutive5xx] != 1 || state.Ejections[EjectConsecutiveGatewayFailure] != 1 {
+		t.Errorf("unexpected ejections: %v", state.Ejections)
+	}
+}
+
+func TestOutlierMaxEjectionPercent(t *testing.T) {
+	d, backends := prepareOutlierDetector(t, 10, cluster_conf.OutlierDetectionConf{
+		ConsecutiveGatewayFailure: intPtr(1),
+		MaxEjectionPercent:        intPtr(20),
+	})
+	now := time.Now()
+
+	for _, back := range backends {
+		d.onResult(back, OutlierGatewayFailure, now)
+	}
+
+	state := d.State()
+	if state.EjectedNum != 2 || state.OverflowNum != 8 {
+		t.Errorf("EjectedNum should be 2 and OverflowNum should be 8: %+v", state)
+	}
+
+	// at least one backend can be ejected in small cluster
+	d, backends = prepareOutlierDetector(t, 3, cluster_conf.OutlierDetectionConf{
+		ConsecutiveGatewayFailure: intPtr(1),
+		MaxEjectionPercent:        intPtr(20),
+	})
+	for _, back := range backends {
+		d.onResult(back, OutlierGatewayFailure, now)
+	}
+	state = d.State()
+	if state.EjectedNum != 1 || state.OverflowNum != 2 {
+		t.Errorf("EjectedNum should be 1 and OverflowNum should be 2: %+v", state)
+	}
+}
+
+func TestOutlierCheckLoop(t *testing.T) {
+	d, backends := prepareOutlierDetector(t, 2, cluster_conf.OutlierDetectionConf{
+		Consecutive5xx:   intPtr(1),
+		BaseEjectionTime: intPtr(10),
+		MaxEjectionTime:  intPtr(10),
+		Interval:         intPtr(10),
+	})
+	d.onResult(backends[0], OutlierError5xx, time.Now())
+	if !backends[0].Ejected() {
+		t.Fatalf("backend should be ejected")
+	}
+
+	// brought back by check loop, without any request result
+	deadline := time.Now().Add(time.Second)
+	for backends[0].Ejected() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if backends[0].Ejected() {
+		t.Errorf("backend should be brought back by check loop")
+	}
+}
+
+func TestOutlierSuccessRate(t *testing.T) {
+	d, backends := prepareOutlierDetector(t, 10, cluster_conf.OutlierDetectionConf{
+		SuccessRateStdevFactor:   intPtr(1900),
+		SuccessRateRequestVolume: intPtr(100),
+		Interval:                 intPtr(1000),
+	})
+	now := time.Now()
+	d.check(now)
+
+	// 10% errors on backends[0], never trips consecutive detectors
+	for i := 0; i < 100; i++ {
+		for j, back := range backends {
+			result := OutlierSuccess
+			if j == 0 && i%10 == 0 {
+				result = OutlierError5xx
+			}
+			d.onResult(back, result, now)
+		}
+	}
+	if backends[0].Ejected() {
+		t.Fatalf("backend should not be ejected before interval")
+	}
+
+	d.check(now.Add(time.Second))
+	for i, back := range backends {
+		if back.Ejected() != (i == 0) {
+			t.Errorf("backend %d: unexpected ejected %v", i, back.Ejected())
+		}
+	}
+
+	// counters are reset for next interval
+	if _, ok := backends[1].successRate(100); ok {
+		t.Errorf("counters should be reset")
+	}
+}
+
+func TestOutlierDisabled(t *testing.T) {
+	d, backends := prepareOutlierDetector(t, 2, cluster_conf.OutlierDetectionConf{
+		Consecutive5xx: intPtr(1),
+	})
+	d.onResult(backends[0], OutlierError5xx, time.Now())
+	if !backends[0].Ejected() {
+		t.Fatalf("backend should be ejected")
+	}
+
+	// disable outlier detection, ejected backends are brought back
+	conf := cluster_conf.OutlierDetectionConf{}
+	cluster_conf.OutlierDetectionConfCheck(&conf)
+	d.SetConf(&conf)
+	if backends[0].Ejected() {
+		t.Errorf("backend should be brought back")
+	}
+
+	d.onResult(backends[0], OutlierError5xx, time.Now())
+	if backends[0].Ejected() || d.State().Enabled {
+		t.Errorf("outlier detection should be disabled")
+	}
+}
+
+func TestOutlierOnResultWithoutLock(t *testing.T) {
+	d, backends := prepareOutlierDetector(t, 2, cluster_conf.OutlierDetectionConf{
+		Consecutive5xx:         intPtr(5),
+		SuccessRateStdevFactor: intPtr(1900),
+	})
+
+	// results below thresholds are recorded while detector is locked
+	d.lock.Lock()
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			d.OnResult(backends[0], OutlierSuccess)
+			d.OnResult(backends[1], OutlierError5xx)
+			d.OnResult(backends[1], OutlierSuccess)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("OnResult should not wait for lock of detector")
+	}
+	d.lock.Unlock()
+
+	if rate, ok := backends[1].successRate(200); !ok || rate != 50 {
+		t.Errorf("success rate should be 50, not %v", rate)
+	}
+}
+
+func TestOutlierConcurrentResults(t *testing.T) {
+	d, backends := prepareOutlierDetector(t, 4, cluster_conf.OutlierDetectionConf{
+		Consecutive5xx:            intPtr(3),
+		ConsecutiveGatewayFailure: intPtr(2),
+		SuccessRateStdevFactor:    intPtr(1900),
+		Interval:                  intPtr(10),
+		BaseEjectionTime:          intPtr(10),
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				d.OnResult(backends[(i+j)%len(backends)], (i+j)%3)
+			}
+		}(i)
+	}
+	for i := 0; i < 10; i++ {
+		d.check(time.Now().Add(time.Duration(i) * 10 * time.Millisecond))
+		d.State()
+	}
+	wg.Wait()
+}
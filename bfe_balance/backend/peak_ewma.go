@@ -0,0 +1,103 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// peak exponentially weighted moving average of backend latency
+//
+// Algorithm (see Finagle PeakEwma):
+//   - on each observed latency rtt, with w = exp(-elapsed / decayTime):
+//     cost = rtt,                   if rtt > cost (react to peaks at once)
+//     cost = cost*w + rtt*(1 - w),  otherwise
+//   - load of backend is cost * (in-flight requests + 1)
+
+package backend
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// PeakEwmaDecayTime is decay time of latency moving average.
+	PeakEwmaDecayTime = 10 * time.Second
+
+	// PeakEwmaPenalty is latency (in ns) assumed for backends without any
+	// observation yet, but with in-flight requests.
+	PeakEwmaPenalty = float64(time.Second)
+)
+
+// peakEwma is the latency state of backend, guarded by BfeBackend lock.
+type peakEwma struct {
+	cost  float64   // moving average of latency, in ns
+	stamp time.Time // time of last update
+}
+
+// observe updates moving average with latency (in ns) at given time.
+func (e *peakEwma) observe(rtt float64, now time.Time) {
+	if e.stamp.IsZero() {
+		e.cost = rtt
+		e.stamp = now
+		return
+	}
+
+	td := now.Sub(e.stamp)
+	if td < 0 {
+		td = 0
+	}
+	w := math.Exp(-float64(td) / float64(PeakEwmaDecayTime))
+
+	if rtt > e.cost {
+		e.cost = rtt
+	} else {
+		e.cost = e.cost*w + rtt*(1-w)
+	}
+	e.stamp = now
+}
+
+// OnResponseTime records latency of request to backend.
+func (back *BfeBackend) OnResponseTime(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+
+	back.Lock()
+	back.ewma.observe(float64(d), time.Now())
+	back.Unlock()
+}
+
+// EwmaLatency returns current moving average of latency.
+func (back *BfeBackend) EwmaLatency() time.Duration {
+	back.RLock()
+	cost := back.ewma.cost
+	back.RUnlock()
+
+	return time.Duration(cost)
+}
+
+// PeakEwmaLoad returns load of backend: latency moving average multiplied
+// by number of in-flight requests.
+func (back *BfeBackend) PeakEwmaLoad() float64 {
+	back.Lock()
+	defer back.Unlock()
+
+	// decay cost towards zero if no latency observed recently
+	if !back.ewma.stamp.IsZero() {
+		back.ewma.observe(0, time.Now())
+	}
+
+	pending := float64(back.connNum)
+	if back.ewma.cost == 0 && pending != 0 {
+		return PeakEwmaPenalty + pending
+	}
+	return back.ewma.cost * (pending + 1)
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPeakEwmaObserve(t *testing.T) {
+	var e peakEwma
+	now := time.Now()
+
+	// first observation
+	e.observe(100, now)
+	if e.cost != 100 {
+		t.Errorf("cost should be 100, not %f", e.cost)
+	}
+
+	// peak is taken at once
+	e.observe(500, now.Add(time.Millisecond))
+	if e.cost != 500 {
+		t.Errorf("cost should be 500, not %f", e.cost)
+	}
+
+	// lower latency decays slowly
+	e.observe(100, now.Add(2*time.Millisecond))
+	if e.cost <= 100 || e.cost >= 500 {
+		t.Errorf("cost should be in (100, 500), not %f", e.cost)
+	}
+
+	// lower latency after a long time dominates
+	e.observe(100, now.Add(10*PeakEwmaDecayTime))
+	if e.cost > 101 {
+		t.Errorf("cost should be about 100, not %f", e.cost)
+	}
+}
+
+func TestPeakEwmaLoad(t *testing.T) {
+	back := NewBfeBackend()
+	if back.PeakEwmaLoad() != 0 {
+		t.Errorf("load of idle backend should be 0")
+	}
+
+	// no observation but with in-flight requests
+	back.IncConnNum()
+	if back.PeakEwmaLoad() < PeakEwmaPenalty {
+		t.Errorf("load should be penalized")
+	}
+
+	back.OnResponseTime(10 * time.Millisecond)
+	load1 := back.PeakEwmaLoad()
+	back.IncConnNum()
+	load2 := back.PeakEwmaLoad()
+	if load2 <= load1 {
+		t.Errorf("load should grow with in-flight requests: %f, %f", load1, load2)
+	}
+	if back.EwmaLatency() <= 0 {
+		t.Errorf("ewma latency should be positive")
+	}
+}
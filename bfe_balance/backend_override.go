@@ -0,0 +1,277 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// runtime override of backends
+//
+// Weight and admin mode (draining or maintenance) of a backend could be
+// overridden at runtime, without modifying cluster table. Overrides are kept
+// across reloads of cluster table, until they are cleared or expired.
+
+package bfe_balance
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+import (
+	"github.com/bfenetworks/go-lib/log"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_balance/backend"
+	"github.com/bfenetworks/bfe/bfe_balance/bal_gslb"
+	"github.com/bfenetworks/bfe/bfe_config/bfe_cluster_conf/cluster_table_conf"
+)
+
+const overrideCheckInterval = time.Second // interval of checking expired overrides
+
+// BackendOverride is runtime override of a backend.
+type BackendOverride struct {
+	Cluster    string    // name of cluster
+	Backend    string    // address of backend, e.g. "10.1.1.1:8080"
+	Weight     *int      // weight of backend, nil if not overridden
+	Mode       string    // admin mode of backend, e.g. draining or maintenance
+	CreateTime time.Time // time of override created
+	ExpireTime time.Time // time of override expired, zero for never
+}
+
+// backendOverrides holds mappings from cluster to backend to override.
+type backendOverrides map[string]map[string]*BackendOverride
+
+// BackendOverrideCheck checks override of backend.
+func BackendOverrideCheck(o BackendOverride) error {
+	if o.Cluster == "" {
+		return fmt.Errorf("no cluster")
+	}
+	if o.Backend == "" {
+		return fmt.Errorf("no backend")
+	}
+	if o.Weight == nil && o.Mode == backend.ModeNormal {
+		return fmt.Errorf("neither weight nor mode is set")
+	}
+	if o.Weight != nil && *o.Weight < 0 {
+		return fmt.Errorf("invalid weight %d", *o.Weight)
+	}
+
+	switch o.Mode {
+	case backend.ModeNormal, backend.ModeDraining, backend.ModeMaintenance:
+	default:
+		return fmt.Errorf("invalid mode %s", o.Mode)
+	}
+	return nil
+}
+
+func (o *BackendOverride) expired(now time.Time) bool {
+	return !o.ExpireTime.IsZero() && !now.Before(o.ExpireTime)
+}
+
+// applyWeight replaces weight of backends in conf with overridden ones.
+// Backends with overridden weight are copied, and conf is left untouched.
+func (bo backendOverrides) applyWeight(conf cluster_table_conf.AllClusterBackend) cluster_table_conf.AllClusterBackend {
+	applied := make(cluster_table_conf.AllClusterBackend, len(conf))
+	for clusterName, clusterBackend := range conf {
+		overrides, ok := bo[clusterName]
+		if !ok {
+			applied[clusterName] = clusterBackend
+			continue
+		}
+
+		newClusterBackend := make(cluster_table_conf.ClusterBackend, len(clusterBackend))
+		for subName, backends := range clusterBackend {
+			newBackends := make(cluster_table_conf.SubClusterBackend, 0, len(backends))
+			for _, backendConf := range backends {
+				o, ok := overrides[backendConf.AddrInfo()]
+				if ok && o.Weight != nil {
+					newConf := *backendConf
+					newConf.Weight = new(int)
+					*newConf.Weight = *o.Weight
+					backendConf = &newConf
+				}
+				newBackends = append(newBackends, backendConf)
+			}
+			newClusterBackend[subName] = newBackends
+		}
+		applied[clusterName] = newClusterBackend
+	}
+
+	return applied
+}
+
+// applyMode sets admin mode of backends in cluster.
+func (bo backendOverrides) applyMode(clusterName string, bal *bal_gslb.BalanceGslb) {
+	overrides := bo[clusterName]
+	for _, back := range bal.Backends() {
+		mode := backend.ModeNormal
+		if o, ok := overrides[back.GetAddrInfo()]; ok {
+			mode = o.Mode
+		}
+		back.SetMode(mode)
+	}
+}
+
+// reloadBackendOverride applies overrides to backends of given cluster.
+// Note: caller should hold t.lock
+func (t *BalTable) reloadBackendOverride(clusterName string) error {
+	bal, err := t.lookup(clusterName)
+	if err != nil {
+		return err
+	}
+
+	allBackendConf := t.overrides.applyWeight(t.discovery.Expand(t.backendConfs))
+	if backendConf, ok := allBackendConf[clusterName]; ok {
+		if err := bal.BackendReload(backendConf); err != nil {
+			return err
+		}
+	}
+	t.overrides.applyMode(clusterName, bal)
+	return nil
+}
+
+// SetBackendOverride sets override of backend. Existing override of the
+// backend is replaced.
+func (t *BalTable) SetBackendOverride(o BackendOverride) error {
+	if err := BackendOverrideCheck(o); err != nil {
+		return err
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	bal, err := t.lookup(o.Cluster)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, back := range bal.Backends() {
+		if back.GetAddrInfo() == o.Backend {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("no backend %s in cluster %s", o.Backend, o.Cluster)
+	}
+
+	if o.CreateTime.IsZero() {
+		o.CreateTime = time.Now()
+	}
+	if t.overrides[o.Cluster] == nil {
+		t.overrides[o.Cluster] = make(map[string]*BackendOverride)
+	}
+	t.overrides[o.Cluster][o.Backend] = &o
+
+	log.Logger.Info("SetBackendOverride(): cluster[%s] backend[%s] weight[%v] mode[%s] expire[%v]",
+		o.Cluster, o.Backend, o.Weight, o.Mode, o.ExpireTime)
+	return t.reloadBackendOverride(o.Cluster)
+}
+
+// ClearBackendOverride clears override of backend. Overrides of all backends
+// in cluster are cleared if backend is empty. It returns number of overrides
+// cleared.
+func (t *BalTable) ClearBackendOverride(clusterName, backendAddr string) (int, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	overrides := t.overrides[clusterName]
+	num := 0
+	for addr := range overrides {
+		if backendAddr == "" || addr == backendAddr {
+			delete(overrides, addr)
+			num++
+		}
+	}
+	if num == 0 {
+		return 0, nil
+	}
+	if len(overrides) == 0 {
+		delete(t.overrides, clusterName)
+	}
+
+	log.Logger.Info("ClearBackendOverride(): cluster[%s] backend[%s] cleared[%d]",
+		clusterName, backendAddr, num)
+	if _, err := t.lookup(clusterName); err != nil {
+		// cluster has been removed from cluster table
+		return num, nil
+	}
+	return num, t.reloadBackendOverride(clusterName)
+}
+
+// ExpireBackendOverrides clears overrides which are expired.
+func (t *BalTable) ExpireBackendOverrides(now time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for clusterName, overrides := range t.overrides {
+		expired := false
+		for addr, o := range overrides {
+			if o.expired(now) {
+				log.Logger.Info("ExpireBackendOverrides(): cluster[%s] backend[%s] expired",
+					clusterName, addr)
+				delete(overrides, addr)
+				expired = true
+			}
+		}
+		if len(overrides) == 0 {
+			delete(t.overrides, clusterName)
+		}
+		if !expired {
+			continue
+		}
+
+		if _, err := t.lookup(clusterName); err != nil {
+			continue
+		}
+		if err := t.reloadBackendOverride(clusterName); err != nil {
+			log.Logger.Error("ExpireBackendOverrides():err[%s] in reload cluster %s",
+				err.Error(), clusterName)
+		}
+	}
+}
+
+// StartBackendOverrideExpire starts to clear expired overrides periodically.
+func (t *BalTable) StartBackendOverrideExpire() {
+	go func() {
+		ticker := time.NewTicker(overrideCheckInterval)
+		defer ticker.Stop()
+
+		for now := range ticker.C {
+			t.ExpireBackendOverrides(now)
+		}
+	}()
+}
+
+// GetBackendOverrides returns all overrides of backends, sorted by cluster
+// and backend.
+func (t *BalTable) GetBackendOverrides() []BackendOverride {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	list := make([]BackendOverride, 0)
+	for _, overrides := range t.overrides {
+		for _, o := range overrides {
+			list = append(list, *o)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Cluster != list[j].Cluster {
+			return list[i].Cluster < list[j].Cluster
+		}
+		return list[i].Backend < list[j].Backend
+	})
+	return list
+}
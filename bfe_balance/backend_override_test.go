@@ -0,0 +1,164 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bfe_balance
+
+import (
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_balance/backend"
+	"github.com/bfenetworks/bfe/bfe_config/bfe_cluster_conf/cluster_table_conf"
+)
+
+const (
+	overrideCluster = "cluster_c1"
+	overrideBackend = "10.209.52.59:8080"
+)
+
+func prepareOverrideBalTable(t *testing.T) *BalTable {
+	balTable := NewBalTable(nil)
+	if err := balTable.Init("testdata/bal_table/case1/gslb.data",
+		"testdata/bal_table/case1/cluster_table.data"); err != nil {
+		t.Fatalf("Init() err: %s", err)
+	}
+	return balTable
+}
+
+func overrideBackendMode(t *testing.T, balTable *BalTable) string {
+	bal, err := balTable.Lookup(overrideCluster)
+	if err != nil {
+		t.Fatalf("Lookup() err: %s", err)
+	}
+	for _, back := range bal.Backends() {
+		if back.GetAddrInfo() == overrideBackend {
+			return back.Mode()
+		}
+	}
+	t.Fatalf("no backend %s", overrideBackend)
+	return ""
+}
+
+func TestSetBackendOverride(t *testing.T) {
+	balTable := prepareOverrideBalTable(t)
+	weight := 0
+
+	// invalid overrides
+	cases := []BackendOverride{
+		{Cluster: overrideCluster, Backend: overrideBackend},
+		{Cluster: overrideCluster, Backend: overrideBackend, Mode: "unknown"},
+		{Cluster: "cluster_unknown", Backend: overrideBackend, Mode: backend.ModeDraining},
+		{Cluster: overrideCluster, Backend: "10.0.0.1:80", Mode: backend.ModeDraining},
+	}
+	for i, o := range cases {
+		if err := balTable.SetBackendOverride(o); err == nil {
+			t.Errorf("case %d: SetBackendOverride() should return err", i)
+		}
+	}
+
+	o := BackendOverride{
+		Cluster: overrideCluster,
+		Backend: overrideBackend,
+		Weight:  &weight,
+		Mode:    backend.ModeDraining,
+	}
+	if err := balTable.SetBackendOverride(o); err != nil {
+		t.Fatalf("SetBackendOverride() err: %s", err)
+	}
+	if mode := overrideBackendMode(t, balTable); mode != backend.ModeDraining {
+		t.Errorf("mode should be draining, got %q", mode)
+	}
+
+	// override survives reload of cluster table
+	gslbConf, backendConf, err := balTable.BalTableConfLoad("testdata/bal_table/case1/gslb.data",
+		"testdata/bal_table/case1/cluster_table.data")
+	if err != nil {
+		t.Fatalf("BalTableConfLoad() err: %s", err)
+	}
+	if err := balTable.BalTableReload(gslbConf, backendConf); err != nil {
+		t.Fatalf("BalTableReload() err: %s", err)
+	}
+	if mode := overrideBackendMode(t, balTable); mode != backend.ModeDraining {
+		t.Errorf("mode should be draining after reload, got %q", mode)
+	}
+
+	overrides := balTable.GetBackendOverrides()
+	if len(overrides) != 1 || *overrides[0].Weight != 0 || overrides[0].CreateTime.IsZero() {
+		t.Errorf("unexpected overrides: %+v", overrides)
+	}
+
+	// clear override
+	if num, err := balTable.ClearBackendOverride(overrideCluster, ""); err != nil || num != 1 {
+		t.Errorf("ClearBackendOverride() = %d, %v", num, err)
+	}
+	if mode := overrideBackendMode(t, balTable); mode != backend.ModeNormal {
+		t.Errorf("mode should be normal after clear, got %q", mode)
+	}
+	if len(balTable.GetBackendOverrides()) != 0 {
+		t.Errorf("overrides should be cleared")
+	}
+}
+
+func TestExpireBackendOverrides(t *testing.T) {
+	balTable := prepareOverrideBalTable(t)
+	now := time.Now()
+
+	o := BackendOverride{
+		Cluster:    overrideCluster,
+		Backend:    overrideBackend,
+		Mode:       backend.ModeMaintenance,
+		ExpireTime: now.Add(time.Minute),
+	}
+	if err := balTable.SetBackendOverride(o); err != nil {
+		t.Fatalf("SetBackendOverride() err: %s", err)
+	}
+
+	balTable.ExpireBackendOverrides(now)
+	if mode := overrideBackendMode(t, balTable); mode != backend.ModeMaintenance {
+		t.Errorf("mode should be maintenance before expired, got %q", mode)
+	}
+
+	balTable.ExpireBackendOverrides(now.Add(time.Minute))
+	if mode := overrideBackendMode(t, balTable); mode != backend.ModeNormal {
+		t.Errorf("mode should be normal after expired, got %q", mode)
+	}
+	if len(balTable.GetBackendOverrides()) != 0 {
+		t.Errorf("overrides should be expired")
+	}
+}
+
+func TestApplyOverrideWeight(t *testing.T) {
+	name, addr, port, weight := "b1", "10.0.0.1", 80, 10
+	conf := cluster_table_conf.AllClusterBackend{
+		"c1": cluster_table_conf.ClusterBackend{
+			"sub1": cluster_table_conf.SubClusterBackend{
+				{Name: &name, Addr: &addr, Port: &port, Weight: &weight},
+			},
+		},
+	}
+
+	newWeight := 1
+	overrides := backendOverrides{
+		"c1": {"10.0.0.1:80": &BackendOverride{Weight: &newWeight}},
+	}
+	applied := overrides.applyWeight(conf)
+	if *applied["c1"]["sub1"][0].Weight != 1 {
+		t.Errorf("weight should be overridden")
+	}
+	if *conf["c1"]["sub1"][0].Weight != 10 {
+		t.Errorf("origin conf should not be modified")
+	}
+}
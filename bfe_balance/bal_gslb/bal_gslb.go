@@ -45,6 +45,15 @@ const (
 	REQ_CTX_EPP          = "epp_ctx"
 )
 
+// localZone is zone where bfe instance is deployed
+var localZone string
+
+// SetLocalZone sets zone of bfe instance, used for zone aware balance.
+// Note: it should be called before any request is balanced.
+func SetLocalZone(zone string) {
+	localZone = zone
+}
+
 type BalanceGslb struct {
 	lock sync.Mutex
 
@@ -60,6 +69,15 @@ type BalanceGslb struct {
 	hashConf    cluster_conf.HashConf // gslb hash conf
 	BalanceMode string                // balanceMode, WRR or WLC, defined in cluster_conf
 
+	hashLoadFactor int // load factor for bounded load consistent hash, in percent
+
+	subClusterZone     map[string]string // zone of sub clusters
+	zoneAwareThreshold int               // min healthy percent of local zone, 0 means disabled
+
+	panicThreshold int // min healthy percent of sub cluster before entering panic mode
+
+	outlier *bal_backend.OutlierDetector // passive outlier detection for backends
+
 	// EPP related
 	eppClient      epp.EppGrpcClient
 	eppAddrs       []string
@@ -81,6 +99,7 @@ func NewBalanceGslb(name string) *BalanceGslb {
 		SessionSticky: &defaultSessionSticky,
 	}
 	bal.BalanceMode = cluster_conf.BalanceModeWrr
+	bal.outlier = bal_backend.NewOutlierDetector(name)
 
 	return bal
 }
@@ -92,6 +111,22 @@ func (bal *BalanceGslb) SetGslbBasic(gslbBasic cluster_conf.GslbBasicConf) {
 	bal.retryMax = *gslbBasic.RetryMax
 	bal.hashConf = *gslbBasic.HashConf
 	bal.BalanceMode = *gslbBasic.BalanceMode
+	if gslbBasic.HashLoadFactor != nil {
+		bal.hashLoadFactor = *gslbBasic.HashLoadFactor
+	}
+	if gslbBasic.PanicThreshold != nil {
+		bal.panicThreshold = *gslbBasic.PanicThreshold
+	}
+	for _, sub := range bal.subClusters {
+		sub.setHashLoadFactor(bal.hashLoadFactor)
+		sub.setPanicThreshold(bal.panicThreshold)
+	}
+	if gslbBasic.SubClusterZone != nil {
+		bal.subClusterZone = *gslbBasic.SubClusterZone
+	}
+	if gslbBasic.ZoneAwareThreshold != nil {
+		bal.zoneAwareThreshold = *gslbBasic.ZoneAwareThreshold
+	}
 
 	bal.lock.Unlock()
 	// close EPP client if any
@@ -120,6 +155,38 @@ func (bal *BalanceGslb) SetSlowStart(backendConf cluster_conf.BackendBasic) {
 	bal.lock.Unlock()
 }
 
+// SetOutlierDetection sets conf of passive outlier detection.
+func (bal *BalanceGslb) SetOutlierDetection(conf *cluster_conf.OutlierDetectionConf) {
+	bal.outlier.SetConf(conf)
+}
+
+// OnOutlierResult records result of request to backend for outlier detection.
+func (bal *BalanceGslb) OnOutlierResult(backend *bal_backend.BfeBackend, result int) {
+	bal.outlier.OnResult(backend, result)
+}
+
+// updateOutlierBackends updates backends for outlier detection.
+// Note: caller should hold bal.lock
+func (bal *BalanceGslb) updateOutlierBackends() {
+	var backends []*bal_backend.BfeBackend
+	for _, sub := range bal.subClusters {
+		backends = append(backends, sub.backendList()...)
+	}
+	bal.outlier.SetBackends(backends)
+}
+
+// Backends returns all backends of cluster.
+func (bal *BalanceGslb) Backends() []*bal_backend.BfeBackend {
+	bal.lock.Lock()
+	defer bal.lock.Unlock()
+
+	var backends []*bal_backend.BfeBackend
+	for _, sub := range bal.subClusters {
+		backends = append(backends, sub.backendList()...)
+	}
+	return backends
+}
+
 // initEPP initializes or refreshes EPP client with given addresses.
 func (bal *BalanceGslb) initEPP(addrs []string) error {
 	if len(addrs) == 0 {
@@ -322,6 +389,7 @@ func (bal *BalanceGslb) BackendInit(clusterBackend cluster_table_conf.ClusterBac
 			subCluster.init(backend)
 		}
 	}
+	bal.updateOutlierBackends()
 
 	bal.lock.Unlock()
 	return nil
@@ -369,6 +437,8 @@ func (bal *BalanceGslb) Reload(gslbConf gslb_conf.GslbClusterConf) error {
 			// create new sub cluster
 			sub := newSubCluster(subName)
 			sub.weight = weight
+			sub.setHashLoadFactor(bal.hashLoadFactor)
+			sub.setPanicThreshold(bal.panicThreshold)
 
 			// add sub cluster to subListNew
 			subListNew = append(subListNew, sub)
@@ -408,6 +478,7 @@ func (bal *BalanceGslb) Reload(gslbConf gslb_conf.GslbClusterConf) error {
 
 	// update gslb.subClusters
 	bal.subClusters = subListNew
+	bal.updateOutlierBackends()
 
 	return nil
 }
@@ -420,6 +491,7 @@ func (bal *BalanceGslb) BackendReload(clusterBackend cluster_table_conf.ClusterB
 			subCluster.update(backend)
 		}
 	}
+	bal.updateOutlierBackends()
 
 	bal.lock.Unlock()
 
@@ -436,6 +508,8 @@ func (bal *BalanceGslb) Release() {
 	}
 
 	bal.lock.Unlock()
+
+	bal.outlier.Stop()
 }
 
 // getHashKey returns hash key according hash strategy
@@ -507,26 +581,54 @@ func (bal *BalanceGslb) BalanceEpp(req *bfe_basic.Request) (*bal_backend.BfeBack
 	// If BalanceMode == EPP, try to get backend from EPP service first
 	addrinfo, eppClient, err := bal.chooseBackendFromEPP(req)
 	if err == nil && addrinfo != "" {
-		req.SetContext(REQ_CTX_EPP, eppClient)
 		// try to find backend in subclusters
 		for _, sub := range bal.subClusters {
 			if sub == nil {
 				continue
 			}
 			bk, berr := sub.backends.LookUpBackend(addrinfo)
-			if berr == nil && bk != nil {
+			// Note: EPP decides backend for new requests, so draining
+			// backend is refused
+			if berr == nil && bk != nil && bk.InService() {
+				req.SetContext(REQ_CTX_EPP, eppClient)
 				req.Backend.SubclusterName = sub.Name
 				return bk, nil
 			}
 		}
+
+		// known backend which is unavailable, draining or in maintenance
+		if bk := bal.lookupBackend(addrinfo); bk != nil {
+			eppClient.Close()
+			log.Logger.Info("EPP returned addr %s not available (mode[%s])", addrinfo, bk.Mode())
+			return nil, fmt.Errorf("EPP backend %s not available", addrinfo)
+		}
+
 		// not found: log and make a temporary backend
 		log.Logger.Info("EPP returned addr %s not found in local backends", addrinfo)
+		req.SetContext(REQ_CTX_EPP, eppClient)
 		backend = bal_backend.NewBfeBackendByAddrinfo("EPP_temp", addrinfo, addrinfo)
 		return backend, nil
 	}
 	return nil, fmt.Errorf("EPP no decision")
 }
 
+// lookupBackend finds backend with given addrInfo(ip:port) in all sub
+// clusters, no matter whether it is available.
+// Note: caller should hold bal.lock
+func (bal *BalanceGslb) lookupBackend(addrInfo string) *bal_backend.BfeBackend {
+	for _, sub := range bal.subClusters {
+		if sub == nil {
+			continue
+		}
+		for _, bk := range sub.backendList() {
+			if bk.GetAddrInfo() == addrInfo {
+				return bk
+			}
+		}
+	}
+	return nil
+}
+
 // Balance selects a backend for given request.
 func (bal *BalanceGslb) Balance(req *bfe_basic.Request) (*bal_backend.BfeBackend, error) {
 	var backend *bal_backend.BfeBackend
@@ -556,13 +658,21 @@ func (bal *BalanceGslb) Balance(req *bfe_basic.Request) (*bal_backend.BfeBackend
 	switch bal.BalanceMode {
 	case cluster_conf.BalanceModeWlc:
 		balAlgor = bal_slb.WlcSmooth
+	case cluster_conf.BalanceModeChash:
+		balAlgor = bal_slb.ChashRing
+	case cluster_conf.BalanceModeMaglev:
+		balAlgor = bal_slb.ChashMaglev
+	case cluster_conf.BalanceModePewma:
+		balAlgor = bal_slb.PewmaP2C
 	default:
 		balAlgor = bal_slb.WrrSmooth
 	}
 
 	// If use sticky session feature, bfe bind a user's session to a specific backend.
 	// All requests from the user during the session are sent to the same backend.
-	if *bal.hashConf.SessionSticky {
+	// Note: consistent hash is already sticky, and is more stable than WrrSticky.
+	if *bal.hashConf.SessionSticky && balAlgor != bal_slb.ChashRing &&
+		balAlgor != bal_slb.ChashMaglev {
 		balAlgor = bal_slb.WrrSticky
 	}
 
@@ -657,6 +767,10 @@ func (bal *BalanceGslb) subClusterBalance(value []byte) (*SubCluster, error) {
 		return bal.subClusters[bal.avail], nil
 	}
 
+	if subCluster, ok := bal.zoneAwareBalance(value); ok {
+		return subCluster, nil
+	}
+
 	w = bal_slb.GetHash(value, uint(bal.totalWeight))
 
 	for i := 0; i < len(bal.subClusters); i++ {
@@ -674,6 +788,90 @@ func (bal *BalanceGslb) subClusterBalance(value []byte) (*SubCluster, error) {
 	return subCluster, nil
 }
 
+// zoneAwareBalance selects one sub cluster, preferring sub clusters in local
+// zone. ok is false if zone aware balance is not applicable.
+//
+// Let h be healthy percent of backends in local zone and t be the threshold:
+//   - h >= t: all traffic goes to local zone
+//   - h < t: h/t of traffic goes to local zone, and the rest spills to other
+//     zones (by weight of sub clusters)
+func (bal *BalanceGslb) zoneAwareBalance(value []byte) (*SubCluster, bool) {
+	if bal.zoneAwareThreshold <= 0 || localZone == "" {
+		return nil, false
+	}
+
+	localWeight, remoteWeight := 0, 0
+	availWeight, allWeight := 0, 0
+	for _, sub := range bal.subClusters {
+		if sub.weight <= 0 {
+			continue
+		}
+		if !bal.inLocalZone(sub) {
+			remoteWeight += sub.weight
+			continue
+		}
+		localWeight += sub.weight
+		avail, total := sub.availWeight()
+		availWeight += avail
+		allWeight += total
+	}
+	if localWeight == 0 {
+		return nil, false
+	}
+
+	// percent of traffic stays in local zone
+	localPercent := 100
+	if remoteWeight > 0 {
+		healthyPercent := 0
+		if allWeight > 0 {
+			healthyPercent = availWeight * 100 / allWeight
+		}
+		if healthyPercent < bal.zoneAwareThreshold {
+			localPercent = healthyPercent * 100 / bal.zoneAwareThreshold
+		}
+	}
+
+	// weight of local sub cluster is scaled by localPercent*remoteWeight, and
+	// weight of remote sub cluster is scaled by (100-localPercent)*localWeight
+	total := 100 * localWeight
+	if remoteWeight > 0 {
+		total *= remoteWeight
+	}
+	w := bal_slb.GetHash(value, uint(total))
+
+	var subCluster *SubCluster
+	for _, sub := range bal.subClusters {
+		if sub.weight <= 0 {
+			continue
+		}
+		subCluster = sub
+		if bal.inLocalZone(sub) {
+			if remoteWeight > 0 {
+				w -= sub.weight * localPercent * remoteWeight
+			} else {
+				w -= sub.weight * 100
+			}
+		} else {
+			w -= sub.weight * (100 - localPercent) * localWeight
+		}
+		// got it
+		if w < 0 {
+			break
+		}
+	}
+
+	if !bal.inLocalZone(subCluster) {
+		state.ZoneSpill.Inc(1)
+	}
+	return subCluster, true
+}
+
+// inLocalZone checks whether sub cluster is in the same zone as bfe instance.
+func (bal *BalanceGslb) inLocalZone(sub *SubCluster) bool {
+	zone, ok := bal.subClusterZone[sub.Name]
+	return ok && zone == localZone
+}
+
 // randomSelectExclude randomly selects a sub cluster, exclude exclude_sub_cluster, gslb blackhole.
 func (bal *BalanceGslb) randomSelectExclude(excludeCluster *SubCluster) (*SubCluster, error) {
 	var i int
@@ -761,6 +959,8 @@ type BalErrState struct {
 	ErrBkNoBackend         *metrics.Counter
 	ErrBkRetryTooMany      *metrics.Counter
 	ErrGslbBlackhole       *metrics.Counter
+	ZoneSpill              *metrics.Counter // requests spilled from local zone to other zones
+	BalPanicMode           *metrics.Counter // requests balanced in panic mode, disregarding health status
 }
 
 var state BalErrState
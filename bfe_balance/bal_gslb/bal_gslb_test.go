@@ -15,12 +15,15 @@
 package bal_gslb
 
 import (
+	"fmt"
 	"io/ioutil"
 	"net"
 	"testing"
 )
 
 import (
+	"github.com/bfenetworks/go-lib/web-monitor/metrics"
+
 	"github.com/bfenetworks/bfe/bfe_basic"
 	"github.com/bfenetworks/bfe/bfe_config/bfe_cluster_conf/cluster_conf"
 	"github.com/bfenetworks/bfe/bfe_config/bfe_cluster_conf/cluster_table_conf"
@@ -195,3 +198,82 @@ func TestSlowStart(t *testing.T) {
 	t.Logf("%+v", bal.subClusters[1])
 	t.Logf("%+v", bal.subClusters[2])
 }
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestZoneAwareBalance(t *testing.T) {
+	var c cluster_table_conf.ClusterBackend
+	loadJson("testdata/cluster1", &c)
+	g := gslb_conf.GslbClusterConf{
+		"light.example.dx": 50,
+		"light.example.wt": 50,
+		"GSLB_BLACKHOLE":   0,
+	}
+	gb := cluster_conf.GslbBasicConf{
+		SubClusterZone: &map[string]string{
+			"light.example.dx": "zone_a",
+			"light.example.wt": "zone_b",
+		},
+		ZoneAwareThreshold: intPtr(80),
+	}
+	if err := cluster_conf.GslbBasicConfCheck(&gb); err != nil {
+		t.Fatalf("GslbBasicConfCheck err %s", err)
+	}
+
+	bal := NewBalanceGslb("cluster_zone")
+	if err := bal.Init(g); err != nil {
+		t.Fatalf("init error %s", err)
+	}
+	bal.BackendReload(c)
+	bal.SetGslbBasic(gb)
+
+	SetLocalZone("zone_a")
+	defer SetLocalZone("")
+	state.ZoneSpill = new(metrics.Counter)
+
+	// count requests balanced to local zone
+	localCount := func() int {
+		count := 0
+		for i := 0; i < 10000; i++ {
+			sub, err := bal.subClusterBalance([]byte(fmt.Sprintf("key%d", i)))
+			if err != nil {
+				t.Fatalf("subClusterBalance err %s", err)
+			}
+			if sub.Name == "light.example.dx" {
+				count++
+			}
+		}
+		return count
+	}
+
+	// local zone is healthy, all traffic stays in local zone
+	if n := localCount(); n != 10000 {
+		t.Errorf("all traffic should stay in local zone, got %d", n)
+	}
+
+	// 50% healthy in local zone, about 50/80 of traffic stays in local zone
+	var local *SubCluster
+	for _, sub := range bal.subClusters {
+		if sub.Name == "light.example.dx" {
+			local = sub
+		}
+	}
+	local.backendList()[0].SetAvail(false)
+	if n := localCount(); n < 5800 || n > 6700 {
+		t.Errorf("about 62%% traffic should stay in local zone, got %d", n)
+	}
+
+	// no healthy backend in local zone, all traffic spills
+	local.backendList()[1].SetAvail(false)
+	if n := localCount(); n != 0 {
+		t.Errorf("all traffic should spill to other zone, got %d", n)
+	}
+
+	// zone aware balance is disabled
+	bal.zoneAwareThreshold = 0
+	if n := localCount(); n < 4500 || n > 5500 {
+		t.Errorf("about 50%% traffic should go to local zone, got %d", n)
+	}
+}
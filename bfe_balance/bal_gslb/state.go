@@ -14,15 +14,21 @@
 
 package bal_gslb
 
+import (
+	"github.com/bfenetworks/bfe/bfe_balance/backend"
+)
+
 // SubClusterState is state of sub-cluster.
 type SubClusterState struct {
-	BackendNum int // number of backends
+	BackendNum int  // number of backends
+	PanicMode  bool // in panic mode or not
 }
 
 // GslbState is state of cluster.
 type GslbState struct {
 	SubClusters map[string]*SubClusterState // state of sub-cluster
 	BackendNum  int                         // number of cluster backend
+	Outlier     *backend.OutlierState       // state of outlier detection
 }
 
 func State(bal *BalanceGslb) *GslbState {
@@ -34,6 +40,7 @@ func State(bal *BalanceGslb) *GslbState {
 	for _, sub := range bal.subClusters {
 		subState := &SubClusterState{
 			BackendNum: sub.Len(),
+			PanicMode:  sub.backends.PanicMode(),
 		}
 
 		gslbState.SubClusters[sub.Name] = subState
@@ -42,5 +49,7 @@ func State(bal *BalanceGslb) *GslbState {
 
 	bal.lock.Unlock()
 
+	gslbState.Outlier = bal.outlier.State()
+
 	return gslbState
 }
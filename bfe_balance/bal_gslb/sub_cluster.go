@@ -82,13 +82,35 @@ func (sub *SubCluster) balance(algor int, key []byte) (*backend.BfeBackend, erro
 	}
 
 	// balance from sub-cluster
-	return sub.backends.Balance(algor, key)
+	backend, err := sub.backends.Balance(algor, key)
+	if err == nil && sub.backends.PanicMode() {
+		state.BalPanicMode.Inc(1)
+	}
+	return backend, err
 }
 
 func (sub *SubCluster) setSlowStart(slowStartTime int) {
 	sub.backends.SetSlowStart(slowStartTime)
 }
 
+// backendList returns all backends of sub-cluster.
+func (sub *SubCluster) backendList() []*backend.BfeBackend {
+	return sub.backends.Backends()
+}
+
+// availWeight returns sum weight of available backends and all backends.
+func (sub *SubCluster) availWeight() (int, int) {
+	return sub.backends.AvailWeight()
+}
+
+func (sub *SubCluster) setHashLoadFactor(factor int) {
+	sub.backends.SetHashLoadFactor(factor)
+}
+
+func (sub *SubCluster) setPanicThreshold(threshold int) {
+	sub.backends.SetPanicThreshold(threshold)
+}
+
 // SubClusterList is a list of sub-cluster.
 type SubClusterList []*SubCluster
 
@@ -37,6 +37,7 @@ type BackendRR struct {
 	backend     *backend.BfeBackend // point to BfeBackend
 	inSlowStart bool                // indicate if in slow-start phase
 	weightSS    WeightSS            // slow_start related parameters
+	panic       bool                // sub cluster in panic mode, ignore health status
 }
 
 func NewBackendRR() *BackendRR {
@@ -57,6 +58,16 @@ func (backRR *BackendRR) Init(subClusterName string, conf *cluster_table_conf.Ba
 	back.Init(subClusterName, conf)
 }
 
+// avail checks whether backend is available for balance. Health status of
+// backend is ignored in panic mode, but draining or maintenance backend is
+// never selected.
+func (backRR *BackendRR) avail() bool {
+	if !backRR.backend.InService() {
+		return false
+	}
+	return backRR.panic || backRR.backend.Avail()
+}
+
 func (backRR *BackendRR) UpdateWeight(weight int) {
 	backRR.weight = weight * 100
 
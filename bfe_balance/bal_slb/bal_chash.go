@@ -0,0 +1,405 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// consistent hash balance
+//
+// Two lookup structures are supported:
+//   - ring hash: each backend owns a number of virtual nodes (proportional to
+//     its weight) on a 64-bit hash ring; a key is served by the first virtual
+//     node clockwise from hash(key). Hash of virtual nodes are cached, so only
+//     virtual nodes of new backends are calculated on update.
+//   - maglev: a fixed size lookup table is filled by backend preference
+//     permutations (see "Maglev: A Fast and Reliable Software Network Load
+//     Balancer"); a key is served by table[hash(key) % size].
+//
+// Both tables are built over all backends with weight > 0, regardless of
+// their health status. Unavailable backends are skipped at lookup time, so a
+// health flip only remaps keys owned by the flipped backend.
+//
+// Bounded load (see "Consistent Hashing with Bounded Loads"): if load factor
+// c (in percent) is configured, a backend is eligible only if its active
+// connections are less than ceil(c * (totalConns + 1) * weight / totalWeight).
+// Otherwise the key overflows to the next backend in preference order.
+
+package bal_slb
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+)
+
+import (
+	"github.com/spaolacci/murmur3"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_balance/backend"
+)
+
+const (
+	RingVnodesPerWeight = 64      // number of virtual nodes per weight unit of backend
+	RingMaxSize         = 1 << 20 // max number of virtual nodes in hash ring
+	MaglevTableSize     = 65537   // size of maglev lookup table, should be a prime
+)
+
+// hashTable is lookup structure for consistent hash balance.
+type hashTable interface {
+	// algor returns balance algorithm of the table
+	algor() int
+
+	// build (re)builds the table with given backends
+	build(backends BackendList)
+
+	// walk calls fn for each backend in preference order for the hash,
+	// until fn returns true or all backends have been visited.
+	walk(hash uint64, fn func(*BackendRR) bool)
+}
+
+func newHashTable(algor int) hashTable {
+	if algor == ChashMaglev {
+		return newMaglevTable()
+	}
+	return newHashRing()
+}
+
+// hashBackends returns sorted backends which take part in consistent hash.
+func hashBackends(backends BackendList) BackendList {
+	backs := make(BackendList, 0, len(backends))
+	for _, backendRR := range backends {
+		if backendRR.weight > 0 {
+			backs = append(backs, backendRR)
+		}
+	}
+
+	// keep same order on all bfe instances
+	sort.Sort(BackendListSorter{backs})
+	return backs
+}
+
+// hashKey returns 64-bit hash value for given key.
+func hashKey(key []byte) uint64 {
+	if key == nil {
+		return rand.Uint64()
+	}
+	return murmur3.Sum64(key)
+}
+
+// rehash mixes hash with attempt to generate a new hash value (splitmix64).
+func rehash(hash uint64, attempt int) uint64 {
+	z := hash + uint64(attempt)*0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+type ringEntry struct {
+	hash  uint64 // hash of virtual node
+	index int    // index of backend in ring.backends
+}
+
+type hashRing struct {
+	backends BackendList
+	entries  []ringEntry
+	points   map[string][]uint64 // cached hash of virtual nodes for each backend
+}
+
+func newHashRing() *hashRing {
+	ring := new(hashRing)
+	ring.points = make(map[string][]uint64)
+	return ring
+}
+
+func (ring *hashRing) algor() int {
+	return ChashRing
+}
+
+// vnodePoints returns hash of the first num virtual nodes for backend.
+// Hash of virtual nodes are cached, so only new virtual nodes are calculated
+// when backend list is updated.
+func (ring *hashRing) vnodePoints(addrInfo string, num int) []uint64 {
+	points := ring.points[addrInfo]
+	if len(points) >= num {
+		return points[:num]
+	}
+
+	buf := make([]byte, 0, len(addrInfo)+8)
+	for i := len(points); i < num; i++ {
+		buf = append(buf[:0], addrInfo...)
+		buf = append(buf, '_')
+		buf = strconv.AppendInt(buf, int64(i), 10)
+		points = append(points, murmur3.Sum64(buf))
+	}
+	ring.points[addrInfo] = points
+	return points
+}
+
+// ringVnodeNum returns number of virtual nodes for backend with weight.
+func ringVnodeNum(weight int) int {
+	// weight of BackendRR is scaled up 100 times from conf file
+	num := weight * RingVnodesPerWeight / 100
+	if num < 1 {
+		num = 1
+	}
+	return num
+}
+
+func (ring *hashRing) build(backends BackendList) {
+	backs := hashBackends(backends)
+
+	// number of virtual nodes only depends on weight of backend itself, so
+	// that adding/removing a backend never moves keys between other backends
+	total := 0
+	for _, backendRR := range backs {
+		total += ringVnodeNum(backendRR.weight)
+	}
+	scale := 1.0
+	if total > RingMaxSize {
+		scale = float64(RingMaxSize) / float64(total)
+	}
+
+	entries := make([]ringEntry, 0, int(float64(total)*scale)+len(backs))
+	inUse := make(map[string]bool, len(backs))
+	for index, backendRR := range backs {
+		num := int(math.Ceil(float64(ringVnodeNum(backendRR.weight)) * scale))
+		addrInfo := backendRR.backend.AddrInfo
+		for _, hash := range ring.vnodePoints(addrInfo, num) {
+			entries = append(entries, ringEntry{hash, index})
+		}
+		inUse[addrInfo] = true
+	}
+
+	// drop cached virtual nodes of removed backends
+	for addrInfo := range ring.points {
+		if !inUse[addrInfo] {
+			delete(ring.points, addrInfo)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].hash != entries[j].hash {
+			return entries[i].hash < entries[j].hash
+		}
+		return entries[i].index < entries[j].index
+	})
+
+	ring.backends = backs
+	ring.entries = entries
+}
+
+func (ring *hashRing) walk(hash uint64, fn func(*BackendRR) bool) {
+	num := len(ring.entries)
+	if num == 0 {
+		return
+	}
+
+	start := sort.Search(num, func(i int) bool {
+		return ring.entries[i].hash >= hash
+	})
+
+	visited := make([]bool, len(ring.backends))
+	left := len(ring.backends)
+	for i := 0; i < num && left > 0; i++ {
+		index := ring.entries[(start+i)%num].index
+		if visited[index] {
+			continue
+		}
+		visited[index] = true
+		left--
+
+		if fn(ring.backends[index]) {
+			return
+		}
+	}
+}
+
+type maglevTable struct {
+	backends BackendList
+	table    []int32 // index of backend in maglev.backends
+}
+
+func newMaglevTable() *maglevTable {
+	return new(maglevTable)
+}
+
+func (m *maglevTable) algor() int {
+	return ChashMaglev
+}
+
+func (m *maglevTable) build(backends BackendList) {
+	backs := hashBackends(backends)
+	m.backends = backs
+	if len(backs) == 0 {
+		m.table = nil
+		return
+	}
+
+	size := uint64(MaglevTableSize)
+	offsets := make([]uint64, len(backs))
+	skips := make([]uint64, len(backs))
+	nexts := make([]uint64, len(backs))
+	targets := make([]int, len(backs))
+
+	maxWeight := 0
+	for i, backendRR := range backs {
+		h1, h2 := murmur3.Sum128([]byte(backendRR.backend.AddrInfo))
+		offsets[i] = h1 % size
+		skips[i] = h2%(size-1) + 1
+		if backendRR.weight > maxWeight {
+			maxWeight = backendRR.weight
+		}
+	}
+	for i := range targets {
+		targets[i] = maxWeight
+	}
+
+	table := make([]int32, size)
+	for i := range table {
+		table[i] = -1
+	}
+
+	// fill table by preference of each backend. In each iteration, a backend
+	// with max weight fills one slot, while a backend with 1/n of max weight
+	// fills one slot per n iterations.
+	filled := uint64(0)
+	for iteration := 1; filled < size; iteration++ {
+		for i, backendRR := range backs {
+			if filled >= size {
+				break
+			}
+			if iteration*backendRR.weight < targets[i] {
+				continue
+			}
+			targets[i] += maxWeight
+
+			c := (offsets[i] + nexts[i]*skips[i]) % size
+			for table[c] >= 0 {
+				nexts[i]++
+				c = (offsets[i] + nexts[i]*skips[i]) % size
+			}
+			table[c] = int32(i)
+			nexts[i]++
+			filled++
+		}
+	}
+
+	m.table = table
+}
+
+func (m *maglevTable) walk(hash uint64, fn func(*BackendRR) bool) {
+	if len(m.table) == 0 {
+		return
+	}
+
+	// probe table with rehashed key, then fall back to linear scan
+	visited := make([]bool, len(m.backends))
+	left := len(m.backends)
+	for attempt := 0; attempt < 2*len(m.backends) && left > 0; attempt++ {
+		h := hash
+		if attempt > 0 {
+			h = rehash(hash, attempt)
+		}
+		index := m.table[h%uint64(len(m.table))]
+		if visited[index] {
+			continue
+		}
+		visited[index] = true
+		left--
+
+		if fn(m.backends[index]) {
+			return
+		}
+	}
+
+	for index, backendRR := range m.backends {
+		if visited[index] {
+			continue
+		}
+		if fn(backendRR) {
+			return
+		}
+	}
+}
+
+// SetHashLoadFactor sets load factor for bounded load consistent hash.
+func (brr *BalanceRR) SetHashLoadFactor(factor int) {
+	brr.Lock()
+	brr.hashLoadFactor = factor
+	brr.Unlock()
+}
+
+// ensureHashTableUnlocked returns hash table for algor, build it if necessary.
+func (brr *BalanceRR) ensureHashTableUnlocked(algor int) hashTable {
+	if brr.hashTable == nil || brr.hashTable.algor() != algor {
+		brr.hashTable = newHashTable(algor)
+		brr.hashTable.build(brr.backends)
+	}
+	return brr.hashTable
+}
+
+func (brr *BalanceRR) chashBalance(algor int, key []byte) (*backend.BfeBackend, error) {
+	brr.Lock()
+	defer brr.Unlock()
+
+	table := brr.ensureHashTableUnlocked(algor)
+
+	// prepare for bounded load
+	factor := brr.hashLoadFactor
+	totalConns, totalWeight := 0, 0
+	if factor > 0 {
+		for _, backendRR := range brr.backends {
+			if backendRR.avail() && backendRR.weight > 0 {
+				totalConns += backendRR.backend.ConnNum()
+				totalWeight += backendRR.weight
+			}
+		}
+	}
+
+	var first, best *BackendRR
+	table.walk(hashKey(key), func(backendRR *BackendRR) bool {
+		if !backendRR.avail() || backendRR.weight <= 0 {
+			return false
+		}
+		if first == nil {
+			first = backendRR
+		}
+		if factor <= 0 || backendRR.backend.ConnNum() < hashCapacity(factor,
+			totalConns, backendRR.weight, totalWeight) {
+			best = backendRR
+			return true
+		}
+		return false
+	})
+
+	if best == nil {
+		// all backends are overloaded, select the preferred one
+		best = first
+	}
+	if best == nil {
+		return nil, fmt.Errorf("rr_bal:all backend is down")
+	}
+
+	return best.backend, nil
+}
+
+// hashCapacity returns max connections of backend for bounded load.
+func hashCapacity(factor, totalConns, weight, totalWeight int) int {
+	if totalWeight <= 0 {
+		return 0
+	}
+	capacity := float64(factor) / 100 * float64(totalConns+1) * float64(weight) / float64(totalWeight)
+	return int(math.Ceil(capacity))
+}
@@ -0,0 +1,178 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bal_slb
+
+import (
+	"fmt"
+	"testing"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_config/bfe_cluster_conf/cluster_table_conf"
+)
+
+func prepareBalanceRRHash(num int) *BalanceRR {
+	rr := NewBalanceRR("sub")
+	for i := 0; i < num; i++ {
+		b := populateBackend(fmt.Sprintf("b%d", i), "10.0.0.1", 8000+i, true)
+		rr.backends = append(rr.backends, &BackendRR{weight: 100, current: 100, backend: b})
+	}
+	return rr
+}
+
+func balanceKeys(t *testing.T, rr *BalanceRR, algor int, num int) map[string]string {
+	result := make(map[string]string)
+	for i := 0; i < num; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		b, err := rr.Balance(algor, []byte(key))
+		if err != nil {
+			t.Fatalf("Balance() error: %v", err)
+		}
+		result[key] = b.Name
+	}
+	return result
+}
+
+func TestChashBalanceStable(t *testing.T) {
+	for _, algor := range []int{ChashRing, ChashMaglev} {
+		rr := prepareBalanceRRHash(10)
+		before := balanceKeys(t, rr, algor, 10000)
+
+		// same key always goes to same backend
+		again := balanceKeys(t, rr, algor, 10000)
+		for key, name := range before {
+			if again[key] != name {
+				t.Fatalf("algor %d: key %s moved from %s to %s", algor, key, name, again[key])
+			}
+		}
+
+		// health flip only remaps keys of flipped backend
+		rr.backends[3].backend.SetAvail(false)
+		after := balanceKeys(t, rr, algor, 10000)
+		for key, name := range before {
+			if name != "b3" && after[key] != name {
+				t.Errorf("algor %d: key %s moved from %s to %s", algor, key, name, after[key])
+			}
+			if after[key] == "b3" {
+				t.Errorf("algor %d: key %s should not go to unavailable backend", algor, key)
+			}
+		}
+	}
+}
+
+func TestChashBalanceUpdate(t *testing.T) {
+	for _, algor := range []int{ChashRing, ChashMaglev} {
+		var conf cluster_table_conf.SubClusterBackend
+		for i := 0; i < 10; i++ {
+			name := fmt.Sprintf("b%d", i)
+			addr := "10.0.0.1"
+			port := 8000 + i
+			weight := 1
+			conf = append(conf, &cluster_table_conf.BackendConf{
+				Name: &name, Addr: &addr, Port: &port, Weight: &weight,
+			})
+		}
+
+		rr := NewBalanceRR("sub")
+		rr.Init(conf)
+		before := balanceKeys(t, rr, algor, 10000)
+
+		// remove one backend
+		rr.Update(conf[:9])
+		after := balanceKeys(t, rr, algor, 10000)
+
+		moved, unexpected := 0, 0
+		for key, name := range before {
+			if after[key] != name {
+				moved++
+				if name != "b9" {
+					unexpected++
+				}
+			}
+		}
+		// about 1/10 of keys are expected to be moved
+		if moved == 0 || moved > 2000 {
+			t.Errorf("algor %d: unexpected moved keys: %d", algor, moved)
+		}
+		// ring hash has minimal disruption, maglev has near minimal disruption
+		if (algor == ChashRing && unexpected != 0) || unexpected > 500 {
+			t.Errorf("algor %d: keys of other backends moved: %d", algor, unexpected)
+		}
+		rr.Release()
+	}
+}
+
+func TestChashBalanceWeight(t *testing.T) {
+	for _, algor := range []int{ChashRing, ChashMaglev} {
+		rr := prepareBalanceRRHash(2)
+		rr.backends[0].weight = 3000
+		rr.backends[1].weight = 1000
+
+		count := make(map[string]int)
+		for _, name := range balanceKeys(t, rr, algor, 40000) {
+			count[name]++
+		}
+
+		// expect about 3:1
+		ratio := float64(count["b0"]) / float64(count["b1"])
+		if ratio < 2.5 || ratio > 3.5 {
+			t.Errorf("algor %d: unexpected ratio %f, count %v", algor, ratio, count)
+		}
+	}
+}
+
+func TestChashBalanceBoundedLoad(t *testing.T) {
+	for _, algor := range []int{ChashRing, ChashMaglev} {
+		rr := prepareBalanceRRHash(4)
+		rr.SetHashLoadFactor(125)
+
+		key := []byte("hot-key")
+		first, err := rr.Balance(algor, key)
+		if err != nil {
+			t.Fatalf("Balance() error: %v", err)
+		}
+
+		// overload preferred backend, requests should overflow to another one
+		for i := 0; i < 10; i++ {
+			first.IncConnNum()
+		}
+		next, err := rr.Balance(algor, key)
+		if err != nil {
+			t.Fatalf("Balance() error: %v", err)
+		}
+		if next == first {
+			t.Errorf("algor %d: request should overflow from %s", algor, first.Name)
+		}
+
+		// unbounded, always the preferred backend
+		rr.SetHashLoadFactor(0)
+		next, _ = rr.Balance(algor, key)
+		if next != first {
+			t.Errorf("algor %d: request should go to %s, not %s", algor, first.Name, next.Name)
+		}
+	}
+}
+
+func TestChashBalanceAllDown(t *testing.T) {
+	for _, algor := range []int{ChashRing, ChashMaglev} {
+		rr := prepareBalanceRRHash(3)
+		for _, b := range rr.backends {
+			b.backend.SetAvail(false)
+		}
+		if _, err := rr.Balance(algor, []byte("key")); err == nil {
+			t.Errorf("algor %d: should return error when all backends are down", algor)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// latency-aware balance using peak-ewma and power of two choices
+//
+// Algorithm:
+//   1. randomly pick two distinct available backends (by weight),
+//   2. select the one with lower peak-ewma load divided by its weight.
+
+package bal_slb
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_balance/backend"
+)
+
+func (brr *BalanceRR) pewmaBalance() (*backend.BfeBackend, error) {
+	brr.Lock()
+	defer brr.Unlock()
+
+	candidates := make(BackendList, 0, len(brr.backends))
+	totalWeight := 0
+	for _, backendRR := range brr.backends {
+		if backendRR.avail() && backendRR.weight > 0 {
+			candidates = append(candidates, backendRR)
+			totalWeight += backendRR.weight
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("rr_bal:all backend is down")
+	}
+
+	if len(candidates) == 1 {
+		return candidates[0].backend, nil
+	}
+
+	// pick two distinct backends
+	first := weightedRandomSelect(candidates, totalWeight, -1)
+	second := weightedRandomSelect(candidates, totalWeight-candidates[first].weight, first)
+
+	a, b := candidates[first], candidates[second]
+	if pewmaLoad(b) < pewmaLoad(a) {
+		return b.backend, nil
+	}
+	return a.backend, nil
+}
+
+// weightedRandomSelect returns index of a backend randomly selected by
+// weight, excluding backend with index exclude.
+func weightedRandomSelect(backs BackendList, totalWeight int, exclude int) int {
+	if totalWeight <= 0 {
+		return 0
+	}
+
+	value := rand.Intn(totalWeight)
+	for i, backendRR := range backs {
+		if i == exclude {
+			continue
+		}
+		value -= backendRR.weight
+		if value < 0 {
+			return i
+		}
+	}
+
+	/* never come here */
+	return 0
+}
+
+// pewmaLoad returns peak-ewma load per weight unit of backend.
+func pewmaLoad(backendRR *BackendRR) float64 {
+	return backendRR.backend.PeakEwmaLoad() / float64(backendRR.weight)
+}
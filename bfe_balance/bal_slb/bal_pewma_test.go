@@ -0,0 +1,70 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bal_slb
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPewmaBalance(t *testing.T) {
+	rr := prepareBalanceRRHash(2)
+	slow, fast := rr.backends[0].backend, rr.backends[1].backend
+	slow.OnResponseTime(500 * time.Millisecond)
+	fast.OnResponseTime(10 * time.Millisecond)
+
+	// with two backends, power of two choices always compares both
+	for i := 0; i < 100; i++ {
+		b, err := rr.Balance(PewmaP2C, nil)
+		if err != nil {
+			t.Fatalf("Balance() error: %v", err)
+		}
+		if b != fast {
+			t.Fatalf("should select fast backend, not %s", b.Name)
+		}
+	}
+
+	// fast backend is overloaded by in-flight requests
+	for i := 0; i < 100; i++ {
+		fast.IncConnNum()
+	}
+	if b, _ := rr.Balance(PewmaP2C, nil); b != slow {
+		t.Errorf("should select slow backend, not %s", b.Name)
+	}
+
+	// unavailable backend is never selected
+	slow.SetAvail(false)
+	if b, _ := rr.Balance(PewmaP2C, nil); b != fast {
+		t.Errorf("should select fast backend, not %s", b.Name)
+	}
+	fast.SetAvail(false)
+	if _, err := rr.Balance(PewmaP2C, nil); err == nil {
+		t.Errorf("should return error when all backends are down")
+	}
+}
+
+func TestWeightedRandomSelect(t *testing.T) {
+	rr := prepareBalanceRR()
+	count := make([]int, len(rr.backends))
+	for i := 0; i < 6000; i++ {
+		count[weightedRandomSelect(rr.backends, 300, 0)]++
+	}
+	if count[0] != 0 {
+		t.Errorf("excluded backend should not be selected")
+	}
+	if count[1] < count[2] {
+		t.Errorf("backend with higher weight should be selected more: %v", count)
+	}
+}
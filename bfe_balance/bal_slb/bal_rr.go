@@ -61,6 +61,13 @@ const (
 	WrrSticky = 2
 	WlcSimple = 3
 	WlcSmooth = 4
+
+	// consistent hash algorithms
+	ChashRing   = 5
+	ChashMaglev = 6
+
+	// peak-ewma latency with power of two choices
+	PewmaP2C = 7
 )
 
 type BackendList []*BackendRR
@@ -96,6 +103,12 @@ type BalanceRR struct {
 
 	slowStartNum  int // number of backends in slow_start phase
 	slowStartTime int // time for backend increases the weight to the full value, in seconds
+
+	hashTable      hashTable // lookup table for consistent hash
+	hashLoadFactor int       // load factor for bounded load consistent hash, in percent
+
+	panicThreshold int  // enter panic mode if healthy percent of backends is below it
+	panicMode      bool // in panic mode, health status of backends is ignored
 }
 
 func NewBalanceRR(name string) *BalanceRR {
@@ -114,6 +127,7 @@ func (brr *BalanceRR) Init(conf cluster_table_conf.SubClusterBackend) {
 	}
 	brr.sorted = false
 	brr.next = 0
+	brr.hashTable = nil
 }
 
 func (brr *BalanceRR) SetSlowStart(ssTime int) {
@@ -137,6 +151,55 @@ func (brr *BalanceRR) checkSlowStart() {
 	}
 }
 
+// SetPanicThreshold sets panic threshold (in percent) of sub cluster.
+func (brr *BalanceRR) SetPanicThreshold(threshold int) {
+	brr.Lock()
+	brr.panicThreshold = threshold
+	brr.Unlock()
+}
+
+// checkPanic enters panic mode if healthy percent of backends is below panic
+// threshold, and leaves panic mode otherwise.
+func (brr *BalanceRR) checkPanic() {
+	brr.Lock()
+	defer brr.Unlock()
+
+	panicMode := false
+	if brr.panicThreshold > 0 {
+		healthy, total := 0, 0
+		for _, backendRR := range brr.backends {
+			if backendRR.weight <= 0 || !backendRR.backend.InService() {
+				continue
+			}
+			total++
+			if backendRR.backend.Avail() {
+				healthy++
+			}
+		}
+		panicMode = total > 0 && healthy*100 < brr.panicThreshold*total
+	}
+
+	if panicMode != brr.panicMode {
+		log.Logger.Warn("rr_bal:sub cluster[%s] panic mode changed to %v", brr.Name, panicMode)
+		brr.panicMode = panicMode
+	} else if !panicMode {
+		return
+	}
+
+	// Note: backends may be added by Update() in panic mode
+	for _, backendRR := range brr.backends {
+		backendRR.panic = panicMode
+	}
+}
+
+// PanicMode checks whether sub cluster is in panic mode.
+func (brr *BalanceRR) PanicMode() bool {
+	brr.Lock()
+	defer brr.Unlock()
+
+	return brr.panicMode
+}
+
 // Release releases backend list.
 func (brr *BalanceRR) Release() {
 	for _, back := range brr.backends {
@@ -196,6 +259,11 @@ func (brr *BalanceRR) Update(conf cluster_table_conf.SubClusterBackend) {
 	brr.backends = backendsNew
 	brr.sorted = false
 	brr.next = 0
+
+	// rebuild consistent hash table if in use
+	if brr.hashTable != nil {
+		brr.hashTable.build(brr.backends)
+	}
 }
 
 // initWeight initializes all backendRR.current to backendRR.weight.
@@ -221,10 +289,12 @@ func (brr *BalanceRR) ensureSortedUnlocked() {
 
 // Balance select one backend from sub cluster in round-robin manner.
 func (brr *BalanceRR) Balance(algor int, key []byte) (*backend.BfeBackend, error) {
-	// Slow start is not supported when session sticky is enabled
-	if algor != WrrSticky {
+	// Slow start is not supported when session sticky or consistent hash is enabled
+	if algor != WrrSticky && algor != ChashRing && algor != ChashMaglev {
 		brr.checkSlowStart()
 	}
+	brr.checkPanic()
+
 	switch algor {
 	case WrrSimple:
 		return brr.simpleBalance()
@@ -236,6 +306,10 @@ func (brr *BalanceRR) Balance(algor int, key []byte) (*backend.BfeBackend, error
 		return brr.leastConnsSimpleBalance()
 	case WlcSmooth:
 		return brr.leastConnsSmoothBalance()
+	case ChashRing, ChashMaglev:
+		return brr.chashBalance(algor, key)
+	case PewmaP2C:
+		return brr.pewmaBalance()
 	default:
 		return brr.smoothBalance()
 	}
@@ -253,9 +327,8 @@ func smoothBalance(backs BackendList) (*backend.BfeBackend, error) {
 	total, max := 0, 0
 
 	for _, backendRR := range backs {
-		backend := backendRR.backend
 		// skip ineligible backend
-		if !backend.Avail() || backendRR.weight <= 0 {
+		if !backendRR.avail() || backendRR.weight <= 0 {
 			continue
 		}
 
@@ -328,7 +401,7 @@ func leastConnsBalance(backs BackendList) (BackendList, error) {
 	// select available candidates
 	singleBackend := true
 	for _, backendRR := range backs {
-		if !backendRR.backend.Avail() || backendRR.weight <= 0 {
+		if !backendRR.avail() || backendRR.weight <= 0 {
 			continue
 		}
 
@@ -387,7 +460,7 @@ func (brr *BalanceRR) simpleBalance() (*backend.BfeBackend, error) {
 		backendRR = backends[next]
 		backend = backendRR.backend
 
-		avail := backend.Avail()
+		avail := backendRR.avail()
 		if avail && backendRR.current > 0 {
 			// find one available backend
 			break
@@ -447,7 +520,7 @@ func (brr *BalanceRR) stickyBalance(key []byte) (*backend.BfeBackend, error) {
 	// select available candidates
 	brr.ensureSortedUnlocked()
 	for _, backendRR := range brr.backends {
-		if backendRR.backend.Avail() && backendRR.weight > 0 {
+		if backendRR.avail() && backendRR.weight > 0 {
 			candidates = append(candidates, backendRR)
 			totalWeight += backendRR.weight
 		}
@@ -494,6 +567,37 @@ func (brr *BalanceRR) Len() int {
 	return len(brr.backends)
 }
 
+// Backends returns all backends of sub cluster.
+func (brr *BalanceRR) Backends() []*backend.BfeBackend {
+	brr.Lock()
+	defer brr.Unlock()
+
+	backs := make([]*backend.BfeBackend, 0, len(brr.backends))
+	for _, backendRR := range brr.backends {
+		backs = append(backs, backendRR.backend)
+	}
+	return backs
+}
+
+// AvailWeight returns sum weight of available backends and sum weight of all
+// backends in sub cluster.
+func (brr *BalanceRR) AvailWeight() (int, int) {
+	brr.Lock()
+	defer brr.Unlock()
+
+	avail, total := 0, 0
+	for _, backendRR := range brr.backends {
+		if backendRR.weight <= 0 || !backendRR.backend.InService() {
+			continue
+		}
+		total += backendRR.weight
+		if backendRR.backend.Avail() {
+			avail += backendRR.weight
+		}
+	}
+	return avail, total
+}
+
 func GetHash(value []byte, base uint) int {
 	var hash uint64
 
@@ -507,13 +611,18 @@ func GetHash(value []byte, base uint) int {
 }
 
 // Look up backend with given addrInfo(ip:port)
+//
+// Note: draining backend could still be found, so that existing sessions
+// sticky to it are allowed to finish.
 func (brr *BalanceRR) LookUpBackend(addrInfo string) (*backend.BfeBackend, error) {
 	brr.Lock()
 	defer brr.Unlock()
 
 	for _, backendRR := range brr.backends {
-		if backendRR.backend.AddrInfo == addrInfo && backendRR.backend.Avail() && backendRR.weight > 0 {
-			return backendRR.backend, nil
+		back := backendRR.backend
+		if back.AddrInfo == addrInfo && back.Avail() && backendRR.weight > 0 &&
+			back.Mode() != backend.ModeMaintenance {
+			return back, nil
 		}
 	}
 	/* never come here */
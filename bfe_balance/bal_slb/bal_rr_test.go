@@ -363,3 +363,101 @@ func TestSlowStart(t *testing.T) {
 	rr := prepareBalanceRR()
 	rr.SetSlowStart(30)
 }
+
+func TestPanicMode(t *testing.T) {
+	rr := prepareBalanceRR()
+	rr.SetPanicThreshold(50)
+
+	// 2 of 3 backends are healthy, not in panic mode
+	rr.backends[0].backend.SetAvail(false)
+	for i := 0; i < 10; i++ {
+		b, err := rr.Balance(WrrSmooth, nil)
+		if err != nil || b.Name == "b1" {
+			t.Fatalf("should not select unhealthy backend: %v %v", b, err)
+		}
+	}
+	if rr.PanicMode() {
+		t.Errorf("should not be in panic mode")
+	}
+
+	// 1 of 3 backends are healthy, balance across all backends
+	rr.backends[1].backend.SetAvail(false)
+	counts := make(map[string]int)
+	for i := 0; i < 60; i++ {
+		b, err := rr.Balance(WrrSmooth, nil)
+		if err != nil {
+			t.Fatalf("Balance() err: %v", err)
+		}
+		counts[b.Name]++
+	}
+	if !rr.PanicMode() {
+		t.Errorf("should be in panic mode")
+	}
+	if counts["b1"] != 30 || counts["b2"] != 20 || counts["b3"] != 10 {
+		t.Errorf("unexpected result in panic mode: %v", counts)
+	}
+
+	// all backends are down, still balance in panic mode
+	rr.backends[2].backend.SetAvail(false)
+	if _, err := rr.Balance(WlcSmooth, nil); err != nil {
+		t.Errorf("Balance() err: %v", err)
+	}
+
+	// leave panic mode
+	for _, backendRR := range rr.backends {
+		backendRR.backend.SetAvail(true)
+	}
+	rr.backends[0].backend.SetAvail(false)
+	if b, err := rr.Balance(PewmaP2C, nil); err != nil || b.Name == "b1" || rr.PanicMode() {
+		t.Errorf("should leave panic mode: %v %v", b, err)
+	}
+}
+
+func TestBackendMode(t *testing.T) {
+	rr := prepareBalanceRR()
+	rr.backends[0].backend.SetMode(backend.ModeDraining)
+	rr.backends[1].backend.SetMode(backend.ModeMaintenance)
+
+	// no new requests to draining or maintenance backend
+	algors := []int{WrrSimple, WrrSmooth, WrrSticky, WlcSimple, WlcSmooth, ChashRing, PewmaP2C}
+	for _, algor := range algors {
+		for i := 0; i < 10; i++ {
+			b, err := rr.Balance(algor, []byte(fmt.Sprintf("key%d", i)))
+			if err != nil || b.Name != "b3" {
+				t.Fatalf("algor %d: should select b3 only: %v %v", algor, b, err)
+			}
+		}
+	}
+
+	// backends out of service are not counted for panic mode
+	rr.SetPanicThreshold(50)
+	if _, err := rr.Balance(WrrSmooth, nil); err != nil || rr.PanicMode() {
+		t.Errorf("should not be in panic mode: %v", err)
+	}
+	if avail, total := rr.AvailWeight(); avail != 100 || total != 100 {
+		t.Errorf("AvailWeight() = %d, %d, want 100, 100", avail, total)
+	}
+
+	// existing sessions to draining backend are allowed to finish
+	if _, err := rr.LookUpBackend("127.0.0.1:80"); err != nil {
+		t.Errorf("LookUpBackend() should find draining backend: %v", err)
+	}
+	if _, err := rr.LookUpBackend("127.0.0.1:81"); err == nil {
+		t.Errorf("LookUpBackend() should not find backend in maintenance")
+	}
+
+	// back to service
+	rr.backends[0].backend.SetMode(backend.ModeNormal)
+	rr.backends[1].backend.SetMode(backend.ModeNormal)
+	counts := make(map[string]int)
+	for i := 0; i < 60; i++ {
+		b, err := rr.Balance(WrrSmooth, nil)
+		if err != nil {
+			t.Fatalf("Balance() err: %v", err)
+		}
+		counts[b.Name]++
+	}
+	if counts["b1"] != 30 || counts["b2"] != 20 || counts["b3"] != 10 {
+		t.Errorf("unexpected result after back to service: %v", counts)
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 )
 
 import (
@@ -41,6 +42,10 @@ type BalTable struct {
 	lock     sync.RWMutex
 	balTable BalMap // from cluster to balancer
 	versions BalVersion
+
+	backendConfs cluster_table_conf.AllClusterBackend // backend conf before domain expanded
+	discovery    *DomainDiscovery                     // backend discovery by dns
+	overrides    backendOverrides                     // runtime override of backends
 }
 
 type BalVersion struct {
@@ -57,6 +62,8 @@ type BalTableState struct {
 func NewBalTable(checkConfFetcher backend.CheckConfFetcher) *BalTable {
 	t := new(BalTable)
 	t.balTable = make(BalMap)
+	t.discovery = NewDomainDiscovery(nil)
+	t.overrides = make(backendOverrides)
 	backend.SetCheckConfFetcher(checkConfFetcher)
 	return t
 }
@@ -82,6 +89,27 @@ func (t *BalTable) BalTableConfLoad(gslbConfFilename, clusterTableFilename strin
 	return gslbConf, backendConf, err
 }
 
+// BalTableConfCheck checks whether gslb and cluster_table conf could be
+// reloaded, before they are applied.
+func BalTableConfCheck(gslbConfs gslb_conf.GslbConf, backendConfs cluster_table_conf.ClusterTableConf) error {
+	for clusterName, gslbConf := range *gslbConfs.Clusters {
+		if _, ok := (*backendConfs.Config)[clusterName]; !ok {
+			return fmt.Errorf("no backend conf for %s", clusterName)
+		}
+
+		totalWeight := 0
+		for _, weight := range gslbConf {
+			if weight > 0 {
+				totalWeight += weight
+			}
+		}
+		if totalWeight == 0 {
+			return fmt.Errorf("gslb total weight = 0 [%s]", clusterName)
+		}
+	}
+	return nil
+}
+
 func (t *BalTable) Init(gslbConfFilename, clusterTableFilename string) error {
 	gslbConf, backendConf, err := t.BalTableConfLoad(gslbConfFilename, clusterTableFilename)
 
@@ -135,9 +163,13 @@ func (t *BalTable) gslbInit(gslbConfs gslb_conf.GslbConf) error {
 func (t *BalTable) backendInit(backendConfs cluster_table_conf.ClusterTableConf) error {
 	fails := make([]string, 0)
 
+	// expand backends declared as domain
+	t.backendConfs = *backendConfs.Config
+	allBackendConf := t.overrides.applyWeight(t.discovery.Expand(t.backendConfs))
+
 	for clusterName, bal := range t.balTable {
 		// get gslbConf
-		backendConf, ok := (*backendConfs.Config)[clusterName]
+		backendConf, ok := allBackendConf[clusterName]
 		if !ok {
 			// external checking guarantee. should not come here in theory
 			log.Logger.Error("BalTable.backendInit():no backend conf for %s", clusterName)
@@ -153,6 +185,7 @@ func (t *BalTable) backendInit(backendConfs cluster_table_conf.ClusterTableConf)
 			fails = append(fails, clusterName)
 			continue
 		}
+		t.overrides.applyMode(clusterName, bal)
 	}
 
 	// update versions
@@ -211,10 +244,39 @@ func (t *BalTable) SetSlowStart(clusterTable *bfe_route.ClusterTable) {
 	}
 }
 
+// SetOutlierDetection sets outlier detection conf (from server data conf) for BalTable.
+//
+// Note:
+//  - SetOutlierDetection() is called after server reload gslb conf or server data conf
+//  - SetOutlierDetection() should be concurrency safe
+func (t *BalTable) SetOutlierDetection(clusterTable *bfe_route.ClusterTable) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if clusterTable == nil {
+		return
+	}
+
+	for clusterName, bal := range t.balTable {
+		cluster, err := clusterTable.Lookup(clusterName)
+		if err != nil {
+			continue
+		}
+
+		bal.SetOutlierDetection(cluster.OutlierDetectionConf())
+	}
+}
+
 func (t *BalTable) BalTableReload(gslbConfs gslb_conf.GslbConf,
 	backendConfs cluster_table_conf.ClusterTableConf) error {
+	// expand backends declared as domain
+	allBackendConf := t.discovery.Expand(*backendConfs.Config)
+
 	t.lock.Lock()
 
+	// overrides are kept across reload
+	allBackendConf = t.overrides.applyWeight(allBackendConf)
+
 	var fails []string
 	bmNew := make(BalMap)
 	for clusterName, gslbConf := range *gslbConfs.Clusters {
@@ -243,7 +305,7 @@ func (t *BalTable) BalTableReload(gslbConfs gslb_conf.GslbConf,
 
 	t.balTable = bmNew
 	for clusterName, bal := range t.balTable {
-		backendConf, ok1 := (*backendConfs.Config)[clusterName]
+		backendConf, ok1 := allBackendConf[clusterName]
 		if !ok1 {
 			// never comes here
 			log.Logger.Error("BalTableReload():no backend conf for %s", clusterName)
@@ -256,8 +318,11 @@ func (t *BalTable) BalTableReload(gslbConfs gslb_conf.GslbConf,
 				err.Error(), clusterName)
 			fails = append(fails, clusterName)
 		}
+		t.overrides.applyMode(clusterName, bal)
 	}
 
+	t.backendConfs = *backendConfs.Config
+
 	// update versions
 	t.versions.ClusterTableConfVer = *backendConfs.Version
 	t.versions.GslbConfTimeStamp = *gslbConfs.Ts
@@ -271,6 +336,60 @@ func (t *BalTable) BalTableReload(gslbConfs gslb_conf.GslbConf,
 	return nil
 }
 
+// RefreshDomains resolves domains in cluster table which are due, and updates
+// backends of clusters if resolving result changed.
+//
+// Note: new backends are added with slow start (if enabled for the cluster),
+// and the last good result is kept if resolving failed.
+func (t *BalTable) RefreshDomains(now time.Time) {
+	if !t.discovery.Refresh(now) {
+		return
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	allBackendConf := t.overrides.applyWeight(t.discovery.Expand(t.backendConfs))
+	for clusterName, bal := range t.balTable {
+		if !t.backendConfs[clusterName].HasDomain() {
+			continue
+		}
+
+		backendConf, ok := allBackendConf[clusterName]
+		if !ok {
+			continue
+		}
+		if err := bal.BackendReload(backendConf); err != nil {
+			log.Logger.Error("RefreshDomains():err[%s] in bal.BackendReload() for %s",
+				err.Error(), clusterName)
+		}
+		t.overrides.applyMode(clusterName, bal)
+	}
+}
+
+// StartDomainDiscovery starts to refresh domains in cluster table periodically.
+func (t *BalTable) StartDomainDiscovery() {
+	go func() {
+		ticker := time.NewTicker(domainCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case now := <-ticker.C:
+				t.RefreshDomains(now)
+			case <-t.discovery.Notify():
+				// resolve new domains at once
+				t.RefreshDomains(time.Now())
+			}
+		}
+	}()
+}
+
+// GetDomainState returns resolving state of domains in cluster table.
+func (t *BalTable) GetDomainState() map[string]DomainState {
+	return t.discovery.GetState()
+}
+
 func (t *BalTable) lookup(clusterName string) (*bal_gslb.BalanceGslb, error) {
 	bal, ok := t.balTable[clusterName]
 	if !ok {
@@ -48,6 +48,34 @@ func TestBalTableConfLoad(t *testing.T) {
 	}
 }
 
+func TestBalTableConfCheck(t *testing.T) {
+	balTable := NewBalTable(nil)
+	gslbConf, backendConf, err := balTable.BalTableConfLoad("testdata/bal_table/case1/gslb.data",
+		"testdata/bal_table/case1/cluster_table.data")
+	if err != nil {
+		t.Fatalf("BalTableConfLoad() err: %s", err)
+	}
+	if err := BalTableConfCheck(gslbConf, backendConf); err != nil {
+		t.Errorf("BalTableConfCheck() err: %s", err)
+	}
+
+	// cluster without backend conf
+	(*gslbConf.Clusters)["cluster_unknown"] = gslb_conf.GslbClusterConf{"a": 100}
+	if err := BalTableConfCheck(gslbConf, backendConf); err == nil {
+		t.Errorf("BalTableConfCheck() should return err for cluster without backend conf")
+	}
+	delete(*gslbConf.Clusters, "cluster_unknown")
+
+	// cluster with zero weight
+	for name := range *gslbConf.Clusters {
+		(*gslbConf.Clusters)[name] = gslb_conf.GslbClusterConf{"a": 0}
+		break
+	}
+	if err := BalTableConfCheck(gslbConf, backendConf); err == nil {
+		t.Errorf("BalTableConfCheck() should return err for zero weight")
+	}
+}
+
 func TestGslbInit(t *testing.T) {
 	balTable := NewBalTable(nil)
 	gslbFile := "testdata/bal_table/case1/gslb.data"
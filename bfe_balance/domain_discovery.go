@@ -0,0 +1,344 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// backend discovery by dns
+//
+// Backends of sub cluster may be declared as a domain. The domain is resolved
+// periodically according to TTL of records, and the result is expanded into
+// backends with literal address. If resolving fails, the last good result is
+// kept.
+//
+// Domains are always resolved in background, so loading of cluster table
+// never blocks on dns. A new domain is expanded into no backend until it is
+// resolved for the first time.
+
+package bfe_balance
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+import (
+	"github.com/bfenetworks/go-lib/log"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_config/bfe_cluster_conf/cluster_table_conf"
+	"github.com/bfenetworks/bfe/bfe_util/bns"
+)
+
+const (
+	DomainRefreshMin = 5 * time.Second  // min interval of resolving a domain
+	DomainRefreshMax = 5 * time.Minute  // max interval of resolving a domain
+	DomainRetryMax   = 30 * time.Second // max interval of retrying after failure
+
+	domainCheckInterval = time.Second // interval of checking domains to resolve
+)
+
+// DomainResolver resolves domain to instances.
+type DomainResolver interface {
+	Resolve(domain string, dnsType string, port int) ([]bns.Instance, time.Duration, error)
+}
+
+type domainKey struct {
+	Domain string
+	Type   string
+	Port   int
+}
+
+// DomainState is resolving state of a domain.
+type DomainState struct {
+	Domain      string
+	Type        string
+	Port        int
+	Instances   []bns.Instance // result of last successful resolving
+	TTL         int            // TTL of records, in seconds
+	LastUpdate  time.Time      // time of last successful resolving
+	NextResolve time.Time      // time of next resolving
+	Fails       int            // num of continuous failures
+	LastError   string         // error of last failed resolving
+}
+
+// DomainDiscovery maintains resolving result of domains in cluster table.
+type DomainDiscovery struct {
+	lock     sync.Mutex
+	resolver DomainResolver
+	domains  map[domainKey]*DomainState
+	notify   chan struct{} // notified when new domains are added
+}
+
+func NewDomainDiscovery(resolver DomainResolver) *DomainDiscovery {
+	d := new(DomainDiscovery)
+	d.resolver = resolver
+	d.domains = make(map[domainKey]*DomainState)
+	d.notify = make(chan struct{}, 1)
+	return d
+}
+
+// Notify returns channel which is notified when new domains are added and
+// should be resolved at once.
+func (d *DomainDiscovery) Notify() <-chan struct{} {
+	return d.notify
+}
+
+func newDomainKey(conf *cluster_table_conf.BackendConf) domainKey {
+	key := domainKey{
+		Domain: *conf.Domain,
+		Type:   conf.GetDomainType(),
+	}
+	// port is provided by SRV records
+	if key.Type == cluster_table_conf.DomainTypeA {
+		key.Port = conf.GetPort()
+	}
+	return key
+}
+
+func (d *DomainDiscovery) getResolver() (DomainResolver, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.resolver == nil {
+		resolver, err := bns.NewDNSResolverFromConf(bns.DefaultResolvConf)
+		if err != nil {
+			return nil, err
+		}
+		d.resolver = resolver
+	}
+	return d.resolver, nil
+}
+
+// resolve resolves domain, and returns new state of the domain.
+func (d *DomainDiscovery) resolve(state DomainState, now time.Time) DomainState {
+	var instances []bns.Instance
+	var ttl time.Duration
+
+	resolver, err := d.getResolver()
+	if err == nil {
+		instances, ttl, err = resolver.Resolve(state.Domain, state.Type, state.Port)
+	}
+
+	if err != nil {
+		// keep the last good result
+		state.Fails++
+		state.LastError = err.Error()
+		state.NextResolve = now.Add(retryInterval(state.Fails))
+		log.Logger.Warn("DomainDiscovery: resolve %s %s err [%s]", state.Type, state.Domain, err)
+		return state
+	}
+
+	state.Instances = instances
+	state.TTL = int(ttl / time.Second)
+	state.LastUpdate = now
+	state.NextResolve = now.Add(refreshInterval(ttl))
+	state.Fails = 0
+	state.LastError = ""
+	return state
+}
+
+func refreshInterval(ttl time.Duration) time.Duration {
+	if ttl < DomainRefreshMin {
+		return DomainRefreshMin
+	}
+	if ttl > DomainRefreshMax {
+		return DomainRefreshMax
+	}
+	return ttl
+}
+
+func retryInterval(fails int) time.Duration {
+	interval := time.Second
+	for i := 1; i < fails && interval < DomainRetryMax; i++ {
+		interval *= 2
+	}
+	if interval > DomainRetryMax {
+		interval = DomainRetryMax
+	}
+	return interval
+}
+
+// Expand replaces backends declared as domain in conf with resolved ones.
+// Domains not in conf are removed from DomainDiscovery, and new domains are
+// added to be resolved by Refresh() in background.
+func (d *DomainDiscovery) Expand(conf cluster_table_conf.AllClusterBackend) cluster_table_conf.AllClusterBackend {
+	// collect domains in conf
+	keys := make(map[domainKey]bool)
+	for _, clusterBackend := range conf {
+		for _, backends := range clusterBackend {
+			for _, backend := range backends {
+				if backend.IsDomain() {
+					keys[newDomainKey(backend)] = true
+				}
+			}
+		}
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	// add new domains, which are due to resolve at once
+	added := false
+	for key := range keys {
+		if _, ok := d.domains[key]; !ok {
+			d.domains[key] = &DomainState{Domain: key.Domain, Type: key.Type, Port: key.Port}
+			added = true
+		}
+	}
+	for key := range d.domains {
+		if !keys[key] {
+			delete(d.domains, key)
+		}
+	}
+	if added {
+		select {
+		case d.notify <- struct{}{}:
+		default:
+		}
+	}
+
+	// expand backends with the last good result
+	expanded := make(cluster_table_conf.AllClusterBackend, len(conf))
+	for clusterName, clusterBackend := range conf {
+		if !clusterBackend.HasDomain() {
+			expanded[clusterName] = clusterBackend
+			continue
+		}
+
+		newClusterBackend := make(cluster_table_conf.ClusterBackend, len(clusterBackend))
+		for subName, backends := range clusterBackend {
+			newClusterBackend[subName] = d.expandSubCluster(backends)
+		}
+		expanded[clusterName] = newClusterBackend
+	}
+
+	return expanded
+}
+
+func (d *DomainDiscovery) expandSubCluster(backends cluster_table_conf.SubClusterBackend) cluster_table_conf.SubClusterBackend {
+	var expanded cluster_table_conf.SubClusterBackend
+	seen := make(map[string]bool)
+
+	for _, backend := range backends {
+		if !backend.IsDomain() {
+			expanded = append(expanded, backend)
+			seen[backend.AddrInfo()] = true
+			continue
+		}
+
+		state, ok := d.domains[newDomainKey(backend)]
+		if !ok {
+			continue
+		}
+		for _, instance := range state.Instances {
+			conf := newInstanceBackendConf(backend, instance)
+			if *conf.Weight <= 0 {
+				log.Logger.Warn("DomainDiscovery: ignore instance %s of %s for weight %d",
+					conf.AddrInfo(), state.Domain, *conf.Weight)
+				continue
+			}
+			if seen[conf.AddrInfo()] {
+				continue
+			}
+			expanded = append(expanded, conf)
+			seen[conf.AddrInfo()] = true
+		}
+	}
+
+	return expanded
+}
+
+// newInstanceBackendConf creates backend conf for a resolved instance. Name
+// of backend is suffixed with address of instance, so that it is unique in
+// sub cluster.
+func newInstanceBackendConf(backend *cluster_table_conf.BackendConf,
+	instance bns.Instance) *cluster_table_conf.BackendConf {
+	name := fmt.Sprintf("%s_%s", *backend.Name, net.JoinHostPort(instance.Host, strconv.Itoa(instance.Port)))
+	addr := instance.Host
+	port := instance.Port
+	weight := *backend.Weight
+	// weight of SRV records is used if provided. Note: instance with
+	// invalid weight (<= 0 after mapping) is ignored by caller
+	if backend.GetDomainType() == cluster_table_conf.DomainTypeSRV && instance.Weight != 0 {
+		weight = instance.Weight
+	}
+
+	return &cluster_table_conf.BackendConf{
+		Name:   &name,
+		Addr:   &addr,
+		Port:   &port,
+		Weight: &weight,
+	}
+}
+
+// Refresh resolves domains which are due. It returns true if result of any
+// domain changed.
+func (d *DomainDiscovery) Refresh(now time.Time) bool {
+	d.lock.Lock()
+	var dueStates []DomainState
+	for _, state := range d.domains {
+		if !now.Before(state.NextResolve) {
+			dueStates = append(dueStates, *state)
+		}
+	}
+	d.lock.Unlock()
+
+	changed := false
+	for _, state := range dueStates {
+		newState := d.resolve(state, now)
+		key := domainKey{Domain: state.Domain, Type: state.Type, Port: state.Port}
+
+		d.lock.Lock()
+		if _, ok := d.domains[key]; ok {
+			// domain may be removed by Expand() during resolving
+			d.domains[key] = &newState
+			if !sameInstances(state.Instances, newState.Instances) {
+				changed = true
+			}
+		}
+		d.lock.Unlock()
+	}
+
+	return changed
+}
+
+func sameInstances(a, b []bns.Instance) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// GetState returns resolving state of all domains.
+func (d *DomainDiscovery) GetState() map[string]DomainState {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	states := make(map[string]DomainState, len(d.domains))
+	for key, state := range d.domains {
+		name := fmt.Sprintf("%s:%s", key.Type, key.Domain)
+		if key.Type == cluster_table_conf.DomainTypeA {
+			name = fmt.Sprintf("%s:%d", name, key.Port)
+		}
+		states[name] = *state
+	}
+	return states
+}
@@ -0,0 +1,225 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bfe_balance
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_config/bfe_cluster_conf/cluster_table_conf"
+	"github.com/bfenetworks/bfe/bfe_util/bns"
+)
+
+type fakeResolver struct {
+	instances map[string][]bns.Instance
+	ttl       time.Duration
+	err       error
+	calls     int
+}
+
+func (r *fakeResolver) Resolve(domain string, dnsType string, port int) ([]bns.Instance, time.Duration, error) {
+	r.calls++
+	if r.err != nil {
+		return nil, 0, r.err
+	}
+
+	var instances []bns.Instance
+	for _, instance := range r.instances[domain] {
+		if dnsType == cluster_table_conf.DomainTypeA {
+			instance.Port = port
+		}
+		instances = append(instances, instance)
+	}
+	return instances, r.ttl, nil
+}
+
+func newTestBackendConf(name, addr, domain, domainType string, port, weight int) *cluster_table_conf.BackendConf {
+	conf := &cluster_table_conf.BackendConf{
+		Name:   &name,
+		Port:   &port,
+		Weight: &weight,
+	}
+	if len(addr) > 0 {
+		conf.Addr = &addr
+	}
+	if len(domain) > 0 {
+		conf.Domain = &domain
+		conf.DomainType = &domainType
+	}
+	return conf
+}
+
+func subClusterAddrs(backends cluster_table_conf.SubClusterBackend) []string {
+	var addrs []string
+	for _, backend := range backends {
+		addrs = append(addrs, backend.AddrInfo())
+	}
+	return addrs
+}
+
+func TestDomainDiscoveryExpand(t *testing.T) {
+	resolver := &fakeResolver{
+		instances: map[string][]bns.Instance{
+			"app.example.org": {{Host: "10.0.0.1"}, {Host: "10.0.0.2"}},
+			"_http._tcp.example.org": {
+				{Host: "10.0.1.1", Port: 8081, Weight: 5},
+				{Host: "10.0.1.2", Port: 8081},
+				{Host: "10.0.1.3", Port: 8081, Weight: -1},
+			},
+		},
+		ttl: 30 * time.Second,
+	}
+	d := NewDomainDiscovery(resolver)
+
+	conf := cluster_table_conf.AllClusterBackend{
+		"static": {
+			"sub1": {newTestBackendConf("s1", "10.1.0.1", "", "", 80, 10)},
+		},
+		"dynamic": {
+			"sub1": {
+				newTestBackendConf("s1", "10.1.0.1", "", "", 80, 10),
+				newTestBackendConf("a", "", "app.example.org", "A", 8080, 10),
+				// duplicated backends are ignored
+				newTestBackendConf("a", "", "app.example.org", "A", 8080, 10),
+			},
+			"sub2": {newTestBackendConf("srv", "", "_http._tcp.example.org", "SRV", 0, 10)},
+		},
+	}
+
+	// new domains are resolved in background
+	expanded := d.Expand(conf)
+	if resolver.calls != 0 || len(expanded["dynamic"]["sub2"]) != 0 {
+		t.Errorf("domains should not be resolved in Expand()")
+	}
+	select {
+	case <-d.Notify():
+	default:
+		t.Errorf("new domains should be notified")
+	}
+	if !d.Refresh(time.Now()) || resolver.calls != 2 {
+		t.Errorf("expect 2 resolving, got %d", resolver.calls)
+	}
+
+	expanded = d.Expand(conf)
+
+	addrs := subClusterAddrs(expanded["dynamic"]["sub1"])
+	expect := []string{"10.1.0.1:80", "10.0.0.1:8080", "10.0.0.2:8080"}
+	if len(addrs) != len(expect) {
+		t.Fatalf("expect %v, got %v", expect, addrs)
+	}
+	for i := range expect {
+		if addrs[i] != expect[i] {
+			t.Errorf("expect %v, got %v", expect, addrs)
+		}
+	}
+
+	// backends of instances have unique names
+	sub1 := expanded["dynamic"]["sub1"]
+	if *sub1[1].Name != "a_10.0.0.1:8080" || *sub1[2].Name != "a_10.0.0.2:8080" {
+		t.Errorf("unexpected names: %s %s", *sub1[1].Name, *sub1[2].Name)
+	}
+
+	// instance with invalid weight is ignored
+	sub2 := expanded["dynamic"]["sub2"]
+	if len(sub2) != 2 || *sub2[0].Weight != 5 || *sub2[1].Weight != 10 {
+		t.Errorf("unexpected backends of SRV: %v", subClusterAddrs(sub2))
+	}
+	if len(expanded["static"]["sub1"]) != 1 {
+		t.Errorf("static cluster should not change")
+	}
+
+	// domains not in conf are removed
+	delete(conf, "dynamic")
+	d.Expand(conf)
+	if len(d.GetState()) != 0 {
+		t.Errorf("expect no domain, got %v", d.GetState())
+	}
+}
+
+func TestDomainDiscoveryRefresh(t *testing.T) {
+	resolver := &fakeResolver{
+		instances: map[string][]bns.Instance{
+			"app.example.org": {{Host: "10.0.0.1"}},
+		},
+		ttl: 10 * time.Second,
+	}
+	d := NewDomainDiscovery(resolver)
+
+	conf := cluster_table_conf.AllClusterBackend{
+		"c": {
+			"sub1": {newTestBackendConf("a", "", "app.example.org", "A", 8080, 10)},
+		},
+	}
+	d.Expand(conf)
+	now := time.Now()
+	if !d.Refresh(now) || resolver.calls != 1 {
+		t.Errorf("new domain should be resolved")
+	}
+
+	// not due
+	if d.Refresh(now) || resolver.calls != 1 {
+		t.Errorf("domain should not be resolved before TTL expired")
+	}
+
+	// due but no change
+	now = now.Add(11 * time.Second)
+	if d.Refresh(now) || resolver.calls != 2 {
+		t.Errorf("domain should be resolved without change")
+	}
+
+	// result changed
+	resolver.instances["app.example.org"] = []bns.Instance{{Host: "10.0.0.2"}}
+	now = now.Add(11 * time.Second)
+	if !d.Refresh(now) {
+		t.Errorf("result of domain should be changed")
+	}
+
+	// resolving failed, keep last good result
+	resolver.err = errors.New("timeout")
+	now = now.Add(11 * time.Second)
+	if d.Refresh(now) {
+		t.Errorf("result of domain should be kept")
+	}
+	state := d.GetState()["A:app.example.org:8080"]
+	if state.Fails != 1 || state.LastError != "timeout" {
+		t.Errorf("unexpected state: %+v", state)
+	}
+	if !state.NextResolve.Equal(now.Add(time.Second)) {
+		t.Errorf("unexpected next resolve time: %s", state.NextResolve)
+	}
+
+	addrs := subClusterAddrs(d.Expand(conf)["c"]["sub1"])
+	if len(addrs) != 1 || addrs[0] != "10.0.0.2:8080" {
+		t.Errorf("expect last good result, got %v", addrs)
+	}
+}
+
+func TestDomainRefreshInterval(t *testing.T) {
+	if refreshInterval(time.Second) != DomainRefreshMin {
+		t.Errorf("refresh interval should not be less than %s", DomainRefreshMin)
+	}
+	if refreshInterval(time.Hour) != DomainRefreshMax {
+		t.Errorf("refresh interval should not be more than %s", DomainRefreshMax)
+	}
+	if retryInterval(1) != time.Second || retryInterval(3) != 4*time.Second {
+		t.Errorf("unexpected retry interval")
+	}
+	if retryInterval(100) != DomainRetryMax {
+		t.Errorf("retry interval should not be more than %s", DomainRetryMax)
+	}
+}
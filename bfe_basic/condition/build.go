@@ -453,6 +453,27 @@ func buildPrimitive(node *parser.CallExpr) (Condition, error) {
 			fetcher: &MethodFetcher{},
 			matcher: NewInMatcher(node.Args[0].Value, true),
 		}, nil
+	case "req_grpc_service_in":
+		return &PrimitiveCond{
+			name:    node.Fun.Name,
+			node:    node,
+			fetcher: &GrpcServiceFetcher{},
+			matcher: NewInMatcher(node.Args[0].Value, false),
+		}, nil
+	case "req_grpc_method_in":
+		return &PrimitiveCond{
+			name:    node.Fun.Name,
+			node:    node,
+			fetcher: &GrpcMethodFetcher{},
+			matcher: NewInMatcher(node.Args[0].Value, false),
+		}, nil
+	case "req_h2_fingerprint_in":
+		return &PrimitiveCond{
+			name:    node.Fun.Name,
+			node:    node,
+			fetcher: &H2FingerprintFetcher{},
+			matcher: NewInMatcher(node.Args[0].Value, true),
+		}, nil
 	case "res_code_in":
 		return &PrimitiveCond{
 			name:    node.Fun.Name,
@@ -460,6 +481,13 @@ func buildPrimitive(node *parser.CallExpr) (Condition, error) {
 			fetcher: &ResCodeFetcher{},
 			matcher: NewInMatcher(node.Args[0].Value, false),
 		}, nil
+	case "res_grpc_status_in":
+		return &PrimitiveCond{
+			name:    node.Fun.Name,
+			node:    node,
+			fetcher: &ResGrpcStatusFetcher{},
+			matcher: NewInMatcher(node.Args[0].Value, false),
+		}, nil
 	case "res_header_key_in":
 		return &PrimitiveCond{
 			name: node.Fun.Name,
@@ -517,6 +545,20 @@ func buildPrimitive(node *parser.CallExpr) (Condition, error) {
 			fetcher: &ClientCANameFetcher{},
 			matcher: NewInMatcher(node.Args[0].Value, false),
 		}, nil
+	case "ses_tls_ja3_in":
+		return &PrimitiveCond{
+			name:    node.Fun.Name,
+			node:    node,
+			fetcher: &TlsJA3Fetcher{},
+			matcher: NewInMatcher(node.Args[0].Value, true),
+		}, nil
+	case "ses_tls_ja4_in":
+		return &PrimitiveCond{
+			name:    node.Fun.Name,
+			node:    node,
+			fetcher: &TlsJA4Fetcher{},
+			matcher: NewInMatcher(node.Args[0].Value, true),
+		}, nil
 	case "req_context_value_in":
 		return &PrimitiveCond{
 			name:    node.Fun.Name,
@@ -456,3 +456,54 @@ func TestBuildHostTagIn(t *testing.T) {
 		t.Fatalf("should not match host tag %s", req.Route.HostTag)
 	}
 }
+
+func TestBuildTlsJA3In(t *testing.T) {
+	buildTlsJA3In, err := Build("ses_tls_ja3_in(\"E7D705A3286E19EA42F587B344EE6865\")")
+	if err != nil {
+		t.Fatalf("build failed, ses_tls_ja3_in(), err(%s)", err.Error())
+	}
+
+	req.Session = &bfe_basic.Session{TlsState: &bfe_tls.ConnectionState{JA3Hash: "e7d705a3286e19ea42f587b344ee6865"}, IsSecure: true}
+	if !buildTlsJA3In.Match(&req) {
+		t.Errorf("ja3 not match ses_tls_ja3_in()")
+	}
+
+	req.Session = &bfe_basic.Session{TlsState: &bfe_tls.ConnectionState{JA3Hash: "e7d705a3286e19ea42f587b344ee6865"}}
+	if buildTlsJA3In.Match(&req) {
+		t.Errorf("ja3 match ses_tls_ja3_in() for non-tls session")
+	}
+}
+
+func TestBuildTlsJA4In(t *testing.T) {
+	buildTlsJA4In, err := Build("ses_tls_ja4_in(\"t13d1516h2_8daaf6152771_e5627efa2ab1|t13d1517h2_8daaf6152771_b0da82dd1658\")")
+	if err != nil {
+		t.Fatalf("build failed, ses_tls_ja4_in(), err(%s)", err.Error())
+	}
+
+	req.Session = &bfe_basic.Session{TlsState: &bfe_tls.ConnectionState{JA4: "t13d1516h2_8daaf6152771_e5627efa2ab1"}, IsSecure: true}
+	if !buildTlsJA4In.Match(&req) {
+		t.Errorf("ja4 not match ses_tls_ja4_in()")
+	}
+
+	req.Session = &bfe_basic.Session{TlsState: &bfe_tls.ConnectionState{JA4: "t12d1516h2_8daaf6152771_e5627efa2ab1"}, IsSecure: true}
+	if buildTlsJA4In.Match(&req) {
+		t.Errorf("ja4 match ses_tls_ja4_in()")
+	}
+}
+
+func TestBuildH2FingerprintIn(t *testing.T) {
+	buildH2FingerprintIn, err := Build("req_h2_fingerprint_in(\"4420df39710ed13773d86b9de54c4cdf\")")
+	if err != nil {
+		t.Fatalf("build failed, req_h2_fingerprint_in(), err(%s)", err.Error())
+	}
+
+	r := bfe_basic.Request{Session: &bfe_basic.Session{}, HttpRequest: &bfe_http.Request{}}
+	if buildH2FingerprintIn.Match(&r) {
+		t.Errorf("request without h2 fingerprint match req_h2_fingerprint_in()")
+	}
+
+	r.HttpRequest.State = &bfe_http.RequestState{H2FingerprintHash: "4420df39710ed13773d86b9de54c4cdf"}
+	if !buildH2FingerprintIn.Match(&r) {
+		t.Errorf("h2 fingerprint not match req_h2_fingerprint_in()")
+	}
+}
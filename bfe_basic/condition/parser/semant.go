@@ -68,14 +68,20 @@ var funcProtos = map[string][]Token{
 	"req_cip_range":              {STRING, STRING},
 	"req_vip_range":              {STRING, STRING},
 	"req_cip_hash_in":            {STRING},
+	"req_grpc_service_in":        {STRING},
+	"req_grpc_method_in":         {STRING},
+	"req_h2_fingerprint_in":      {STRING},
 	"res_code_in":                {STRING},
 	"res_header_key_in":          {STRING},
 	"res_header_value_in":        {STRING, STRING, BOOL},
+	"res_grpc_status_in":         {STRING},
 	"ses_vip_range":              {STRING, STRING},
 	"ses_sip_range":              {STRING, STRING},
 	"ses_tls_sni_in":             {STRING},
 	"ses_tls_client_auth":        nil,
 	"ses_tls_client_ca_in":       {STRING},
+	"ses_tls_ja3_in":             {STRING},
+	"ses_tls_ja4_in":             {STRING},
 	"req_context_value_in":       {STRING, STRING, BOOL},
 	"bfe_time_range":             []Token{STRING, STRING},
 	"bfe_periodic_time_range":    []Token{STRING, STRING, STRING},
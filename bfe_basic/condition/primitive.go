@@ -635,6 +635,77 @@ func (rf *ResCodeFetcher) Fetch(req *bfe_basic.Request) (interface{}, error) {
 	return strconv.Itoa(req.HttpResponse.StatusCode), nil
 }
 
+// GrpcServiceFetcher fetches service of gRPC request
+type GrpcServiceFetcher struct{}
+
+func (gf *GrpcServiceFetcher) Fetch(req *bfe_basic.Request) (interface{}, error) {
+	if req == nil {
+		return nil, fmt.Errorf("fetcher: nil pointer")
+	}
+
+	info := req.GetGrpcInfo()
+	if info == nil {
+		return nil, fmt.Errorf("fetcher: not grpc request")
+	}
+	return info.Service, nil
+}
+
+// GrpcMethodFetcher fetches method of gRPC request
+type GrpcMethodFetcher struct{}
+
+func (gf *GrpcMethodFetcher) Fetch(req *bfe_basic.Request) (interface{}, error) {
+	if req == nil {
+		return nil, fmt.Errorf("fetcher: nil pointer")
+	}
+
+	info := req.GetGrpcInfo()
+	if info == nil {
+		return nil, fmt.Errorf("fetcher: not grpc request")
+	}
+	return info.Method, nil
+}
+
+// H2FingerprintFetcher fetches hash of HTTP/2 fingerprint of request
+type H2FingerprintFetcher struct{}
+
+func (hf *H2FingerprintFetcher) Fetch(req *bfe_basic.Request) (interface{}, error) {
+	if req == nil || req.HttpRequest == nil {
+		return nil, fmt.Errorf("fetcher: nil pointer")
+	}
+
+	state := req.HttpRequest.State
+	if state == nil || state.H2FingerprintHash == "" {
+		return nil, fmt.Errorf("fetcher: no h2 fingerprint")
+	}
+	return state.H2FingerprintHash, nil
+}
+
+// ResGrpcStatusFetcher fetches gRPC status of response. Status in trailers
+// is available only after response is finished.
+type ResGrpcStatusFetcher struct{}
+
+func (rf *ResGrpcStatusFetcher) Fetch(req *bfe_basic.Request) (interface{}, error) {
+	if req == nil {
+		return nil, fmt.Errorf("fetcher: nil pointer")
+	}
+
+	info := req.GetGrpcInfo()
+	if info == nil {
+		return nil, fmt.Errorf("fetcher: not grpc request")
+	}
+	if info.Status != "" {
+		return info.Status, nil
+	}
+
+	// status in headers of trailers-only response
+	if req.HttpResponse != nil {
+		if status := req.HttpResponse.Header.Get(bfe_basic.HeaderGrpcStatus); status != "" {
+			return status, nil
+		}
+	}
+	return nil, fmt.Errorf("fetcher: no grpc status")
+}
+
 type TrustedCIpMatcher struct{}
 
 func (m *TrustedCIpMatcher) Match(req *bfe_basic.Request) bool {
@@ -975,6 +1046,38 @@ func (fetcher *ClientCANameFetcher) Fetch(req *bfe_basic.Request) (interface{},
 	return req.Session.TlsState.ClientCAName, nil
 }
 
+// TlsJA3Fetcher fetches JA3 fingerprint hash of tls client
+type TlsJA3Fetcher struct{}
+
+func (fetcher *TlsJA3Fetcher) Fetch(req *bfe_basic.Request) (interface{}, error) {
+	if req == nil {
+		return nil, fmt.Errorf("fetcher: no req")
+	}
+
+	ses := req.Session
+	if ses == nil || !ses.IsSecure || ses.TlsState == nil || ses.TlsState.JA3Hash == "" {
+		return nil, fmt.Errorf("fetcher: no ja3")
+	}
+
+	return ses.TlsState.JA3Hash, nil
+}
+
+// TlsJA4Fetcher fetches JA4 fingerprint of tls client
+type TlsJA4Fetcher struct{}
+
+func (fetcher *TlsJA4Fetcher) Fetch(req *bfe_basic.Request) (interface{}, error) {
+	if req == nil {
+		return nil, fmt.Errorf("fetcher: no req")
+	}
+
+	ses := req.Session
+	if ses == nil || !ses.IsSecure || ses.TlsState == nil || ses.TlsState.JA4 == "" {
+		return nil, fmt.Errorf("fetcher: no ja4")
+	}
+
+	return ses.TlsState.JA4, nil
+}
+
 type ContextValueFetcher struct {
 	key string
 }
@@ -425,3 +425,40 @@ func TestReqBodyJsonPrefixInCombineWithIn(t *testing.T) {
 		t.Errorf("should match combined condition")
 	}
 }
+
+func TestGrpcPrimitives(t *testing.T) {
+	cond, err := Build(`req_grpc_service_in("helloworld.Greeter") && req_grpc_method_in("SayHello|SayBye")`)
+	if err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+	statusCond, err := Build(`res_grpc_status_in("4|14")`)
+	if err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+
+	httpReq, _ := bfe_http.NewRequest("POST", "http://example.org/helloworld.Greeter/SayHello", nil)
+	req := bfe_basic.NewRequest(httpReq, nil, nil, &bfe_basic.Session{}, nil)
+	if cond.Match(req) {
+		t.Errorf("should not match non-grpc request")
+	}
+
+	req.SetGrpcInfo(&bfe_basic.GrpcInfo{Service: "helloworld.Greeter", Method: "SayHello"})
+	if !cond.Match(req) {
+		t.Errorf("should match grpc service and method")
+	}
+	if statusCond.Match(req) {
+		t.Errorf("should not match without grpc status")
+	}
+
+	// status in headers of trailers-only response
+	req.HttpResponse = &bfe_http.Response{Header: make(bfe_http.Header)}
+	req.HttpResponse.Header.Set("Grpc-Status", "14")
+	if !statusCond.Match(req) {
+		t.Errorf("should match grpc status in headers")
+	}
+
+	req.GetGrpcInfo().Status = "0"
+	if statusCond.Match(req) {
+		t.Errorf("should not match grpc status 0")
+	}
+}
@@ -48,6 +48,8 @@ var (
 	ErrBkNoSubClusterCross = errors.New("BK_NO_SUB_CLUSTER_CROSS") // no sub-cluster found
 	ErrBkCrossRetryBalance = errors.New("BK_CROSS_RETRY_BALANCE")  // cross retry balance failed
 	ErrBkBodyProcess       = errors.New("BK_BODY_PROCESS")         // body process error
+	ErrBkCircuitOpen       = errors.New("BK_CIRCUIT_OPEN")         // circuit breaker of cluster is open
+	ErrBkGrpcStatus        = errors.New("BK_GRPC_STATUS")          // non-OK grpc status from backend
 
 	// GSLB error
 	ErrGslbBlackhole = errors.New("GSLB_BLACKHOLE") // deny by blackhole
@@ -0,0 +1,178 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// gRPC context for request
+
+package bfe_basic
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+import (
+	"google.golang.org/grpc/codes"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_http"
+)
+
+const CtxGrpcInfo = "__REQ_GRPC_INFO"
+
+const (
+	HeaderGrpcStatus  = "Grpc-Status"
+	HeaderGrpcMessage = "Grpc-Message"
+	HeaderGrpcTimeout = "Grpc-Timeout"
+
+	GrpcContentType        = "application/grpc"
+	GrpcWebContentType     = "application/grpc-web"
+	GrpcWebTextContentType = "application/grpc-web-text"
+)
+
+// GrpcInfo holds information of a gRPC request.
+type GrpcInfo struct {
+	Service  string    // full name of service, e.g. "helloworld.Greeter"
+	Method   string    // name of method, e.g. "SayHello"
+	Deadline time.Time // deadline set by grpc-timeout, zero if not set
+	Status   string    // gRPC status code of response, empty if unknown
+	Message  string    // gRPC status message of response
+}
+
+func (r *Request) SetGrpcInfo(info *GrpcInfo) {
+	r.SetContext(CtxGrpcInfo, info)
+}
+
+func (r *Request) GetGrpcInfo() *GrpcInfo {
+	val := r.GetContext(CtxGrpcInfo)
+	if val == nil {
+		return nil
+	}
+	info, ok := val.(*GrpcInfo)
+	if !ok {
+		return nil
+	}
+	return info
+}
+
+// IsGrpcRequest checks whether request is a gRPC request, e.g. with
+// content-type application/grpc or application/grpc+proto.
+// Note: gRPC-Web requests are not included.
+func IsGrpcRequest(req *bfe_http.Request) bool {
+	return matchContentType(req.Header.Get("Content-Type"), GrpcContentType)
+}
+
+// IsGrpcWebRequest checks whether request is a gRPC-Web request, e.g. with
+// content-type application/grpc-web or application/grpc-web-text+proto.
+func IsGrpcWebRequest(req *bfe_http.Request) bool {
+	ctype := req.Header.Get("Content-Type")
+	return matchContentType(ctype, GrpcWebContentType) || matchContentType(ctype, GrpcWebTextContentType)
+}
+
+// matchContentType checks whether ctype is base type, with optional
+// suffix (e.g. +proto) or parameters.
+func matchContentType(ctype string, base string) bool {
+	if !strings.HasPrefix(ctype, base) {
+		return false
+	}
+	rest := ctype[len(base):]
+	return len(rest) == 0 || rest[0] == '+' || rest[0] == ';'
+}
+
+// ParseGrpcPath parses service and method from path of gRPC request, which
+// is in form of "/{service}/{method}".
+func ParseGrpcPath(path string) (string, string, error) {
+	if !strings.HasPrefix(path, "/") {
+		return "", "", fmt.Errorf("invalid grpc path %s", path)
+	}
+	pos := strings.LastIndex(path, "/")
+	if pos <= 0 {
+		return "", "", fmt.Errorf("invalid grpc path %s", path)
+	}
+	service, method := path[1:pos], path[pos+1:]
+	if service == "" || method == "" {
+		return "", "", fmt.Errorf("invalid grpc path %s", path)
+	}
+	return service, method, nil
+}
+
+// GrpcCodeFromErr maps error of proxy to gRPC status code.
+func GrpcCodeFromErr(err error) codes.Code {
+	switch err {
+	case ErrBkRespHeaderTimeout, ErrClientTimeout:
+		return codes.DeadlineExceeded
+	case ErrBkFindProduct, ErrBkFindLocation, ErrBkNoCluster:
+		return codes.Unimplemented
+	case ErrBkNoSubCluster, ErrBkNoSubClusterCross, ErrBkNoBackend, ErrBkConnectBackend,
+		ErrBkWriteRequest, ErrBkReadRespHeader, ErrBkRequestBackend, ErrBkTransportBroken,
+		ErrBkRetryTooMany, ErrBkCrossRetryBalance, ErrBkCircuitOpen, ErrGslbBlackhole:
+		return codes.Unavailable
+	default:
+		return codes.Internal
+	}
+}
+
+// GrpcCodeFromHTTPStatus maps HTTP status code of response without
+// grpc-status to gRPC status code.
+// See https://github.com/grpc/grpc/blob/master/doc/http-grpc-status-mapping.md
+func GrpcCodeFromHTTPStatus(statusCode int) codes.Code {
+	switch statusCode {
+	case bfe_http.StatusBadRequest:
+		return codes.Internal
+	case bfe_http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case bfe_http.StatusForbidden:
+		return codes.PermissionDenied
+	case bfe_http.StatusNotFound:
+		return codes.Unimplemented
+	case 429, bfe_http.StatusBadGateway, // 429: too many requests
+		bfe_http.StatusServiceUnavailable, bfe_http.StatusGatewayTimeout:
+		return codes.Unavailable
+	default:
+		return codes.Unknown
+	}
+}
+
+// CreateGrpcErrResp returns a gRPC trailers-only response with given status.
+// Trailers-only response is also valid for gRPC-Web requests.
+func CreateGrpcErrResp(request *Request, code codes.Code, msg string) *bfe_http.Response {
+	ctype := GrpcContentType
+	if request.HttpRequest != nil && IsGrpcWebRequest(request.HttpRequest) {
+		ctype = request.HttpRequest.Header.Get("Content-Type")
+	}
+
+	res := CreateInternalResp(request, bfe_http.StatusOK)
+	res.Header.Set("Content-Type", ctype)
+	res.Header.Set(HeaderGrpcStatus, strconv.Itoa(int(code)))
+	if msg != "" {
+		res.Header.Set(HeaderGrpcMessage, EncodeGrpcMessage(msg))
+	}
+	return res
+}
+
+// EncodeGrpcMessage percent-encodes gRPC status message.
+func EncodeGrpcMessage(msg string) string {
+	var b strings.Builder
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		if c >= ' ' && c <= '~' && c != '%' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bfe_basic
+
+import (
+	"testing"
+)
+
+func TestParseGrpcPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		service string
+		method  string
+		ok      bool
+	}{
+		{"/helloworld.Greeter/SayHello", "helloworld.Greeter", "SayHello", true},
+		{"helloworld.Greeter/SayHello", "", "", false},
+		{"", "", "", false},
+		{"/", "", "", false},
+		{"/svc", "", "", false},
+		{"/svc/", "", "", false},
+		{"//SayHello", "", "", false},
+	}
+
+	for _, tt := range tests {
+		service, method, err := ParseGrpcPath(tt.path)
+		if (err == nil) != tt.ok {
+			t.Errorf("ParseGrpcPath(%q) error: %v, want ok %v", tt.path, err, tt.ok)
+			continue
+		}
+		if service != tt.service || method != tt.method {
+			t.Errorf("ParseGrpcPath(%q) = %q, %q, want %q, %q", tt.path, service, method, tt.service, tt.method)
+		}
+	}
+}
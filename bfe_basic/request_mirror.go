@@ -0,0 +1,47 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// mirror policy context for request
+
+package bfe_basic
+
+import (
+	"time"
+)
+
+const CtxMirrorPolicy = "__REQ_MIRROR_POLICY"
+
+// MirrorPolicy describes how to mirror a request to a shadow cluster.
+// Responses from shadow cluster are discarded.
+type MirrorPolicy struct {
+	ClusterName string        // name of shadow cluster
+	MaxBodySize int64         // max size of request body to be mirrored
+	Timeout     time.Duration // timeout of mirrored request
+}
+
+func (r *Request) SetMirrorPolicy(policy *MirrorPolicy) {
+	r.SetContext(CtxMirrorPolicy, policy)
+}
+
+func (r *Request) GetMirrorPolicy() *MirrorPolicy {
+	val := r.GetContext(CtxMirrorPolicy)
+	if val == nil {
+		return nil
+	}
+	policy, ok := val.(*MirrorPolicy)
+	if !ok {
+		return nil
+	}
+	return policy
+}
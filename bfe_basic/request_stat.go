@@ -51,6 +51,9 @@ type RequestStat struct {
 
 	// some status
 	IsCrossCluster bool // with cross-cluster retry?
+	IsRetryDenied  bool // retry denied by circuit breaker or retry budget?
+	IsHedged       bool // with hedged request?
+	IsHedgeWon     bool // response from hedged request?
 }
 
 func NewRequestStat(start time.Time) *RequestStat {
@@ -72,7 +72,12 @@ func NewSession(conn net.Conn) *Session {
 
 	s.Connection = conn
 	if conn != nil {
-		s.RemoteAddr = conn.RemoteAddr().(*net.TCPAddr)
+		switch addr := conn.RemoteAddr().(type) {
+		case *net.TCPAddr:
+			s.RemoteAddr = addr
+		case *net.UDPAddr: // for quic connection
+			s.RemoteAddr = &net.TCPAddr{IP: addr.IP, Port: addr.Port, Zone: addr.Zone}
+		}
 	}
 
 	s.Use100Continue = false
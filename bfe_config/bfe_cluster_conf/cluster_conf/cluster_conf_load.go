@@ -61,6 +61,29 @@ const (
 	BalanceModeWrr = "WRR" // weighted round robin
 	BalanceModeWlc = "WLC" // weighted least connection
 	BalanceModeEPP     = "EPP" // balance by epp
+	BalanceModeChash  = "CHASH"  // consistent hash using ring hash
+	BalanceModeMaglev = "MAGLEV" // consistent hash using maglev lookup table
+	BalanceModePewma  = "PEWMA"  // peak-ewma latency with power of two choices
+)
+
+// HashLoadFactor bounds for CHASH/MAGLEV bounded load, in percent.
+const (
+	MinHashLoadFactor = 100 // a backend never takes more than its fair share
+	MaxHashLoadFactor = 1000
+)
+
+// retry budget and hedging of cluster
+const (
+	DefaultRetryBudgetMinRetries = 3 // min retries per second allowed by retry budget
+
+	MinHedgePercentile = 50
+	MaxHedgePercentile = 99
+)
+
+// connection pool of http/2 over TLS (h2) backend
+const (
+	DefaultH2ConnsPerHost         = 2   // default max connections for each backend
+	DefaultH2MaxConcurrentStreams = 100 // default max concurrent streams for each connection
 )
 
 const (
@@ -76,7 +99,7 @@ const (
 
 // BackendCheck is conf of backend check
 type BackendCheck struct {
-	Schem      *string // protocol for health check (HTTP/HTTPS/TCP)
+	Schem      *string // protocol for health check (HTTP/HTTPS/TLS/TCP/H2C/H2/GRPC)
 	Uri        *string // uri used in health check
 	Host       *string // if check request use special host header
 	HostType   *string // extending the type of Host.
@@ -90,6 +113,46 @@ type BackendCheck struct {
 	SuccNum         *int // healthy threshold (consecutive successes of normal request)
 	CheckTimeout    *int // timeout for health check, in ms
 	CheckInterval   *int // interval of health check, in ms
+
+	// GRPCServiceName is service name in grpc health check request.
+	// Empty means checking overall health status of server.
+	GRPCServiceName *string
+
+	// response matching for HTTP/HTTPS/H2C/H2 health check, all configured
+	// conditions should be satisfied
+	BodyContains    *string            // substring required in response body
+	BodyRegex       *string            // regular expression which response body should match
+	BodyJSONPath    *string            // path of field in JSON response body, e.g. "status", "data.state"
+	BodyJSONValue   *string            // expected value of field at BodyJSONPath
+	RequiredHeaders *map[string]string // required response headers; empty value means header should be present
+
+	bodyRegex *regexp.Regexp // cache of BodyRegex
+}
+
+// MaxCheckBodySize is max size of response body read in health check.
+const MaxCheckBodySize = 64 * 1024
+
+// BodyRegexp returns compiled BodyRegex, nil if not configured.
+//
+// Note: BodyRegex is compiled on the fly if conf is not checked by
+// BackendCheckCheck (or modified after that), and an error is returned if
+// it is invalid, so body matching is never skipped silently.
+func (conf *BackendCheck) BodyRegexp() (*regexp.Regexp, error) {
+	if conf.BodyRegex == nil || *conf.BodyRegex == "" {
+		return nil, nil
+	}
+	if re := conf.bodyRegex; re != nil && re.String() == *conf.BodyRegex {
+		return re, nil
+	}
+	return regexp.Compile(*conf.BodyRegex)
+}
+
+// NeedMatchResponse checks whether response header or body should be matched.
+func (conf *BackendCheck) NeedMatchResponse() bool {
+	return (conf.BodyContains != nil && *conf.BodyContains != "") ||
+		(conf.BodyRegex != nil && *conf.BodyRegex != "") ||
+		(conf.BodyJSONPath != nil && *conf.BodyJSONPath != "") ||
+		(conf.RequiredHeaders != nil && len(*conf.RequiredHeaders) > 0)
 }
 
 // FCGIConf are FastCGI related configurations
@@ -98,6 +161,12 @@ type FCGIConf struct {
 	Root    string            // the server root
 }
 
+// H2Conf are http/2 over TLS (h2) related configurations
+type H2Conf struct {
+	ConnsPerHost         int // max connections for each backend
+	MaxConcurrentStreams int // max concurrent streams for each connection
+}
+
 // BackendBasic is conf of backend basic
 type BackendBasic struct {
 	Protocol                 *string // backend protocol
@@ -110,6 +179,7 @@ type BackendBasic struct {
 	OutlierDetectionHttpCode *string // outlier detection http status code
 	// protocol specific configurations
 	FCGIConf *FCGIConf
+	H2Conf   *H2Conf
 }
 
 // BackendHTTPS is conf of backend https
@@ -232,8 +302,10 @@ func (conf *BackendHTTPS) CheckBFECertAndKey() error {
 			return err
 		}
 	}
-	if certPem == nil || keyPem == nil {
+	if certPem == nil && keyPem == nil {
 		return nil
+	} else if certPem == nil || keyPem == nil {
+		return errors.New("BFECertFile and BFEKeyFile should be set together")
 	} else if cert, err = bfe_tls.X509KeyPair(certPem, keyPem); err != nil {
 		return err
 	}
@@ -264,6 +336,27 @@ type GslbBasicConf struct {
 
 	BalanceMode *string // balanceMode, default WRR
 	EPPAddr     *[]string // EPP address
+
+	// HashLoadFactor is load factor (in percent) for bounded load consistent
+	// hashing in CHASH/MAGLEV mode, e.g. 125 means a backend takes at most
+	// 1.25 times of its fair share before overflowing to the next backend.
+	// 0 means unbounded.
+	HashLoadFactor *int
+
+	// SubClusterZone is zone of each sub cluster (sub cluster name => zone).
+	// Sub clusters in the same zone as bfe instance are preferred if
+	// ZoneAwareThreshold > 0.
+	SubClusterZone *map[string]string
+
+	// ZoneAwareThreshold is min healthy percent of backends in local zone.
+	// Traffic stays in local zone while healthy percent >= threshold, and
+	// spills to other zones proportionally below it. 0 means disabled.
+	ZoneAwareThreshold *int
+
+	// PanicThreshold is min healthy percent of backends in sub cluster. Below
+	// it, sub cluster enters panic mode and balances across all backends
+	// disregarding health status. 0 means disabled.
+	PanicThreshold *int
 }
 
 // ClusterBasicConf is basic conf for cluster.
@@ -279,6 +372,39 @@ type ClusterBasicConf struct {
 
 	DisableHostHeader  *bool // disable host header when forward to backend
 	DisableHealthCheck *bool // disable health check for backend
+
+	// circuit breaker of cluster (zero means unrestricted)
+	MaxRequests        *int // max concurrent requests to cluster
+	MaxPendingRequests *int // max concurrent requests waiting for response header from cluster
+	MaxRetries         *int // max concurrent retries to cluster
+
+	// retry budget of cluster: retries are limited to a percentage of recent
+	// requests, and at least RetryBudgetMinRetries retries per second
+	RetryBudgetPercent    *int // zero means disabled
+	RetryBudgetMinRetries *int
+
+	// hedging of idempotent requests: if no response is received within
+	// the HedgePercentile latency of cluster, send a hedged request to
+	// another backend, and the first response wins
+	HedgePercentile *int // zero means disabled
+	HedgeMinDelay   *int // min delay before sending hedged request, in ms
+}
+
+// OutlierDetectionConf is conf of passive outlier detection, which ejects
+// backends temporarily based on results of normal requests.
+type OutlierDetectionConf struct {
+	Consecutive5xx            *int // eject after consecutive 5xx responses, 0 means disabled
+	ConsecutiveGatewayFailure *int // eject after consecutive gateway failures (502/503/504, connect/read error), 0 means disabled
+	Interval                  *int // interval of success rate analysis and ejection expiry check, in ms
+	BaseEjectionTime          *int // ejection time for the first ejection, doubled on each consecutive ejection, in ms
+	MaxEjectionTime           *int // max ejection time, in ms
+	MaxEjectionPercent        *int // max percent of backends in cluster that can be ejected
+	SuccessRateMinimumHosts   *int // min number of backends with enough requests for success rate analysis
+	SuccessRateRequestVolume  *int // min number of requests in an interval for a backend to be analysed
+	// SuccessRateStdevFactor is used to calculate ejection threshold of
+	// success rate: mean - stdev * (SuccessRateStdevFactor / 1000).
+	// 0 means success rate analysis is disabled.
+	SuccessRateStdevFactor *int
 }
 
 // ClusterConf is conf of cluster.
@@ -289,6 +415,8 @@ type ClusterConf struct {
 	ClusterBasic *ClusterBasicConf // basic conf for cluster
 	HTTPSConf    *BackendHTTPS     // backend's https conf
 	AIConf             *AIConf         // ai conf for cluster
+
+	OutlierDetection *OutlierDetectionConf // passive outlier detection conf
 }
 
 type ClusterToConf map[string]ClusterConf
@@ -301,7 +429,7 @@ type BfeClusterConf struct {
 
 // BackendHTTPS is https conf of backend.
 func BackendHTTPSCheck(protocol *string, conf *BackendHTTPS) error {
-	if protocol == nil || *protocol != "https" {
+	if protocol == nil || (*protocol != "https" && *protocol != "h2") {
 		return nil
 	}
 	conf.protocol = *protocol
@@ -348,9 +476,9 @@ func BackendBasicCheck(conf *BackendBasic) error {
 	}
 	*conf.Protocol = strings.ToLower(*conf.Protocol)
 	switch *conf.Protocol {
-	case "http", "tcp", "ws", "fcgi", "h2c", "https":
+	case "http", "tcp", "ws", "fcgi", "h2c", "https", "h2":
 	default:
-		return fmt.Errorf("protocol only support http/tcp/ws/fcgi/h2c/https, but is:%s", *conf.Protocol)
+		return fmt.Errorf("protocol only support http/tcp/ws/fcgi/h2c/https/h2, but is:%s", *conf.Protocol)
 	}
 
 	if conf.TimeoutConnSrv == nil {
@@ -399,6 +527,19 @@ func BackendBasicCheck(conf *BackendBasic) error {
 		conf.FCGIConf = defaultFCGIConf
 	}
 
+	if conf.H2Conf == nil {
+		conf.H2Conf = new(H2Conf)
+	}
+	if conf.H2Conf.ConnsPerHost < 0 || conf.H2Conf.MaxConcurrentStreams < 0 {
+		return errors.New("H2Conf.ConnsPerHost and H2Conf.MaxConcurrentStreams should not be negative")
+	}
+	if conf.H2Conf.ConnsPerHost == 0 {
+		conf.H2Conf.ConnsPerHost = DefaultH2ConnsPerHost
+	}
+	if conf.H2Conf.MaxConcurrentStreams == 0 {
+		conf.H2Conf.MaxConcurrentStreams = DefaultH2MaxConcurrentStreams
+	}
+
 	return nil
 }
 
@@ -525,8 +666,12 @@ func BackendCheckCheck(conf *BackendCheck) error {
 		// set default schem to http
 		schem := "http"
 		conf.Schem = &schem
-	} else if *conf.Schem != "http" && *conf.Schem != "https" && *conf.Schem != "tls" && *conf.Schem != "tcp" {
-		return errors.New("Schem for BackendCheck should be http/https/tls/tcp")
+	} else {
+		switch *conf.Schem {
+		case "http", "https", "tls", "tcp", "h2c", "h2", "grpc":
+		default:
+			return errors.New("Schem for BackendCheck should be http/https/tls/tcp/h2c/h2/grpc")
+		}
 	}
 
 	if conf.Uri == nil {
@@ -564,7 +709,12 @@ func BackendCheckCheck(conf *BackendCheck) error {
 		conf.SuccNum = &succNum
 	}
 
-	if *conf.Schem == "http" || *conf.Schem == "https" {
+	if conf.GRPCServiceName == nil {
+		serviceName := ""
+		conf.GRPCServiceName = &serviceName
+	}
+
+	if *conf.Schem == "http" || *conf.Schem == "https" || *conf.Schem == "h2c" || *conf.Schem == "h2" {
 		if !strings.HasPrefix(*conf.Uri, "/") {
 			return errors.New("Uri should be start with '/'")
 		}
@@ -582,6 +732,19 @@ func BackendCheckCheck(conf *BackendCheck) error {
 		return errors.New("SuccNum should be bigger than 0")
 	}
 
+	conf.bodyRegex = nil
+	if conf.BodyRegex != nil && *conf.BodyRegex != "" {
+		re, err := regexp.Compile(*conf.BodyRegex)
+		if err != nil {
+			return fmt.Errorf("BodyRegex: %s", err.Error())
+		}
+		conf.bodyRegex = re
+	}
+
+	if conf.BodyJSONValue != nil && (conf.BodyJSONPath == nil || *conf.BodyJSONPath == "") {
+		return errors.New("BodyJSONPath should be configured with BodyJSONValue")
+	}
+
 	return nil
 }
 
@@ -606,15 +769,49 @@ func GslbBasicConfCheck(conf *GslbBasicConf) error {
 		conf.BalanceMode = &defaultBalMode
 	}
 
+	if conf.HashLoadFactor == nil {
+		defaultHashLoadFactor := 0
+		conf.HashLoadFactor = &defaultHashLoadFactor
+	}
+
+	if conf.SubClusterZone == nil {
+		conf.SubClusterZone = &map[string]string{}
+	}
+
+	if conf.ZoneAwareThreshold == nil {
+		defaultZoneAwareThreshold := 0
+		conf.ZoneAwareThreshold = &defaultZoneAwareThreshold
+	}
+
+	if conf.PanicThreshold == nil {
+		defaultPanicThreshold := 0
+		conf.PanicThreshold = &defaultPanicThreshold
+	}
+
 	if err := HashConfCheck(conf.HashConf); err != nil {
 		return err
 	}
 
+	if *conf.ZoneAwareThreshold < 0 || *conf.ZoneAwareThreshold > 100 {
+		return fmt.Errorf("ZoneAwareThreshold should be in [0, 100]")
+	}
+
+	if *conf.PanicThreshold < 0 || *conf.PanicThreshold > 100 {
+		return fmt.Errorf("PanicThreshold should be in [0, 100]")
+	}
+
 	// check balanceMode
 	*conf.BalanceMode = strings.ToUpper(*conf.BalanceMode)
 	switch *conf.BalanceMode {
 	case BalanceModeWrr:
 	case BalanceModeWlc:
+	case BalanceModePewma:
+	case BalanceModeChash, BalanceModeMaglev:
+		if *conf.HashLoadFactor != 0 && (*conf.HashLoadFactor < MinHashLoadFactor ||
+			*conf.HashLoadFactor > MaxHashLoadFactor) {
+			return fmt.Errorf("HashLoadFactor should be 0 or in [%d, %d]",
+				MinHashLoadFactor, MaxHashLoadFactor)
+		}
 	case BalanceModeEPP:
 		if conf.EPPAddr == nil || len(*conf.EPPAddr) == 0 {
 			return errors.New("EPPAddr is nil or empty")
@@ -704,6 +901,146 @@ func ClusterBasicConfCheck(conf *ClusterBasicConf) error {
 		conf.DisableHealthCheck = &disableHealthCheck
 	}
 
+	if conf.MaxRequests == nil {
+		maxRequests := 0
+		conf.MaxRequests = &maxRequests
+	}
+	if *conf.MaxRequests < 0 {
+		return fmt.Errorf("MaxRequests should >= 0")
+	}
+
+	if conf.MaxPendingRequests == nil {
+		maxPendingRequests := 0
+		conf.MaxPendingRequests = &maxPendingRequests
+	}
+	if *conf.MaxPendingRequests < 0 {
+		return fmt.Errorf("MaxPendingRequests should >= 0")
+	}
+
+	if conf.MaxRetries == nil {
+		maxRetries := 0
+		conf.MaxRetries = &maxRetries
+	}
+	if *conf.MaxRetries < 0 {
+		return fmt.Errorf("MaxRetries should >= 0")
+	}
+
+	if conf.RetryBudgetPercent == nil {
+		retryBudgetPercent := 0
+		conf.RetryBudgetPercent = &retryBudgetPercent
+	}
+	if *conf.RetryBudgetPercent < 0 || *conf.RetryBudgetPercent > 100 {
+		return fmt.Errorf("RetryBudgetPercent should be in [0, 100]")
+	}
+
+	if conf.RetryBudgetMinRetries == nil {
+		retryBudgetMinRetries := DefaultRetryBudgetMinRetries
+		conf.RetryBudgetMinRetries = &retryBudgetMinRetries
+	}
+	if *conf.RetryBudgetMinRetries < 0 {
+		return fmt.Errorf("RetryBudgetMinRetries should >= 0")
+	}
+
+	if conf.HedgePercentile == nil {
+		hedgePercentile := 0
+		conf.HedgePercentile = &hedgePercentile
+	}
+	if *conf.HedgePercentile != 0 && (*conf.HedgePercentile < MinHedgePercentile ||
+		*conf.HedgePercentile > MaxHedgePercentile) {
+		return fmt.Errorf("HedgePercentile should be 0 or in [%d, %d]",
+			MinHedgePercentile, MaxHedgePercentile)
+	}
+
+	if conf.HedgeMinDelay == nil {
+		hedgeMinDelay := 0
+		conf.HedgeMinDelay = &hedgeMinDelay
+	}
+	if *conf.HedgeMinDelay < 0 {
+		return fmt.Errorf("HedgeMinDelay should >= 0")
+	}
+
+	return nil
+}
+
+// OutlierDetectionConfCheck check OutlierDetectionConf config.
+func OutlierDetectionConfCheck(conf *OutlierDetectionConf) error {
+	if conf.Consecutive5xx == nil {
+		consecutive5xx := 0
+		conf.Consecutive5xx = &consecutive5xx
+	}
+
+	if conf.ConsecutiveGatewayFailure == nil {
+		consecutiveGatewayFailure := 0
+		conf.ConsecutiveGatewayFailure = &consecutiveGatewayFailure
+	}
+
+	if conf.Interval == nil {
+		interval := 10000
+		conf.Interval = &interval
+	}
+
+	if conf.BaseEjectionTime == nil {
+		baseEjectionTime := 30000
+		conf.BaseEjectionTime = &baseEjectionTime
+	}
+
+	if conf.MaxEjectionTime == nil {
+		maxEjectionTime := 300000
+		conf.MaxEjectionTime = &maxEjectionTime
+	}
+
+	if conf.MaxEjectionPercent == nil {
+		maxEjectionPercent := 10
+		conf.MaxEjectionPercent = &maxEjectionPercent
+	}
+
+	if conf.SuccessRateMinimumHosts == nil {
+		successRateMinimumHosts := 5
+		conf.SuccessRateMinimumHosts = &successRateMinimumHosts
+	}
+
+	if conf.SuccessRateRequestVolume == nil {
+		successRateRequestVolume := 100
+		conf.SuccessRateRequestVolume = &successRateRequestVolume
+	}
+
+	if conf.SuccessRateStdevFactor == nil {
+		successRateStdevFactor := 0
+		conf.SuccessRateStdevFactor = &successRateStdevFactor
+	}
+
+	if *conf.Consecutive5xx < 0 || *conf.ConsecutiveGatewayFailure < 0 {
+		return errors.New("Consecutive5xx/ConsecutiveGatewayFailure should not be negative")
+	}
+
+	if *conf.Interval <= 0 {
+		return errors.New("Interval should be bigger than 0")
+	}
+
+	if *conf.BaseEjectionTime <= 0 {
+		return errors.New("BaseEjectionTime should be bigger than 0")
+	}
+
+	if *conf.MaxEjectionTime < *conf.BaseEjectionTime {
+		return errors.New("MaxEjectionTime should not be less than BaseEjectionTime")
+	}
+
+	if *conf.MaxEjectionPercent < 0 || *conf.MaxEjectionPercent > 100 {
+		return errors.New("MaxEjectionPercent should be in [0, 100]")
+	}
+
+	if *conf.SuccessRateMinimumHosts < 1 {
+		return errors.New("SuccessRateMinimumHosts should be bigger than 0")
+	}
+
+	if *conf.SuccessRateRequestVolume < 1 {
+		return errors.New("SuccessRateRequestVolume should be bigger than 0")
+	}
+
+	if *conf.SuccessRateStdevFactor < 0 {
+		return errors.New("SuccessRateStdevFactor should not be negative")
+	}
+
 	return nil
 }
 
@@ -747,6 +1084,15 @@ func ClusterConfCheck(conf *ClusterConf) error {
 		return fmt.Errorf("ClusterBasic:%s", err.Error())
 	}
 
+	// check OutlierDetection
+	if conf.OutlierDetection == nil {
+		conf.OutlierDetection = &OutlierDetectionConf{}
+	}
+	err = OutlierDetectionConfCheck(conf.OutlierDetection)
+	if err != nil {
+		return fmt.Errorf("OutlierDetection:%s", err.Error())
+	}
+
 	// check AIConf
 	if conf.AIConf != nil {
 		err = AIConfCheck(conf.AIConf)
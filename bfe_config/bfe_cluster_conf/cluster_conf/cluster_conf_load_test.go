@@ -15,6 +15,7 @@
 package cluster_conf
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -118,6 +119,213 @@ func TestStatusCodeRange(t *testing.T) {
 	})
 }
 
+func TestGslbBasicConfCheckHashMode(t *testing.T) {
+	cases := []struct {
+		mode   string
+		factor *int
+		ok     bool
+	}{
+		{"chash", nil, true},
+		{"maglev", nil, true},
+		{"CHASH", intPtr(125), true},
+		{"MAGLEV", intPtr(99), false},
+		{"CHASH", intPtr(1001), false},
+	}
+
+	for _, c := range cases {
+		mode := c.mode
+		conf := GslbBasicConf{BalanceMode: &mode, HashLoadFactor: c.factor}
+		err := GslbBasicConfCheck(&conf)
+		if (err == nil) != c.ok {
+			t.Errorf("GslbBasicConfCheck(%s, %v) err: %v", c.mode, c.factor, err)
+		}
+		if err == nil && *conf.BalanceMode != strings.ToUpper(c.mode) {
+			t.Errorf("BalanceMode should be %s", strings.ToUpper(c.mode))
+		}
+	}
+}
+
+func TestGslbBasicConfCheckZoneAware(t *testing.T) {
+	conf := GslbBasicConf{}
+	if err := GslbBasicConfCheck(&conf); err != nil {
+		t.Fatalf("GslbBasicConfCheck() err: %v", err)
+	}
+	if *conf.ZoneAwareThreshold != 0 || conf.SubClusterZone == nil {
+		t.Errorf("zone aware balance should be disabled by default")
+	}
+
+	for _, threshold := range []int{-1, 101} {
+		conf := GslbBasicConf{ZoneAwareThreshold: intPtr(threshold)}
+		if err := GslbBasicConfCheck(&conf); err == nil {
+			t.Errorf("GslbBasicConfCheck() should fail for ZoneAwareThreshold %d", threshold)
+		}
+	}
+}
+
+func TestGslbBasicConfCheckPanicThreshold(t *testing.T) {
+	conf := GslbBasicConf{}
+	if err := GslbBasicConfCheck(&conf); err != nil || *conf.PanicThreshold != 0 {
+		t.Errorf("PanicThreshold should be 0 by default: %v", err)
+	}
+
+	for _, c := range []struct {
+		threshold int
+		ok        bool
+	}{{50, true}, {100, true}, {-1, false}, {101, false}} {
+		conf := GslbBasicConf{PanicThreshold: intPtr(c.threshold)}
+		if err := GslbBasicConfCheck(&conf); (err == nil) != c.ok {
+			t.Errorf("GslbBasicConfCheck(PanicThreshold %d) err: %v", c.threshold, err)
+		}
+	}
+}
+
+func TestClusterBasicConfCheckCircuitBreaker(t *testing.T) {
+	conf := ClusterBasicConf{}
+	if err := ClusterBasicConfCheck(&conf); err != nil {
+		t.Fatalf("ClusterBasicConfCheck() err: %v", err)
+	}
+	if *conf.MaxRequests != 0 || *conf.MaxPendingRequests != 0 || *conf.MaxRetries != 0 {
+		t.Errorf("circuit breaker should be disabled by default")
+	}
+
+	conf = ClusterBasicConf{MaxRetries: intPtr(-1)}
+	if err := ClusterBasicConfCheck(&conf); err == nil {
+		t.Errorf("ClusterBasicConfCheck() should fail for negative MaxRetries")
+	}
+}
+
+func TestClusterBasicConfCheckRetryBudgetAndHedge(t *testing.T) {
+	conf := ClusterBasicConf{}
+	if err := ClusterBasicConfCheck(&conf); err != nil {
+		t.Fatalf("ClusterBasicConfCheck() err: %v", err)
+	}
+	if *conf.RetryBudgetPercent != 0 || *conf.RetryBudgetMinRetries != DefaultRetryBudgetMinRetries ||
+		*conf.HedgePercentile != 0 || *conf.HedgeMinDelay != 0 {
+		t.Errorf("unexpected default conf: %+v", conf)
+	}
+
+	cases := []struct {
+		conf ClusterBasicConf
+		ok   bool
+	}{
+		{ClusterBasicConf{RetryBudgetPercent: intPtr(20)}, true},
+		{ClusterBasicConf{RetryBudgetPercent: intPtr(101)}, false},
+		{ClusterBasicConf{RetryBudgetMinRetries: intPtr(-1)}, false},
+		{ClusterBasicConf{HedgePercentile: intPtr(95)}, true},
+		{ClusterBasicConf{HedgePercentile: intPtr(30)}, false},
+		{ClusterBasicConf{HedgePercentile: intPtr(100)}, false},
+		{ClusterBasicConf{HedgeMinDelay: intPtr(-1)}, false},
+	}
+	for i, c := range cases {
+		if err := ClusterBasicConfCheck(&c.conf); (err == nil) != c.ok {
+			t.Errorf("case %d: ClusterBasicConfCheck() err: %v", i, err)
+		}
+	}
+}
+
+func TestBackendBasicCheckH2(t *testing.T) {
+	protocol := "H2"
+	conf := BackendBasic{Protocol: &protocol}
+	if err := BackendBasicCheck(&conf); err != nil {
+		t.Fatalf("BackendBasicCheck() err: %v", err)
+	}
+	if *conf.Protocol != "h2" || conf.H2Conf.ConnsPerHost != DefaultH2ConnsPerHost ||
+		conf.H2Conf.MaxConcurrentStreams != DefaultH2MaxConcurrentStreams {
+		t.Errorf("unexpected default conf: %+v %+v", conf, conf.H2Conf)
+	}
+
+	conf = BackendBasic{H2Conf: &H2Conf{ConnsPerHost: -1}}
+	if err := BackendBasicCheck(&conf); err == nil {
+		t.Errorf("BackendBasicCheck() should fail for negative ConnsPerHost")
+	}
+}
+
+func TestBackendHTTPSCheckBFECert(t *testing.T) {
+	protocol := "h2"
+	certFile := "../../../conf/tls_conf/backend_rs/r_bfe_dev.crt"
+	keyFile := "../../../conf/tls_conf/backend_rs/r_bfe_dev_prv.pem"
+
+	conf := BackendHTTPS{BFECertFile: &certFile, BFEKeyFile: &keyFile}
+	if err := BackendHTTPSCheck(&protocol, &conf); err != nil {
+		t.Fatalf("BackendHTTPSCheck() err: %v", err)
+	}
+	if _, err := conf.GetBFECert(); err != nil {
+		t.Errorf("GetBFECert() err: %v", err)
+	}
+
+	// cert without key
+	conf = BackendHTTPS{BFECertFile: &certFile}
+	if err := BackendHTTPSCheck(&protocol, &conf); err == nil {
+		t.Errorf("BackendHTTPSCheck() should fail without BFEKeyFile")
+	}
+}
+
+func TestBackendCheckCheckResponseMatch(t *testing.T) {
+	regex := "status.*(ok"
+	conf := BackendCheck{BodyRegex: &regex}
+	if err := BackendCheckCheck(&conf); err == nil {
+		t.Errorf("BackendCheckCheck() should fail for invalid BodyRegex")
+	}
+
+	regex = `"status":\s*"ok"`
+	conf = BackendCheck{BodyRegex: &regex}
+	if err := BackendCheckCheck(&conf); err != nil {
+		t.Fatalf("BackendCheckCheck() err: %v", err)
+	}
+	if re, err := conf.BodyRegexp(); re == nil || err != nil || !conf.NeedMatchResponse() {
+		t.Errorf("BodyRegex should be compiled")
+	}
+
+	// BodyRegex is compiled on the fly if conf is not checked
+	conf = BackendCheck{BodyRegex: &regex}
+	if re, err := conf.BodyRegexp(); re == nil || err != nil || !conf.NeedMatchResponse() {
+		t.Errorf("BodyRegex should be compiled without BackendCheckCheck()")
+	}
+	invalid := "status.*(ok"
+	conf = BackendCheck{BodyRegex: &invalid}
+	if _, err := conf.BodyRegexp(); err == nil {
+		t.Errorf("BodyRegexp() should fail for invalid BodyRegex")
+	}
+
+	value := "ok"
+	conf = BackendCheck{BodyJSONValue: &value}
+	if err := BackendCheckCheck(&conf); err == nil {
+		t.Errorf("BackendCheckCheck() should fail without BodyJSONPath")
+	}
+}
+
+func TestOutlierDetectionConfCheck(t *testing.T) {
+	// default conf, all detectors are disabled
+	conf := OutlierDetectionConf{}
+	if err := OutlierDetectionConfCheck(&conf); err != nil {
+		t.Fatalf("OutlierDetectionConfCheck() err: %v", err)
+	}
+	if *conf.Consecutive5xx != 0 || *conf.SuccessRateStdevFactor != 0 || *conf.MaxEjectionPercent != 10 {
+		t.Errorf("unexpected default conf: %+v", conf)
+	}
+
+	cases := []struct {
+		conf OutlierDetectionConf
+		ok   bool
+	}{
+		{OutlierDetectionConf{Consecutive5xx: intPtr(5), SuccessRateStdevFactor: intPtr(1900)}, true},
+		{OutlierDetectionConf{Consecutive5xx: intPtr(-1)}, false},
+		{OutlierDetectionConf{Interval: intPtr(0)}, false},
+		{OutlierDetectionConf{BaseEjectionTime: intPtr(1000), MaxEjectionTime: intPtr(500)}, false},
+		{OutlierDetectionConf{MaxEjectionPercent: intPtr(101)}, false},
+		{OutlierDetectionConf{SuccessRateMinimumHosts: intPtr(0)}, false},
+	}
+	for i, c := range cases {
+		err := OutlierDetectionConfCheck(&c.conf)
+		if (err == nil) != c.ok {
+			t.Errorf("case %d: OutlierDetectionConfCheck() err: %v", i, err)
+		}
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
 
 func TestModelTableCheck(t *testing.T) {
 	t.Run("valid RMB table", func(t *testing.T) {
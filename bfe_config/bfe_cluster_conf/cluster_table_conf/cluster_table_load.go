@@ -30,30 +30,97 @@ import (
 	"github.com/bfenetworks/bfe/bfe_util/json"
 )
 
+const (
+	DomainTypeA   = "A"   // resolve domain by A and AAAA records
+	DomainTypeSRV = "SRV" // resolve domain by SRV records
+)
+
 // BackendConf is conf of backend
 type BackendConf struct {
 	Name   *string // e.g., "a-05.a"
 	Addr   *string // e.g., "10.26.35.33"
 	Port   *int    // e.g., 8000
 	Weight *int    // weight in load balance, e.g., 10
+
+	// backends discovered by dns, Addr should be empty if Domain is set
+	Domain     *string // e.g., "app.example.org"
+	DomainType *string // DomainTypeA (default) or DomainTypeSRV
 }
 
 func (b *BackendConf) AddrInfo() string {
+	if b.Addr == nil {
+		return fmt.Sprintf("%s:%d", b.DomainInfo(), b.GetPort())
+	}
 	return fmt.Sprintf("%s:%d", *b.Addr, *b.Port)
 }
 
+// IsDomain checks whether backends should be discovered by dns.
+func (b *BackendConf) IsDomain() bool {
+	return b.Domain != nil
+}
+
+// GetDomainType returns type of dns records for domain.
+func (b *BackendConf) GetDomainType() string {
+	if b.DomainType == nil {
+		return DomainTypeA
+	}
+	return *b.DomainType
+}
+
+// GetPort returns port of backend, 0 if not set.
+func (b *BackendConf) GetPort() int {
+	if b.Port == nil {
+		return 0
+	}
+	return *b.Port
+}
+
+// DomainInfo returns domain with type, e.g., "SRV:_http._tcp.example.org".
+func (b *BackendConf) DomainInfo() string {
+	if b.Domain == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s:%s", b.GetDomainType(), *b.Domain)
+}
+
 type SubClusterBackend []*BackendConf
 type ClusterBackend map[string]SubClusterBackend
 type AllClusterBackend map[string]ClusterBackend
 
 func (s SubClusterBackend) Len() int { return len(s) }
 func (s SubClusterBackend) Less(i, j int) bool {
+	if s[i].Addr == nil || s[j].Addr == nil {
+		return s[i].AddrInfo() < s[j].AddrInfo()
+	}
+
 	if *s[i].Addr != *s[j].Addr {
 		return *s[i].Addr < *s[j].Addr
 	}
 
 	return *s[i].Port < *s[j].Port
 }
+
+// HasDomain checks whether any backend should be discovered by dns.
+func (allClusterBackend AllClusterBackend) HasDomain() bool {
+	for _, clusterBackend := range allClusterBackend {
+		if clusterBackend.HasDomain() {
+			return true
+		}
+	}
+	return false
+}
+
+// HasDomain checks whether any backend should be discovered by dns.
+func (clusterBackend ClusterBackend) HasDomain() bool {
+	for _, backends := range clusterBackend {
+		for _, backend := range backends {
+			if backend.IsDomain() {
+				return true
+			}
+		}
+	}
+	return false
+}
 func (s SubClusterBackend) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
 
 // Sort sorted backends by addr and port
@@ -143,12 +210,18 @@ func BackendConfCheck(conf *BackendConf) error {
 		return errors.New("no Name")
 	}
 
-	if conf.Addr == nil {
-		return errors.New("no Addr")
-	}
+	if conf.Domain != nil {
+		if err := backendDomainCheck(conf); err != nil {
+			return err
+		}
+	} else {
+		if conf.Addr == nil {
+			return errors.New("no Addr")
+		}
 
-	if conf.Port == nil {
-		return errors.New("no Port")
+		if conf.Port == nil {
+			return errors.New("no Port")
+		}
 	}
 
 	if conf.Weight == nil {
@@ -158,6 +231,29 @@ func BackendConfCheck(conf *BackendConf) error {
 	return nil
 }
 
+func backendDomainCheck(conf *BackendConf) error {
+	if len(*conf.Domain) == 0 {
+		return errors.New("Domain is empty")
+	}
+
+	if conf.Addr != nil {
+		return errors.New("Addr and Domain should not be both set")
+	}
+
+	switch conf.GetDomainType() {
+	case DomainTypeA:
+		if conf.Port == nil {
+			return errors.New("no Port")
+		}
+	case DomainTypeSRV:
+		// port is provided by SRV records
+	default:
+		return fmt.Errorf("invalid DomainType: %s", *conf.DomainType)
+	}
+
+	return nil
+}
+
 func (conf *AllClusterBackend) Check() error {
 	return AllClusterBackendCheck(conf)
 }
@@ -35,3 +35,30 @@ func TestClusterTableLoad_1(t *testing.T) {
 		return
 	}
 }
+
+func TestBackendConfCheck_Domain(t *testing.T) {
+	str := func(s string) *string { return &s }
+	num := func(i int) *int { return &i }
+
+	tests := []struct {
+		Conf BackendConf
+		Ok   bool
+	}{
+		{BackendConf{Name: str("a"), Domain: str("app.example.org"), Port: num(80), Weight: num(1)}, true},
+		{BackendConf{Name: str("a"), Domain: str("app.example.org"), DomainType: str("A"), Weight: num(1)}, false},
+		{BackendConf{Name: str("a"), Domain: str("_http._tcp.example.org"), DomainType: str("SRV"), Weight: num(1)}, true},
+		{BackendConf{Name: str("a"), Domain: str("app.example.org"), DomainType: str("MX"), Port: num(80), Weight: num(1)}, false},
+		{BackendConf{Name: str("a"), Domain: str(""), Port: num(80), Weight: num(1)}, false},
+		{BackendConf{Name: str("a"), Domain: str("app.example.org"), Addr: str("10.0.0.1"), Port: num(80), Weight: num(1)}, false},
+	}
+
+	for i, tt := range tests {
+		err := BackendConfCheck(&tt.Conf)
+		if tt.Ok && err != nil {
+			t.Errorf("case %d unexpected error: %s", i, err)
+		}
+		if !tt.Ok && err == nil {
+			t.Errorf("case %d expect error", i)
+		}
+	}
+}
@@ -30,6 +30,18 @@ type BfeConfig struct {
 
 	// session cache config
 	SessionTicket ConfigSessionTicket
+
+	// dynamic config from xds server
+	Xds ConfigXds
+
+	// certificates from acme server
+	Acme ConfigAcme
+
+	// ocsp stapling for server certificates
+	OcspStapling ConfigOcspStapling
+
+	// private keys of server certificates on key server
+	KeylessTls ConfigKeylessTls
 }
 
 func SetDefaultConf(conf *BfeConfig) {
@@ -37,6 +49,10 @@ func SetDefaultConf(conf *BfeConfig) {
 	conf.HttpsBasic.SetDefaultConf()
 	conf.SessionCache.SetDefaultConf()
 	conf.SessionTicket.SetDefaultConf()
+	conf.Xds.SetDefaultConf()
+	conf.Acme.SetDefaultConf()
+	conf.OcspStapling.SetDefaultConf()
+	conf.KeylessTls.SetDefaultConf()
 }
 
 // BfeConfigLoad loads config from config file.
@@ -69,5 +85,21 @@ func BfeConfigLoad(filePath string, confRoot string) (BfeConfig, error) {
 		return cfg, err
 	}
 
+	if err = cfg.Xds.Check(confRoot); err != nil {
+		return cfg, err
+	}
+
+	if err = cfg.Acme.Check(confRoot); err != nil {
+		return cfg, err
+	}
+
+	if err = cfg.OcspStapling.Check(confRoot); err != nil {
+		return cfg, err
+	}
+
+	if err = cfg.KeylessTls.Check(confRoot); err != nil {
+		return cfg, err
+	}
+
 	return cfg, nil
 }
@@ -130,8 +130,8 @@ func TestBfeConfigLoadUsingDefault(t *testing.T) {
 		t.Errorf("CipherSuites length should be 9")
 	}
 
-	if len(config.HttpsBasic.CurvePreferences) != 1 && config.HttpsBasic.CurvePreferences[0] != "CurveP256" {
-		t.Errorf("CurvePreferences should be CurveP256")
+	if len(config.HttpsBasic.CurvePreferences) != 3 || config.HttpsBasic.CurvePreferences[0] != "X25519MLKEM768" {
+		t.Errorf("CurvePreferences should be X25519MLKEM768,X25519,CurveP256")
 	}
 
 	if !config.SessionCache.SessionCacheDisabled {
@@ -0,0 +1,119 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bfe_conf
+
+import (
+	"fmt"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_util"
+)
+
+const (
+	AcmeChallengeHTTP01    = "http-01"
+	AcmeChallengeTLSALPN01 = "tls-alpn-01"
+)
+
+type ConfigAcme struct {
+	// obtain and renew certificates from acme server or not
+	Enabled bool
+
+	// directory url of acme server
+	DirectoryURL string
+
+	// contact email of acme account
+	Email string
+
+	// ca certificates for verifying acme server (system roots if empty)
+	CACertFile string
+
+	// hostnames to obtain certificates for
+	AcmeConf string
+
+	// dir for account, certificates and keys
+	StorageDir string
+
+	// types of challenges to use, in order of preference
+	ChallengeTypes []string
+
+	// renew certificate when it expires within RenewBefore (day)
+	RenewBefore int
+
+	// interval for checking certificates (s)
+	CheckInterval int
+}
+
+func (cfg *ConfigAcme) SetDefaultConf() {
+	cfg.Enabled = false
+	cfg.DirectoryURL = "https://acme-v02.api.letsencrypt.org/directory"
+	cfg.AcmeConf = "tls_conf/acme_conf.data"
+	cfg.StorageDir = "tls_conf/acme"
+	cfg.RenewBefore = 30
+	cfg.CheckInterval = 3600
+}
+
+func (cfg *ConfigAcme) Check(confRoot string) error {
+	if !cfg.Enabled {
+		return nil
+	}
+	return ConfAcmeCheck(cfg, confRoot)
+}
+
+func ConfAcmeCheck(cfg *ConfigAcme, confRoot string) error {
+	// check DirectoryURL
+	if len(cfg.DirectoryURL) == 0 {
+		return fmt.Errorf("DirectoryURL not set")
+	}
+
+	// check CACertFile
+	if len(cfg.CACertFile) > 0 {
+		cfg.CACertFile = bfe_util.ConfPathProc(cfg.CACertFile, confRoot)
+	}
+
+	// check AcmeConf
+	if len(cfg.AcmeConf) == 0 {
+		return fmt.Errorf("AcmeConf not set")
+	}
+	cfg.AcmeConf = bfe_util.ConfPathProc(cfg.AcmeConf, confRoot)
+
+	// check StorageDir
+	if len(cfg.StorageDir) == 0 {
+		return fmt.Errorf("StorageDir not set")
+	}
+	cfg.StorageDir = bfe_util.ConfPathProc(cfg.StorageDir, confRoot)
+
+	// check ChallengeTypes
+	if len(cfg.ChallengeTypes) == 0 {
+		cfg.ChallengeTypes = []string{AcmeChallengeTLSALPN01, AcmeChallengeHTTP01}
+	}
+	for _, typ := range cfg.ChallengeTypes {
+		if typ != AcmeChallengeHTTP01 && typ != AcmeChallengeTLSALPN01 {
+			return fmt.Errorf("ChallengeTypes[%s] not supported", typ)
+		}
+	}
+
+	// check RenewBefore
+	if cfg.RenewBefore <= 0 {
+		return fmt.Errorf("RenewBefore[%d] should > 0", cfg.RenewBefore)
+	}
+
+	// check CheckInterval
+	if cfg.CheckInterval <= 0 {
+		return fmt.Errorf("CheckInterval[%d] should > 0", cfg.CheckInterval)
+	}
+
+	return nil
+}
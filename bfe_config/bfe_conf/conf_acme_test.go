@@ -0,0 +1,91 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bfe_conf
+
+import (
+	"testing"
+)
+
+import (
+	gcfg "gopkg.in/gcfg.v1"
+)
+
+func confAcmeLoad(filePath string, confRoot string) (ConfigAcme, error) {
+	var cfg BfeConfig
+	cfg.Acme.SetDefaultConf()
+
+	// read config from file
+	if err := gcfg.ReadFileInto(&cfg, filePath); err != nil {
+		return cfg.Acme, err
+	}
+
+	// check acme conf
+	if err := cfg.Acme.Check(confRoot); err != nil {
+		return cfg.Acme, err
+	}
+
+	return cfg.Acme, nil
+}
+
+func TestConfAcmeLoad(t *testing.T) {
+	conf, err := confAcmeLoad("testdata/conf_acme/bfe_1.conf", "/home/bfe/conf")
+	if err != nil {
+		t.Fatalf("load config err: %s", err)
+	}
+
+	if conf.DirectoryURL != "https://localhost:14000/dir" {
+		t.Errorf("wrong DirectoryURL: %s", conf.DirectoryURL)
+	}
+	if conf.CACertFile != "/home/bfe/conf/tls_conf/pebble.minica.pem" {
+		t.Errorf("wrong CACertFile: %s", conf.CACertFile)
+	}
+	if conf.AcmeConf != "/home/bfe/conf/tls_conf/acme_conf.data" {
+		t.Errorf("wrong AcmeConf: %s", conf.AcmeConf)
+	}
+	if conf.StorageDir != "/home/bfe/conf/tls_conf/acme" {
+		t.Errorf("wrong StorageDir: %s", conf.StorageDir)
+	}
+	if len(conf.ChallengeTypes) != 1 || conf.ChallengeTypes[0] != AcmeChallengeHTTP01 {
+		t.Errorf("wrong ChallengeTypes: %v", conf.ChallengeTypes)
+	}
+	if conf.RenewBefore != 30 || conf.CheckInterval != 3600 {
+		t.Errorf("wrong RenewBefore/CheckInterval: %d/%d", conf.RenewBefore, conf.CheckInterval)
+	}
+}
+
+func TestConfAcmeLoadDefaultChallengeTypes(t *testing.T) {
+	var conf ConfigAcme
+	conf.SetDefaultConf()
+	conf.Enabled = true
+	if err := conf.Check("./"); err != nil {
+		t.Fatalf("check config err: %s", err)
+	}
+
+	if len(conf.ChallengeTypes) != 2 || conf.ChallengeTypes[0] != AcmeChallengeTLSALPN01 ||
+		conf.ChallengeTypes[1] != AcmeChallengeHTTP01 {
+		t.Errorf("wrong ChallengeTypes: %v", conf.ChallengeTypes)
+	}
+}
+
+func TestConfAcmeLoadInvalid(t *testing.T) {
+	for _, confFile := range []string{
+		"testdata/conf_acme/bfe_2.conf",
+		"testdata/conf_acme/bfe_3.conf",
+	} {
+		if _, err := confAcmeLoad(confFile, "./"); err == nil {
+			t.Errorf("should found err while loading config %s", confFile)
+		}
+	}
+}
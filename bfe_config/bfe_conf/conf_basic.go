@@ -32,6 +32,8 @@ type ConfigBasic struct {
 	HttpsPort      int    // listen port for https
 	HttpAddr       string // listen address for http, default all interfaces
 	HttpsAddr      string // listen address for https, default all interfaces
+	Http3Port      int    // listen port for http3 over udp, 0 means disabled
+	Http3Addr      string // listen address for http3, default all interfaces
 	MonitorPort    int    // web server port for monitor
 	MonitorAddr    string // listen address for monitor, default all interfaces
 	MaxCpus        int    // number of max cpus to use
@@ -41,6 +43,9 @@ type ConfigBasic struct {
 	// settings of layer-4 load balancer
 	Layer4LoadBalancer string
 
+	// zone where bfe instance is deployed, used for zone aware balance
+	Zone string
+
 	// settings of communicate with http client
 	TlsHandshakeTimeout     int  // tls handshake timeout, in seconds
 	ClientReadTimeout       int  // read timeout, in seconds
@@ -144,6 +149,12 @@ func basicConfCheck(cfg *ConfigBasic) error {
 			cfg.HttpsPort)
 	}
 
+	// check Http3Port
+	if cfg.Http3Port < 0 || cfg.Http3Port > 65535 {
+		return fmt.Errorf("Http3Port[%d] should be in [0, 65535]",
+			cfg.Http3Port)
+	}
+
 	// check MonitorPort if MonitorEnabled enabled
 	if cfg.MonitorEnabled && (cfg.MonitorPort < 1 || cfg.MonitorPort > 65535) {
 		return fmt.Errorf("MonitorPort[%d] should be in [1, 65535]",
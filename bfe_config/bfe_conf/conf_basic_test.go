@@ -103,6 +103,7 @@ func Test_conf_basic_check(t *testing.T) {
 		conf *ConfigBasic
 		err  string
 	}{
+		{&ConfigBasic{HttpPort: 80, HttpsPort: 443, Http3Port: 65536}, "Http3Port[65536] should be in [0, 65535]"},
 		{&ConfigBasic{HttpPort: 80, HttpsPort: 443, MonitorPort: -1, MonitorEnabled: true}, "MonitorPort[-1] should be in [1, 65535]"},
 		{&ConfigBasic{HttpPort: 80, HttpsPort: 443, MonitorPort: 8080, MonitorEnabled: false, MaxCpus: -1}, "MaxCpus[-1] is too small"},
 		{&ConfigBasic{HttpPort: 80, HttpsPort: 443, MonitorPort: 8080, MonitorEnabled: true, MaxCpus: 10, TlsHandshakeTimeout: 30,
@@ -35,12 +35,15 @@ var TlsVersionMap = map[string]uint16{
 	"VersionTLS10": bfe_tls.VersionTLS10,
 	"VersionTLS11": bfe_tls.VersionTLS11,
 	"VersionTLS12": bfe_tls.VersionTLS12,
+	"VersionTLS13": bfe_tls.VersionTLS13,
 }
 
 var CurvesMap = map[string]bfe_tls.CurveID{
-	"CurveP256": bfe_tls.CurveP256,
-	"CurveP384": bfe_tls.CurveP384,
-	"CurveP521": bfe_tls.CurveP521,
+	"X25519MLKEM768": bfe_tls.X25519MLKEM768,
+	"X25519":         bfe_tls.X25519,
+	"CurveP256":      bfe_tls.CurveP256,
+	"CurveP384":      bfe_tls.CurveP384,
+	"CurveP521":      bfe_tls.CurveP521,
 }
 
 var CipherSuitesMap = map[string]uint16{
@@ -79,6 +82,8 @@ type ConfigHttpsBasic struct {
 
 	ClientCABaseDir  string // client root CAs base directory
 	ClientCRLBaseDir string // client cert CRL base directory
+
+	ClientRevocationTimeout int // timeout for fetching ocsp response or crl of client cert (ms)
 }
 
 // SetDefaultConf sets default value of ConfigHttpsBasic.
@@ -89,6 +94,7 @@ func (cfg *ConfigHttpsBasic) SetDefaultConf() {
 	cfg.EnableSslv2ClientHello = true
 	cfg.ClientCABaseDir = "tls_conf/client_ca"
 	cfg.ClientCRLBaseDir = "tls_conf/client_crl"
+	cfg.ClientRevocationTimeout = 3000
 }
 
 func (cfg *ConfigHttpsBasic) Check(confRoot string) error {
@@ -127,6 +133,19 @@ func (cfg *ConfigHttpsBasic) Check(confRoot string) error {
 		return err
 	}
 
+	err = clientRevocationConfCheck(cfg)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func clientRevocationConfCheck(cfg *ConfigHttpsBasic) error {
+	if cfg.ClientRevocationTimeout <= 0 {
+		cfg.ClientRevocationTimeout = 3000
+		log.Logger.Warn("ClientRevocationTimeout not set, use default value [%d]", cfg.ClientRevocationTimeout)
+	}
 	return nil
 }
 
@@ -180,6 +199,8 @@ func cipherSuitesCheck(cfg *ConfigHttpsBasic) error {
 func curvePreferencesCheck(cfg *ConfigHttpsBasic) error {
 	if len(cfg.CurvePreferences) == 0 {
 		cfg.CurvePreferences = []string{
+			"X25519MLKEM768",
+			"X25519",
 			"CurveP256",
 		}
 		log.Logger.Warn("CurvePreferences not set, use default value %v", cfg.CurvePreferences)
@@ -214,7 +235,7 @@ func clientCABaseDirCheck(cfg *ConfigHttpsBasic, confRoot string) error {
 
 func tlsVersionCheck(cfg *ConfigHttpsBasic) error {
 	if len(cfg.MaxTlsVersion) == 0 {
-		cfg.MaxTlsVersion = "VersionTLS12"
+		cfg.MaxTlsVersion = "VersionTLS13"
 	}
 	if len(cfg.MinTlsVersion) == 0 {
 		cfg.MinTlsVersion = "VersionSSL30"
@@ -281,7 +302,7 @@ func GetCipherSuites(cipherConf []string) ([]uint16, []uint16, error) {
 func GetTlsVersion(cfg *ConfigHttpsBasic) (maxVer, minVer uint16) {
 	maxTlsVersion, ok := TlsVersionMap[cfg.MaxTlsVersion]
 	if !ok {
-		maxTlsVersion = bfe_tls.VersionTLS12
+		maxTlsVersion = bfe_tls.VersionTLS13
 	}
 
 	minTlsVersion, ok := TlsVersionMap[cfg.MinTlsVersion]
@@ -22,6 +22,10 @@ import (
 	gcfg "gopkg.in/gcfg.v1"
 )
 
+import (
+	"github.com/bfenetworks/bfe/bfe_tls"
+)
+
 func confHttpsBasicLoad(filePath string, confRoot string) (BfeConfig, error) {
 	var cfg BfeConfig
 	var err error
@@ -82,3 +86,43 @@ func TestConfHttpsBasicLoad2(t *testing.T) {
 		}
 	}
 }
+
+func TestConfHttpsBasicTlsVersionDefault(t *testing.T) {
+	var cfg ConfigHttpsBasic
+	cfg.SetDefaultConf()
+	if err := tlsVersionCheck(&cfg); err != nil {
+		t.Fatalf("tlsVersionCheck err: %s", err)
+	}
+
+	maxVer, minVer := GetTlsVersion(&cfg)
+	if maxVer != bfe_tls.VersionTLS13 {
+		t.Errorf("wrong max tls version (expect %x, actual %x)", bfe_tls.VersionTLS13, maxVer)
+	}
+	if minVer != bfe_tls.VersionSSL30 {
+		t.Errorf("wrong min tls version (expect %x, actual %x)", bfe_tls.VersionSSL30, minVer)
+	}
+
+	if err := curvePreferencesCheck(&cfg); err != nil {
+		t.Fatalf("curvePreferencesCheck err: %s", err)
+	}
+	curves, err := GetCurvePreferences(cfg.CurvePreferences)
+	if err != nil {
+		t.Fatalf("GetCurvePreferences err: %s", err)
+	}
+	if len(curves) != 3 || curves[0] != bfe_tls.X25519MLKEM768 || curves[1] != bfe_tls.X25519 ||
+		curves[2] != bfe_tls.CurveP256 {
+		t.Errorf("wrong default curvePreferences: %v", curves)
+	}
+}
+
+func TestConfHttpsBasicClientRevocationTimeout(t *testing.T) {
+	conf, err := confHttpsBasicLoad("testdata/conf_https_basic/bfe_1.conf", "./")
+	if err != nil {
+		t.Fatalf("load config err: %s", err)
+	}
+
+	if conf.HttpsBasic.ClientRevocationTimeout != 3000 {
+		t.Errorf("wrong ClientRevocationTimeout (expect 3000, actual %d)",
+			conf.HttpsBasic.ClientRevocationTimeout)
+	}
+}
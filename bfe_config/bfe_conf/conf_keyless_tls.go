@@ -0,0 +1,105 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bfe_conf
+
+import (
+	"fmt"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_util"
+	"github.com/bfenetworks/bfe/bfe_util/keyless"
+)
+
+type ConfigKeylessTls struct {
+	// delegate private key operations of server certificates to key server or not
+	Enabled bool
+
+	// address of key server, e.g., "keyserver.example.org:8443"
+	Address string
+
+	// certificate and key for authenticating bfe to key server
+	ClientCertFile string
+	ClientKeyFile  string
+
+	// ca certificates for verifying key server (system roots if empty)
+	CACertFile string
+
+	// name for verifying certificate of key server (host in Address if empty)
+	ServerName string
+
+	// timeout for one private key operation (ms)
+	Timeout int
+
+	// max number of connections to key server
+	PoolSize int
+
+	// when local key of certificate is used instead of key server:
+	// "Disabled" (never) or "TransportError" (on connection failure or timeout)
+	FallbackPolicy string
+}
+
+func (cfg *ConfigKeylessTls) SetDefaultConf() {
+	cfg.Enabled = false
+	cfg.Timeout = 1000
+	cfg.PoolSize = 8
+	cfg.FallbackPolicy = keyless.FallbackDisabled
+}
+
+func (cfg *ConfigKeylessTls) Check(confRoot string) error {
+	if !cfg.Enabled {
+		return nil
+	}
+	return ConfKeylessTlsCheck(cfg, confRoot)
+}
+
+func ConfKeylessTlsCheck(cfg *ConfigKeylessTls, confRoot string) error {
+	// check Address
+	if len(cfg.Address) == 0 {
+		return fmt.Errorf("Address not set")
+	}
+
+	// check ClientCertFile and ClientKeyFile
+	if len(cfg.ClientCertFile) == 0 || len(cfg.ClientKeyFile) == 0 {
+		return fmt.Errorf("ClientCertFile or ClientKeyFile not set")
+	}
+	cfg.ClientCertFile = bfe_util.ConfPathProc(cfg.ClientCertFile, confRoot)
+	cfg.ClientKeyFile = bfe_util.ConfPathProc(cfg.ClientKeyFile, confRoot)
+
+	// check CACertFile
+	if len(cfg.CACertFile) > 0 {
+		cfg.CACertFile = bfe_util.ConfPathProc(cfg.CACertFile, confRoot)
+	}
+
+	// check Timeout
+	if cfg.Timeout <= 0 {
+		return fmt.Errorf("Timeout[%d] should > 0", cfg.Timeout)
+	}
+
+	// check PoolSize
+	if cfg.PoolSize <= 0 {
+		return fmt.Errorf("PoolSize[%d] should > 0", cfg.PoolSize)
+	}
+
+	// check FallbackPolicy
+	switch cfg.FallbackPolicy {
+	case keyless.FallbackDisabled, keyless.FallbackTransportError:
+	default:
+		return fmt.Errorf("FallbackPolicy[%s] should be %s or %s", cfg.FallbackPolicy,
+			keyless.FallbackDisabled, keyless.FallbackTransportError)
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bfe_conf
+
+import (
+	"testing"
+)
+
+import (
+	gcfg "gopkg.in/gcfg.v1"
+)
+
+func confKeylessTlsLoad(filePath string, confRoot string) (ConfigKeylessTls, error) {
+	var cfg BfeConfig
+	cfg.KeylessTls.SetDefaultConf()
+
+	// read config from file
+	if err := gcfg.ReadFileInto(&cfg, filePath); err != nil {
+		return cfg.KeylessTls, err
+	}
+
+	// check keyless tls conf
+	if err := cfg.KeylessTls.Check(confRoot); err != nil {
+		return cfg.KeylessTls, err
+	}
+
+	return cfg.KeylessTls, nil
+}
+
+func TestConfKeylessTlsLoad(t *testing.T) {
+	conf, err := confKeylessTlsLoad("testdata/conf_keyless_tls/bfe_1.conf", "/home/bfe/conf")
+	if err != nil {
+		t.Fatalf("load config err: %s", err)
+	}
+
+	if !conf.Enabled {
+		t.Errorf("Enabled should be true")
+	}
+	if conf.Address != "keyserver.example.org:8443" {
+		t.Errorf("wrong Address: %s", conf.Address)
+	}
+	if conf.ClientCertFile != "/home/bfe/conf/tls_conf/keyless/client.crt" {
+		t.Errorf("wrong ClientCertFile: %s", conf.ClientCertFile)
+	}
+	if conf.Timeout != 500 || conf.PoolSize != 8 {
+		t.Errorf("wrong Timeout/PoolSize: %d/%d", conf.Timeout, conf.PoolSize)
+	}
+	if conf.FallbackPolicy != "TransportError" {
+		t.Errorf("wrong FallbackPolicy: %s", conf.FallbackPolicy)
+	}
+}
+
+func TestConfKeylessTlsLoadInvalid(t *testing.T) {
+	for _, confFile := range []string{
+		"testdata/conf_keyless_tls/bfe_2.conf",
+		"testdata/conf_keyless_tls/bfe_3.conf",
+		"testdata/conf_keyless_tls/bfe_4.conf",
+	} {
+		if _, err := confKeylessTlsLoad(confFile, "./"); err == nil {
+			t.Errorf("should found err while loading config %s", confFile)
+		}
+	}
+}
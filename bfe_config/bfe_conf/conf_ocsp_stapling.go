@@ -0,0 +1,66 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bfe_conf
+
+import (
+	"fmt"
+)
+
+type ConfigOcspStapling struct {
+	// fetch ocsp responses for server certificates or not
+	Enabled bool
+
+	// timeout for fetching ocsp response (s)
+	FetchTimeout int
+
+	// interval for retrying after fetch failed (s)
+	RetryInterval int
+
+	// interval for refreshing ocsp response without next update time (s)
+	RefreshInterval int
+}
+
+func (cfg *ConfigOcspStapling) SetDefaultConf() {
+	cfg.Enabled = false
+	cfg.FetchTimeout = 10
+	cfg.RetryInterval = 300
+	cfg.RefreshInterval = 3600
+}
+
+func (cfg *ConfigOcspStapling) Check(confRoot string) error {
+	if !cfg.Enabled {
+		return nil
+	}
+	return ConfOcspStaplingCheck(cfg, confRoot)
+}
+
+func ConfOcspStaplingCheck(cfg *ConfigOcspStapling, confRoot string) error {
+	// check FetchTimeout
+	if cfg.FetchTimeout <= 0 {
+		return fmt.Errorf("FetchTimeout[%d] should > 0", cfg.FetchTimeout)
+	}
+
+	// check RetryInterval
+	if cfg.RetryInterval <= 0 {
+		return fmt.Errorf("RetryInterval[%d] should > 0", cfg.RetryInterval)
+	}
+
+	// check RefreshInterval
+	if cfg.RefreshInterval <= 0 {
+		return fmt.Errorf("RefreshInterval[%d] should > 0", cfg.RefreshInterval)
+	}
+
+	return nil
+}
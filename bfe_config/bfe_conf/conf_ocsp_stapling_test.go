@@ -0,0 +1,68 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bfe_conf
+
+import (
+	"testing"
+)
+
+import (
+	gcfg "gopkg.in/gcfg.v1"
+)
+
+func confOcspStaplingLoad(filePath string, confRoot string) (ConfigOcspStapling, error) {
+	var cfg BfeConfig
+	cfg.OcspStapling.SetDefaultConf()
+
+	// read config from file
+	if err := gcfg.ReadFileInto(&cfg, filePath); err != nil {
+		return cfg.OcspStapling, err
+	}
+
+	// check ocsp stapling conf
+	if err := cfg.OcspStapling.Check(confRoot); err != nil {
+		return cfg.OcspStapling, err
+	}
+
+	return cfg.OcspStapling, nil
+}
+
+func TestConfOcspStaplingLoad(t *testing.T) {
+	conf, err := confOcspStaplingLoad("testdata/conf_ocsp_stapling/bfe_1.conf", "./")
+	if err != nil {
+		t.Fatalf("load config err: %s", err)
+	}
+
+	if !conf.Enabled {
+		t.Errorf("Enabled should be true")
+	}
+	if conf.FetchTimeout != 5 || conf.RetryInterval != 60 {
+		t.Errorf("wrong FetchTimeout/RetryInterval: %d/%d", conf.FetchTimeout, conf.RetryInterval)
+	}
+	if conf.RefreshInterval != 3600 {
+		t.Errorf("wrong RefreshInterval: %d", conf.RefreshInterval)
+	}
+}
+
+func TestConfOcspStaplingLoadInvalid(t *testing.T) {
+	for _, confFile := range []string{
+		"testdata/conf_ocsp_stapling/bfe_2.conf",
+		"testdata/conf_ocsp_stapling/bfe_3.conf",
+	} {
+		if _, err := confOcspStaplingLoad(confFile, "./"); err == nil {
+			t.Errorf("should found err while loading config %s", confFile)
+		}
+	}
+}
@@ -14,6 +14,10 @@
 
 package bfe_conf
 
+import (
+	"fmt"
+)
+
 import (
 	"github.com/bfenetworks/go-lib/log"
 )
@@ -22,17 +26,38 @@ import (
 	"github.com/bfenetworks/bfe/bfe_util"
 )
 
+const (
+	TicketKeyRotationNone   = "NONE"   // use key in SessionTicketKeyFile
+	TicketKeyRotationRandom = "RANDOM" // generate random key periodically
+	TicketKeyRotationSeed   = "SEED"   // derive key from shared seed periodically
+)
+
 type ConfigSessionTicket struct {
 	// disable session cache or not
 	SessionTicketsDisabled bool
 
 	// session ticket key (in hex format)
 	SessionTicketKeyFile string
+
+	// rotation of session ticket key (NONE/RANDOM/SEED)
+	SessionTicketKeyRotation string
+
+	// interval for rotating session ticket key (s)
+	SessionTicketKeyRotateInterval int
+
+	// max number of session ticket keys, including the active key
+	SessionTicketKeyNum int
+
+	// shared seed for deriving session ticket keys (in hex format)
+	SessionTicketKeySeedFile string
 }
 
 func (cfg *ConfigSessionTicket) SetDefaultConf() {
 	cfg.SessionTicketsDisabled = true
 	cfg.SessionTicketKeyFile = "tls_conf/session_ticket_key.data"
+	cfg.SessionTicketKeyRotation = TicketKeyRotationNone
+	cfg.SessionTicketKeyRotateInterval = 3600
+	cfg.SessionTicketKeyNum = 3
 }
 
 func (cfg *ConfigSessionTicket) Check(confRoot string) error {
@@ -51,5 +76,26 @@ func ConfSessionTicketCheck(cfg *ConfigSessionTicket, confRoot string) error {
 	}
 	cfg.SessionTicketKeyFile = bfe_util.ConfPathProc(cfg.SessionTicketKeyFile, confRoot)
 
+	// check rotation of session ticket key
+	switch cfg.SessionTicketKeyRotation {
+	case TicketKeyRotationNone, TicketKeyRotationRandom:
+	case TicketKeyRotationSeed:
+		if cfg.SessionTicketKeySeedFile == "" {
+			return fmt.Errorf("SessionTicketKeySeedFile not set")
+		}
+		cfg.SessionTicketKeySeedFile = bfe_util.ConfPathProc(cfg.SessionTicketKeySeedFile, confRoot)
+	default:
+		return fmt.Errorf("SessionTicketKeyRotation[%s] should be NONE/RANDOM/SEED",
+			cfg.SessionTicketKeyRotation)
+	}
+
+	if cfg.SessionTicketKeyRotateInterval <= 0 {
+		return fmt.Errorf("SessionTicketKeyRotateInterval[%d] should > 0", cfg.SessionTicketKeyRotateInterval)
+	}
+
+	if cfg.SessionTicketKeyNum <= 0 {
+		return fmt.Errorf("SessionTicketKeyNum[%d] should > 0", cfg.SessionTicketKeyNum)
+	}
+
 	return nil
 }
@@ -26,6 +26,8 @@ func confSessionTicketLoad(filePath string, confRoot string) (BfeConfig, error)
 	var cfg BfeConfig
 	var err error
 
+	cfg.SessionTicket.SetDefaultConf()
+
 	// read config from file
 	err = gcfg.ReadFileInto(&cfg, filePath)
 	if err != nil {
@@ -67,3 +69,35 @@ func TestConfSessionTicketLoad2(t *testing.T) {
 		t.Errorf("should found err while loading config %s", confFile)
 	}
 }
+
+func TestConfSessionTicketLoadRotation(t *testing.T) {
+	conf, err := confSessionTicketLoad("testdata/conf_session_ticket/bfe_3.conf", "/home/bfe/conf")
+	if err != nil {
+		t.Fatalf("load config err: %s", err)
+	}
+	ticketConf := conf.SessionTicket
+
+	if ticketConf.SessionTicketKeyRotation != TicketKeyRotationSeed {
+		t.Errorf("wrong SessionTicketKeyRotation: %s", ticketConf.SessionTicketKeyRotation)
+	}
+	if ticketConf.SessionTicketKeyRotateInterval != 600 || ticketConf.SessionTicketKeyNum != 3 {
+		t.Errorf("wrong SessionTicketKeyRotateInterval/SessionTicketKeyNum: %d/%d",
+			ticketConf.SessionTicketKeyRotateInterval, ticketConf.SessionTicketKeyNum)
+	}
+	seedFileExpect := "/home/bfe/conf/tls_conf/session_ticket_seed.data"
+	if ticketConf.SessionTicketKeySeedFile != seedFileExpect {
+		t.Errorf("wrong SessionTicketKeySeedFile, expect %s, actual %s",
+			seedFileExpect, ticketConf.SessionTicketKeySeedFile)
+	}
+}
+
+func TestConfSessionTicketLoadRotationInvalid(t *testing.T) {
+	for _, confFile := range []string{
+		"testdata/conf_session_ticket/bfe_4.conf",
+		"testdata/conf_session_ticket/bfe_5.conf",
+	} {
+		if _, err := confSessionTicketLoad(confFile, "./"); err == nil {
+			t.Errorf("should found err while loading config %s", confFile)
+		}
+	}
+}
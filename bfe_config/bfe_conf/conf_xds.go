@@ -0,0 +1,98 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bfe_conf
+
+import (
+	"fmt"
+	"os"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_util"
+)
+
+type ConfigXds struct {
+	// enable dynamic config from xds server or not
+	Enabled bool
+
+	// address of xds server, e.g., "xds.example.org:18000"
+	Address string
+
+	// node id and cluster reported to xds server
+	NodeId      string
+	NodeCluster string
+
+	// connect xds server with tls or not
+	TLSEnabled bool
+
+	// ca certificates for verifying xds server (system roots if empty)
+	CACertFile string
+
+	// timeout for connecting xds server (ms)
+	ConnectTimeout int
+
+	// dir for config accepted from xds server
+	DataPath string
+}
+
+func (cfg *ConfigXds) SetDefaultConf() {
+	cfg.Enabled = false
+	cfg.ConnectTimeout = 3000
+	cfg.DataPath = "xds_data"
+}
+
+func (cfg *ConfigXds) Check(confRoot string) error {
+	if !cfg.Enabled {
+		return nil
+	}
+	return ConfXdsCheck(cfg, confRoot)
+}
+
+func ConfXdsCheck(cfg *ConfigXds, confRoot string) error {
+	// check Address
+	if len(cfg.Address) == 0 {
+		return fmt.Errorf("Address not set")
+	}
+
+	// check NodeId
+	if len(cfg.NodeId) == 0 {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("NodeId not set and get hostname err: %s", err)
+		}
+		cfg.NodeId = hostname
+	}
+
+	// check ConnectTimeout
+	if cfg.ConnectTimeout <= 0 {
+		return fmt.Errorf("ConnectTimeout[%d] should > 0", cfg.ConnectTimeout)
+	}
+
+	// check CACertFile
+	if len(cfg.CACertFile) > 0 {
+		if !cfg.TLSEnabled {
+			return fmt.Errorf("CACertFile should be used with TLSEnabled")
+		}
+		cfg.CACertFile = bfe_util.ConfPathProc(cfg.CACertFile, confRoot)
+	}
+
+	// check DataPath
+	if len(cfg.DataPath) == 0 {
+		return fmt.Errorf("DataPath not set")
+	}
+	cfg.DataPath = bfe_util.ConfPathProc(cfg.DataPath, confRoot)
+
+	return nil
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acme_conf
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_util/json"
+)
+
+// AcmeConf is conf of hostnames to obtain certificates from acme server.
+type AcmeConf struct {
+	Version string   // version of config
+	Hosts   []string // hostnames, one certificate for each
+}
+
+// AcmeConfCheck check integrity of config.
+func AcmeConfCheck(conf *AcmeConf) error {
+	if len(conf.Version) == 0 {
+		return fmt.Errorf("no Version")
+	}
+
+	hosts := make(map[string]bool, len(conf.Hosts))
+	for i, host := range conf.Hosts {
+		if err := hostCheck(host); err != nil {
+			return fmt.Errorf("Hosts[%d] %s", i, err)
+		}
+
+		host = strings.ToLower(host)
+		if hosts[host] {
+			return fmt.Errorf("Hosts[%d] duplicated host %s", i, host)
+		}
+		hosts[host] = true
+		conf.Hosts[i] = host
+	}
+
+	return nil
+}
+
+func hostCheck(host string) error {
+	if len(host) == 0 {
+		return fmt.Errorf("empty host")
+	}
+	// Note: wildcard certificate requires dns-01 challenge, which is not supported
+	if strings.Contains(host, "*") {
+		return fmt.Errorf("wildcard host %s not supported", host)
+	}
+	if strings.ContainsAny(host, ":/ ") {
+		return fmt.Errorf("invalid host %s", host)
+	}
+	return nil
+}
+
+// AcmeConfLoad loads config of acme from file.
+func AcmeConfLoad(filename string) (AcmeConf, error) {
+	var config AcmeConf
+
+	// open the file
+	file, err := os.Open(filename)
+	if err != nil {
+		return config, err
+	}
+	defer file.Close()
+
+	// decode the file
+	decoder := json.NewDecoder(file)
+	if err := decoder.Decode(&config); err != nil {
+		return config, err
+	}
+
+	// check conf
+	if err := AcmeConfCheck(&config); err != nil {
+		return config, err
+	}
+
+	return config, nil
+}
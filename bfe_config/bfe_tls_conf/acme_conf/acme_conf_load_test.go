@@ -0,0 +1,47 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acme_conf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAcmeConfLoad(t *testing.T) {
+	conf, err := AcmeConfLoad("./testdata/acme_conf.data")
+	if err != nil {
+		t.Fatalf("found err while loading conf: %s", err)
+	}
+
+	if conf.Version != "20260101000000" {
+		t.Errorf("wrong version: %s", conf.Version)
+	}
+	hostsExpect := []string{"example.org", "www.example.org"}
+	if !reflect.DeepEqual(conf.Hosts, hostsExpect) {
+		t.Errorf("wrong hosts (expect: %v, actual: %v)", hostsExpect, conf.Hosts)
+	}
+}
+
+func TestAcmeConfLoadInvalid(t *testing.T) {
+	for _, filename := range []string{
+		"./testdata/acme_conf.data2",
+		"./testdata/acme_conf.data3",
+		"./testdata/acme_conf.data4",
+	} {
+		if _, err := AcmeConfLoad(filename); err == nil {
+			t.Errorf("should found err while loading conf %s", filename)
+		}
+	}
+}
@@ -15,7 +15,9 @@
 package server_cert_conf
 
 import (
+	"crypto"
 	"crypto/x509"
+	"encoding/pem"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -30,6 +32,7 @@ import (
 	"github.com/bfenetworks/bfe/bfe_tls"
 	"github.com/bfenetworks/bfe/bfe_util"
 	"github.com/bfenetworks/bfe/bfe_util/json"
+	"github.com/bfenetworks/bfe/bfe_util/keyless"
 )
 
 const (
@@ -40,6 +43,7 @@ const (
 type ServerCertConf struct {
 	ServerCertFile   string // path to server certificate
 	ServerKeyFile    string // path to server priavet key
+	ServerKeyRef     string // reference of server private key on key server (keyless tls)
 	OcspResponseFile string // path to ocsp response file
 }
 
@@ -66,12 +70,16 @@ func (conf *ServerCertConf) Check(confRoot string) error {
 	}
 
 	// check ServerKeyFile
-	if len(conf.ServerKeyFile) == 0 {
-		return fmt.Errorf("no ServerKeyFile")
+	// Note: if ServerKeyRef specified, ServerKeyFile is optional and used as
+	// fallback when key server fails
+	if len(conf.ServerKeyFile) == 0 && len(conf.ServerKeyRef) == 0 {
+		return fmt.Errorf("no ServerKeyFile or ServerKeyRef")
 	}
-	conf.ServerKeyFile = bfe_util.ConfPathProc(conf.ServerKeyFile, confRoot)
-	if _, err := os.Stat(conf.ServerKeyFile); os.IsNotExist(err) {
-		return fmt.Errorf("server key not exist: %s", conf.ServerKeyFile)
+	if len(conf.ServerKeyFile) > 0 {
+		conf.ServerKeyFile = bfe_util.ConfPathProc(conf.ServerKeyFile, confRoot)
+		if _, err := os.Stat(conf.ServerKeyFile); os.IsNotExist(err) {
+			return fmt.Errorf("server key not exist: %s", conf.ServerKeyFile)
+		}
 	}
 
 	// check OcspResponseFile
@@ -179,16 +187,67 @@ func OcspResponseLoad(filename string) ([]byte, error) {
 	return ocspResponse, nil
 }
 
-func ServerCertParse(certConf BfeServerCertConf) (map[string]*bfe_tls.Certificate, error) {
+// RemoteKeyCertLoad loads certificate with private key on key server. If
+// ServerKeyFile is also specified, the local key is used as fallback
+// according to fallback policy of keyClient.
+func RemoteKeyCertLoad(conf ServerCertConf, keyClient *keyless.Client) (bfe_tls.Certificate, error) {
+	if keyClient == nil {
+		return bfe_tls.Certificate{}, fmt.Errorf("keyless tls not enabled")
+	}
+
+	// load local key for fallback
+	var fallback crypto.Signer
+	if len(conf.ServerKeyFile) != 0 {
+		localCert, err := bfe_tls.LoadX509KeyPair(conf.ServerCertFile, conf.ServerKeyFile)
+		if err != nil {
+			return bfe_tls.Certificate{}, err
+		}
+		var ok bool
+		if fallback, ok = localCert.PrivateKey.(crypto.Signer); !ok {
+			return bfe_tls.Certificate{}, fmt.Errorf("unsupported server key: %s", conf.ServerKeyFile)
+		}
+	}
+
+	// get public key from leaf certificate
+	certPEMBlock, err := ioutil.ReadFile(conf.ServerCertFile)
+	if err != nil {
+		return bfe_tls.Certificate{}, err
+	}
+	block, _ := pem.Decode(certPEMBlock)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return bfe_tls.Certificate{}, fmt.Errorf("failed to parse certificate PEM data")
+	}
+	x509Cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return bfe_tls.Certificate{}, err
+	}
+
+	key := keyClient.NewKey(conf.ServerKeyRef, x509Cert.PublicKey, fallback)
+	return bfe_tls.X509CertWithKey(certPEMBlock, key)
+}
+
+// ServerCertParse loads certificates in certConf. keyClient is client of key
+// server for certificates with ServerKeyRef, and may be nil if keyless tls
+// is not enabled.
+func ServerCertParse(certConf BfeServerCertConf, keyClient *keyless.Client) (map[string]*bfe_tls.Certificate, error) {
 	certMap := make(map[string]*bfe_tls.Certificate)
 
 	for name, conf := range certConf.Config.CertConf {
 		var cert bfe_tls.Certificate
+		var err error
 
-		// load x509 certificate and key
-		cert, err := bfe_tls.LoadX509KeyPair(conf.ServerCertFile, conf.ServerKeyFile)
-		if err != nil {
-			return nil, fmt.Errorf("in LoadX509KeyPair() :%s", err.Error())
+		if len(conf.ServerKeyRef) != 0 {
+			// load x509 certificate with key on key server
+			cert, err = RemoteKeyCertLoad(conf, keyClient)
+			if err != nil {
+				return nil, fmt.Errorf("in RemoteKeyCertLoad() for %s :%s", name, err.Error())
+			}
+		} else {
+			// load x509 certificate and key
+			cert, err = bfe_tls.LoadX509KeyPair(conf.ServerCertFile, conf.ServerKeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("in LoadX509KeyPair() :%s", err.Error())
+			}
 		}
 
 		// load ocsp response for stapling
@@ -0,0 +1,130 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server_cert_conf
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_tls"
+	"github.com/bfenetworks/bfe/bfe_util/keyless"
+)
+
+func TestServerCertConfLoadKeyRef(t *testing.T) {
+	if _, err := ServerCertConfLoad("testdata/server_cert_conf.data2", "testdata"); err == nil {
+		t.Errorf("should fail without ServerKeyFile and ServerKeyRef")
+	}
+
+	conf, err := ServerCertConfLoad("testdata/server_cert_conf.data", "testdata")
+	if err != nil {
+		t.Fatalf("ServerCertConfLoad(): %s", err)
+	}
+
+	// keyless tls not enabled
+	if _, err := ServerCertParse(conf, nil); err == nil {
+		t.Errorf("ServerCertParse() should fail without key server client")
+	}
+
+	// key server not available
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	client := keyless.NewClient(keyless.Config{Address: addr, Timeout: time.Second,
+		FallbackPolicy: keyless.FallbackTransportError})
+	certMap, err := ServerCertParse(conf, client)
+	if err != nil {
+		t.Fatalf("ServerCertParse(): %s", err)
+	}
+	if err := testSign(certMap, "example.org"); err == nil {
+		t.Errorf("Sign() should fail without key server")
+	}
+	if err := testSign(certMap, "fallback.example.org"); err != nil {
+		t.Errorf("Sign() should succeed with fallback key: %s", err)
+	}
+
+	// fallback is disabled by default
+	client = keyless.NewClient(keyless.Config{Address: addr, Timeout: time.Second})
+	certMap, err = ServerCertParse(conf, client)
+	if err != nil {
+		t.Fatalf("ServerCertParse(): %s", err)
+	}
+	if err := testSign(certMap, "fallback.example.org"); err == nil {
+		t.Errorf("Sign() should not use fallback key by default")
+	}
+}
+
+func TestServerCertConfLoadKeyRefServerError(t *testing.T) {
+	conf, err := ServerCertConfLoad("testdata/server_cert_conf.data", "testdata")
+	if err != nil {
+		t.Fatalf("ServerCertConfLoad(): %s", err)
+	}
+
+	// key server without key example.org
+	cert, err := tls.LoadX509KeyPair("testdata/example.crt", "testdata/example.key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM, err := os.ReadFile("testdata/example.crt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(certPEM)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go keyless.NewServer().Serve(ln, &tls.Config{Certificates: []tls.Certificate{cert}, ClientCAs: pool})
+
+	client := keyless.NewClient(keyless.Config{
+		Address: ln.Addr().String(),
+		// Note: test certificate has no subject alternative name
+		TLSConfig:      &tls.Config{Certificates: []tls.Certificate{cert}, InsecureSkipVerify: true},
+		Timeout:        time.Second,
+		FallbackPolicy: keyless.FallbackTransportError,
+	})
+	certMap, err := ServerCertParse(conf, client)
+	if err != nil {
+		t.Fatalf("ServerCertParse(): %s", err)
+	}
+
+	// error replied by key server never falls back
+	err = testSign(certMap, "fallback.example.org")
+	if err == nil || keyless.IsTransportError(err) {
+		t.Errorf("Sign() should fail with key server error: %v", err)
+	}
+}
+
+func testSign(certMap map[string]*bfe_tls.Certificate, name string) error {
+	digest := sha256.Sum256([]byte("hello"))
+	signer := certMap[name].PrivateKey.(crypto.Signer)
+	_, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	return err
+}
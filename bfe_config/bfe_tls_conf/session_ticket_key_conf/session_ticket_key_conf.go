@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -28,7 +29,8 @@ import (
 )
 
 const (
-	RawSessionTicketKeySize = 48 // bytes
+	RawSessionTicketKeySize  = 48 // bytes
+	MinSessionTicketSeedSize = 32 // bytes
 )
 
 // SessionTicketKeyConf is session ticket key config.
@@ -101,3 +103,22 @@ func SessionTicketKeyConfLoad(filename string) (SessionTicketKeyConf, error) {
 
 	return config, nil
 }
+
+// SessionTicketKeySeedLoad loads shared seed for deriving session ticket keys
+// from file (hex encode).
+func SessionTicketKeySeedLoad(filename string) ([]byte, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	seed, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("session ticket key seed %s", err.Error())
+	}
+	if len(seed) < MinSessionTicketSeedSize {
+		return nil, fmt.Errorf("session ticket key seed should be at least %d bytes", MinSessionTicketSeedSize)
+	}
+
+	return seed, nil
+}
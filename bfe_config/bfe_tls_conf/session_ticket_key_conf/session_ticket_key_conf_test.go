@@ -48,3 +48,18 @@ func TestSessionTicketKeyConfLoad2(t *testing.T) {
 		t.Errorf("shuold found err while loading conf")
 	}
 }
+
+func TestSessionTicketKeySeedLoad(t *testing.T) {
+	seed, err := SessionTicketKeySeedLoad("./testdata/session_ticket_seed.data")
+	if err != nil {
+		t.Fatalf("found err while loading seed: %s", err.Error())
+	}
+	if len(seed) != 32 || seed[0] != 0x5a || seed[31] != 0x0f {
+		t.Errorf("wrong seed: %x", seed)
+	}
+
+	_, err = SessionTicketKeySeedLoad("./testdata/session_ticket_seed.data2")
+	if err == nil {
+		t.Errorf("should found err while loading short seed")
+	}
+}
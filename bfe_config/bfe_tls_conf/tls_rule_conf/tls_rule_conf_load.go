@@ -68,6 +68,17 @@ import (
 //
 // Notes about`ClientCAName`:
 //  * The CA certificate file is <ClientCAName>.crt under ClientCABaseDir configured in bfe.conf
+//
+// Notes about `CurvePreferences`:
+//  * CurvePreferences represents an optional list of key exchange groups in preference order
+//  * If not configured, CurvePreferences in bfe.conf is used
+//
+// Notes about `ClientRevocation`:
+//  * ClientRevocation represents an optional map from ClientCAName to online revocation
+//    checking for client certificates, besides CRLs under ClientCRLBaseDir
+//  * Mode should be OCSP, CRL or OCSP_CRL (CRL is used if OCSP status is unknown)
+//  * FailPolicy should be SOFT_FAIL (accept certificate if status is unknown, by default)
+//    or HARD_FAIL (reject certificate if status is unknown)
 
 // application level protocols over tls
 const (
@@ -79,6 +90,19 @@ const (
 
 var validNextProtos = []string{HTTP11, HTTP2, SPDY31, STREAM}
 
+// modes of online revocation checking for client certificates
+const (
+	RevocationModeOCSP    = "OCSP"     // check by ocsp responder
+	RevocationModeCRL     = "CRL"      // check by crl distribution point
+	RevocationModeOCSPCRL = "OCSP_CRL" // check by ocsp responder, then crl distribution point
+)
+
+// policies if revocation status of client certificate is unknown
+const (
+	RevocationSoftFail = "SOFT_FAIL" // accept certificate
+	RevocationHardFail = "HARD_FAIL" // reject certificate
+)
+
 // negotiation level for protocols
 const (
 	PROTO_OPTIONAL    = 0 // proto is negotiatory, may be disabled if needed
@@ -96,10 +120,19 @@ type TlsRuleConf struct {
 	ClientCAName  string   // client CA certificate name
 	Chacha20      bool     // enable chacha20-poly1305 cipher suites
 	DynamicRecord bool     // enable dynamic record size
+
+	CurvePreferences []string // curve preferences for key exchange (optional)
 }
 
 type TlsRuleMap map[string]*TlsRuleConf // product -> pointer to tls rule conf
 
+type ClientRevocationConf struct {
+	Mode       string // OCSP, CRL or OCSP_CRL
+	FailPolicy string // SOFT_FAIL or HARD_FAIL
+}
+
+type ClientRevocationMap map[string]*ClientRevocationConf // client CA name -> revocation conf
+
 const (
 	ProxyProtocolDisabled  = 0
 	ProxyProtocolV1Enabled = 1
@@ -130,6 +163,7 @@ type BfeTlsRuleConf struct {
 	DefaultNextProtos    []string
 	DefaultChacha20      bool
 	DefaultDynamicRecord bool
+	ClientRevocation     ClientRevocationMap // optional
 }
 
 func TlsRuleConfCheck(conf *TlsRuleConf) error {
@@ -150,6 +184,10 @@ func TlsRuleConfCheck(conf *TlsRuleConf) error {
 		return fmt.Errorf("ClientAuth enabled, but ClientCAName is empty")
 	}
 
+	if _, err := bfe_conf.GetCurvePreferences(conf.CurvePreferences); err != nil {
+		return fmt.Errorf("invalid CurvePreferences: %s", err)
+	}
+
 	for i, vip := range conf.VipConf {
 		vaddr := net.ParseIP(vip)
 		if vaddr == nil {
@@ -387,6 +425,44 @@ func BfeTlsRuleConfCheck(conf *BfeTlsRuleConf) error {
 		return err
 	}
 
+	if err := checkClientRevocation(conf.ClientRevocation, conf.Config); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func checkClientRevocation(revocationMap ClientRevocationMap, ruleMap TlsRuleMap) error {
+	clientCANames := make(map[string]bool)
+	for _, rule := range ruleMap {
+		if rule.ClientAuth {
+			clientCANames[rule.ClientCAName] = true
+		}
+	}
+
+	for clientCAName, conf := range revocationMap {
+		if conf == nil {
+			return fmt.Errorf("ClientRevocation[%s] is nil", clientCAName)
+		}
+		if !clientCANames[clientCAName] {
+			return fmt.Errorf("ClientRevocation[%s] not used by any rule with ClientAuth", clientCAName)
+		}
+
+		switch conf.Mode {
+		case RevocationModeOCSP, RevocationModeCRL, RevocationModeOCSPCRL:
+		default:
+			return fmt.Errorf("ClientRevocation[%s] invalid Mode[%s]", clientCAName, conf.Mode)
+		}
+
+		switch conf.FailPolicy {
+		case "":
+			conf.FailPolicy = RevocationSoftFail
+		case RevocationSoftFail, RevocationHardFail:
+		default:
+			return fmt.Errorf("ClientRevocation[%s] invalid FailPolicy[%s]", clientCAName, conf.FailPolicy)
+		}
+	}
+
 	return nil
 }
 
@@ -540,6 +616,22 @@ func ClientCRLLoad(clientCAMap map[string]*x509.CertPool, clientCRLDir string) (
 	return clientCRLPoolMap, nil
 }
 
+// ClientRevocationLoad creates online revocation checkers for client CAs.
+func ClientRevocationLoad(revocationMap ClientRevocationMap,
+	fetcher *bfe_tls.RevocationFetcher) map[string]*bfe_tls.RevocationChecker {
+	checkerMap := make(map[string]*bfe_tls.RevocationChecker)
+	for clientCAName, conf := range revocationMap {
+		checkerMap[clientCAName] = &bfe_tls.RevocationChecker{
+			Fetcher:  fetcher,
+			OCSP:     conf.Mode == RevocationModeOCSP || conf.Mode == RevocationModeOCSPCRL,
+			CRL:      conf.Mode == RevocationModeCRL || conf.Mode == RevocationModeOCSPCRL,
+			HardFail: conf.FailPolicy == RevocationHardFail,
+		}
+	}
+
+	return checkerMap
+}
+
 // TlsRuleConfLoad load config of rule from file.
 func TlsRuleConfLoad(filename string) (BfeTlsRuleConf, error) {
 	var config BfeTlsRuleConf
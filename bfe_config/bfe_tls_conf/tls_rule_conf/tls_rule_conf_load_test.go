@@ -194,3 +194,61 @@ func TestTlsRuleConfLoad6(t *testing.T) {
 		t.Errorf("config expect %v, actual %v", confExpect, confActual)
 	}
 }
+
+func TestTlsRuleConfLoad7(t *testing.T) {
+	file := "./testdata/tls_rule.data13"
+
+	conf, err := TlsRuleConfLoad(file)
+	if err != nil {
+		t.Fatalf("should have no error, not %v", err)
+	}
+
+	expect := []string{"X25519MLKEM768", "X25519"}
+	if !reflect.DeepEqual(conf.Config["pb"].CurvePreferences, expect) {
+		t.Errorf("CurvePreferences expect %v, actual %v", expect, conf.Config["pb"].CurvePreferences)
+	}
+	if len(conf.Config["pn"].CurvePreferences) != 0 {
+		t.Errorf("CurvePreferences should be empty, not %v", conf.Config["pn"].CurvePreferences)
+	}
+
+	file = "./testdata/tls_rule.data14"
+	if _, err := TlsRuleConfLoad(file); err == nil {
+		t.Errorf("should found err while loading config %s", file)
+	}
+}
+
+func TestTlsRuleConfLoadClientRevocation(t *testing.T) {
+	conf, err := TlsRuleConfLoad("./testdata/tls_rule.data15")
+	if err != nil {
+		t.Fatalf("should have no error, not %v", err)
+	}
+
+	expect := ClientRevocationMap{
+		"pa": {Mode: RevocationModeOCSPCRL, FailPolicy: RevocationHardFail},
+	}
+	if !reflect.DeepEqual(conf.ClientRevocation, expect) {
+		t.Errorf("ClientRevocation expect %v, actual %v", expect, conf.ClientRevocation)
+	}
+
+	// invalid Mode, unknown client CA name and invalid FailPolicy
+	for _, file := range []string{
+		"./testdata/tls_rule.data16",
+		"./testdata/tls_rule.data17",
+		"./testdata/tls_rule.data18",
+	} {
+		if _, err := TlsRuleConfLoad(file); err == nil {
+			t.Errorf("should found err while loading config %s", file)
+		}
+	}
+}
+
+func TestTlsRuleConfLoadClientRevocationDefaultPolicy(t *testing.T) {
+	conf := ClientRevocationMap{"pa": {Mode: RevocationModeOCSP}}
+	ruleMap := TlsRuleMap{"pa": {ClientAuth: true, ClientCAName: "pa"}}
+	if err := checkClientRevocation(conf, ruleMap); err != nil {
+		t.Fatalf("should have no error, not %v", err)
+	}
+	if conf["pa"].FailPolicy != RevocationSoftFail {
+		t.Errorf("FailPolicy should be %s, not %s", RevocationSoftFail, conf["pa"].FailPolicy)
+	}
+}
@@ -0,0 +1,243 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// conversion of envoy cluster and endpoint resources
+//
+// Fields of envoy Cluster with equivalents in cluster_conf are converted, and
+// other fields of cluster_conf.ClusterConf may be set in filter metadata of
+// the Cluster under MetadataKey. Converted fields take priority over the
+// ones in metadata.
+
+package bfe_xds_conf
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+import (
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_config/bfe_cluster_conf/cluster_conf"
+	"github.com/bfenetworks/bfe/bfe_config/bfe_cluster_conf/cluster_table_conf"
+	"github.com/bfenetworks/bfe/bfe_util/json"
+)
+
+// MetadataKey is key of filter metadata in envoy Cluster, for fields of
+// cluster_conf.ClusterConf without equivalents in envoy Cluster.
+const MetadataKey = "bfe"
+
+// clusterConfFromEnvoy converts envoy Cluster to cluster conf.
+func clusterConfFromEnvoy(name string, data []byte) (cluster_conf.ClusterConf, error) {
+	var conf cluster_conf.ClusterConf
+
+	cluster := new(clusterv3.Cluster)
+	if err := proto.Unmarshal(data, cluster); err != nil {
+		return conf, err
+	}
+	if cluster.GetName() != name {
+		return conf, fmt.Errorf("cluster name %s mismatch", cluster.GetName())
+	}
+
+	if metadata := cluster.GetMetadata().GetFilterMetadata()[MetadataKey]; metadata != nil {
+		data, err := protojson.Marshal(metadata)
+		if err != nil {
+			return conf, err
+		}
+		if err := json.Unmarshal(data, &conf); err != nil {
+			return conf, fmt.Errorf("metadata %s: %s", MetadataKey, err)
+		}
+	}
+	if conf.BackendConf == nil {
+		conf.BackendConf = new(cluster_conf.BackendBasic)
+	}
+	if conf.ClusterBasic == nil {
+		conf.ClusterBasic = new(cluster_conf.ClusterBasicConf)
+	}
+
+	setInt(&conf.BackendConf.TimeoutConnSrv, msecOf(cluster.GetConnectTimeout()))
+
+	for _, threshold := range cluster.GetCircuitBreakers().GetThresholds() {
+		if threshold.GetPriority() != corev3.RoutingPriority_DEFAULT {
+			continue
+		}
+		setInt(&conf.ClusterBasic.MaxRequests, intOf(threshold.GetMaxRequests()))
+		setInt(&conf.ClusterBasic.MaxPendingRequests, intOf(threshold.GetMaxPendingRequests()))
+		setInt(&conf.ClusterBasic.MaxRetries, intOf(threshold.GetMaxRetries()))
+	}
+
+	if od := cluster.GetOutlierDetection(); od != nil {
+		if conf.OutlierDetection == nil {
+			conf.OutlierDetection = new(cluster_conf.OutlierDetectionConf)
+		}
+		o := conf.OutlierDetection
+		setInt(&o.Consecutive5xx, intOf(od.GetConsecutive_5Xx()))
+		setInt(&o.ConsecutiveGatewayFailure, intOf(od.GetConsecutiveGatewayFailure()))
+		setInt(&o.Interval, msecOf(od.GetInterval()))
+		setInt(&o.BaseEjectionTime, msecOf(od.GetBaseEjectionTime()))
+		setInt(&o.MaxEjectionTime, msecOf(od.GetMaxEjectionTime()))
+		setInt(&o.MaxEjectionPercent, intOf(od.GetMaxEjectionPercent()))
+		setInt(&o.SuccessRateMinimumHosts, intOf(od.GetSuccessRateMinimumHosts()))
+		setInt(&o.SuccessRateRequestVolume, intOf(od.GetSuccessRateRequestVolume()))
+		setInt(&o.SuccessRateStdevFactor, intOf(od.GetSuccessRateStdevFactor()))
+	}
+
+	if err := checkConfFromEnvoy(&conf, cluster.GetHealthChecks()); err != nil {
+		return conf, err
+	}
+
+	return conf, nil
+}
+
+// checkConfFromEnvoy converts health check of envoy Cluster to CheckConf.
+func checkConfFromEnvoy(conf *cluster_conf.ClusterConf, healthChecks []*corev3.HealthCheck) error {
+	if len(healthChecks) == 0 {
+		return nil
+	}
+	if len(healthChecks) > 1 {
+		return fmt.Errorf("only one health check is supported")
+	}
+
+	if conf.CheckConf == nil {
+		conf.CheckConf = new(cluster_conf.BackendCheck)
+	}
+	check := conf.CheckConf
+	hc := healthChecks[0]
+
+	switch checker := hc.GetHealthChecker().(type) {
+	case *corev3.HealthCheck_HttpHealthCheck_:
+		// Note: Schem in metadata is kept, e.g. https or h2
+		if check.Schem == nil {
+			setString(&check.Schem, "http")
+		}
+		setString(&check.Uri, checker.HttpHealthCheck.GetPath())
+		setString(&check.Host, checker.HttpHealthCheck.GetHost())
+	case *corev3.HealthCheck_TcpHealthCheck_:
+		setString(&check.Schem, "tcp")
+	case *corev3.HealthCheck_GrpcHealthCheck_:
+		setString(&check.Schem, "grpc")
+		service := checker.GrpcHealthCheck.GetServiceName()
+		check.GRPCServiceName = &service
+	default:
+		return fmt.Errorf("unsupported health checker %T", checker)
+	}
+
+	setInt(&check.CheckTimeout, msecOf(hc.GetTimeout()))
+	setInt(&check.CheckInterval, msecOf(hc.GetInterval()))
+	setInt(&check.FailNum, intOf(hc.GetUnhealthyThreshold()))
+	setInt(&check.SuccNum, intOf(hc.GetHealthyThreshold()))
+	return nil
+}
+
+// clusterBackendFromEnvoy converts envoy ClusterLoadAssignment to backends of
+// cluster. Endpoints are grouped into sub-clusters by sub_zone (or zone if
+// sub_zone is empty) of their locality.
+//
+// Note: health status of endpoints is ignored, backends are checked by
+// health check of bfe
+func clusterBackendFromEnvoy(name string, data []byte) (cluster_table_conf.ClusterBackend, error) {
+	cla := new(endpointv3.ClusterLoadAssignment)
+	if err := proto.Unmarshal(data, cla); err != nil {
+		return nil, err
+	}
+	if cla.GetClusterName() != name {
+		return nil, fmt.Errorf("cluster name %s mismatch", cla.GetClusterName())
+	}
+
+	conf := make(cluster_table_conf.ClusterBackend)
+	for _, endpoints := range cla.GetEndpoints() {
+		subCluster := endpoints.GetLocality().GetSubZone()
+		if len(subCluster) == 0 {
+			subCluster = endpoints.GetLocality().GetZone()
+		}
+		if len(subCluster) == 0 {
+			return nil, fmt.Errorf("no sub_zone or zone in locality")
+		}
+
+		for _, lbEndpoint := range endpoints.GetLbEndpoints() {
+			backend, err := backendFromEnvoy(lbEndpoint)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s", subCluster, err)
+			}
+			conf[subCluster] = append(conf[subCluster], backend)
+		}
+	}
+	return conf, nil
+}
+
+func backendFromEnvoy(lbEndpoint *endpointv3.LbEndpoint) (*cluster_table_conf.BackendConf, error) {
+	endpoint := lbEndpoint.GetEndpoint()
+	socketAddr := endpoint.GetAddress().GetSocketAddress()
+	if socketAddr == nil {
+		return nil, fmt.Errorf("no socket address in endpoint")
+	}
+
+	addr := socketAddr.GetAddress()
+	port := int(socketAddr.GetPortValue())
+	name := endpoint.GetHostname()
+	if len(name) == 0 {
+		name = net.JoinHostPort(addr, strconv.Itoa(port))
+	}
+	weight := 1
+	if w := lbEndpoint.GetLoadBalancingWeight(); w != nil {
+		weight = int(w.GetValue())
+	}
+
+	backend := &cluster_table_conf.BackendConf{Name: &name, Port: &port, Weight: &weight}
+	if net.ParseIP(addr) != nil {
+		backend.Addr = &addr
+	} else {
+		// backend discovered by dns
+		backend.Domain = &addr
+	}
+	return backend, nil
+}
+
+func intOf(v *wrapperspb.UInt32Value) *int {
+	if v == nil {
+		return nil
+	}
+	i := int(v.GetValue())
+	return &i
+}
+
+func msecOf(d *durationpb.Duration) *int {
+	if d == nil {
+		return nil
+	}
+	i := int(d.AsDuration() / time.Millisecond)
+	return &i
+}
+
+func setInt(dst **int, v *int) {
+	if v != nil {
+		*dst = v
+	}
+}
+
+func setString(dst **string, v string) {
+	if len(v) > 0 {
+		*dst = &v
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bfe_xds_conf
+
+import (
+	"testing"
+	"time"
+)
+
+import (
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const testClusterMetadata = `{
+	"BackendConf": {"TimeoutResponseHeader": 50000},
+	"CheckConf": {"StatusCode": 200},
+	"GslbBasic": {"CrossRetry": 0, "RetryMax": 2},
+	"ClusterBasic": {"TimeoutReadClient": 30000}
+}`
+
+// newTestCluster returns envoy Cluster with bfe fields in metadata.
+func newTestCluster(t *testing.T, name string, metadata string) *clusterv3.Cluster {
+	cluster := &clusterv3.Cluster{
+		Name:           name,
+		ConnectTimeout: durationpb.New(2 * time.Second),
+		HealthChecks: []*corev3.HealthCheck{{
+			Timeout:            durationpb.New(time.Second),
+			Interval:           durationpb.New(3 * time.Second),
+			UnhealthyThreshold: wrapperspb.UInt32(5),
+			HealthyThreshold:   wrapperspb.UInt32(1),
+			HealthChecker: &corev3.HealthCheck_HttpHealthCheck_{
+				HttpHealthCheck: &corev3.HealthCheck_HttpHealthCheck{Path: "/healthcheck"},
+			},
+		}},
+	}
+	if len(metadata) > 0 {
+		fields := new(structpb.Struct)
+		if err := protojson.Unmarshal([]byte(metadata), fields); err != nil {
+			t.Fatalf("protojson.Unmarshal(): %s", err)
+		}
+		cluster.Metadata = &corev3.Metadata{
+			FilterMetadata: map[string]*structpb.Struct{MetadataKey: fields},
+		}
+	}
+	return cluster
+}
+
+// newTestClusterLoadAssignment returns envoy ClusterLoadAssignment with one
+// endpoint in sub-cluster sub1.
+func newTestClusterLoadAssignment(name string, addr string) *endpointv3.ClusterLoadAssignment {
+	return &endpointv3.ClusterLoadAssignment{
+		ClusterName: name,
+		Endpoints: []*endpointv3.LocalityLbEndpoints{{
+			Locality: &corev3.Locality{Zone: "zone1", SubZone: "sub1"},
+			LbEndpoints: []*endpointv3.LbEndpoint{{
+				HostIdentifier: &endpointv3.LbEndpoint_Endpoint{
+					Endpoint: &endpointv3.Endpoint{
+						Address: &corev3.Address{
+							Address: &corev3.Address_SocketAddress{
+								SocketAddress: &corev3.SocketAddress{
+									Address:       addr,
+									PortSpecifier: &corev3.SocketAddress_PortValue{PortValue: 8080},
+								},
+							},
+						},
+					},
+				},
+				LoadBalancingWeight: wrapperspb.UInt32(10),
+			}},
+		}},
+	}
+}
+
+func marshalTestResource(t *testing.T, m proto.Message) []byte {
+	data, err := proto.Marshal(m)
+	if err != nil {
+		t.Fatalf("proto.Marshal(): %s", err)
+	}
+	return data
+}
+
+func TestClusterConfFromEnvoy(t *testing.T) {
+	cluster := newTestCluster(t, "cluster1", testClusterMetadata)
+	cluster.CircuitBreakers = &clusterv3.CircuitBreakers{
+		Thresholds: []*clusterv3.CircuitBreakers_Thresholds{
+			{Priority: corev3.RoutingPriority_HIGH, MaxRequests: wrapperspb.UInt32(1)},
+			{Priority: corev3.RoutingPriority_DEFAULT, MaxRequests: wrapperspb.UInt32(100)},
+		},
+	}
+	cluster.OutlierDetection = &clusterv3.OutlierDetection{
+		Consecutive_5Xx:  wrapperspb.UInt32(3),
+		BaseEjectionTime: durationpb.New(30 * time.Second),
+	}
+
+	conf, err := clusterConfFromEnvoy("cluster1", marshalTestResource(t, cluster))
+	if err != nil {
+		t.Fatalf("clusterConfFromEnvoy(): %s", err)
+	}
+	if *conf.BackendConf.TimeoutConnSrv != 2000 || *conf.BackendConf.TimeoutResponseHeader != 50000 {
+		t.Errorf("unexpected backend conf: %+v", conf.BackendConf)
+	}
+	check := conf.CheckConf
+	if *check.Schem != "http" || *check.Uri != "/healthcheck" || *check.StatusCode != 200 ||
+		*check.CheckTimeout != 1000 || *check.CheckInterval != 3000 || *check.FailNum != 5 || *check.SuccNum != 1 {
+		t.Errorf("unexpected check conf: %+v", check)
+	}
+	if *conf.ClusterBasic.MaxRequests != 100 || *conf.ClusterBasic.TimeoutReadClient != 30000 {
+		t.Errorf("unexpected cluster basic: %+v", conf.ClusterBasic)
+	}
+	if *conf.OutlierDetection.Consecutive5xx != 3 || *conf.OutlierDetection.BaseEjectionTime != 30000 {
+		t.Errorf("unexpected outlier detection: %+v", conf.OutlierDetection)
+	}
+
+	// name mismatch
+	if _, err := clusterConfFromEnvoy("cluster2", marshalTestResource(t, cluster)); err == nil {
+		t.Errorf("cluster with mismatched name should be rejected")
+	}
+
+	// more than one health check
+	cluster.HealthChecks = append(cluster.HealthChecks, cluster.HealthChecks[0])
+	if _, err := clusterConfFromEnvoy("cluster1", marshalTestResource(t, cluster)); err == nil {
+		t.Errorf("cluster with two health checks should be rejected")
+	}
+}
+
+func TestClusterBackendFromEnvoy(t *testing.T) {
+	cla := newTestClusterLoadAssignment("cluster1", "10.0.0.1")
+	cla.Endpoints = append(cla.Endpoints, &endpointv3.LocalityLbEndpoints{
+		Locality: &corev3.Locality{Zone: "zone2"},
+		LbEndpoints: []*endpointv3.LbEndpoint{{
+			HostIdentifier: &endpointv3.LbEndpoint_Endpoint{
+				Endpoint: &endpointv3.Endpoint{
+					Hostname: "b",
+					Address: &corev3.Address{
+						Address: &corev3.Address_SocketAddress{
+							SocketAddress: &corev3.SocketAddress{
+								Address:       "backend.example.org",
+								PortSpecifier: &corev3.SocketAddress_PortValue{PortValue: 80},
+							},
+						},
+					},
+				},
+			},
+		}},
+	})
+
+	conf, err := clusterBackendFromEnvoy("cluster1", marshalTestResource(t, cla))
+	if err != nil {
+		t.Fatalf("clusterBackendFromEnvoy(): %s", err)
+	}
+
+	backend := conf["sub1"][0]
+	if *backend.Name != "10.0.0.1:8080" || *backend.Addr != "10.0.0.1" || *backend.Port != 8080 ||
+		*backend.Weight != 10 || backend.Domain != nil {
+		t.Errorf("unexpected backend in sub1: %+v", backend)
+	}
+	backend = conf["zone2"][0]
+	if *backend.Name != "b" || *backend.Domain != "backend.example.org" || *backend.Weight != 1 ||
+		backend.Addr != nil {
+		t.Errorf("unexpected backend in zone2: %+v", backend)
+	}
+
+	// locality without zone
+	cla.Endpoints[1].Locality = nil
+	if _, err := clusterBackendFromEnvoy("cluster1", marshalTestResource(t, cla)); err == nil {
+		t.Errorf("endpoints without zone should be rejected")
+	}
+}
@@ -0,0 +1,370 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// resources of dynamic config from xds server
+//
+// Each resource is for one cluster or product, identified by type url and
+// resource name. Clusters and endpoints are envoy resources, and others are
+// JSON documents since they have no equivalents in envoy:
+//   - ClusterConf:  cluster name => envoy Cluster
+//   - ClusterTable: cluster name => envoy ClusterLoadAssignment
+//   - Gslb:         cluster name => gslb_conf.GslbClusterConf
+//   - Host:         product name => HostResource
+//   - Route:        product name => RouteResource
+
+package bfe_xds_conf
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_config/bfe_cluster_conf/cluster_conf"
+	"github.com/bfenetworks/bfe/bfe_config/bfe_cluster_conf/cluster_table_conf"
+	"github.com/bfenetworks/bfe/bfe_config/bfe_cluster_conf/gslb_conf"
+	"github.com/bfenetworks/bfe/bfe_config/bfe_route_conf/host_rule_conf"
+	"github.com/bfenetworks/bfe/bfe_config/bfe_route_conf/route_rule_conf"
+	"github.com/bfenetworks/bfe/bfe_util/json"
+)
+
+const (
+	envoyTypeURLPrefix = "type.googleapis.com/envoy."
+	typeURLPrefix      = "type.bfe-networks.net/bfe."
+)
+
+// type urls of resources
+const (
+	TypeClusterConf  = envoyTypeURLPrefix + "config.cluster.v3.Cluster"
+	TypeClusterTable = envoyTypeURLPrefix + "config.endpoint.v3.ClusterLoadAssignment"
+	TypeGslb         = typeURLPrefix + "Gslb"
+	TypeHost         = typeURLPrefix + "Host"
+	TypeRoute        = typeURLPrefix + "Route"
+)
+
+// TypeURLs are type urls of all resources, in the order of subscribing.
+var TypeURLs = []string{
+	TypeClusterTable,
+	TypeGslb,
+	TypeClusterConf,
+	TypeRoute,
+	TypeHost,
+}
+
+// names of config files dumped from snapshot
+const (
+	ClusterConfFile  = "cluster_conf.data"
+	ClusterTableFile = "cluster_table.data"
+	GslbFile         = "gslb.data"
+	HostRuleFile     = "host_rule.data"
+	RouteRuleFile    = "route_rule.data"
+)
+
+// HostResource is host conf of a product.
+type HostResource struct {
+	Hosts   *host_rule_conf.HostTagToHost // host-tag => hosts
+	Default bool                          // whether the product is default product
+}
+
+// RouteResource is route rules of a product.
+type RouteResource struct {
+	BasicRule   route_rule_conf.BasicRouteRuleFiles
+	ProductRule route_rule_conf.AdvancedRouteRuleFiles
+}
+
+// Snapshot holds all resources from xds server.
+type Snapshot struct {
+	Version       string
+	ClusterConfs  map[string]cluster_conf.ClusterConf
+	ClusterTables map[string]cluster_table_conf.ClusterBackend
+	Gslbs         map[string]gslb_conf.GslbClusterConf
+	Hosts         map[string]HostResource
+	Routes        map[string]RouteResource
+}
+
+func NewSnapshot() *Snapshot {
+	s := new(Snapshot)
+	s.ClusterConfs = make(map[string]cluster_conf.ClusterConf)
+	s.ClusterTables = make(map[string]cluster_table_conf.ClusterBackend)
+	s.Gslbs = make(map[string]gslb_conf.GslbClusterConf)
+	s.Hosts = make(map[string]HostResource)
+	s.Routes = make(map[string]RouteResource)
+	return s
+}
+
+// Clone returns a copy of snapshot. Resources are shared since they are
+// never modified after set.
+func (s *Snapshot) Clone() *Snapshot {
+	c := NewSnapshot()
+	c.Version = s.Version
+	for name, conf := range s.ClusterConfs {
+		c.ClusterConfs[name] = conf
+	}
+	for name, conf := range s.ClusterTables {
+		c.ClusterTables[name] = conf
+	}
+	for name, conf := range s.Gslbs {
+		c.Gslbs[name] = conf
+	}
+	for name, conf := range s.Hosts {
+		c.Hosts[name] = conf
+	}
+	for name, conf := range s.Routes {
+		c.Routes[name] = conf
+	}
+	return c
+}
+
+// SetResource decodes and checks resource, then adds it to snapshot.
+func (s *Snapshot) SetResource(typeURL string, name string, data []byte) error {
+	if len(name) == 0 {
+		return fmt.Errorf("no resource name")
+	}
+
+	switch typeURL {
+	case TypeClusterConf:
+		conf, err := clusterConfFromEnvoy(name, data)
+		if err != nil {
+			return err
+		}
+		if err := cluster_conf.ClusterConfCheck(&conf); err != nil {
+			return err
+		}
+		s.ClusterConfs[name] = conf
+
+	case TypeClusterTable:
+		conf, err := clusterBackendFromEnvoy(name, data)
+		if err != nil {
+			return err
+		}
+		for subName, subConf := range conf {
+			if err := subConf.Check(); err != nil {
+				return fmt.Errorf("%s %s", subName, err)
+			}
+		}
+		s.ClusterTables[name] = conf
+
+	case TypeGslb:
+		var conf gslb_conf.GslbClusterConf
+		if err := json.Unmarshal(data, &conf); err != nil {
+			return err
+		}
+		if err := conf.Check(); err != nil {
+			return err
+		}
+		s.Gslbs[name] = conf
+
+	case TypeHost:
+		var conf HostResource
+		if err := json.Unmarshal(data, &conf); err != nil {
+			return err
+		}
+		if err := hostResourceCheck(name, conf); err != nil {
+			return err
+		}
+		s.Hosts[name] = conf
+
+	case TypeRoute:
+		var conf RouteResource
+		if err := json.Unmarshal(data, &conf); err != nil {
+			return err
+		}
+		if err := routeResourceCheck(name, conf); err != nil {
+			return err
+		}
+		s.Routes[name] = conf
+
+	default:
+		return fmt.Errorf("unknown type url: %s", typeURL)
+	}
+
+	return nil
+}
+
+// RemoveResource removes resource from snapshot.
+func (s *Snapshot) RemoveResource(typeURL string, name string) error {
+	switch typeURL {
+	case TypeClusterConf:
+		delete(s.ClusterConfs, name)
+	case TypeClusterTable:
+		delete(s.ClusterTables, name)
+	case TypeGslb:
+		delete(s.Gslbs, name)
+	case TypeHost:
+		delete(s.Hosts, name)
+	case TypeRoute:
+		delete(s.Routes, name)
+	default:
+		return fmt.Errorf("unknown type url: %s", typeURL)
+	}
+	return nil
+}
+
+func hostResourceCheck(product string, conf HostResource) error {
+	if conf.Hosts == nil {
+		return fmt.Errorf("no Hosts")
+	}
+
+	version := ""
+	hostTags := make(host_rule_conf.ProductToHostTag)
+	hostTags[product] = hostTagsOf(conf.Hosts)
+	return host_rule_conf.HostTableConfCheck(host_rule_conf.HostTableConf{
+		Version:  &version,
+		Hosts:    conf.Hosts,
+		HostTags: &hostTags,
+	})
+}
+
+func hostTagsOf(hosts *host_rule_conf.HostTagToHost) *host_rule_conf.HostTagList {
+	tags := make(host_rule_conf.HostTagList, 0, len(*hosts))
+	for tag := range *hosts {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return &tags
+}
+
+func routeResourceCheck(product string, conf RouteResource) error {
+	version := ""
+	basicRule := route_rule_conf.ProductBasicRouteRuleFile{product: conf.BasicRule}
+	productRule := route_rule_conf.ProductAdvancedRouteRuleFile{product: conf.ProductRule}
+	_, err := route_rule_conf.Convert(&route_rule_conf.RouteTableFile{
+		Version:     &version,
+		BasicRule:   &basicRule,
+		ProductRule: &productRule,
+	})
+	return err
+}
+
+// Check checks dependency among resources in snapshot.
+func (s *Snapshot) Check() error {
+	for name := range s.ClusterConfs {
+		if _, ok := s.Gslbs[name]; !ok {
+			return fmt.Errorf("cluster[%s] in ClusterConf should exist in Gslb", name)
+		}
+	}
+
+	for name := range s.Gslbs {
+		if _, ok := s.ClusterTables[name]; !ok {
+			return fmt.Errorf("cluster[%s] in Gslb should exist in ClusterTable", name)
+		}
+	}
+
+	defaultProduct := ""
+	for product, conf := range s.Hosts {
+		if !conf.Default {
+			continue
+		}
+		if len(defaultProduct) > 0 {
+			return fmt.Errorf("more than one default product: %s, %s", defaultProduct, product)
+		}
+		defaultProduct = product
+	}
+
+	return nil
+}
+
+// ClusterConf returns content of cluster_conf.data.
+func (s *Snapshot) ClusterConf() cluster_conf.BfeClusterConf {
+	version := s.Version
+	config := make(cluster_conf.ClusterToConf, len(s.ClusterConfs))
+	for name, conf := range s.ClusterConfs {
+		config[name] = conf
+	}
+	return cluster_conf.BfeClusterConf{Version: &version, Config: &config}
+}
+
+// ClusterTable returns content of cluster_table.data.
+func (s *Snapshot) ClusterTable() cluster_table_conf.ClusterTableConf {
+	version := s.Version
+	config := make(cluster_table_conf.AllClusterBackend, len(s.ClusterTables))
+	for name, conf := range s.ClusterTables {
+		config[name] = conf
+	}
+	return cluster_table_conf.ClusterTableConf{Version: &version, Config: &config}
+}
+
+// Gslb returns content of gslb.data.
+func (s *Snapshot) Gslb() gslb_conf.GslbConf {
+	hostname := "xds"
+	ts := s.Version
+	clusters := make(gslb_conf.GslbClustersConf, len(s.Gslbs))
+	for name, conf := range s.Gslbs {
+		clusters[name] = conf
+	}
+	return gslb_conf.GslbConf{Clusters: &clusters, Hostname: &hostname, Ts: &ts}
+}
+
+// HostTable returns content of host_rule.data.
+func (s *Snapshot) HostTable() host_rule_conf.HostTableConf {
+	version := s.Version
+	hosts := make(host_rule_conf.HostTagToHost)
+	hostTags := make(host_rule_conf.ProductToHostTag, len(s.Hosts))
+	conf := host_rule_conf.HostTableConf{Version: &version, Hosts: &hosts, HostTags: &hostTags}
+
+	for product, res := range s.Hosts {
+		for tag, hostnames := range *res.Hosts {
+			hosts[tag] = hostnames
+		}
+		hostTags[product] = hostTagsOf(res.Hosts)
+		if res.Default {
+			defaultProduct := product
+			conf.DefaultProduct = &defaultProduct
+		}
+	}
+	return conf
+}
+
+// RouteTable returns content of route_rule.data.
+func (s *Snapshot) RouteTable() route_rule_conf.RouteTableFile {
+	version := s.Version
+	basicRule := make(route_rule_conf.ProductBasicRouteRuleFile, len(s.Routes))
+	productRule := make(route_rule_conf.ProductAdvancedRouteRuleFile, len(s.Routes))
+	for product, res := range s.Routes {
+		if res.BasicRule != nil {
+			basicRule[product] = res.BasicRule
+		}
+		if res.ProductRule != nil {
+			productRule[product] = res.ProductRule
+		}
+	}
+	return route_rule_conf.RouteTableFile{Version: &version, BasicRule: &basicRule, ProductRule: &productRule}
+}
+
+// Dump writes config files of snapshot to dir.
+func (s *Snapshot) Dump(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	files := map[string]interface{}{
+		ClusterConfFile:  s.ClusterConf(),
+		ClusterTableFile: s.ClusterTable(),
+		GslbFile:         s.Gslb(),
+		HostRuleFile:     s.HostTable(),
+		RouteRuleFile:    s.RouteTable(),
+	}
+	for filename, conf := range files {
+		data, err := json.MarshalIndent(conf, "", "    ")
+		if err != nil {
+			return fmt.Errorf("marshal %s: %s", filename, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, filename), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bfe_xds_conf
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_config/bfe_cluster_conf/cluster_conf"
+	"github.com/bfenetworks/bfe/bfe_config/bfe_cluster_conf/cluster_table_conf"
+	"github.com/bfenetworks/bfe/bfe_config/bfe_cluster_conf/gslb_conf"
+	"github.com/bfenetworks/bfe/bfe_config/bfe_route_conf/host_rule_conf"
+	"github.com/bfenetworks/bfe/bfe_config/bfe_route_conf/route_rule_conf"
+)
+
+const (
+	testGslb  = `{"GSLB_BLACKHOLE": 0, "sub1": 100}`
+	testHost  = `{"Hosts": {"tag1": ["example.org"]}, "Default": true}`
+	testRoute = `{"ProductRule": [{"Cond": "default_t()", "ClusterName": "cluster1"}]}`
+)
+
+func newTestSnapshot(t *testing.T) *Snapshot {
+	s := NewSnapshot()
+	resources := []struct {
+		TypeURL string
+		Name    string
+		Data    []byte
+	}{
+		{TypeClusterConf, "cluster1", marshalTestResource(t, newTestCluster(t, "cluster1", testClusterMetadata))},
+		{TypeClusterTable, "cluster1", marshalTestResource(t, newTestClusterLoadAssignment("cluster1", "10.0.0.1"))},
+		{TypeGslb, "cluster1", []byte(testGslb)},
+		{TypeHost, "product1", []byte(testHost)},
+		{TypeRoute, "product1", []byte(testRoute)},
+	}
+	for _, r := range resources {
+		if err := s.SetResource(r.TypeURL, r.Name, r.Data); err != nil {
+			t.Fatalf("SetResource(%s, %s): %s", r.TypeURL, r.Name, err)
+		}
+	}
+	s.Version = "20260101000000"
+	return s
+}
+
+func TestSetResourceInvalid(t *testing.T) {
+	tests := []struct {
+		TypeURL string
+		Name    string
+		Data    []byte
+	}{
+		{TypeClusterConf, "", marshalTestResource(t, newTestCluster(t, "cluster1", testClusterMetadata))},
+		{TypeClusterConf, "cluster1", marshalTestResource(t, newTestCluster(t, "cluster1", `{"BackendConf": {"Protocol": "ftp"}}`))},
+		{TypeClusterConf, "cluster1", []byte(`{"BackendConf": {}}`)},
+		{TypeClusterTable, "cluster1", marshalTestResource(t, newTestClusterLoadAssignment("cluster1", ""))},
+		{TypeGslb, "cluster1", []byte(`{"sub1": -1}`)},
+		{TypeHost, "product1", []byte(`{"Default": true}`)},
+		{TypeRoute, "product1", []byte(`{"ProductRule": [{"Cond": "unknown()", "ClusterName": "cluster1"}]}`)},
+		{TypeRoute, "product1", []byte(`[]`)},
+		{"type.example.org/unknown", "name", []byte(`{}`)},
+	}
+
+	s := NewSnapshot()
+	for i, tt := range tests {
+		if err := s.SetResource(tt.TypeURL, tt.Name, tt.Data); err == nil {
+			t.Errorf("case %d expect error", i)
+		}
+	}
+}
+
+func TestSnapshotCheck(t *testing.T) {
+	s := newTestSnapshot(t)
+	if err := s.Check(); err != nil {
+		t.Fatalf("Check(): %s", err)
+	}
+
+	// cluster without gslb
+	c := s.Clone()
+	c.RemoveResource(TypeGslb, "cluster1")
+	if err := c.Check(); err == nil {
+		t.Errorf("cluster without gslb should be invalid")
+	}
+
+	// gslb without cluster table
+	c = s.Clone()
+	c.RemoveResource(TypeClusterTable, "cluster1")
+	if err := c.Check(); err == nil {
+		t.Errorf("gslb without cluster table should be invalid")
+	}
+
+	// more than one default product
+	c = s.Clone()
+	c.SetResource(TypeHost, "product2", []byte(`{"Hosts": {"tag2": ["example.com"]}, "Default": true}`))
+	if err := c.Check(); err == nil {
+		t.Errorf("more than one default product should be invalid")
+	}
+
+	// clone should not affect original
+	if len(s.Gslbs) != 1 || len(s.ClusterTables) != 1 || len(s.Hosts) != 1 {
+		t.Errorf("snapshot should not be modified by its clone")
+	}
+}
+
+func TestSnapshotDump(t *testing.T) {
+	s := newTestSnapshot(t)
+	dir := t.TempDir()
+	if err := s.Dump(dir); err != nil {
+		t.Fatalf("Dump(): %s", err)
+	}
+
+	// config files should be loaded by file based loaders
+	clusterConf, err := cluster_conf.ClusterConfLoad(filepath.Join(dir, ClusterConfFile))
+	if err != nil {
+		t.Fatalf("ClusterConfLoad(): %s", err)
+	}
+	if _, ok := (*clusterConf.Config)["cluster1"]; !ok || *clusterConf.Version != s.Version {
+		t.Errorf("unexpected cluster conf")
+	}
+
+	if _, err := cluster_table_conf.ClusterTableLoad(filepath.Join(dir, ClusterTableFile)); err != nil {
+		t.Errorf("ClusterTableLoad(): %s", err)
+	}
+	if _, err := gslb_conf.GslbConfLoad(filepath.Join(dir, GslbFile)); err != nil {
+		t.Errorf("GslbConfLoad(): %s", err)
+	}
+	if _, err := route_rule_conf.RouteConfLoad(filepath.Join(dir, RouteRuleFile)); err != nil {
+		t.Errorf("RouteConfLoad(): %s", err)
+	}
+
+	hostConf, err := host_rule_conf.HostRuleConfLoad(filepath.Join(dir, HostRuleFile))
+	if err != nil {
+		t.Fatalf("HostRuleConfLoad(): %s", err)
+	}
+	if hostConf.DefaultProduct != "product1" || hostConf.HostMap["example.org"] != "tag1" {
+		t.Errorf("unexpected host conf: %+v", hostConf)
+	}
+}
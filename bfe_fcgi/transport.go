@@ -57,8 +57,12 @@ func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 		}
 	}
 
+	// abort request by closing connection if request is canceled
+	stop := http.WatchCancel(req, client.Close)
+
 	reader, err := client.Do(metaData, req.Body)
 	if err != nil {
+		stop()
 		return nil, WriteRequestError{
 			Err: err,
 		}
@@ -66,13 +70,28 @@ func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 
 	rsp, err := readResponse(reader, req)
 	if err != nil {
+		stop()
 		return nil, ReadRespHeaderError{
 			Err: err,
 		}
 	}
+	rsp.Body = &stopBody{ReadCloser: rsp.Body, stop: stop}
 	return rsp, nil
 }
 
+// stopBody is response body which stops watching cancel of request when
+// closed.
+type stopBody struct {
+	io.ReadCloser
+	stop func()
+}
+
+func (b *stopBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.stop()
+	return err
+}
+
 func buildMetaValsAndMethod(r *http.Request, root string, envVars map[string]string) {
 	ip, port := r.RemoteAddr, ""
 	if idx := strings.LastIndex(r.RemoteAddr, ":"); idx > -1 {
@@ -354,6 +354,34 @@ func (e TransportBrokenError) Error() string {
 	return "TransportBrokenError: transport closed before response was received"
 }
 
+type RequestCanceledError struct{}
+
+func (e RequestCanceledError) Error() string {
+	return "RequestCanceledError: request canceled"
+}
+
+// WatchCancel calls onCancel if req is canceled (see Request.Cancel) before
+// the returned stop func is called. stop may be called more than once.
+func WatchCancel(req *Request, onCancel func()) (stop func()) {
+	if req.Cancel == nil {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-req.Cancel:
+			onCancel()
+		case <-done:
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
 type FlowLimiter interface {
 	// AcceptConn check whether current connection should be accept or not
 	AcceptConn() bool
@@ -213,6 +213,12 @@ type Request struct {
 	// This field is ignored by the HTTP client.
 	TLS *bfe_tls.ConnectionState
 
+	// Cancel is an optional channel whose closure indicates that the client
+	// request should be regarded as canceled. Not all implementations of
+	// RoundTripper may support Cancel.
+	// This field is ignored by the HTTP server.
+	Cancel <-chan struct{}
+
 	// State allows HTTP server and other software to record
 	// information about the request. This filed may be not filled.
 	State *RequestState
@@ -243,6 +249,9 @@ type RequestState struct {
 	BodySize uint32
 
 	H2Fingerprint string
+
+	// H2FingerprintHash is md5 hash (in hex) of H2Fingerprint.
+	H2FingerprintHash string
 }
 
 // ProtoAtLeast reports whether the HTTP protocol used
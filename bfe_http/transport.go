@@ -951,7 +951,12 @@ func (pc *persistConn) readLoop() {
 		// Wait for the just-returned response body to be fully consumed
 		// before we race and peek on the underlying bufio reader.
 		if waitForBodyRead != nil {
-			alive = <-waitForBodyRead
+			select {
+			case alive = <-waitForBodyRead:
+			case <-rc.req.Cancel:
+				alive = false
+				pc.t.CancelRequest(rc.req)
+			}
 		}
 
 		pc.t.setReqConn(rc.req, nil)
@@ -1067,9 +1072,14 @@ func (pc *persistConn) roundTrip(req *transportRequest) (resp *Response, err err
 	var pconnDeadCh = pc.closech
 	var failTicker <-chan time.Time
 	var respHeaderTicker <-chan time.Time
+	var cancelc = req.Request.Cancel
 WaitResponse:
 	for {
 		select {
+		case <-cancelc:
+			pc.close()
+			re = responseAndError{err: RequestCanceledError{}}
+			break WaitResponse
 		case err := <-writeErrCh:
 			if err != nil {
 				re = responseAndError{nil, err}
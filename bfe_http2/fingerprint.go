@@ -16,6 +16,8 @@ package bfe_http2
 
 import (
 	"bytes"
+	"crypto/md5"
+	"encoding/hex"
 	"fmt"
 	"strings"
 	"sync"
@@ -32,8 +34,9 @@ type fingerprint struct {
 	priorities    []string
 	pseudoHeaders []byte
 
-	// the final value of the fingerprint.
+	// the final value of the fingerprint, and its md5 hash in hex.
 	value string
+	hash  string
 }
 
 func newFingerprint() *fingerprint {
@@ -170,6 +173,8 @@ func (fp *fingerprint) Calculate() string {
 
 	fp.calculated = true
 	fp.value = buf.String()
+	sum := md5.Sum([]byte(fp.value))
+	fp.hash = hex.EncodeToString(sum[:])
 	return fp.value
 }
 
@@ -183,3 +188,13 @@ func (fp *fingerprint) Get() string {
 
 	return fp.Calculate()
 }
+
+// GetHash returns md5 hash of the fingerprint in hex. Unlike the fingerprint,
+// it could be matched in lists concatenated with '|'.
+func (fp *fingerprint) GetHash() string {
+	fp.Get()
+
+	fp.lock.RLock()
+	defer fp.lock.RUnlock()
+	return fp.hash
+}
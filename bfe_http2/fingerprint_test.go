@@ -326,3 +326,13 @@ func TestNewFingerprintWithFakeBrowsers(t *testing.T) {
 		}
 	}
 }
+
+func TestFingerprintGetHash(t *testing.T) {
+	fp := newFingerprint()
+	if got, want := fp.GetHash(), "4420df39710ed13773d86b9de54c4cdf"; got != want {
+		t.Errorf("GetHash result = %s; want %s", got, want)
+	}
+	if got, want := fp.Get(), "|00|0|"; got != want {
+		t.Errorf("Get result = %s; want %s", got, want)
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// gRPC deadline propagation for backend requests
+//
+// Deadline of request to backend is derived from grpc-timeout header, so
+// stream to backend is reset once the deadline of gRPC client passes.
+
+package bfe_http2
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_http"
+)
+
+const grpcTimeoutHeader = "Grpc-Timeout"
+
+// maxGrpcTimeoutValue is max value of grpc-timeout (at most 8 digits).
+const maxGrpcTimeoutValue = 99999999
+
+var grpcTimeoutUnits = []struct {
+	unit byte
+	d    time.Duration
+}{
+	{'n', time.Nanosecond},
+	{'u', time.Microsecond},
+	{'m', time.Millisecond},
+	{'S', time.Second},
+	{'M', time.Minute},
+	{'H', time.Hour},
+}
+
+// ParseGrpcTimeout parses value of grpc-timeout header, e.g. "100m".
+func ParseGrpcTimeout(s string) (time.Duration, error) {
+	if len(s) < 2 || len(s) > 9 {
+		return 0, fmt.Errorf("invalid grpc-timeout %q", s)
+	}
+
+	unit := s[len(s)-1]
+	value, err := strconv.ParseInt(s[:len(s)-1], 10, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid grpc-timeout %q", s)
+	}
+
+	for _, u := range grpcTimeoutUnits {
+		if u.unit != unit {
+			continue
+		}
+		if value > int64(math.MaxInt64/u.d) {
+			return math.MaxInt64, nil
+		}
+		return time.Duration(value) * u.d, nil
+	}
+	return 0, fmt.Errorf("invalid grpc-timeout unit %q", s)
+}
+
+// EncodeGrpcTimeout encodes timeout as value of grpc-timeout header, with
+// the finest unit which fits in 8 digits.
+func EncodeGrpcTimeout(timeout time.Duration) string {
+	if timeout <= 0 {
+		return "0n"
+	}
+	for _, u := range grpcTimeoutUnits {
+		value := timeout / u.d
+		if timeout%u.d != 0 {
+			// round up, to avoid the deadline being earlier than expected
+			value++
+		}
+		if value <= maxGrpcTimeoutValue {
+			return strconv.FormatInt(int64(value), 10) + string(u.unit)
+		}
+	}
+	return strconv.Itoa(maxGrpcTimeoutValue) + "H"
+}
+
+// grpcDeadline sets deadline of request by its grpc-timeout header. The
+// returned cancel func is nil if there is no grpc-timeout.
+func grpcDeadline(req *http.Request) (*http.Request, context.CancelFunc) {
+	value := req.Header.Get(grpcTimeoutHeader)
+	if value == "" {
+		return req, nil
+	}
+	timeout, err := ParseGrpcTimeout(value)
+	if err != nil {
+		return req, nil
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	return req.WithContext(ctx), cancel
+}
+
+// grpcRoundTrip sends request by rt, within deadline of grpc-timeout header.
+// The request is aborted if it is canceled by caller.
+func grpcRoundTrip(rt func(*http.Request) (*http.Response, error), r *bfe_http.Request) (
+	*bfe_http.Response, error) {
+	req, cancel := grpcDeadline(toHTTPRequest(r))
+	req, cancel = requestCancel(req, cancel, r)
+
+	res, err := rt(req)
+	if err != nil {
+		if cancel != nil {
+			switch req.Context().Err() {
+			case context.DeadlineExceeded:
+				err = bfe_http.RespHeaderTimeoutError{}
+			case context.Canceled:
+				err = bfe_http.RequestCanceledError{}
+			}
+			cancel()
+		}
+		return nil, err
+	}
+
+	if cancel != nil {
+		// deadline is kept until response body is finished
+		res.Body = &cancelBody{ReadCloser: res.Body, cancel: cancel}
+	}
+	return toBfeResponse(res, r), nil
+}
+
+// requestCancel cancels context of req if r is canceled (see
+// bfe_http.Request.Cancel). The returned cancel func also calls the given one.
+func requestCancel(req *http.Request, cancel context.CancelFunc,
+	r *bfe_http.Request) (*http.Request, context.CancelFunc) {
+	if r.Cancel == nil {
+		return req, cancel
+	}
+
+	ctx, cancelCtx := context.WithCancel(req.Context())
+	stop := bfe_http.WatchCancel(r, cancelCtx)
+	return req.WithContext(ctx), func() {
+		stop()
+		cancelCtx()
+		if cancel != nil {
+			cancel()
+		}
+	}
+}
+
+// cancelBody is response body which cancels context of request when closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bfe_http2
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_http"
+)
+
+func TestParseGrpcTimeout(t *testing.T) {
+	cases := []struct {
+		value   string
+		timeout time.Duration
+		ok      bool
+	}{
+		{"100m", 100 * time.Millisecond, true},
+		{"1S", time.Second, true},
+		{"2H", 2 * time.Hour, true},
+		{"5u", 5 * time.Microsecond, true},
+		{"99999999n", 99999999 * time.Nanosecond, true},
+		{"", 0, false},
+		{"m", 0, false},
+		{"10", 0, false},
+		{"10x", 0, false},
+		{"-1S", 0, false},
+		{"123456789S", 0, false},
+	}
+
+	for _, c := range cases {
+		timeout, err := ParseGrpcTimeout(c.value)
+		if (err == nil) != c.ok {
+			t.Errorf("ParseGrpcTimeout(%q) err %v, want ok %v", c.value, err, c.ok)
+			continue
+		}
+		if c.ok && timeout != c.timeout {
+			t.Errorf("ParseGrpcTimeout(%q) = %v, want %v", c.value, timeout, c.timeout)
+		}
+	}
+}
+
+func TestEncodeGrpcTimeout(t *testing.T) {
+	cases := []struct {
+		timeout time.Duration
+		value   string
+	}{
+		{0, "0n"},
+		{500 * time.Nanosecond, "500n"},
+		{100 * time.Millisecond, "100000u"},
+		{time.Hour, "3600000m"},
+		{1000 * time.Hour, "3600000S"},
+		{100000 * time.Hour, "6000000M"},
+	}
+
+	for _, c := range cases {
+		value := EncodeGrpcTimeout(c.timeout)
+		if value != c.value {
+			t.Errorf("EncodeGrpcTimeout(%v) = %s, want %s", c.timeout, value, c.value)
+		}
+		if timeout, err := ParseGrpcTimeout(value); err != nil || timeout < c.timeout {
+			t.Errorf("ParseGrpcTimeout(%s) = %v, %v", value, timeout, err)
+		}
+	}
+}
+
+func TestGrpcRoundTripDeadline(t *testing.T) {
+	r := &bfe_http.Request{
+		Method: "POST",
+		URL:    &url.URL{Scheme: "http", Host: "127.0.0.1:8080", Path: "/helloworld.Greeter/SayHello"},
+		Header: bfe_http.Header{"Grpc-Timeout": {"10m"}},
+	}
+
+	// backend does not respond before deadline
+	_, err := grpcRoundTrip(func(req *http.Request) (*http.Response, error) {
+		if _, ok := req.Context().Deadline(); !ok {
+			t.Errorf("deadline of request should be set")
+		}
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	}, r)
+	if _, ok := err.(bfe_http.RespHeaderTimeoutError); !ok {
+		t.Errorf("err should be RespHeaderTimeoutError, got %v", err)
+	}
+
+	// deadline is kept until response body is closed
+	var ctx context.Context
+	res, err := grpcRoundTrip(func(req *http.Request) (*http.Response, error) {
+		ctx = req.Context()
+		return &http.Response{StatusCode: 200, Header: http.Header{}, Body: http.NoBody}, nil
+	}, r)
+	if err != nil {
+		t.Fatalf("grpcRoundTrip() err %v", err)
+	}
+	if ctx.Err() != nil {
+		t.Errorf("context should not be canceled before body closed")
+	}
+	res.Body.Close()
+	if ctx.Err() != context.Canceled {
+		t.Errorf("context should be canceled after body closed, got %v", ctx.Err())
+	}
+}
@@ -2056,6 +2056,7 @@ func (sc *serverConn) runHandler(rw *responseWriter, req *http.Request, handler
 		rw.handlerDone()
 	}()
 	req.State.H2Fingerprint = sc.fingerprint.Get()
+	req.State.H2FingerprintHash = sc.fingerprint.GetHash()
 	handler(rw, req)
 	didPanic = false
 }
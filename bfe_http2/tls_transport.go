@@ -0,0 +1,367 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// transport for http/2 over TLS (h2) backends
+//
+// Requests are multiplexed over a small pool of connections per backend, and
+// number of concurrent streams per connection is capped. Backends which do not
+// negotiate h2 by ALPN fall back to HTTP/1.1 for a while.
+
+package bfe_http2
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+import (
+	"github.com/bfenetworks/go-lib/log"
+	"golang.org/x/net/http2"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_config/bfe_cluster_conf/cluster_conf"
+	"github.com/bfenetworks/bfe/bfe_http"
+	"github.com/bfenetworks/bfe/bfe_tls"
+)
+
+const (
+	// H2FallbackDuration is duration of sending requests by HTTP/1.1 to
+	// backend which does not negotiate h2.
+	H2FallbackDuration = 5 * time.Minute
+
+	// H2IdleConnTimeout is max time an idle connection is kept in pool.
+	H2IdleConnTimeout = 90 * time.Second
+)
+
+var (
+	errH2NotNegotiated = errors.New("http2: h2 not negotiated by ALPN")
+
+	// ErrH2StreamsExhausted is returned if all connections to backend
+	// reach limit of concurrent streams.
+	ErrH2StreamsExhausted = errors.New("http2: concurrent streams to backend exhausted")
+)
+
+// TLSTransport is transport for http/2 over TLS (h2) backends.
+type TLSTransport struct {
+	// Dial specifies the dial function for creating TCP connections.
+	Dial func(network, addr string) (net.Conn, error)
+
+	// HttpsConf specifies TLS settings (CA list, client cert and server
+	// name) for connecting backends.
+	HttpsConf *cluster_conf.BackendHTTPS
+
+	// ConnsPerHost is max number of connections for each backend.
+	ConnsPerHost int
+
+	// MaxConcurrentStreams is max number of concurrent streams for each
+	// connection.
+	MaxConcurrentStreams int
+
+	// Fallback is used for backends which do not negotiate h2.
+	Fallback bfe_http.RoundTripper
+
+	// TLSHandshakeTimeout specifies the maximum amount of time to wait
+	// for a TLS handshake. Zero means no timeout.
+	TLSHandshakeTimeout time.Duration
+
+	// ResponseHeaderTimeout, if non-zero, specifies the amount of time to
+	// wait for response headers of backend after sending request.
+	ResponseHeaderTimeout time.Duration
+
+	t     *http2.Transport
+	mu    sync.Mutex
+	pools map[string][]*http2.ClientConn // backend => connections
+	noH2  map[string]time.Time           // backend => end of falling back
+}
+
+// NewTLSTransport creates transport for http/2 over TLS (h2) backends.
+func NewTLSTransport(dial func(network, addr string) (net.Conn, error), httpsConf *cluster_conf.BackendHTTPS,
+	h2Conf *cluster_conf.H2Conf, fallback bfe_http.RoundTripper) *TLSTransport {
+	t := &TLSTransport{
+		Dial:                 dial,
+		HttpsConf:            httpsConf,
+		ConnsPerHost:         cluster_conf.DefaultH2ConnsPerHost,
+		MaxConcurrentStreams: cluster_conf.DefaultH2MaxConcurrentStreams,
+		Fallback:             fallback,
+		pools:                make(map[string][]*http2.ClientConn),
+		noH2:                 make(map[string]time.Time),
+	}
+	if h2Conf != nil {
+		t.ConnsPerHost = h2Conf.ConnsPerHost
+		t.MaxConcurrentStreams = h2Conf.MaxConcurrentStreams
+	}
+	t.t = &http2.Transport{IdleConnTimeout: H2IdleConnTimeout}
+
+	return t
+}
+
+// RoundTrip sends request to backend over h2, or over HTTP/1.1 if backend
+// does not negotiate h2.
+func (t *TLSTransport) RoundTrip(r *bfe_http.Request) (*bfe_http.Response, error) {
+	addr := r.URL.Host
+	r.URL.Scheme = "https"
+
+	if t.fallingBack(addr) {
+		return t.Fallback.RoundTrip(r)
+	}
+
+	cc, err := t.getClientConn(addr)
+	if err == errH2NotNegotiated {
+		t.setFallback(addr, time.Now().Add(H2FallbackDuration))
+		return t.Fallback.RoundTrip(r)
+	}
+	if err != nil {
+		return nil, bfe_http.ConnectError{Err: err, Addr: addr}
+	}
+
+	rt := cc.RoundTrip
+	if t.ResponseHeaderTimeout > 0 {
+		rt = func(req *http.Request) (*http.Response, error) {
+			return roundTripWithHeaderTimeout(cc.RoundTrip, req, t.ResponseHeaderTimeout)
+		}
+	}
+	return grpcRoundTrip(rt, r)
+}
+
+// roundTripWithHeaderTimeout sends request by rt, and aborts the request if
+// response headers are not received within timeout.
+func roundTripWithHeaderTimeout(rt func(*http.Request) (*http.Response, error), req *http.Request,
+	timeout time.Duration) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	timer := time.AfterFunc(timeout, cancel)
+
+	res, err := rt(req.WithContext(ctx))
+	if !timer.Stop() {
+		// timer fired before response headers received
+		if err == nil {
+			res.Body.Close()
+		}
+		cancel()
+		return nil, bfe_http.RespHeaderTimeoutError{}
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// context is kept until response body is finished
+	res.Body = &cancelBody{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+func (t *TLSTransport) fallingBack(addr string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	end, ok := t.noH2[addr]
+	if !ok {
+		return false
+	}
+	if time.Now().After(end) {
+		delete(t.noH2, addr)
+		return false
+	}
+	return true
+}
+
+func (t *TLSTransport) setFallback(addr string, end time.Time) {
+	t.mu.Lock()
+	t.noH2[addr] = end
+	t.mu.Unlock()
+}
+
+// getClientConn returns connection to backend with a stream reserved. The
+// least loaded connection is preferred, and new connection is created if all
+// connections reach limit of concurrent streams.
+func (t *TLSTransport) getClientConn(addr string) (*http2.ClientConn, error) {
+	t.mu.Lock()
+	cc, full := t.reserveClientConn(addr)
+	t.mu.Unlock()
+	if cc != nil {
+		return cc, nil
+	}
+	if full {
+		return nil, ErrH2StreamsExhausted
+	}
+
+	cc, err := t.dialClientConn(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Note: connections may be created by concurrent requests while
+	// dialing, check limit of connections again
+	if len(t.pools[addr]) >= t.ConnsPerHost {
+		cc.Close()
+		if cc, _ := t.reserveClientConn(addr); cc != nil {
+			return cc, nil
+		}
+		return nil, ErrH2StreamsExhausted
+	}
+
+	if !cc.ReserveNewRequest() {
+		cc.Close()
+		return nil, ErrH2StreamsExhausted
+	}
+	t.pools[addr] = append(t.pools[addr], cc)
+	return cc, nil
+}
+
+// reserveClientConn reserves a stream on connection in pool. It returns
+// whether pool is full if no stream is reserved.
+// Note: caller should hold t.mu
+func (t *TLSTransport) reserveClientConn(addr string) (*http2.ClientConn, bool) {
+	var best *http2.ClientConn
+	bestLoad := 0
+
+	conns := t.pools[addr][:0]
+	for _, cc := range t.pools[addr] {
+		state := cc.State()
+		if state.Closed || state.Closing {
+			continue
+		}
+		conns = append(conns, cc)
+
+		load := state.StreamsActive + state.StreamsReserved + state.StreamsPending
+		if load >= t.MaxConcurrentStreams || !cc.CanTakeNewRequest() {
+			continue
+		}
+		if best == nil || load < bestLoad {
+			best = cc
+			bestLoad = load
+		}
+	}
+	if len(conns) == 0 {
+		delete(t.pools, addr)
+	} else {
+		t.pools[addr] = conns
+	}
+
+	if best != nil && best.ReserveNewRequest() {
+		return best, false
+	}
+	return nil, len(conns) >= t.ConnsPerHost
+}
+
+// dialClientConn creates connection to backend, and negotiates h2 by ALPN.
+func (t *TLSTransport) dialClientConn(addr string) (*http2.ClientConn, error) {
+	conf, err := t.tlsConfig(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := t.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConn := tls.Client(conn, conf)
+	if t.TLSHandshakeTimeout > 0 {
+		conn.SetDeadline(time.Now().Add(t.TLSHandshakeTimeout))
+	}
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if t.TLSHandshakeTimeout > 0 {
+		conn.SetDeadline(time.Time{})
+	}
+	if tlsConn.ConnectionState().NegotiatedProtocol != http2.NextProtoTLS {
+		tlsConn.Close()
+		return nil, errH2NotNegotiated
+	}
+
+	return t.t.NewClientConn(tlsConn)
+}
+
+// tlsConfig creates tls config for connecting backend.
+func (t *TLSTransport) tlsConfig(addr string) (*tls.Config, error) {
+	httpsConf := t.HttpsConf
+	if httpsConf == nil {
+		return nil, errors.New("http2: error, httpsConf is nil")
+	}
+
+	host := ""
+	if httpsConf.RSHost != nil && *httpsConf.RSHost != "" {
+		host = *httpsConf.RSHost
+	} else if h, _, err := net.SplitHostPort(addr); err == nil {
+		host = h
+	}
+
+	conf := &tls.Config{
+		ServerName: host,
+		NextProtos: []string{http2.NextProtoTLS, "http/1.1"},
+	}
+	if httpsConf.BFECertFile != nil && *httpsConf.BFECertFile != "" {
+		cert, err := httpsConf.GetBFECert()
+		if err != nil {
+			log.Logger.Warn("http2: get BFECert for %s: %s", addr, err)
+			return nil, err
+		}
+		conf.Certificates = []tls.Certificate{{
+			Certificate: cert.Certificate,
+			PrivateKey:  cert.PrivateKey,
+		}}
+	}
+
+	if httpsConf.RSInsecureSkipVerify != nil && *httpsConf.RSInsecureSkipVerify {
+		conf.InsecureSkipVerify = true
+		return conf, nil
+	}
+
+	rootCAs, err := httpsConf.GetRSCAList()
+	if err != nil {
+		return nil, err
+	}
+	if rootCAs != nil {
+		// use custom cas only, verified by hooks as HTTP/1.1 backends do
+		conf.InsecureSkipVerify = true
+		conf.RootCAs = rootCAs
+		conf.VerifyPeerCertificate = bfe_tls.NewVerifyPeerCertHooks(false, host, rootCAs).Ready()
+	}
+	return conf, nil
+}
+
+// CloseIdleConnections closes connections to backends which have no active
+// streams.
+func (t *TLSTransport) CloseIdleConnections() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for addr, conns := range t.pools {
+		active := conns[:0]
+		for _, cc := range conns {
+			state := cc.State()
+			if state.StreamsActive+state.StreamsReserved+state.StreamsPending == 0 {
+				cc.Close()
+				continue
+			}
+			active = append(active, cc)
+		}
+		if len(active) == 0 {
+			delete(t.pools, addr)
+		} else {
+			t.pools[addr] = active
+		}
+	}
+}
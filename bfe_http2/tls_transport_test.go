@@ -0,0 +1,223 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bfe_http2
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_config/bfe_cluster_conf/cluster_conf"
+	"github.com/bfenetworks/bfe/bfe_http"
+)
+
+func newTestTLSTransport(h2Conf *cluster_conf.H2Conf) *TLSTransport {
+	insecure := true
+	httpsConf := &cluster_conf.BackendHTTPS{RSInsecureSkipVerify: &insecure}
+	fallback := &bfe_http.Transport{
+		Dial:                net.Dial,
+		MaxIdleConnsPerHost: 2,
+	}
+	fallback.SetHttpsConf(httpsConf)
+	return NewTLSTransport(net.Dial, httpsConf, h2Conf, fallback)
+}
+
+func newTestRequest(t *testing.T, addr string) *bfe_http.Request {
+	req, err := bfe_http.NewRequest("GET", "http://"+addr+"/", nil)
+	if err != nil {
+		t.Fatalf("NewRequest() err: %s", err)
+	}
+	req.State = new(bfe_http.RequestState)
+	return req
+}
+
+func newTestServer(h2 bool, handler http.HandlerFunc) (*httptest.Server, *int32) {
+	conns := new(int32)
+	srv := httptest.NewUnstartedServer(handler)
+	srv.EnableHTTP2 = h2
+	srv.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(conns, 1)
+		}
+	}
+	srv.StartTLS()
+	return srv, conns
+}
+
+func TestTLSTransportMultiplex(t *testing.T) {
+	srv, conns := newTestServer(true, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	})
+	defer srv.Close()
+
+	tr := newTestTLSTransport(&cluster_conf.H2Conf{ConnsPerHost: 2, MaxConcurrentStreams: 10})
+	defer tr.CloseIdleConnections()
+
+	for i := 0; i < 10; i++ {
+		res, err := tr.RoundTrip(newTestRequest(t, srv.Listener.Addr().String()))
+		if err != nil {
+			t.Fatalf("RoundTrip() err: %s", err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		if res.ProtoMajor != 2 || string(body) != "HTTP/2.0" {
+			t.Errorf("should be served over h2: %s %s", res.Proto, body)
+		}
+	}
+
+	// sequential requests are multiplexed over one connection
+	if n := atomic.LoadInt32(conns); n != 1 {
+		t.Errorf("num of connections should be 1, got %d", n)
+	}
+}
+
+func TestTLSTransportMaxConcurrentStreams(t *testing.T) {
+	block := make(chan struct{})
+	srv, conns := newTestServer(true, func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	})
+	defer srv.Close()
+
+	tr := newTestTLSTransport(&cluster_conf.H2Conf{ConnsPerHost: 2, MaxConcurrentStreams: 1})
+	defer tr.CloseIdleConnections()
+
+	addr := srv.Listener.Addr().String()
+	results := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		cc, err := tr.getClientConn(addr)
+		if err != nil {
+			t.Fatalf("getClientConn() err: %s", err)
+		}
+		go func() {
+			res, err := cc.RoundTrip(toHTTPRequest(newTestRequest(t, addr)))
+			if err == nil {
+				res.Body.Close()
+			}
+			results <- err
+		}()
+	}
+
+	// both connections reach limit of concurrent streams
+	_, err := tr.RoundTrip(newTestRequest(t, addr))
+	var connErr bfe_http.ConnectError
+	if !errors.As(err, &connErr) || connErr.Err != ErrH2StreamsExhausted {
+		t.Errorf("RoundTrip() should return ErrH2StreamsExhausted, got %v", err)
+	}
+
+	close(block)
+	for i := 0; i < 2; i++ {
+		if err := <-results; err != nil {
+			t.Errorf("RoundTrip() err: %s", err)
+		}
+	}
+	if n := atomic.LoadInt32(conns); n != 2 {
+		t.Errorf("num of connections should be 2, got %d", n)
+	}
+}
+
+func TestTLSTransportConnsPerHost(t *testing.T) {
+	srv, _ := newTestServer(true, func(w http.ResponseWriter, r *http.Request) {})
+	defer srv.Close()
+
+	tr := newTestTLSTransport(&cluster_conf.H2Conf{ConnsPerHost: 1, MaxConcurrentStreams: 10})
+	defer tr.CloseIdleConnections()
+
+	// concurrent requests to a cold backend
+	addr := srv.Listener.Addr().String()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := tr.getClientConn(addr); err != nil {
+				t.Errorf("getClientConn() err: %s", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	tr.mu.Lock()
+	n := len(tr.pools[addr])
+	tr.mu.Unlock()
+	if n != 1 {
+		t.Errorf("num of connections in pool should be 1, got %d", n)
+	}
+}
+
+func TestTLSTransportResponseHeaderTimeout(t *testing.T) {
+	block := make(chan struct{})
+	srv, _ := newTestServer(true, func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	})
+	defer srv.Close()
+	defer close(block)
+
+	tr := newTestTLSTransport(nil)
+	tr.ResponseHeaderTimeout = 50 * time.Millisecond
+	defer tr.CloseIdleConnections()
+
+	_, err := tr.RoundTrip(newTestRequest(t, srv.Listener.Addr().String()))
+	if _, ok := err.(bfe_http.RespHeaderTimeoutError); !ok {
+		t.Errorf("RoundTrip() should return RespHeaderTimeoutError, got %v", err)
+	}
+}
+
+func TestTLSTransportFallback(t *testing.T) {
+	srv, _ := newTestServer(false, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	})
+	defer srv.Close()
+
+	tr := newTestTLSTransport(nil)
+	addr := srv.Listener.Addr().String()
+
+	for i := 0; i < 2; i++ {
+		res, err := tr.RoundTrip(newTestRequest(t, addr))
+		if err != nil {
+			t.Fatalf("RoundTrip() err: %s", err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		if string(body) != "HTTP/1.1" {
+			t.Errorf("should fall back to HTTP/1.1, got %s", body)
+		}
+	}
+	if !tr.fallingBack(addr) {
+		t.Errorf("backend should be falling back to HTTP/1.1")
+	}
+}
+
+func TestTLSTransportBFECertErr(t *testing.T) {
+	srv, _ := newTestServer(true, func(w http.ResponseWriter, r *http.Request) {})
+	defer srv.Close()
+
+	// BFECertFile is set, but cert is not loaded
+	certFile := "bfe.crt"
+	tr := newTestTLSTransport(nil)
+	tr.HttpsConf.BFECertFile = &certFile
+
+	_, err := tr.RoundTrip(newTestRequest(t, srv.Listener.Addr().String()))
+	if _, ok := err.(bfe_http.ConnectError); !ok {
+		t.Errorf("RoundTrip() should fail with ConnectError, got %v", err)
+	}
+}
@@ -30,6 +30,11 @@ type Transport struct {
 
 // RoundTrip is a wrapper function for http2.Transport.RoundTrip
 func (t *Transport) RoundTrip(r *bfe_http.Request) (*bfe_http.Response, error) {
+	return grpcRoundTrip(t.T.RoundTrip, r)
+}
+
+// toHTTPRequest converts bfe_http.Request to http.Request.
+func toHTTPRequest(r *bfe_http.Request) *http.Request {
 	req := http.Request{
 		Method:           r.Method,
 		URL:              r.URL,
@@ -55,10 +60,11 @@ func (t *Transport) RoundTrip(r *bfe_http.Request) (*bfe_http.Response, error) {
 	for k, v := range r.Trailer {
 		req.Trailer[k] = v
 	}
-	res, err := t.T.RoundTrip(&req)
-	if err != nil {
-		return nil, err
-	}
+	return &req
+}
+
+// toBfeResponse converts http.Response to bfe_http.Response.
+func toBfeResponse(res *http.Response, r *bfe_http.Request) *bfe_http.Response {
 	resp := bfe_http.Response{
 		Status:           res.Status,
 		StatusCode:       res.StatusCode,
@@ -77,5 +83,5 @@ func (t *Transport) RoundTrip(r *bfe_http.Request) (*bfe_http.Response, error) {
 	for k, v := range res.Header {
 		resp.Header[k] = v
 	}
-	return &resp, nil
+	return &resp
 }
@@ -0,0 +1,168 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// QUIC connection for http3
+
+package bfe_http3
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+import (
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_http"
+	"github.com/bfenetworks/bfe/bfe_tls"
+)
+
+// connCloseDelay is delay of closing connection after last request
+// finishes, so that pending response data could be sent.
+const connCloseDelay = 500 * time.Millisecond
+
+var errStreamOnly = errors.New("http3: read/write on quic connection, use streams instead")
+
+// Conn is a QUIC connection. It is also presented as net.Conn, so that it
+// could be processed as TCP connections (e.g. in session and modules).
+// Note: data could not be read from or written to Conn directly.
+type Conn struct {
+	quic.Connection
+
+	// TlsState is state of TLS handshake of the connection.
+	TlsState *bfe_tls.ConnectionState
+
+	handler bfe_http.Handler // handler for requests
+	reqSN   uint32           // number of requests arrived on the connection
+
+	mu        sync.Mutex
+	active    int       // number of active requests
+	closing   bool      // close the connection after active requests finish
+	closeOnce sync.Once // for delayed close of the connection
+}
+
+// NewConn creates Conn for QUIC connection which finishes handshake.
+func NewConn(qc quic.Connection) *Conn {
+	c := &Conn{Connection: qc}
+	c.TlsState = tlsState(qc.ConnectionState().TLS)
+	return c
+}
+
+func (c *Conn) Read(b []byte) (int, error) {
+	return 0, errStreamOnly
+}
+
+func (c *Conn) Write(b []byte) (int, error) {
+	return 0, errStreamOnly
+}
+
+// Close closes the connection immediately.
+func (c *Conn) Close() error {
+	return c.CloseWithError(quic.ApplicationErrorCode(http3.ErrCodeNoError), "")
+}
+
+// SetDeadline is no-op, deadlines are set on streams instead.
+func (c *Conn) SetDeadline(t time.Time) error {
+	return nil
+}
+
+// SetReadDeadline is no-op, deadlines are set on streams instead.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+// SetWriteDeadline is no-op, deadlines are set on streams instead.
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+// VirtualAddr returns the local address on which the connection arrived.
+// Nil is returned if the address is unknown.
+func (c *Conn) VirtualAddr() net.Addr {
+	addr, ok := c.LocalAddr().(*net.UDPAddr)
+	if !ok || addr.IP.IsUnspecified() {
+		return nil
+	}
+	return addr
+}
+
+// BalancerAddr returns nil, since layer-4 balancer in PROXY mode is not
+// supported for QUIC.
+func (c *Conn) BalancerAddr() net.Addr {
+	return nil
+}
+
+func (c *Conn) nextSerialNumber() uint32 {
+	return atomic.AddUint32(&c.reqSN, 1)
+}
+
+func (c *Conn) reqStart() {
+	c.mu.Lock()
+	c.active++
+	c.mu.Unlock()
+}
+
+func (c *Conn) reqDone() {
+	c.mu.Lock()
+	c.active--
+	idle := c.closing && c.active == 0
+	c.mu.Unlock()
+
+	if idle {
+		c.closeLater()
+	}
+}
+
+// closeAfterRequests closes the connection once active requests finish.
+func (c *Conn) closeAfterRequests() {
+	c.mu.Lock()
+	c.closing = true
+	idle := c.active == 0
+	c.mu.Unlock()
+
+	if idle {
+		c.closeLater()
+	}
+}
+
+func (c *Conn) closeLater() {
+	c.closeOnce.Do(func() {
+		time.AfterFunc(connCloseDelay, func() {
+			c.Close()
+		})
+	})
+}
+
+// tlsState converts state of TLS connection to the one used by bfe.
+func tlsState(cs tls.ConnectionState) *bfe_tls.ConnectionState {
+	return &bfe_tls.ConnectionState{
+		Version:                    cs.Version,
+		HandshakeComplete:          cs.HandshakeComplete,
+		DidResume:                  cs.DidResume,
+		CipherSuite:                cs.CipherSuite,
+		NegotiatedProtocolIsMutual: true,
+		NegotiatedProtocol:         cs.NegotiatedProtocol,
+		ServerName:                 cs.ServerName,
+		PeerCertificates:           cs.PeerCertificates,
+		VerifiedChains:             cs.VerifiedChains,
+		ClientAuth:                 len(cs.VerifiedChains) > 0,
+	}
+}
@@ -0,0 +1,292 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// HTTP/3 server over QUIC connections
+//
+// Framing of HTTP/3 is done by quic-go. Requests are converted and passed
+// to handler of bfe, as requests of other protocols.
+
+package bfe_http3
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+import (
+	"github.com/bfenetworks/go-lib/gotrack"
+	"github.com/bfenetworks/go-lib/log"
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_http"
+)
+
+// NextProtoH3 is ALPN protocol of HTTP/3.
+const NextProtoH3 = http3.NextProtoH3
+
+type contextKey struct{}
+
+// connContextKey is context key for Conn of request.
+var connContextKey = contextKey{}
+
+// HopHeaders are connection-specific header fields, which are not allowed
+// in http/3 messages.
+var HopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Connection",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// Server serves HTTP/3 requests on QUIC connections.
+type Server struct {
+	// MaxHeaderBytes is max size of request header.
+	MaxHeaderBytes int
+
+	// IdleTimeout is max time an idle connection (without any request) is
+	// kept. Zero means no timeout.
+	IdleTimeout time.Duration
+
+	once sync.Once
+	h3   *http3.Server
+}
+
+func (s *Server) init() {
+	s.h3 = &http3.Server{
+		Handler:        http.HandlerFunc(s.serveHTTP),
+		MaxHeaderBytes: s.MaxHeaderBytes,
+		IdleTimeout:    s.IdleTimeout,
+		ConnContext: func(ctx context.Context, qc quic.Connection) context.Context {
+			return context.WithValue(ctx, connContextKey, qc)
+		},
+	}
+}
+
+// ServeConn serves requests on connection by handler, until the connection
+// is closed or the server is shutdown.
+func (s *Server) ServeConn(c *Conn, handler bfe_http.Handler) error {
+	s.once.Do(s.init)
+	c.handler = handler
+
+	state.H3ConnServed.Inc(1)
+	state.H3ConnActive.Inc(1)
+	defer state.H3ConnActive.Dec(1)
+
+	cs := c.ConnectionState()
+	if cs.TLS.DidResume {
+		state.H3ConnResumed.Inc(1)
+	}
+	switch cs.Version {
+	case quic.Version1:
+		state.H3ConnQuicV1.Inc(1)
+	case quic.Version2:
+		state.H3ConnQuicV2.Inc(1)
+	}
+
+	err := s.h3.ServeQUICConn(c)
+	recordConnClose(c)
+	return err
+}
+
+// Shutdown shuts down the server gracefully. GOAWAY is sent on connections,
+// and it waits for active requests to finish, until ctx is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.once.Do(s.init)
+	return s.h3.Shutdown(ctx)
+}
+
+// recordConnClose records reason of connection close.
+func recordConnClose(c *Conn) {
+	ctx := c.Context()
+	if ctx.Err() == nil {
+		// connection is still open, e.g. in graceful shutdown
+		return
+	}
+
+	var idleErr *quic.IdleTimeoutError
+	var appErr *quic.ApplicationError
+	var transportErr *quic.TransportError
+	var resetErr *quic.StatelessResetError
+
+	err := context.Cause(ctx)
+	switch {
+	case errors.As(err, &idleErr):
+		state.H3ConnCloseIdleTimeout.Inc(1)
+	case errors.As(err, &resetErr):
+		state.H3ConnCloseReset.Inc(1)
+	case errors.As(err, &appErr) && appErr.Remote,
+		errors.As(err, &transportErr) && transportErr.Remote:
+		state.H3ConnCloseByPeer.Inc(1)
+	default:
+		state.H3ConnCloseByLocal.Inc(1)
+	}
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	c, ok := r.Context().Value(connContextKey).(*Conn)
+	if !ok {
+		// never go here
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	defer func() {
+		if err := recover(); err != nil {
+			log.Logger.Warn("http3: panic serving %v: %v\n%s", c.RemoteAddr(), err, gotrack.CurrentStackTrace(0))
+			state.H3PanicStream.Inc(1)
+			panic(http.ErrAbortHandler) // reset the stream
+		}
+	}()
+
+	c.reqStart()
+	defer c.reqDone()
+	state.H3ReqServed.Inc(1)
+	state.H3ReqActive.Inc(1)
+	defer state.H3ReqActive.Dec(1)
+
+	req := newRequest(c, r, w)
+	rw := &responseWriter{w: w, ctx: r.Context()}
+	c.handler.ServeHTTP(rw, req)
+}
+
+// streamDeadliner sets deadlines of request stream. It is implemented by
+// response writer of http3.
+type streamDeadliner interface {
+	SetReadDeadline(time.Time) error
+	SetWriteDeadline(time.Time) error
+}
+
+// RequestBody is body of http/3 request.
+type RequestBody struct {
+	io.ReadCloser
+
+	conn   *Conn           // connection of request
+	stream streamDeadliner // stream of request
+}
+
+func newRequest(c *Conn, r *http.Request, w http.ResponseWriter) *bfe_http.Request {
+	body := &RequestBody{ReadCloser: r.Body, conn: c}
+	body.stream, _ = w.(streamDeadliner)
+
+	contentLength := r.ContentLength
+	if contentLength < 0 && (r.Method == http.MethodGet || r.Method == http.MethodHead) {
+		// length of body is unknown until the stream ends. Treat body of
+		// GET/HEAD request without content-length as empty, as its length
+		// is checked again when forwarding.
+		contentLength = 0
+	}
+
+	return &bfe_http.Request{
+		Method:        r.Method,
+		URL:           r.URL,
+		Proto:         r.Proto,
+		ProtoMajor:    r.ProtoMajor,
+		ProtoMinor:    r.ProtoMinor,
+		Header:        bfe_http.Header(r.Header),
+		Body:          body,
+		ContentLength: contentLength,
+		Host:          r.Host,
+		RemoteAddr:    r.RemoteAddr,
+		RequestURI:    r.RequestURI,
+		TLS:           c.TlsState,
+		State: &bfe_http.RequestState{
+			SerialNumber: c.nextSerialNumber(),
+			Conn:         c,
+			StartTime:    time.Now(),
+		},
+	}
+}
+
+// SetReadStreamTimeout sets timeout for reading request from stream.
+func SetReadStreamTimeout(body *RequestBody, d time.Duration) {
+	if body.stream != nil {
+		body.stream.SetReadDeadline(time.Now().Add(d))
+	}
+}
+
+// SetWriteStreamTimeout sets timeout for writing response to stream.
+func SetWriteStreamTimeout(body *RequestBody, d time.Duration) {
+	if body.stream != nil {
+		body.stream.SetWriteDeadline(time.Now().Add(d))
+	}
+}
+
+// CloseConn closes connection of request, after active requests on the
+// connection finish.
+func CloseConn(body io.ReadCloser) {
+	if b, ok := body.(*RequestBody); ok {
+		b.conn.closeAfterRequests()
+	}
+}
+
+// responseWriter is writer for http/3 response.
+type responseWriter struct {
+	w           http.ResponseWriter
+	ctx         context.Context // context of request, done when stream closed
+	wroteHeader bool
+
+	closeNotifyOnce sync.Once
+	closeNotifyCh   chan bool
+}
+
+func (rw *responseWriter) Header() bfe_http.Header {
+	return bfe_http.Header(rw.w.Header())
+}
+
+func (rw *responseWriter) WriteHeader(code int) {
+	if !rw.wroteHeader {
+		rw.wroteHeader = true
+		header := rw.w.Header()
+		for _, k := range HopHeaders {
+			header.Del(k)
+		}
+	}
+	rw.w.WriteHeader(code)
+}
+
+func (rw *responseWriter) Write(p []byte) (int, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	return rw.w.Write(p)
+}
+
+func (rw *responseWriter) Flush() error {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if f, ok := rw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+func (rw *responseWriter) CloseNotify() <-chan bool {
+	rw.closeNotifyOnce.Do(func() {
+		rw.closeNotifyCh = make(chan bool, 1)
+		go func() {
+			<-rw.ctx.Done()
+			rw.closeNotifyCh <- true
+		}()
+	})
+	return rw.closeNotifyCh
+}
@@ -0,0 +1,210 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bfe_http3
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/bfenetworks/go-lib/web-monitor/metrics"
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_http"
+)
+
+func init() {
+	var m metrics.Metrics
+	m.Init(GetHttp3State(), "test", 0)
+}
+
+func newTestTLSConfig(t *testing.T) *tls.Config {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey(): %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "example.org"},
+		DNSNames:     []string{"example.org"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate(): %v", err)
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+		NextProtos:   []string{NextProtoH3},
+	}
+}
+
+// startTestServer starts http3 server with handler, and returns address of server.
+func startTestServer(t *testing.T, handler bfe_http.Handler) string {
+	ln, err := quic.ListenAddr("127.0.0.1:0", newTestTLSConfig(t), nil)
+	if err != nil {
+		t.Fatalf("ListenAddr(): %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	s := &Server{IdleTimeout: time.Minute}
+	go func() {
+		for {
+			qc, err := ln.Accept(context.Background())
+			if err != nil {
+				return
+			}
+			go s.ServeConn(NewConn(qc), handler)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func newTestClient(t *testing.T) *http.Client {
+	tr := &http3.RoundTripper{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true, ServerName: "example.org"},
+	}
+	t.Cleanup(func() { tr.Close() })
+	return &http.Client{Transport: tr, Timeout: 5 * time.Second}
+}
+
+func TestServeConn(t *testing.T) {
+	var got *bfe_http.Request
+	var body string
+	handler := bfe_http.HandlerFunc(func(w bfe_http.ResponseWriter, r *bfe_http.Request) {
+		got = r
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Test", "1")
+		w.Header().Set(http.TrailerPrefix+"X-Trailer", "2")
+		w.WriteHeader(bfe_http.StatusCreated)
+		w.Write([]byte("hello"))
+	})
+	addr := startTestServer(t, handler)
+	client := newTestClient(t)
+
+	resp, err := client.Post("https://"+addr+"/path?a=1", "text/plain", strings.NewReader("ping"))
+	if err != nil {
+		t.Fatalf("Post(): %v", err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	// check request
+	if got == nil {
+		t.Fatalf("request not served")
+	}
+	if got.Method != "POST" || got.URL.Path != "/path" || got.URL.RawQuery != "a=1" {
+		t.Errorf("unexpected request %s %s", got.Method, got.URL)
+	}
+	if got.ProtoMajor != 3 || got.Header.Get("Content-Type") != "text/plain" {
+		t.Errorf("unexpected proto %s or header %v", got.Proto, got.Header)
+	}
+	if body != "ping" {
+		t.Errorf("request body should be ping, but is %q", body)
+	}
+	if got.TLS == nil || got.TLS.ServerName != "example.org" || got.TLS.NegotiatedProtocol != NextProtoH3 {
+		t.Errorf("unexpected tls state %+v", got.TLS)
+	}
+	if _, ok := got.Body.(*RequestBody); !ok {
+		t.Errorf("request body should be *RequestBody, but is %T", got.Body)
+	}
+	if got.State == nil || got.State.SerialNumber != 1 {
+		t.Errorf("unexpected request state %+v", got.State)
+	}
+	if _, ok := got.State.Conn.(*Conn); !ok {
+		t.Errorf("conn of request should be *Conn, but is %T", got.State.Conn)
+	}
+
+	// check response
+	if resp.StatusCode != http.StatusCreated || string(data) != "hello" {
+		t.Errorf("unexpected response %d %q", resp.StatusCode, data)
+	}
+	if resp.Header.Get("X-Test") != "1" || resp.Header.Get("Connection") != "" {
+		t.Errorf("unexpected response header %v", resp.Header)
+	}
+	if resp.Trailer.Get("X-Trailer") != "2" {
+		t.Errorf("unexpected response trailer %v", resp.Trailer)
+	}
+}
+
+func TestCloseConn(t *testing.T) {
+	var conn *Conn
+	handler := bfe_http.HandlerFunc(func(w bfe_http.ResponseWriter, r *bfe_http.Request) {
+		conn = r.State.Conn.(*Conn)
+		CloseConn(r.Body)
+		w.Write([]byte("bye"))
+	})
+	addr := startTestServer(t, handler)
+	client := newTestClient(t)
+
+	resp, err := client.Get("https://" + addr + "/")
+	if err != nil {
+		t.Fatalf("Get(): %v", err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(data) != "bye" {
+		t.Errorf("response body should be bye, but is %q", data)
+	}
+
+	// connection is closed after request finishes
+	select {
+	case <-conn.Context().Done():
+	case <-time.After(4 * connCloseDelay):
+		t.Errorf("connection should be closed")
+	}
+}
+
+func TestNewRequestContentLength(t *testing.T) {
+	cases := []struct {
+		method string
+		length int64
+		want   int64
+	}{
+		{"GET", -1, 0},
+		{"HEAD", -1, 0},
+		{"POST", -1, -1},
+		{"POST", 10, 10},
+	}
+
+	for _, c := range cases {
+		r, _ := http.NewRequest(c.method, "https://example.org/", nil)
+		r.ContentLength = c.length
+		req := newRequest(&Conn{}, r, nil)
+		if req.ContentLength != c.want {
+			t.Errorf("%s with length %d: ContentLength should be %d, but is %d",
+				c.method, c.length, c.want, req.ContentLength)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bfe_http3
+
+import (
+	"github.com/bfenetworks/go-lib/web-monitor/metrics"
+)
+
+type Http3State struct {
+	H3ConnServed           *metrics.Counter // quic connections served
+	H3ConnActive           *metrics.Gauge   // quic connections in progress
+	H3ConnResumed          *metrics.Counter // connections with tls session resumed
+	H3ConnQuicV1           *metrics.Counter // connections of QUIC version 1
+	H3ConnQuicV2           *metrics.Counter // connections of QUIC version 2
+	H3ConnCloseIdleTimeout *metrics.Counter // connections closed for idle timeout
+	H3ConnCloseByPeer      *metrics.Counter // connections closed by client
+	H3ConnCloseByLocal     *metrics.Counter // connections closed by bfe
+	H3ConnCloseReset       *metrics.Counter // connections closed by stateless reset
+	H3ReqServed            *metrics.Counter // requests served
+	H3ReqActive            *metrics.Gauge   // requests in progress
+	H3PanicConn            *metrics.Counter
+	H3PanicStream          *metrics.Counter
+}
+
+var state Http3State
+
+func GetHttp3State() *Http3State {
+	return &state
+}
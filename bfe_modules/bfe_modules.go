@@ -33,11 +33,13 @@ import (
 	"github.com/bfenetworks/bfe/bfe_modules/mod_doh"
 	"github.com/bfenetworks/bfe/bfe_modules/mod_errors"
 	"github.com/bfenetworks/bfe/bfe_modules/mod_geo"
+	"github.com/bfenetworks/bfe/bfe_modules/mod_grpc_web"
 	"github.com/bfenetworks/bfe/bfe_modules/mod_header"
 	"github.com/bfenetworks/bfe/bfe_modules/mod_http_code"
 	"github.com/bfenetworks/bfe/bfe_modules/mod_key_log"
 	"github.com/bfenetworks/bfe/bfe_modules/mod_logid"
 	"github.com/bfenetworks/bfe/bfe_modules/mod_markdown"
+	"github.com/bfenetworks/bfe/bfe_modules/mod_mirror"
 	"github.com/bfenetworks/bfe/bfe_modules/mod_prison"
 	"github.com/bfenetworks/bfe/bfe_modules/mod_redirect"
 	"github.com/bfenetworks/bfe/bfe_modules/mod_rewrite"
@@ -82,6 +84,10 @@ var moduleList = []bfe_module.BfeModule{
 	// mod_cors
 	mod_cors.NewModuleCors(),
 
+	// mod_grpc_web
+	// Requirement: After mod_cors
+	mod_grpc_web.NewModuleGrpcWeb(),
+
 	// mod_block
 	// Requirement: After mod_logid
 	mod_block.NewModuleBlock(),
@@ -119,6 +125,9 @@ var moduleList = []bfe_module.BfeModule{
 	// mod_header
 	mod_header.NewModuleHeader(),
 
+	// mod_mirror
+	mod_mirror.NewModuleMirror(),
+
 	// mod_auth_request
 	mod_auth_request.NewModuleAuthRequest(),
 
@@ -24,6 +24,9 @@ const (
 	FormatClusterDuration
 	FormatClusterName
 	FormatConnectTime
+	FormatGrpcMethod
+	FormatGrpcService
+	FormatGrpcStatus
 	FormatReqHeaderLen
 	FormatHost
 	FormatIsTrustIP
@@ -52,6 +55,10 @@ const (
 	FormatServerAddr
 	FormatSinceSessionTime
 	FormatSubclusterName
+	FormatTLSJA3Hash
+	FormatTLSJA4
+	FormatH2Fingerprint
+	FormatH2FingerprintHash
 	FormatTime
 	FormatURL
 	FormatVIP
@@ -67,6 +74,10 @@ const (
 	FormatSesReadTotal
 	FormatSesTLSClientRandom
 	FormatSesTLSServerRandom
+	FormatSesTLSCurve
+	FormatSesTLSJA3Raw
+	FormatSesTLSJA3Hash
+	FormatSesTLSJA4
 	FormatSesUse100
 	FormatSesWriteTotal
 	FormatSesStartTime
@@ -97,6 +108,11 @@ var (
 		"cluster_duration":      FormatClusterDuration,
 		"connect_time":          FormatConnectTime,
 		"error":                 FormatReqErrorCode,
+		"grpc_method":           FormatGrpcMethod,
+		"grpc_service":          FormatGrpcService,
+		"grpc_status":           FormatGrpcStatus,
+		"h2_fingerprint":        FormatH2Fingerprint,
+		"h2_fingerprint_hash":   FormatH2FingerprintHash,
 		"host":                  FormatHost,
 		"is_trust_clientip":     FormatIsTrustIP,
 		"last_backend_duration": FormatLastBackendDuration,
@@ -121,6 +137,8 @@ var (
 		"since_ses_start_time":  FormatSinceSessionTime,
 		"status_code":           FormatStatusCode,
 		"subcluster":            FormatSubclusterName,
+		"tls_ja3_hash":          FormatTLSJA3Hash,
+		"tls_ja4":               FormatTLSJA4,
 		"url":                   FormatURL,
 		"vip":                   FormatVIP,
 		"write_serve_time":      FormatWriteServeTime,
@@ -134,6 +152,10 @@ var (
 		"ses_start_time":        FormatSesStartTime,
 		"ses_tls_client_random": FormatSesTLSClientRandom,
 		"ses_tls_server_random": FormatSesTLSServerRandom,
+		"ses_tls_curve":         FormatSesTLSCurve,
+		"ses_tls_ja3_raw":       FormatSesTLSJA3Raw,
+		"ses_tls_ja3_hash":      FormatSesTLSJA3Hash,
+		"ses_tls_ja4":           FormatSesTLSJA4,
 		"ses_use100":            FormatSesUse100,
 		"ses_write_total":       FormatSesWriteTotal,
 		"ses_keepalive_num":     FormatSesKeepaliveNum,
@@ -152,6 +174,11 @@ var (
 		FormatClusterDuration:     Request,
 		FormatClusterName:         Request,
 		FormatConnectTime:         Request,
+		FormatGrpcMethod:          Request,
+		FormatGrpcService:         Request,
+		FormatGrpcStatus:          Request,
+		FormatH2Fingerprint:       Request,
+		FormatH2FingerprintHash:   Request,
 		FormatHost:                Request,
 		FormatIsTrustIP:           Request,
 		FormatLastBackendDuration: Request,
@@ -180,6 +207,8 @@ var (
 		FormatServerAddr:          Request,
 		FormatSinceSessionTime:    Request,
 		FormatSubclusterName:      Request,
+		FormatTLSJA3Hash:          Request,
+		FormatTLSJA4:              Request,
 		FormatURL:                 Request,
 		FormatVIP:                 Request,
 		FormatWriteServeTime:      Request,
@@ -193,6 +222,10 @@ var (
 		FormatSesStartTime:       Session,
 		FormatSesTLSClientRandom: Session,
 		FormatSesTLSServerRandom: Session,
+		FormatSesTLSCurve:        Session,
+		FormatSesTLSJA3Raw:       Session,
+		FormatSesTLSJA3Hash:      Session,
+		FormatSesTLSJA4:          Session,
 		FormatSesUse100:          Session,
 		FormatSesWriteTotal:      Session,
 		FormatSesKeepaliveNum:    Session,
@@ -208,6 +241,11 @@ var (
 		FormatClusterDuration:     onLogFmtClusterDuration,
 		FormatClusterName:         onLogFmtClusterName,
 		FormatConnectTime:         onLogFmtConnectBackendTime,
+		FormatGrpcMethod:          onLogFmtGrpcMethod,
+		FormatGrpcService:         onLogFmtGrpcService,
+		FormatGrpcStatus:          onLogFmtGrpcStatus,
+		FormatH2Fingerprint:       onLogFmtH2Fingerprint,
+		FormatH2FingerprintHash:   onLogFmtH2FingerprintHash,
 		FormatIsTrustIP:           onLogFmtIsTrustip,
 		FormatLastBackendDuration: onLogFmtLastBackendDuration,
 		FormatLogID:               onLogFmtLogId,
@@ -235,6 +273,8 @@ var (
 		FormatSinceSessionTime:    onLogFmtSinceSessionTime,
 		FormatStatusCode:          onLogFmtStatusCode,
 		FormatSubclusterName:      onLogFmtSubclusterName,
+		FormatTLSJA3Hash:          onLogFmtTLSJA3Hash,
+		FormatTLSJA4:              onLogFmtTLSJA4,
 		FormatURL:                 onLogFmtUrl,
 		FormatVIP:                 onLogFmtVip,
 		FormatWriteServeTime:      onLogFmtWriteSrvTime,
@@ -249,6 +289,10 @@ var (
 		FormatSesReadTotal:       onLogFmtSesReadTotal,
 		FormatSesTLSClientRandom: onLogFmtSesTLSClientRandom,
 		FormatSesTLSServerRandom: onLogFmtSesTLSServerRandom,
+		FormatSesTLSCurve:        onLogFmtSesTLSCurve,
+		FormatSesTLSJA3Raw:       onLogFmtSesTLSJA3Raw,
+		FormatSesTLSJA3Hash:      onLogFmtSesTLSJA3Hash,
+		FormatSesTLSJA4:          onLogFmtSesTLSJA4,
 		FormatSesUse100:          onLogFmtSesUse100,
 		FormatSesWriteTotal:      onLogFmtSesWriteTotal,
 		FormatSesStartTime:       onLogFmtSesStartTime,
@@ -611,6 +611,101 @@ func onLogFmtSubclusterName(m *ModuleAccess, logItem *LogFmtItem, buff *bytes.Bu
 	return nil
 }
 
+func onLogFmtGrpcMethod(m *ModuleAccess, logItem *LogFmtItem, buff *bytes.Buffer,
+	req *bfe_basic.Request, res *bfe_http.Response) error {
+	if req == nil {
+		return errors.New("req is nil")
+	}
+
+	msg := "-"
+	if info := req.GetGrpcInfo(); info != nil && info.Method != "" {
+		msg = info.Method
+	}
+	buff.WriteString(msg)
+
+	return nil
+}
+
+func onLogFmtGrpcService(m *ModuleAccess, logItem *LogFmtItem, buff *bytes.Buffer,
+	req *bfe_basic.Request, res *bfe_http.Response) error {
+	if req == nil {
+		return errors.New("req is nil")
+	}
+
+	msg := "-"
+	if info := req.GetGrpcInfo(); info != nil && info.Service != "" {
+		msg = info.Service
+	}
+	buff.WriteString(msg)
+
+	return nil
+}
+
+func onLogFmtGrpcStatus(m *ModuleAccess, logItem *LogFmtItem, buff *bytes.Buffer,
+	req *bfe_basic.Request, res *bfe_http.Response) error {
+	if req == nil {
+		return errors.New("req is nil")
+	}
+
+	msg := "-"
+	if info := req.GetGrpcInfo(); info != nil && info.Status != "" {
+		msg = info.Status
+	}
+	buff.WriteString(msg)
+
+	return nil
+}
+
+func onLogFmtH2Fingerprint(m *ModuleAccess, logItem *LogFmtItem, buff *bytes.Buffer,
+	req *bfe_basic.Request, res *bfe_http.Response) error {
+	if req == nil {
+		return errors.New("req is nil")
+	}
+
+	msg := "-"
+	if req.HttpRequest != nil && req.HttpRequest.State != nil &&
+		req.HttpRequest.State.H2Fingerprint != "" {
+		msg = req.HttpRequest.State.H2Fingerprint
+	}
+	buff.WriteString(msg)
+
+	return nil
+}
+
+func onLogFmtH2FingerprintHash(m *ModuleAccess, logItem *LogFmtItem, buff *bytes.Buffer,
+	req *bfe_basic.Request, res *bfe_http.Response) error {
+	if req == nil {
+		return errors.New("req is nil")
+	}
+
+	msg := "-"
+	if req.HttpRequest != nil && req.HttpRequest.State != nil &&
+		req.HttpRequest.State.H2FingerprintHash != "" {
+		msg = req.HttpRequest.State.H2FingerprintHash
+	}
+	buff.WriteString(msg)
+
+	return nil
+}
+
+func onLogFmtTLSJA3Hash(m *ModuleAccess, logItem *LogFmtItem, buff *bytes.Buffer,
+	req *bfe_basic.Request, res *bfe_http.Response) error {
+	if req == nil {
+		return errors.New("req is nil")
+	}
+
+	return onLogFmtSesTLSJA3Hash(m, logItem, buff, req.Session)
+}
+
+func onLogFmtTLSJA4(m *ModuleAccess, logItem *LogFmtItem, buff *bytes.Buffer,
+	req *bfe_basic.Request, res *bfe_http.Response) error {
+	if req == nil {
+		return errors.New("req is nil")
+	}
+
+	return onLogFmtSesTLSJA4(m, logItem, buff, req.Session)
+}
+
 func onLogFmtTime(m *ModuleAccess, buff *bytes.Buffer) error {
 	now := time.Now()
 	timeNowStr := fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d",
@@ -25,6 +25,7 @@ import (
 import (
 	"github.com/bfenetworks/bfe/bfe_basic"
 	"github.com/bfenetworks/bfe/bfe_http"
+	"github.com/bfenetworks/bfe/bfe_tls"
 )
 
 type testConn struct {
@@ -127,6 +128,75 @@ func TestOnLogFmtRequestLine(t *testing.T) {
 	}
 }
 
+func TestOnLogFmtGrpc(t *testing.T) {
+	req, res, buff := prepareRequestLogTest(t)
+	onLogFmtGrpcStatus(nil, &LogFmtItem{}, buff, req, res)
+	if buff.String() != "-" {
+		t.Errorf("onLogFmtGrpcStatus() non-grpc got: %s, want: -", buff.String())
+	}
+
+	req.SetGrpcInfo(&bfe_basic.GrpcInfo{Service: "helloworld.Greeter", Method: "SayHello", Status: "14"})
+	tests := []struct {
+		name string
+		fn   func(*ModuleAccess, *LogFmtItem, *bytes.Buffer, *bfe_basic.Request, *bfe_http.Response) error
+		want string
+	}{
+		{"GrpcMethod", onLogFmtGrpcMethod, "SayHello"},
+		{"GrpcService", onLogFmtGrpcService, "helloworld.Greeter"},
+		{"GrpcStatus", onLogFmtGrpcStatus, "14"},
+	}
+	for _, tt := range tests {
+		buff.Reset()
+		if err := tt.fn(nil, &LogFmtItem{}, buff, req, res); err != nil {
+			t.Errorf("onLogFmt%s() error: %v", tt.name, err)
+		}
+		if buff.String() != tt.want {
+			t.Errorf("onLogFmt%s() got: %s, want: %s", tt.name, buff.String(), tt.want)
+		}
+	}
+}
+
+func TestOnLogFmtFingerprint(t *testing.T) {
+	req, res, buff := prepareRequestLogTest(t)
+	tests := []struct {
+		name string
+		fn   func(*ModuleAccess, *LogFmtItem, *bytes.Buffer, *bfe_basic.Request, *bfe_http.Response) error
+		want string
+	}{
+		{"H2Fingerprint", onLogFmtH2Fingerprint, "1:65536;3:1000|6291456|1|m,a,s,p"},
+		{"H2FingerprintHash", onLogFmtH2FingerprintHash, "0be9a5e4c6ef38a8a4ea6d7ae0e1b96c"},
+		{"TLSJA3Hash", onLogFmtTLSJA3Hash, "e7d705a3286e19ea42f587b344ee6865"},
+		{"TLSJA4", onLogFmtTLSJA4, "t13d1516h2_8daaf6152771_e5627efa2ab1"},
+	}
+
+	// no fingerprint for plain http/1.1 request
+	for _, tt := range tests {
+		buff.Reset()
+		if err := tt.fn(nil, &LogFmtItem{}, buff, req, res); err != nil {
+			t.Errorf("onLogFmt%s() error: %v", tt.name, err)
+		}
+		if buff.String() != "-" {
+			t.Errorf("onLogFmt%s() got: %s, want: -", tt.name, buff.String())
+		}
+	}
+
+	req.HttpRequest.State.H2Fingerprint = "1:65536;3:1000|6291456|1|m,a,s,p"
+	req.HttpRequest.State.H2FingerprintHash = "0be9a5e4c6ef38a8a4ea6d7ae0e1b96c"
+	req.Session.TlsState = &bfe_tls.ConnectionState{
+		JA3Hash: "e7d705a3286e19ea42f587b344ee6865",
+		JA4:     "t13d1516h2_8daaf6152771_e5627efa2ab1",
+	}
+	for _, tt := range tests {
+		buff.Reset()
+		if err := tt.fn(nil, &LogFmtItem{}, buff, req, res); err != nil {
+			t.Errorf("onLogFmt%s() error: %v", tt.name, err)
+		}
+		if buff.String() != tt.want {
+			t.Errorf("onLogFmt%s() got: %s, want: %s", tt.name, buff.String(), tt.want)
+		}
+	}
+}
+
 func TestOnLogFmtHost(t *testing.T) {
 	req, res, buff := prepareRequestLogTest(t)
 	err := onLogFmtHost(nil, &LogFmtItem{}, buff, req, res)
@@ -641,6 +711,11 @@ func TestOnLogFmtNilReq(t *testing.T) {
 		{"ClusterDuration", onLogFmtClusterDuration},
 		{"ClusterName", onLogFmtClusterName},
 		{"ConnectBackendTime", onLogFmtConnectBackendTime},
+		{"GrpcMethod", onLogFmtGrpcMethod},
+		{"GrpcService", onLogFmtGrpcService},
+		{"GrpcStatus", onLogFmtGrpcStatus},
+		{"H2Fingerprint", onLogFmtH2Fingerprint},
+		{"H2FingerprintHash", onLogFmtH2FingerprintHash},
 		{"Host", onLogFmtHost},
 		{"IsTrustip", onLogFmtIsTrustip},
 		{"LastBackendDuration", onLogFmtLastBackendDuration},
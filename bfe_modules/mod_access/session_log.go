@@ -23,6 +23,7 @@ import (
 
 import (
 	"github.com/bfenetworks/bfe/bfe_basic"
+	"github.com/bfenetworks/bfe/bfe_tls"
 )
 
 func onLogFmtSesClientIp(m *ModuleAccess, logItem *LogFmtItem, buff *bytes.Buffer,
@@ -150,6 +151,66 @@ func onLogFmtSesTLSServerRandom(m *ModuleAccess, logItem *LogFmtItem, buff *byte
 	return nil
 }
 
+func onLogFmtSesTLSCurve(m *ModuleAccess, logItem *LogFmtItem, buff *bytes.Buffer,
+	session *bfe_basic.Session) error {
+	if session == nil {
+		return errors.New("session is nil")
+	}
+
+	msg := "-"
+	if session.TlsState != nil && session.TlsState.CurveID != 0 {
+		msg = bfe_tls.CurveText(session.TlsState.CurveID)
+	}
+	buff.WriteString(msg)
+
+	return nil
+}
+
+func onLogFmtSesTLSJA3Raw(m *ModuleAccess, logItem *LogFmtItem, buff *bytes.Buffer,
+	session *bfe_basic.Session) error {
+	if session == nil {
+		return errors.New("session is nil")
+	}
+
+	msg := "-"
+	if session.TlsState != nil && session.TlsState.JA3Raw != "" {
+		msg = session.TlsState.JA3Raw
+	}
+	buff.WriteString(msg)
+
+	return nil
+}
+
+func onLogFmtSesTLSJA3Hash(m *ModuleAccess, logItem *LogFmtItem, buff *bytes.Buffer,
+	session *bfe_basic.Session) error {
+	if session == nil {
+		return errors.New("session is nil")
+	}
+
+	msg := "-"
+	if session.TlsState != nil && session.TlsState.JA3Hash != "" {
+		msg = session.TlsState.JA3Hash
+	}
+	buff.WriteString(msg)
+
+	return nil
+}
+
+func onLogFmtSesTLSJA4(m *ModuleAccess, logItem *LogFmtItem, buff *bytes.Buffer,
+	session *bfe_basic.Session) error {
+	if session == nil {
+		return errors.New("session is nil")
+	}
+
+	msg := "-"
+	if session.TlsState != nil && session.TlsState.JA4 != "" {
+		msg = session.TlsState.JA4
+	}
+	buff.WriteString(msg)
+
+	return nil
+}
+
 func onLogFmtSesUse100(m *ModuleAccess, logItem *LogFmtItem, buff *bytes.Buffer,
 	session *bfe_basic.Session) error {
 	if session == nil {
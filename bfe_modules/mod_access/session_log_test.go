@@ -44,6 +44,7 @@ func prepareSessionLogTest(t *testing.T) (*bfe_basic.Session, *bytes.Buffer) {
 	session.TlsState = &bfe_tls.ConnectionState{
 		ClientRandom: []byte{0x01, 0x02, 0x03},
 		ServerRandom: []byte{0x04, 0x05, 0x06},
+		CurveID:      bfe_tls.X25519MLKEM768,
 	}
 
 	return session, bytes.NewBuffer(nil)
@@ -196,6 +197,61 @@ func TestOnLogFmtSesTLSServerRandom(t *testing.T) {
 	}
 }
 
+func TestOnLogFmtSesTLSCurve(t *testing.T) {
+	session, buff := prepareSessionLogTest(t)
+	err := onLogFmtSesTLSCurve(nil, &LogFmtItem{}, buff, session)
+	if err != nil {
+		t.Errorf("onLogFmtSesTLSCurve() error: %v", err)
+	}
+	if buff.String() != "X25519MLKEM768" {
+		t.Errorf("onLogFmtSesTLSCurve() got: %s", buff.String())
+	}
+
+	// no key exchange for resumed TLS 1.2 session
+	session.TlsState.CurveID = 0
+	buff.Reset()
+	onLogFmtSesTLSCurve(nil, &LogFmtItem{}, buff, session)
+	if buff.String() != "-" {
+		t.Errorf("onLogFmtSesTLSCurve() got: %s, want: -", buff.String())
+	}
+}
+
+func TestOnLogFmtSesTLSFingerprint(t *testing.T) {
+	session, buff := prepareSessionLogTest(t)
+	session.TlsState.JA3Raw = "771,4865-4866,0-10-11,29-23,0"
+	session.TlsState.JA3Hash = "e7d705a3286e19ea42f587b344ee6865"
+	session.TlsState.JA4 = "t13d1516h2_8daaf6152771_e5627efa2ab1"
+
+	tests := []struct {
+		name string
+		fn   func(*ModuleAccess, *LogFmtItem, *bytes.Buffer, *bfe_basic.Session) error
+		want string
+	}{
+		{"TLSJA3Raw", onLogFmtSesTLSJA3Raw, "771,4865-4866,0-10-11,29-23,0"},
+		{"TLSJA3Hash", onLogFmtSesTLSJA3Hash, "e7d705a3286e19ea42f587b344ee6865"},
+		{"TLSJA4", onLogFmtSesTLSJA4, "t13d1516h2_8daaf6152771_e5627efa2ab1"},
+	}
+	for _, tt := range tests {
+		buff.Reset()
+		if err := tt.fn(nil, &LogFmtItem{}, buff, session); err != nil {
+			t.Errorf("onLogFmtSes%s() error: %v", tt.name, err)
+		}
+		if buff.String() != tt.want {
+			t.Errorf("onLogFmtSes%s() got: %s, want: %s", tt.name, buff.String(), tt.want)
+		}
+	}
+
+	// no fingerprint for non-tls session
+	session.TlsState = nil
+	for _, tt := range tests {
+		buff.Reset()
+		tt.fn(nil, &LogFmtItem{}, buff, session)
+		if buff.String() != "-" {
+			t.Errorf("onLogFmtSes%s() got: %s, want: -", tt.name, buff.String())
+		}
+	}
+}
+
 func TestOnLogFmtSesTLSRandomNil(t *testing.T) {
 	conn := newTestConn()
 	session := bfe_basic.NewSession(conn)
@@ -227,6 +283,10 @@ func TestOnLogFmtSesNilSession(t *testing.T) {
 		{"ReadTotal", onLogFmtSesReadTotal},
 		{"TLSClientRandom", onLogFmtSesTLSClientRandom},
 		{"TLSServerRandom", onLogFmtSesTLSServerRandom},
+		{"TLSCurve", onLogFmtSesTLSCurve},
+		{"TLSJA3Raw", onLogFmtSesTLSJA3Raw},
+		{"TLSJA3Hash", onLogFmtSesTLSJA3Hash},
+		{"TLSJA4", onLogFmtSesTLSJA4},
 		{"Use100", onLogFmtSesUse100},
 		{"WriteTotal", onLogFmtSesWriteTotal},
 		{"StartTime", onLogFmtSesStartTime},
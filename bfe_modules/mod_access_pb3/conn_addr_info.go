@@ -15,9 +15,8 @@
 package mod_access_pb3
 
 import (
-	"net"
-
 	"github.com/bfenetworks/bfe/bfe_basic"
+	"github.com/bfenetworks/bfe/bfe_util"
 	"github.com/bfenetworks/bfe/bfe_util/net_util"
 	"google.golang.org/protobuf/proto"
 
@@ -29,7 +28,7 @@ func ConnAddrInfoGen(session *bfe_basic.Session) *bfe_access_pb3.ConnAddrInfo {
 
 	// bfe ip
 	info.BfeIp = proto.Uint32(0)
-	localIp := session.Connection.LocalAddr().(*net.TCPAddr).IP.To4()
+	localIp := bfe_util.GetLocalIP(session.Connection).To4()
 	ip, err := net_util.IPv4ToUint32(localIp)
 	if err == nil {
 		info.BfeIp = proto.Uint32(ip)
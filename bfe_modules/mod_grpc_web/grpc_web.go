@@ -0,0 +1,270 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// translation between gRPC-Web and gRPC
+//
+// Messages of gRPC-Web share the same framing as gRPC, so request body is
+// forwarded as is, except that it is base64 decoded for grpc-web-text.
+// Trailers of gRPC response are encoded as the last frame of gRPC-Web
+// response body, with flag 0x80.
+// See https://github.com/grpc/grpc/blob/master/doc/PROTOCOL-WEB.md
+
+package mod_grpc_web
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_basic"
+	"github.com/bfenetworks/bfe/bfe_http"
+)
+
+const (
+	trailerFrameFlag = 0x80 // flag of frame with trailers
+	frameHeaderLen   = 5    // flag (1 byte) and length (4 bytes)
+	readBufSize      = 32 * 1024
+)
+
+// isTextContentType checks whether content type is grpc-web-text.
+func isTextContentType(ctype string) bool {
+	return strings.HasPrefix(ctype, bfe_basic.GrpcWebTextContentType)
+}
+
+// toGrpcContentType converts content type of gRPC-Web request to gRPC, e.g.
+// application/grpc-web-text+proto to application/grpc+proto.
+func toGrpcContentType(ctype string) string {
+	base := bfe_basic.GrpcWebContentType
+	if isTextContentType(ctype) {
+		base = bfe_basic.GrpcWebTextContentType
+	}
+	return bfe_basic.GrpcContentType + strings.TrimPrefix(ctype, base)
+}
+
+// toGrpcWebContentType converts content type of gRPC response to gRPC-Web.
+// It returns false if response is not gRPC.
+func toGrpcWebContentType(ctype string, text bool) (string, bool) {
+	if !strings.HasPrefix(ctype, bfe_basic.GrpcContentType) {
+		return "", false
+	}
+	rest := ctype[len(bfe_basic.GrpcContentType):]
+	if len(rest) > 0 && rest[0] != '+' && rest[0] != ';' {
+		return "", false
+	}
+
+	if text {
+		return bfe_basic.GrpcWebTextContentType + rest, true
+	}
+	return bfe_basic.GrpcWebContentType + rest, true
+}
+
+// encodeTrailerFrame encodes trailers as frame of gRPC-Web body. Keys of
+// trailers are in lower case, and sorted for stable output.
+func encodeTrailerFrame(trailer http.Header) []byte {
+	keys := make([]string, 0, len(trailer))
+	for k := range trailer {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var block bytes.Buffer
+	for _, k := range keys {
+		for _, v := range trailer[k] {
+			block.WriteString(strings.ToLower(k))
+			block.WriteString(": ")
+			block.WriteString(v)
+			block.WriteString("\r\n")
+		}
+	}
+
+	frame := make([]byte, frameHeaderLen, frameHeaderLen+block.Len())
+	frame[0] = trailerFrameFlag
+	binary.BigEndian.PutUint32(frame[1:], uint32(block.Len()))
+	return append(frame, block.Bytes()...)
+}
+
+// textDecoder decodes body of grpc-web-text request. Body may consist of
+// multiple base64 chunks, each of which is padded separately.
+type textDecoder struct {
+	src     io.ReadCloser
+	in      []byte // buffer for reading src
+	buf     []byte // undecoded input, less than 4 bytes
+	decoded []byte // decoded output, not read yet
+	err     error
+}
+
+func newTextDecoder(src io.ReadCloser) *textDecoder {
+	return &textDecoder{src: src, in: make([]byte, readBufSize)}
+}
+
+func (d *textDecoder) Read(p []byte) (int, error) {
+	for len(d.decoded) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+
+		n, err := d.src.Read(d.in)
+		data := append(d.buf, d.in[:n]...)
+
+		// decode by quantum of 4 bytes, since padding may appear inside
+		quanta := len(data) / 4 * 4
+		out := make([]byte, 0, quanta/4*3)
+		for i := 0; i < quanta; i += 4 {
+			var dst [3]byte
+			m, derr := base64.StdEncoding.Decode(dst[:], data[i:i+4])
+			if derr != nil {
+				return 0, derr
+			}
+			out = append(out, dst[:m]...)
+		}
+		d.buf = append([]byte(nil), data[quanta:]...)
+		d.decoded = out
+
+		if err == io.EOF && len(d.buf) > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		d.err = err
+	}
+
+	n := copy(p, d.decoded)
+	d.decoded = d.decoded[n:]
+	return n, nil
+}
+
+func (d *textDecoder) Close() error {
+	return d.src.Close()
+}
+
+// webBody is body of gRPC-Web response. Trailers of gRPC response are
+// appended as the last frame, and body is base64 encoded for grpc-web-text.
+type webBody struct {
+	src     io.ReadCloser
+	in      []byte       // buffer for reading src
+	trailer *http.Header // trailers of gRPC response, populated at EOF
+	text    bool
+	onEOF   func(trailer http.Header) // callback at EOF of response
+
+	pending []byte       // input to be base64 encoded, less than 3 bytes
+	out     bytes.Buffer // output not read yet
+	err     error
+}
+
+func newWebBody(src io.ReadCloser, trailer *http.Header, text bool,
+	onEOF func(trailer http.Header)) *webBody {
+	return &webBody{
+		src:     src,
+		in:      make([]byte, readBufSize),
+		trailer: trailer,
+		text:    text,
+		onEOF:   onEOF,
+	}
+}
+
+func (b *webBody) Read(p []byte) (int, error) {
+	for b.out.Len() == 0 {
+		if b.err != nil {
+			return 0, b.err
+		}
+
+		n, err := b.src.Read(b.in)
+		if n > 0 {
+			b.write(b.in[:n], false)
+		}
+		if err == io.EOF {
+			var trailer http.Header
+			if b.trailer != nil {
+				trailer = *b.trailer
+			}
+			if len(trailer) > 0 {
+				b.write(encodeTrailerFrame(trailer), true)
+			} else {
+				b.write(nil, true)
+			}
+			if b.onEOF != nil {
+				b.onEOF(trailer)
+			}
+		}
+		b.err = err
+	}
+
+	return b.out.Read(p)
+}
+
+// write writes data to output, with base64 encoding for grpc-web-text.
+// Encoding input is kept in multiple of 3 bytes to avoid padding, until the
+// end of body.
+func (b *webBody) write(data []byte, final bool) {
+	if !b.text {
+		b.out.Write(data)
+		return
+	}
+
+	data = append(b.pending, data...)
+	size := len(data)
+	if !final {
+		size = size / 3 * 3
+	}
+
+	if size > 0 {
+		encoded := make([]byte, base64.StdEncoding.EncodedLen(size))
+		base64.StdEncoding.Encode(encoded, data[:size])
+		b.out.Write(encoded)
+	}
+	b.pending = append([]byte(nil), data[size:]...)
+}
+
+func (b *webBody) Close() error {
+	return b.src.Close()
+}
+
+// addExposeHeaders exposes grpc-status and grpc-message to browser, if
+// response is for CORS request (e.g. processed by mod_cors).
+func addExposeHeaders(header bfe_http.Header) {
+	if header.Get("Access-Control-Allow-Origin") == "" {
+		return
+	}
+
+	exposed := header.Get("Access-Control-Expose-Headers")
+	var add []string
+	for _, h := range []string{"grpc-status", "grpc-message"} {
+		if !containsToken(exposed, h) {
+			add = append(add, h)
+		}
+	}
+	if len(add) == 0 {
+		return
+	}
+
+	if exposed != "" {
+		add = append([]string{exposed}, add...)
+	}
+	header.Set("Access-Control-Expose-Headers", strings.Join(add, ","))
+}
+
+// containsToken checks whether comma separated list contains token.
+func containsToken(list string, token string) bool {
+	for _, s := range strings.Split(list, ",") {
+		s = strings.TrimSpace(s)
+		if s == "*" || strings.EqualFold(s, token) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mod_grpc_web
+
+import (
+	"fmt"
+	"os"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_basic/condition"
+	"github.com/bfenetworks/bfe/bfe_util/json"
+)
+
+type GrpcWebRuleFile struct {
+	Version string             // version
+	Config  ProductRuleRawList // product -> raw rule list
+}
+
+type GrpcWebRuleConf struct {
+	Version string          // version
+	Config  ProductRuleList // product -> rule list
+}
+
+type GrpcWebRuleRaw struct {
+	Cond string // condition
+}
+
+type ProductRuleRawList map[string]RuleRawList // product => raw rule list
+type RuleRawList []GrpcWebRuleRaw
+
+func GrpcWebRuleCheck(ruleFile *GrpcWebRuleFile) error {
+	if ruleFile == nil {
+		return fmt.Errorf("grpcWebRuleFile is nil")
+	}
+
+	if len(ruleFile.Version) == 0 {
+		return fmt.Errorf("no Version")
+	}
+
+	if ruleFile.Config == nil {
+		return fmt.Errorf("no Config")
+	}
+
+	return nil
+}
+
+func ruleListConvert(rawRuleList RuleRawList) (GrpcWebRuleList, error) {
+	ruleList := GrpcWebRuleList{}
+	for i, rawRule := range rawRuleList {
+		cond, err := condition.Build(rawRule.Cond)
+		if err != nil {
+			return nil, fmt.Errorf("rule [%d] error: %v", i, err)
+		}
+
+		ruleList = append(ruleList, GrpcWebRule{Cond: cond})
+	}
+
+	return ruleList, nil
+}
+
+func GrpcWebRuleFileLoad(filename string) (*GrpcWebRuleConf, error) {
+	var ruleFile GrpcWebRuleFile
+	var ruleConf GrpcWebRuleConf
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+
+	err = decoder.Decode(&ruleFile)
+	if err != nil {
+		return nil, err
+	}
+
+	err = GrpcWebRuleCheck(&ruleFile)
+	if err != nil {
+		return nil, err
+	}
+
+	ruleConf.Version = ruleFile.Version
+	ruleConf.Config = make(ProductRuleList)
+
+	for product, rawRuleList := range ruleFile.Config {
+		ruleList, err := ruleListConvert(rawRuleList)
+		if err != nil {
+			return nil, fmt.Errorf("product[%s] rule error: %v", product, err)
+		}
+		ruleConf.Config[product] = ruleList
+	}
+
+	return &ruleConf, nil
+}
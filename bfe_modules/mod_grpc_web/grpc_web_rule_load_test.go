@@ -0,0 +1,56 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mod_grpc_web
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGrpcWebRuleFileLoad(t *testing.T) {
+	conf, err := GrpcWebRuleFileLoad("testdata/mod_grpc_web/grpc_web_rule.data")
+	if err != nil {
+		t.Fatalf("should have no error, but error is %v", err)
+	}
+
+	if conf.Version != "20260101000000" {
+		t.Errorf("Version should be 20260101000000, but it's %s", conf.Version)
+	}
+
+	if ruleList, ok := conf.Config[expectProduct]; !ok || len(ruleList) != 1 {
+		t.Errorf("product %s should have 1 rule", expectProduct)
+	}
+}
+
+func TestGrpcWebRuleFileLoadError(t *testing.T) {
+	tests := []struct {
+		path   string
+		errMsg string
+	}{
+		{"testdata/mod_grpc_web/grpc_web_rule.data1", "no Version"},
+		{"testdata/mod_grpc_web/grpc_web_rule.data2", "rule [0] error"},
+	}
+
+	for _, tt := range tests {
+		_, err := GrpcWebRuleFileLoad(tt.path)
+		if err == nil {
+			t.Errorf("%s: should have error", tt.path)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.errMsg) {
+			t.Errorf("%s: error message is not expected: %v", tt.path, err)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mod_grpc_web
+
+import (
+	"sync"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_basic/condition"
+)
+
+type GrpcWebRuleTable struct {
+	lock        sync.RWMutex
+	version     string
+	productRule ProductRuleList // product => rule list
+}
+
+type GrpcWebRule struct {
+	Cond condition.Condition
+}
+
+type ProductRuleList map[string]GrpcWebRuleList // product => list of grpc-web rule
+type GrpcWebRuleList []GrpcWebRule
+
+func NewGrpcWebRuleTable() *GrpcWebRuleTable {
+	t := new(GrpcWebRuleTable)
+	t.productRule = make(ProductRuleList)
+	return t
+}
+
+func (t *GrpcWebRuleTable) Update(ruleConf *GrpcWebRuleConf) {
+	t.lock.Lock()
+	t.version = ruleConf.Version
+	t.productRule = ruleConf.Config
+	t.lock.Unlock()
+}
+
+func (t *GrpcWebRuleTable) Search(product string) (GrpcWebRuleList, bool) {
+	t.lock.RLock()
+	ruleList, ok := t.productRule[product]
+	t.lock.RUnlock()
+
+	return ruleList, ok
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mod_grpc_web
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"testing"
+	"testing/iotest"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_http"
+)
+
+func TestContentTypeConvert(t *testing.T) {
+	requests := map[string]string{
+		"application/grpc-web":            "application/grpc",
+		"application/grpc-web+proto":      "application/grpc+proto",
+		"application/grpc-web-text":       "application/grpc",
+		"application/grpc-web-text+proto": "application/grpc+proto",
+	}
+	for web, grpc := range requests {
+		if got := toGrpcContentType(web); got != grpc {
+			t.Errorf("toGrpcContentType(%s) = %s, want %s", web, got, grpc)
+		}
+	}
+
+	responses := []struct {
+		ctype string
+		text  bool
+		want  string
+		ok    bool
+	}{
+		{"application/grpc", false, "application/grpc-web", true},
+		{"application/grpc+proto", true, "application/grpc-web-text+proto", true},
+		{"application/grpc-web", false, "", false},
+		{"text/html", false, "", false},
+	}
+	for _, tt := range responses {
+		got, ok := toGrpcWebContentType(tt.ctype, tt.text)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("toGrpcWebContentType(%s, %v) = %s %v, want %s %v", tt.ctype, tt.text, got, ok,
+				tt.want, tt.ok)
+		}
+	}
+}
+
+func TestEncodeTrailerFrame(t *testing.T) {
+	frame := encodeTrailerFrame(http.Header{"Grpc-Status": {"0"}, "Grpc-Message": {"ok"}})
+	block := "grpc-message: ok\r\ngrpc-status: 0\r\n"
+	want := append([]byte{0x80, 0, 0, 0, byte(len(block))}, block...)
+	if !bytes.Equal(frame, want) {
+		t.Errorf("encodeTrailerFrame() = %q, want %q", frame, want)
+	}
+}
+
+func TestTextDecoder(t *testing.T) {
+	msg1 := []byte("\x00\x00\x00\x00\x01a")
+	msg2 := []byte("\x00\x00\x00\x00\x02bc")
+
+	// chunks are padded separately
+	body := base64.StdEncoding.EncodeToString(msg1) + base64.StdEncoding.EncodeToString(msg2)
+	d := newTextDecoder(io.NopCloser(iotest.OneByteReader(bytes.NewBufferString(body))))
+	got, err := io.ReadAll(d)
+	if err != nil {
+		t.Fatalf("ReadAll() error: %v", err)
+	}
+	if want := append(msg1, msg2...); !bytes.Equal(got, want) {
+		t.Errorf("decoded %q, want %q", got, want)
+	}
+
+	d = newTextDecoder(io.NopCloser(bytes.NewBufferString("AAAA!!!!")))
+	if _, err := io.ReadAll(d); err == nil {
+		t.Errorf("should fail for invalid base64 data")
+	}
+
+	d = newTextDecoder(io.NopCloser(bytes.NewBufferString("AAAAAA")))
+	if _, err := io.ReadAll(d); err != io.ErrUnexpectedEOF {
+		t.Errorf("should fail for truncated data, got %v", err)
+	}
+}
+
+func TestWebBody(t *testing.T) {
+	msg := []byte("\x00\x00\x00\x00\x03abc")
+	trailer := http.Header{}
+	var eofTrailer http.Header
+
+	for _, text := range []bool{false, true} {
+		src := &trailerBody{Reader: iotest.OneByteReader(bytes.NewReader(msg)), trailer: &trailer}
+		b := newWebBody(src, &trailer, text, func(h http.Header) { eofTrailer = h })
+		got, err := io.ReadAll(b)
+		if err != nil {
+			t.Fatalf("ReadAll() error: %v", err)
+		}
+
+		want := append(append([]byte{}, msg...), encodeTrailerFrame(trailer)...)
+		if text {
+			want = []byte(base64.StdEncoding.EncodeToString(want))
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("text %v: body %q, want %q", text, got, want)
+		}
+		if eofTrailer.Get("Grpc-Status") != "0" {
+			t.Errorf("trailers should be passed at EOF")
+		}
+	}
+}
+
+// trailerBody populates trailers at EOF, as http2 transport does.
+type trailerBody struct {
+	io.Reader
+	trailer *http.Header
+}
+
+func (b *trailerBody) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	if err == io.EOF {
+		*b.trailer = http.Header{"Grpc-Status": {"0"}}
+	}
+	return n, err
+}
+
+func (b *trailerBody) Close() error {
+	return nil
+}
+
+func TestAddExposeHeaders(t *testing.T) {
+	tests := []struct {
+		header bfe_http.Header
+		want   string
+	}{
+		{bfe_http.Header{}, ""},
+		{bfe_http.Header{"Access-Control-Allow-Origin": {"*"}}, "grpc-status,grpc-message"},
+		{bfe_http.Header{"Access-Control-Allow-Origin": {"*"}, "Access-Control-Expose-Headers": {"X-Id"}},
+			"X-Id,grpc-status,grpc-message"},
+		{bfe_http.Header{"Access-Control-Allow-Origin": {"*"}, "Access-Control-Expose-Headers": {"Grpc-Status"}},
+			"Grpc-Status,grpc-message"},
+	}
+
+	for _, tt := range tests {
+		addExposeHeaders(tt.header)
+		if got := tt.header.Get("Access-Control-Expose-Headers"); got != tt.want {
+			t.Errorf("expose headers %s, want %s", got, tt.want)
+		}
+	}
+}
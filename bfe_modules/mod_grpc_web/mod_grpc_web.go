@@ -0,0 +1,259 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mod_grpc_web
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+)
+
+import (
+	"github.com/bfenetworks/go-lib/log"
+	"github.com/bfenetworks/go-lib/web-monitor/web_monitor"
+	"google.golang.org/grpc/codes"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_basic"
+	"github.com/bfenetworks/bfe/bfe_http"
+	"github.com/bfenetworks/bfe/bfe_module"
+)
+
+const (
+	ModGrpcWeb = "mod_grpc_web"
+
+	CtxGrpcWeb = "mod_grpc_web.state"
+)
+
+var (
+	openDebug = false
+)
+
+// grpcWebState is state of gRPC-Web request.
+type grpcWebState struct {
+	text       bool // whether request is in grpc-web-text (base64) format
+	translated bool // whether request to backend is translated to gRPC
+}
+
+// ModuleGrpcWeb translates gRPC-Web requests from browsers to gRPC, and
+// translates gRPC responses back to gRPC-Web.
+type ModuleGrpcWeb struct {
+	name      string
+	conf      *ConfModGrpcWeb
+	ruleTable *GrpcWebRuleTable
+}
+
+func NewModuleGrpcWeb() *ModuleGrpcWeb {
+	m := new(ModuleGrpcWeb)
+	m.name = ModGrpcWeb
+	m.ruleTable = NewGrpcWebRuleTable()
+	return m
+}
+
+func (m *ModuleGrpcWeb) Name() string {
+	return m.name
+}
+
+func (m *ModuleGrpcWeb) loadRuleData(query url.Values) (string, error) {
+	// get file path
+	path := query.Get("path")
+	if path == "" {
+		// use default
+		path = m.conf.Basic.DataPath
+	}
+
+	// load from config file
+	conf, err := GrpcWebRuleFileLoad(path)
+	if err != nil {
+		return "", fmt.Errorf("%s: GrpcWebRuleFileLoad(%s) error: %v", m.name, path, err)
+	}
+
+	// update to rule table
+	m.ruleTable.Update(conf)
+
+	_, fileName := filepath.Split(path)
+	return fmt.Sprintf("%s=%s", fileName, conf.Version), nil
+}
+
+func getState(request *bfe_basic.Request) *grpcWebState {
+	state, _ := request.GetContext(CtxGrpcWeb).(*grpcWebState)
+	return state
+}
+
+// isH2Cluster checks whether backends of target cluster speak http/2, which
+// is required by gRPC.
+func isH2Cluster(request *bfe_basic.Request) bool {
+	if request.SvrDataConf == nil {
+		return false
+	}
+	cluster, err := request.SvrDataConf.ClusterTableLookup(request.Route.ClusterName)
+	if err != nil || cluster == nil {
+		return false
+	}
+	backendConf := cluster.BackendConf()
+	if backendConf == nil || backendConf.Protocol == nil {
+		return false
+	}
+	protocol := *backendConf.Protocol
+	return protocol == "h2c" || protocol == "h2"
+}
+
+// grpcWebHandler checks whether gRPC-Web request should be translated.
+// Request is rejected if target cluster does not speak http/2.
+func (m *ModuleGrpcWeb) grpcWebHandler(request *bfe_basic.Request) (int, *bfe_http.Response) {
+	if !bfe_basic.IsGrpcWebRequest(request.HttpRequest) {
+		return bfe_module.BfeHandlerGoOn, nil
+	}
+
+	rules, ok := m.ruleTable.Search(request.Route.Product)
+	if !ok {
+		return bfe_module.BfeHandlerGoOn, nil
+	}
+
+	for _, rule := range rules {
+		if !rule.Cond.Match(request) {
+			continue
+		}
+
+		if !isH2Cluster(request) {
+			log.Logger.Warn("%s: cluster %s of product %s is not h2c/h2, grpc-web request rejected",
+				m.name, request.Route.ClusterName, request.Route.Product)
+			msg := fmt.Sprintf("bfe: grpc-web requires h2c/h2 cluster, cluster %s is not",
+				request.Route.ClusterName)
+			return bfe_module.BfeHandlerResponse, bfe_basic.CreateGrpcErrResp(request, codes.Internal, msg)
+		}
+
+		if openDebug {
+			log.Logger.Info("%s translate grpc-web request: %s", request.Route.Product,
+				request.HttpRequest.URL.Path)
+		}
+		text := isTextContentType(request.HttpRequest.Header.Get("Content-Type"))
+		request.SetContext(CtxGrpcWeb, &grpcWebState{text: text})
+		break
+	}
+
+	return bfe_module.BfeHandlerGoOn, nil
+}
+
+// forwardHandler translates gRPC-Web request to gRPC before forwarding.
+// Note: it may be invoked more than once for retries.
+func (m *ModuleGrpcWeb) forwardHandler(request *bfe_basic.Request) int {
+	state := getState(request)
+	if state == nil || state.translated {
+		return bfe_module.BfeHandlerGoOn
+	}
+
+	outreq := request.OutRequest
+	header := make(bfe_http.Header, len(outreq.Header))
+	bfe_http.CopyHeader(header, outreq.Header)
+	header.Set("Content-Type", toGrpcContentType(header.Get("Content-Type")))
+	header.Set("Te", "trailers")
+	header.Del("X-Grpc-Web")
+	outreq.Header = header
+
+	if state.text {
+		outreq.Body = newTextDecoder(outreq.Body)
+		outreq.ContentLength = -1
+		outreq.Header.Del("Content-Length")
+	}
+
+	state.translated = true
+	return bfe_module.BfeHandlerGoOn
+}
+
+// responseHandler translates gRPC response to gRPC-Web.
+func (m *ModuleGrpcWeb) responseHandler(request *bfe_basic.Request, res *bfe_http.Response) int {
+	state := getState(request)
+	if state == nil || res == nil {
+		return bfe_module.BfeHandlerGoOn
+	}
+
+	ctype, ok := toGrpcWebContentType(res.Header.Get("Content-Type"), state.text)
+	if !ok {
+		// not gRPC response, e.g. error page of backend
+		return bfe_module.BfeHandlerGoOn
+	}
+	res.Header.Set("Content-Type", ctype)
+	res.Header.Del("Content-Length")
+	res.ContentLength = -1
+	addExposeHeaders(res.Header)
+
+	// trailers are sent in body, instead of http trailers
+	trailer := res.H2Trailer
+	res.H2Trailer = nil
+	res.Body = newWebBody(res.Body, trailer, state.text, func(trailer http.Header) {
+		info := request.GetGrpcInfo()
+		if info != nil && trailer.Get(bfe_basic.HeaderGrpcStatus) != "" {
+			info.Status = trailer.Get(bfe_basic.HeaderGrpcStatus)
+			info.Message = trailer.Get(bfe_basic.HeaderGrpcMessage)
+		}
+	})
+
+	return bfe_module.BfeHandlerGoOn
+}
+
+func (m *ModuleGrpcWeb) reloadHandlers() map[string]interface{} {
+	handlers := map[string]interface{}{
+		m.name: m.loadRuleData,
+	}
+	return handlers
+}
+
+func (m *ModuleGrpcWeb) init(conf *ConfModGrpcWeb, cbs *bfe_module.BfeCallbacks, whs *web_monitor.WebHandlers) error {
+	var err error
+
+	_, err = m.loadRuleData(nil)
+	if err != nil {
+		return err
+	}
+
+	err = cbs.AddFilter(bfe_module.HandleAfterLocation, m.grpcWebHandler)
+	if err != nil {
+		return fmt.Errorf("%s.Init(): AddFilter(m.grpcWebHandler): %v", m.name, err)
+	}
+
+	err = cbs.AddFilter(bfe_module.HandleForward, m.forwardHandler)
+	if err != nil {
+		return fmt.Errorf("%s.Init(): AddFilter(m.forwardHandler): %v", m.name, err)
+	}
+
+	err = cbs.AddFilter(bfe_module.HandleReadResponse, m.responseHandler)
+	if err != nil {
+		return fmt.Errorf("%s.Init(): AddFilter(m.responseHandler): %v", m.name, err)
+	}
+
+	err = web_monitor.RegisterHandlers(whs, web_monitor.WebHandleReload, m.reloadHandlers())
+	if err != nil {
+		return fmt.Errorf("%s.Init():RegisterHandlers(m.reloadHandlers): %v", m.name, err)
+	}
+
+	return nil
+}
+
+func (m *ModuleGrpcWeb) Init(cbs *bfe_module.BfeCallbacks, whs *web_monitor.WebHandlers, cr string) error {
+	var err error
+	var conf *ConfModGrpcWeb
+
+	confPath := bfe_module.ModConfPath(cr, m.name)
+	if conf, err = ConfLoad(confPath, cr); err != nil {
+		return fmt.Errorf("%s: conf load err %s", m.name, err.Error())
+	}
+
+	m.conf = conf
+	openDebug = conf.Log.OpenDebug
+	return m.init(conf, cbs, whs)
+}
@@ -0,0 +1,203 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mod_grpc_web
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+import (
+	"github.com/bfenetworks/go-lib/web-monitor/web_monitor"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_basic"
+	"github.com/bfenetworks/bfe/bfe_config/bfe_cluster_conf/cluster_conf"
+	"github.com/bfenetworks/bfe/bfe_http"
+	"github.com/bfenetworks/bfe/bfe_module"
+	"github.com/bfenetworks/bfe/bfe_route/bfe_cluster"
+)
+
+const (
+	expectProduct = "example_product"
+	expectCluster = "example_cluster"
+)
+
+type testServerDataConf struct {
+	protocol string // backend protocol of cluster
+}
+
+func (c *testServerDataConf) ClusterTableLookup(clusterName string) (*bfe_cluster.BfeCluster, error) {
+	basic := cluster_conf.ClusterBasicConf{}
+	cluster_conf.ClusterBasicConfCheck(&basic)
+
+	cluster := bfe_cluster.NewBfeCluster(clusterName)
+	cluster.BasicInit(cluster_conf.ClusterConf{
+		BackendConf:  &cluster_conf.BackendBasic{Protocol: &c.protocol},
+		ClusterBasic: &basic,
+	})
+	return cluster, nil
+}
+
+func (c *testServerDataConf) HostTableLookup(hostname string) (string, error) {
+	return hostname, nil
+}
+
+func newTestModule(t *testing.T) *ModuleGrpcWeb {
+	m := NewModuleGrpcWeb()
+	cb := bfe_module.NewBfeCallbacks()
+	wh := web_monitor.NewWebHandlers()
+	if err := m.Init(cb, wh, "./testdata"); err != nil {
+		t.Fatalf("Init() error: %v", err)
+	}
+	return m
+}
+
+func newTestRequest(t *testing.T, url string, ctype string, body string) *bfe_basic.Request {
+	httpReq, err := bfe_http.NewRequest("POST", url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("bfe_http.NewRequest error: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", ctype)
+	httpReq.Header.Set("X-Grpc-Web", "1")
+
+	req := bfe_basic.NewRequest(httpReq, nil, nil, new(bfe_basic.Session), &testServerDataConf{"h2c"})
+	req.Route.Product = expectProduct
+	req.Route.ClusterName = expectCluster
+	req.SetGrpcInfo(&bfe_basic.GrpcInfo{Service: "helloworld.Greeter", Method: "SayHello"})
+
+	outreq := new(bfe_http.Request)
+	*outreq = *httpReq
+	req.OutRequest = outreq
+	return req
+}
+
+func TestGrpcWebHandler(t *testing.T) {
+	m := newTestModule(t)
+
+	tests := []struct {
+		url   string
+		ctype string
+		match bool
+	}{
+		{"http://grpc.example.org/helloworld.Greeter/SayHello", "application/grpc-web+proto", true},
+		{"http://grpc.example.org/helloworld.Greeter/SayHello", "application/grpc", false},
+		{"http://www.example.org/helloworld.Greeter/SayHello", "application/grpc-web", false},
+	}
+
+	for _, tt := range tests {
+		req := newTestRequest(t, tt.url, tt.ctype, "")
+		ret, res := m.grpcWebHandler(req)
+		if ret != bfe_module.BfeHandlerGoOn || res != nil {
+			t.Errorf("grpcWebHandler() should go on")
+		}
+		if matched := getState(req) != nil; matched != tt.match {
+			t.Errorf("%s %s: matched %v, want %v", tt.url, tt.ctype, matched, tt.match)
+		}
+	}
+}
+
+func TestGrpcWebHandlerNonH2Cluster(t *testing.T) {
+	m := newTestModule(t)
+
+	req := newTestRequest(t, "http://grpc.example.org/helloworld.Greeter/SayHello",
+		"application/grpc-web+proto", "")
+	req.SvrDataConf = &testServerDataConf{"http"}
+	ret, res := m.grpcWebHandler(req)
+	if ret != bfe_module.BfeHandlerResponse || res == nil {
+		t.Fatalf("grpc-web request to http cluster should be rejected")
+	}
+	if res.StatusCode != 200 || res.Header.Get("Grpc-Status") != "13" {
+		t.Errorf("unexpected response: %d %v", res.StatusCode, res.Header)
+	}
+	if ctype := res.Header.Get("Content-Type"); ctype != "application/grpc-web+proto" {
+		t.Errorf("content type of response should be application/grpc-web+proto, got %s", ctype)
+	}
+	if getState(req) != nil {
+		t.Errorf("request should not be translated")
+	}
+}
+
+func TestGrpcWebTranslate(t *testing.T) {
+	m := newTestModule(t)
+
+	msg := []byte("\x00\x00\x00\x00\x03abc")
+	req := newTestRequest(t, "http://grpc.example.org/helloworld.Greeter/SayHello",
+		"application/grpc-web-text+proto", base64.StdEncoding.EncodeToString(msg))
+	m.grpcWebHandler(req)
+
+	// translate request, only once for retries
+	m.forwardHandler(req)
+	m.forwardHandler(req)
+	outreq := req.OutRequest
+	if ctype := outreq.Header.Get("Content-Type"); ctype != "application/grpc+proto" {
+		t.Errorf("content type of request should be application/grpc+proto, got %s", ctype)
+	}
+	if outreq.Header.Get("Te") != "trailers" || outreq.Header.Get("X-Grpc-Web") != "" {
+		t.Errorf("unexpected header of request: %v", outreq.Header)
+	}
+	if req.HttpRequest.Header.Get("Content-Type") != "application/grpc-web-text+proto" {
+		t.Errorf("header of original request should not be modified")
+	}
+	body, err := io.ReadAll(outreq.Body)
+	if err != nil || !bytes.Equal(body, msg) {
+		t.Errorf("body of request should be decoded, got %q %v", body, err)
+	}
+
+	// translate response
+	trailer := http.Header{}
+	res := &bfe_http.Response{
+		StatusCode: 200,
+		Header: bfe_http.Header{
+			"Content-Type":                {"application/grpc+proto"},
+			"Access-Control-Allow-Origin": {"*"},
+		},
+		Body:      &trailerBody{Reader: bytes.NewReader(msg), trailer: &trailer},
+		H2Trailer: &trailer,
+	}
+	if ret := m.responseHandler(req, res); ret != bfe_module.BfeHandlerGoOn {
+		t.Errorf("responseHandler() should go on")
+	}
+	if ctype := res.Header.Get("Content-Type"); ctype != "application/grpc-web-text+proto" {
+		t.Errorf("content type of response should be application/grpc-web-text+proto, got %s", ctype)
+	}
+	if res.Header.Get("Access-Control-Expose-Headers") != "grpc-status,grpc-message" {
+		t.Errorf("grpc-status and grpc-message should be exposed")
+	}
+	if res.H2Trailer != nil {
+		t.Errorf("trailers should be sent in body")
+	}
+
+	body, err = io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("ReadAll() error: %v", err)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(string(body))
+	if err != nil {
+		t.Fatalf("body of response should be base64 encoded: %v", err)
+	}
+	want := append(append([]byte{}, msg...), encodeTrailerFrame(trailer)...)
+	if !bytes.Equal(decoded, want) {
+		t.Errorf("body of response %q, want %q", decoded, want)
+	}
+	if req.GetGrpcInfo().Status != "0" {
+		t.Errorf("grpc status should be recorded, got %s", req.GetGrpcInfo().Status)
+	}
+}
@@ -64,6 +64,7 @@ var VariableHandlers = map[string]HeaderValueHandler{
 	"bfe_ssl_version":                         getBfeSslVersion,
 	"bfe_ssl_ja3_raw":                         getBfeSslJa3Raw,
 	"bfe_ssl_ja3_hash":                        getBfeSslJa3Hash,
+	"bfe_ssl_ja4":                             getBfeSslJa4,
 	"bfe_protocol":                            getBfeProtocol,
 	"client_cert_serial_number":               getClientCertSerialNumber,
 	"client_cert_subject_title":               getClientCertSubjectTitle,
@@ -124,6 +125,8 @@ func getProto(proto string) string {
 		return "31"
 	case "h2":
 		return "h2"
+	case "h3":
+		return "h3"
 	case "stream":
 		return "st"
 	default:
@@ -207,6 +210,15 @@ func getBfeSslJa3Hash(req *bfe_basic.Request) string {
 	return state.JA3Hash
 }
 
+// get tls ja4 fingerprint
+func getBfeSslJa4(req *bfe_basic.Request) string {
+	if req.Session.TlsState == nil {
+		return ""
+	}
+	state := req.Session.TlsState
+	return state.JA4
+}
+
 // get protocol for application level
 func getBfeProtocol(req *bfe_basic.Request) string {
 	return req.Protocol()
@@ -137,6 +137,7 @@ func TestGetProto(t *testing.T) {
 		{"spdy/3", "30"},
 		{"spdy/3.1", "31"},
 		{"h2", "h2"},
+		{"h3", "h3"},
 		{"stream", "st"},
 		{"http/1.1", "00"},
 	}
@@ -197,6 +198,7 @@ func TestGetBfeSslFunctions(t *testing.T) {
 		DidResume:   true,
 		JA3Raw:      "raw",
 		JA3Hash:     "hash",
+		JA4:         "ja4",
 	}
 
 	if got := getBfeSslResume(req); got != "R" {
@@ -214,6 +216,9 @@ func TestGetBfeSslFunctions(t *testing.T) {
 	if got := getBfeSslJa3Hash(req); got != "hash" {
 		t.Errorf("getBfeSslJa3Hash = %s, want hash", got)
 	}
+	if got := getBfeSslJa4(req); got != "ja4" {
+		t.Errorf("getBfeSslJa4 = %s, want ja4", got)
+	}
 
 	req.Session.TlsState = nil
 	if got := getBfeSslResume(req); got != "" {
@@ -344,6 +349,9 @@ func TestGetBfeSslJa3NilState(t *testing.T) {
 	if got := getBfeSslJa3Hash(req); got != "" {
 		t.Errorf("getBfeSslJa3Hash(nil) = %s, want empty", got)
 	}
+	if got := getBfeSslJa4(req); got != "" {
+		t.Errorf("getBfeSslJa4(nil) = %s, want empty", got)
+	}
 }
 
 func TestGetBfeCluster(t *testing.T) {
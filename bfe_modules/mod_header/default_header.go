@@ -21,6 +21,7 @@ import (
 
 import (
 	"github.com/bfenetworks/bfe/bfe_basic"
+	"github.com/bfenetworks/bfe/bfe_util"
 )
 
 func modHeaderForwardedAddr(req *bfe_basic.Request) {
@@ -50,6 +51,6 @@ func setHeaderRealAddr(req *bfe_basic.Request, clientIP string, clientPort strin
 }
 
 func setHeaderBfeIP(req *bfe_basic.Request) {
-	localip := req.Connection.LocalAddr().(*net.TCPAddr).IP.String()
+	localip := bfe_util.GetLocalIP(req.Connection).String()
 	req.HttpRequest.Header.Set(bfe_basic.HeaderBfeIP, localip)
 }
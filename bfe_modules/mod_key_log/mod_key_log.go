@@ -29,6 +29,7 @@ import (
 import (
 	"github.com/bfenetworks/bfe/bfe_basic"
 	"github.com/bfenetworks/bfe/bfe_module"
+	"github.com/bfenetworks/bfe/bfe_tls"
 	"github.com/bfenetworks/bfe/bfe_util/access_log"
 )
 
@@ -64,6 +65,11 @@ func (m *ModuleKeyLog) logTlsKey(session *bfe_basic.Session) int {
 		if tlsState == nil {
 			return bfe_module.BfeHandlerGoOn
 		}
+		if tlsState.Version == bfe_tls.VersionTLS13 {
+			m.logTls13Key(tlsState)
+			return bfe_module.BfeHandlerGoOn
+		}
+
 		// key log format: <label> <ClientRandom> <MasterSecret>
 		keyLog := fmt.Sprintf("CLIENT_RANDOM %s %s",
 			hex.EncodeToString(tlsState.ClientRandom), // connection id
@@ -73,6 +79,27 @@ func (m *ModuleKeyLog) logTlsKey(session *bfe_basic.Session) int {
 	return bfe_module.BfeHandlerGoOn
 }
 
+// logTls13Key writes traffic secrets of TLS 1.3 connection
+func (m *ModuleKeyLog) logTls13Key(tlsState *bfe_tls.ConnectionState) {
+	labels := []string{
+		bfe_tls.KeyLogLabelClientHandshake,
+		bfe_tls.KeyLogLabelServerHandshake,
+		bfe_tls.KeyLogLabelClientTraffic,
+		bfe_tls.KeyLogLabelServerTraffic,
+	}
+	for _, label := range labels {
+		secret, ok := tlsState.TrafficSecrets[label]
+		if !ok {
+			continue
+		}
+		// key log format: <label> <ClientRandom> <Secret>
+		keyLog := fmt.Sprintf("%s %s %s", label,
+			hex.EncodeToString(tlsState.ClientRandom),
+			hex.EncodeToString(secret))
+		m.logger.Info(keyLog)
+	}
+}
+
 // isNeedKeyLog Determine if you need to print the key log
 func (m *ModuleKeyLog) isNeedKeyLog(session *bfe_basic.Session) bool {
 	rules, ok := m.ruleTable.Search(session.Product)
@@ -118,6 +118,53 @@ func TestLogTlsKey(t *testing.T) {
 	}
 }
 
+func TestLogTlsKeyTLS13(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mod_key_log_test")
+	if err != nil {
+		t.Fatalf("TempDir() error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	logFile, err := ioutil.TempFile(dir, "key.log")
+	if err != nil {
+		t.Fatalf("TempFile() error: %v", err)
+	}
+	logFile.Close()
+
+	m := NewModuleKeyLog()
+	m.logger, err = access_log.LoggerInit(access_log.LogConfig{LogFile: logFile.Name()})
+	if err != nil {
+		t.Fatalf("logger init error: %v", err)
+	}
+
+	conf, err := keyLogConfLoad("./testdata/mod_key_log/key_log.json")
+	if err != nil {
+		t.Fatalf("keyLogConfLoad() error: %v", err)
+	}
+	m.ruleTable.Update(conf)
+
+	session := &bfe_basic.Session{
+		Product:    "example_product",
+		RemoteAddr: &net.TCPAddr{IP: net.ParseIP("10.0.0.5"), Port: 12345},
+		TlsState: &bfe_tls.ConnectionState{
+			Version:      bfe_tls.VersionTLS13,
+			ServerName:   "example.com",
+			ClientRandom: []byte{0x01, 0x02, 0x03},
+			TrafficSecrets: map[string][]byte{
+				bfe_tls.KeyLogLabelClientHandshake: {0x04},
+				bfe_tls.KeyLogLabelServerHandshake: {0x05},
+				bfe_tls.KeyLogLabelClientTraffic:   {0x06},
+				bfe_tls.KeyLogLabelServerTraffic:   {0x07},
+			},
+		},
+	}
+
+	ret := m.logTlsKey(session)
+	if ret != bfe_module.BfeHandlerGoOn {
+		t.Errorf("ret should be BfeHandlerGoOn, got: %d", ret)
+	}
+}
+
 func TestLogTlsKeyNotNeed(t *testing.T) {
 	m := NewModuleKeyLog()
 	conf, err := keyLogConfLoad("./testdata/mod_key_log/key_log.json")
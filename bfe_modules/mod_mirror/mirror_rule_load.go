@@ -0,0 +1,177 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mod_mirror
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_basic/condition"
+	"github.com/bfenetworks/bfe/bfe_util/json"
+)
+
+const (
+	DefaultMirrorPercent     = 100
+	DefaultMirrorMaxBodySize = 64 * 1024 // in bytes
+	DefaultMirrorTimeout     = 1000      // in ms
+)
+
+type MirrorRuleFile struct {
+	Version string             // version
+	Config  ProductRuleRawList // product -> raw rule list
+}
+
+type MirrorRuleConf struct {
+	Version string          // version
+	Config  ProductRuleList // product -> rule list
+}
+
+type MirrorRuleRaw struct {
+	Cond   string          // condition
+	Action MirrorActionRaw // mirror action
+}
+
+type MirrorActionRaw struct {
+	Cluster     string // name of shadow cluster
+	Percent     *int   // percent of requests to mirror, default 100
+	MaxBodySize *int64 // max size of request body to mirror, in bytes
+	Timeout     *int   // timeout of mirrored request, in ms
+}
+
+type ProductRuleRawList map[string]RuleRawList // product => raw rule list
+type RuleRawList []MirrorRuleRaw
+
+func MirrorRuleCheck(ruleFile *MirrorRuleFile) error {
+	if ruleFile == nil {
+		return fmt.Errorf("mirrorRuleFile is nil")
+	}
+
+	if len(ruleFile.Version) == 0 {
+		return fmt.Errorf("no Version")
+	}
+
+	if ruleFile.Config == nil {
+		return fmt.Errorf("no Config")
+	}
+
+	return nil
+}
+
+func actionConvert(rawAction MirrorActionRaw) (*MirrorAction, error) {
+	if len(rawAction.Cluster) == 0 {
+		return nil, fmt.Errorf("Cluster may be empty")
+	}
+
+	action := &MirrorAction{
+		Cluster:     rawAction.Cluster,
+		Percent:     DefaultMirrorPercent,
+		MaxBodySize: DefaultMirrorMaxBodySize,
+		Timeout:     DefaultMirrorTimeout * time.Millisecond,
+	}
+
+	if rawAction.Percent != nil {
+		if *rawAction.Percent < 0 || *rawAction.Percent > 100 {
+			return nil, fmt.Errorf("Percent should be in [0, 100]")
+		}
+		action.Percent = *rawAction.Percent
+	}
+
+	if rawAction.MaxBodySize != nil {
+		if *rawAction.MaxBodySize < 0 {
+			return nil, fmt.Errorf("MaxBodySize should >= 0")
+		}
+		action.MaxBodySize = *rawAction.MaxBodySize
+	}
+
+	if rawAction.Timeout != nil {
+		if *rawAction.Timeout <= 0 {
+			return nil, fmt.Errorf("Timeout should > 0")
+		}
+		action.Timeout = time.Duration(*rawAction.Timeout) * time.Millisecond
+	}
+
+	return action, nil
+}
+
+func ruleConvert(rawRule MirrorRuleRaw) (*MirrorRule, error) {
+	cond, err := condition.Build(rawRule.Cond)
+	if err != nil {
+		return nil, err
+	}
+
+	action, err := actionConvert(rawRule.Action)
+	if err != nil {
+		return nil, err
+	}
+
+	var rule MirrorRule
+	rule.Cond = cond
+	rule.Action = *action
+
+	return &rule, nil
+}
+
+func ruleListConvert(rawRuleList RuleRawList) (MirrorRuleList, error) {
+	ruleList := MirrorRuleList{}
+	for i, rawRule := range rawRuleList {
+		rule, err := ruleConvert(rawRule)
+		if err != nil {
+			return nil, fmt.Errorf("rule [%d] error: %v", i, err)
+		}
+
+		ruleList = append(ruleList, *rule)
+	}
+
+	return ruleList, nil
+}
+
+func MirrorRuleFileLoad(filename string) (*MirrorRuleConf, error) {
+	var ruleFile MirrorRuleFile
+	var ruleConf MirrorRuleConf
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+
+	err = decoder.Decode(&ruleFile)
+	if err != nil {
+		return nil, err
+	}
+
+	err = MirrorRuleCheck(&ruleFile)
+	if err != nil {
+		return nil, err
+	}
+
+	ruleConf.Version = ruleFile.Version
+	ruleConf.Config = make(ProductRuleList)
+
+	for product, rawRuleList := range ruleFile.Config {
+		ruleList, err := ruleListConvert(rawRuleList)
+		if err != nil {
+			return nil, fmt.Errorf("product[%s] rule error: %v", product, err)
+		}
+		ruleConf.Config[product] = ruleList
+	}
+
+	return &ruleConf, nil
+}
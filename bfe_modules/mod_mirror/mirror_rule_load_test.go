@@ -0,0 +1,72 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mod_mirror
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMirrorRuleFileLoad(t *testing.T) {
+	conf, err := MirrorRuleFileLoad("testdata/mod_mirror/mirror_rule.data")
+	if err != nil {
+		t.Fatalf("should have no error, but error is %v", err)
+	}
+
+	if conf.Version != "20260101000000" {
+		t.Errorf("Version should be 20260101000000, but it's %s", conf.Version)
+	}
+
+	ruleList, ok := conf.Config[expectProduct]
+	if !ok || len(ruleList) != 3 {
+		t.Fatalf("product %s should have 3 rules", expectProduct)
+	}
+
+	action := ruleList[1].Action
+	if action.Cluster != "cluster_shadow" || action.Percent != 100 ||
+		action.MaxBodySize != 1024 || action.Timeout != 500*time.Millisecond {
+		t.Errorf("unexpected action: %+v", action)
+	}
+
+	// default values
+	action = ruleList[2].Action
+	if action.Percent != DefaultMirrorPercent || action.MaxBodySize != DefaultMirrorMaxBodySize ||
+		action.Timeout != DefaultMirrorTimeout*time.Millisecond {
+		t.Errorf("unexpected default action: %+v", action)
+	}
+}
+
+func TestMirrorRuleFileLoadError(t *testing.T) {
+	tests := []struct {
+		path   string
+		errMsg string
+	}{
+		{"testdata/mod_mirror/mirror_rule.data1", "Cluster may be empty"},
+		{"testdata/mod_mirror/mirror_rule.data2", "Percent should be in [0, 100]"},
+		{"testdata/mod_mirror/mirror_rule.data3", "Timeout should > 0"},
+	}
+
+	for _, tt := range tests {
+		_, err := MirrorRuleFileLoad(tt.path)
+		if err == nil {
+			t.Errorf("%s: should have error", tt.path)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.errMsg) {
+			t.Errorf("%s: error message is not expected: %v", tt.path, err)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mod_mirror
+
+import (
+	"sync"
+	"time"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_basic/condition"
+)
+
+type MirrorRuleTable struct {
+	lock        sync.RWMutex
+	version     string
+	productRule ProductRuleList // product => rule list
+}
+
+type MirrorRule struct {
+	Cond   condition.Condition
+	Action MirrorAction
+}
+
+type MirrorAction struct {
+	Cluster     string        // name of shadow cluster
+	Percent     int           // percent of requests to mirror
+	MaxBodySize int64         // max size of request body to mirror
+	Timeout     time.Duration // timeout of mirrored request
+}
+
+type ProductRuleList map[string]MirrorRuleList // product => list of mirror rule
+type MirrorRuleList []MirrorRule
+
+func NewMirrorRuleTable() *MirrorRuleTable {
+	t := new(MirrorRuleTable)
+	t.productRule = make(ProductRuleList)
+	return t
+}
+
+func (t *MirrorRuleTable) Update(ruleConf *MirrorRuleConf) {
+	t.lock.Lock()
+	t.version = ruleConf.Version
+	t.productRule = ruleConf.Config
+	t.lock.Unlock()
+}
+
+func (t *MirrorRuleTable) Search(product string) (MirrorRuleList, bool) {
+	t.lock.RLock()
+	ruleList, ok := t.productRule[product]
+	t.lock.RUnlock()
+
+	return ruleList, ok
+}
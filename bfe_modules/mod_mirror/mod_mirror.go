@@ -0,0 +1,154 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mod_mirror
+
+import (
+	"fmt"
+	"math/rand"
+	"net/url"
+	"path/filepath"
+)
+
+import (
+	"github.com/bfenetworks/go-lib/log"
+	"github.com/bfenetworks/go-lib/web-monitor/web_monitor"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_basic"
+	"github.com/bfenetworks/bfe/bfe_http"
+	"github.com/bfenetworks/bfe/bfe_module"
+)
+
+const (
+	ModMirror = "mod_mirror"
+)
+
+var (
+	openDebug = false
+)
+
+// ModuleMirror selects requests to be mirrored to shadow cluster. The
+// mirrored requests are sent by bfe_server asynchronously.
+type ModuleMirror struct {
+	name      string
+	conf      *ConfModMirror
+	ruleTable *MirrorRuleTable
+}
+
+func NewModuleMirror() *ModuleMirror {
+	m := new(ModuleMirror)
+	m.name = ModMirror
+	m.ruleTable = NewMirrorRuleTable()
+	return m
+}
+
+func (m *ModuleMirror) Name() string {
+	return m.name
+}
+
+func (m *ModuleMirror) loadRuleData(query url.Values) (string, error) {
+	// get file path
+	path := query.Get("path")
+	if path == "" {
+		// use default
+		path = m.conf.Basic.DataPath
+	}
+
+	// load from config file
+	conf, err := MirrorRuleFileLoad(path)
+	if err != nil {
+		return "", fmt.Errorf("%s: MirrorRuleFileLoad(%s) error: %v", m.name, path, err)
+	}
+
+	// update to rule table
+	m.ruleTable.Update(conf)
+
+	_, fileName := filepath.Split(path)
+	return fmt.Sprintf("%s=%s", fileName, conf.Version), nil
+}
+
+func (m *ModuleMirror) mirrorHandler(request *bfe_basic.Request) (int, *bfe_http.Response) {
+	rules, ok := m.ruleTable.Search(request.Route.Product)
+	if !ok {
+		return bfe_module.BfeHandlerGoOn, nil
+	}
+
+	for _, rule := range rules {
+		if !rule.Cond.Match(request) {
+			continue
+		}
+
+		// only the first matched rule takes effect
+		action := rule.Action
+		if action.Percent < 100 && rand.Intn(100) >= action.Percent {
+			break
+		}
+
+		if openDebug {
+			log.Logger.Info("%s mirror request to cluster: %s", request.Route.Product, action.Cluster)
+		}
+		request.SetMirrorPolicy(&bfe_basic.MirrorPolicy{
+			ClusterName: action.Cluster,
+			MaxBodySize: action.MaxBodySize,
+			Timeout:     action.Timeout,
+		})
+		break
+	}
+
+	return bfe_module.BfeHandlerGoOn, nil
+}
+
+func (m *ModuleMirror) reloadHandlers() map[string]interface{} {
+	handlers := map[string]interface{}{
+		m.name: m.loadRuleData,
+	}
+	return handlers
+}
+
+func (m *ModuleMirror) init(conf *ConfModMirror, cbs *bfe_module.BfeCallbacks, whs *web_monitor.WebHandlers) error {
+	var err error
+
+	_, err = m.loadRuleData(nil)
+	if err != nil {
+		return err
+	}
+
+	err = cbs.AddFilter(bfe_module.HandleAfterLocation, m.mirrorHandler)
+	if err != nil {
+		return fmt.Errorf("%s.Init(): AddFilter(m.mirrorHandler): %v", m.name, err)
+	}
+
+	err = web_monitor.RegisterHandlers(whs, web_monitor.WebHandleReload, m.reloadHandlers())
+	if err != nil {
+		return fmt.Errorf("%s.Init():RegisterHandlers(m.reloadHandlers): %v", m.name, err)
+	}
+
+	return nil
+}
+
+func (m *ModuleMirror) Init(cbs *bfe_module.BfeCallbacks, whs *web_monitor.WebHandlers, cr string) error {
+	var err error
+	var conf *ConfModMirror
+
+	confPath := bfe_module.ModConfPath(cr, m.name)
+	if conf, err = ConfLoad(confPath, cr); err != nil {
+		return fmt.Errorf("%s: conf load err %s", m.name, err.Error())
+	}
+
+	m.conf = conf
+	openDebug = conf.Log.OpenDebug
+	return m.init(conf, cbs, whs)
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mod_mirror
+
+import (
+	"testing"
+)
+
+import (
+	"github.com/bfenetworks/go-lib/web-monitor/web_monitor"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_basic"
+	"github.com/bfenetworks/bfe/bfe_http"
+	"github.com/bfenetworks/bfe/bfe_module"
+)
+
+const (
+	expectProduct = "example_product"
+)
+
+func newTestRequest(t *testing.T, product string, url string) *bfe_basic.Request {
+	httpReq, err := bfe_http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Fatalf("bfe_http.NewRequest error: %v", err)
+	}
+	req := bfe_basic.NewRequest(httpReq, nil, nil, new(bfe_basic.Session), nil)
+	req.Route.Product = product
+	return req
+}
+
+func TestMirrorHandler(t *testing.T) {
+	m := NewModuleMirror()
+	cb := bfe_module.NewBfeCallbacks()
+	wh := web_monitor.NewWebHandlers()
+	if err := m.Init(cb, wh, "./testdata"); err != nil {
+		t.Fatalf("Init() error: %v", err)
+	}
+
+	tests := []struct {
+		product string
+		url     string
+		cluster string // expected shadow cluster, empty for no mirroring
+	}{
+		{expectProduct, "http://example.org/", "cluster_shadow"},
+		{expectProduct, "http://example.com/", "cluster_other"},
+		{"zero_product", "http://example.org/", ""},
+		{"unknown_product", "http://example.org/", ""},
+	}
+
+	for _, tt := range tests {
+		req := newTestRequest(t, tt.product, tt.url)
+		ret, res := m.mirrorHandler(req)
+		if ret != bfe_module.BfeHandlerGoOn || res != nil {
+			t.Errorf("mirrorHandler() should go on")
+		}
+
+		policy := req.GetMirrorPolicy()
+		switch {
+		case tt.cluster == "" && policy != nil:
+			t.Errorf("%s %s: should not be mirrored", tt.product, tt.url)
+		case tt.cluster != "" && (policy == nil || policy.ClusterName != tt.cluster):
+			t.Errorf("%s %s: should be mirrored to %s, policy %+v", tt.product, tt.url, tt.cluster, policy)
+		}
+	}
+}
@@ -34,6 +34,8 @@ type BfeCluster struct {
 	httpsConf   *cluster_conf.BackendHTTPS  // https basic
 	AIConf 	    *cluster_conf.AIConf        // ai conf for cluster
 
+	outlierDetection *cluster_conf.OutlierDetectionConf // passive outlier detection conf
+
 	timeoutReadClient      time.Duration // timeout for read client body
 	timeoutReadClientAgain time.Duration // timeout for read client again
 	timeoutWriteClient     time.Duration // timeout for write response to client
@@ -45,6 +47,15 @@ type BfeCluster struct {
 
 	DisableHostHeader  bool // disable setting host header for backend
 	DisableHealthCheck bool // disable health check for backend
+
+	maxRequests        int // max concurrent requests to cluster
+	maxPendingRequests int // max concurrent requests waiting for response header
+	maxRetries         int // max concurrent retries to cluster
+
+	retryBudgetPercent    int           // retries allowed in percentage of recent requests
+	retryBudgetMinRetries int           // min retries per second allowed by retry budget
+	hedgePercentile       int           // latency percentile to send hedged request
+	hedgeMinDelay         time.Duration // min delay before sending hedged request
 }
 
 func NewBfeCluster(name string) *BfeCluster {
@@ -63,6 +74,7 @@ func (cluster *BfeCluster) BasicInit(clusterConf cluster_conf.ClusterConf) {
 	cluster.GslbBasic = clusterConf.GslbBasic
 
 	cluster.AIConf = clusterConf.AIConf
+	cluster.outlierDetection = clusterConf.OutlierDetection
 	
 	cluster.timeoutReadClient =
 		time.Duration(*clusterConf.ClusterBasic.TimeoutReadClient) * time.Millisecond
@@ -81,6 +93,16 @@ func (cluster *BfeCluster) BasicInit(clusterConf cluster_conf.ClusterConf) {
 	cluster.DisableHostHeader = *clusterConf.ClusterBasic.DisableHostHeader
 	cluster.DisableHealthCheck = *clusterConf.ClusterBasic.DisableHealthCheck
 
+	cluster.maxRequests = *clusterConf.ClusterBasic.MaxRequests
+	cluster.maxPendingRequests = *clusterConf.ClusterBasic.MaxPendingRequests
+	cluster.maxRetries = *clusterConf.ClusterBasic.MaxRetries
+
+	cluster.retryBudgetPercent = *clusterConf.ClusterBasic.RetryBudgetPercent
+	cluster.retryBudgetMinRetries = *clusterConf.ClusterBasic.RetryBudgetMinRetries
+	cluster.hedgePercentile = *clusterConf.ClusterBasic.HedgePercentile
+	cluster.hedgeMinDelay =
+		time.Duration(*clusterConf.ClusterBasic.HedgeMinDelay) * time.Millisecond
+
 	log.Logger.Info("cluster %s init success", cluster.Name)
 }
 
@@ -134,6 +156,14 @@ func (cluster *BfeCluster) OutlierDetectionHttpCode() string {
 	return *outlierDetectionHttpCode
 }
 
+func (cluster *BfeCluster) OutlierDetectionConf() *cluster_conf.OutlierDetectionConf {
+	cluster.RLock()
+	res := cluster.outlierDetection
+	cluster.RUnlock()
+
+	return res
+}
+
 func (cluster *BfeCluster) TimeoutReadClient() time.Duration {
 	cluster.RLock()
 	res := cluster.timeoutReadClient
@@ -193,3 +223,59 @@ func (cluster *BfeCluster) CancelOnClientClose() bool {
 
 	return res
 }
+
+func (cluster *BfeCluster) MaxRequests() int {
+	cluster.RLock()
+	res := cluster.maxRequests
+	cluster.RUnlock()
+
+	return res
+}
+
+func (cluster *BfeCluster) MaxPendingRequests() int {
+	cluster.RLock()
+	res := cluster.maxPendingRequests
+	cluster.RUnlock()
+
+	return res
+}
+
+func (cluster *BfeCluster) MaxRetries() int {
+	cluster.RLock()
+	res := cluster.maxRetries
+	cluster.RUnlock()
+
+	return res
+}
+
+func (cluster *BfeCluster) RetryBudgetPercent() int {
+	cluster.RLock()
+	res := cluster.retryBudgetPercent
+	cluster.RUnlock()
+
+	return res
+}
+
+func (cluster *BfeCluster) RetryBudgetMinRetries() int {
+	cluster.RLock()
+	res := cluster.retryBudgetMinRetries
+	cluster.RUnlock()
+
+	return res
+}
+
+func (cluster *BfeCluster) HedgePercentile() int {
+	cluster.RLock()
+	res := cluster.hedgePercentile
+	cluster.RUnlock()
+
+	return res
+}
+
+func (cluster *BfeCluster) HedgeMinDelay() time.Duration {
+	cluster.RLock()
+	res := cluster.hedgeMinDelay
+	cluster.RUnlock()
+
+	return res
+}
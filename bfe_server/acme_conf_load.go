@@ -0,0 +1,129 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// certificates from acme server
+//
+// Certificates of hosts in acme conf are obtained and renewed by acme client,
+// and selected by sni in tls handshake before certificates in server cert
+// conf. Challenges are answered by bfe itself: http-01 over http/https
+// connections, and tls-alpn-01 in tls handshake.
+
+package bfe_server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+import (
+	"github.com/bfenetworks/go-lib/log"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_config/bfe_tls_conf/acme_conf"
+	"github.com/bfenetworks/bfe/bfe_http"
+	"github.com/bfenetworks/bfe/bfe_util/acme_client"
+)
+
+// InitAcmeClient initializes client for certificates from acme server.
+func (srv *BfeServer) InitAcmeClient() error {
+	acmeConf := srv.Config.Acme
+	if !acmeConf.Enabled {
+		return nil
+	}
+
+	clientConf := acme_client.Config{
+		DirectoryURL:   acmeConf.DirectoryURL,
+		Email:          acmeConf.Email,
+		StorageDir:     acmeConf.StorageDir,
+		ChallengeTypes: acmeConf.ChallengeTypes,
+		RenewBefore:    time.Duration(acmeConf.RenewBefore) * 24 * time.Hour,
+		CheckInterval:  time.Duration(acmeConf.CheckInterval) * time.Second,
+	}
+	if len(acmeConf.CACertFile) > 0 {
+		tlsConfig, err := newClientTLSConfig(acmeConf.CACertFile)
+		if err != nil {
+			return fmt.Errorf("InitAcmeClient(): %s", err)
+		}
+		clientConf.HTTPClient = &http.Client{
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		}
+	}
+
+	client, err := acme_client.NewClient(clientConf)
+	if err != nil {
+		return fmt.Errorf("InitAcmeClient(): %s", err)
+	}
+	srv.acmeClient = client
+	if err := srv.acmeConfLoad(acmeConf.AcmeConf); err != nil {
+		return fmt.Errorf("InitAcmeClient(): %s", err)
+	}
+
+	// Note: config is shared with https listener
+	srv.TLSConfig.GetCertificate = client.GetCertificate
+	client.Start()
+	return nil
+}
+
+// AcmeConfReload reloads hosts of acme conf.
+func (srv *BfeServer) AcmeConfReload(query url.Values) error {
+	if srv.acmeClient == nil {
+		return fmt.Errorf("acme is not enabled")
+	}
+
+	path := query.Get("path")
+	if path == "" {
+		path = srv.Config.Acme.AcmeConf
+	}
+	return srv.acmeConfLoad(path)
+}
+
+func (srv *BfeServer) acmeConfLoad(filename string) error {
+	conf, err := acme_conf.AcmeConfLoad(filename)
+	if err != nil {
+		return fmt.Errorf("in AcmeConfLoad() :%s", err.Error())
+	}
+
+	srv.acmeClient.SetHosts(conf.Hosts)
+	log.Logger.Info("update acme conf, version %s, %d hosts", conf.Version, len(conf.Hosts))
+	return nil
+}
+
+// AcmeStateGet returns state of acme client.
+func (srv *BfeServer) AcmeStateGet(query url.Values) ([]byte, error) {
+	if srv.acmeClient == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(srv.acmeClient.GetState())
+}
+
+// serveAcmeChallenge answers http-01 challenge request from acme server.
+// It returns false if the request is not for challenge.
+func (srv *BfeServer) serveAcmeChallenge(w bfe_http.ResponseWriter, req *bfe_http.Request) bool {
+	if srv.acmeClient == nil || req.Method != "GET" {
+		return false
+	}
+
+	resp, ok := srv.acmeClient.HTTP01Response(req.URL.Path)
+	if !ok {
+		return false
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(bfe_http.StatusOK)
+	w.Write([]byte(resp))
+	return true
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bfe_server
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_config/bfe_conf"
+	"github.com/bfenetworks/bfe/bfe_util/acme_client"
+)
+
+func TestAcmeConfReload(t *testing.T) {
+	var cfg bfe_conf.BfeConfig
+	cfg.Acme.SetDefaultConf()
+	cfg.Acme.AcmeConf = "./testdata/acme/acme_conf.data"
+	srv := NewBfeServer(cfg, "", "test")
+
+	// acme not enabled
+	if err := srv.AcmeConfReload(url.Values{}); err == nil {
+		t.Errorf("reload should fail if acme not enabled")
+	}
+	if data, _ := srv.AcmeStateGet(url.Values{}); string(data) != "{}" {
+		t.Errorf("unexpected state: %s", data)
+	}
+
+	client, err := acme_client.NewClient(acme_client.Config{
+		DirectoryURL:  "http://127.0.0.1/dir",
+		StorageDir:    t.TempDir(),
+		CheckInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("NewClient(): %s", err)
+	}
+	srv.acmeClient = client
+
+	if err := srv.AcmeConfReload(url.Values{}); err != nil {
+		t.Fatalf("AcmeConfReload(): %s", err)
+	}
+	query := url.Values{"path": []string{"./testdata/acme/acme_conf.data2"}}
+	if err := srv.AcmeConfReload(query); err == nil {
+		t.Errorf("reload should fail for invalid conf")
+	}
+
+	data, err := srv.AcmeStateGet(url.Values{})
+	if err != nil {
+		t.Fatalf("AcmeStateGet(): %s", err)
+	}
+	var state acme_client.ClientState
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatalf("unmarshal state: %s", err)
+	}
+	if len(state.Hosts) != 2 || state.Hosts["example.org"] == nil || state.Hosts["www.example.org"] == nil {
+		t.Errorf("unexpected hosts in state: %s", data)
+	}
+}
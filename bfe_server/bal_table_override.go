@@ -0,0 +1,92 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// runtime override of backends through monitor port
+
+package bfe_server
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_balance"
+	"github.com/bfenetworks/bfe/bfe_balance/backend"
+	"github.com/bfenetworks/bfe/bfe_util/json"
+)
+
+// parseBackendOverride parses override of backend from query, e.g.
+// cluster_name=c1&backend=10.1.1.1:8080&weight=0&mode=draining&ttl=600
+func parseBackendOverride(query url.Values, now time.Time) (bfe_balance.BackendOverride, error) {
+	o := bfe_balance.BackendOverride{
+		Cluster:    query.Get("cluster_name"),
+		Backend:    query.Get("backend"),
+		Mode:       query.Get("mode"),
+		CreateTime: now,
+	}
+	if o.Mode == "normal" {
+		o.Mode = backend.ModeNormal
+	}
+
+	if str := query.Get("weight"); str != "" {
+		weight, err := strconv.Atoi(str)
+		if err != nil {
+			return o, fmt.Errorf("invalid weight %s", str)
+		}
+		o.Weight = &weight
+	}
+
+	if str := query.Get("ttl"); str != "" {
+		ttl, err := strconv.Atoi(str)
+		if err != nil || ttl < 0 {
+			return o, fmt.Errorf("invalid ttl %s", str)
+		}
+		if ttl > 0 {
+			o.ExpireTime = now.Add(time.Duration(ttl) * time.Second)
+		}
+	}
+
+	return o, bfe_balance.BackendOverrideCheck(o)
+}
+
+// BalTableOverrideSet sets weight or admin mode of backend at runtime.
+func (srv *BfeServer) BalTableOverrideSet(query url.Values) error {
+	o, err := parseBackendOverride(query, time.Now())
+	if err != nil {
+		return fmt.Errorf("BalTableOverrideSet(): %s", err)
+	}
+
+	return srv.balTable.SetBackendOverride(o)
+}
+
+// BalTableOverrideClear clears override of backend. Overrides of all backends
+// in cluster are cleared if backend is not given.
+func (srv *BfeServer) BalTableOverrideClear(query url.Values) error {
+	clusterName := query.Get("cluster_name")
+	if clusterName == "" {
+		return fmt.Errorf("BalTableOverrideClear(): no cluster_name")
+	}
+
+	_, err := srv.balTable.ClearBackendOverride(clusterName, query.Get("backend"))
+	return err
+}
+
+// BalTableOverrideGet returns runtime overrides of backends.
+func (srv *BfeServer) BalTableOverrideGet(query url.Values) ([]byte, error) {
+	output := srv.balTable.GetBackendOverrides()
+	return json.Marshal(output)
+}
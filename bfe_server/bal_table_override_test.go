@@ -0,0 +1,61 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bfe_server
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_balance/backend"
+)
+
+func TestParseBackendOverride(t *testing.T) {
+	now := time.Now()
+
+	query, _ := url.ParseQuery("cluster_name=c1&backend=10.1.1.1:8080&weight=5&mode=draining&ttl=600")
+	o, err := parseBackendOverride(query, now)
+	if err != nil {
+		t.Fatalf("parseBackendOverride() err: %s", err)
+	}
+	if o.Cluster != "c1" || o.Backend != "10.1.1.1:8080" || *o.Weight != 5 ||
+		o.Mode != backend.ModeDraining || !o.ExpireTime.Equal(now.Add(600*time.Second)) {
+		t.Errorf("unexpected override: %+v", o)
+	}
+
+	query, _ = url.ParseQuery("cluster_name=c1&backend=10.1.1.1:8080&weight=0&mode=normal")
+	o, err = parseBackendOverride(query, now)
+	if err != nil || o.Mode != backend.ModeNormal || !o.ExpireTime.IsZero() {
+		t.Errorf("unexpected override: %+v, %v", o, err)
+	}
+
+	invalids := []string{
+		"backend=10.1.1.1:8080&mode=draining",
+		"cluster_name=c1&mode=draining",
+		"cluster_name=c1&backend=10.1.1.1:8080",
+		"cluster_name=c1&backend=10.1.1.1:8080&weight=-1",
+		"cluster_name=c1&backend=10.1.1.1:8080&weight=a",
+		"cluster_name=c1&backend=10.1.1.1:8080&mode=down",
+		"cluster_name=c1&backend=10.1.1.1:8080&mode=draining&ttl=-1",
+	}
+	for _, str := range invalids {
+		query, _ := url.ParseQuery(str)
+		if _, err := parseBackendOverride(query, now); err == nil {
+			t.Errorf("parseBackendOverride(%s) should return err", str)
+		}
+	}
+}
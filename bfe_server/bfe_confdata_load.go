@@ -17,11 +17,11 @@
 package bfe_server
 
 import (
-	"encoding/hex"
 	"fmt"
 	"net/url"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 import (
@@ -29,9 +29,11 @@ import (
 )
 
 import (
+	"github.com/bfenetworks/bfe/bfe_config/bfe_cluster_conf/cluster_table_conf"
+	"github.com/bfenetworks/bfe/bfe_config/bfe_cluster_conf/gslb_conf"
 	"github.com/bfenetworks/bfe/bfe_config/bfe_tls_conf/server_cert_conf"
-	"github.com/bfenetworks/bfe/bfe_config/bfe_tls_conf/session_ticket_key_conf"
 	"github.com/bfenetworks/bfe/bfe_config/bfe_tls_conf/tls_rule_conf"
+	"github.com/bfenetworks/bfe/bfe_config/bfe_xds_conf"
 	"github.com/bfenetworks/bfe/bfe_route"
 	"github.com/bfenetworks/bfe/bfe_util/bns"
 )
@@ -48,6 +50,7 @@ func (srv *BfeServer) InitDataLoad() error {
 
 	srv.ServerConf = serverConf
 	srv.ReverseProxy.setTransports(srv.ServerConf.ClusterTable.ClusterMap())
+	srv.ReverseProxy.breakers.Update(srv.ServerConf.ClusterTable.ClusterMap())
 	log.Logger.Info("init serverDataConf success")
 
 	// load bal table
@@ -55,12 +58,15 @@ func (srv *BfeServer) InitDataLoad() error {
 		srv.Config.Server.ClusterTableConf); err != nil {
 		return fmt.Errorf("InitDataLoad():balTableInit Error %s", err)
 	}
+	srv.balTable.StartDomainDiscovery()
+	srv.balTable.StartBackendOverrideExpire()
 
-	// set gslb retry config, slow_start config
+	// set gslb retry config, slow_start config, outlier detection config
 	if srv.ServerConf != nil {
 		ct := srv.ServerConf.ClusterTable
 		srv.balTable.SetGslbBasic(ct)
 		srv.balTable.SetSlowStart(ct)
+		srv.balTable.SetOutlierDetection(ct)
 	}
 	log.Logger.Info("init bal table success")
 
@@ -99,6 +105,15 @@ func (srv *BfeServer) ServerDataConfReload(query url.Values) error {
 		clusterConfFile = joinPath(path, clusterConfFile)
 	}
 
+	srv.reloadLock.Lock()
+	defer srv.reloadLock.Unlock()
+	if len(srv.xdsDataPath) > 0 {
+		// Note: products and clusters from xds server take priority
+		log.Logger.Info("ServerDataConfReload(): use host, route and cluster conf in %s", srv.xdsDataPath)
+		hostFile = filepath.Join(srv.xdsDataPath, bfe_xds_conf.HostRuleFile)
+		routeFile = filepath.Join(srv.xdsDataPath, bfe_xds_conf.RouteRuleFile)
+		clusterConfFile = filepath.Join(srv.xdsDataPath, bfe_xds_conf.ClusterConfFile)
+	}
 	return srv.serverDataConfReload(hostFile, vipFile, routeFile, clusterConfFile)
 }
 
@@ -109,18 +124,25 @@ func (srv *BfeServer) serverDataConfReload(hostFile, vipFile, routeFile, cluster
 		return err
 	}
 
+	srv.serverDataConfApply(newServerConf)
+	return nil
+}
+
+// serverDataConfApply applies server data conf which has been checked.
+func (srv *BfeServer) serverDataConfApply(newServerConf *bfe_route.ServerDataConf) {
 	srv.confLock.Lock()
 	srv.ServerConf = newServerConf
 	srv.confLock.Unlock()
 
 	srv.ReverseProxy.setTransports(srv.ServerConf.ClusterTable.ClusterMap())
+	srv.ReverseProxy.breakers.Update(srv.ServerConf.ClusterTable.ClusterMap())
 
 	// set gslb basic
 	srv.balTable.SetGslbBasic(newServerConf.ClusterTable)
 	// set slow_start config
 	srv.balTable.SetSlowStart(newServerConf.ClusterTable)
-
-	return nil
+	// set outlier detection config
+	srv.balTable.SetOutlierDetection(newServerConf.ClusterTable)
 }
 
 // GslbDataConfReload reloads gslb and cluster conf.
@@ -134,6 +156,14 @@ func (srv *BfeServer) GslbDataConfReload(query url.Values) error {
 		clusterTableFile = joinPath(path, clusterTableFile)
 	}
 
+	srv.reloadLock.Lock()
+	defer srv.reloadLock.Unlock()
+	if len(srv.xdsDataPath) > 0 {
+		// Note: clusters from xds server take priority
+		log.Logger.Info("GslbDataConfReload(): use gslb and cluster table conf in %s", srv.xdsDataPath)
+		gslbFile = filepath.Join(srv.xdsDataPath, bfe_xds_conf.GslbFile)
+		clusterTableFile = filepath.Join(srv.xdsDataPath, bfe_xds_conf.ClusterTableFile)
+	}
 	return srv.gslbDataConfReload(gslbFile, clusterTableFile)
 }
 
@@ -145,6 +175,12 @@ func (srv *BfeServer) gslbDataConfReload(gslbFile, clusterTableFile string) erro
 		return err
 	}
 
+	return srv.gslbDataConfApply(gslbConf, backendConf)
+}
+
+// gslbDataConfApply applies gslb and cluster_table conf which have been checked.
+func (srv *BfeServer) gslbDataConfApply(gslbConf gslb_conf.GslbConf,
+	backendConf cluster_table_conf.ClusterTableConf) error {
 	if err := srv.balTable.BalTableReload(gslbConf, backendConf); err != nil {
 		log.Logger.Error("GslbDataConfReload():BalTableReload err [%s]", err)
 		return err
@@ -157,6 +193,8 @@ func (srv *BfeServer) gslbDataConfReload(gslbFile, clusterTableFile string) erro
 	srv.balTable.SetGslbBasic(serverConf.ClusterTable)
 	// set slow_start config
 	srv.balTable.SetSlowStart(serverConf.ClusterTable)
+	// set outlier detection config
+	srv.balTable.SetOutlierDetection(serverConf.ClusterTable)
 
 	return nil
 }
@@ -164,27 +202,12 @@ func (srv *BfeServer) gslbDataConfReload(gslbFile, clusterTableFile string) erro
 // SessionTicketKeyReload reloads for session ticket key.
 func (srv *BfeServer) SessionTicketKeyReload() error {
 	log.Logger.Info("start session ticket key reload")
-	sessionTicketConf := srv.Config.SessionTicket
-	if sessionTicketConf.SessionTicketsDisabled {
+	if srv.ticketKeyRotator == nil {
 		return nil
 	}
 
-	// load session ticket key
-	keyFile := sessionTicketConf.SessionTicketKeyFile
-	keyConf, err := session_ticket_key_conf.SessionTicketKeyConfLoad(keyFile)
-	if err != nil {
-		return err
-	}
-	key, err := hex.DecodeString(keyConf.SessionTicketKey)
-	if err != nil { // never go here
-		return fmt.Errorf("wrong session ticket key %s (%s)", err, key)
-	}
-
-	// update session ticket key
-	srv.HttpsListener.UpdateSessionTicketKey(key)
-	log.Logger.Debug("update session ticket key for %s", keyConf.SessionTicketKey)
-
-	return nil
+	// Note: previous keys are kept as decrypt-only keys
+	return srv.ticketKeyRotator.reload(time.Now())
 }
 
 func (srv *BfeServer) TLSConfReload(query url.Values) error {
@@ -215,7 +238,7 @@ func (srv *BfeServer) tlsConfLoad(certConfFile string, tlsRuleFile string) error
 	}
 
 	// parse certificate
-	certMap, err := server_cert_conf.ServerCertParse(certConf)
+	certMap, err := server_cert_conf.ServerCertParse(certConf, srv.keylessClient)
 	if err != nil {
 		return fmt.Errorf("in ServerCertParse() :%s", err.Error())
 	}
@@ -240,14 +263,24 @@ func (srv *BfeServer) tlsConfLoad(certConfFile string, tlsRuleFile string) error
 		return fmt.Errorf("in ClientCRLLoad(): %s", err.Error())
 	}
 
+	// create online revocation checkers for client cert
+	clientRevocationMap := tls_rule_conf.ClientRevocationLoad(tlsRule.ClientRevocation,
+		srv.clientRevocationFetcher)
+
 	// validate tls conf
 	if err := tls_rule_conf.CheckTlsConf(certMap, tlsRule.Config); err != nil {
 		return fmt.Errorf("in CheckTlsConf() :%s", err.Error())
 	}
+	if err := srv.checkHttp3TlsRule(tlsRule.Config); err != nil {
+		return fmt.Errorf("in checkHttp3TlsRule() :%s", err.Error())
+	}
 
 	// update certificates and tls rule data
 	srv.MultiCert.Update(certMap, tlsRule.Config)
-	srv.TLSServerRule.Update(tlsRule, clientCAMap, clientCRLPoolMap)
+	srv.TLSServerRule.Update(tlsRule, clientCAMap, clientCRLPoolMap, clientRevocationMap)
+	if srv.ocspFetcher != nil {
+		srv.ocspFetcher.SetCerts(certMap, time.Now())
+	}
 	log.Logger.Debug("update tls server rule success")
 
 	return nil
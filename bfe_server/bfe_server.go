@@ -17,7 +17,7 @@
 package bfe_server
 
 import (
-	"encoding/hex"
+	"context"
 	"fmt"
 	"net"
 	"os"
@@ -26,18 +26,22 @@ import (
 	"time"
 
 	"github.com/bfenetworks/bfe/bfe_balance"
+	"github.com/bfenetworks/bfe/bfe_balance/bal_gslb"
 	"github.com/bfenetworks/bfe/bfe_config/bfe_cluster_conf/cluster_conf"
 	"github.com/bfenetworks/bfe/bfe_config/bfe_conf"
-	"github.com/bfenetworks/bfe/bfe_config/bfe_tls_conf/session_ticket_key_conf"
 	"github.com/bfenetworks/bfe/bfe_config/bfe_tls_conf/tls_rule_conf"
 	"github.com/bfenetworks/bfe/bfe_http"
 	"github.com/bfenetworks/bfe/bfe_http2"
+	"github.com/bfenetworks/bfe/bfe_http3"
 	"github.com/bfenetworks/bfe/bfe_module"
 	"github.com/bfenetworks/bfe/bfe_route"
 	"github.com/bfenetworks/bfe/bfe_spdy"
 	"github.com/bfenetworks/bfe/bfe_stream"
 	"github.com/bfenetworks/bfe/bfe_tls"
+	"github.com/bfenetworks/bfe/bfe_util/acme_client"
+	"github.com/bfenetworks/bfe/bfe_util/keyless"
 	"github.com/bfenetworks/bfe/bfe_util/signal_table"
+	"github.com/bfenetworks/bfe/bfe_util/xds"
 	"github.com/bfenetworks/bfe/bfe_websocket"
 	"github.com/bfenetworks/go-lib/log"
 )
@@ -49,6 +53,11 @@ type BfeServer struct {
 	listenerMap   map[string]net.Listener // all listeners
 	HttpListener  net.Listener            // listener for http
 	HttpsListener *HttpsListener          // listener for https
+	Http3Listener *Http3Listener          // listener for http3, nil if disabled
+
+	// for http3 server
+	Http3Server *bfe_http3.Server
+	altSvc      string // value of Alt-Svc header to advertise http3
 
 	connWaitGroup sync.WaitGroup // waits for server conns to finish
 
@@ -84,10 +93,24 @@ type BfeServer struct {
 	// server status
 	serverStatus *ServerStatus
 
+	reloadLock sync.Mutex                // serialize reloading of data conf
 	confLock   sync.RWMutex              // mutex when reload data conf
 	ServerConf *bfe_route.ServerDataConf // cluster_conf and host table conf
 	balTable   *bfe_balance.BalTable     // for balance
 
+	xdsClient   *xds.Client // for dynamic config from xds server
+	xdsDataPath string      // path of config applied from xds server, guarded by reloadLock
+
+	acmeClient *acme_client.Client // for certificates from acme server
+
+	ticketKeyRotator *ticketKeyRotator // for session ticket keys
+
+	ocspFetcher *ocspFetcher // for ocsp staples of server certificates
+
+	clientRevocationFetcher *bfe_tls.RevocationFetcher // for online revocation checking of client certificates
+
+	keylessClient *keyless.Client // for private keys on key server
+
 	Version string // version of bfe server
 }
 
@@ -118,6 +141,7 @@ func NewBfeServer(cfg bfe_conf.BfeConfig, confRoot string,
 
 	// initialize balTable
 	s.balTable = bfe_balance.NewBalTable(s.GetCheckConf)
+	bal_gslb.SetLocalZone(cfg.Server.Zone)
 
 	// set keep-alive
 	s.SetKeepAlivesEnabled(cfg.Server.KeepAliveEnabled)
@@ -250,21 +274,15 @@ func (srv *BfeServer) initTLSSessionCache() {
 func (srv *BfeServer) initTLSSessionTicket() error {
 	sessionTicketConf := srv.Config.SessionTicket
 
-	// initialize session ticket key
+	// initialize session ticket keys
 	if !sessionTicketConf.SessionTicketsDisabled {
 		srv.TLSConfig.SessionTicketsDisabled = false
-		keyFile := sessionTicketConf.SessionTicketKeyFile
-		keyConf, err := session_ticket_key_conf.SessionTicketKeyConfLoad(keyFile)
+		rotator, err := newTicketKeyRotator(sessionTicketConf, srv.updateSessionTicketKeys)
 		if err != nil {
 			return err
 		}
-		key, err := hex.DecodeString(keyConf.SessionTicketKey)
-		if err != nil {
-			return fmt.Errorf("wrong session ticket key %s (%s)", err, key)
-		}
-
-		copy(srv.TLSConfig.SessionTicketKeyName[:], key[:16])
-		copy(srv.TLSConfig.SessionTicketKey[:], key[16:])
+		srv.ticketKeyRotator = rotator
+		srv.TLSConfig.SessionTicketKeys = rotator.keys
 	} else {
 		srv.TLSConfig.SessionTicketsDisabled = true
 	}
@@ -272,9 +290,43 @@ func (srv *BfeServer) initTLSSessionTicket() error {
 	return nil
 }
 
+// updateSessionTicketKeys updates session ticket keys for http3 listener.
+// Note: keys are shared with https listener by tls config.
+func (srv *BfeServer) updateSessionTicketKeys(keys []bfe_tls.TicketKey) {
+	if srv.Http3Listener != nil {
+		srv.Http3Listener.UpdateSessionTicketKeys(keys)
+	}
+}
+
+// StartTicketKeyRotator starts rotation of session ticket keys if enabled.
+func (srv *BfeServer) StartTicketKeyRotator() {
+	if srv.ticketKeyRotator != nil {
+		srv.ticketKeyRotator.Start()
+	}
+}
+
+// StartClientRevocationFetcher starts refreshing revocation status of client
+// certificates in background.
+func (srv *BfeServer) StartClientRevocationFetcher() {
+	if srv.clientRevocationFetcher != nil {
+		srv.clientRevocationFetcher.Start()
+	}
+}
+
 func (srv *BfeServer) initTLSRule(httpsConf bfe_conf.ConfigHttpsBasic) error {
 	srv.MultiCert = NewMultiCertMap(srv.serverStatus.ProxyState)
 	srv.TLSServerRule = NewTLSServerRuleMap(srv.serverStatus.ProxyState)
+	srv.clientRevocationFetcher = bfe_tls.NewRevocationFetcher(
+		time.Duration(httpsConf.ClientRevocationTimeout) * time.Millisecond)
+	if srv.Config.OcspStapling.Enabled {
+		srv.ocspFetcher = newOcspFetcher(srv.Config.OcspStapling, srv.serverStatus.ProxyState,
+			srv.MultiCert.UpdateOcspStaple)
+	}
+	if srv.Config.KeylessTls.Enabled {
+		if err := srv.initKeylessClient(srv.Config.KeylessTls); err != nil {
+			return err
+		}
+	}
 	if err := srv.tlsConfLoad(httpsConf.ServerCertConf, httpsConf.TlsRuleConf); err != nil {
 		return err
 	}
@@ -436,6 +488,37 @@ func (srv *BfeServer) InitListeners(config bfe_conf.BfeConfig) error {
 	srv.HttpListener = listenerMap["HTTP"]
 	srv.HttpsListener = NewHttpsListener(srv.listenerMap["HTTPS"], srv.TLSConfig)
 
+	// listener for http3 over udp (optional)
+	if config.Server.Http3Port > 0 {
+		if err := srv.initHttp3Listener(config); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (srv *BfeServer) initHttp3Listener(config bfe_conf.BfeConfig) error {
+	addr, port := config.Server.Http3Addr, config.Server.Http3Port
+	pconn, err := net.ListenPacket("udp", fmt.Sprintf("%s:%d", addr, port))
+	if err != nil {
+		return err
+	}
+
+	srv.Http3Listener, err = NewHttp3Listener(pconn, srv)
+	if err != nil {
+		pconn.Close()
+		return err
+	}
+	srv.Http3Server = &bfe_http3.Server{
+		MaxHeaderBytes: srv.MaxHeaderBytes,
+		IdleTimeout:    srv.ReadTimeout,
+	}
+
+	// advertise http3 for clients over https/h2
+	srv.altSvc = fmt.Sprintf("%s=\":%d\"; ma=86400", bfe_http3.NextProtoH3, port)
+	log.Logger.Info("InitListeners(): begin to listen [%s:%d] (udp)", addr, port)
+
 	return nil
 }
 
@@ -445,4 +528,12 @@ func (srv *BfeServer) closeListeners() {
 			log.Logger.Error("closeListeners(): %s, %s", err, ln.Addr())
 		}
 	}
+
+	if srv.Http3Listener != nil {
+		if err := srv.Http3Listener.Close(); err != nil {
+			log.Logger.Error("closeListeners(): %s, %s", err, srv.Http3Listener.Addr())
+		}
+		// notify clients by GOAWAY, and wait for active requests to finish
+		go srv.Http3Server.Shutdown(context.Background())
+	}
 }
@@ -90,6 +90,27 @@ func StartUp(cfg bfe_conf.BfeConfig, version string, confRoot string, dryRun boo
 		return err
 	}
 
+	// start rotation of session ticket keys if enabled
+	bfeServer.StartTicketKeyRotator()
+
+	// start fetching ocsp staples if enabled
+	bfeServer.StartOcspFetcher()
+
+	// start refreshing revocation status of client certificates
+	bfeServer.StartClientRevocationFetcher()
+
+	// start dynamic config client if enabled
+	if err = bfeServer.InitXdsClient(); err != nil {
+		log.Logger.Error("StartUp(): InitXdsClient():%s", err.Error())
+		return err
+	}
+
+	// start acme client if enabled
+	if err = bfeServer.InitAcmeClient(); err != nil {
+		log.Logger.Error("StartUp(): InitAcmeClient():%s", err.Error())
+		return err
+	}
+
 	// start embedded web server if enabled
 	if cfg.Server.MonitorEnabled {
 		bfeServer.Monitor.Start()
@@ -113,6 +134,14 @@ func StartUp(cfg bfe_conf.BfeConfig, version string, confRoot string, dryRun boo
 		}()
 	}
 
+	// start goroutine to accept http3 connections
+	if bfeServer.Http3Listener != nil {
+		go func() {
+			http3Err := bfeServer.ServeHttp3(bfeServer.Http3Listener)
+			serveChan <- http3Err
+		}()
+	}
+
 	err = <-serveChan
 	return err
 }
@@ -0,0 +1,174 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// circuit breaker for cluster
+
+package bfe_server
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_route"
+	"github.com/bfenetworks/bfe/bfe_route/bfe_cluster"
+)
+
+// CircuitBreaker limits concurrent requests, pending requests and retries
+// to a cluster. Requests exceeding the limits fail fast, instead of piling up
+// on slow backends.
+type CircuitBreaker struct {
+	requests int64 // active requests, until response body is closed
+	pending  int64 // requests waiting for response header from backends
+	retries  int64 // retries in progress
+
+	budget  RetryBudget // retry budget of cluster
+	latency latencyStat // latency of recent responses, for hedging
+}
+
+// tryAcquire increases counter if it does not exceed max (zero means
+// unrestricted).
+func tryAcquire(counter *int64, max int) bool {
+	if n := atomic.AddInt64(counter, 1); max > 0 && n > int64(max) {
+		atomic.AddInt64(counter, -1)
+		return false
+	}
+	return true
+}
+
+// AcquireRequest tries to admit a new request to cluster. The request holds
+// both a request and a pending slot if admitted.
+func (cb *CircuitBreaker) AcquireRequest(cluster *bfe_cluster.BfeCluster, state *ProxyState) bool {
+	if !tryAcquire(&cb.requests, cluster.MaxRequests()) {
+		state.CircuitOpenMaxRequests.Inc(1)
+		return false
+	}
+	if !tryAcquire(&cb.pending, cluster.MaxPendingRequests()) {
+		cb.ReleaseRequest()
+		state.CircuitOpenMaxPendingRequests.Inc(1)
+		return false
+	}
+
+	if percent := cluster.RetryBudgetPercent(); percent > 0 {
+		cb.budget.Deposit(time.Now(), percent)
+	}
+	return true
+}
+
+// ReleasePending releases pending slot after response header is received
+// or request fails.
+func (cb *CircuitBreaker) ReleasePending() {
+	atomic.AddInt64(&cb.pending, -1)
+}
+
+// ReleaseRequest releases request slot after request finishes.
+func (cb *CircuitBreaker) ReleaseRequest() {
+	atomic.AddInt64(&cb.requests, -1)
+}
+
+// AcquireRetry tries to admit a retry to cluster, limited by both max retries
+// and retry budget.
+func (cb *CircuitBreaker) AcquireRetry(cluster *bfe_cluster.BfeCluster, state *ProxyState) bool {
+	if !tryAcquire(&cb.retries, cluster.MaxRetries()) {
+		state.CircuitOpenMaxRetries.Inc(1)
+		return false
+	}
+
+	if cluster.RetryBudgetPercent() > 0 &&
+		!cb.budget.Withdraw(time.Now(), cluster.RetryBudgetMinRetries()) {
+		cb.ReleaseRetry()
+		state.RetryBudgetExhausted.Inc(1)
+		return false
+	}
+	return true
+}
+
+// ReleaseRetry releases retry slot after retry finishes.
+func (cb *CircuitBreaker) ReleaseRetry() {
+	atomic.AddInt64(&cb.retries, -1)
+}
+
+// Requests returns number of active requests.
+func (cb *CircuitBreaker) Requests() int64 {
+	return atomic.LoadInt64(&cb.requests)
+}
+
+// Pending returns number of pending requests.
+func (cb *CircuitBreaker) Pending() int64 {
+	return atomic.LoadInt64(&cb.pending)
+}
+
+// Retries returns number of retries in progress.
+func (cb *CircuitBreaker) Retries() int64 {
+	return atomic.LoadInt64(&cb.retries)
+}
+
+// breakerBody releases request slot of circuit breaker when response body
+// is closed.
+type breakerBody struct {
+	io.ReadCloser
+	once    sync.Once
+	breaker *CircuitBreaker
+}
+
+func (b *breakerBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.breaker.ReleaseRequest)
+	return err
+}
+
+// CircuitBreakerTable holds mappings from cluster-name to CircuitBreaker.
+type CircuitBreakerTable struct {
+	lock     sync.RWMutex
+	breakers map[string]*CircuitBreaker
+}
+
+func NewCircuitBreakerTable() *CircuitBreakerTable {
+	t := new(CircuitBreakerTable)
+	t.breakers = make(map[string]*CircuitBreaker)
+	return t
+}
+
+// Get returns circuit breaker of cluster, create one if not exist.
+func (t *CircuitBreakerTable) Get(name string) *CircuitBreaker {
+	t.lock.RLock()
+	cb, ok := t.breakers[name]
+	t.lock.RUnlock()
+	if ok {
+		return cb
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if cb, ok = t.breakers[name]; !ok {
+		cb = new(CircuitBreaker)
+		t.breakers[name] = cb
+	}
+	return cb
+}
+
+// Update removes circuit breakers of clusters not in clusterMap.
+func (t *CircuitBreakerTable) Update(clusterMap bfe_route.ClusterMap) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for name := range t.breakers {
+		if _, ok := clusterMap[name]; !ok {
+			delete(t.breakers, name)
+		}
+	}
+}
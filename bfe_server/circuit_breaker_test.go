@@ -0,0 +1,121 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bfe_server
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_config/bfe_cluster_conf/cluster_conf"
+	"github.com/bfenetworks/bfe/bfe_route"
+	"github.com/bfenetworks/bfe/bfe_route/bfe_cluster"
+)
+
+func newBreakerCluster(t *testing.T, maxRequests, maxPending, maxRetries int) *bfe_cluster.BfeCluster {
+	basic := cluster_conf.ClusterBasicConf{
+		MaxRequests:        &maxRequests,
+		MaxPendingRequests: &maxPending,
+		MaxRetries:         &maxRetries,
+	}
+	if err := cluster_conf.ClusterBasicConfCheck(&basic); err != nil {
+		t.Fatalf("ClusterBasicConfCheck() error: %v", err)
+	}
+
+	cluster := bfe_cluster.NewBfeCluster("example")
+	cluster.BasicInit(cluster_conf.ClusterConf{ClusterBasic: &basic})
+	return cluster
+}
+
+func TestCircuitBreakerRequests(t *testing.T) {
+	state := NewServerStatus().ProxyState
+	cluster := newBreakerCluster(t, 2, 1, 0)
+	cb := new(CircuitBreaker)
+
+	if !cb.AcquireRequest(cluster, state) {
+		t.Fatalf("first request should be admitted")
+	}
+
+	// reach max pending requests
+	if cb.AcquireRequest(cluster, state) {
+		t.Fatalf("request should be rejected for max pending requests")
+	}
+	if cb.Requests() != 1 || cb.Pending() != 1 {
+		t.Errorf("unexpected counters: requests %d, pending %d", cb.Requests(), cb.Pending())
+	}
+
+	// response header received, request is still active until body closed
+	cb.ReleasePending()
+	body := &breakerBody{ReadCloser: io.NopCloser(strings.NewReader("")), breaker: cb}
+	if !cb.AcquireRequest(cluster, state) {
+		t.Fatalf("second request should be admitted")
+	}
+	cb.ReleasePending()
+
+	// reach max requests
+	if cb.AcquireRequest(cluster, state) {
+		t.Fatalf("request should be rejected for max requests")
+	}
+
+	// duplicated close releases request slot only once
+	body.Close()
+	body.Close()
+	if cb.Requests() != 1 || cb.Pending() != 0 {
+		t.Errorf("unexpected counters: requests %d, pending %d", cb.Requests(), cb.Pending())
+	}
+}
+
+func TestCircuitBreakerRetries(t *testing.T) {
+	state := NewServerStatus().ProxyState
+	cb := new(CircuitBreaker)
+
+	// unrestricted
+	cluster := newBreakerCluster(t, 0, 0, 0)
+	for i := 0; i < 10; i++ {
+		if !cb.AcquireRetry(cluster, state) {
+			t.Fatalf("retry should be admitted")
+		}
+	}
+	for i := 0; i < 10; i++ {
+		cb.ReleaseRetry()
+	}
+
+	cluster = newBreakerCluster(t, 0, 0, 1)
+	if !cb.AcquireRetry(cluster, state) {
+		t.Fatalf("retry should be admitted")
+	}
+	if cb.AcquireRetry(cluster, state) {
+		t.Errorf("retry should be rejected for max retries")
+	}
+	cb.ReleaseRetry()
+	if cb.Retries() != 0 {
+		t.Errorf("retries should be 0, not %d", cb.Retries())
+	}
+}
+
+func TestCircuitBreakerTable(t *testing.T) {
+	table := NewCircuitBreakerTable()
+	cb := table.Get("example")
+	if table.Get("example") != cb {
+		t.Errorf("should get the same circuit breaker")
+	}
+
+	table.Update(bfe_route.ClusterMap{"other": nil})
+	if table.Get("example") == cb {
+		t.Errorf("circuit breaker of removed cluster should be deleted")
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// gRPC aware proxying
+//
+// For gRPC requests, deadline of client (by grpc-timeout) is propagated to
+// backend, and proxy-side failures are returned as gRPC status, since gRPC
+// clients can not interpret HTTP status codes.
+
+package bfe_server
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+import (
+	"github.com/bfenetworks/go-lib/log"
+	"google.golang.org/grpc/codes"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_basic"
+	"github.com/bfenetworks/bfe/bfe_http"
+	"github.com/bfenetworks/bfe/bfe_http2"
+)
+
+// setGrpcInfo sets gRPC info for gRPC (or gRPC-Web) request.
+func setGrpcInfo(basicReq *bfe_basic.Request) {
+	req := basicReq.HttpRequest
+	if !bfe_basic.IsGrpcRequest(req) && !bfe_basic.IsGrpcWebRequest(req) {
+		return
+	}
+
+	info := new(bfe_basic.GrpcInfo)
+	service, method, err := bfe_basic.ParseGrpcPath(req.URL.Path)
+	if err == nil {
+		info.Service = service
+		info.Method = method
+	}
+
+	if value := req.Header.Get(bfe_basic.HeaderGrpcTimeout); value != "" {
+		timeout, err := bfe_http2.ParseGrpcTimeout(value)
+		if err != nil {
+			log.Logger.Debug("setGrpcInfo(): %s", err.Error())
+		} else {
+			start := basicReq.Stat.ReadReqStart
+			if start.IsZero() {
+				start = time.Now()
+			}
+			info.Deadline = start.Add(timeout)
+		}
+	}
+
+	basicReq.SetGrpcInfo(info)
+}
+
+// grpcDeadlineExceeded checks whether deadline of gRPC request passes.
+func grpcDeadlineExceeded(basicReq *bfe_basic.Request) bool {
+	info := basicReq.GetGrpcInfo()
+	if info == nil || info.Deadline.IsZero() {
+		return false
+	}
+	return !time.Now().Before(info.Deadline)
+}
+
+// setGrpcTimeout propagates remaining time before deadline of gRPC request
+// to backend. It returns false if deadline has passed.
+func setGrpcTimeout(basicReq *bfe_basic.Request, outreq *bfe_http.Request) bool {
+	info := basicReq.GetGrpcInfo()
+	if info == nil || info.Deadline.IsZero() {
+		return true
+	}
+
+	remain := time.Until(info.Deadline)
+	if remain <= 0 {
+		return false
+	}
+
+	// header of outreq is shared with original request, copy before modify
+	header := make(bfe_http.Header, len(outreq.Header))
+	bfe_http.CopyHeader(header, outreq.Header)
+	header.Set(bfe_basic.HeaderGrpcTimeout, bfe_http2.EncodeGrpcTimeout(remain))
+	outreq.Header = header
+	return true
+}
+
+// createErrResp creates response for proxy-side failure. gRPC status is
+// returned for gRPC request, and HTTP 500 for others.
+//
+// Note: for gRPC request, only status code of response is HTTP 200, and
+// BfeStatusCode keeps the original error code of bfe.
+func createErrResp(basicReq *bfe_basic.Request) *bfe_http.Response {
+	if basicReq.GetGrpcInfo() == nil {
+		return bfe_basic.CreateInternalSrvErrResp(basicReq)
+	}
+
+	code := bfe_basic.GrpcCodeFromErr(basicReq.ErrCode)
+	if grpcDeadlineExceeded(basicReq) {
+		code = codes.DeadlineExceeded
+	}
+	msg := "bfe: internal error"
+	if basicReq.ErrCode != nil {
+		msg = "bfe: " + basicReq.ErrCode.Error()
+	}
+	return bfe_basic.CreateGrpcErrResp(basicReq, code, msg)
+}
+
+// recordGrpcStatus records gRPC status of response. It should be invoked
+// after response body is closed, so that trailers are populated. Request
+// with non-OK gRPC status is regarded as failed.
+func recordGrpcStatus(basicReq *bfe_basic.Request, res *bfe_http.Response) {
+	info := basicReq.GetGrpcInfo()
+	if info == nil || res == nil {
+		return
+	}
+
+	// status is in trailers, or in headers for trailers-only response
+	if res.H2Trailer != nil && (*res.H2Trailer).Get(bfe_basic.HeaderGrpcStatus) != "" {
+		info.Status = (*res.H2Trailer).Get(bfe_basic.HeaderGrpcStatus)
+		info.Message = (*res.H2Trailer).Get(bfe_basic.HeaderGrpcMessage)
+	} else if status := res.Header.Get(bfe_basic.HeaderGrpcStatus); status != "" {
+		info.Status = status
+		info.Message = res.Header.Get(bfe_basic.HeaderGrpcMessage)
+	} else if res.StatusCode != bfe_http.StatusOK {
+		// status is derived from HTTP status, as gRPC clients do
+		info.Status = strconv.Itoa(int(bfe_basic.GrpcCodeFromHTTPStatus(res.StatusCode)))
+	}
+
+	// Note: error of proxy (if any) is kept
+	if basicReq.ErrCode == nil && info.Status != "" && info.Status != strconv.Itoa(int(codes.OK)) {
+		basicReq.ErrCode = bfe_basic.ErrBkGrpcStatus
+		basicReq.ErrMsg = fmt.Sprintf("grpc-status: %s, grpc-message: %s", info.Status, info.Message)
+	}
+}
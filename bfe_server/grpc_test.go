@@ -0,0 +1,170 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bfe_server
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_basic"
+	"github.com/bfenetworks/bfe/bfe_http"
+)
+
+func newGrpcTestRequest(ctype, timeout string) *bfe_basic.Request {
+	req := &bfe_http.Request{
+		Method: "POST",
+		URL:    &url.URL{Path: "/helloworld.Greeter/SayHello"},
+		Header: bfe_http.Header{"Content-Type": []string{ctype}},
+	}
+	if timeout != "" {
+		req.Header.Set("Grpc-Timeout", timeout)
+	}
+	return bfe_basic.NewRequest(req, nil, bfe_basic.NewRequestStat(time.Now()), nil, nil)
+}
+
+func TestSetGrpcInfo(t *testing.T) {
+	basicReq := newGrpcTestRequest("text/plain", "")
+	setGrpcInfo(basicReq)
+	if basicReq.GetGrpcInfo() != nil {
+		t.Errorf("non-grpc request should not be treated as grpc")
+	}
+
+	basicReq = newGrpcTestRequest("application/grpc-web-text", "")
+	setGrpcInfo(basicReq)
+	if basicReq.GetGrpcInfo() == nil {
+		t.Errorf("grpc info should be set for grpc-web request")
+	}
+
+	basicReq = newGrpcTestRequest("application/grpc+proto", "1S")
+	setGrpcInfo(basicReq)
+	info := basicReq.GetGrpcInfo()
+	if info == nil {
+		t.Fatalf("grpc info should be set")
+	}
+	if info.Service != "helloworld.Greeter" || info.Method != "SayHello" {
+		t.Errorf("wrong service/method %s/%s", info.Service, info.Method)
+	}
+	if remain := time.Until(info.Deadline); remain <= 0 || remain > time.Second {
+		t.Errorf("wrong deadline %v", info.Deadline)
+	}
+}
+
+func TestSetGrpcTimeout(t *testing.T) {
+	basicReq := newGrpcTestRequest("application/grpc", "1S")
+	setGrpcInfo(basicReq)
+
+	outreq := new(bfe_http.Request)
+	*outreq = *basicReq.HttpRequest
+	if !setGrpcTimeout(basicReq, outreq) {
+		t.Fatalf("deadline should not pass")
+	}
+	if value := outreq.Header.Get("Grpc-Timeout"); value == "1S" || value == "" {
+		t.Errorf("grpc-timeout should be remaining time, got %s", value)
+	}
+	if basicReq.HttpRequest.Header.Get("Grpc-Timeout") != "1S" {
+		t.Errorf("header of original request should not be modified")
+	}
+
+	basicReq.GetGrpcInfo().Deadline = time.Now().Add(-time.Second)
+	if setGrpcTimeout(basicReq, outreq) {
+		t.Errorf("deadline should pass")
+	}
+}
+
+func TestCreateErrResp(t *testing.T) {
+	basicReq := newGrpcTestRequest("text/plain", "")
+	setGrpcInfo(basicReq)
+	res := createErrResp(basicReq)
+	if res.StatusCode != bfe_http.StatusInternalServerError {
+		t.Errorf("status code should be 500, got %d", res.StatusCode)
+	}
+
+	tests := []struct {
+		err      error
+		deadline time.Time
+		status   string
+	}{
+		{bfe_basic.ErrBkNoBackend, time.Time{}, "14"},
+		{bfe_basic.ErrBkRespHeaderTimeout, time.Time{}, "4"},
+		{bfe_basic.ErrBkFindLocation, time.Time{}, "12"},
+		{bfe_basic.ErrBkConnectBackend, time.Now().Add(-time.Second), "4"},
+		{nil, time.Time{}, "13"},
+	}
+
+	for _, tt := range tests {
+		basicReq = newGrpcTestRequest("application/grpc", "")
+		setGrpcInfo(basicReq)
+		basicReq.GetGrpcInfo().Deadline = tt.deadline
+		basicReq.ErrCode = tt.err
+		basicReq.BfeStatusCode = bfe_http.StatusInternalServerError
+
+		res = createErrResp(basicReq)
+		if res.StatusCode != bfe_http.StatusOK {
+			t.Errorf("status code should be 200, got %d", res.StatusCode)
+		}
+		if res.Header.Get("Content-Type") != "application/grpc" {
+			t.Errorf("wrong content type %s", res.Header.Get("Content-Type"))
+		}
+		if status := res.Header.Get("Grpc-Status"); status != tt.status {
+			t.Errorf("grpc status for %v should be %s, got %s", tt.err, tt.status, status)
+		}
+		if basicReq.BfeStatusCode != bfe_http.StatusInternalServerError {
+			t.Errorf("BfeStatusCode should be kept as 500, got %d", basicReq.BfeStatusCode)
+		}
+	}
+}
+
+func TestRecordGrpcStatus(t *testing.T) {
+	tests := []struct {
+		statusCode int
+		header     bfe_http.Header
+		trailer    http.Header
+		status     string
+		message    string
+	}{
+		{200, bfe_http.Header{}, http.Header{"Grpc-Status": {"5"}, "Grpc-Message": {"not found"}}, "5", "not found"},
+		{200, bfe_http.Header{"Grpc-Status": {"7"}}, http.Header{}, "7", ""},
+		{200, bfe_http.Header{}, http.Header{"Grpc-Status": {"0"}}, "0", ""},
+		{200, bfe_http.Header{}, http.Header{}, "", ""},
+		{503, bfe_http.Header{}, nil, "14", ""},
+	}
+
+	for i, tt := range tests {
+		basicReq := newGrpcTestRequest("application/grpc", "")
+		setGrpcInfo(basicReq)
+
+		res := &bfe_http.Response{StatusCode: tt.statusCode, Header: tt.header}
+		if tt.trailer != nil {
+			res.H2Trailer = &tt.trailer
+		}
+		recordGrpcStatus(basicReq, res)
+
+		info := basicReq.GetGrpcInfo()
+		if info.Status != tt.status || info.Message != tt.message {
+			t.Errorf("case %d: got status %s message %s, want %s %s", i, info.Status, info.Message,
+				tt.status, tt.message)
+		}
+
+		// non-OK status is regarded as error of backend
+		failed := tt.status != "" && tt.status != "0"
+		if (basicReq.ErrCode == bfe_basic.ErrBkGrpcStatus) != failed {
+			t.Errorf("case %d: unexpected ErrCode %v", i, basicReq.ErrCode)
+		}
+	}
+}
@@ -0,0 +1,389 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// hedged requests for idempotent requests
+//
+// If no response is received within a latency percentile of cluster, a
+// hedged request is sent to another backend. The first successful response
+// wins, and the other request is cancelled.
+//
+// Result of the winning attempt is recorded by clusterInvoke as usual, and
+// that of the losing attempt is recorded to backend health and outlier
+// detection here. A losing attempt cancelled by proxy is neutral to its
+// backend. The hedged request is charged to retry budget when it is sent,
+// whether it wins or loses.
+
+package bfe_server
+
+import (
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+import (
+	bfe_cluster_backend "github.com/bfenetworks/bfe/bfe_balance/backend"
+	"github.com/bfenetworks/bfe/bfe_balance/bal_gslb"
+	"github.com/bfenetworks/bfe/bfe_basic"
+	"github.com/bfenetworks/bfe/bfe_config/bfe_cluster_conf/cluster_conf"
+	"github.com/bfenetworks/bfe/bfe_fcgi"
+	"github.com/bfenetworks/bfe/bfe_http"
+	"github.com/bfenetworks/bfe/bfe_route/bfe_cluster"
+)
+
+const (
+	latencySampleSize   = 1000 // number of recent latencies kept
+	latencyMinSamples   = 100  // min number of latencies before hedging
+	latencySortInterval = 100  // sort latencies again after number of new samples
+)
+
+// latencyStat keeps latencies of recent responses from cluster.
+type latencyStat struct {
+	lock    sync.Mutex
+	samples []time.Duration // ring buffer of recent latencies
+	next    int             // next position in ring buffer
+	sorted  []time.Duration // sorted samples
+	updates int             // number of new samples since last sort
+}
+
+// Add adds latency of a response.
+func (l *latencyStat) Add(d time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if len(l.samples) < latencySampleSize {
+		l.samples = append(l.samples, d)
+	} else {
+		l.samples[l.next] = d
+		l.next = (l.next + 1) % latencySampleSize
+	}
+	l.updates++
+}
+
+// Percentile returns latency of given percentile. It returns false if there
+// are not enough samples.
+func (l *latencyStat) Percentile(percentile int) (time.Duration, bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if len(l.samples) < latencyMinSamples {
+		return 0, false
+	}
+	if len(l.sorted) == 0 || l.updates >= latencySortInterval {
+		l.sorted = append(l.sorted[:0], l.samples...)
+		sort.Slice(l.sorted, func(i, j int) bool { return l.sorted[i] < l.sorted[j] })
+		l.updates = 0
+	}
+	return l.sorted[(len(l.sorted)-1)*percentile/100], true
+}
+
+// isHedgeable checks whether request is idempotent and without body.
+func isHedgeable(req *bfe_http.Request) bool {
+	switch req.Method {
+	case "GET", "HEAD", "OPTIONS":
+	default:
+		return false
+	}
+	return req.ContentLength == 0 && (req.Body == nil || req.Body == bfe_http.EofReader)
+}
+
+// cloneOutRequest clones request for an attempt, which may run concurrently
+// with other attempts.
+func cloneOutRequest(req *bfe_http.Request) *bfe_http.Request {
+	clone := new(bfe_http.Request)
+	*clone = *req
+	if req.URL != nil {
+		u := *req.URL
+		clone.URL = &u
+	}
+	if req.State != nil {
+		s := *req.State
+		clone.State = &s
+	}
+	clone.Header = req.Header.Clone()
+	clone.Trailer = req.Trailer.Clone()
+	return clone
+}
+
+// hedgeAttempt is an attempt of hedged requests.
+type hedgeAttempt struct {
+	req    *bfe_http.Request
+	cancel chan struct{}
+	once   sync.Once
+}
+
+func newHedgeAttempt(outreq *bfe_http.Request) *hedgeAttempt {
+	a := &hedgeAttempt{req: cloneOutRequest(outreq), cancel: make(chan struct{})}
+	a.req.Cancel = a.cancel
+	return a
+}
+
+// Cancel cancels the attempt, which is aborted by transport.
+func (a *hedgeAttempt) Cancel() {
+	a.once.Do(func() { close(a.cancel) })
+}
+
+// Cancelled checks whether the attempt is cancelled.
+func (a *hedgeAttempt) Cancelled() bool {
+	select {
+	case <-a.cancel:
+		return true
+	default:
+		return false
+	}
+}
+
+// hedgeAttempts keeps attempts of a request.
+type hedgeAttempts struct {
+	lock       sync.Mutex
+	attempts   []*hedgeAttempt
+	clientGone bool
+}
+
+// Add adds a new attempt. It returns nil if client has gone away.
+func (h *hedgeAttempts) Add(outreq *bfe_http.Request) *hedgeAttempt {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.clientGone {
+		return nil
+	}
+	a := newHedgeAttempt(outreq)
+	h.attempts = append(h.attempts, a)
+	return a
+}
+
+// CancelAll cancels all attempts for client has gone away.
+func (h *hedgeAttempts) CancelAll() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.clientGone = true
+	for _, a := range h.attempts {
+		a.Cancel()
+	}
+}
+
+// hedgeDelay returns delay before sending hedged request. It returns false
+// if request should not be hedged.
+func hedgeDelay(cluster *bfe_cluster.BfeCluster, bal *bal_gslb.BalanceGslb,
+	breaker *CircuitBreaker, request *bfe_basic.Request) (time.Duration, bool) {
+	percentile := cluster.HedgePercentile()
+	if percentile <= 0 || request.RetryTime > 0 ||
+		bal.BalanceMode == cluster_conf.BalanceModeEPP || !isHedgeable(request.OutRequest) {
+		return 0, false
+	}
+
+	delay, ok := breaker.latency.Percentile(percentile)
+	if !ok {
+		return 0, false
+	}
+	if minDelay := cluster.HedgeMinDelay(); delay < minDelay {
+		delay = minDelay
+	}
+	return delay, true
+}
+
+// hedgeResult is result of an attempt.
+type hedgeResult struct {
+	res       *bfe_http.Response
+	err       error
+	backend   *bfe_cluster_backend.BfeBackend
+	outreq    *bfe_http.Request
+	latency   time.Duration
+	hedged    bool // result of hedged request?
+	cancelled bool // attempt cancelled before it finished?
+}
+
+// hedgeRoundTrip sends request to backend, and sends a hedged request to
+// another backend if no response is received within delay. Attempts in
+// flight are canceled if client has gone away.
+//
+// Note: connection num of winning backend is decreased by caller (via
+// request.Trans.Backend), and that of the other backend is decreased here.
+func (p *ReverseProxy) hedgeRoundTrip(cluster *bfe_cluster.BfeCluster, bal *bal_gslb.BalanceGslb,
+	breaker *CircuitBreaker, request *bfe_basic.Request, rw bfe_http.ResponseWriter,
+	backend *bfe_cluster_backend.BfeBackend, delay time.Duration) (
+	*bfe_http.Response, *bfe_cluster_backend.BfeBackend, error) {
+	transport := request.Trans.Transport
+	outreq := request.OutRequest
+	results := make(chan hedgeResult, 2)
+
+	send := func(backend *bfe_cluster_backend.BfeBackend, attempt *hedgeAttempt, hedged bool) {
+		start := time.Now()
+		res, err := transport.RoundTrip(attempt.req)
+		if hedged {
+			breaker.ReleaseRetry()
+		}
+		results <- hedgeResult{res, err, backend, attempt.req, time.Since(start), hedged, attempt.Cancelled()}
+	}
+
+	// cancel attempts in flight if client has gone away
+	attempts := new(hedgeAttempts)
+	primary := attempts.Add(outreq)
+	if cn, ok := rw.(bfe_http.CloseNotifier); ok {
+		cw := bfe_http.NewCloseWatcher(cn, attempts.CancelAll)
+		go cw.WatchLoop()
+		defer cw.Stop()
+	}
+
+	go send(backend, primary, false)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var winner hedgeResult
+	select {
+	case winner = <-results:
+		p.finishHedge(breaker, request, winner)
+		return winner.res, winner.backend, winner.err
+	case <-timer.C:
+	}
+
+	// send hedged request to another backend. hedged request is limited as
+	// a retry
+	var hedged *hedgeAttempt
+	hedgeBackend := p.selectHedgeBackend(bal, request, backend)
+	if hedgeBackend != nil && breaker.AcquireRetry(cluster, p.proxyState) {
+		if hedged = attempts.Add(outreq); hedged == nil {
+			breaker.ReleaseRetry()
+		}
+	}
+	if hedged == nil {
+		winner = <-results
+		p.finishHedge(breaker, request, winner)
+		return winner.res, winner.backend, winner.err
+	}
+	request.Stat.IsHedged = true
+	p.proxyState.HedgeRequests.Inc(1)
+
+	setBackendAddr(hedged.req, hedgeBackend)
+	hedgeBackend.IncConnNum()
+	go send(hedgeBackend, hedged, true)
+
+	// the first successful response wins
+	winner = <-results
+	if winner.err != nil {
+		loser := winner
+		winner = <-results
+		p.finishHedgeLoser(cluster, bal, breaker, request, loser)
+	} else {
+		if winner.hedged {
+			primary.Cancel()
+		} else {
+			hedged.Cancel()
+		}
+		go func() {
+			p.finishHedgeLoser(cluster, bal, breaker, request, <-results)
+		}()
+	}
+
+	if winner.hedged {
+		request.Stat.IsHedgeWon = true
+		p.proxyState.HedgeWins.Inc(1)
+		request.Trans.Backend = winner.backend
+	}
+	p.finishHedge(breaker, request, winner)
+	return winner.res, winner.backend, winner.err
+}
+
+// selectHedgeBackend selects a backend different from the given one for
+// hedged request. It returns nil if there is no such backend.
+func (p *ReverseProxy) selectHedgeBackend(bal *bal_gslb.BalanceGslb, request *bfe_basic.Request,
+	backend *bfe_cluster_backend.BfeBackend) *bfe_cluster_backend.BfeBackend {
+	// Note: balance on a snapshot of request, for fields of request (eg.
+	// ErrCode, Backend and Stat) should not be modified by hedged request.
+	// Attempts in flight only access their own clone of out request.
+	snapshot := *request
+	if request.Stat != nil {
+		stat := *request.Stat
+		snapshot.Stat = &stat
+	}
+
+	hedgeBackend, err := bal.Balance(&snapshot)
+	if err != nil || hedgeBackend == backend {
+		return nil
+	}
+	return hedgeBackend
+}
+
+// finishHedge records result of winning attempt to request.
+func (p *ReverseProxy) finishHedge(breaker *CircuitBreaker, request *bfe_basic.Request, winner hedgeResult) {
+	outreq := request.OutRequest
+	setBackendAddr(outreq, winner.backend)
+	if outreq.State != nil && winner.outreq.State != nil {
+		*outreq.State = *winner.outreq.State
+	}
+	if winner.err == nil {
+		breaker.latency.Add(winner.latency)
+	}
+}
+
+// finishHedgeLoser records result of the losing attempt to its backend, and
+// discards the result.
+//
+// Note: it may run after request is finished, so only immutable fields of
+// request are accessed.
+func (p *ReverseProxy) finishHedgeLoser(cluster *bfe_cluster.BfeCluster, bal *bal_gslb.BalanceGslb,
+	breaker *CircuitBreaker, request *bfe_basic.Request, loser hedgeResult) {
+	defer loser.backend.DecConnNum()
+	if loser.res != nil {
+		loser.res.Body.Close()
+	}
+
+	result, ok := hedgeOutlierResult(loser, request.RemoteAddr)
+	if !ok {
+		return
+	}
+
+	backend := loser.backend
+	if loser.err == nil || isRespHeaderTimeout(loser.err) {
+		backend.OnResponseTime(loser.latency)
+	}
+	if loser.err == nil {
+		breaker.latency.Add(loser.latency)
+		if checkBackendStatus(cluster.OutlierDetectionHttpCode(), loser.res.StatusCode) {
+			backend.OnFailByCluster(cluster)
+		} else {
+			backend.OnSuccess()
+		}
+	} else {
+		backend.OnFailByCluster(cluster)
+	}
+	bal.OnOutlierResult(backend, result)
+}
+
+// hedgeOutlierResult returns outlier result of an attempt, the same as
+// clusterInvoke does. It returns false if the attempt is neutral to backend,
+// e.g. cancelled by proxy, or failed for client or transport.
+func hedgeOutlierResult(r hedgeResult, remoteAddr net.Addr) (int, bool) {
+	if r.cancelled {
+		return 0, false
+	}
+	if r.err == nil {
+		return outlierResultByStatus(r.res.StatusCode), true
+	}
+
+	switch err := r.err.(type) {
+	case bfe_http.ConnectError, bfe_fcgi.ConnectError, bfe_http.ReadRespHeaderError,
+		bfe_fcgi.ReadRespHeaderError, bfe_http.RespHeaderTimeoutError:
+		return bfe_cluster_backend.OutlierGatewayFailure, true
+	case bfe_http.WriteRequestError:
+		if !err.CheckTargetError(remoteAddr) {
+			return bfe_cluster_backend.OutlierGatewayFailure, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bfe_server
+
+import (
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+import (
+	bfe_cluster_backend "github.com/bfenetworks/bfe/bfe_balance/backend"
+	"github.com/bfenetworks/bfe/bfe_http"
+)
+
+func TestLatencyStatPercentile(t *testing.T) {
+	var l latencyStat
+	for i := 1; i < latencyMinSamples; i++ {
+		l.Add(time.Duration(i) * time.Millisecond)
+	}
+	if _, ok := l.Percentile(90); ok {
+		t.Fatalf("percentile should not be available without enough samples")
+	}
+
+	l.Add(latencyMinSamples * time.Millisecond)
+	d, ok := l.Percentile(90)
+	if !ok || d != 90*time.Millisecond {
+		t.Errorf("Percentile(90) = %v, %v, want 90ms", d, ok)
+	}
+
+	// old samples are replaced by new ones
+	for i := 0; i < latencySampleSize; i++ {
+		l.Add(time.Second)
+	}
+	if d, _ := l.Percentile(50); d != time.Second {
+		t.Errorf("Percentile(50) = %v, want 1s", d)
+	}
+}
+
+func TestIsHedgeable(t *testing.T) {
+	tests := []struct {
+		method string
+		body   string
+		want   bool
+	}{
+		{"GET", "", true},
+		{"HEAD", "", true},
+		{"POST", "", false},
+		{"GET", "data", false},
+	}
+
+	for _, tt := range tests {
+		req := &bfe_http.Request{Method: tt.method, Body: bfe_http.EofReader}
+		if tt.body != "" {
+			req.Body = io.NopCloser(strings.NewReader(tt.body))
+			req.ContentLength = int64(len(tt.body))
+		}
+		if got := isHedgeable(req); got != tt.want {
+			t.Errorf("isHedgeable(%s, %q) = %v, want %v", tt.method, tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestCloneOutRequest(t *testing.T) {
+	req := &bfe_http.Request{
+		URL:    &url.URL{Host: "10.0.0.1:80", Path: "/"},
+		Header: bfe_http.Header{"X-Foo": {"bar"}},
+		State:  new(bfe_http.RequestState),
+	}
+	clone := cloneOutRequest(req)
+	clone.URL.Host = "10.0.0.2:80"
+	clone.State.SerialNumber = 1
+	clone.Header.Set("X-Foo", "baz")
+
+	if req.URL.Host != "10.0.0.1:80" || req.State.SerialNumber != 0 ||
+		req.Header.Get("X-Foo") != "bar" {
+		t.Errorf("clone should not modify original request")
+	}
+}
+
+func TestHedgeAttemptsCancelAll(t *testing.T) {
+	req := &bfe_http.Request{URL: &url.URL{Host: "10.0.0.1:80", Path: "/"}}
+	attempts := new(hedgeAttempts)
+	a := attempts.Add(req)
+	if a == nil || a.req.Cancel == nil {
+		t.Fatalf("attempt should be cancelable")
+	}
+
+	// attempt canceled by caller is canceled again if client has gone away
+	a.Cancel()
+	attempts.CancelAll()
+	select {
+	case <-a.req.Cancel:
+	default:
+		t.Errorf("attempt should be canceled")
+	}
+
+	if attempts.Add(req) != nil {
+		t.Errorf("no attempt should be added after client has gone away")
+	}
+}
+
+func TestHedgeOutlierResult(t *testing.T) {
+	clientAddr := &net.TCPAddr{IP: net.ParseIP("10.0.0.2"), Port: 12345}
+	tests := []struct {
+		name   string
+		result hedgeResult
+		want   int
+		ok     bool
+	}{
+		{"ok", hedgeResult{res: &bfe_http.Response{StatusCode: 200}}, bfe_cluster_backend.OutlierSuccess, true},
+		{"500", hedgeResult{res: &bfe_http.Response{StatusCode: 500}}, bfe_cluster_backend.OutlierError5xx, true},
+		{"503", hedgeResult{res: &bfe_http.Response{StatusCode: 503}}, bfe_cluster_backend.OutlierGatewayFailure, true},
+		{"connect", hedgeResult{err: bfe_http.ConnectError{}}, bfe_cluster_backend.OutlierGatewayFailure, true},
+		{"timeout", hedgeResult{err: bfe_http.RespHeaderTimeoutError{}}, bfe_cluster_backend.OutlierGatewayFailure, true},
+		{"write backend", hedgeResult{err: bfe_http.WriteRequestError{Err: io.ErrClosedPipe}},
+			bfe_cluster_backend.OutlierGatewayFailure, true},
+		{"write client", hedgeResult{err: bfe_http.WriteRequestError{Err: &net.OpError{Addr: clientAddr}}}, 0, false},
+		{"broken", hedgeResult{err: bfe_http.TransportBrokenError{}}, 0, false},
+		// cancelled loser is neutral, even if a response is received
+		{"cancelled", hedgeResult{err: bfe_http.ReadRespHeaderError{}, cancelled: true}, 0, false},
+		{"cancelled ok", hedgeResult{res: &bfe_http.Response{StatusCode: 200}, cancelled: true}, 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := hedgeOutlierResult(tt.result, clientAddr)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("%s: hedgeOutlierResult() = %d, %v, want %d, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestHedgeAttemptCancelled(t *testing.T) {
+	a := newHedgeAttempt(&bfe_http.Request{URL: &url.URL{Host: "10.0.0.1:80", Path: "/"}})
+	if a.Cancelled() {
+		t.Fatalf("attempt should not be cancelled")
+	}
+	a.Cancel()
+	if !a.Cancelled() {
+		t.Errorf("attempt should be cancelled")
+	}
+}
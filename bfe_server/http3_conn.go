@@ -0,0 +1,118 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// server side of http3 connection
+
+package bfe_server
+
+import (
+	"github.com/bfenetworks/go-lib/gotrack"
+	"github.com/bfenetworks/go-lib/log"
+	"github.com/quic-go/quic-go"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_basic"
+	"github.com/bfenetworks/bfe/bfe_http3"
+	"github.com/bfenetworks/bfe/bfe_module"
+)
+
+// newHttp3Conn creates connection for quic connection which finishes
+// tls handshake. Requests are read from streams, instead of conn buffer.
+func newHttp3Conn(qc quic.Connection, srv *BfeServer) *conn {
+	h3c := bfe_http3.NewConn(qc)
+
+	c := new(conn)
+	c.remoteAddr = h3c.RemoteAddr().String()
+	c.server = srv
+	c.rwc = h3c
+
+	c.session = bfe_basic.NewSession(h3c)
+	c.initSessionVip()
+	c.session.IsSecure = true
+	c.session.TlsState = h3c.TlsState
+
+	return c
+}
+
+// serveHttp3 serves a new http3 connection.
+func (c *conn) serveHttp3() {
+	var hl *bfe_module.HandlerList
+	var retVal int
+	session := c.session
+	c.server.connWaitGroup.Add(1)
+	proxyState := c.server.serverStatus.ProxyState
+
+	defer func() {
+		if err := recover(); err != nil {
+			log.Logger.Warn("panic: conn.serveHttp3(): %v, readTotal=%d,writeTotal=%d,reqNum=%d,%v\n%s",
+				c.remoteAddr,
+				c.session.ReadTotal(), c.session.WriteTotal(),
+				c.session.ReqNum(),
+				err, gotrack.CurrentStackTrace(0))
+
+			proxyState.PanicClientConnServe.Inc(1)
+			bfe_http3.GetHttp3State().H3PanicConn.Inc(1)
+		}
+		c.server.connWaitGroup.Done()
+	}()
+
+	defer func() {
+		// callback of finish connection
+		c.finish()
+		c.close()
+
+		if len(session.Proto) > 0 {
+			proxyState.ClientConnActiveDec(session.Proto, 1)
+		}
+		if session.ReqNumActive() != 0 {
+			proxyState.ClientConnUnfinishedReq.Inc(1)
+		}
+	}()
+
+	// Callback for HANDLE_ACCEPT
+	hl = c.server.CallBacks.GetHandlerList(bfe_module.HandleAccept)
+	if hl != nil {
+		retVal = hl.FilterAccept(c.session)
+		if retVal == bfe_module.BfeHandlerClose {
+			// close the connection
+			return
+		}
+	}
+
+	// Note: tls handshake is finished by quic before connection accepted
+	proxyState.TlsHandshakeAll.Inc(1)
+	proxyState.TlsHandshakeSucc.Inc(1)
+
+	// Callback for HANDLE_HANDSHAKE
+	hl = c.server.CallBacks.GetHandlerList(bfe_module.HandleHandshake)
+	if hl != nil {
+		retVal = hl.FilterAccept(c.session)
+		if retVal == bfe_module.BfeHandlerClose {
+			// close the connection
+			return
+		}
+	}
+
+	proto := bfe_http3.NextProtoH3
+	proxyState.ClientConnServedInc(proto, 1)
+	proxyState.ClientConnActiveInc(proto, 1)
+	c.session.Proto = proto
+
+	// process requests from http3 streams
+	handler := NewProtocolHandler(c, proto)
+	if err := c.server.Http3Server.ServeConn(c.rwc.(*bfe_http3.Conn), handler); err != nil {
+		log.Logger.Debug("conn.serveHttp3(): serve conn %s: %v", c.remoteAddr, err)
+	}
+}
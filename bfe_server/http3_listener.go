@@ -0,0 +1,251 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// listener for http3 over quic
+
+package bfe_server
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+import (
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_config/bfe_tls_conf/tls_rule_conf"
+	"github.com/bfenetworks/bfe/bfe_tls"
+)
+
+var errNoCertificate = errors.New("http3: no certificate available")
+
+type Http3Listener struct {
+	transport *quic.Transport // quic transport on udp socket
+	listener  *quic.Listener  // listener for quic connections
+
+	srv    *BfeServer  // bfe server
+	vip    net.IP      // vip of listener, nil if listen on wildcard address
+	config *tls.Config // base tls config for listener
+	lock   sync.Mutex
+}
+
+// NewHttp3Listener creates listener for http3 on given udp socket.
+// Certificates and tls rules are shared with https listener.
+//
+// Note: Destination address of udp packets is unknown to listener on
+// wildcard address, so certificates and tls rules are chosen by vip only if
+// listener is on specified vip (see checkHttp3TlsRule).
+func NewHttp3Listener(conn net.PacketConn, srv *BfeServer) (*Http3Listener, error) {
+	l := &Http3Listener{srv: srv}
+	l.transport = &quic.Transport{Conn: conn}
+	if addr, ok := conn.LocalAddr().(*net.UDPAddr); ok && !addr.IP.IsUnspecified() {
+		l.vip = addr.IP
+	}
+
+	// tls 1.3 is required by quic
+	l.config = &tls.Config{MinVersion: tls.VersionTLS13}
+	if srv.TLSConfig.SessionTicketsDisabled {
+		l.config.SessionTicketsDisabled = true
+	} else {
+		l.config.SetSessionTicketKeys(http3TicketKeys(srv.ticketKeyRotator.keys.Keys()))
+	}
+
+	tlsConf := http3.ConfigureTLSConfig(&tls.Config{
+		GetConfigForClient: l.getConfigForClient,
+	})
+	quicConf := &quic.Config{
+		MaxIdleTimeout: srv.ReadTimeout,
+	}
+
+	var err error
+	l.listener, err = l.transport.Listen(tlsConf, quicConf)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *Http3Listener) getConfig() *tls.Config {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.config
+}
+
+// getConfigForClient returns tls config for the connection, with
+// certificate, client auth and curve preferences chosen by vip and sni.
+//
+// Note: Grade of tls rule is always satisfied since only tls 1.3 is allowed.
+// Chacha20 of tls rule is ignored since cipher suites of tls 1.3 are not
+// configurable in crypto/tls.
+func (l *Http3Listener) getConfigForClient(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+	vip := l.vip
+	serverName := strings.ToLower(hello.ServerName)
+
+	cert := l.srv.MultiCert.GetByVipSni(vip, serverName)
+	if l.srv.acmeClient != nil {
+		// certificate from acme server is preferred
+		c, err := l.srv.acmeClient.GetCertificate(&bfe_tls.ClientHelloInfo{ServerName: serverName})
+		if err != nil {
+			return nil, err
+		}
+		if c != nil {
+			cert = c
+		}
+	}
+	if cert == nil {
+		return nil, errNoCertificate
+	}
+
+	config := l.getConfig().Clone()
+	config.Certificates = []tls.Certificate{{
+		Certificate: cert.Certificate,
+		PrivateKey:  cert.PrivateKey,
+		OCSPStaple:  cert.OCSPStaple,
+		Leaf:        cert.Leaf,
+	}}
+
+	rule := l.srv.TLSServerRule.GetByVipSni(vip, serverName)
+	if rule.ClientAuth {
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+		config.ClientCAs = rule.ClientCAs
+		config.VerifyPeerCertificate = checkCertRevoked(rule.ClientCRLPool, rule.ClientRevocation)
+	}
+
+	curves := rule.CurvePreferences
+	if len(curves) == 0 {
+		curves = l.srv.TLSConfig.CurvePreferences
+	}
+	config.CurvePreferences = http3CurvePreferences(curves)
+
+	return config, nil
+}
+
+// http3CurvePreferences converts curve preferences for crypto/tls. Curves not
+// supported by crypto/tls are ignored.
+func http3CurvePreferences(curves []bfe_tls.CurveID) []tls.CurveID {
+	var prefs []tls.CurveID
+	for _, curve := range curves {
+		switch id := tls.CurveID(curve); id {
+		case tls.X25519, tls.CurveP256, tls.CurveP384, tls.CurveP521:
+			prefs = append(prefs, id)
+		}
+	}
+	return prefs
+}
+
+// http3Vip returns vip of http3 listener on given address, or nil if
+// listener is on wildcard address.
+func http3Vip(addr string) net.IP {
+	ip := net.ParseIP(addr)
+	if ip == nil || ip.IsUnspecified() {
+		return nil
+	}
+	return ip
+}
+
+// checkHttp3TlsRule checks tls rules for http3 listener. Vip of connection is
+// unknown to listener on wildcard address, so tls rules with vip conf are not
+// allowed in that case.
+func (srv *BfeServer) checkHttp3TlsRule(ruleMap tls_rule_conf.TlsRuleMap) error {
+	if srv.Config.Server.Http3Port == 0 || http3Vip(srv.Config.Server.Http3Addr) != nil {
+		return nil
+	}
+	for product, ruleConf := range ruleMap {
+		if len(ruleConf.VipConf) != 0 {
+			return fmt.Errorf("VipConf of %s is not supported while Http3Addr is wildcard address", product)
+		}
+	}
+	return nil
+}
+
+// checkCertRevoked checks client certificates against crl pool, and checks
+// revocation status of leaf certificate online.
+func checkCertRevoked(pool *bfe_tls.CRLPool,
+	checker *bfe_tls.RevocationChecker) func([][]byte, [][]*x509.Certificate) error {
+	if pool == nil && checker == nil {
+		return nil
+	}
+	return func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+		for _, chain := range verifiedChains {
+			for _, cert := range chain {
+				if pool != nil && pool.CheckCertRevoked(cert) {
+					return fmt.Errorf("tls: revoked client certificate: %s %s",
+						strings.ToUpper(cert.SerialNumber.Text(16)), cert.Subject.CommonName)
+				}
+			}
+		}
+
+		if checker != nil && len(verifiedChains) > 0 && len(verifiedChains[0]) > 1 {
+			leaf := verifiedChains[0][0]
+			if err := checker.Check(leaf, verifiedChains[0][1]); err != nil {
+				if errors.Is(err, bfe_tls.ErrCertRevoked) {
+					return fmt.Errorf("tls: revoked client certificate: %s %s",
+						strings.ToUpper(leaf.SerialNumber.Text(16)), leaf.Subject.CommonName)
+				}
+				return fmt.Errorf("tls: failed to check revocation of client's certificate: %v", err)
+			}
+		}
+		return nil
+	}
+}
+
+// UpdateSessionTicketKeys updates session ticket keys.
+func (l *Http3Listener) UpdateSessionTicketKeys(keys []bfe_tls.TicketKey) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	// clone and modify config
+	config := l.config.Clone()
+	config.SetSessionTicketKeys(http3TicketKeys(keys))
+	l.config = config
+}
+
+// http3TicketKeys converts session ticket keys for crypto/tls. The first key
+// is the active key.
+func http3TicketKeys(keys []bfe_tls.TicketKey) [][32]byte {
+	ticketKeys := make([][32]byte, 0, len(keys))
+	for _, key := range keys {
+		raw := make([]byte, 0, 48)
+		raw = append(raw, key.Name[:]...)
+		raw = append(raw, key.Key[:]...)
+		ticketKeys = append(ticketKeys, sha256.Sum256(raw))
+	}
+	return ticketKeys
+}
+
+// Accept waits for and returns the next quic connection.
+func (l *Http3Listener) Accept() (quic.Connection, error) {
+	return l.listener.Accept(context.Background())
+}
+
+// Close stops accepting new connections. Established connections are not
+// closed, so that they could finish gracefully.
+func (l *Http3Listener) Close() error {
+	return l.listener.Close()
+}
+
+// Addr returns local address of the listener.
+func (l *Http3Listener) Addr() net.Addr {
+	return l.listener.Addr()
+}
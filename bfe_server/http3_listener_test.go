@@ -0,0 +1,134 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bfe_server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"testing"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_config/bfe_tls_conf/tls_rule_conf"
+	"github.com/bfenetworks/bfe/bfe_tls"
+)
+
+func newTestHttp3Listener() (*Http3Listener, *bfe_tls.Certificate, *bfe_tls.Certificate) {
+	state := NewServerStatus().ProxyState
+	defaultCert := &bfe_tls.Certificate{Certificate: [][]byte{[]byte("default")}}
+	vipCert := &bfe_tls.Certificate{Certificate: [][]byte{[]byte("vip")}}
+
+	srv := new(BfeServer)
+	srv.TLSConfig = new(bfe_tls.Config)
+	srv.MultiCert = NewMultiCertMap(state)
+	srv.MultiCert.defaultCert = defaultCert
+	srv.MultiCert.vipCertMap["10.0.0.1"] = vipCert
+
+	rule := new(ServerRule)
+	rule.TlsRule.ClientAuth = true
+	rule.TlsRule.ClientCAs = x509.NewCertPool()
+	rule.TlsRule.CurvePreferences = []bfe_tls.CurveID{bfe_tls.X25519}
+	srv.TLSServerRule = NewTLSServerRuleMap(state)
+	srv.TLSServerRule.vipRuleMap["10.0.0.1"] = rule
+
+	l := &Http3Listener{srv: srv, config: &tls.Config{MinVersion: tls.VersionTLS13}}
+	return l, defaultCert, vipCert
+}
+
+func TestHttp3ListenerGetConfigForClient(t *testing.T) {
+	l, defaultCert, vipCert := newTestHttp3Listener()
+
+	cases := []struct {
+		vip        string
+		cert       *bfe_tls.Certificate
+		clientAuth tls.ClientAuthType
+		curves     int
+	}{
+		{"10.0.0.1", vipCert, tls.RequireAndVerifyClientCert, 1},
+		{"10.0.0.2", defaultCert, tls.NoClientCert, 0},
+		{"", defaultCert, tls.NoClientCert, 0},
+	}
+
+	for _, c := range cases {
+		l.vip = http3Vip(c.vip)
+		config, err := l.getConfigForClient(&tls.ClientHelloInfo{ServerName: "example.org"})
+		if err != nil {
+			t.Fatalf("getConfigForClient(%s): %v", c.vip, err)
+		}
+		if len(config.Certificates) != 1 || string(config.Certificates[0].Certificate[0]) != string(c.cert.Certificate[0]) {
+			t.Errorf("getConfigForClient(%s): unexpected certificate", c.vip)
+		}
+		if config.ClientAuth != c.clientAuth {
+			t.Errorf("getConfigForClient(%s): ClientAuth should be %v, but is %v", c.vip, c.clientAuth, config.ClientAuth)
+		}
+		if len(config.CurvePreferences) != c.curves {
+			t.Errorf("getConfigForClient(%s): CurvePreferences should be %d, but is %v", c.vip, c.curves, config.CurvePreferences)
+		}
+		if config.MinVersion != tls.VersionTLS13 {
+			t.Errorf("getConfigForClient(%s): MinVersion should be tls1.3", c.vip)
+		}
+	}
+}
+
+func TestHttp3ListenerNoCertificate(t *testing.T) {
+	l, _, _ := newTestHttp3Listener()
+	l.srv.MultiCert.defaultCert = nil
+
+	if _, err := l.getConfigForClient(&tls.ClientHelloInfo{}); err != errNoCertificate {
+		t.Errorf("getConfigForClient() should return errNoCertificate, but is %v", err)
+	}
+}
+
+func TestHttp3CurvePreferences(t *testing.T) {
+	curves := []bfe_tls.CurveID{bfe_tls.X25519, bfe_tls.CurveID(0xff), bfe_tls.CurveP256}
+	prefs := http3CurvePreferences(curves)
+	if len(prefs) != 2 || prefs[0] != tls.X25519 || prefs[1] != tls.CurveP256 {
+		t.Errorf("http3CurvePreferences() should be [X25519 P256], but is %v", prefs)
+	}
+}
+
+func TestCheckHttp3TlsRule(t *testing.T) {
+	ruleMap := tls_rule_conf.TlsRuleMap{
+		"product": &tls_rule_conf.TlsRuleConf{VipConf: []string{"10.0.0.1"}},
+	}
+
+	cases := []struct {
+		port    int
+		addr    string
+		success bool
+	}{
+		{0, "", true},
+		{443, "10.0.0.1", true},
+		{443, "", false},
+		{443, "0.0.0.0", false},
+		{443, "::", false},
+	}
+
+	for _, c := range cases {
+		srv := new(BfeServer)
+		srv.Config.Server.Http3Port = c.port
+		srv.Config.Server.Http3Addr = c.addr
+		err := srv.checkHttp3TlsRule(ruleMap)
+		if (err == nil) != c.success {
+			t.Errorf("checkHttp3TlsRule(%d, %q) should succeed: %v, but err is %v", c.port, c.addr, c.success, err)
+		}
+	}
+
+	srv := new(BfeServer)
+	srv.Config.Server.Http3Port = 443
+	if err := srv.checkHttp3TlsRule(tls_rule_conf.TlsRuleMap{"product": &tls_rule_conf.TlsRuleConf{}}); err != nil {
+		t.Errorf("checkHttp3TlsRule() without vip conf should succeed, but err is %v", err)
+	}
+}
@@ -34,6 +34,7 @@ import (
 	"github.com/bfenetworks/bfe/bfe_basic/condition"
 	"github.com/bfenetworks/bfe/bfe_bufio"
 	"github.com/bfenetworks/bfe/bfe_http"
+	"github.com/bfenetworks/bfe/bfe_http3"
 	"github.com/bfenetworks/bfe/bfe_module"
 	"github.com/bfenetworks/bfe/bfe_tls"
 	"github.com/bfenetworks/bfe/bfe_util"
@@ -146,22 +147,7 @@ func newConn(rwc net.Conn, srv *BfeServer) (c *conn, err error) {
 	c.reqSN = 0
 
 	c.session = bfe_basic.NewSession(rwc)
-	vip, vport, err := bfe_util.GetVipPort(rwc)
-	if err == nil {
-		c.session.Vip = vip
-		c.session.Vport = vport
-
-		// get product if vip -> product table is set
-		sf := srv.GetServerConf()
-		product, err := sf.HostTable.LookupProductByVip(vip.String())
-		if err == nil {
-			c.session.Product = product
-		}
-
-		log.Logger.Debug("newConn(): VIP: %v, Port: %v, Product: %v", c.session.Vip.String(), vport, product)
-	} else {
-		log.Logger.Debug("newConn(): GetVip: %s", err.Error())
-	}
+	c.initSessionVip()
 
 	if sc, ok := rwc.(*bfe_tls.Conn); ok {
 		c.session.IsSecure = true
@@ -171,6 +157,27 @@ func newConn(rwc net.Conn, srv *BfeServer) (c *conn, err error) {
 	return c, nil
 }
 
+// initSessionVip sets vip and product of connection for session.
+func (c *conn) initSessionVip() {
+	vip, vport, err := bfe_util.GetVipPort(c.rwc)
+	if err != nil {
+		log.Logger.Debug("newConn(): GetVip: %s", err.Error())
+		return
+	}
+
+	c.session.Vip = vip
+	c.session.Vport = vport
+
+	// get product if vip -> product table is set
+	sf := c.server.GetServerConf()
+	product, err := sf.HostTable.LookupProductByVip(vip.String())
+	if err == nil {
+		c.session.Product = product
+	}
+
+	log.Logger.Debug("newConn(): VIP: %v, Port: %v, Product: %v", c.session.Vip.String(), vport, product)
+}
+
 // Read next request from connection.
 func (c *conn) readRequest() (request *bfe_basic.Request, err error) {
 	c.lr.N = int64(c.server.MaxHeaderBytes) + 4096 /* bufio slop */
@@ -351,6 +358,11 @@ func (c *conn) serve() {
 			}
 		}
 
+		// connection for tls-alpn-01 challenge is closed after handshake
+		if tlsState.NegotiatedProtocol == bfe_tls.ACMETLSProtocol {
+			return
+		}
+
 		// upgrade to negotiated protocol
 		proto := tlsState.NegotiatedProtocol
 		if mandatoryProtocol, ok := c.getMandatoryProtocol(tlsConn); ok {
@@ -467,6 +479,12 @@ func (c *conn) serve() {
 			break
 		}
 
+		// answer http-01 challenge from acme server
+		if c.server.serveAcmeChallenge(w, req) {
+			w.finishRequest()
+			break
+		}
+
 		// check whether client request for http upgrade (over http/https conn)
 		if firstRequest {
 			nextProto := checkHttpUpgrade(request)
@@ -525,6 +543,11 @@ func (c *conn) serveRequest(w bfe_http.ResponseWriter, request *bfe_basic.Reques
 	proxyState.ClientReqServedInc(session.Proto, 1)
 	proxyState.ClientReqActiveInc(session.Proto, 1)
 
+	// advertise http3 for request over tcp
+	if len(c.server.altSvc) > 0 && session.IsSecure && session.Proto != bfe_http3.NextProtoH3 {
+		w.Header().Set("Alt-Svc", c.server.altSvc)
+	}
+
 	// HTTP cannot have multiple simultaneous active requests.[*]
 	// Until the server replies to this request, it can't read another,
 	// so we might as well run the handler in this goroutine.
@@ -27,6 +27,7 @@ import (
 
 import (
 	"github.com/bfenetworks/go-lib/log"
+	"github.com/quic-go/quic-go"
 )
 
 func delayCalc(delay time.Duration) time.Duration {
@@ -56,6 +57,33 @@ func (srv *BfeServer) ServeHttps(ln *HttpsListener) error {
 	return srv.Serve(ln.tlsListener, ln.tcpListener, "HTTPS")
 }
 
+// ServeHttp3 accept incoming http3 connections
+func (srv *BfeServer) ServeHttp3(ln *Http3Listener) error {
+	proxyState := srv.serverStatus.ProxyState
+
+	for {
+		// accept new quic connection (after handshake)
+		qc, e := ln.Accept()
+		if e != nil {
+			proxyState.ErrClientConnAccept.Inc(1)
+
+			// if in GraceShutdown state, exit accept loop after timeout
+			if srv.CheckGracefulShutdown() {
+				shutdownTimeout := srv.Config.Server.GracefulShutdownTimeout
+				time.Sleep(time.Duration(shutdownTimeout) * time.Second)
+			}
+
+			return e
+		}
+
+		// start go-routine for new connection
+		go func(qc quic.Connection, srv *BfeServer) {
+			c := newHttp3Conn(qc, srv)
+			c.serveHttp3()
+		}(qc, srv)
+	}
+}
+
 // Serve accepts incoming connections on the Listener l, creating a
 // new service goroutine for each.  The service goroutines read requests and
 // then call srv.Handler to reply to them.
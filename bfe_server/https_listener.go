@@ -18,7 +18,6 @@ package bfe_server
 
 import (
 	"net"
-	"sync"
 )
 
 import (
@@ -28,31 +27,12 @@ import (
 type HttpsListener struct {
 	tlsListener net.Listener // listener for https
 	tcpListener net.Listener // underlying tcp listener
-
-	config *bfe_tls.Config // tls config for listener
-	lock   sync.Mutex
 }
 
 func NewHttpsListener(listener net.Listener, config *bfe_tls.Config) *HttpsListener {
 	httpsListener := &HttpsListener{
 		tcpListener: listener,
-		config:      config,
 		tlsListener: bfe_tls.NewListener(listener, config),
 	}
 	return httpsListener
 }
-
-// UpdateSessionTicketKey updates session ticket key.
-func (l *HttpsListener) UpdateSessionTicketKey(key []byte) {
-	l.lock.Lock()
-	defer l.lock.Unlock()
-
-	// clone and modify config
-	config := l.config.Clone()
-	copy(config.SessionTicketKeyName[:], key[:16])
-	copy(config.SessionTicketKey[:], key[16:])
-
-	// update config for listener
-	l.config = config
-	bfe_tls.UpdateListener(l.tlsListener, config)
-}
@@ -0,0 +1,183 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// traffic mirroring to shadow cluster
+//
+// Requests with mirror policy (set by mod_mirror) are copied to the shadow
+// cluster asynchronously. Responses from shadow cluster are discarded, and
+// never affect the client.
+
+package bfe_server
+
+import (
+	"bytes"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+import (
+	"github.com/bfenetworks/go-lib/log"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_basic"
+	"github.com/bfenetworks/bfe/bfe_config/bfe_cluster_conf/cluster_conf"
+	"github.com/bfenetworks/bfe/bfe_http"
+	"github.com/bfenetworks/bfe/bfe_route"
+	"github.com/bfenetworks/bfe/bfe_route/bfe_cluster"
+)
+
+// bufferMirrorBody buffers request body for mirroring. Body of outreq is
+// replaced with the buffered one, so it can still be sent to the primary
+// cluster. It returns false if body is larger than maxSize or fails to read.
+func bufferMirrorBody(outreq *bfe_http.Request, maxSize int64) ([]byte, bool) {
+	if outreq.Body == nil || outreq.Body == bfe_http.EofReader || outreq.ContentLength == 0 {
+		return nil, true
+	}
+	if outreq.ContentLength > maxSize {
+		return nil, false
+	}
+
+	src := outreq.Body
+	buf, err := io.ReadAll(io.LimitReader(src, maxSize+1))
+
+	// Note: data read out is restored even if reading fails, so primary
+	// cluster gets the same body (and the same error) as without mirroring
+	outreq.Body = &mirrorBody{Reader: io.MultiReader(bytes.NewReader(buf), src), Closer: src}
+	if err != nil || int64(len(buf)) > maxSize {
+		return nil, false
+	}
+	return buf, true
+}
+
+// mirrorBody is request body restored after buffered for mirroring.
+type mirrorBody struct {
+	io.Reader
+	io.Closer
+}
+
+// newMirrorRequest creates request to shadow cluster from outreq.
+func newMirrorRequest(outreq *bfe_http.Request, body []byte) *bfe_http.Request {
+	mirror := cloneOutRequest(outreq)
+	mirror.ContentLength = int64(len(body))
+	mirror.TransferEncoding = nil
+	if len(body) > 0 {
+		mirror.Body = io.NopCloser(bytes.NewReader(body))
+	} else {
+		mirror.Body = bfe_http.EofReader
+	}
+	return mirror
+}
+
+// mirrorRequest copies request to shadow cluster if mirror policy is set.
+func (p *ReverseProxy) mirrorRequest(basicReq *bfe_basic.Request, serverConf *bfe_route.ServerDataConf) {
+	policy := basicReq.GetMirrorPolicy()
+	if policy == nil {
+		return
+	}
+
+	cluster, err := serverConf.ClusterTable.Lookup(policy.ClusterName)
+	if err != nil {
+		log.Logger.Debug("mirrorRequest(): no cluster for %s", policy.ClusterName)
+		p.proxyState.MirrorReqDrop.Inc(1)
+		return
+	}
+
+	body, ok := bufferMirrorBody(basicReq.OutRequest, policy.MaxBodySize)
+	if !ok {
+		p.proxyState.MirrorReqSkipLargeBody.Inc(1)
+		return
+	}
+
+	mirror := newMirrorRequest(basicReq.OutRequest, body)
+	if cluster.DisableHostHeader {
+		mirror.Host = ""
+	}
+
+	// pseudo request for balance
+	mirrorReq := bfe_basic.NewRequest(mirror, basicReq.Connection, bfe_basic.NewRequestStat(time.Now()),
+		basicReq.Session, nil)
+	mirrorReq.ClientAddr = basicReq.ClientAddr
+	mirrorReq.Route = basicReq.Route
+	mirrorReq.Route.ClusterName = cluster.Name
+	mirrorReq.Backend.ClusterName = cluster.Name
+
+	p.proxyState.MirrorReq.Inc(1)
+	go p.sendMirrorRequest(mirrorReq, cluster, policy.Timeout)
+}
+
+// sendMirrorRequest sends mirrored request to shadow cluster, and discards
+// the response.
+func (p *ReverseProxy) sendMirrorRequest(mirrorReq *bfe_basic.Request, cluster *bfe_cluster.BfeCluster,
+	timeout time.Duration) {
+	p.proxyState.MirrorReqActive.Inc(1)
+	defer p.proxyState.MirrorReqActive.Dec(1)
+
+	clusterName := cluster.Name
+	bal, err := p.server.balTable.Lookup(clusterName)
+	if err != nil || bal.BalanceMode == cluster_conf.BalanceModeEPP {
+		p.proxyState.MirrorReqDrop.Inc(1)
+		return
+	}
+
+	// mirrored requests are limited by circuit breaker of shadow cluster
+	breaker := p.breakers.Get(clusterName)
+	if !breaker.AcquireRequest(cluster, p.proxyState) {
+		p.proxyState.MirrorReqDrop.Inc(1)
+		return
+	}
+	defer breaker.ReleaseRequest()
+	defer breaker.ReleasePending()
+
+	backend, err := bal.Balance(mirrorReq)
+	if err != nil {
+		log.Logger.Debug("sendMirrorRequest(): cluster [%s] select backend failed, err[%s]",
+			clusterName, err.Error())
+		p.proxyState.MirrorReqDrop.Inc(1)
+		return
+	}
+	backend.IncConnNum()
+	defer backend.DecConnNum()
+
+	mirror := mirrorReq.HttpRequest
+	setBackendAddr(mirror, backend)
+	transport := p.getTransport(cluster)
+
+	// Note: request is aborted by transport (of any protocol) once canceled
+	var timedOut int32
+	cancel := make(chan struct{})
+	mirror.Cancel = cancel
+	timer := time.AfterFunc(timeout, func() {
+		atomic.StoreInt32(&timedOut, 1)
+		close(cancel)
+	})
+	defer timer.Stop()
+
+	res, err := transport.RoundTrip(mirror)
+	if err == nil {
+		_, err = io.Copy(io.Discard, res.Body)
+		res.Body.Close()
+	}
+
+	if err != nil {
+		if atomic.LoadInt32(&timedOut) == 1 {
+			p.proxyState.MirrorReqTimeout.Inc(1)
+		} else {
+			p.proxyState.MirrorReqFail.Inc(1)
+		}
+		log.Logger.Debug("sendMirrorRequest(): cluster [%s] backend [%s] err[%s]",
+			clusterName, backend.GetAddrInfo(), err.Error())
+	}
+}
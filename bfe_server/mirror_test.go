@@ -0,0 +1,106 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bfe_server
+
+import (
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_http"
+)
+
+func newMirrorTestRequest(body string, contentLength int64) *bfe_http.Request {
+	return &bfe_http.Request{
+		Method:        "POST",
+		URL:           &url.URL{Path: "/"},
+		Header:        bfe_http.Header{"X-Test": []string{"1"}},
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: contentLength,
+	}
+}
+
+func TestBufferMirrorBody(t *testing.T) {
+	tests := []struct {
+		body          string
+		contentLength int64
+		ok            bool
+	}{
+		{"hello", 5, true},
+		{"hello", -1, true},
+		{"hello world", 11, false},
+		{"hello world", -1, false},
+	}
+
+	for _, tt := range tests {
+		outreq := newMirrorTestRequest(tt.body, tt.contentLength)
+		buf, ok := bufferMirrorBody(outreq, 5)
+		if ok != tt.ok {
+			t.Errorf("bufferMirrorBody(%q) = %v, want %v", tt.body, ok, tt.ok)
+			continue
+		}
+		if ok && string(buf) != tt.body {
+			t.Errorf("buffered body should be %q, not %q", tt.body, buf)
+		}
+
+		// body of primary request is not affected
+		data, _ := io.ReadAll(outreq.Body)
+		if string(data) != tt.body {
+			t.Errorf("body of out request should be %q, not %q", tt.body, data)
+		}
+	}
+}
+
+func TestBufferMirrorBodyReadError(t *testing.T) {
+	errRead := errors.New("read error")
+	outreq := newMirrorTestRequest("", -1)
+	outreq.Body = io.NopCloser(io.MultiReader(strings.NewReader("hel"), iotest.ErrReader(errRead)))
+
+	if _, ok := bufferMirrorBody(outreq, 1024); ok {
+		t.Errorf("bufferMirrorBody() should fail for read error")
+	}
+
+	// data read out is restored for primary request
+	data, err := io.ReadAll(outreq.Body)
+	if string(data) != "hel" || err != errRead {
+		t.Errorf("body of out request should be %q with err %v, not %q %v", "hel", errRead, data, err)
+	}
+}
+
+func TestNewMirrorRequest(t *testing.T) {
+	outreq := newMirrorTestRequest("hello", 5)
+	buf, _ := bufferMirrorBody(outreq, 1024)
+	mirror := newMirrorRequest(outreq, buf)
+
+	mirror.Header.Set("X-Test", "2")
+	mirror.URL.Host = "10.0.0.1:8080"
+	if outreq.Header.Get("X-Test") != "1" || outreq.URL.Host != "" {
+		t.Errorf("mirror request should not modify out request")
+	}
+
+	data, _ := io.ReadAll(mirror.Body)
+	if string(data) != "hello" || mirror.ContentLength != 5 {
+		t.Errorf("unexpected body of mirror request: %q, %d", data, mirror.ContentLength)
+	}
+	data, _ = io.ReadAll(outreq.Body)
+	if string(data) != "hello" {
+		t.Errorf("unexpected body of out request: %q", data)
+	}
+}
@@ -144,6 +144,12 @@ func (srv *BfeServer) BalTableStatusGet(query url.Values) ([]byte, error) {
 	return buff, err
 }
 
+// BalTableDomainGet returns resolving state of domains in balTable.
+func (srv *BfeServer) BalTableDomainGet(query url.Values) ([]byte, error) {
+	output := srv.balTable.GetDomainState()
+	return json.Marshal(output)
+}
+
 // BalTableVersionGet returns versions of balTable.
 func (srv *BfeServer) BalTableVersionGet(query url.Values) ([]byte, error) {
 	// get versions
@@ -30,6 +30,7 @@ import (
 	"github.com/bfenetworks/bfe/bfe_config/bfe_tls_conf/tls_rule_conf"
 	"github.com/bfenetworks/bfe/bfe_http"
 	"github.com/bfenetworks/bfe/bfe_http2"
+	"github.com/bfenetworks/bfe/bfe_http3"
 	"github.com/bfenetworks/bfe/bfe_spdy"
 )
 
@@ -72,6 +73,8 @@ func (p *ProtocolHandler) ServeHTTP(rw bfe_http.ResponseWriter, request *bfe_htt
 				bfe_spdy.CloseConn(request.Body)
 			case tls_rule_conf.HTTP2:
 				bfe_http2.CloseConn(request.Body)
+			case bfe_http3.NextProtoH3:
+				bfe_http3.CloseConn(request.Body)
 			/* never go here */
 			default:
 				return
@@ -88,6 +91,8 @@ func CheckSupportMultiplex(proto string) bool {
 		return true
 	case tls_rule_conf.HTTP2:
 		return true
+	case bfe_http3.NextProtoH3:
+		return true
 	default:
 		return false
 	}
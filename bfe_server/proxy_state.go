@@ -53,6 +53,25 @@ type ProxyState struct {
 	ErrBkRespHeaderTimeout *metrics.Counter
 	ErrBkTransportBroken   *metrics.Counter
 
+	// circuit breaker of cluster
+	ErrBkCircuitOpen              *metrics.Counter // req rejected by circuit breaker
+	CircuitOpenMaxRequests        *metrics.Counter // rejected for reaching max requests
+	CircuitOpenMaxPendingRequests *metrics.Counter // rejected for reaching max pending requests
+	CircuitOpenMaxRetries         *metrics.Counter // retry rejected for reaching max retries
+	RetryBudgetExhausted          *metrics.Counter // retry rejected for exhausted retry budget
+
+	// hedged requests
+	HedgeRequests *metrics.Counter // hedged requests sent
+	HedgeWins     *metrics.Counter // responses from hedged requests
+
+	// traffic mirroring
+	MirrorReq              *metrics.Counter // req mirrored to shadow cluster
+	MirrorReqFail          *metrics.Counter // mirrored req failed
+	MirrorReqTimeout       *metrics.Counter // mirrored req timeout
+	MirrorReqDrop          *metrics.Counter // req not mirrored for no cluster/backend
+	MirrorReqSkipLargeBody *metrics.Counter // req not mirrored for large body
+	MirrorReqActive        *metrics.Gauge   // mirrored req in progress
+
 	// tls handshake
 	TlsHandshakeAll  *metrics.Counter
 	TlsHandshakeSucc *metrics.Counter
@@ -78,11 +97,25 @@ type ProxyState struct {
 	TlsMultiCertUpdate         *metrics.Counter
 	TlsMultiCertUpdateErr      *metrics.Counter
 
+	// tls ocsp stapling
+	TlsOcspFetch        *metrics.Counter
+	TlsOcspFetchErr     *metrics.Counter
+	TlsOcspStapleUpdate *metrics.Counter
+	TlsOcspCertRevoked  *metrics.Counter
+
+	// tls ocsp staples of certificates
+	TlsOcspStatusGood    *metrics.Gauge // certificates with good ocsp status
+	TlsOcspStatusRevoked *metrics.Gauge // certificates with revoked ocsp status
+	TlsOcspStatusUnknown *metrics.Gauge // certificates with unknown ocsp status
+	TlsOcspStatusNone    *metrics.Gauge // certificates without ocsp response
+	TlsOcspStapleMaxAge  *metrics.Gauge // max seconds since this update of staples in use
+
 	// client side
 	ClientReqWithRetry       *metrics.Counter // req served with retry
 	ClientReqWithCrossRetry  *metrics.Counter // req served with cross cluster retry
 	ClientReqFail            *metrics.Counter // req with ErrCode != nil
 	ClientReqFailWithNoRetry *metrics.Counter // req fail with no retry
+	ClientReqRetryDenied     *metrics.Counter // req fail with retry denied by circuit breaker
 	ClientConnUse100Continue *metrics.Counter // connection used Expect 100 Continue
 	ClientConnUnfinishedReq  *metrics.Counter // connection closed with unfinished request
 
@@ -91,6 +124,7 @@ type ProxyState struct {
 	HttpClientReqServed  *metrics.Counter
 	HttpsClientReqServed *metrics.Counter
 	Http2ClientReqServed *metrics.Counter
+	Http3ClientReqServed *metrics.Counter
 	SpdyClientReqServed  *metrics.Counter
 
 	// active request
@@ -98,6 +132,7 @@ type ProxyState struct {
 	HttpClientReqActive  *metrics.Gauge
 	HttpsClientReqActive *metrics.Gauge
 	Http2ClientReqActive *metrics.Gauge
+	Http3ClientReqActive *metrics.Gauge
 	SpdyClientReqActive  *metrics.Gauge
 
 	// connection successful accepted
@@ -105,6 +140,7 @@ type ProxyState struct {
 	HttpClientConnServed   *metrics.Counter
 	HttpsClientConnServed  *metrics.Counter
 	Http2ClientConnServed  *metrics.Counter
+	Http3ClientConnServed  *metrics.Counter
 	SpdyClientConnServed   *metrics.Counter
 	StreamClientConnServed *metrics.Counter
 	WsClientConnServed     *metrics.Counter
@@ -115,6 +151,7 @@ type ProxyState struct {
 	HttpClientConnActive   *metrics.Gauge
 	HttpsClientConnActive  *metrics.Gauge
 	Http2ClientConnActive  *metrics.Gauge
+	Http3ClientConnActive  *metrics.Gauge
 	SpdyClientConnActive   *metrics.Gauge
 	StreamClientConnActive *metrics.Gauge
 	WsClientConnActive     *metrics.Gauge
@@ -133,6 +170,8 @@ func (s *ProxyState) ClientConnServedInc(proto string, value uint) {
 		s.HttpsClientConnServed.Inc(value)
 	case "h2":
 		s.Http2ClientConnServed.Inc(value)
+	case "h3":
+		s.Http3ClientConnServed.Inc(value)
 	case "spdy/3.1":
 		s.SpdyClientConnServed.Inc(value)
 	case "ws":
@@ -153,6 +192,8 @@ func (s *ProxyState) ClientConnActiveInc(proto string, value uint) {
 		s.HttpsClientConnActive.Inc(value)
 	case "h2":
 		s.Http2ClientConnActive.Inc(value)
+	case "h3":
+		s.Http3ClientConnActive.Inc(value)
 	case "spdy/3.1":
 		s.SpdyClientConnActive.Inc(value)
 	case "ws":
@@ -173,6 +214,8 @@ func (s *ProxyState) ClientConnActiveDec(proto string, value uint) {
 		s.HttpsClientConnActive.Dec(value)
 	case "h2":
 		s.Http2ClientConnActive.Dec(value)
+	case "h3":
+		s.Http3ClientConnActive.Dec(value)
 	case "spdy/3.1":
 		s.SpdyClientConnActive.Dec(value)
 	case "ws":
@@ -193,6 +236,8 @@ func (s *ProxyState) ClientReqServedInc(proto string, value uint) {
 		s.HttpsClientReqServed.Inc(value)
 	case "h2":
 		s.Http2ClientReqServed.Inc(value)
+	case "h3":
+		s.Http3ClientReqServed.Inc(value)
 	case "spdy/3.1":
 		s.SpdyClientReqServed.Inc(value)
 	}
@@ -207,6 +252,8 @@ func (s *ProxyState) ClientReqActiveInc(proto string, value uint) {
 		s.HttpsClientReqActive.Inc(value)
 	case "h2":
 		s.Http2ClientReqActive.Inc(value)
+	case "h3":
+		s.Http3ClientReqActive.Inc(value)
 	case "spdy/3.1":
 		s.SpdyClientReqActive.Inc(value)
 	}
@@ -221,6 +268,8 @@ func (s *ProxyState) ClientReqActiveDec(proto string, value uint) {
 		s.HttpsClientReqActive.Dec(value)
 	case "h2":
 		s.Http2ClientReqActive.Dec(value)
+	case "h3":
+		s.Http3ClientReqActive.Dec(value)
 	case "spdy/3.1":
 		s.SpdyClientReqActive.Dec(value)
 	}
@@ -0,0 +1,81 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// retry budget for cluster
+
+package bfe_server
+
+import (
+	"sync"
+	"time"
+)
+
+// retryBudgetWindow is time window of retry budget, in seconds. Tokens
+// deposited before the window expire.
+const retryBudgetWindow = 10
+
+// budgetSlot is tokens deposited and withdrawn in one second.
+type budgetSlot struct {
+	second   int64 // unix time, in seconds
+	deposit  int64 // tokens deposited, in 1/100 token
+	withdraw int64 // tokens withdrawn
+}
+
+// RetryBudget is a token bucket which limits retries to a percentage of
+// recent requests:
+//   - each request deposits percent/100 token,
+//   - each retry withdraws one token,
+//   - minRetries tokens per second are always available.
+type RetryBudget struct {
+	lock  sync.Mutex
+	slots [retryBudgetWindow]budgetSlot
+}
+
+// slot returns slot for given second, reset it if expired.
+func (b *RetryBudget) slot(second int64) *budgetSlot {
+	s := &b.slots[second%retryBudgetWindow]
+	if s.second != second {
+		*s = budgetSlot{second: second}
+	}
+	return s
+}
+
+// Deposit deposits percent/100 token for a request.
+func (b *RetryBudget) Deposit(now time.Time, percent int) {
+	b.lock.Lock()
+	b.slot(now.Unix()).deposit += int64(percent)
+	b.lock.Unlock()
+}
+
+// Withdraw withdraws one token for a retry. It returns false if budget is
+// exhausted.
+func (b *RetryBudget) Withdraw(now time.Time, minRetries int) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	second := now.Unix()
+	balance := int64(minRetries) * retryBudgetWindow * 100
+	for i := range b.slots {
+		s := &b.slots[i]
+		if s.second <= second && second-s.second < retryBudgetWindow {
+			balance += s.deposit - s.withdraw*100
+		}
+	}
+	if balance < 100 {
+		return false
+	}
+
+	b.slot(second).withdraw++
+	return true
+}
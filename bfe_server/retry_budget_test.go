@@ -0,0 +1,89 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bfe_server
+
+import (
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_config/bfe_cluster_conf/cluster_conf"
+	"github.com/bfenetworks/bfe/bfe_route/bfe_cluster"
+)
+
+func TestRetryBudget(t *testing.T) {
+	var b RetryBudget
+	now := time.Unix(1000, 0)
+
+	// 100 requests with 20 percent budget allow 20 retries
+	for i := 0; i < 100; i++ {
+		b.Deposit(now, 20)
+	}
+	for i := 0; i < 20; i++ {
+		if !b.Withdraw(now, 0) {
+			t.Fatalf("retry %d should be allowed", i)
+		}
+	}
+	if b.Withdraw(now, 0) {
+		t.Errorf("retry should be rejected when budget exhausted")
+	}
+
+	// min retries per second are always available
+	if !b.Withdraw(now, 1) {
+		t.Errorf("retry should be allowed for min retries")
+	}
+
+	// deposits expire after the window
+	later := now.Add(retryBudgetWindow * time.Second)
+	b.Deposit(later, 100)
+	if !b.Withdraw(later, 0) {
+		t.Errorf("retry should be allowed after new deposit")
+	}
+	if b.Withdraw(later, 0) {
+		t.Errorf("expired deposits should not be used")
+	}
+}
+
+func TestCircuitBreakerRetryBudget(t *testing.T) {
+	state := NewServerStatus().ProxyState
+	percent, minRetries := 50, 0
+	basic := cluster_conf.ClusterBasicConf{
+		RetryBudgetPercent:    &percent,
+		RetryBudgetMinRetries: &minRetries,
+	}
+	if err := cluster_conf.ClusterBasicConfCheck(&basic); err != nil {
+		t.Fatalf("ClusterBasicConfCheck() error: %v", err)
+	}
+	cluster := bfe_cluster.NewBfeCluster("example")
+	cluster.BasicInit(cluster_conf.ClusterConf{ClusterBasic: &basic})
+	cb := new(CircuitBreaker)
+
+	for i := 0; i < 2; i++ {
+		if !cb.AcquireRequest(cluster, state) {
+			t.Fatalf("request should be admitted")
+		}
+	}
+	if !cb.AcquireRetry(cluster, state) {
+		t.Fatalf("retry should be admitted")
+	}
+	cb.ReleaseRetry()
+	if cb.AcquireRetry(cluster, state) {
+		t.Errorf("retry should be rejected for retry budget")
+	}
+	if cb.Retries() != 0 {
+		t.Errorf("retries should be 0, not %d", cb.Retries())
+	}
+}
@@ -23,6 +23,7 @@ package bfe_server
 import (
 	"crypto/tls"
 	"errors"
+	"fmt"
 	"io"
 	"math/rand"
 	"net"
@@ -45,6 +46,7 @@ import (
 	"github.com/bfenetworks/bfe/bfe_fcgi"
 	"github.com/bfenetworks/bfe/bfe_http"
 	"github.com/bfenetworks/bfe/bfe_http2"
+	"github.com/bfenetworks/bfe/bfe_http3"
 	"github.com/bfenetworks/bfe/bfe_module"
 	"github.com/bfenetworks/bfe/bfe_modules/mod_ai_token_auth"
 	"github.com/bfenetworks/bfe/bfe_modules/mod_body_process"
@@ -82,6 +84,8 @@ type ReverseProxy struct {
 	transports RoundTripperMap
 	bufferPool *bfe_util.FixedPool
 
+	breakers *CircuitBreakerTable // circuit breakers of clusters
+
 	server     *BfeServer  // link to bfe server
 	proxyState *ProxyState // state of proxy
 }
@@ -90,6 +94,7 @@ type ReverseProxy struct {
 func NewReverseProxy(server *BfeServer, state *ProxyState) *ReverseProxy {
 	rp := new(ReverseProxy)
 	rp.transports = make(RoundTripperMap)
+	rp.breakers = NewCircuitBreakerTable()
 	rp.server = server
 	rp.proxyState = state
 	rp.bufferPool = bfe_util.NewFixedPool(32 * 1024)
@@ -190,6 +195,27 @@ func compareHttpsConf(src, dst *cluster_conf.BackendHTTPS) bool {
 	return true
 }
 
+// httpTransportChanged checks whether conf of http transport is changed.
+func httpTransportChanged(t *bfe_http.Transport, conf *bfe_cluster.BfeCluster) bool {
+	backendConf := conf.BackendConf()
+
+	return !compareHttpsConf(t.HttpsConf, conf.BackendHTTPSConf()) ||
+		(t.MaxIdleConnsPerHost != *backendConf.MaxIdleConnsPerHost) ||
+		(t.MaxConnsPerHost != *backendConf.MaxConnsPerHost) ||
+		(t.ResponseHeaderTimeout != time.Millisecond*time.Duration(*backendConf.TimeoutResponseHeader)) ||
+		(t.ReqWriteBufferSize != conf.ReqWriteBufferSize()) ||
+		(t.ReqFlushInterval != conf.ReqFlushInterval())
+}
+
+// compareH2Conf checks whether connection pool of h2 transport matches conf.
+func compareH2Conf(t *bfe_http2.TLSTransport, conf *cluster_conf.H2Conf) bool {
+	if conf == nil {
+		return t.ConnsPerHost == cluster_conf.DefaultH2ConnsPerHost &&
+			t.MaxConcurrentStreams == cluster_conf.DefaultH2MaxConcurrentStreams
+	}
+	return t.ConnsPerHost == conf.ConnsPerHost && t.MaxConcurrentStreams == conf.MaxConcurrentStreams
+}
+
 func (p *ReverseProxy) setTransports(clusterMap bfe_route.ClusterMap) {
 	p.tsMu.Lock()
 	defer p.tsMu.Unlock()
@@ -213,13 +239,7 @@ func (p *ReverseProxy) setTransports(clusterMap bfe_route.ClusterMap) {
 				proto = "https"
 			}
 
-			if (proto != *backendConf.Protocol) ||
-				!compareHttpsConf(t.HttpsConf, conf.BackendHTTPSConf()) ||
-				(t.MaxIdleConnsPerHost != *backendConf.MaxIdleConnsPerHost) ||
-				(t.MaxConnsPerHost != *backendConf.MaxConnsPerHost) ||
-				(t.ResponseHeaderTimeout != time.Millisecond*time.Duration(*backendConf.TimeoutResponseHeader)) ||
-				(t.ReqWriteBufferSize != conf.ReqWriteBufferSize()) ||
-				(t.ReqFlushInterval != conf.ReqFlushInterval()) {
+			if (proto != *backendConf.Protocol) || httpTransportChanged(t, conf) {
 				// create new transport with newConf instead of update transport
 				// update transport needs lock
 				transport = createTransport(conf)
@@ -227,12 +247,34 @@ func (p *ReverseProxy) setTransports(clusterMap bfe_route.ClusterMap) {
 				continue
 			}
 			newTransports[cluster] = transport
+		case *bfe_http2.TLSTransport:
+			// keep connection pool of h2 backends if conf not changed
+			backendConf := conf.BackendConf()
+			fallback, ok := t.Fallback.(*bfe_http.Transport)
+
+			if *backendConf.Protocol != "h2" || !ok || httpTransportChanged(fallback, conf) ||
+				!compareH2Conf(t, backendConf.H2Conf) ||
+				t.TLSHandshakeTimeout != time.Duration(conf.TimeoutConnSrv())*time.Millisecond {
+				transport = createTransport(conf)
+				newTransports[cluster] = transport
+				continue
+			}
+			newTransports[cluster] = transport
 		default:
 			transport = createTransport(conf)
 			newTransports[cluster] = transport
 		}
 	}
 
+	// close idle connections of h2 transports replaced or removed.
+	// Note: active connections are closed by IdleConnTimeout of transport
+	// after streams finish
+	for cluster, transport := range p.transports {
+		if t, ok := transport.(*bfe_http2.TLSTransport); ok && newTransports[cluster] != transport {
+			t.CloseIdleConnections()
+		}
+	}
+
 	p.transports = newTransports
 }
 
@@ -259,7 +301,7 @@ func createTransport(cluster *bfe_cluster.BfeCluster) bfe_http.RoundTripper {
 	log.Logger.Debug("create a new transport for %s, timeout %d", cluster.Name, *backendConf.TimeoutResponseHeader)
 
 	switch protocol {
-	case "http", "https":
+	case "http", "https", "h2":
 		// cluster has its own Connect Server Timeout.
 		// so each cluster has a different transport
 		// once cluster's timeout updated, dailer use new value
@@ -278,9 +320,16 @@ func createTransport(cluster *bfe_cluster.BfeCluster) bfe_http.RoundTripper {
 			DisableCompression:    true,
 			MaxConnsPerHost:       *backendConf.MaxConnsPerHost,
 		}
-		if protocol == "https" {
+		if protocol == "https" || protocol == "h2" {
 			transport.SetHttpsConf(cluster.BackendHTTPSConf())
 		}
+		if protocol == "h2" {
+			// fall back to HTTP/1.1 if backend does not negotiate h2
+			t := bfe_http2.NewTLSTransport(dailer, cluster.BackendHTTPSConf(), backendConf.H2Conf, transport)
+			t.TLSHandshakeTimeout = time.Duration(cluster.TimeoutConnSrv()) * time.Millisecond
+			t.ResponseHeaderTimeout = transport.ResponseHeaderTimeout
+			return t
+		}
 		return transport
 	case "fcgi":
 		return &bfe_fcgi.Transport{
@@ -332,6 +381,31 @@ func (p *ReverseProxy) clusterInvoke(srv *BfeServer, cluster *bfe_cluster.BfeClu
 		return
 	}
 
+	// fail fast if circuit breaker of cluster is open
+	breaker := p.breakers.Get(cluster.Name)
+	if !breaker.AcquireRequest(cluster, p.proxyState) {
+		log.Logger.Info("circuit breaker of cluster [%s] is open", cluster.Name)
+		request.Stat.ResponseStart = time.Now()
+		request.ErrCode = bfe_basic.ErrBkCircuitOpen
+		request.ErrMsg = fmt.Sprintf("cluster[%s] reach max requests", cluster.Name)
+		p.proxyState.ErrBkCircuitOpen.Inc(1)
+		err = bfe_basic.ErrBkCircuitOpen
+		return
+	}
+	retrying := false // retry slot of circuit breaker is held?
+	defer func() {
+		if retrying {
+			breaker.ReleaseRetry()
+		}
+		breaker.ReleasePending()
+		// request slot is released when response body is closed
+		if err == nil && res != nil && res.Body != nil {
+			res.Body = &breakerBody{ReadCloser: res.Body, breaker: breaker}
+		} else {
+			breaker.ReleaseRequest()
+		}
+	}()
+
 	// When request.RetryTime exceeds some value, srv.clusterTable.Lookup()
 	// will return error. Here set a limit of 20, to avoid endless loop
 	for i := 0; i < 20; i++ {
@@ -401,10 +475,26 @@ func (p *ReverseProxy) clusterInvoke(srv *BfeServer, cluster *bfe_cluster.BfeClu
 
 		transport := request.Trans.Transport
 
-		res, err = transport.RoundTrip(outreq)
+		if delay, ok := hedgeDelay(cluster, bal, breaker, request); ok {
+			res, backend, err = p.hedgeRoundTrip(cluster, bal, breaker, request, rw, backend, delay)
+		} else {
+			res, err = transport.RoundTrip(outreq)
+			if err == nil && cluster.HedgePercentile() > 0 {
+				breaker.latency.Add(time.Since(request.Stat.BackendStart))
+			}
+		}
 
+		if retrying {
+			breaker.ReleaseRetry()
+			retrying = false
+		}
 		request.Stat.BackendEnd = time.Now()
 
+		// record latency of backend for latency-aware balance
+		if err == nil || isRespHeaderTimeout(err) {
+			backend.OnResponseTime(request.Stat.BackendEnd.Sub(request.Stat.BackendStart))
+		}
+
 		// record backend info to request, no matter succeed or fail
 		request.Backend.SubclusterName = backend.SubCluster
 		request.Backend.BackendName = backend.Name
@@ -417,6 +507,7 @@ func (p *ReverseProxy) clusterInvoke(srv *BfeServer, cluster *bfe_cluster.BfeClu
 			} else {
 				backend.OnSuccess()
 			}
+			bal.OnOutlierResult(backend, outlierResultByStatus(res.StatusCode))
 
 			// clear err msg in req.
 			// this step is required, if finally succeed after retry
@@ -453,6 +544,7 @@ func (p *ReverseProxy) clusterInvoke(srv *BfeServer, cluster *bfe_cluster.BfeClu
 			p.proxyState.ErrBkConnectBackend.Inc(1)
 			allowRetry = true
 			backend.OnFailByCluster(cluster)
+			bal.OnOutlierResult(backend, bfe_cluster_backend.OutlierGatewayFailure)
 
 		case bfe_http.WriteRequestError, bfe_fcgi.WriteRequestError:
 			var be *mod_body_process.BPError
@@ -475,6 +567,7 @@ func (p *ReverseProxy) clusterInvoke(srv *BfeServer, cluster *bfe_cluster.BfeClu
 			rerr := err.(bfe_http.WriteRequestError)
 			if !rerr.CheckTargetError(request.RemoteAddr) {
 				backend.OnFailByCluster(cluster)
+				bal.OnOutlierResult(backend, bfe_cluster_backend.OutlierGatewayFailure)
 			}
 
 		case bfe_http.ReadRespHeaderError, bfe_fcgi.ReadRespHeaderError:
@@ -483,6 +576,7 @@ func (p *ReverseProxy) clusterInvoke(srv *BfeServer, cluster *bfe_cluster.BfeClu
 			p.proxyState.ErrBkReadRespHeader.Inc(1)
 			allowRetry = checkAllowRetry(cluster.RetryLevel(), outreq)
 			backend.OnFailByCluster(cluster)
+			bal.OnOutlierResult(backend, bfe_cluster_backend.OutlierGatewayFailure)
 
 		case bfe_http.RespHeaderTimeoutError:
 			request.ErrCode = bfe_basic.ErrBkRespHeaderTimeout
@@ -490,6 +584,7 @@ func (p *ReverseProxy) clusterInvoke(srv *BfeServer, cluster *bfe_cluster.BfeClu
 			p.proxyState.ErrBkRespHeaderTimeout.Inc(1)
 			allowRetry = checkAllowRetry(cluster.RetryLevel(), outreq)
 			backend.OnFailByCluster(cluster)
+			bal.OnOutlierResult(backend, bfe_cluster_backend.OutlierGatewayFailure)
 
 		case bfe_http.TransportBrokenError:
 			request.ErrCode = bfe_basic.ErrBkTransportBroken
@@ -498,8 +593,15 @@ func (p *ReverseProxy) clusterInvoke(srv *BfeServer, cluster *bfe_cluster.BfeClu
 			allowRetry = checkAllowRetry(cluster.RetryLevel(), outreq)
 
 		default:
-			// never go here
+			// e.g. stream error of http2 transport
 			log.Logger.Info("roundtrip %s %s", reflect.TypeOf(err), err)
+			request.ErrCode = bfe_basic.ErrBkRequestBackend
+			request.ErrMsg = err.Error()
+		}
+
+		// no retry after deadline of gRPC client passes
+		if allowRetry && grpcDeadlineExceeded(request) {
+			allowRetry = false
 		}
 
 		if !allowRetry {
@@ -508,6 +610,16 @@ func (p *ReverseProxy) clusterInvoke(srv *BfeServer, cluster *bfe_cluster.BfeClu
 			break
 		}
 
+		// limit retries to avoid amplifying load on backends.
+		// Note: error of the last try is kept if retry is denied
+		if !breaker.AcquireRetry(cluster, p.proxyState) {
+			log.Logger.Debug("cluster [%s] retry denied by circuit breaker", cluster.Name)
+			request.Stat.IsRetryDenied = true
+			p.proxyState.ClientReqRetryDenied.Inc(1)
+			break
+		}
+		retrying = true
+
 		request.RetryTime += 1
 	}
 
@@ -530,6 +642,10 @@ func (p *ReverseProxy) sendResponse(rw bfe_http.ResponseWriter, res *bfe_http.Re
 	// prepare SignCalculator for response
 	p.prepareSigner(rw, res)
 
+	// Alt-Svc from backend takes precedence over the one of bfe
+	if _, ok := res.Header["Alt-Svc"]; ok {
+		rw.Header().Del("Alt-Svc")
+	}
 	bfe_http.CopyHeader(rw.Header(), res.Header)
 
 	// note: writeheader don't guarantee send header
@@ -621,6 +737,16 @@ func (p *ReverseProxy) setTimeout(stage bfe_basic.OperationStage,
 		} else {
 			//skip timeout setingg
 		}
+	case *bfe_http3.RequestBody: // http3
+		// Note: timeout of idle connection is controlled by quic
+		if d >= 0 {
+			if stage == bfe_basic.StageReadReqBody {
+				bfe_http3.SetReadStreamTimeout(b, d)
+			}
+			if stage == bfe_basic.StageWriteClient {
+				bfe_http3.SetWriteStreamTimeout(b, d)
+			}
+		}
 	case *bfe_spdy.RequestBody: // spdy
 		if stage == bfe_basic.StageReadReqBody {
 			bfe_spdy.SetReadStreamTimeout(b, d)
@@ -689,6 +815,9 @@ func (p *ReverseProxy) ServeHTTP(rw bfe_http.ResponseWriter, basicReq *bfe_basic
 	// set clientip of original user for request
 	setClientAddr(basicReq)
 
+	// set service and deadline for gRPC request
+	setGrpcInfo(basicReq)
+
 	// Callback for HandleBeforeLocation
 	hl = srv.CallBacks.GetHandlerList(bfe_module.HandleBeforeLocation)
 	if hl != nil {
@@ -724,7 +853,7 @@ func (p *ReverseProxy) ServeHTTP(rw bfe_http.ResponseWriter, basicReq *bfe_basic
 			basicReq.HttpRequest.Host, basicReq.Session.Vip, basicReq.ClientAddr)
 
 		// close connection
-		res = bfe_basic.CreateInternalSrvErrResp(basicReq)
+		res = createErrResp(basicReq)
 		action = closeAfterReply
 		goto response_got
 	}
@@ -763,7 +892,7 @@ func (p *ReverseProxy) ServeHTTP(rw bfe_http.ResponseWriter, basicReq *bfe_basic
 		log.Logger.Info("FindLocation error[%s] host[%s]", err, basicReq.HttpRequest.Host)
 
 		// close connection
-		res = bfe_basic.CreateInternalSrvErrResp(basicReq)
+		res = createErrResp(basicReq)
 		action = closeAfterReply
 		goto response_got
 	}
@@ -779,7 +908,7 @@ func (p *ReverseProxy) ServeHTTP(rw bfe_http.ResponseWriter, basicReq *bfe_basic
 		basicReq.ErrMsg = err.Error()
 		p.proxyState.ErrBkNoCluster.Inc(1)
 
-		res = bfe_basic.CreateInternalSrvErrResp(basicReq)
+		res = createErrResp(basicReq)
 		action = closeAfterReply
 		goto response_got
 	}
@@ -848,6 +977,15 @@ func (p *ReverseProxy) ServeHTTP(rw bfe_http.ResponseWriter, basicReq *bfe_basic
 		outreq.Host = ""
 	}
 
+	// propagate remaining time of gRPC deadline to backend
+	if !setGrpcTimeout(basicReq, outreq) {
+		basicReq.Stat.ResponseStart = time.Now()
+		basicReq.ErrCode = bfe_basic.ErrClientTimeout
+		basicReq.ErrMsg = "grpc deadline exceeded before forwarding"
+		res = createErrResp(basicReq)
+		goto response_got
+	}
+
 	/*
 		// do body process before forwarding
 		bf, ok = outreq.Body.(BufferFiller)
@@ -870,6 +1008,9 @@ func (p *ReverseProxy) ServeHTTP(rw bfe_http.ResponseWriter, basicReq *bfe_basic
 			}
 		}
 	*/
+	// mirror request to shadow cluster if required
+	p.mirrorRequest(basicReq, serverConf)
+
 	// invoke cluster to get response
 	res, action, err = p.clusterInvoke(srv, cluster, basicReq, rw)
 	basicReq.HttpResponse = res
@@ -888,7 +1029,7 @@ func (p *ReverseProxy) ServeHTTP(rw bfe_http.ResponseWriter, basicReq *bfe_basic
 
 		basicReq.Stat.ResponseStart = time.Now()
 		basicReq.BfeStatusCode = bfe_http.StatusInternalServerError
-		res = bfe_basic.CreateInternalSrvErrResp(basicReq)
+		res = createErrResp(basicReq)
 		goto response_got
 	}
 	if resFlushInterval == 0 && basicReq.HttpRequest.Header.Get("Accept") == "text/event-stream" {
@@ -975,6 +1116,7 @@ send_response:
 
 			p.proxyState.ErrClientWrite.Inc(1)
 		}
+		recordGrpcStatus(basicReq, res)
 	}
 	return
 }
@@ -1026,6 +1168,25 @@ func (p *ReverseProxy) copyResponse(dst io.Writer, src io.ReadCloser,
 	return err
 }
 
+// outlierResultByStatus returns result for outlier detection by status code.
+func outlierResultByStatus(statusCode int) int {
+	switch {
+	case statusCode == bfe_http.StatusBadGateway || statusCode == bfe_http.StatusServiceUnavailable ||
+		statusCode == bfe_http.StatusGatewayTimeout:
+		return bfe_cluster_backend.OutlierGatewayFailure
+	case statusCode >= 500:
+		return bfe_cluster_backend.OutlierError5xx
+	default:
+		return bfe_cluster_backend.OutlierSuccess
+	}
+}
+
+// isRespHeaderTimeout checks whether backend fails to response in time.
+func isRespHeaderTimeout(err error) bool {
+	_, ok := err.(bfe_http.RespHeaderTimeoutError)
+	return ok
+}
+
 func checkAllowRetry(retryLevel int, outreq *bfe_http.Request) bool {
 	if retryLevel == cluster_conf.RetryGet {
 		// if forward GET request error (eg. backend restart)
@@ -23,6 +23,7 @@ import (
 	bal "github.com/bfenetworks/bfe/bfe_balance/bal_gslb"
 	"github.com/bfenetworks/bfe/bfe_http"
 	"github.com/bfenetworks/bfe/bfe_http2"
+	"github.com/bfenetworks/bfe/bfe_http3"
 	"github.com/bfenetworks/bfe/bfe_module"
 	"github.com/bfenetworks/bfe/bfe_proxy"
 	"github.com/bfenetworks/bfe/bfe_spdy"
@@ -67,6 +68,10 @@ type ServerStatus struct {
 	Http2State   *bfe_http2.Http2State
 	Http2Metrics metrics.Metrics
 
+	// for http3 protocol
+	Http3State   *bfe_http3.Http3State
+	Http3Metrics metrics.Metrics
+
 	// for http protocol
 	HttpState   *bfe_http.HttpState
 	HttpMetrics metrics.Metrics
@@ -107,6 +112,7 @@ func NewServerStatus() *ServerStatus {
 	m.TlsState = bfe_tls.GetTlsState()
 	m.SpdyState = bfe_spdy.GetSpdyState()
 	m.Http2State = bfe_http2.GetHttp2State()
+	m.Http3State = bfe_http3.GetHttp3State()
 	m.HttpState = bfe_http.GetHttpState()
 	m.StreamState = bfe_stream.GetStreamState()
 	m.WebSocketState = bfe_websocket.GetWebSocketState()
@@ -118,6 +124,7 @@ func NewServerStatus() *ServerStatus {
 	m.TlsMetrics.Init(m.TlsState, KP_PROXY_STATE, 0)
 	m.SpdyMetrics.Init(m.SpdyState, KP_PROXY_STATE, 0)
 	m.Http2Metrics.Init(m.Http2State, KP_PROXY_STATE, 0)
+	m.Http3Metrics.Init(m.Http3State, KP_PROXY_STATE, 0)
 	m.HttpMetrics.Init(m.HttpState, KP_PROXY_STATE, 0)
 	m.StreamMetrics.Init(m.StreamState, KP_PROXY_STATE, 0)
 	m.WebSocketMetrics.Init(m.WebSocketState, KP_PROXY_STATE, 0)
@@ -186,6 +193,16 @@ func (srv *BfeServer) http2StateGetDiff(params map[string][]string) ([]byte, err
 	return s.Format(params)
 }
 
+func (srv *BfeServer) http3StateGetAll(params map[string][]string) ([]byte, error) {
+	s := srv.serverStatus.Http3Metrics.GetAll()
+	return s.Format(params)
+}
+
+func (srv *BfeServer) http3StateGetDiff(params map[string][]string) ([]byte, error) {
+	s := srv.serverStatus.Http3Metrics.GetDiff()
+	return s.Format(params)
+}
+
 func (srv *BfeServer) httpStateGetAll(params map[string][]string) ([]byte, error) {
 	s := srv.serverStatus.HttpMetrics.GetAll()
 	return s.Format(params)
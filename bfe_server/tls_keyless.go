@@ -0,0 +1,72 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// private keys of server certificates on key server (keyless tls)
+//
+// For certificates with ServerKeyRef in server cert conf, signing and RSA
+// decryption in tls handshake are delegated to key server, so that private
+// keys are not needed on bfe.
+
+package bfe_server
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_config/bfe_conf"
+	"github.com/bfenetworks/bfe/bfe_util/keyless"
+)
+
+// initKeylessClient initializes client of key server.
+func (srv *BfeServer) initKeylessClient(conf bfe_conf.ConfigKeylessTls) error {
+	tlsConfig, err := newClientTLSConfig(conf.CACertFile)
+	if err != nil {
+		return fmt.Errorf("initKeylessClient(): %s", err)
+	}
+	cert, err := tls.LoadX509KeyPair(conf.ClientCertFile, conf.ClientKeyFile)
+	if err != nil {
+		return fmt.Errorf("initKeylessClient(): %s", err)
+	}
+	tlsConfig.Certificates = []tls.Certificate{cert}
+	tlsConfig.ServerName = conf.ServerName
+	if len(tlsConfig.ServerName) == 0 {
+		if tlsConfig.ServerName, _, err = net.SplitHostPort(conf.Address); err != nil {
+			return fmt.Errorf("initKeylessClient(): %s", err)
+		}
+	}
+
+	srv.keylessClient = keyless.NewClient(keyless.Config{
+		Address:   conf.Address,
+		TLSConfig: tlsConfig,
+		Timeout:   time.Duration(conf.Timeout) * time.Millisecond,
+		PoolSize:  conf.PoolSize,
+
+		FallbackPolicy: conf.FallbackPolicy,
+	})
+	return nil
+}
+
+// KeylessStateGet returns state of key server client.
+func (srv *BfeServer) KeylessStateGet(query url.Values) ([]byte, error) {
+	if srv.keylessClient == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(srv.keylessClient.GetState())
+}
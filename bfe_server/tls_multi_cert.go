@@ -17,11 +17,18 @@
 package bfe_server
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"net"
 	"strings"
 	"sync"
 )
 
+import (
+	"golang.org/x/crypto/ocsp"
+)
+
 import (
 	"github.com/bfenetworks/bfe/bfe_config/bfe_tls_conf/server_cert_conf"
 	"github.com/bfenetworks/bfe/bfe_config/bfe_tls_conf/tls_rule_conf"
@@ -32,20 +39,44 @@ type MultiCertMap struct {
 	vipCertMap  map[string]*bfe_tls.Certificate // vip -> certificate
 	nameCertMap *NameCertMap                    // name -> certificate
 	defaultCert *bfe_tls.Certificate            // default cert
+	ocspStaples map[string]*ocspStaple          // cert digest -> fetched ocsp staple
 	lock        sync.RWMutex
 	state       *ProxyState // state for MultiCertMap
 }
 
+// ocspStaple is ocsp response fetched for certificate. Staple is nil if
+// certificate should not be stapled (eg. certificate revoked).
+type ocspStaple struct {
+	staple []byte
+	parse  *ocsp.Response
+}
+
 func NewMultiCertMap(state *ProxyState) *MultiCertMap {
 	m := new(MultiCertMap)
 	m.vipCertMap = make(map[string]*bfe_tls.Certificate)
 	m.nameCertMap = NewNameCertMap()
+	m.ocspStaples = make(map[string]*ocspStaple)
 	m.state = state
 	return m
 }
 
+// certDigest returns digest of leaf certificate.
+func certDigest(cert *bfe_tls.Certificate) string {
+	if cert == nil || len(cert.Certificate) == 0 {
+		return ""
+	}
+	digest := sha256.Sum256(cert.Certificate[0])
+	return hex.EncodeToString(digest[:])
+}
+
 // Get gets certificate for given connection.
 func (m *MultiCertMap) Get(c *bfe_tls.Conn) *bfe_tls.Certificate {
+	return m.GetByVipSni(c.GetVip(), c.GetServerName())
+}
+
+// GetByVipSni gets certificate for given vip and server name. Vip (or
+// server name) is nil (or empty) if unknown.
+func (m *MultiCertMap) GetByVipSni(vip net.IP, serverName string) *bfe_tls.Certificate {
 	var cert *bfe_tls.Certificate
 	m.state.TlsMultiCertGet.Inc(1)
 
@@ -53,7 +84,6 @@ func (m *MultiCertMap) Get(c *bfe_tls.Conn) *bfe_tls.Certificate {
 	defer m.lock.RUnlock()
 
 	// choose certificate by vip
-	vip := c.GetVip()
 	if vip != nil {
 		key := vip.String()
 		cert = m.vipCertMap[key]
@@ -67,7 +97,6 @@ func (m *MultiCertMap) Get(c *bfe_tls.Conn) *bfe_tls.Certificate {
 	// if vip for connection is not found unexpectedly, or vip for connection is unknown,
 	// try to choose cert by SNI (Server Name Indication)
 	if cert == nil {
-		serverName := c.GetServerName()
 		if len(serverName) > 0 {
 			cert = m.nameCertMap.Get(serverName)
 		} else {
@@ -122,6 +151,7 @@ func (m *MultiCertMap) Update(certConf map[string]*bfe_tls.Certificate,
 	}
 
 	m.lock.Lock()
+	m.applyOcspStaples(certConf)
 	m.vipCertMap = vipCertMap
 	m.nameCertMap = nameCertMap
 	m.defaultCert = defaultCert
@@ -130,6 +160,73 @@ func (m *MultiCertMap) Update(certConf map[string]*bfe_tls.Certificate,
 	return nil
 }
 
+// applyOcspStaples applies fetched ocsp staples to new certificates, unless
+// staple loaded from OcspResponseFile is newer. Staples of certificates which
+// no longer exist are removed.
+// Note: certificates are not in use yet, so they are updated in place.
+func (m *MultiCertMap) applyOcspStaples(certConf map[string]*bfe_tls.Certificate) {
+	ocspStaples := make(map[string]*ocspStaple)
+	for _, cert := range certConf {
+		key := certDigest(cert)
+		s, ok := m.ocspStaples[key]
+		if !ok {
+			continue
+		}
+		ocspStaples[key] = s
+
+		if cert.OCSPParse != nil && cert.OCSPParse.ThisUpdate.After(s.parse.ThisUpdate) {
+			continue
+		}
+		cert.OCSPStaple = s.staple
+		cert.OCSPParse = s.parse
+		if s.staple == nil {
+			cert.OCSPParse = nil
+		}
+	}
+	m.ocspStaples = ocspStaples
+}
+
+// UpdateOcspStaple updates ocsp staple of certificate with given digest. Nil
+// staple means certificate should not be stapled.
+// Note: certificates may be in use by handshakes, so they are replaced by
+// copies with new staple instead of updated in place.
+func (m *MultiCertMap) UpdateOcspStaple(key string, staple []byte, parse *ocsp.Response) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.ocspStaples[key] = &ocspStaple{staple: staple, parse: parse}
+
+	replaced := make(map[*bfe_tls.Certificate]*bfe_tls.Certificate)
+	replace := func(cert *bfe_tls.Certificate) *bfe_tls.Certificate {
+		if c, ok := replaced[cert]; ok {
+			return c
+		}
+		if certDigest(cert) != key {
+			replaced[cert] = cert
+			return cert
+		}
+		c := *cert
+		c.OCSPStaple = staple
+		c.OCSPParse = parse
+		if staple == nil {
+			c.OCSPParse = nil
+		}
+		replaced[cert] = &c
+		return &c
+	}
+
+	for vip, cert := range m.vipCertMap {
+		m.vipCertMap[vip] = replace(cert)
+	}
+	for name, cert := range m.nameCertMap.normalCertMap {
+		m.nameCertMap.normalCertMap[name] = replace(cert)
+	}
+	for name, cert := range m.nameCertMap.wildcardCertMap {
+		m.nameCertMap.wildcardCertMap[name] = replace(cert)
+	}
+	m.defaultCert = replace(m.defaultCert)
+}
+
 type NameCertMap struct {
 	normalCertMap   map[string]*bfe_tls.Certificate // cert map for normal name
 	wildcardCertMap map[string]*bfe_tls.Certificate // cert map for wildcard name
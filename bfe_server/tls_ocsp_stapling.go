@@ -0,0 +1,454 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// ocsp stapling for server certificates
+//
+// Ocsp responses of certificates in server cert conf are fetched from
+// responders in certificates, validated with issuers in certificate chains,
+// and refreshed halfway between this update and next update. If responder
+// is unavailable, the last valid response is kept serving. Responses of
+// different certificates are fetched in parallel, by at most
+// maxOcspFetchWorkers workers.
+
+package bfe_server
+
+import (
+	"bytes"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+import (
+	"github.com/bfenetworks/go-lib/log"
+	"golang.org/x/crypto/ocsp"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_config/bfe_conf"
+	"github.com/bfenetworks/bfe/bfe_config/bfe_tls_conf/server_cert_conf"
+	"github.com/bfenetworks/bfe/bfe_tls"
+	"github.com/bfenetworks/bfe/bfe_util/json"
+)
+
+// max size of ocsp response
+const maxOcspResponseSize = 1024 * 1024
+
+// max number of ocsp responses fetched in parallel
+const maxOcspFetchWorkers = 8
+
+const (
+	OcspStatusNone    = "none"
+	OcspStatusGood    = "good"
+	OcspStatusRevoked = "revoked"
+	OcspStatusUnknown = "unknown"
+)
+
+type ocspEntry struct {
+	name      string // name of certificate in server cert conf
+	leaf      *x509.Certificate
+	issuer    *x509.Certificate
+	responder string // url of ocsp responder
+
+	staple []byte         // staple in use
+	parse  *ocsp.Response // parsed staple in use, or revoked response
+
+	// newer staple, which is in use after it is in time range
+	pending      []byte
+	pendingParse *ocsp.Response
+
+	nextFetch   time.Time
+	lastFetch   time.Time
+	lastSuccess time.Time
+	lastErr     string
+}
+
+// newOcspEntry creates entry for certificate. It returns nil if there is no
+// ocsp responder in certificate.
+func newOcspEntry(name string, cert *bfe_tls.Certificate, now time.Time) (*ocspEntry, error) {
+	leaf := cert.Leaf
+	if leaf == nil {
+		var err error
+		if leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return nil, err
+		}
+	}
+	if len(leaf.OCSPServer) == 0 {
+		return nil, nil
+	}
+	if len(cert.Certificate) < 2 {
+		return nil, fmt.Errorf("no issuer certificate in chain")
+	}
+	issuer, err := x509.ParseCertificate(cert.Certificate[1])
+	if err != nil {
+		return nil, err
+	}
+
+	e := &ocspEntry{
+		name:      name,
+		leaf:      leaf,
+		issuer:    issuer,
+		responder: leaf.OCSPServer[0],
+		nextFetch: now,
+	}
+
+	// staple loaded from OcspResponseFile
+	if cert.OCSPParse != nil {
+		e.staple = cert.OCSPStaple
+		e.parse = cert.OCSPParse
+	}
+	return e, nil
+}
+
+type ocspFetcher struct {
+	conf   bfe_conf.ConfigOcspStapling
+	client *http.Client
+	state  *ProxyState
+	apply  func(key string, staple []byte, parse *ocsp.Response) // called after staple updated
+
+	lock    sync.Mutex
+	entries map[string]*ocspEntry // cert digest -> entry
+	wakeup  chan struct{}
+}
+
+func newOcspFetcher(conf bfe_conf.ConfigOcspStapling, state *ProxyState,
+	apply func(key string, staple []byte, parse *ocsp.Response)) *ocspFetcher {
+	return &ocspFetcher{
+		conf:    conf,
+		client:  &http.Client{Timeout: time.Duration(conf.FetchTimeout) * time.Second},
+		state:   state,
+		apply:   apply,
+		entries: make(map[string]*ocspEntry),
+		wakeup:  make(chan struct{}, 1),
+	}
+}
+
+// SetCerts sets certificates to fetch ocsp responses for. State of existing
+// certificates is kept.
+func (f *ocspFetcher) SetCerts(certMap map[string]*bfe_tls.Certificate, now time.Time) {
+	f.lock.Lock()
+	entries := make(map[string]*ocspEntry)
+	for name, cert := range certMap {
+		if name == server_cert_conf.DefaultCert {
+			continue
+		}
+		key := certDigest(cert)
+		if _, ok := entries[key]; ok {
+			continue
+		}
+		if e, ok := f.entries[key]; ok {
+			e.name = name
+			entries[key] = e
+			continue
+		}
+
+		e, err := newOcspEntry(name, cert, now)
+		if err != nil {
+			log.Logger.Warn("ocsp stapling: ignore certificate %s: %v", name, err)
+			continue
+		}
+		if e == nil {
+			log.Logger.Debug("ocsp stapling: no ocsp responder in certificate %s", name)
+			continue
+		}
+		if e.parse != nil {
+			e.nextFetch = f.refreshTime(e.parse, now)
+		}
+		entries[key] = e
+	}
+	f.entries = entries
+	f.lock.Unlock()
+
+	select {
+	case f.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// refreshTime returns time for refreshing ocsp response.
+func (f *ocspFetcher) refreshTime(parse *ocsp.Response, now time.Time) time.Time {
+	if parse.NextUpdate.IsZero() {
+		return now.Add(time.Duration(f.conf.RefreshInterval) * time.Second)
+	}
+	refresh := parse.ThisUpdate.Add(parse.NextUpdate.Sub(parse.ThisUpdate) / 2)
+	if refresh.Before(now) {
+		return now
+	}
+	return refresh
+}
+
+// fetch fetches ocsp response for entry from responder, and validates it.
+func (f *ocspFetcher) fetch(e *ocspEntry, now time.Time) ([]byte, *ocsp.Response, error) {
+	f.state.TlsOcspFetch.Inc(1)
+
+	reqData, err := ocsp.CreateRequest(e.leaf, e.issuer, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, e.responder, bytes.NewReader(reqData))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/ocsp-request")
+	req.Header.Set("Accept", "application/ocsp-response")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	staple, err := io.ReadAll(io.LimitReader(resp.Body, maxOcspResponseSize))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// check signature and serial number
+	parse, err := ocsp.ParseResponseForCert(staple, e.leaf, e.issuer)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !parse.NextUpdate.IsZero() && !now.Before(parse.NextUpdate) {
+		return nil, nil, fmt.Errorf("ocsp response expired at %s", parse.NextUpdate)
+	}
+	if parse.Status == ocsp.Unknown {
+		return nil, nil, fmt.Errorf("ocsp status is unknown")
+	}
+	return staple, parse, nil
+}
+
+// update updates entry with result of fetch. Last valid staple is kept
+// serving if fetch failed.
+func (f *ocspFetcher) update(key string, e *ocspEntry, staple []byte,
+	parse *ocsp.Response, err error, now time.Time) {
+	e.lastFetch = now
+	if err != nil {
+		f.state.TlsOcspFetchErr.Inc(1)
+		log.Logger.Warn("ocsp stapling: fetch ocsp response for %s from %s: %v",
+			e.name, e.responder, err)
+		e.lastErr = err.Error()
+		e.nextFetch = now.Add(time.Duration(f.conf.RetryInterval) * time.Second)
+		return
+	}
+
+	e.lastErr = ""
+	e.lastSuccess = now
+	e.nextFetch = f.refreshTime(parse, now)
+	e.pending, e.pendingParse = nil, nil
+
+	if parse.Status == ocsp.Revoked {
+		f.state.TlsOcspCertRevoked.Inc(1)
+		log.Logger.Warn("ocsp stapling: certificate %s revoked at %s", e.name, parse.RevokedAt)
+		e.staple, e.parse = nil, parse
+		f.apply(key, nil, parse)
+		return
+	}
+
+	// Note: staple is rejected by handshake within OcspTimeTolerance since
+	// this update, so current staple is kept serving until then
+	if e.staple != nil && bfe_tls.OcspTimeRangeCheck(e.parse) && !bfe_tls.OcspTimeRangeCheck(parse) {
+		e.pending, e.pendingParse = staple, parse
+		return
+	}
+	f.setStaple(key, e, staple, parse)
+}
+
+func (f *ocspFetcher) setStaple(key string, e *ocspEntry, staple []byte, parse *ocsp.Response) {
+	f.state.TlsOcspStapleUpdate.Inc(1)
+	e.staple, e.parse = staple, parse
+	f.apply(key, staple, parse)
+	log.Logger.Info("ocsp stapling: update staple for %s, this update %s, next update %s",
+		e.name, parse.ThisUpdate, parse.NextUpdate)
+}
+
+// process fetches ocsp responses which need to be refreshed, and promotes
+// pending staples. It returns time for next process.
+func (f *ocspFetcher) process(now time.Time) time.Time {
+	// collect entries to fetch
+	f.lock.Lock()
+	dues := make(map[string]*ocspEntry)
+	for key, e := range f.entries {
+		if !now.Before(e.nextFetch) {
+			dues[key] = e
+		}
+	}
+	f.lock.Unlock()
+
+	var wg sync.WaitGroup
+	workers := make(chan struct{}, maxOcspFetchWorkers)
+	for key, e := range dues {
+		wg.Add(1)
+		workers <- struct{}{}
+		go func(key string, e *ocspEntry) {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+			staple, parse, err := f.fetch(e, now)
+
+			f.lock.Lock()
+			if f.entries[key] == e {
+				f.update(key, e, staple, parse, err, now)
+			}
+			f.lock.Unlock()
+		}(key, e)
+	}
+	wg.Wait()
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	next := now.Add(time.Duration(f.conf.RefreshInterval) * time.Second)
+	for key, e := range f.entries {
+		if e.pending != nil {
+			if bfe_tls.OcspTimeRangeCheck(e.pendingParse) || !bfe_tls.OcspTimeRangeCheck(e.parse) {
+				f.setStaple(key, e, e.pending, e.pendingParse)
+				e.pending, e.pendingParse = nil, nil
+			} else if t := e.pendingParse.ThisUpdate.Add(bfe_tls.OcspTimeTolerance); t.Before(next) {
+				next = t
+			}
+		}
+		if e.nextFetch.Before(next) {
+			next = e.nextFetch
+		}
+	}
+	f.updateGauges(now)
+	return next
+}
+
+// updateGauges updates gauges of ocsp status and staple age. Caller should
+// hold f.lock.
+func (f *ocspFetcher) updateGauges(now time.Time) {
+	var good, revoked, unknown, none, maxAge int64
+	for _, e := range f.entries {
+		if e.parse == nil {
+			none++
+			continue
+		}
+		switch e.parse.Status {
+		case ocsp.Good:
+			good++
+		case ocsp.Revoked:
+			revoked++
+		default:
+			unknown++
+		}
+		if e.staple != nil {
+			if age := int64(now.Sub(e.parse.ThisUpdate) / time.Second); age > maxAge {
+				maxAge = age
+			}
+		}
+	}
+
+	f.state.TlsOcspStatusGood.Set(good)
+	f.state.TlsOcspStatusRevoked.Set(revoked)
+	f.state.TlsOcspStatusUnknown.Set(unknown)
+	f.state.TlsOcspStatusNone.Set(none)
+	f.state.TlsOcspStapleMaxAge.Set(maxAge)
+}
+
+// Start starts to fetch ocsp responses periodically.
+func (f *ocspFetcher) Start() {
+	go func() {
+		for {
+			next := f.process(time.Now())
+
+			timer := time.NewTimer(time.Until(next))
+			select {
+			case <-timer.C:
+			case <-f.wakeup:
+				timer.Stop()
+			}
+		}
+	}()
+}
+
+// OcspStapleState is state of ocsp staple for certificate.
+type OcspStapleState struct {
+	Name        string    // name of certificate in server cert conf
+	Responder   string    // url of ocsp responder
+	Status      string    // cert status in staple or revoked response (none if not fetched)
+	Stapled     bool      // staple in time range and served or not
+	ThisUpdate  time.Time // this update of staple
+	NextUpdate  time.Time // next update of staple
+	Age         int64     // seconds since this update of staple
+	LastFetch   time.Time // time of last fetch
+	LastSuccess time.Time // time of last successful fetch
+	LastError   string    // error of last fetch
+	NextFetch   time.Time // time of next fetch
+}
+
+// GetState returns state of ocsp staples, sorted by certificate name.
+func (f *ocspFetcher) GetState(now time.Time) []OcspStapleState {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	states := make([]OcspStapleState, 0, len(f.entries))
+	for _, e := range f.entries {
+		s := OcspStapleState{
+			Name:        e.name,
+			Responder:   e.responder,
+			Status:      OcspStatusNone,
+			LastFetch:   e.lastFetch,
+			LastSuccess: e.lastSuccess,
+			LastError:   e.lastErr,
+			NextFetch:   e.nextFetch,
+		}
+		if e.parse != nil {
+			s.Status = ocspStatusString(e.parse.Status)
+			s.Stapled = e.staple != nil && bfe_tls.OcspTimeRangeCheck(e.parse)
+			s.ThisUpdate = e.parse.ThisUpdate
+			s.NextUpdate = e.parse.NextUpdate
+			s.Age = int64(now.Sub(e.parse.ThisUpdate) / time.Second)
+		}
+		states = append(states, s)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Name < states[j].Name
+	})
+	return states
+}
+
+func ocspStatusString(status int) string {
+	switch status {
+	case ocsp.Good:
+		return OcspStatusGood
+	case ocsp.Revoked:
+		return OcspStatusRevoked
+	default:
+		return OcspStatusUnknown
+	}
+}
+
+// StartOcspFetcher starts fetching ocsp responses if enabled.
+func (srv *BfeServer) StartOcspFetcher() {
+	if srv.ocspFetcher != nil {
+		srv.ocspFetcher.Start()
+	}
+}
+
+// OcspStaplesGet returns state of ocsp staples.
+func (srv *BfeServer) OcspStaplesGet(query url.Values) ([]byte, error) {
+	if srv.ocspFetcher == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(srv.ocspFetcher.GetState(time.Now()))
+}
@@ -0,0 +1,390 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bfe_server
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+import (
+	"golang.org/x/crypto/ocsp"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_config/bfe_conf"
+	"github.com/bfenetworks/bfe/bfe_config/bfe_tls_conf/server_cert_conf"
+	"github.com/bfenetworks/bfe/bfe_tls"
+)
+
+// testOcspResponder is ocsp responder for testing.
+type testOcspResponder struct {
+	issuer    *x509.Certificate
+	issuerKey crypto.Signer
+
+	lock       sync.Mutex
+	status     int           // cert status in response
+	thisUpdate time.Duration // this update relative to now
+	fail       bool          // respond with error
+	signer     crypto.Signer // key for signing response
+	requests   int
+}
+
+func (r *testOcspResponder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.requests++
+
+	if r.fail {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	data, _ := io.ReadAll(req.Body)
+	ocspReq, err := ocsp.ParseRequest(data)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	template := ocsp.Response{
+		Status:       r.status,
+		SerialNumber: ocspReq.SerialNumber,
+		ThisUpdate:   now.Add(r.thisUpdate),
+		NextUpdate:   now.Add(r.thisUpdate + 8*time.Hour),
+	}
+	if r.status == ocsp.Revoked {
+		template.RevokedAt = now.Add(-time.Hour)
+	}
+	resp, err := ocsp.CreateResponse(r.issuer, r.issuer, template, r.signer)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/ocsp-response")
+	w.Write(resp)
+}
+
+func (r *testOcspResponder) set(f func(r *testOcspResponder)) {
+	r.lock.Lock()
+	f(r)
+	r.lock.Unlock()
+}
+
+func newTestOcspCerts(t *testing.T, responderURL string) (*testOcspResponder, *bfe_tls.Certificate) {
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, caKey.Public(), caKey)
+	if err != nil {
+		t.Fatalf("create ca certificate: %v", err)
+	}
+	ca, _ := x509.ParseCertificate(caDER)
+
+	leafKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	leafTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "example.org"},
+		DNSNames:     []string{"example.org"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		OCSPServer:   []string{responderURL},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTemplate, ca, leafKey.Public(), caKey)
+	if err != nil {
+		t.Fatalf("create leaf certificate: %v", err)
+	}
+
+	responder := &testOcspResponder{
+		issuer:     ca,
+		issuerKey:  caKey,
+		status:     ocsp.Good,
+		thisUpdate: -2 * time.Hour,
+		signer:     caKey,
+	}
+	cert := &bfe_tls.Certificate{
+		Certificate: [][]byte{leafDER, caDER},
+		PrivateKey:  leafKey,
+	}
+	return responder, cert
+}
+
+func newTestOcspFetcher(t *testing.T) (*ocspFetcher, *MultiCertMap, *testOcspResponder, *bfe_tls.Certificate) {
+	var responder *testOcspResponder
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		responder.ServeHTTP(w, req)
+	}))
+	t.Cleanup(ts.Close)
+	responder, cert := newTestOcspCerts(t, ts.URL)
+
+	var conf bfe_conf.ConfigOcspStapling
+	conf.SetDefaultConf()
+	conf.Enabled = true
+
+	state := NewServerStatus().ProxyState
+	m := NewMultiCertMap(state)
+	f := newOcspFetcher(conf, state, m.UpdateOcspStaple)
+	updateTestCerts(t, m, f, cert)
+	return f, m, responder, cert
+}
+
+func updateTestCerts(t *testing.T, m *MultiCertMap, f *ocspFetcher, cert *bfe_tls.Certificate) {
+	certMap := map[string]*bfe_tls.Certificate{
+		"example":                    cert,
+		server_cert_conf.DefaultCert: cert,
+	}
+	if err := m.Update(certMap, nil); err != nil {
+		t.Fatalf("update certificates: %v", err)
+	}
+	f.SetCerts(certMap, time.Now())
+}
+
+func TestOcspFetcherFetch(t *testing.T) {
+	f, m, responder, cert := newTestOcspFetcher(t)
+
+	now := time.Now()
+	f.process(now)
+	if responder.requests != 1 {
+		t.Fatalf("wrong number of requests: %d", responder.requests)
+	}
+
+	// certificates in use are replaced
+	c := m.GetByVipSni(nil, "example.org")
+	if c == cert || len(c.OCSPStaple) == 0 || c.OCSPParse.Status != ocsp.Good {
+		t.Fatalf("staple not updated")
+	}
+	if m.GetDefault() != c {
+		t.Errorf("default certificate should be replaced by the same copy")
+	}
+	if len(cert.OCSPStaple) != 0 {
+		t.Errorf("certificate in use should not be modified")
+	}
+
+	states := f.GetState(now)
+	if len(states) != 1 || states[0].Name != "example" || states[0].Status != OcspStatusGood ||
+		!states[0].Stapled || states[0].Age < 7200 {
+		t.Errorf("wrong state: %+v", states)
+	}
+	// refresh halfway between this update and next update
+	if d := states[0].NextFetch.Sub(now); d < 119*time.Minute || d > 121*time.Minute {
+		t.Errorf("wrong next fetch: %s", d)
+	}
+
+	if f.state.TlsOcspStatusGood.Get() != 1 || f.state.TlsOcspStatusNone.Get() != 0 {
+		t.Errorf("wrong status gauges: %d/%d", f.state.TlsOcspStatusGood.Get(), f.state.TlsOcspStatusNone.Get())
+	}
+	if age := f.state.TlsOcspStapleMaxAge.Get(); age < 7200 {
+		t.Errorf("wrong max age: %d", age)
+	}
+}
+
+func TestOcspFetcherResponderOutage(t *testing.T) {
+	f, m, responder, _ := newTestOcspFetcher(t)
+
+	now := time.Now()
+	f.process(now)
+	staple := m.GetDefault().OCSPStaple
+
+	// keep serving last valid staple
+	responder.set(func(r *testOcspResponder) { r.fail = true })
+	later := now.Add(5 * time.Hour)
+	next := f.process(later)
+	if responder.requests != 2 {
+		t.Fatalf("wrong number of requests: %d", responder.requests)
+	}
+	if next != later.Add(300*time.Second) {
+		t.Errorf("should retry after RetryInterval: %s", next.Sub(later))
+	}
+	if string(m.GetDefault().OCSPStaple) != string(staple) {
+		t.Errorf("last valid staple should be kept")
+	}
+	states := f.GetState(later)
+	if states[0].LastError == "" || !states[0].Stapled {
+		t.Errorf("wrong state: %+v", states[0])
+	}
+
+	// response signed by unknown key is rejected
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	responder.set(func(r *testOcspResponder) {
+		r.fail = false
+		r.signer = otherKey
+	})
+	f.process(next)
+	if string(m.GetDefault().OCSPStaple) != string(staple) {
+		t.Errorf("invalid response should be rejected")
+	}
+
+	// recover
+	responder.set(func(r *testOcspResponder) { r.signer = r.issuerKey })
+	f.process(next.Add(300 * time.Second))
+	if string(m.GetDefault().OCSPStaple) == string(staple) {
+		t.Errorf("staple should be refreshed")
+	}
+	if states := f.GetState(later); states[0].LastError != "" {
+		t.Errorf("wrong state: %+v", states[0])
+	}
+}
+
+func TestOcspFetcherPending(t *testing.T) {
+	f, m, responder, _ := newTestOcspFetcher(t)
+
+	now := time.Now()
+	f.process(now)
+	staple := m.GetDefault().OCSPStaple
+
+	// new staple is not in time range yet
+	responder.set(func(r *testOcspResponder) { r.thisUpdate = 0 })
+	later := now.Add(5 * time.Hour)
+	next := f.process(later)
+	if string(m.GetDefault().OCSPStaple) != string(staple) {
+		t.Errorf("staple in time range should be kept")
+	}
+	if d := time.Until(next); d < 50*time.Minute || d > time.Hour {
+		t.Errorf("wrong time for promoting pending staple: %s", d)
+	}
+
+	// promote pending staple once current staple is not in use
+	f.lock.Lock()
+	for _, e := range f.entries {
+		e.parse.NextUpdate = time.Now()
+	}
+	f.lock.Unlock()
+	f.process(later)
+	if string(m.GetDefault().OCSPStaple) == string(staple) {
+		t.Errorf("pending staple should be promoted")
+	}
+}
+
+func TestOcspFetcherRevoked(t *testing.T) {
+	f, m, responder, _ := newTestOcspFetcher(t)
+	f.process(time.Now())
+
+	responder.set(func(r *testOcspResponder) { r.status = ocsp.Revoked })
+	f.process(time.Now().Add(5 * time.Hour))
+	if c := m.GetDefault(); c.OCSPStaple != nil || c.OCSPParse != nil {
+		t.Errorf("staple should be removed for revoked certificate")
+	}
+	if states := f.GetState(time.Now()); states[0].Status != OcspStatusRevoked || states[0].Stapled {
+		t.Errorf("wrong state: %+v", states[0])
+	}
+	if f.state.TlsOcspStatusRevoked.Get() != 1 || f.state.TlsOcspStatusGood.Get() != 0 {
+		t.Errorf("wrong status gauges: %d/%d", f.state.TlsOcspStatusRevoked.Get(), f.state.TlsOcspStatusGood.Get())
+	}
+	if age := f.state.TlsOcspStapleMaxAge.Get(); age != 0 {
+		t.Errorf("revoked certificate should not count in max age: %d", age)
+	}
+}
+
+func TestOcspFetcherParallel(t *testing.T) {
+	var lock sync.Mutex
+	var active, maxActive int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		lock.Lock()
+		active++
+		if active > maxActive {
+			maxActive = active
+		}
+		lock.Unlock()
+
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusServiceUnavailable)
+
+		lock.Lock()
+		active--
+		lock.Unlock()
+	}))
+	defer ts.Close()
+
+	var conf bfe_conf.ConfigOcspStapling
+	conf.SetDefaultConf()
+	state := NewServerStatus().ProxyState
+	f := newOcspFetcher(conf, state, func(string, []byte, *ocsp.Response) {})
+
+	certMap := make(map[string]*bfe_tls.Certificate)
+	for i := 0; i < 2*maxOcspFetchWorkers; i++ {
+		_, cert := newTestOcspCerts(t, ts.URL)
+		certMap[fmt.Sprintf("example%d", i)] = cert
+	}
+	f.SetCerts(certMap, time.Now())
+
+	start := time.Now()
+	f.process(time.Now())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("ocsp responses should be fetched in parallel, elapsed %s", elapsed)
+	}
+	if maxActive < 2 || maxActive > maxOcspFetchWorkers {
+		t.Errorf("wrong number of parallel fetches: %d", maxActive)
+	}
+	if int(state.TlsOcspFetchErr.Get()) != len(certMap) || int(state.TlsOcspStatusNone.Get()) != len(certMap) {
+		t.Errorf("wrong state %d/%d", state.TlsOcspFetchErr.Get(), state.TlsOcspStatusNone.Get())
+	}
+}
+
+func TestOcspFetcherReload(t *testing.T) {
+	f, m, responder, cert := newTestOcspFetcher(t)
+	f.process(time.Now())
+	staple := m.GetDefault().OCSPStaple
+
+	// staple is applied to reloaded certificates, without fetching again
+	reloaded := &bfe_tls.Certificate{
+		Certificate: cert.Certificate,
+		PrivateKey:  cert.PrivateKey,
+	}
+	updateTestCerts(t, m, f, reloaded)
+	if string(m.GetDefault().OCSPStaple) != string(staple) {
+		t.Errorf("staple should be applied to reloaded certificate")
+	}
+	f.process(time.Now())
+	if responder.requests != 1 {
+		t.Errorf("wrong number of requests: %d", responder.requests)
+	}
+}
+
+func TestOcspFetcherNoResponder(t *testing.T) {
+	_, cert := newTestOcspCerts(t, "")
+	leaf, _ := x509.ParseCertificate(cert.Certificate[0])
+	leaf.OCSPServer = nil
+	cert.Leaf = leaf
+
+	e, err := newOcspEntry("example", cert, time.Now())
+	if e != nil || err != nil {
+		t.Errorf("certificate without responder should be ignored: %v", err)
+	}
+
+	cert.Leaf = nil
+	cert.Certificate = cert.Certificate[:1]
+	if _, err := newOcspEntry("example", cert, time.Now()); err == nil {
+		t.Errorf("certificate without issuer should be rejected")
+	}
+}
@@ -29,6 +29,7 @@ import (
 )
 
 import (
+	"github.com/bfenetworks/bfe/bfe_config/bfe_conf"
 	"github.com/bfenetworks/bfe/bfe_config/bfe_tls_conf/tls_rule_conf"
 	"github.com/bfenetworks/bfe/bfe_http2"
 	"github.com/bfenetworks/bfe/bfe_stream"
@@ -93,26 +94,35 @@ func (m *TLSServerRuleMap) GetStreamRule(c *bfe_tls.Conn) *bfe_stream.Rule {
 	return &r.StreamRule
 }
 
+// GetByVipSni returns tls rule for given vip and server name.
+func (m *TLSServerRuleMap) GetByVipSni(vip net.IP, name string) *bfe_tls.Rule {
+	r := m.getRuleByVipSni(vip, name)
+	return &r.TlsRule
+}
+
 func (m *TLSServerRuleMap) getRule(c *bfe_tls.Conn) *ServerRule {
+	return m.getRuleByVipSni(c.GetVip(), c.GetServerName())
+}
+
+func (m *TLSServerRuleMap) getRuleByVipSni(vip net.IP, name string) *ServerRule {
 	m.lock.RLock()
 	defer m.lock.RUnlock()
 
 	// get tls rule conf by vip
-	if rule := m.getRuleByVip(c); rule != nil {
+	if rule := m.getRuleByVip(vip); rule != nil {
 		return rule
 	}
 
 	// get tls rule conf by sni (supported by modern browser)
-	if rule := m.getRuleBySni(c); rule != nil {
+	if rule := m.getRuleBySni(name); rule != nil {
 		return rule
 	}
 
 	// get default rule
-	return m.getDefaultRule(c)
+	return m.getDefaultRule()
 }
 
-func (m *TLSServerRuleMap) getRuleByVip(c *bfe_tls.Conn) *ServerRule {
-	vip := c.GetVip()
+func (m *TLSServerRuleMap) getRuleByVip(vip net.IP) *ServerRule {
 	if vip == nil {
 		return nil
 	}
@@ -121,12 +131,11 @@ func (m *TLSServerRuleMap) getRuleByVip(c *bfe_tls.Conn) *ServerRule {
 	return m.vipRuleMap[key]
 }
 
-func (m *TLSServerRuleMap) getRuleBySni(c *bfe_tls.Conn) *ServerRule {
-	name := c.GetServerName()
+func (m *TLSServerRuleMap) getRuleBySni(name string) *ServerRule {
 	return m.sniRuleMap[name]
 }
 
-func (m *TLSServerRuleMap) getDefaultRule(c *bfe_tls.Conn) *ServerRule {
+func (m *TLSServerRuleMap) getDefaultRule() *ServerRule {
 	rule := new(ServerRule)
 
 	rule.TlsRule.NextProtos = m.nextProtosDef
@@ -145,14 +154,16 @@ func (m *TLSServerRuleMap) getDefaultRule(c *bfe_tls.Conn) *ServerRule {
 }
 
 func (m *TLSServerRuleMap) Update(conf tls_rule_conf.BfeTlsRuleConf,
-	clientCAMap map[string]*x509.CertPool, clientCRLPoolMap map[string]*bfe_tls.CRLPool) {
+	clientCAMap map[string]*x509.CertPool, clientCRLPoolMap map[string]*bfe_tls.CRLPool,
+	clientRevocationMap map[string]*bfe_tls.RevocationChecker) {
 	vipRuleMap := make(map[string]*ServerRule)
 	sniRuleMap := make(map[string]*ServerRule)
 
 	for _, ruleConf := range conf.Config {
 		clientCAs := clientCAMap[ruleConf.ClientCAName]
 		clientCRLPool := clientCRLPoolMap[ruleConf.ClientCAName]
-		rule := m.createServerRule(ruleConf, clientCAs, clientCRLPool, conf.DefaultNextProtos)
+		clientRevocation := clientRevocationMap[ruleConf.ClientCAName]
+		rule := m.createServerRule(ruleConf, clientCAs, clientCRLPool, clientRevocation, conf.DefaultNextProtos)
 		for _, vip := range ruleConf.VipConf {
 			vipRuleMap[vip] = rule
 		}
@@ -182,7 +193,8 @@ func (m *TLSServerRuleMap) Update(conf tls_rule_conf.BfeTlsRuleConf,
 }
 
 func (m *TLSServerRuleMap) createServerRule(conf *tls_rule_conf.TlsRuleConf,
-	clientCAs *x509.CertPool, clientCRLPool *bfe_tls.CRLPool, defaultNextProtos []string) *ServerRule {
+	clientCAs *x509.CertPool, clientCRLPool *bfe_tls.CRLPool, clientRevocation *bfe_tls.RevocationChecker,
+	defaultNextProtos []string) *ServerRule {
 	r := new(ServerRule)
 
 	// tls next protos
@@ -204,6 +216,9 @@ func (m *TLSServerRuleMap) createServerRule(conf *tls_rule_conf.TlsRuleConf,
 	r.TlsRule.ClientCAs = clientCAs
 	r.TlsRule.ClientCAName = conf.ClientCAName
 	r.TlsRule.ClientCRLPool = clientCRLPool
+	if conf.ClientAuth {
+		r.TlsRule.ClientRevocation = clientRevocation
+	}
 
 	// enable chacha20-poly1305 cipher suites
 	r.TlsRule.Chacha20 = conf.Chacha20
@@ -211,6 +226,9 @@ func (m *TLSServerRuleMap) createServerRule(conf *tls_rule_conf.TlsRuleConf,
 	// enable dynamic tls record
 	r.TlsRule.DynamicRecord = conf.DynamicRecord
 
+	// tls curve preferences (checked while loading)
+	r.TlsRule.CurvePreferences, _ = bfe_conf.GetCurvePreferences(conf.CurvePreferences)
+
 	// h2/stream related settings
 	for _, protoConf := range conf.NextProtos {
 		proto, params, _ := tls_rule_conf.ParseNextProto(protoConf)
@@ -0,0 +1,225 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// rotation of session ticket keys
+//
+// Session ticket keys are kept in an ordered set shared by tls configs: the
+// active key for encrypting new tickets, and decrypt-only keys for tickets
+// issued before. Keys are updated by rotation:
+//   - NONE: key is loaded from SessionTicketKeyFile, and replaced by reload
+//   - RANDOM: random key is generated every interval
+//   - SEED: keys are derived from shared seed and index of interval, so that
+//     instances with the same seed rotate keys in lockstep
+
+package bfe_server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"sync"
+	"time"
+)
+
+import (
+	"github.com/bfenetworks/go-lib/log"
+	"golang.org/x/crypto/hkdf"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_config/bfe_conf"
+	"github.com/bfenetworks/bfe/bfe_config/bfe_tls_conf/session_ticket_key_conf"
+	"github.com/bfenetworks/bfe/bfe_tls"
+	"github.com/bfenetworks/bfe/bfe_util/json"
+)
+
+const ticketKeyDeriveLabel = "bfe session ticket key"
+
+type ticketKeyRotator struct {
+	conf     bfe_conf.ConfigSessionTicket
+	keys     *bfe_tls.SessionTicketKeys
+	onUpdate func(keys []bfe_tls.TicketKey) // called after keys updated
+
+	lock sync.Mutex
+	seed []byte // shared seed for SEED rotation
+}
+
+func newTicketKeyRotator(conf bfe_conf.ConfigSessionTicket,
+	onUpdate func(keys []bfe_tls.TicketKey)) (*ticketKeyRotator, error) {
+	r := &ticketKeyRotator{
+		conf:     conf,
+		keys:     bfe_tls.NewSessionTicketKeys(),
+		onUpdate: onUpdate,
+	}
+	if err := r.reload(time.Now()); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *ticketKeyRotator) interval() time.Duration {
+	return time.Duration(r.conf.SessionTicketKeyRotateInterval) * time.Second
+}
+
+// reload loads key file (NONE) or seed file (SEED), or generates new key
+// (RANDOM). For NONE rotation, the previous key is kept as decrypt-only key.
+func (r *ticketKeyRotator) reload(now time.Time) error {
+	switch r.conf.SessionTicketKeyRotation {
+	case bfe_conf.TicketKeyRotationSeed:
+		seed, err := session_ticket_key_conf.SessionTicketKeySeedLoad(r.conf.SessionTicketKeySeedFile)
+		if err != nil {
+			return err
+		}
+		r.lock.Lock()
+		r.seed = seed
+		r.lock.Unlock()
+		return r.rotate(now)
+
+	case bfe_conf.TicketKeyRotationRandom:
+		return r.rotate(now)
+
+	default:
+		keyConf, err := session_ticket_key_conf.SessionTicketKeyConfLoad(r.conf.SessionTicketKeyFile)
+		if err != nil {
+			return err
+		}
+		key, err := hex.DecodeString(keyConf.SessionTicketKey)
+		if err != nil { // never go here
+			return fmt.Errorf("wrong session ticket key %s (%s)", err, key)
+		}
+		r.keys.Rotate(bfe_tls.NewTicketKey(key, now), r.conf.SessionTicketKeyNum)
+		r.notify()
+		return nil
+	}
+}
+
+// rotate updates keys for RANDOM or SEED rotation.
+func (r *ticketKeyRotator) rotate(now time.Time) error {
+	switch r.conf.SessionTicketKeyRotation {
+	case bfe_conf.TicketKeyRotationSeed:
+		r.lock.Lock()
+		seed := r.seed
+		r.lock.Unlock()
+		r.keys.Set(deriveTicketKeys(seed, now, r.interval(), r.conf.SessionTicketKeyNum))
+
+	case bfe_conf.TicketKeyRotationRandom:
+		key := make([]byte, session_ticket_key_conf.RawSessionTicketKeySize)
+		if _, err := io.ReadFull(rand.Reader, key); err != nil {
+			return err
+		}
+		r.keys.Rotate(bfe_tls.NewTicketKey(key, now), r.conf.SessionTicketKeyNum)
+
+	default:
+		return nil
+	}
+
+	r.notify()
+	return nil
+}
+
+func (r *ticketKeyRotator) notify() {
+	if r.onUpdate != nil {
+		r.onUpdate(r.keys.Keys())
+	}
+}
+
+// nextRotate returns duration until next rotation. For SEED rotation, keys
+// are rotated at the boundary of intervals.
+func (r *ticketKeyRotator) nextRotate(now time.Time) time.Duration {
+	interval := r.interval()
+	if r.conf.SessionTicketKeyRotation == bfe_conf.TicketKeyRotationSeed {
+		return interval - time.Duration(now.UnixNano()%int64(interval))
+	}
+	return interval
+}
+
+// Start starts to rotate keys periodically (for RANDOM or SEED rotation).
+func (r *ticketKeyRotator) Start() {
+	if r.conf.SessionTicketKeyRotation == bfe_conf.TicketKeyRotationNone {
+		return
+	}
+
+	go func() {
+		for {
+			time.Sleep(r.nextRotate(time.Now()))
+			if err := r.rotate(time.Now()); err != nil {
+				log.Logger.Warn("rotate session ticket key: %v", err)
+				continue
+			}
+			log.Logger.Info("rotate session ticket key success")
+		}
+	}()
+}
+
+// deriveTicketKeys derives keys for interval of given time: the active key,
+// key of next interval (accepted in case of clock skew between instances),
+// and keys of previous intervals.
+func deriveTicketKeys(seed []byte, now time.Time, interval time.Duration, num int) []bfe_tls.TicketKey {
+	epoch := now.UnixNano() / int64(interval)
+
+	keys := make([]bfe_tls.TicketKey, 0, num+1)
+	keys = append(keys, deriveTicketKey(seed, epoch, interval))
+	keys = append(keys, deriveTicketKey(seed, epoch+1, interval))
+	for i := 1; i < num; i++ {
+		keys = append(keys, deriveTicketKey(seed, epoch-int64(i), interval))
+	}
+	return keys
+}
+
+func deriveTicketKey(seed []byte, epoch int64, interval time.Duration) bfe_tls.TicketKey {
+	info := make([]byte, len(ticketKeyDeriveLabel)+8)
+	copy(info, ticketKeyDeriveLabel)
+	binary.BigEndian.PutUint64(info[len(ticketKeyDeriveLabel):], uint64(epoch))
+
+	key := make([]byte, session_ticket_key_conf.RawSessionTicketKeySize)
+	io.ReadFull(hkdf.New(sha256.New, seed, nil, info), key)
+	return bfe_tls.NewTicketKey(key, time.Unix(0, epoch*int64(interval)))
+}
+
+// TicketKeyState is state of session ticket key. Key itself is not exposed.
+type TicketKeyState struct {
+	Name    string    // name of key (hex encode)
+	Created time.Time // time when key created
+	Age     int64     // seconds since key created (negative for key of next interval)
+	Active  bool      // active key for encrypting new tickets
+}
+
+// TicketKeysState is state of session ticket keys.
+type TicketKeysState struct {
+	Rotation string
+	Keys     []TicketKeyState
+}
+
+// SessionTicketKeysGet returns state of session ticket keys.
+func (srv *BfeServer) SessionTicketKeysGet(query url.Values) ([]byte, error) {
+	if srv.ticketKeyRotator == nil {
+		return []byte("{}"), nil
+	}
+
+	now := time.Now()
+	state := TicketKeysState{Rotation: srv.ticketKeyRotator.conf.SessionTicketKeyRotation}
+	for i, key := range srv.ticketKeyRotator.keys.Keys() {
+		state.Keys = append(state.Keys, TicketKeyState{
+			Name:    hex.EncodeToString(key.Name[:]),
+			Created: key.Created,
+			Age:     int64(now.Sub(key.Created) / time.Second),
+			Active:  i == 0,
+		})
+	}
+	return json.Marshal(state)
+}
@@ -0,0 +1,185 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bfe_server
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_config/bfe_conf"
+	"github.com/bfenetworks/bfe/bfe_tls"
+	"github.com/bfenetworks/bfe/bfe_util/json"
+)
+
+func newTestTicketKeyConf(rotation string) bfe_conf.ConfigSessionTicket {
+	var conf bfe_conf.ConfigSessionTicket
+	conf.SetDefaultConf()
+	conf.SessionTicketsDisabled = false
+	conf.SessionTicketKeyRotation = rotation
+	return conf
+}
+
+func TestTicketKeyRotatorNone(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "session_ticket_key.data")
+	writeKey := func(b byte) {
+		key := strings.Repeat(hex.EncodeToString([]byte{b}), 48)
+		data := `{"Version": "1", "SessionTicketKey": "` + key + `"}`
+		if err := os.WriteFile(keyFile, []byte(data), 0600); err != nil {
+			t.Fatalf("write key file: %s", err)
+		}
+	}
+
+	conf := newTestTicketKeyConf(bfe_conf.TicketKeyRotationNone)
+	conf.SessionTicketKeyFile = keyFile
+	conf.SessionTicketKeyNum = 2
+
+	var updated []bfe_tls.TicketKey
+	writeKey(1)
+	r, err := newTicketKeyRotator(conf, func(keys []bfe_tls.TicketKey) { updated = keys })
+	if err != nil {
+		t.Fatalf("newTicketKeyRotator(): %s", err)
+	}
+
+	// previous keys are kept as decrypt-only keys after reload
+	for _, b := range []byte{2, 3} {
+		writeKey(b)
+		if err := r.reload(time.Now()); err != nil {
+			t.Fatalf("reload(): %s", err)
+		}
+	}
+	keys := r.keys.Keys()
+	if len(keys) != 2 || keys[0].Name[0] != 3 || keys[1].Name[0] != 2 {
+		t.Errorf("unexpected keys: %v", keys)
+	}
+	if len(updated) != 2 || updated[0] != keys[0] {
+		t.Errorf("unexpected updated keys: %v", updated)
+	}
+}
+
+func TestTicketKeyRotatorRandom(t *testing.T) {
+	conf := newTestTicketKeyConf(bfe_conf.TicketKeyRotationRandom)
+	r, err := newTicketKeyRotator(conf, nil)
+	if err != nil {
+		t.Fatalf("newTicketKeyRotator(): %s", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		active := r.keys.Keys()[0]
+		if err := r.rotate(time.Now()); err != nil {
+			t.Fatalf("rotate(): %s", err)
+		}
+		keys := r.keys.Keys()
+		if keys[0] == active || keys[1] != active {
+			t.Errorf("active key should be rotated")
+		}
+	}
+	if n := len(r.keys.Keys()); n != conf.SessionTicketKeyNum {
+		t.Errorf("unexpected number of keys: %d", n)
+	}
+	if d := r.nextRotate(time.Now()); d != time.Hour {
+		t.Errorf("unexpected next rotation: %s", d)
+	}
+}
+
+func TestTicketKeyRotatorSeed(t *testing.T) {
+	seedFile := filepath.Join(t.TempDir(), "session_ticket_seed.data")
+	seed := strings.Repeat("5a", 32)
+	if err := os.WriteFile(seedFile, []byte(seed+"\n"), 0600); err != nil {
+		t.Fatalf("write seed file: %s", err)
+	}
+	conf := newTestTicketKeyConf(bfe_conf.TicketKeyRotationSeed)
+	conf.SessionTicketKeySeedFile = seedFile
+
+	// instances with the same seed derive the same keys
+	r1, err := newTicketKeyRotator(conf, nil)
+	if err != nil {
+		t.Fatalf("newTicketKeyRotator(): %s", err)
+	}
+	r2, _ := newTicketKeyRotator(conf, nil)
+
+	now := time.Unix(1800000000, 0) // boundary of interval
+	r1.rotate(now)
+	r2.rotate(now.Add(30 * time.Minute))
+	keys1, keys2 := r1.keys.Keys(), r2.keys.Keys()
+	if len(keys1) != 4 {
+		t.Fatalf("unexpected number of keys: %d", len(keys1))
+	}
+	for i := range keys1 {
+		if keys1[i] != keys2[i] {
+			t.Errorf("key %d should be the same", i)
+		}
+	}
+	if !keys1[0].Created.Equal(now) || !keys1[1].Created.Equal(now.Add(time.Hour)) ||
+		!keys1[2].Created.Equal(now.Add(-time.Hour)) {
+		t.Errorf("unexpected created time of keys")
+	}
+
+	// key of next interval becomes active, and active key becomes decrypt-only
+	r2.rotate(now.Add(time.Hour))
+	keys2 = r2.keys.Keys()
+	if keys2[0] != keys1[1] || keys2[2] != keys1[0] || keys2[3] != keys1[2] {
+		t.Errorf("unexpected keys after rotation")
+	}
+
+	// keys differ with another seed
+	otherKeys := deriveTicketKeys([]byte(strings.Repeat("b", 32)), now, time.Hour, 3)
+	if otherKeys[0] == keys1[0] {
+		t.Errorf("keys should differ with another seed")
+	}
+
+	// rotated at the boundary of intervals
+	if d := r1.nextRotate(now.Add(20 * time.Minute)); d != 40*time.Minute {
+		t.Errorf("unexpected next rotation: %s", d)
+	}
+}
+
+func TestSessionTicketKeysGet(t *testing.T) {
+	srv := NewBfeServer(bfe_conf.BfeConfig{}, "", "test")
+	if data, _ := srv.SessionTicketKeysGet(nil); string(data) != "{}" {
+		t.Errorf("unexpected state: %s", data)
+	}
+
+	conf := newTestTicketKeyConf(bfe_conf.TicketKeyRotationRandom)
+	r, err := newTicketKeyRotator(conf, nil)
+	if err != nil {
+		t.Fatalf("newTicketKeyRotator(): %s", err)
+	}
+	r.rotate(time.Now())
+	srv.ticketKeyRotator = r
+
+	data, err := srv.SessionTicketKeysGet(nil)
+	if err != nil {
+		t.Fatalf("SessionTicketKeysGet(): %s", err)
+	}
+	var state TicketKeysState
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatalf("unmarshal state: %s", err)
+	}
+	keys := r.keys.Keys()
+	if state.Rotation != bfe_conf.TicketKeyRotationRandom || len(state.Keys) != 2 ||
+		!state.Keys[0].Active || state.Keys[1].Active ||
+		state.Keys[0].Name != hex.EncodeToString(keys[0].Name[:]) {
+		t.Errorf("unexpected state: %s", data)
+	}
+	if strings.Contains(string(data), hex.EncodeToString(keys[0].Key[:])) {
+		t.Errorf("key should not be exposed")
+	}
+}
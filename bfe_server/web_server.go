@@ -59,8 +59,25 @@ func (m *BfeMonitor) monitorHandlers() map[string]interface{} {
 		"cluster_table_version": m.srv.ClusterTableVersionGet,
 
 		// for bal-table
-		"bal_table_status":  m.srv.BalTableStatusGet,
-		"bal_table_version": m.srv.BalTableVersionGet,
+		"bal_table_status":   m.srv.BalTableStatusGet,
+		"bal_table_version":  m.srv.BalTableVersionGet,
+		"bal_table_domain":   m.srv.BalTableDomainGet,
+		"bal_table_override": m.srv.BalTableOverrideGet,
+
+		// for xds
+		"xds_state": m.srv.XdsStateGet,
+
+		// for acme
+		"acme_state": m.srv.AcmeStateGet,
+
+		// for session ticket keys
+		"tls_session_ticket_keys": m.srv.SessionTicketKeysGet,
+
+		// for ocsp stapling
+		"tls_ocsp_staples": m.srv.OcspStaplesGet,
+
+		// for keyless tls
+		"tls_keyless_state": m.srv.KeylessStateGet,
 
 		// for proxy_state
 		"proxy_state":      m.srv.proxyStateGetAll,
@@ -86,6 +103,10 @@ func (m *BfeMonitor) monitorHandlers() map[string]interface{} {
 		"http2_state":      m.srv.http2StateGetAll,
 		"http2_state_diff": m.srv.http2StateGetDiff,
 
+		// for http3
+		"http3_state":      m.srv.http3StateGetAll,
+		"http3_state_diff": m.srv.http3StateGetDiff,
+
 		// for http
 		"http_state":      m.srv.httpStateGetAll,
 		"http_state_diff": m.srv.httpStateGetDiff,
@@ -132,9 +153,14 @@ func (m *BfeMonitor) reloadHandlers() map[string]interface{} {
 		// for name conf
 		"name_conf": m.srv.NameConfReload,
 
+		// for runtime override of backends
+		"bal_table_override_set":   m.srv.BalTableOverrideSet,
+		"bal_table_override_clear": m.srv.BalTableOverrideClear,
+
 		// for tls
 		"tls_conf":               m.srv.TLSConfReload,
 		"tls_session_ticket_key": m.srv.SessionTicketKeyReload,
+		"acme_conf":              m.srv.AcmeConfReload,
 	}
 	return handlers
 }
@@ -0,0 +1,284 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// dynamic config from xds server
+//
+// Resources from xds server are merged into a snapshot. Once resources of all
+// types are received, each response is applied atomically: config files are
+// generated from the snapshot, checked by the same loaders as file based
+// reloading, and then applied. Accepted config files are kept in DataPath,
+// and can be used as startup config.
+//
+// Once config from xds server is applied, it takes priority over file based
+// config: host, route, cluster, gslb and cluster table conf are reloaded from
+// accepted config files in DataPath, and only vip conf is reloaded from file.
+
+package bfe_server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+import (
+	"github.com/bfenetworks/go-lib/log"
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_balance"
+	"github.com/bfenetworks/bfe/bfe_config/bfe_xds_conf"
+	"github.com/bfenetworks/bfe/bfe_route"
+	"github.com/bfenetworks/bfe/bfe_util/json"
+	"github.com/bfenetworks/bfe/bfe_util/xds"
+)
+
+const xdsStagingDir = "staging"
+
+type xdsConfLoader struct {
+	srv      *BfeServer
+	dataPath string
+
+	lock     sync.Mutex
+	snapshot *bfe_xds_conf.Snapshot // accepted resources
+	received map[string]bool        // types of resources received
+}
+
+func newXdsConfLoader(srv *BfeServer, dataPath string) *xdsConfLoader {
+	l := new(xdsConfLoader)
+	l.srv = srv
+	l.dataPath = dataPath
+	l.snapshot = bfe_xds_conf.NewSnapshot()
+	l.received = make(map[string]bool)
+	return l
+}
+
+// handleResponse merges resources in response, and applies them if resources
+// of all types are received.
+func (l *xdsConfLoader) handleResponse(resp *discoveryv3.DeltaDiscoveryResponse) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	snapshot := l.snapshot.Clone()
+	for _, res := range resp.Resources {
+		if res.Resource == nil {
+			return fmt.Errorf("resource[%s]: no content", res.Name)
+		}
+		if res.Resource.TypeUrl != resp.TypeUrl {
+			return fmt.Errorf("resource[%s]: type url %s mismatch", res.Name, res.Resource.TypeUrl)
+		}
+		if err := snapshot.SetResource(resp.TypeUrl, res.Name, res.Resource.Value); err != nil {
+			return fmt.Errorf("resource[%s]: %s", res.Name, err)
+		}
+	}
+	for _, name := range resp.RemovedResources {
+		if err := snapshot.RemoveResource(resp.TypeUrl, name); err != nil {
+			return err
+		}
+	}
+
+	snapshot.Version = resp.SystemVersionInfo
+	if len(snapshot.Version) == 0 {
+		snapshot.Version = time.Now().Format("20060102150405")
+	}
+
+	l.received[resp.TypeUrl] = true
+	if !l.receivedAll() {
+		// wait for resources of other types
+		l.snapshot = snapshot
+		return nil
+	}
+
+	if err := l.apply(snapshot); err != nil {
+		log.Logger.Warn("xdsConfLoader: apply version %s err [%s]", snapshot.Version, err)
+		return err
+	}
+	l.snapshot = snapshot
+	log.Logger.Info("xdsConfLoader: apply version %s success", snapshot.Version)
+	return nil
+}
+
+func (l *xdsConfLoader) receivedAll() bool {
+	for _, typeURL := range bfe_xds_conf.TypeURLs {
+		if !l.received[typeURL] {
+			return false
+		}
+	}
+	return true
+}
+
+// apply checks and applies config in snapshot.
+func (l *xdsConfLoader) apply(snapshot *bfe_xds_conf.Snapshot) error {
+	srv := l.srv
+
+	if err := snapshot.Check(); err != nil {
+		return err
+	}
+
+	// generate and load config files
+	stagingPath := filepath.Join(l.dataPath, xdsStagingDir)
+	if err := snapshot.Dump(stagingPath); err != nil {
+		return fmt.Errorf("dump config err: %s", err)
+	}
+	stagingFile := func(name string) string {
+		return filepath.Join(stagingPath, name)
+	}
+
+	serverConf, err := bfe_route.LoadServerDataConf(stagingFile(bfe_xds_conf.HostRuleFile),
+		srv.Config.Server.VipRuleConf, stagingFile(bfe_xds_conf.RouteRuleFile),
+		stagingFile(bfe_xds_conf.ClusterConfFile))
+	if err != nil {
+		return err
+	}
+
+	gslbConf, backendConf, err := srv.balTable.BalTableConfLoad(stagingFile(bfe_xds_conf.GslbFile),
+		stagingFile(bfe_xds_conf.ClusterTableFile))
+	if err != nil {
+		return err
+	}
+
+	if err := bfe_balance.BalTableConfCheck(gslbConf, backendConf); err != nil {
+		return err
+	}
+	merged := l.mergeRemovedClusters(snapshot)
+	if merged != nil {
+		if err := bfe_balance.BalTableConfCheck(merged.Gslb(), merged.ClusterTable()); err != nil {
+			return err
+		}
+	}
+
+	// Note: file based reloading is not allowed while applying
+	srv.reloadLock.Lock()
+	defer srv.reloadLock.Unlock()
+
+	if merged != nil {
+		// make before break: balancers of removed clusters are kept until
+		// routes to them are removed
+		if err := srv.gslbDataConfApply(merged.Gslb(), merged.ClusterTable()); err != nil {
+			return err
+		}
+		srv.confLock.RLock()
+		oldServerConf := srv.ServerConf
+		srv.confLock.RUnlock()
+
+		srv.serverDataConfApply(serverConf)
+		if err := srv.gslbDataConfApply(gslbConf, backendConf); err != nil {
+			// roll back, for the update is refused
+			srv.gslbDataConfApply(merged.Gslb(), merged.ClusterTable())
+			srv.serverDataConfApply(oldServerConf)
+			return err
+		}
+	} else {
+		// balancers of new clusters are added before routes to them
+		if err := srv.gslbDataConfApply(gslbConf, backendConf); err != nil {
+			return err
+		}
+		srv.serverDataConfApply(serverConf)
+	}
+
+	// keep accepted config files
+	for _, name := range []string{bfe_xds_conf.ClusterConfFile, bfe_xds_conf.ClusterTableFile,
+		bfe_xds_conf.GslbFile, bfe_xds_conf.HostRuleFile, bfe_xds_conf.RouteRuleFile} {
+		if err := os.Rename(stagingFile(name), filepath.Join(l.dataPath, name)); err != nil {
+			log.Logger.Warn("xdsConfLoader: keep config file %s err [%s]", name, err)
+		}
+	}
+	srv.xdsDataPath = l.dataPath
+
+	return nil
+}
+
+// mergeRemovedClusters returns snapshot with clusters removed from accepted
+// snapshot added back, or nil if no cluster removed.
+func (l *xdsConfLoader) mergeRemovedClusters(snapshot *bfe_xds_conf.Snapshot) *bfe_xds_conf.Snapshot {
+	var merged *bfe_xds_conf.Snapshot
+	for name, conf := range l.snapshot.Gslbs {
+		if _, ok := snapshot.Gslbs[name]; ok {
+			continue
+		}
+		backendConf, ok := l.snapshot.ClusterTables[name]
+		if !ok {
+			continue
+		}
+
+		if merged == nil {
+			merged = snapshot.Clone()
+		}
+		merged.Gslbs[name] = conf
+		merged.ClusterTables[name] = backendConf
+	}
+	return merged
+}
+
+// newClientTLSConfig creates tls config for connecting server, e.g., xds or
+// acme server, which is verified by given ca certificates.
+func newClientTLSConfig(caCertFile string) (*tls.Config, error) {
+	config := new(tls.Config)
+	if len(caCertFile) == 0 {
+		return config, nil
+	}
+
+	data, err := os.ReadFile(caCertFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no valid certificate in %s", caCertFile)
+	}
+	config.RootCAs = pool
+	return config, nil
+}
+
+// InitXdsClient initializes client for dynamic config from xds server.
+func (srv *BfeServer) InitXdsClient() error {
+	xdsConf := srv.Config.Xds
+	if !xdsConf.Enabled {
+		return nil
+	}
+
+	clientConf := xds.Config{
+		Address:        xdsConf.Address,
+		NodeId:         xdsConf.NodeId,
+		NodeCluster:    xdsConf.NodeCluster,
+		ConnectTimeout: time.Duration(xdsConf.ConnectTimeout) * time.Millisecond,
+		TypeURLs:       bfe_xds_conf.TypeURLs,
+	}
+	if xdsConf.TLSEnabled {
+		tlsConfig, err := newClientTLSConfig(xdsConf.CACertFile)
+		if err != nil {
+			return fmt.Errorf("InitXdsClient(): %s", err)
+		}
+		clientConf.TLSConfig = tlsConfig
+	}
+
+	loader := newXdsConfLoader(srv, xdsConf.DataPath)
+	srv.xdsClient = xds.NewClient(clientConf, loader.handleResponse)
+	srv.xdsClient.Start()
+	return nil
+}
+
+// XdsStateGet returns state of xds client.
+func (srv *BfeServer) XdsStateGet(query url.Values) ([]byte, error) {
+	if srv.xdsClient == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(srv.xdsClient.GetState())
+}
@@ -0,0 +1,208 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bfe_server
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+import (
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_config/bfe_conf"
+	"github.com/bfenetworks/bfe/bfe_config/bfe_xds_conf"
+	"github.com/bfenetworks/bfe/bfe_route"
+)
+
+// newTestXdsCluster returns envoy Cluster with http health check.
+func newTestXdsCluster(t *testing.T, name string) string {
+	cluster := &clusterv3.Cluster{
+		Name: name,
+		HealthChecks: []*corev3.HealthCheck{{
+			HealthChecker: &corev3.HealthCheck_HttpHealthCheck_{
+				HttpHealthCheck: &corev3.HealthCheck_HttpHealthCheck{Path: "/"},
+			},
+		}},
+	}
+	return marshalTestXdsResource(t, cluster)
+}
+
+// newTestXdsClusterLoadAssignment returns envoy ClusterLoadAssignment with one
+// endpoint in sub-cluster sub1.
+func newTestXdsClusterLoadAssignment(t *testing.T, name string, addr string) string {
+	cla := &endpointv3.ClusterLoadAssignment{
+		ClusterName: name,
+		Endpoints: []*endpointv3.LocalityLbEndpoints{{
+			Locality: &corev3.Locality{SubZone: "sub1"},
+			LbEndpoints: []*endpointv3.LbEndpoint{{
+				HostIdentifier: &endpointv3.LbEndpoint_Endpoint{
+					Endpoint: &endpointv3.Endpoint{
+						Address: &corev3.Address{
+							Address: &corev3.Address_SocketAddress{
+								SocketAddress: &corev3.SocketAddress{
+									Address:       addr,
+									PortSpecifier: &corev3.SocketAddress_PortValue{PortValue: 8080},
+								},
+							},
+						},
+					},
+				},
+			}},
+		}},
+	}
+	return marshalTestXdsResource(t, cla)
+}
+
+func marshalTestXdsResource(t *testing.T, m proto.Message) string {
+	data, err := proto.Marshal(m)
+	if err != nil {
+		t.Fatalf("proto.Marshal(): %s", err)
+	}
+	return string(data)
+}
+
+func newTestXdsResponse(typeURL, version string, resources map[string]string) *discoveryv3.DeltaDiscoveryResponse {
+	resp := &discoveryv3.DeltaDiscoveryResponse{
+		TypeUrl:           typeURL,
+		SystemVersionInfo: version,
+	}
+	for name, data := range resources {
+		resp.Resources = append(resp.Resources, &discoveryv3.Resource{
+			Name:     name,
+			Version:  version,
+			Resource: &anypb.Any{TypeUrl: typeURL, Value: []byte(data)},
+		})
+	}
+	return resp
+}
+
+func TestXdsConfLoader(t *testing.T) {
+	var cfg bfe_conf.BfeConfig
+	cfg.Server.VipRuleConf = "./testdata/xds/vip_rule.data"
+	srv := NewBfeServer(cfg, "", "test")
+	srv.ServerConf = new(bfe_route.ServerDataConf)
+
+	dataPath := t.TempDir()
+	loader := newXdsConfLoader(srv, dataPath)
+
+	responses := []*discoveryv3.DeltaDiscoveryResponse{
+		newTestXdsResponse(bfe_xds_conf.TypeClusterTable, "v1", map[string]string{
+			"cluster1": newTestXdsClusterLoadAssignment(t, "cluster1", "10.0.0.1"),
+		}),
+		newTestXdsResponse(bfe_xds_conf.TypeGslb, "v1", map[string]string{
+			"cluster1": `{"GSLB_BLACKHOLE": 0, "sub1": 100}`,
+		}),
+		newTestXdsResponse(bfe_xds_conf.TypeClusterConf, "v1", map[string]string{
+			"cluster1": newTestXdsCluster(t, "cluster1"),
+		}),
+		newTestXdsResponse(bfe_xds_conf.TypeRoute, "v1", map[string]string{
+			"product1": `{"ProductRule": [{"Cond": "default_t()", "ClusterName": "cluster1"}]}`,
+		}),
+	}
+	for _, resp := range responses {
+		if err := loader.handleResponse(resp); err != nil {
+			t.Fatalf("handleResponse(%s): %s", resp.TypeUrl, err)
+		}
+	}
+
+	// not applied before all types received
+	if _, err := srv.balTable.Lookup("cluster1"); err == nil {
+		t.Fatalf("config should not be applied before all types received")
+	}
+
+	resp := newTestXdsResponse(bfe_xds_conf.TypeHost, "v1", map[string]string{
+		"product1": `{"Hosts": {"tag1": ["example.org"]}}`,
+	})
+	if err := loader.handleResponse(resp); err != nil {
+		t.Fatalf("handleResponse(%s): %s", resp.TypeUrl, err)
+	}
+	if _, err := srv.balTable.Lookup("cluster1"); err != nil {
+		t.Errorf("cluster1 should be applied: %s", err)
+	}
+	if product, err := srv.ServerConf.HostTableLookup("example.org"); err != nil || product != "product1" {
+		t.Errorf("host of product1 should be applied: %s %v", product, err)
+	}
+	if _, err := os.Stat(filepath.Join(dataPath, bfe_xds_conf.ClusterConfFile)); err != nil {
+		t.Errorf("accepted config should be kept: %s", err)
+	}
+
+	// file based reloading should not override config from xds server
+	if err := srv.GslbDataConfReload(url.Values{}); err != nil {
+		t.Errorf("GslbDataConfReload(): %s", err)
+	}
+	if err := srv.ServerDataConfReload(url.Values{}); err != nil {
+		t.Errorf("ServerDataConfReload(): %s", err)
+	}
+	if _, err := srv.balTable.Lookup("cluster1"); err != nil {
+		t.Errorf("cluster1 should be kept after file based reloading: %s", err)
+	}
+
+	// route to unknown cluster is rejected
+	resp = newTestXdsResponse(bfe_xds_conf.TypeRoute, "v2", map[string]string{
+		"product1": `{"ProductRule": [{"Cond": "default_t()", "ClusterName": "cluster2"}]}`,
+	})
+	if err := loader.handleResponse(resp); err == nil {
+		t.Errorf("route to unknown cluster should be rejected")
+	}
+	if loader.snapshot.Version != "v1" {
+		t.Errorf("rejected snapshot should not be accepted")
+	}
+
+	// removing cluster together with routes to it
+	responses = []*discoveryv3.DeltaDiscoveryResponse{
+		newTestXdsResponse(bfe_xds_conf.TypeClusterTable, "v3", map[string]string{
+			"cluster2": newTestXdsClusterLoadAssignment(t, "cluster2", "10.0.0.2"),
+		}),
+		newTestXdsResponse(bfe_xds_conf.TypeGslb, "v3", map[string]string{
+			"cluster2": `{"GSLB_BLACKHOLE": 0, "sub1": 100}`,
+		}),
+		newTestXdsResponse(bfe_xds_conf.TypeClusterConf, "v3", map[string]string{
+			"cluster2": newTestXdsCluster(t, "cluster2"),
+		}),
+		newTestXdsResponse(bfe_xds_conf.TypeRoute, "v3", map[string]string{
+			"product1": `{"ProductRule": [{"Cond": "default_t()", "ClusterName": "cluster2"}]}`,
+		}),
+	}
+	for _, resp := range responses {
+		if err := loader.handleResponse(resp); err != nil {
+			t.Fatalf("handleResponse(%s): %s", resp.TypeUrl, err)
+		}
+	}
+
+	for _, typeURL := range []string{bfe_xds_conf.TypeClusterConf, bfe_xds_conf.TypeGslb,
+		bfe_xds_conf.TypeClusterTable} {
+		resp = newTestXdsResponse(typeURL, "v4", nil)
+		resp.RemovedResources = []string{"cluster1"}
+		if err := loader.handleResponse(resp); err != nil {
+			t.Fatalf("handleResponse(%s): %s", resp.TypeUrl, err)
+		}
+	}
+	if _, err := srv.balTable.Lookup("cluster1"); err == nil {
+		t.Errorf("cluster1 should be removed")
+	}
+	if _, err := srv.balTable.Lookup("cluster2"); err != nil {
+		t.Errorf("cluster2 should be added: %s", err)
+	}
+}
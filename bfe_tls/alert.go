@@ -52,6 +52,8 @@ const (
 	alertInappropriateFallback  alert = 86
 	alertUserCanceled           alert = 90
 	alertNoRenegotiation        alert = 100
+	alertMissingExtension       alert = 109
+	alertCertificateRequired    alert = 116
 )
 
 var alertText = map[alert]string{
@@ -78,6 +80,8 @@ var alertText = map[alert]string{
 	alertInappropriateFallback:  "inappropriate fallback",
 	alertUserCanceled:           "user canceled",
 	alertNoRenegotiation:        "no renegotiation",
+	alertMissingExtension:       "missing extension",
+	alertCertificateRequired:    "certificate required",
 }
 
 func (e alert) String() string {
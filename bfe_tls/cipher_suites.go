@@ -19,6 +19,7 @@
 package bfe_tls
 
 import (
+	"crypto"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/des"
@@ -119,6 +120,34 @@ var cipherSuites = []*cipherSuite{
 	{TLS_RSA_WITH_SM4_SM3, 16, 32, 16, rsaKA, 0, cipherSM4, macSM3, nil},
 }
 
+// A cipherSuiteTLS13 defines only the pair of the AEAD algorithm and hash
+// algorithm to be used with HKDF. See RFC 8446, Appendix B.4.
+type cipherSuiteTLS13 struct {
+	id     uint16
+	keyLen int
+	aead   func(key, fixedNonce []byte) cipher.AEAD
+	hash   crypto.Hash
+	// flags is a bitmask of the suite* values, above.
+	flags int
+}
+
+// cipherSuitesTLS13 are cipher suites supported in TLS 1.3, in server
+// preference order.
+var cipherSuitesTLS13 = []*cipherSuiteTLS13{
+	{TLS_AES_128_GCM_SHA256, 16, aeadAESGCMTLS13, crypto.SHA256, 0},
+	{TLS_CHACHA20_POLY1305_SHA256, 32, aeadChaCha20Poly1305, crypto.SHA256, suiteChacha20},
+	{TLS_AES_256_GCM_SHA384, 32, aeadAESGCMTLS13, crypto.SHA384, 0},
+}
+
+func cipherSuiteTLS13ByID(id uint16) *cipherSuiteTLS13 {
+	for _, suite := range cipherSuitesTLS13 {
+		if suite.id == id {
+			return suite
+		}
+	}
+	return nil
+}
+
 // CheckSuiteRSA checks whether cipher suite using RSA key argreement
 func CheckSuiteRSA(id uint16) bool {
 	switch id {
@@ -186,6 +215,8 @@ func checkCipherSuiteHttp2Accepted(suite uint16) bool {
 		return true
 	case TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256:
 		return true
+	case TLS_AES_128_GCM_SHA256, TLS_AES_256_GCM_SHA384, TLS_CHACHA20_POLY1305_SHA256:
+		return true
 	default:
 		return false
 	}
@@ -340,6 +371,26 @@ func aeadAESGCM(key, fixedNonce []byte) cipher.AEAD {
 	return &fixedNonceAEAD{nonce1, nonce2, aead}
 }
 
+// aeadAESGCMTLS13 returns AES-GCM for TLS 1.3, with per-record nonce
+// formed by XORing the sequence number with the fixed nonce.
+// See RFC 8446, Section 5.3
+func aeadAESGCMTLS13(key, fixedNonce []byte) cipher.AEAD {
+	aes, err := aes.NewCipher(key)
+	if err != nil {
+		panic(err)
+	}
+	aead, err := cipher.NewGCM(aes)
+	if err != nil {
+		panic(err)
+	}
+
+	nonce1, nonce2 := make([]byte, 12), make([]byte, 12)
+	copy(nonce1, fixedNonce)
+	copy(nonce2, fixedNonce)
+
+	return &xorNonceAEAD{nonce1, nonce2, aead}
+}
+
 func aeadChaCha20Poly1305(key, fixedNonce []byte) cipher.AEAD {
 	aead, err := chacha20poly1305.New(key)
 	if err != nil {
@@ -478,6 +529,11 @@ const (
 	TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256 uint16 = 0xcca9
 	TLS_RSA_WITH_SM4_SM3                          uint16 = 0xe019
 
+	// TLS 1.3 cipher suites.
+	TLS_AES_128_GCM_SHA256       uint16 = 0x1301
+	TLS_AES_256_GCM_SHA384       uint16 = 0x1302
+	TLS_CHACHA20_POLY1305_SHA256 uint16 = 0x1303
+
 	// TLS_FALLBACK_SCSV isn't a standard cipher suite but an indicator
 	// that the client is doing version fallback. See
 	// https://tools.ietf.org/html/draft-ietf-tls-downgrade-scsv-00.
@@ -506,6 +562,9 @@ var cipherSuiteTextMap = map[uint16]string{
 	TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256:   "TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256",
 	TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256: "TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256",
 	TLS_RSA_WITH_SM4_SM3:                          "TLS_RSA_WITH_SM4_SM3",
+	TLS_AES_128_GCM_SHA256:                        "TLS_AES_128_GCM_SHA256",
+	TLS_AES_256_GCM_SHA384:                        "TLS_AES_256_GCM_SHA384",
+	TLS_CHACHA20_POLY1305_SHA256:                  "TLS_CHACHA20_POLY1305_SHA256",
 }
 
 func CipherSuiteText(suite uint16) string {
@@ -533,6 +592,9 @@ var cipherSuiteTextMapForOpenSSL = map[uint16]string{
 	TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256:       "ECDHE-ECDSA-AES128-GCM-SHA256",
 	TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256:   "ECDHE-RSA-CHACHA20-POLY1305",
 	TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256: "ECDHE-ECDSA-CHACHA20-POLY1305",
+	TLS_AES_128_GCM_SHA256:                        "TLS_AES_128_GCM_SHA256",
+	TLS_AES_256_GCM_SHA384:                        "TLS_AES_256_GCM_SHA384",
+	TLS_CHACHA20_POLY1305_SHA256:                  "TLS_CHACHA20_POLY1305_SHA256",
 }
 
 func CipherSuiteTextForOpenSSL(suite uint16) string {
@@ -38,6 +38,7 @@ const (
 	VersionTLS10 = 0x0301
 	VersionTLS11 = 0x0302
 	VersionTLS12 = 0x0303
+	VersionTLS13 = 0x0304
 )
 
 const (
@@ -47,8 +48,10 @@ const (
 	recordHeaderLen = 5            // record header length
 	maxHandshake    = 65536        // maximum handshake we support (protocol max is 16 MB)
 
+	maxEarlyDataSize = 16384 // maximum bytes of 0-RTT data skipped (TLS 1.3)
+
 	minVersion = VersionSSL30
-	maxVersion = VersionTLS12
+	maxVersion = VersionTLS13
 
 	ticketKeyNameLen = 16 // length for session ticket key name
 )
@@ -96,18 +99,21 @@ const (
 
 // TLS handshake message types.
 const (
-	typeClientHello        uint8 = 1
-	typeServerHello        uint8 = 2
-	typeNewSessionTicket   uint8 = 4
-	typeCertificate        uint8 = 11
-	typeServerKeyExchange  uint8 = 12
-	typeCertificateRequest uint8 = 13
-	typeServerHelloDone    uint8 = 14
-	typeCertificateVerify  uint8 = 15
-	typeClientKeyExchange  uint8 = 16
-	typeFinished           uint8 = 20
-	typeCertificateStatus  uint8 = 22
-	typeNextProtocol       uint8 = 67 // Not IANA assigned
+	typeClientHello         uint8 = 1
+	typeServerHello         uint8 = 2
+	typeNewSessionTicket    uint8 = 4
+	typeEncryptedExtensions uint8 = 8
+	typeCertificate         uint8 = 11
+	typeServerKeyExchange   uint8 = 12
+	typeCertificateRequest  uint8 = 13
+	typeServerHelloDone     uint8 = 14
+	typeCertificateVerify   uint8 = 15
+	typeClientKeyExchange   uint8 = 16
+	typeFinished            uint8 = 20
+	typeCertificateStatus   uint8 = 22
+	typeKeyUpdate           uint8 = 24
+	typeNextProtocol        uint8 = 67  // Not IANA assigned
+	typeMessageHash         uint8 = 254 // synthetic message
 )
 
 // TLS compression types.
@@ -117,16 +123,24 @@ const (
 
 // TLS extension numbers
 const (
-	extensionServerName          uint16 = 0
-	extensionStatusRequest       uint16 = 5
-	extensionSupportedCurves     uint16 = 10
-	extensionSupportedPoints     uint16 = 11
-	extensionSignatureAlgorithms uint16 = 13
-	extensionALPN                uint16 = 16
-	extensionPadding             uint16 = 21
-	extensionSessionTicket       uint16 = 35
-	extensionNextProtoNeg        uint16 = 13172 // not IANA assigned
-	extensionRenegotiationInfo   uint16 = 0xff01
+	extensionServerName              uint16 = 0
+	extensionStatusRequest           uint16 = 5
+	extensionSupportedCurves         uint16 = 10
+	extensionSupportedPoints         uint16 = 11
+	extensionSignatureAlgorithms     uint16 = 13
+	extensionALPN                    uint16 = 16
+	extensionPadding                 uint16 = 21
+	extensionSessionTicket           uint16 = 35
+	extensionPreSharedKey            uint16 = 41
+	extensionEarlyData               uint16 = 42
+	extensionSupportedVersions       uint16 = 43
+	extensionCookie                  uint16 = 44
+	extensionPSKModes                uint16 = 45
+	extensionCertificateAuthorities  uint16 = 47
+	extensionSignatureAlgorithmsCert uint16 = 50
+	extensionKeyShare                uint16 = 51
+	extensionNextProtoNeg            uint16 = 13172 // not IANA assigned
+	extensionRenegotiationInfo       uint16 = 0xff01
 )
 
 // TLS signaling cipher suite values
@@ -142,8 +156,49 @@ const (
 	CurveP256 CurveID = 23
 	CurveP384 CurveID = 24
 	CurveP521 CurveID = 25
+	X25519    CurveID = 29 // only supported in TLS 1.3
+
+	// X25519MLKEM768 is the hybrid post-quantum key exchange which combines
+	// ML-KEM-768 and X25519, only supported in TLS 1.3.
+	// See https://datatracker.ietf.org/doc/draft-ietf-tls-ecdhe-mlkem/
+	X25519MLKEM768 CurveID = 4588
+)
+
+var curveTextMap = map[CurveID]string{
+	CurveP256:      "CurveP256",
+	CurveP384:      "CurveP384",
+	CurveP521:      "CurveP521",
+	X25519:         "X25519",
+	X25519MLKEM768: "X25519MLKEM768",
+}
+
+// CurveText returns name of the given curve.
+func CurveText(curve CurveID) string {
+	if text, ok := curveTextMap[curve]; ok {
+		return text
+	}
+	return fmt.Sprintf("CURVE_%d", curve)
+}
+
+// TLS 1.3 Key Share. See RFC 8446, Section 4.2.8.
+type keyShare struct {
+	group CurveID
+	data  []byte
+}
+
+// TLS 1.3 PSK Key Exchange Modes. See RFC 8446, Section 4.2.9.
+const (
+	pskModePlain uint8 = 0
+	pskModeDHE   uint8 = 1
 )
 
+// TLS 1.3 PSK Identity. Can be a Session Ticket, or a reference to a saved
+// session. See RFC 8446, Section 4.2.11.
+type pskIdentity struct {
+	label               []byte
+	obfuscatedTicketAge uint32
+}
+
 // TLS Elliptic Curve Point Formats
 // http://www.iana.org/assignments/tls-parameters/tls-parameters.xml#tls-parameters-9
 const (
@@ -174,6 +229,8 @@ const (
 const (
 	hashSHA1   uint8 = 2
 	hashSHA256 uint8 = 4
+	hashSHA384 uint8 = 5
+	hashSHA512 uint8 = 6
 )
 
 // Signature algorithms for TLS 1.2 (See RFC 5246, section A.4.1)
@@ -205,6 +262,28 @@ var supportedClientCertSignatureAlgorithms = []signatureAndHash{
 	{hashSHA256, signatureECDSA},
 }
 
+// Signature schemes for TLS 1.3 (See RFC 8446, section 4.2.3). They are
+// encoded in the same way as SignatureAndHashAlgorithm of TLS 1.2.
+var (
+	sigECDSAWithP256AndSHA256 = signatureAndHash{hashSHA256, signatureECDSA} // 0x0403
+	sigECDSAWithP384AndSHA384 = signatureAndHash{hashSHA384, signatureECDSA} // 0x0503
+	sigECDSAWithP521AndSHA512 = signatureAndHash{hashSHA512, signatureECDSA} // 0x0603
+	sigPSSWithSHA256          = signatureAndHash{0x08, 0x04}                 // rsa_pss_rsae_sha256
+	sigPSSWithSHA384          = signatureAndHash{0x08, 0x05}                 // rsa_pss_rsae_sha384
+	sigPSSWithSHA512          = signatureAndHash{0x08, 0x06}                 // rsa_pss_rsae_sha512
+)
+
+// supportedSignatureAlgorithmsTLS13 contains the signature schemes that the
+// code supports for CertificateVerify in TLS 1.3, in preference order.
+var supportedSignatureAlgorithmsTLS13 = []signatureAndHash{
+	sigPSSWithSHA256,
+	sigECDSAWithP256AndSHA256,
+	sigPSSWithSHA384,
+	sigECDSAWithP384AndSHA384,
+	sigPSSWithSHA512,
+	sigECDSAWithP521AndSHA512,
+}
+
 // ConnectionState records basic TLS details about the connection.
 type ConnectionState struct {
 	Version                    uint16                // TLS version used by the connection (e.g. VersionTLS12)
@@ -226,8 +305,20 @@ type ConnectionState struct {
 	ClientCAName               string                // TLS client CA name
 	JA3Raw                     string                // JA3 fingerprint string for TLS Client
 	JA3Hash                    string                // JA3 fingerprint hash for TLS Client
+	JA4                        string                // JA4 fingerprint for TLS Client
+	TrafficSecrets             map[string][]byte     // TLS 1.3 traffic secrets, keyed by label in NSS key log format
+	CurveID                    CurveID               // key exchange group used by the connection, if any
 }
 
+// Labels of TLS 1.3 traffic secrets in NSS key log format.
+// See https://developer.mozilla.org/en-US/docs/Mozilla/Projects/NSS/Key_Log_Format
+const (
+	KeyLogLabelClientHandshake = "CLIENT_HANDSHAKE_TRAFFIC_SECRET"
+	KeyLogLabelServerHandshake = "SERVER_HANDSHAKE_TRAFFIC_SECRET"
+	KeyLogLabelClientTraffic   = "CLIENT_TRAFFIC_SECRET_0"
+	KeyLogLabelServerTraffic   = "SERVER_TRAFFIC_SECRET_0"
+)
+
 // ClientAuthType declares the policy the server will follow for
 // TLS Client Authentication.
 type ClientAuthType int
@@ -272,6 +363,23 @@ type ServerSessionCache interface {
 	Put(sessionKey string, sessionState []byte) error
 }
 
+// ACMETLSProtocol is the application protocol for ACME TLS-ALPN-01
+// challenge. See RFC 8737
+const ACMETLSProtocol = "acme-tls/1"
+
+// ClientHelloInfo contains information from a ClientHello message in order
+// to guide certificate selection in the GetCertificate callback.
+type ClientHelloInfo struct {
+	// ServerName indicates the name of the server requested by the client
+	ServerName string
+
+	// SupportedProtos lists the application protocols supported by the client
+	SupportedProtos []string
+
+	// Conn is the underlying connection
+	Conn *Conn
+}
+
 type MultiCertificate interface {
 	// Get certificate for the given conn
 	Get(c *Conn) *Certificate
@@ -309,11 +417,18 @@ type Rule struct {
 	// client CRL pool
 	ClientCRLPool *CRLPool
 
+	// online revocation checking of client certificate (optional)
+	ClientRevocation *RevocationChecker
+
 	// enable Chacha20-poly1305 cipher suites
 	Chacha20 bool
 
 	// enable Dynamic TLS record size
 	DynamicRecord bool
+
+	// CurvePreferences contains the key exchange groups in preference
+	// order. If empty, Config.CurvePreferences is used.
+	CurvePreferences []CurveID
 }
 
 type ServerRule interface {
@@ -365,6 +480,17 @@ type Config struct {
 	// default multiply certificates policy for tls server
 	MultiCert MultiCertificate
 
+	// GetCertificate returns a Certificate based on the given
+	// ClientHelloInfo. It is only called in server side, and takes
+	// precedence over MultiCert and NameToCertificate if returns non-nil
+	// Certificate.
+	//
+	// If GetCertificate is not nil, ACME TLS-ALPN-01 challenge is answered
+	// by selecting ACMETLSProtocol for the validation connection. The
+	// challenge certificate should be returned for ClientHelloInfo with
+	// SupportedProtos of ACMETLSProtocol only.
+	GetCertificate func(*ClientHelloInfo) (*Certificate, error)
+
 	// RootCAs defines the set of root certificate authorities
 	// that clients use when verifying server certificates.
 	// If RootCAs is nil, TLS uses the host's root CA set.
@@ -431,6 +557,11 @@ type Config struct {
 	// in SessionTicket
 	SessionTicketKeyName [16]byte
 
+	// SessionTicketKeys is an ordered set of session ticket keys. If it is
+	// not nil and not empty, SessionTicketKey and SessionTicketKeyName are
+	// ignored. Keys may be updated (e.g. rotated) while the config is in use.
+	SessionTicketKeys *SessionTicketKeys
+
 	// SessionCache is a cache of ClientSessionState entries for TLS session
 	// resumption.
 	ClientSessionCache ClientSessionCache
@@ -449,7 +580,7 @@ type Config struct {
 
 	// MaxVersion contains the maximum SSL/TLS version that is acceptable.
 	// If zero, then the maximum version supported by this package is used,
-	// which is currently TLS 1.2.
+	// which is currently TLS 1.3 (TLS 1.2 in client side).
 	MaxVersion uint16
 
 	// CurvePreferences contains the elliptic curves that will be used in
@@ -481,6 +612,7 @@ func (c *Config) Clone() *Config {
 		NameToCertificate:        c.NameToCertificate,
 		VerifyPeerCertificate:    c.VerifyPeerCertificate,
 		MultiCert:                c.MultiCert,
+		GetCertificate:           c.GetCertificate,
 		RootCAs:                  c.RootCAs,
 		NextProtos:               c.NextProtos,
 		ServerName:               c.ServerName,
@@ -494,6 +626,7 @@ func (c *Config) Clone() *Config {
 		SessionTicketsDisabled:   c.SessionTicketsDisabled,
 		SessionTicketKey:         c.SessionTicketKey,
 		SessionTicketKeyName:     c.SessionTicketKeyName,
+		SessionTicketKeys:        c.SessionTicketKeys,
 		ClientSessionCache:       c.ClientSessionCache,
 		ServerSessionCache:       c.ServerSessionCache,
 		SessionCacheDisabled:     c.SessionCacheDisabled,
@@ -522,6 +655,17 @@ func (c *Config) serverInit() {
 	}
 }
 
+// ticketKeys returns keys for session ticket. The first key is the active
+// key for encrypting tickets.
+func (c *Config) ticketKeys() []TicketKey {
+	if c.SessionTicketKeys != nil {
+		if keys := c.SessionTicketKeys.get(); len(keys) > 0 {
+			return keys
+		}
+	}
+	return []TicketKey{{Name: c.SessionTicketKeyName, Key: c.SessionTicketKey}}
+}
+
 func (c *Config) rand() io.Reader {
 	r := c.Rand
 	if r == nil {
@@ -560,7 +704,7 @@ func (c *Config) maxVersion() uint16 {
 	return c.MaxVersion
 }
 
-var defaultCurvePreferences = []CurveID{CurveP256, CurveP384, CurveP521}
+var defaultCurvePreferences = []CurveID{X25519MLKEM768, X25519, CurveP256, CurveP384, CurveP521}
 
 func (c *Config) curvePreferences() []CurveID {
 	if c == nil || len(c.CurvePreferences) == 0 {
@@ -569,21 +713,65 @@ func (c *Config) curvePreferences() []CurveID {
 	return c.CurvePreferences
 }
 
+// legacyCurvePreferences returns the preferred curves which could be used
+// in TLS 1.2 and earlier, since X25519 is only supported in TLS 1.3.
+func (c *Config) legacyCurvePreferences() []CurveID {
+	return legacyCurves(c.curvePreferences())
+}
+
+func legacyCurves(curvePreferences []CurveID) []CurveID {
+	curves := make([]CurveID, 0, len(curvePreferences))
+	for _, curve := range curvePreferences {
+		if _, ok := curveForCurveID(curve); ok {
+			curves = append(curves, curve)
+		}
+	}
+	return curves
+}
+
+// maxLegacyVersion returns the maximum version which could be negotiated
+// without supported_versions extension, which is also the maximum version
+// in client side.
+func (c *Config) maxLegacyVersion() uint16 {
+	maxVersion := c.maxVersion()
+	if maxVersion > VersionTLS12 {
+		maxVersion = VersionTLS12
+	}
+	return maxVersion
+}
+
 // mutualVersion returns the protocol version to use given the advertised
 // version of the peer.
 func (c *Config) mutualVersion(vers uint16) (uint16, bool) {
 	minVersion := c.minVersion()
-	maxVersion := c.maxVersion()
+	maxVersion := c.maxLegacyVersion()
 
-	if vers < minVersion {
-		return 0, false
-	}
 	if vers > maxVersion {
 		vers = maxVersion
 	}
+	if vers < minVersion {
+		return 0, false
+	}
 	return vers, true
 }
 
+// mutualSupportedVersion returns the protocol version to use given the
+// versions in supported_versions extension of the peer.
+// See RFC 8446, Section 4.2.1
+func (c *Config) mutualSupportedVersion(versions []uint16) (uint16, bool) {
+	minVersion := c.minVersion()
+	maxVersion := c.maxVersion()
+
+	var vers uint16
+	for _, v := range versions {
+		// Note: GREASE values are ignored since they are out of range
+		if v >= minVersion && v <= maxVersion && v > vers {
+			vers = v
+		}
+	}
+	return vers, vers != 0
+}
+
 // followed the rule defined in www.ssllabs.com:
 // in Grade "A+", ssl version older than tls1.2 is not allowed
 // in Grade "A", ssl version older than tls1.0 is not allowed
@@ -688,7 +876,11 @@ func (c *Config) BuildNameToCertificate() {
 // A Certificate is a chain of one or more certificates, leaf first.
 type Certificate struct {
 	Certificate [][]byte
-	PrivateKey  crypto.PrivateKey // supported types: *rsa.PrivateKey, *ecdsa.PrivateKey
+	// PrivateKey contains the private key corresponding to the public key in
+	// Leaf. Supported types: *rsa.PrivateKey, *ecdsa.PrivateKey, or other
+	// crypto.Signer (and crypto.Decrypter for RSA key exchange) whose private
+	// part may be remote, eg. key of keyless tls.
+	PrivateKey crypto.PrivateKey
 	// OCSPStaple contains an optional OCSP response which will be served
 	// to clients that request it.
 	OCSPStaple []byte
@@ -833,6 +1025,7 @@ var versionTextMap = map[uint16]string{
 	VersionTLS10: "TLS_VERSION_TLS10",
 	VersionTLS11: "TLS_VERSION_TLS11",
 	VersionTLS12: "TLS_VERSION_TLS12",
+	VersionTLS13: "TLS_VERSION_TLS13",
 }
 
 func VersionText(ver uint16) string {
@@ -848,6 +1041,7 @@ var versionTextMapForOpenSSL = map[uint16]string{
 	VersionTLS10: "TLSv1.0",
 	VersionTLS11: "TLSv1.1",
 	VersionTLS12: "TLSv1.2",
+	VersionTLS13: "TLSv1.3",
 }
 
 func VersionTextForOpenSSL(ver uint16) string {
@@ -857,6 +1051,23 @@ func VersionTextForOpenSSL(ver uint16) string {
 	return fmt.Sprintf("TLS_VERSION_%x", ver)
 }
 
+// Downgrade protection sentinels in the last 8 bytes of server random, if
+// TLS 1.3 is supported but an older version is negotiated.
+// See RFC 8446, Section 4.1.3.
+const (
+	downgradeCanaryTLS12 = "DOWNGRD\x01"
+	downgradeCanaryTLS11 = "DOWNGRD\x00"
+)
+
+// helloRetryRequestRandom is set as the random of ServerHello to indicate
+// the message is a HelloRetryRequest. See RFC 8446, Section 4.1.3.
+var helloRetryRequestRandom = []byte{
+	0xCF, 0x21, 0xAD, 0x74, 0xE5, 0x9A, 0x61, 0x11,
+	0xBE, 0x1D, 0x8C, 0x02, 0x1E, 0x65, 0xB8, 0x91,
+	0xC2, 0xA2, 0x11, 0x16, 0x7A, 0xBB, 0x8C, 0x5E,
+	0x07, 0x9E, 0x09, 0xE2, 0xC8, 0xA8, 0x33, 0x9C,
+}
+
 var (
 	helloRandomMagicNum []byte = []byte{66, 73, 68, 85}
 	helloRandomFormat   int
@@ -874,6 +1085,9 @@ func generateHelloRandom(rand io.Reader) ([]byte, error) {
 	return random, nil
 }
 
+// OcspTimeTolerance is tolerant time for clock skew in OcspTimeRangeCheck.
+const OcspTimeTolerance = time.Duration(3600) * time.Second
+
 // OcspTimeRangeCheck check ocsp time update range
 func OcspTimeRangeCheck(parse *ocsp.Response) bool {
 	serverTime := time.Now()
@@ -881,7 +1095,7 @@ func OcspTimeRangeCheck(parse *ocsp.Response) bool {
 	thisUpdate := parse.ThisUpdate
 
 	// default tolerant time, one hour
-	deltaTime := time.Duration(3600) * time.Second
+	deltaTime := OcspTimeTolerance
 
 	// serverTime should be [thisUpdate+deltaTime, nextUpdate-deltaTime]
 	if serverTime.Sub(thisUpdate) < deltaTime || nextUpdate.Sub(serverTime) < deltaTime {
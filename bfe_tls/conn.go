@@ -69,15 +69,23 @@ type Conn struct {
 	grade               string         // tls security grade, usually is "A", "B", "C"
 	clientAuth          ClientAuthType // tls client auth type
 	clientCAs           *x509.CertPool
-	clientCAName        string   // tls client CA name
-	clientCRLPool       *CRLPool // tls client CRL pool
-	enableDynamicRecord bool     // enable dynamic record size or not
-	clientRandom        []byte   // random in client hello msg
-	serverRandom        []byte   // random in server hello msg
-	masterSecret        []byte   // master secret for conn
-	clientCiphers       []uint16 // ciphers supported by client
-	ja3Raw              string   // JA3 fingerprint string for TLS Client
-	ja3Hash             string   // JA3 fingerprint hash for TLS Client
+	clientCAName        string            // tls client CA name
+	clientCRLPool       *CRLPool          // tls client CRL pool
+	enableDynamicRecord bool              // enable dynamic record size or not
+	clientRandom        []byte            // random in client hello msg
+	serverRandom        []byte            // random in server hello msg
+	masterSecret        []byte            // master secret for conn
+	clientCiphers       []uint16          // ciphers supported by client
+	ja3Raw              string            // JA3 fingerprint string for TLS Client
+	ja3Hash             string            // JA3 fingerprint hash for TLS Client
+	ja4                 string            // JA4 fingerprint for TLS Client
+	trafficSecrets      map[string][]byte // TLS 1.3 traffic secrets for conn
+	skipEarlyData       bool              // skip 0-RTT data offered by client (TLS 1.3)
+	earlyDataSkipped    int               // bytes of 0-RTT data skipped
+	curvePreferences    []CurveID         // curve preferences for current conn (in server side)
+	curveID             CurveID           // key exchange group used by the conn
+
+	clientRevocation *RevocationChecker // online revocation checking of tls client cert
 
 	clientProtocol         string
 	clientProtocolFallback bool
@@ -184,6 +192,8 @@ type halfConn struct {
 	nextCipher interface{} // next encryption state
 	nextMac    macFunction // next MAC algorithm
 
+	trafficSecret []byte // current TLS 1.3 traffic secret
+
 	// used to save allocating a new buffer for each MAC.
 	inDigestBuf, outDigestBuf []byte
 }
@@ -224,6 +234,20 @@ func (hc *halfConn) changeCipherSpec() error {
 	return nil
 }
 
+// setTrafficSecret sets the encryption state from a TLS 1.3 traffic secret.
+// Unlike changeCipherSpec, it takes effect immediately.
+// See RFC 8446, Section 7.3
+func (hc *halfConn) setTrafficSecret(suite *cipherSuiteTLS13, secret []byte) {
+	hc.trafficSecret = secret
+	key, iv := suite.trafficKey(secret)
+	hc.version = VersionTLS13
+	hc.cipher = suite.aead(key, iv)
+	hc.mac = nil
+	hc.nextCipher = nil
+	hc.nextMac = nil
+	hc.resetSeq()
+}
+
 // incSeq increments the sequence number.
 func (hc *halfConn) incSeq() {
 	for i := 7; i >= 0; i-- {
@@ -314,6 +338,10 @@ type cbcMode interface {
 // success boolean, the number of bytes to skip from the start of the record in
 // order to get the application payload, and an optional alert value.
 func (hc *halfConn) decrypt(b *block) (ok bool, prefixLen int, alertValue alert) {
+	if hc.version == VersionTLS13 && hc.cipher != nil {
+		return hc.decryptTLS13(b)
+	}
+
 	// pull out payload
 	payload := b.data[recordHeaderLen:]
 
@@ -414,6 +442,43 @@ func (hc *halfConn) decrypt(b *block) (ok bool, prefixLen int, alertValue alert)
 	return true, recordHeaderLen + explicitIVLen, 0
 }
 
+// decryptTLS13 decrypts a TLS 1.3 record in b, and replaces the outer
+// content type in the record header with the inner one.
+// See RFC 8446, Section 5.2
+func (hc *halfConn) decryptTLS13(b *block) (ok bool, prefixLen int, alertValue alert) {
+	if recordType(b.data[0]) != recordTypeApplicationData {
+		return false, 0, alertUnexpectedMessage
+	}
+
+	c := hc.cipher.(aead)
+	payload := b.data[recordHeaderLen:]
+	if len(payload) < c.Overhead() {
+		return false, 0, alertBadRecordMAC
+	}
+	if len(payload) > maxPlaintext+1+c.Overhead() {
+		return false, 0, alertRecordOverflow
+	}
+
+	plaintext, err := c.Open(payload[:0], hc.seq[:], payload, b.data[:recordHeaderLen])
+	if err != nil {
+		return false, 0, alertBadRecordMAC
+	}
+
+	// strip zero padding and get the real content type
+	i := len(plaintext) - 1
+	for i >= 0 && plaintext[i] == 0 {
+		i--
+	}
+	if i < 0 {
+		return false, 0, alertUnexpectedMessage
+	}
+	b.data[0] = plaintext[i]
+	b.resize(recordHeaderLen + i)
+	hc.incSeq()
+
+	return true, recordHeaderLen, 0
+}
+
 // padToBlockSize calculates the needed padding block, if any, for a payload.
 // On exit, prefix aliases payload and extends to the end of the last full
 // block of payload. finalBlock is a fresh slice which contains the contents of
@@ -433,6 +498,10 @@ func padToBlockSize(payload []byte, blockSize int) (prefix, finalBlock []byte) {
 
 // encrypt encrypts and macs the data in b.
 func (hc *halfConn) encrypt(b *block, explicitIVLen int) (bool, alert) {
+	if hc.version == VersionTLS13 && hc.cipher != nil {
+		return hc.encryptTLS13(b)
+	}
+
 	// mac
 	if hc.mac != nil {
 		mac := hc.mac.MAC(hc.outDigestBuf, hc.seq[0:], b.data[:recordHeaderLen], b.data[recordHeaderLen+explicitIVLen:])
@@ -491,6 +560,29 @@ func (hc *halfConn) encrypt(b *block, explicitIVLen int) (bool, alert) {
 	return true, 0
 }
 
+// encryptTLS13 encrypts the data in b as a TLS 1.3 record, with the real
+// content type appended to the plaintext. See RFC 8446, Section 5.2
+func (hc *halfConn) encryptTLS13(b *block) (bool, alert) {
+	c := hc.cipher.(aead)
+
+	n := len(b.data)
+	b.resize(n + 1 + c.Overhead())
+	b.data[n] = b.data[0]
+	b.data[0] = byte(recordTypeApplicationData)
+
+	payloadLen := n + 1 - recordHeaderLen
+	length := payloadLen + c.Overhead()
+	b.data[3] = byte(length >> 8)
+	b.data[4] = byte(length)
+
+	// the record header (with final length) is the additional data
+	payload := b.data[recordHeaderLen : recordHeaderLen+payloadLen]
+	c.Seal(payload[:0], hc.seq[:], payload, b.data[:recordHeaderLen])
+	hc.incSeq()
+
+	return true, 0
+}
+
 // A block is a simple data buffer.
 type block struct {
 	data []byte
@@ -739,7 +831,8 @@ func (c *Conn) readRecord(want recordType) error {
 		c.sendAlert(alertInternalError)
 		return c.in.setErrorLocked(errors.New("tls: unknown record type requested"))
 	case recordTypeHandshake, recordTypeChangeCipherSpec:
-		if c.handshakeComplete {
+		// Note: post-handshake messages are allowed in TLS 1.3
+		if c.handshakeComplete && !(c.vers == VersionTLS13 && want == recordTypeHandshake) {
 			c.sendAlert(alertInternalError)
 			return c.in.setErrorLocked(errors.New("tls: handshake or ChangeCipherSpec requested after handshake complete"))
 		}
@@ -788,7 +881,8 @@ Again:
 
 	vers := uint16(b.data[1])<<8 | uint16(b.data[2])
 	n := int(b.data[3])<<8 | int(b.data[4])
-	if c.haveVers && vers != c.vers {
+	// Note: record version is frozen at TLS 1.2 in TLS 1.3
+	if c.haveVers && c.vers != VersionTLS13 && vers != c.vers {
 		c.sendAlert(alertProtocolVersion)
 		return c.in.setErrorLocked(fmt.Errorf("tls: received record with version %x when expecting version %x", vers, c.vers))
 	}
@@ -822,11 +916,44 @@ Again:
 
 	// Process message.
 	b, c.rawInput = c.in.splitBlock(b, recordHeaderLen+n)
+
+	if c.vers == VersionTLS13 {
+		// In TLS 1.3, an unencrypted ChangeCipherSpec may be sent for
+		// middlebox compatibility, and must be dropped during handshake.
+		// See RFC 8446, Section 5
+		if typ == recordTypeChangeCipherSpec {
+			data := b.data[recordHeaderLen:]
+			if c.handshakeComplete || len(data) != 1 || data[0] != 1 {
+				c.in.freeBlock(b)
+				return c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
+			}
+			c.in.freeBlock(b)
+			goto Again
+		}
+
+		// 0-RTT data is never accepted, skip it. See RFC 8446, Section 4.2.10
+		if c.skipEarlyData && typ == recordTypeApplicationData && c.in.cipher == nil {
+			c.in.freeBlock(b)
+			if err := c.chargeEarlyData(n); err != nil {
+				return err
+			}
+			goto Again
+		}
+	}
+
 	ok, off, err := c.in.decrypt(b)
 	if !ok {
+		if c.skipEarlyData && typ == recordTypeApplicationData {
+			c.in.freeBlock(b)
+			if err := c.chargeEarlyData(n); err != nil {
+				return err
+			}
+			goto Again
+		}
 		c.in.setErrorLocked(c.sendAlert(err))
 	}
 	b.off = off
+	typ = recordType(b.data[0]) // inner content type in TLS 1.3
 	data := b.data[b.off:]
 	if len(data) > maxPlaintext {
 		err := c.sendAlert(alertRecordOverflow)
@@ -878,7 +1005,7 @@ Again:
 
 	case recordTypeHandshake:
 		// TODO(rsc): Should at least pick off connection close.
-		if typ != want {
+		if typ != want && !(c.vers == VersionTLS13 && c.handshakeComplete) {
 			return c.in.setErrorLocked(c.sendAlert(alertNoRenegotiation))
 		}
 		c.hand.Write(data)
@@ -993,6 +1120,9 @@ func (c *Conn) writeRecord(typ recordType, data []byte) (n int, err error) {
 			// Some TLS servers fail if the record version is
 			// greater than TLS 1.0 for the initial ClientHello.
 			vers = VersionTLS10
+		} else if vers == VersionTLS13 {
+			// Record version is frozen at TLS 1.2 in TLS 1.3
+			vers = VersionTLS12
 		}
 		b.data[1] = byte(vers >> 8)
 		b.data[2] = byte(vers)
@@ -1019,7 +1149,8 @@ func (c *Conn) writeRecord(typ recordType, data []byte) (n int, err error) {
 	}
 	c.out.freeBlock(b)
 
-	if typ == recordTypeChangeCipherSpec {
+	// Note: ChangeCipherSpec is only for middlebox compatibility in TLS 1.3
+	if typ == recordTypeChangeCipherSpec && c.vers != VersionTLS13 {
 		err = c.out.changeCipherSpec()
 		if err != nil {
 			// Cannot call sendAlert directly,
@@ -1075,7 +1206,11 @@ func (c *Conn) readHandshake() (interface{}, error) {
 	case typeNewSessionTicket:
 		m = new(newSessionTicketMsg)
 	case typeCertificate:
-		m = new(certificateMsg)
+		if c.vers == VersionTLS13 {
+			m = new(certificateMsgTLS13)
+		} else {
+			m = new(certificateMsg)
+		}
 	case typeCertificateRequest:
 		m = &certificateRequestMsg{
 			hasSignatureAndHash: c.vers >= VersionTLS12,
@@ -1096,6 +1231,8 @@ func (c *Conn) readHandshake() (interface{}, error) {
 		m = new(nextProtoMsg)
 	case typeFinished:
 		m = new(finishedMsg)
+	case typeKeyUpdate:
+		m = new(keyUpdateMsg)
 	default:
 		return nil, c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
 	}
@@ -1176,6 +1313,11 @@ func (c *Conn) Read(b []byte) (n int, err error) {
 				// Soft error, like EAGAIN
 				return 0, err
 			}
+			for c.hand.Len() > 0 {
+				if err := c.handlePostHandshakeMessage(); err != nil {
+					return 0, err
+				}
+			}
 		}
 		if err := c.in.err; err != nil {
 			return 0, err
@@ -1214,6 +1356,60 @@ func (c *Conn) Read(b []byte) (n int, err error) {
 	return 0, io.ErrNoProgress
 }
 
+// chargeEarlyData charges n bytes of skipped 0-RTT data against
+// maxEarlyDataSize. Connection is aborted if it is exceeded.
+// See RFC 8446, Section 4.2.10
+func (c *Conn) chargeEarlyData(n int) error {
+	c.earlyDataSkipped += n
+	if c.earlyDataSkipped > maxEarlyDataSize {
+		c.sendAlert(alertUnexpectedMessage)
+		return c.in.setErrorLocked(errors.New("tls: too much early data skipped"))
+	}
+	return nil
+}
+
+// handlePostHandshakeMessage processes a handshake message arrived after the
+// handshake is complete. Only KeyUpdate is expected from TLS 1.3 client.
+// c.in.Mutex <= L.
+func (c *Conn) handlePostHandshakeMessage() error {
+	if c.vers != VersionTLS13 || c.isClient {
+		return c.in.setErrorLocked(c.sendAlert(alertNoRenegotiation))
+	}
+
+	msg, err := c.readHandshake()
+	if err != nil {
+		return err
+	}
+	keyUpdate, ok := msg.(*keyUpdateMsg)
+	if !ok {
+		return c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
+	}
+	// key update must be at the end of record. See RFC 8446, Section 5.1
+	if c.hand.Len() > 0 {
+		return c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
+	}
+
+	// update traffic secret. See RFC 8446, Section 4.6.3
+	suite := cipherSuiteTLS13ByID(c.cipherSuite)
+	if suite == nil {
+		return c.in.setErrorLocked(c.sendAlert(alertInternalError))
+	}
+	c.in.setTrafficSecret(suite, suite.nextTrafficSecret(c.in.trafficSecret))
+
+	if keyUpdate.updateRequested {
+		c.out.Lock()
+		defer c.out.Unlock()
+
+		msg := &keyUpdateMsg{}
+		if _, err := c.writeRecord(recordTypeHandshake, msg.marshal()); err != nil {
+			return c.out.setErrorLocked(err)
+		}
+		c.out.setTrafficSecret(suite, suite.nextTrafficSecret(c.out.trafficSecret))
+	}
+
+	return nil
+}
+
 // Close closes the connection.
 func (c *Conn) Close() error {
 	var alertErr error
@@ -1280,6 +1476,9 @@ func (c *Conn) ConnectionState() ConnectionState {
 		state.ClientCAName = c.clientCAName
 		state.JA3Raw = c.ja3Raw
 		state.JA3Hash = c.ja3Hash
+		state.JA4 = c.ja4
+		state.TrafficSecrets = c.trafficSecrets
+		state.CurveID = c.curveID
 	}
 
 	return state
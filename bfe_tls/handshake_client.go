@@ -53,12 +53,12 @@ func (c *Conn) clientHandshake() error {
 	}
 
 	hello := &clientHelloMsg{
-		vers:                c.config.maxVersion(),
+		vers:                c.config.maxLegacyVersion(),
 		compressionMethods:  []uint8{compressionNone},
 		random:              make([]byte, 32),
 		ocspStapling:        true,
 		serverName:          c.config.ServerName,
-		supportedCurves:     c.config.curvePreferences(),
+		supportedCurves:     c.config.legacyCurvePreferences(),
 		supportedPoints:     []uint8{pointFormatUncompressed},
 		nextProtoNeg:        len(c.config.NextProtos) > 0,
 		secureRenegotiation: true,
@@ -119,7 +119,7 @@ NextCipherSuite:
 			}
 
 			versOk := candidateSession.vers >= c.config.minVersion() &&
-				candidateSession.vers <= c.config.maxVersion()
+				candidateSession.vers <= c.config.maxLegacyVersion()
 			if versOk && cipherSuiteOk {
 				session = candidateSession
 			}
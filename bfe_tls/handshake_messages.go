@@ -20,7 +20,11 @@ package bfe_tls
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"sort"
+	"strings"
 )
 
 type clientHelloMsg struct {
@@ -42,6 +46,15 @@ type clientHelloMsg struct {
 	alpnProtocols       []string
 	padding             bool
 	extensionIds        []uint16
+
+	// extensions for TLS 1.3
+	supportedVersions []uint16
+	keyShares         []keyShare
+	earlyData         bool
+	pskModes          []uint8
+	pskIdentities     []pskIdentity
+	pskBinders        [][]byte
+	cookie            []byte
 }
 
 func (m *clientHelloMsg) equal(i interface{}) bool {
@@ -131,6 +144,148 @@ func writeJA3Uint16Values(buf *bytes.Buffer, values []uint16) {
 	}
 }
 
+// JA4String returns a JA4 fingerprint for TLS client over TCP.
+// For more information, see https://github.com/FoxIO-LLC/ja4
+func (m *clientHelloMsg) JA4String() string {
+	var buf bytes.Buffer
+
+	// GREASE values are ignored, as JA3 does
+	ciphers := filterGreaseVals(m.cipherSuites)
+	extensions := filterGreaseVals(m.extensionIds)
+	sigAlgs := make([]uint16, 0, len(m.signatureAndHashes))
+	for _, sigAndHash := range m.signatureAndHashes {
+		sigAlgs = append(sigAlgs, uint16(sigAndHash.hash)<<8|uint16(sigAndHash.signature))
+	}
+	sigAlgs = filterGreaseVals(sigAlgs)
+
+	// protocol and version: highest version in supported_versions extension,
+	// or version in client hello
+	vers := m.vers
+	if versions := filterGreaseVals(m.supportedVersions); len(versions) > 0 {
+		vers = 0
+		for _, v := range versions {
+			if v > vers {
+				vers = v
+			}
+		}
+	}
+	buf.WriteString("t")
+	buf.WriteString(ja4Version(vers))
+
+	// sni: to domain or ip
+	sni := "i"
+	for _, ext := range extensions {
+		if ext == extensionServerName {
+			sni = "d"
+		}
+	}
+	buf.WriteString(sni)
+
+	// number of cipher suites and extensions
+	fmt.Fprintf(&buf, "%02d%02d", min(len(ciphers), 99), min(len(extensions), 99))
+
+	// first and last characters of first alpn protocol
+	buf.WriteString(ja4Alpn(m.alpnProtocols))
+
+	// sorted cipher suites
+	buf.WriteByte('_')
+	if len(ciphers) == 0 {
+		buf.WriteString("000000000000")
+	} else {
+		buf.WriteString(ja4Hash(sortUint16s(ciphers), nil))
+	}
+
+	// sorted extensions (except sni and alpn), and signature algorithms
+	buf.WriteByte('_')
+	if len(extensions) == 0 {
+		buf.WriteString("000000000000")
+	} else {
+		exts := make([]uint16, 0, len(extensions))
+		for _, ext := range extensions {
+			if ext != extensionServerName && ext != extensionALPN {
+				exts = append(exts, ext)
+			}
+		}
+		buf.WriteString(ja4Hash(sortUint16s(exts), sigAlgs))
+	}
+
+	return buf.String()
+}
+
+func filterGreaseVals(values []uint16) []uint16 {
+	result := make([]uint16, 0, len(values))
+	for _, value := range values {
+		if !isGreaseVal(value) {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+func sortUint16s(values []uint16) []uint16 {
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	return values
+}
+
+func ja4Version(vers uint16) string {
+	switch vers {
+	case VersionTLS13:
+		return "13"
+	case VersionTLS12:
+		return "12"
+	case VersionTLS11:
+		return "11"
+	case VersionTLS10:
+		return "10"
+	case VersionSSL30:
+		return "s3"
+	case 0x0002:
+		return "s2"
+	default:
+		return "00"
+	}
+}
+
+func ja4Alpn(protos []string) string {
+	if len(protos) == 0 || len(protos[0]) == 0 {
+		return "00"
+	}
+
+	proto := protos[0]
+	first, last := proto[0], proto[len(proto)-1]
+	if !isAlphanumeric(first) || !isAlphanumeric(last) {
+		// use first and last characters of hex representation instead
+		h := hex.EncodeToString([]byte(proto))
+		first, last = h[0], h[len(h)-1]
+	}
+	return string([]byte{first, last})
+}
+
+func isAlphanumeric(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// ja4Hash returns truncated sha256 hash of values in hex, followed by
+// suffix values if any.
+func ja4Hash(values []uint16, suffix []uint16) string {
+	list := make([]string, 0, len(values))
+	for _, value := range values {
+		list = append(list, fmt.Sprintf("%04x", value))
+	}
+	str := strings.Join(list, ",")
+
+	if len(suffix) > 0 {
+		list = list[:0]
+		for _, value := range suffix {
+			list = append(list, fmt.Sprintf("%04x", value))
+		}
+		str += "_" + strings.Join(list, ",")
+	}
+
+	sum := sha256.Sum256([]byte(str))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
 func (m *clientHelloMsg) marshal() []byte {
 	if m.raw != nil {
 		return m.raw
@@ -408,6 +563,13 @@ func (m *clientHelloMsg) unmarshal(data []byte) bool {
 	m.sessionTicket = nil
 	m.signatureAndHashes = nil
 	m.alpnProtocols = nil
+	m.supportedVersions = nil
+	m.keyShares = nil
+	m.earlyData = false
+	m.pskModes = nil
+	m.pskIdentities = nil
+	m.pskBinders = nil
+	m.cookie = nil
 
 	m.extensionIds = make([]uint16, 0)
 	if len(data) == 0 {
@@ -539,6 +701,78 @@ func (m *clientHelloMsg) unmarshal(data []byte) bool {
 			}
 		case extensionPadding:
 			m.padding = true
+		case extensionSupportedVersions:
+			// RFC 8446, Section 4.2.1
+			if length < 1 {
+				return false
+			}
+			l := int(data[0])
+			if l%2 == 1 || length != l+1 {
+				return false
+			}
+			d := data[1:length]
+			for len(d) != 0 {
+				m.supportedVersions = append(m.supportedVersions, uint16(d[0])<<8|uint16(d[1]))
+				d = d[2:]
+			}
+		case extensionKeyShare:
+			// RFC 8446, Section 4.2.8
+			if length < 2 {
+				return false
+			}
+			l := int(data[0])<<8 | int(data[1])
+			if length != l+2 {
+				return false
+			}
+			d := data[2:length]
+			for len(d) != 0 {
+				if len(d) < 4 {
+					return false
+				}
+				group := CurveID(d[0])<<8 | CurveID(d[1])
+				keyLen := int(d[2])<<8 | int(d[3])
+				d = d[4:]
+				if keyLen == 0 || len(d) < keyLen {
+					return false
+				}
+				m.keyShares = append(m.keyShares, keyShare{group: group, data: d[:keyLen]})
+				d = d[keyLen:]
+			}
+		case extensionEarlyData:
+			// RFC 8446, Section 4.2.10
+			if length != 0 {
+				return false
+			}
+			m.earlyData = true
+		case extensionPSKModes:
+			// RFC 8446, Section 4.2.9
+			if length < 1 {
+				return false
+			}
+			l := int(data[0])
+			if length != l+1 {
+				return false
+			}
+			m.pskModes = make([]uint8, l)
+			copy(m.pskModes, data[1:length])
+		case extensionCookie:
+			// RFC 8446, Section 4.2.2
+			if length < 2 {
+				return false
+			}
+			l := int(data[0])<<8 | int(data[1])
+			if l == 0 || length != l+2 {
+				return false
+			}
+			m.cookie = data[2:length]
+		case extensionPreSharedKey:
+			// RFC 8446, Section 4.2.11
+			if len(data) != length {
+				return false // pre_shared_key must be the last extension
+			}
+			if !m.unmarshalPreSharedKey(data[:length]) {
+				return false
+			}
 		}
 		data = data[length:]
 	}
@@ -546,6 +780,67 @@ func (m *clientHelloMsg) unmarshal(data []byte) bool {
 	return true
 }
 
+// unmarshalPreSharedKey parses identities and binders in pre_shared_key
+// extension of ClientHello.
+func (m *clientHelloMsg) unmarshalPreSharedKey(data []byte) bool {
+	if len(data) < 2 {
+		return false
+	}
+	l := int(data[0])<<8 | int(data[1])
+	if l == 0 || len(data) < 2+l {
+		return false
+	}
+	d := data[2 : 2+l]
+	for len(d) != 0 {
+		if len(d) < 2 {
+			return false
+		}
+		labelLen := int(d[0])<<8 | int(d[1])
+		d = d[2:]
+		if labelLen == 0 || len(d) < labelLen+4 {
+			return false
+		}
+		identity := pskIdentity{label: d[:labelLen]}
+		d = d[labelLen:]
+		identity.obfuscatedTicketAge = uint32(d[0])<<24 | uint32(d[1])<<16 | uint32(d[2])<<8 | uint32(d[3])
+		d = d[4:]
+		m.pskIdentities = append(m.pskIdentities, identity)
+	}
+
+	d = data[2+l:]
+	if len(d) < 2 {
+		return false
+	}
+	l = int(d[0])<<8 | int(d[1])
+	if l == 0 || len(d) != 2+l {
+		return false
+	}
+	d = d[2:]
+	for len(d) != 0 {
+		binderLen := int(d[0])
+		d = d[1:]
+		if binderLen < 32 || len(d) < binderLen {
+			return false
+		}
+		m.pskBinders = append(m.pskBinders, d[:binderLen])
+		d = d[binderLen:]
+	}
+
+	return len(m.pskIdentities) == len(m.pskBinders)
+}
+
+// marshalWithoutBinders returns the ClientHello truncated before the binders
+// list, which is the transcript for computing PSK binders.
+// See RFC 8446, Section 4.2.11.2
+func (m *clientHelloMsg) marshalWithoutBinders() []byte {
+	bindersLen := 2
+	for _, binder := range m.pskBinders {
+		bindersLen += 1 + len(binder)
+	}
+	raw := m.marshal()
+	return raw[:len(raw)-bindersLen]
+}
+
 type serverHelloMsg struct {
 	raw                 []byte
 	vers                uint16
@@ -559,6 +854,15 @@ type serverHelloMsg struct {
 	ticketSupported     bool
 	secureRenegotiation bool
 	alpnProtocol        string
+
+	// extensions for TLS 1.3
+	supportedVersion        uint16
+	serverShare             keyShare
+	selectedIdentityPresent bool
+	selectedIdentity        uint16
+
+	// HelloRetryRequest extensions
+	selectedGroup CurveID
 }
 
 func (m *serverHelloMsg) equal(i interface{}) bool {
@@ -578,7 +882,13 @@ func (m *serverHelloMsg) equal(i interface{}) bool {
 		m.ocspStapling == m1.ocspStapling &&
 		m.ticketSupported == m1.ticketSupported &&
 		m.secureRenegotiation == m1.secureRenegotiation &&
-		m.alpnProtocol == m1.alpnProtocol
+		m.alpnProtocol == m1.alpnProtocol &&
+		m.supportedVersion == m1.supportedVersion &&
+		m.serverShare.group == m1.serverShare.group &&
+		bytes.Equal(m.serverShare.data, m1.serverShare.data) &&
+		m.selectedIdentityPresent == m1.selectedIdentityPresent &&
+		m.selectedIdentity == m1.selectedIdentity &&
+		m.selectedGroup == m1.selectedGroup
 }
 
 func (m *serverHelloMsg) marshal() []byte {
@@ -616,6 +926,22 @@ func (m *serverHelloMsg) marshal() []byte {
 		extensionsLength += 2 + 1 + alpnLen
 		numExtensions++
 	}
+	if m.supportedVersion != 0 {
+		extensionsLength += 2
+		numExtensions++
+	}
+	if m.serverShare.group != 0 {
+		extensionsLength += 4 + len(m.serverShare.data)
+		numExtensions++
+	}
+	if m.selectedIdentityPresent {
+		extensionsLength += 2
+		numExtensions++
+	}
+	if m.selectedGroup != 0 {
+		extensionsLength += 2
+		numExtensions++
+	}
 
 	if numExtensions > 0 {
 		extensionsLength += 4 * numExtensions
@@ -691,6 +1017,48 @@ func (m *serverHelloMsg) marshal() []byte {
 		copy(z[7:], []byte(m.alpnProtocol))
 		z = z[7+alpnLen:]
 	}
+	if m.supportedVersion != 0 {
+		// RFC 8446, Section 4.2.1
+		z[0] = byte(extensionSupportedVersions >> 8)
+		z[1] = byte(extensionSupportedVersions)
+		z[3] = 2
+		z[4] = byte(m.supportedVersion >> 8)
+		z[5] = byte(m.supportedVersion)
+		z = z[6:]
+	}
+	if m.serverShare.group != 0 {
+		// RFC 8446, Section 4.2.8
+		z[0] = byte(extensionKeyShare >> 8)
+		z[1] = byte(extensionKeyShare)
+		l := 4 + len(m.serverShare.data)
+		z[2] = byte(l >> 8)
+		z[3] = byte(l)
+		z[4] = byte(m.serverShare.group >> 8)
+		z[5] = byte(m.serverShare.group)
+		l -= 4
+		z[6] = byte(l >> 8)
+		z[7] = byte(l)
+		copy(z[8:], m.serverShare.data)
+		z = z[8+l:]
+	}
+	if m.selectedIdentityPresent {
+		// RFC 8446, Section 4.2.11
+		z[0] = byte(extensionPreSharedKey >> 8)
+		z[1] = byte(extensionPreSharedKey)
+		z[3] = 2
+		z[4] = byte(m.selectedIdentity >> 8)
+		z[5] = byte(m.selectedIdentity)
+		z = z[6:]
+	}
+	if m.selectedGroup != 0 {
+		// key_share in HelloRetryRequest, RFC 8446, Section 4.2.8
+		z[0] = byte(extensionKeyShare >> 8)
+		z[1] = byte(extensionKeyShare)
+		z[3] = 2
+		z[4] = byte(m.selectedGroup >> 8)
+		z[5] = byte(m.selectedGroup)
+		z = z[6:]
+	}
 
 	m.raw = x
 
@@ -722,6 +1090,11 @@ func (m *serverHelloMsg) unmarshal(data []byte) bool {
 	m.ocspStapling = false
 	m.ticketSupported = false
 	m.alpnProtocol = ""
+	m.supportedVersion = 0
+	m.serverShare = keyShare{}
+	m.selectedIdentityPresent = false
+	m.selectedIdentity = 0
+	m.selectedGroup = 0
 
 	if len(data) == 0 {
 		// ServerHello is optionally followed by extension data
@@ -792,6 +1165,32 @@ func (m *serverHelloMsg) unmarshal(data []byte) bool {
 			}
 			d = d[1:]
 			m.alpnProtocol = string(d)
+		case extensionSupportedVersions:
+			if length != 2 {
+				return false
+			}
+			m.supportedVersion = uint16(data[0])<<8 | uint16(data[1])
+		case extensionKeyShare:
+			if length == 2 {
+				// HelloRetryRequest
+				m.selectedGroup = CurveID(data[0])<<8 | CurveID(data[1])
+				break
+			}
+			if length < 4 {
+				return false
+			}
+			l := int(data[2])<<8 | int(data[3])
+			if l == 0 || length != l+4 {
+				return false
+			}
+			m.serverShare.group = CurveID(data[0])<<8 | CurveID(data[1])
+			m.serverShare.data = data[4:length]
+		case extensionPreSharedKey:
+			if length != 2 {
+				return false
+			}
+			m.selectedIdentityPresent = true
+			m.selectedIdentity = uint16(data[0])<<8 | uint16(data[1])
 		}
 		data = data[length:]
 	}
@@ -263,6 +263,9 @@ func (*sessionState) Generate(rand *rand.Rand, size int) reflect.Value {
 	for i := 0; i < numCerts; i++ {
 		s.certificates[i] = randomBytes(rand.Intn(10)+1, rand)
 	}
+	if s.vers >= VersionTLS13 {
+		s.createdAt = uint64(rand.Int63())
+	}
 	return reflect.ValueOf(s)
 }
 
@@ -306,3 +309,45 @@ func TestJA3Hash(t *testing.T) {
 		}
 	}
 }
+
+var ja4Tests = []struct {
+	vers               uint16
+	cipherSuites       []uint16
+	extensionIds       []uint16
+	supportedVersions  []uint16
+	alpnProtocols      []string
+	signatureAndHashes []signatureAndHash
+	ja4                string
+}{
+	// chrome, see https://github.com/FoxIO-LLC/ja4
+	{771, []uint16{56026, 4865, 4866, 4867, 49195, 49199, 49196, 49200, 52393, 52392, 49171, 49172, 156, 157, 47, 53},
+		[]uint16{60138, 0, 23, 65281, 10, 11, 35, 16, 5, 13, 18, 51, 45, 43, 27, 17513, 56026, 21},
+		[]uint16{0x7a7a, VersionTLS13, VersionTLS12},
+		[]string{"h2", "http/1.1"},
+		[]signatureAndHash{{4, 3}, {8, 4}, {4, 1}, {5, 3}, {8, 5}, {5, 1}, {8, 6}, {6, 1}},
+		"t13d1516h2_8daaf6152771_e5627efa2ab1"},
+	// without sni, alpn and signature algorithms
+	{VersionTLS12, []uint16{0xc02f, 0x009c}, []uint16{10, 11}, nil, nil, nil,
+		"t12i020200_08dfa304a768_33a13ba74d1c"},
+	// alpn with non-alphanumeric characters
+	{VersionTLS12, []uint16{0xc02f, 0x009c}, []uint16{10, 11, 16}, nil, []string{"\x01h2\xff"}, nil,
+		"t12i02030f_08dfa304a768_33a13ba74d1c"},
+	// without cipher suites and extensions
+	{VersionTLS10, nil, nil, nil, nil, nil,
+		"t10i000000_000000000000_000000000000"},
+}
+
+func TestJA4String(t *testing.T) {
+	for i, d := range ja4Tests {
+		msg := clientHelloMsg{}
+		msg.vers = d.vers
+		msg.cipherSuites = d.cipherSuites
+		msg.extensionIds = d.extensionIds
+		msg.supportedVersions = d.supportedVersions
+		msg.alpnProtocols = d.alpnProtocols
+		msg.signatureAndHashes = d.signatureAndHashes
+		if ja4 := msg.JA4String(); ja4 != d.ja4 {
+			t.Errorf("#%d: unexpected ja4 value %s, expect %s", i, ja4, d.ja4)
+		}
+	}
+}
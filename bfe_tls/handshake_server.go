@@ -62,11 +62,7 @@ func (c *Conn) serverHandshake() error {
 	// encrypt the tickets with.
 	config.serverInitOnce.Do(config.serverInit)
 
-	hs := serverHandshakeState{
-		c: c,
-	}
-
-	isResume, err := hs.readClientHello()
+	clientHello, err := c.readClientHello()
 	if err == io.EOF && 0 == c.readFromUntilLen {
 		state.TlsHandshakeZeroData.Inc(1)
 	}
@@ -75,10 +71,30 @@ func (c *Conn) serverHandshake() error {
 		return err
 	}
 
-	// Record JA3 fingerprint for TLS client
-	c.ja3Raw = hs.clientHello.JA3String()
+	// Record JA3 and JA4 fingerprint for TLS client
+	c.ja3Raw = clientHello.JA3String()
 	sum := md5.Sum([]byte(c.ja3Raw))
 	c.ja3Hash = hex.EncodeToString(sum[:])
+	c.ja4 = clientHello.JA4String()
+
+	if c.vers == VersionTLS13 {
+		hs := serverHandshakeStateTLS13{
+			c:           c,
+			clientHello: clientHello,
+		}
+		return hs.handshake(handshakeStart)
+	}
+
+	hs := serverHandshakeState{
+		c:           c,
+		clientHello: clientHello,
+	}
+
+	isResume, err := hs.processClientHello()
+	if err != nil {
+		state.TlsHandshakeReadClientHelloErr.Inc(1)
+		return err
+	}
 
 	// For an overview of TLS handshaking, see https://tools.ietf.org/html/rfc5246#section-7.3
 	if isResume {
@@ -90,6 +106,9 @@ func (c *Conn) serverHandshake() error {
 		if err := hs.establishKeys(); err != nil {
 			return err
 		}
+		if err := hs.sendSessionTicket(); err != nil {
+			return err
+		}
 		if err := hs.sendFinished(); err != nil {
 			return err
 		}
@@ -150,51 +169,157 @@ func (c *Conn) serverHandshake() error {
 	return nil
 }
 
-// readClientHello reads a ClientHello message from the client and decides
-// whether we will perform session resumption.
-func (hs *serverHandshakeState) readClientHello() (isResume bool, err error) {
-	config := hs.c.config
-	c := hs.c
-
+// readClientHello reads a ClientHello message from the client and negotiates
+// the protocol version.
+func (c *Conn) readClientHello() (*clientHelloMsg, error) {
 	msg, err := c.readHandshake()
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	var ok bool
-	hs.clientHello, ok = msg.(*clientHelloMsg)
+	clientHello, ok := msg.(*clientHelloMsg)
 	if !ok {
 		c.sendAlert(alertUnexpectedMessage)
-		return false, unexpectedMessageError(hs.clientHello, msg)
+		return nil, unexpectedMessageError(clientHello, msg)
+	}
+
+	if len(clientHello.supportedVersions) > 0 {
+		c.vers, ok = c.config.mutualSupportedVersion(clientHello.supportedVersions)
+	} else {
+		c.vers, ok = c.config.mutualVersion(clientHello.vers)
 	}
-	c.vers, ok = config.mutualVersion(hs.clientHello.vers)
 	if !ok {
 		c.sendAlert(alertProtocolVersion)
-		return false, fmt.Errorf("tls: client offered an unsupported, maximum protocol version of %x", hs.clientHello.vers)
+		return nil, fmt.Errorf("tls: client offered an unsupported, maximum protocol version of %x", clientHello.vers)
 	}
 
-	if len(hs.clientHello.serverName) > 0 {
-		c.serverName = hs.clientHello.serverName
+	if len(clientHello.serverName) > 0 {
+		c.serverName = clientHello.serverName
 	}
 
-	// get customized config for current connection
+	return clientHello, nil
+}
+
+// loadServerRule gets customized config for current connection, and checks
+// the negotiated version against the security grade.
+func (c *Conn) loadServerRule() (*Rule, error) {
 	var rule *Rule
-	if config.ServerRule != nil {
-		rule = config.ServerRule.Get(c)
+	if c.config.ServerRule != nil {
+		rule = c.config.ServerRule.Get(c)
 	}
 
 	c.grade = GradeC
 	if rule != nil {
 		c.grade = rule.Grade
 		c.enableDynamicRecord = rule.DynamicRecord
+		c.curvePreferences = rule.CurvePreferences
 	}
 
-	c.vers, ok = config.checkVersionGrade(c.vers, c.grade)
+	var ok bool
+	c.vers, ok = c.config.checkVersionGrade(c.vers, c.grade)
 	if !ok {
 		c.sendAlert(alertProtocolVersion)
-		return false, fmt.Errorf("tls: client offered an unsupported suite for this grade, grade is %s", c.grade)
+		return nil, fmt.Errorf("tls: client offered an unsupported suite for this grade, grade is %s", c.grade)
 	}
 
 	c.haveVers = true
+	return rule, nil
+}
+
+// serverNextProtos returns the application protocols for current connection.
+func (c *Conn) serverNextProtos(rule *Rule, clientHello *clientHelloMsg) []string {
+	if c.config.GetCertificate != nil && isACMETLSHello(clientHello) {
+		return []string{ACMETLSProtocol}
+	}
+	if rule != nil {
+		return rule.NextProtos.Get(c)
+	}
+	return c.config.NextProtos
+}
+
+// isACMETLSHello reports whether clientHello is sent by ACME server for
+// TLS-ALPN-01 challenge. See RFC 8737, Section 3
+func isACMETLSHello(clientHello *clientHelloMsg) bool {
+	return len(clientHello.alpnProtocols) == 1 && clientHello.alpnProtocols[0] == ACMETLSProtocol
+}
+
+// serverCurvePreferences returns the curve preferences for current connection.
+func (c *Conn) serverCurvePreferences() []CurveID {
+	if len(c.curvePreferences) > 0 {
+		return c.curvePreferences
+	}
+	return c.config.curvePreferences()
+}
+
+// serverCertificate selects certificate for current connection.
+func (c *Conn) serverCertificate(clientHello *clientHelloMsg) (*Certificate, error) {
+	config := c.config
+	if config.GetCertificate != nil {
+		// select certificate by callback
+		cert, err := config.GetCertificate(&ClientHelloInfo{
+			ServerName:      clientHello.serverName,
+			SupportedProtos: clientHello.alpnProtocols,
+			Conn:            c,
+		})
+		if err != nil {
+			c.sendAlert(alertInternalError)
+			return nil, err
+		}
+		if cert != nil {
+			return cert, nil
+		}
+	}
+
+	if len(config.Certificates) == 0 {
+		c.sendAlert(alertInternalError)
+		return nil, errors.New("tls: no certificates configured")
+	}
+	cert := &config.Certificates[0]
+	if len(clientHello.serverName) > 0 {
+		cert = config.getCertificateForName(clientHello.serverName)
+	}
+
+	if tlsMultiCertificate != nil {
+		// select certificate by third party policy
+		if multiCert := tlsMultiCertificate.Get(c); multiCert != nil {
+			cert = multiCert
+		}
+	} else if config.MultiCert != nil {
+		// select certificate by default policy
+		if multiCert := config.MultiCert.Get(c); multiCert != nil {
+			cert = multiCert
+		}
+	}
+
+	return cert, nil
+}
+
+// loadClientAuth selects client auth policy for current connection.
+func (c *Conn) loadClientAuth(rule *Rule) {
+	c.clientAuth = c.config.ClientAuth
+	if c.clientProtocol == ACMETLSProtocol {
+		// ACME server never presents client certificate
+		c.clientAuth = NoClientCert
+		return
+	}
+	if rule != nil && rule.ClientAuth {
+		c.clientAuth = RequireAndVerifyClientCert
+		c.clientCAs = rule.ClientCAs
+		c.clientCAName = rule.ClientCAName
+		c.clientCRLPool = rule.ClientCRLPool
+		c.clientRevocation = rule.ClientRevocation
+	}
+}
+
+// processClientHello processes the ClientHello message from the client and
+// decides whether we will perform session resumption.
+func (hs *serverHandshakeState) processClientHello() (isResume bool, err error) {
+	config := hs.c.config
+	c := hs.c
+
+	rule, err := c.loadServerRule()
+	if err != nil {
+		return false, err
+	}
 
 	hs.useRC4 = config.checkCipherGrade(c)
 
@@ -209,7 +334,7 @@ func (hs *serverHandshakeState) readClientHello() (isResume bool, err error) {
 	hs.hello = new(serverHelloMsg)
 
 	supportedCurve := false
-	preferredCurves := config.curvePreferences()
+	preferredCurves := legacyCurves(c.serverCurvePreferences())
 Curves:
 	for _, curve := range hs.clientHello.supportedCurves {
 		for _, supported := range preferredCurves {
@@ -249,13 +374,20 @@ Curves:
 		c.sendAlert(alertInternalError)
 		return false, err
 	}
+
+	// Set downgrade protection sentinel if TLS 1.3 is supported by server.
+	// See RFC 8446, Section 4.1.3
+	if config.maxVersion() >= VersionTLS13 {
+		if c.vers == VersionTLS12 {
+			copy(hs.hello.random[24:], downgradeCanaryTLS12)
+		} else {
+			copy(hs.hello.random[24:], downgradeCanaryTLS11)
+		}
+	}
 	hs.hello.secureRenegotiation = hs.clientHello.secureRenegotiation
 	hs.hello.compressionMethod = compressionNone
 
-	nextProtos := config.NextProtos
-	if rule != nil {
-		nextProtos = rule.NextProtos.Get(c)
-	}
+	nextProtos := c.serverNextProtos(rule, hs.clientHello)
 
 	if len(hs.clientHello.alpnProtocols) > 0 {
 		if selectedProto, fallback := mutualProtocol(hs.clientHello.alpnProtocols, nextProtos); !fallback {
@@ -278,37 +410,16 @@ Curves:
 	}
 
 	// Select certificate for current connection
-	if len(config.Certificates) == 0 {
-		c.sendAlert(alertInternalError)
-		return false, errors.New("tls: no certificates configured")
-	}
-	hs.cert = &config.Certificates[0]
-	if len(hs.clientHello.serverName) > 0 {
-		hs.cert = config.getCertificateForName(hs.clientHello.serverName)
+	if hs.cert, err = c.serverCertificate(hs.clientHello); err != nil {
+		return false, err
 	}
 
-	if tlsMultiCertificate != nil {
-		// select certificate by third party policy
-		if cert := tlsMultiCertificate.Get(c); cert != nil {
-			hs.cert = cert
-		}
-	} else if config.MultiCert != nil {
-		// select certificate by default policy
-		if cert := config.MultiCert.Get(c); cert != nil {
-			hs.cert = cert
-		}
+	if signer, ok := hs.cert.PrivateKey.(crypto.Signer); ok {
+		_, hs.ecdsaOk = signer.Public().(*ecdsa.PublicKey)
 	}
 
-	_, hs.ecdsaOk = hs.cert.PrivateKey.(*ecdsa.PrivateKey)
-
 	// Select client auth policy for current connection
-	c.clientAuth = config.ClientAuth
-	if rule != nil && rule.ClientAuth {
-		c.clientAuth = RequireAndVerifyClientCert
-		c.clientCAs = rule.ClientCAs
-		c.clientCAName = rule.ClientCAName
-		c.clientCRLPool = rule.ClientCRLPool
-	}
+	c.loadClientAuth(rule)
 
 	// check whether chacha20-poly1305 is enabled for current connection
 	if rule != nil {
@@ -551,6 +662,8 @@ func (hs *serverHandshakeState) doResumeHandshake() error {
 	// We echo the client's session ID in the ServerHello to let it know
 	// that we're doing a resumption.
 	hs.hello.sessionId = hs.clientHello.sessionId
+	// Issue new ticket if the ticket is encrypted by decrypt-only key.
+	hs.hello.ticketSupported = hs.sessionTicketOK && hs.sessionState.usedOldKey
 	hs.finishedHash.Write(hs.hello.marshal())
 	c.writeRecord(recordTypeHandshake, hs.hello.marshal())
 
@@ -567,11 +680,15 @@ func (hs *serverHandshakeState) doResumeHandshake() error {
 
 // judge whether ocspstapling update time suitable for server time
 func (hs *serverHandshakeState) ocspTimeCheck() bool {
-	if hs.cert.OCSPParse == nil {
+	return ocspTimeCheck(hs.cert)
+}
+
+func ocspTimeCheck(cert *Certificate) bool {
+	if cert.OCSPParse == nil {
 		return false
 	}
 
-	if !OcspTimeRangeCheck(hs.cert.OCSPParse) {
+	if !OcspTimeRangeCheck(cert.OCSPParse) {
 		state.TlsHandshakeOcspTimeErr.Inc(1)
 		return false
 	}
@@ -631,11 +748,18 @@ func (hs *serverHandshakeState) doFullHandshake() error {
 	}
 
 	keyAgreement := hs.suite.ka(c.vers)
+	ecdheKA, isECDHE := keyAgreement.(*ecdheKeyAgreement)
+	if isECDHE {
+		ecdheKA.preferredCurves = legacyCurves(c.serverCurvePreferences())
+	}
 	skx, err := keyAgreement.generateServerKeyExchange(config, hs.cert, hs.clientHello, hs.hello)
 	if err != nil {
 		c.sendAlert(alertHandshakeFailure)
 		return err
 	}
+	if isECDHE {
+		c.curveID = ecdheKA.curveid
+	}
 	if skx != nil {
 		hs.finishedHash.Write(skx.marshal())
 		c.writeRecord(recordTypeHandshake, skx.marshal())
@@ -882,9 +1006,11 @@ func (hs *serverHandshakeState) sendFinished() error {
 // Certificates message or from a sessionState and verifies them. It returns
 // the public key of the leaf certificate.
 func (hs *serverHandshakeState) processCertsFromClient(certificates [][]byte) (crypto.PublicKey, error) {
-	c := hs.c
-
 	hs.certsFromClient = certificates
+	return hs.c.processCertsFromClient(certificates)
+}
+
+func (c *Conn) processCertsFromClient(certificates [][]byte) (crypto.PublicKey, error) {
 	certs := make([]*x509.Certificate, len(certificates))
 	var err error
 	for i, asn1Data := range certificates {
@@ -929,6 +1055,20 @@ func (hs *serverHandshakeState) processCertsFromClient(certificates [][]byte) (c
 			return nil, errors.New("tls: client's certificate's extended key usage doesn't permit it to be used for client authentication")
 		}
 
+		// check revocation status of leaf certificate online
+		// Note: result is cached and refreshed in background, so fetching
+		// only blocks the first check of a certificate (or issuer for crl)
+		if c.clientRevocation != nil && len(chains[0]) > 1 {
+			if err := c.clientRevocation.Check(chains[0][0], chains[0][1]); err != nil {
+				if errors.Is(err, ErrCertRevoked) {
+					c.sendAlert(alertCertificateRevoked)
+					return nil, fmt.Errorf("tls: revoked client certificate: %s %s", strings.ToUpper(certs[0].SerialNumber.Text(16)), certs[0].Subject.CommonName)
+				}
+				c.sendAlert(alertCertificateUnknown)
+				return nil, errors.New("tls: failed to check revocation of client's certificate: " + err.Error())
+			}
+		}
+
 		c.verifiedChains = chains
 	}
 
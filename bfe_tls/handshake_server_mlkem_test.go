@@ -0,0 +1,49 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.24
+
+package bfe_tls
+
+import (
+	"crypto/tls"
+	"testing"
+)
+
+// crypto/tls client supports X25519MLKEM768 since go1.24
+func TestTLS13X25519MLKEM768Handshake(t *testing.T) {
+	serverConfig := newTLS13ServerConfig()
+
+	clientConfig := &tls.Config{
+		InsecureSkipVerify: true,
+		CurvePreferences:   []tls.CurveID{tls.X25519MLKEM768, tls.X25519},
+	}
+	state, _, err := testTLS13Handshake(clientConfig, serverConfig)
+	if err != nil {
+		t.Fatalf("handshake failed: %s", err)
+	}
+	if state.CurveID != X25519MLKEM768 {
+		t.Errorf("unexpected curve %s", CurveText(state.CurveID))
+	}
+
+	// fallback to X25519 if disabled by server
+	serverConfig.CurvePreferences = []CurveID{X25519, CurveP256}
+	state, _, err = testTLS13Handshake(clientConfig, serverConfig)
+	if err != nil {
+		t.Fatalf("handshake failed: %s", err)
+	}
+	if state.CurveID != X25519 {
+		t.Errorf("unexpected curve %s", CurveText(state.CurveID))
+	}
+}
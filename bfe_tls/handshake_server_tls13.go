@@ -0,0 +1,927 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bfe_tls
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+)
+
+// maxSessionTicketLifetime is the maximum allowed lifetime of a TLS 1.3
+// session ticket. See RFC 8446, Section 4.6.1
+const maxSessionTicketLifetime = 7 * 24 * time.Hour
+
+const (
+	serverSignatureContext = "TLS 1.3, server CertificateVerify\x00"
+	clientSignatureContext = "TLS 1.3, client CertificateVerify\x00"
+)
+
+// serverHandshakeStateTLS13 contains details of a TLS 1.3 server handshake
+// in progress. It's discarded once the handshake has completed.
+//
+// Note: 0-RTT data is never accepted. Early data offered by the client is
+// skipped, and the client will send it again after the handshake.
+type serverHandshakeStateTLS13 struct {
+	c               *Conn
+	clientHello     *clientHelloMsg
+	hello           *serverHelloMsg
+	sentDummyCCS    bool
+	usingPSK        bool
+	suite           *cipherSuiteTLS13
+	cert            *Certificate
+	sigAndHash      signatureAndHash
+	earlySecret     []byte
+	sharedKey       []byte
+	handshakeSecret []byte
+	masterSecret    []byte
+	trafficSecret   []byte // client_application_traffic_secret_0
+	trafficSecrets  map[string][]byte
+	transcript      hash.Hash
+	certsFromClient [][]byte
+}
+
+func (hs *serverHandshakeStateTLS13) handshake(handshakeStart time.Time) error {
+	c := hs.c
+	state.TlsHandshakeTLS13.Inc(1)
+
+	// For an overview of the TLS 1.3 handshake, see RFC 8446, Section 2.
+	if err := hs.processClientHello(); err != nil {
+		state.TlsHandshakeReadClientHelloErr.Inc(1)
+		return err
+	}
+	if err := hs.checkForResumption(); err != nil {
+		return err
+	}
+	if hs.usingPSK {
+		state.TlsHandshakeResumeAll.Inc(1)
+	} else {
+		state.TlsHandshakeFullAll.Inc(1)
+	}
+	if err := hs.pickCertificate(); err != nil {
+		return err
+	}
+	if err := hs.sendServerParameters(); err != nil {
+		return err
+	}
+	if err := hs.sendServerCertificate(); err != nil {
+		return err
+	}
+	if err := hs.sendServerFinished(); err != nil {
+		return err
+	}
+	if err := hs.readClientCertificate(); err != nil {
+		return err
+	}
+	if err := hs.readClientFinished(); err != nil {
+		return err
+	}
+	if err := hs.sendSessionTicket(); err != nil {
+		return err
+	}
+
+	c.handshakeComplete = true
+	c.handshakeTime = time.Since(handshakeStart)
+	c.didResume = hs.usingPSK
+	c.cipherSuite = hs.suite.id
+
+	// Record traffic secrets for established tls conn. Traffic secrets may
+	// saved in NSS key log format so that external programs (eg. wireshark)
+	// can decrypt TLS connections for trouble shooting.
+	c.clientRandom = hs.clientHello.random
+	c.serverRandom = hs.hello.random
+	c.trafficSecrets = hs.trafficSecrets
+
+	if hs.usingPSK {
+		state.TlsHandshakeResumeSucc.Inc(1)
+	} else {
+		state.TlsHandshakeFullSucc.Inc(1)
+	}
+	return nil
+}
+
+func (hs *serverHandshakeStateTLS13) processClientHello() error {
+	c := hs.c
+
+	rule, err := c.loadServerRule()
+	if err != nil {
+		return err
+	}
+
+	hs.hello = new(serverHelloMsg)
+	hs.trafficSecrets = make(map[string][]byte)
+
+	// TLS 1.3 froze the ServerHello.legacy_version field, and uses
+	// supported_versions instead. See RFC 8446, sections 4.1.3 and 4.2.1.
+	hs.hello.vers = VersionTLS12
+	hs.hello.supportedVersion = c.vers
+
+	if len(hs.clientHello.compressionMethods) != 1 ||
+		hs.clientHello.compressionMethods[0] != compressionNone {
+		c.sendAlert(alertIllegalParameter)
+		return errors.New("tls: TLS 1.3 client supports illegal compression methods")
+	}
+
+	hs.hello.random, err = generateHelloRandom(c.config.rand())
+	if err != nil {
+		c.sendAlert(alertInternalError)
+		return err
+	}
+
+	if hs.clientHello.earlyData {
+		// 0-RTT is not supported. See RFC 8446, Section 4.2.10 for what
+		// the server is supposed to do.
+		c.skipEarlyData = true
+	}
+
+	hs.hello.sessionId = hs.clientHello.sessionId
+	hs.hello.compressionMethod = compressionNone
+
+	// check whether chacha20-poly1305 is enabled for current connection
+	chachaOk := rule != nil && rule.Chacha20
+
+	var serverSuites []uint16
+	for _, suite := range cipherSuitesTLS13 {
+		serverSuites = append(serverSuites, suite.id)
+	}
+	var preferenceList, supportedList []uint16
+	if c.config.PreferServerCipherSuites {
+		preferenceList = serverSuites
+		supportedList = hs.clientHello.cipherSuites
+	} else {
+		preferenceList = hs.clientHello.cipherSuites
+		supportedList = serverSuites
+	}
+	for _, id := range preferenceList {
+		suite := cipherSuiteTLS13ByID(id)
+		if suite == nil || !containsUint16(supportedList, id) {
+			continue
+		}
+		if suite.flags&suiteChacha20 != 0 && !chachaOk {
+			continue
+		}
+		hs.suite = suite
+		break
+	}
+	if hs.suite == nil {
+		c.sendAlert(alertHandshakeFailure)
+		state.TlsHandshakeNoSharedCipherSuite.Inc(1)
+		return fmt.Errorf("tls: no cipher suite supported by both client and server: %v",
+			hs.clientHello.cipherSuites)
+	}
+	hs.hello.cipherSuite = hs.suite.id
+	hs.transcript = hs.suite.hash.New()
+
+	// Select application protocol for current connection
+	nextProtos := c.serverNextProtos(rule, hs.clientHello)
+	if len(hs.clientHello.alpnProtocols) > 0 {
+		if selectedProto, fallback := mutualProtocol(hs.clientHello.alpnProtocols, nextProtos); !fallback {
+			c.clientProtocol = selectedProto
+		}
+	}
+
+	// Select client auth policy for current connection
+	c.loadClientAuth(rule)
+
+	// Pick the ECDHE group in server preference order, but give priority to
+	// groups with a key share, to avoid a HelloRetryRequest round trip.
+	var selectedGroup CurveID
+	var clientKeyShare *keyShare
+GroupSelection:
+	for _, preferredGroup := range c.serverCurvePreferences() {
+		if !isSupportedKeyShareGroup(preferredGroup) {
+			continue
+		}
+		for i, ks := range hs.clientHello.keyShares {
+			if ks.group == preferredGroup {
+				selectedGroup = ks.group
+				clientKeyShare = &hs.clientHello.keyShares[i]
+				break GroupSelection
+			}
+		}
+		if selectedGroup != 0 {
+			continue
+		}
+		for _, group := range hs.clientHello.supportedCurves {
+			if group == preferredGroup {
+				selectedGroup = group
+				break
+			}
+		}
+	}
+	if selectedGroup == 0 {
+		c.sendAlert(alertHandshakeFailure)
+		return errors.New("tls: no ECDHE curve supported by both client and server")
+	}
+	if clientKeyShare == nil {
+		if err := hs.doHelloRetryRequest(selectedGroup); err != nil {
+			return err
+		}
+		clientKeyShare = &hs.clientHello.keyShares[0]
+	}
+
+	c.curveID = selectedGroup
+	hs.hello.serverShare.group = selectedGroup
+	hs.hello.serverShare.data, hs.sharedKey, err = generateServerKeyShare(c.config.rand(),
+		selectedGroup, clientKeyShare.data)
+	if err != nil {
+		c.sendAlert(alertIllegalParameter)
+		return err
+	}
+
+	return nil
+}
+
+func (hs *serverHandshakeStateTLS13) checkForResumption() error {
+	c := hs.c
+
+	if c.config.SessionTicketsDisabled || len(hs.clientHello.pskIdentities) == 0 {
+		return nil
+	}
+
+	// Only psk_dhe_ke mode is supported. See RFC 8446, Section 4.2.9
+	modeOK := false
+	for _, mode := range hs.clientHello.pskModes {
+		if mode == pskModeDHE {
+			modeOK = true
+			break
+		}
+	}
+	if !modeOK {
+		return nil
+	}
+
+	state.TlsHandshakeCheckResumeSessionTicket.Inc(1)
+	for i, identity := range hs.clientHello.pskIdentities {
+		// Note: decryptTicket decrypts in place, while the identity is
+		// still needed for computing the binder.
+		sessionState, ok := c.decryptTicket(append([]byte(nil), identity.label...))
+		if !ok || sessionState.vers != VersionTLS13 {
+			continue
+		}
+
+		// We don't check the obfuscated ticket age because it's affected by
+		// clock skew and it's only a freshness signal useful for shrinking
+		// the window for replay attacks, which don't affect us as we don't
+		// do 0-RTT.
+		createdAt := time.Unix(int64(sessionState.createdAt), 0)
+		if c.config.time().Sub(createdAt) > maxSessionTicketLifetime {
+			continue
+		}
+
+		// PSK connections don't re-establish client certificates, but carry
+		// them over in the session ticket. Ensure the presence of client
+		// certs in the ticket is consistent with the configured requirements.
+		sessionHasClientCerts := len(sessionState.certificates) != 0
+		needClientCerts := c.clientAuth == RequireAnyClientCert || c.clientAuth == RequireAndVerifyClientCert
+		if needClientCerts && !sessionHasClientCerts {
+			continue
+		}
+		if sessionHasClientCerts && c.clientAuth == NoClientCert {
+			continue
+		}
+
+		pskSuite := cipherSuiteTLS13ByID(sessionState.cipherSuite)
+		if pskSuite == nil || pskSuite.hash != hs.suite.hash {
+			continue
+		}
+
+		hs.earlySecret = hs.suite.extract(sessionState.masterSecret, nil)
+		binderKey := hs.suite.deriveSecret(hs.earlySecret, resumptionBinderLabel, nil)
+		// Clone the transcript in case a HelloRetryRequest was recorded.
+		transcript := cloneHash(hs.transcript, hs.suite.hash)
+		if transcript == nil {
+			c.sendAlert(alertInternalError)
+			return errors.New("tls: internal error: failed to clone hash")
+		}
+		transcript.Write(hs.clientHello.marshalWithoutBinders())
+		pskBinder := hs.suite.finishedHash(binderKey, transcript)
+		if !hmac.Equal(hs.clientHello.pskBinders[i], pskBinder) {
+			c.sendAlert(alertDecryptError)
+			return errors.New("tls: invalid PSK binder")
+		}
+
+		if sessionHasClientCerts {
+			if _, err := c.processCertsFromClient(sessionState.certificates); err != nil {
+				return err
+			}
+		}
+		hs.certsFromClient = sessionState.certificates
+
+		hs.hello.selectedIdentityPresent = true
+		hs.hello.selectedIdentity = uint16(i)
+		hs.usingPSK = true
+		state.TlsHandshakeShouldResumeSessionTicket.Inc(1)
+		return nil
+	}
+
+	hs.earlySecret = nil
+	return nil
+}
+
+// cloneHash uses the encoding.BinaryMarshaler and encoding.BinaryUnmarshaler
+// interfaces implemented by standard library hashes to clone the state of in
+// to a new instance of h. It returns nil if the operation fails.
+func cloneHash(in hash.Hash, h crypto.Hash) hash.Hash {
+	marshaler, ok := in.(encoding.BinaryMarshaler)
+	if !ok {
+		return nil
+	}
+	st, err := marshaler.MarshalBinary()
+	if err != nil {
+		return nil
+	}
+	out := h.New()
+	unmarshaler, ok := out.(encoding.BinaryUnmarshaler)
+	if !ok {
+		return nil
+	}
+	if err := unmarshaler.UnmarshalBinary(st); err != nil {
+		return nil
+	}
+	return out
+}
+
+func (hs *serverHandshakeStateTLS13) pickCertificate() error {
+	c := hs.c
+
+	// Only one of PSK and certificates are used at a time.
+	if hs.usingPSK {
+		return nil
+	}
+
+	var err error
+	if hs.cert, err = c.serverCertificate(hs.clientHello); err != nil {
+		return err
+	}
+
+	hs.sigAndHash, err = selectSignatureSchemeTLS13(hs.cert.PrivateKey, hs.clientHello.signatureAndHashes)
+	if err != nil {
+		// getCertificate returned a certificate that is unsupported or
+		// incompatible with the client's signature algorithms.
+		c.sendAlert(alertHandshakeFailure)
+		return err
+	}
+
+	return nil
+}
+
+// sendDummyChangeCipherSpec sends a ChangeCipherSpec record for compatibility
+// with middleboxes that didn't implement TLS correctly.
+// See RFC 8446, Appendix D.4.
+func (hs *serverHandshakeStateTLS13) sendDummyChangeCipherSpec() error {
+	if hs.sentDummyCCS || len(hs.clientHello.sessionId) == 0 {
+		return nil
+	}
+	hs.sentDummyCCS = true
+
+	_, err := hs.c.writeRecord(recordTypeChangeCipherSpec, []byte{1})
+	return err
+}
+
+func (hs *serverHandshakeStateTLS13) doHelloRetryRequest(selectedGroup CurveID) error {
+	c := hs.c
+	state.TlsHandshakeHelloRetryRequest.Inc(1)
+
+	// The first ClientHello gets double-hashed into the transcript upon a
+	// HelloRetryRequest. See RFC 8446, Section 4.4.1.
+	hs.transcript.Write(hs.clientHello.marshal())
+	chHash := hs.transcript.Sum(nil)
+	hs.transcript.Reset()
+	hs.transcript.Write([]byte{typeMessageHash, 0, 0, uint8(len(chHash))})
+	hs.transcript.Write(chHash)
+
+	helloRetryRequest := &serverHelloMsg{
+		vers:              hs.hello.vers,
+		random:            helloRetryRequestRandom,
+		sessionId:         hs.hello.sessionId,
+		cipherSuite:       hs.hello.cipherSuite,
+		compressionMethod: hs.hello.compressionMethod,
+		supportedVersion:  hs.hello.supportedVersion,
+		selectedGroup:     selectedGroup,
+	}
+
+	hs.transcript.Write(helloRetryRequest.marshal())
+	if _, err := c.writeRecord(recordTypeHandshake, helloRetryRequest.marshal()); err != nil {
+		return err
+	}
+
+	if err := hs.sendDummyChangeCipherSpec(); err != nil {
+		return err
+	}
+
+	msg, err := c.readHandshake()
+	if err != nil {
+		return err
+	}
+
+	clientHello, ok := msg.(*clientHelloMsg)
+	if !ok {
+		c.sendAlert(alertUnexpectedMessage)
+		return unexpectedMessageError(clientHello, msg)
+	}
+
+	if len(clientHello.keyShares) != 1 || clientHello.keyShares[0].group != selectedGroup {
+		c.sendAlert(alertIllegalParameter)
+		return errors.New("tls: client sent invalid key share in second ClientHello")
+	}
+
+	if clientHello.earlyData {
+		c.sendAlert(alertIllegalParameter)
+		return errors.New("tls: client indicated early data in second ClientHello")
+	}
+
+	if illegalClientHelloChange(clientHello, hs.clientHello) {
+		c.sendAlert(alertIllegalParameter)
+		return errors.New("tls: client illegally modified second ClientHello")
+	}
+
+	hs.clientHello = clientHello
+	return nil
+}
+
+// illegalClientHelloChange reports whether the two ClientHello messages are
+// different, with the exception of the changes allowed before and after a
+// HelloRetryRequest. See RFC 8446, Section 4.1.2.
+func illegalClientHelloChange(ch, ch1 *clientHelloMsg) bool {
+	if len(ch.supportedVersions) != len(ch1.supportedVersions) ||
+		len(ch.cipherSuites) != len(ch1.cipherSuites) ||
+		len(ch.supportedCurves) != len(ch1.supportedCurves) ||
+		len(ch.signatureAndHashes) != len(ch1.signatureAndHashes) ||
+		len(ch.alpnProtocols) != len(ch1.alpnProtocols) {
+		return true
+	}
+	for i := range ch.supportedVersions {
+		if ch.supportedVersions[i] != ch1.supportedVersions[i] {
+			return true
+		}
+	}
+	for i := range ch.cipherSuites {
+		if ch.cipherSuites[i] != ch1.cipherSuites[i] {
+			return true
+		}
+	}
+	for i := range ch.supportedCurves {
+		if ch.supportedCurves[i] != ch1.supportedCurves[i] {
+			return true
+		}
+	}
+	for i := range ch.signatureAndHashes {
+		if ch.signatureAndHashes[i] != ch1.signatureAndHashes[i] {
+			return true
+		}
+	}
+	for i := range ch.alpnProtocols {
+		if ch.alpnProtocols[i] != ch1.alpnProtocols[i] {
+			return true
+		}
+	}
+	return ch.vers != ch1.vers ||
+		!bytes.Equal(ch.random, ch1.random) ||
+		!bytes.Equal(ch.sessionId, ch1.sessionId) ||
+		!bytes.Equal(ch.compressionMethods, ch1.compressionMethods) ||
+		ch.serverName != ch1.serverName ||
+		ch.ocspStapling != ch1.ocspStapling ||
+		!bytes.Equal(ch.supportedPoints, ch1.supportedPoints) ||
+		ch.ticketSupported != ch1.ticketSupported ||
+		!bytes.Equal(ch.sessionTicket, ch1.sessionTicket) ||
+		!bytes.Equal(ch.pskModes, ch1.pskModes)
+}
+
+func (hs *serverHandshakeStateTLS13) sendServerParameters() error {
+	c := hs.c
+
+	hs.transcript.Write(hs.clientHello.marshal())
+	hs.transcript.Write(hs.hello.marshal())
+	if _, err := c.writeRecord(recordTypeHandshake, hs.hello.marshal()); err != nil {
+		return err
+	}
+
+	if err := hs.sendDummyChangeCipherSpec(); err != nil {
+		return err
+	}
+
+	earlySecret := hs.earlySecret
+	if earlySecret == nil {
+		earlySecret = hs.suite.extract(nil, nil)
+	}
+	hs.handshakeSecret = hs.suite.extract(hs.sharedKey,
+		hs.suite.deriveSecret(earlySecret, "derived", nil))
+
+	clientSecret := hs.suite.deriveSecret(hs.handshakeSecret,
+		clientHandshakeTrafficLabel, hs.transcript)
+	c.in.setTrafficSecret(hs.suite, clientSecret)
+	serverSecret := hs.suite.deriveSecret(hs.handshakeSecret,
+		serverHandshakeTrafficLabel, hs.transcript)
+	c.out.setTrafficSecret(hs.suite, serverSecret)
+
+	hs.trafficSecrets[KeyLogLabelClientHandshake] = clientSecret
+	hs.trafficSecrets[KeyLogLabelServerHandshake] = serverSecret
+
+	encryptedExtensions := new(encryptedExtensionsMsg)
+	encryptedExtensions.alpnProtocol = c.clientProtocol
+
+	hs.transcript.Write(encryptedExtensions.marshal())
+	if _, err := c.writeRecord(recordTypeHandshake, encryptedExtensions.marshal()); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (hs *serverHandshakeStateTLS13) requestClientCert() bool {
+	return hs.c.clientAuth >= RequestClientCert && !hs.usingPSK
+}
+
+func (hs *serverHandshakeStateTLS13) sendServerCertificate() error {
+	c := hs.c
+
+	// Only one of PSK and certificates are used at a time.
+	if hs.usingPSK {
+		return nil
+	}
+
+	if hs.requestClientCert() {
+		// Request a client certificate
+		certReq := new(certificateRequestMsgTLS13)
+		certReq.supportedSignatureAlgorithms = supportedSignatureAlgorithmsTLS13
+		if clientCAs := c.getClientCAs(); clientCAs != nil {
+			certReq.certificateAuthorities = clientCAs.Subjects()
+		}
+
+		hs.transcript.Write(certReq.marshal())
+		if _, err := c.writeRecord(recordTypeHandshake, certReq.marshal()); err != nil {
+			return err
+		}
+	}
+
+	certMsg := new(certificateMsgTLS13)
+	certMsg.certificates = hs.cert.Certificate
+	if hs.clientHello.ocspStapling && len(hs.cert.OCSPStaple) > 0 {
+		// check ocspstapling time
+		if ocspOk := ocspTimeCheck(hs.cert); ocspOk {
+			certMsg.ocspStapling = true
+			certMsg.ocspStaple = hs.cert.OCSPStaple
+			c.ocspStaple = true
+		}
+	}
+	if hs.clientHello.ocspStapling {
+		state.TlsStatusRequestExtCount.Inc(1)
+	}
+
+	hs.transcript.Write(certMsg.marshal())
+	if _, err := c.writeRecord(recordTypeHandshake, certMsg.marshal()); err != nil {
+		return err
+	}
+
+	certVerifyMsg := new(certificateVerifyMsg)
+	certVerifyMsg.hasSignatureAndHash = true
+	certVerifyMsg.signatureAndHash = hs.sigAndHash
+
+	sig, err := signHandshakeTLS13(c.config.rand(), hs.cert.PrivateKey, hs.sigAndHash,
+		serverSignatureContext, hs.transcript)
+	if err != nil {
+		c.sendAlert(alertInternalError)
+		return errors.New("tls: failed to sign handshake: " + err.Error())
+	}
+	certVerifyMsg.signature = sig
+
+	hs.transcript.Write(certVerifyMsg.marshal())
+	if _, err := c.writeRecord(recordTypeHandshake, certVerifyMsg.marshal()); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (hs *serverHandshakeStateTLS13) sendServerFinished() error {
+	c := hs.c
+
+	finished := &finishedMsg{
+		verifyData: hs.suite.finishedHash(c.out.trafficSecret, hs.transcript),
+	}
+
+	hs.transcript.Write(finished.marshal())
+	if _, err := c.writeRecord(recordTypeHandshake, finished.marshal()); err != nil {
+		return err
+	}
+
+	// Derive secrets that take context through the server Finished.
+	hs.masterSecret = hs.suite.extract(nil,
+		hs.suite.deriveSecret(hs.handshakeSecret, "derived", nil))
+
+	hs.trafficSecret = hs.suite.deriveSecret(hs.masterSecret,
+		clientApplicationTrafficLabel, hs.transcript)
+	serverSecret := hs.suite.deriveSecret(hs.masterSecret,
+		serverApplicationTrafficLabel, hs.transcript)
+	c.out.setTrafficSecret(hs.suite, serverSecret)
+
+	hs.trafficSecrets[KeyLogLabelClientTraffic] = hs.trafficSecret
+	hs.trafficSecrets[KeyLogLabelServerTraffic] = serverSecret
+
+	return nil
+}
+
+func (hs *serverHandshakeStateTLS13) readClientCertificate() error {
+	c := hs.c
+
+	if !hs.requestClientCert() {
+		return nil
+	}
+
+	// If we requested a client certificate, then the client must send a
+	// certificate message. If it's empty, no CertificateVerify is sent.
+	msg, err := c.readHandshake()
+	if err != nil {
+		return err
+	}
+
+	certMsg, ok := msg.(*certificateMsgTLS13)
+	if !ok {
+		c.sendAlert(alertUnexpectedMessage)
+		return unexpectedMessageError(certMsg, msg)
+	}
+	hs.transcript.Write(certMsg.marshal())
+
+	if len(certMsg.certificates) == 0 {
+		// The client didn't actually send a certificate
+		switch c.clientAuth {
+		case RequireAnyClientCert, RequireAndVerifyClientCert:
+			c.sendAlert(alertCertificateRequired)
+			return errors.New("tls: client didn't provide a certificate")
+		}
+	}
+
+	pub, err := c.processCertsFromClient(certMsg.certificates)
+	if err != nil {
+		return err
+	}
+	hs.certsFromClient = certMsg.certificates
+
+	if len(certMsg.certificates) != 0 {
+		msg, err = c.readHandshake()
+		if err != nil {
+			return err
+		}
+
+		certVerify, ok := msg.(*certificateVerifyMsg)
+		if !ok {
+			c.sendAlert(alertUnexpectedMessage)
+			return unexpectedMessageError(certVerify, msg)
+		}
+
+		if !isSupportedSignatureAlgorithm(certVerify.signatureAndHash, supportedSignatureAlgorithmsTLS13) {
+			c.sendAlert(alertIllegalParameter)
+			return errors.New("tls: client certificate used with invalid signature algorithm")
+		}
+		if err := verifyHandshakeSignatureTLS13(pub, certVerify.signatureAndHash,
+			clientSignatureContext, hs.transcript, certVerify.signature); err != nil {
+			c.sendAlert(alertDecryptError)
+			return errors.New("tls: invalid signature by the client certificate: " + err.Error())
+		}
+
+		hs.transcript.Write(certVerify.marshal())
+	}
+
+	return nil
+}
+
+func (hs *serverHandshakeStateTLS13) readClientFinished() error {
+	c := hs.c
+
+	msg, err := c.readHandshake()
+	if err != nil {
+		return err
+	}
+
+	finished, ok := msg.(*finishedMsg)
+	if !ok {
+		c.sendAlert(alertUnexpectedMessage)
+		return unexpectedMessageError(finished, msg)
+	}
+
+	expected := hs.suite.finishedHash(c.in.trafficSecret, hs.transcript)
+	if !hmac.Equal(expected, finished.verifyData) {
+		c.sendAlert(alertDecryptError)
+		return errors.New("tls: invalid client finished hash")
+	}
+
+	hs.transcript.Write(finished.marshal())
+	c.in.setTrafficSecret(hs.suite, hs.trafficSecret)
+
+	// all early data (if any) has been skipped
+	c.skipEarlyData = false
+
+	return nil
+}
+
+func (hs *serverHandshakeStateTLS13) sendSessionTicket() error {
+	c := hs.c
+
+	if c.config.SessionTicketsDisabled {
+		return nil
+	}
+
+	// Don't send tickets the client wouldn't use. See RFC 8446, Section 4.2.9.
+	if !bytes.Contains(hs.clientHello.pskModes, []byte{pskModeDHE}) {
+		return nil
+	}
+
+	resumptionSecret := hs.suite.deriveSecret(hs.masterSecret,
+		resumptionLabel, hs.transcript)
+
+	// Note: ticket nonce is empty, since only one ticket is sent.
+	// See RFC 8446, Section 4.6.1
+	state := sessionState{
+		vers:         c.vers,
+		cipherSuite:  hs.suite.id,
+		masterSecret: hs.suite.expandLabel(resumptionSecret, "resumption", nil, hs.suite.hash.Size()),
+		certificates: hs.certsFromClient,
+		createdAt:    uint64(c.config.time().Unix()),
+	}
+
+	m := new(newSessionTicketMsgTLS13)
+	var err error
+	m.label, err = c.encryptTicket(&state)
+	if err != nil {
+		return err
+	}
+	m.lifetime = uint32(maxSessionTicketLifetime / time.Second)
+
+	// ticket_age_add is a random 32-bit value. See RFC 8446, section 4.6.1
+	// The value is not stored anywhere; we never need to check the ticket age
+	// because 0-RTT is not supported.
+	ageAdd := make([]byte, 4)
+	if _, err := io.ReadFull(c.config.rand(), ageAdd); err != nil {
+		return err
+	}
+	m.ageAdd = binary.LittleEndian.Uint32(ageAdd)
+
+	if _, err := c.writeRecord(recordTypeHandshake, m.marshal()); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// selectSignatureSchemeTLS13 picks a TLS 1.3 signature scheme for the
+// private key, among the ones offered by the client, in client preference.
+func selectSignatureSchemeTLS13(priv crypto.PrivateKey, peerAlgs []signatureAndHash) (signatureAndHash, error) {
+	signer, ok := priv.(crypto.Signer)
+	if !ok {
+		return signatureAndHash{}, errors.New("tls: certificate private key does not implement crypto.Signer")
+	}
+
+	var candidates []signatureAndHash
+	switch pub := signer.Public().(type) {
+	case *ecdsa.PublicKey:
+		switch pub.Curve {
+		case elliptic.P256():
+			candidates = []signatureAndHash{sigECDSAWithP256AndSHA256}
+		case elliptic.P384():
+			candidates = []signatureAndHash{sigECDSAWithP384AndSHA384}
+		case elliptic.P521():
+			candidates = []signatureAndHash{sigECDSAWithP521AndSHA512}
+		}
+	case *rsa.PublicKey:
+		// RSA-PSS is required in TLS 1.3. See RFC 8446, Section 4.2.3
+		for _, sigAndHash := range []signatureAndHash{sigPSSWithSHA256, sigPSSWithSHA384, sigPSSWithSHA512} {
+			sigHash, _ := hashForSignatureSchemeTLS13(sigAndHash)
+			if pub.Size() >= sigHash.Size()*2+2 {
+				candidates = append(candidates, sigAndHash)
+			}
+		}
+	}
+
+	for _, sigAndHash := range peerAlgs {
+		if isSupportedSignatureAlgorithm(sigAndHash, candidates) {
+			return sigAndHash, nil
+		}
+	}
+	return signatureAndHash{}, errors.New("tls: peer doesn't support any of the certificate's signature algorithms")
+}
+
+// hashForSignatureSchemeTLS13 returns the hash function of the TLS 1.3
+// signature scheme.
+func hashForSignatureSchemeTLS13(sigAndHash signatureAndHash) (crypto.Hash, bool) {
+	switch sigAndHash {
+	case sigECDSAWithP256AndSHA256, sigPSSWithSHA256:
+		return crypto.SHA256, true
+	case sigECDSAWithP384AndSHA384, sigPSSWithSHA384:
+		return crypto.SHA384, true
+	case sigECDSAWithP521AndSHA512, sigPSSWithSHA512:
+		return crypto.SHA512, true
+	default:
+		return 0, false
+	}
+}
+
+// isPSSSignatureScheme reports whether the signature scheme is RSA-PSS.
+func isPSSSignatureScheme(sigAndHash signatureAndHash) bool {
+	switch sigAndHash {
+	case sigPSSWithSHA256, sigPSSWithSHA384, sigPSSWithSHA512:
+		return true
+	}
+	return false
+}
+
+// signedMessageTLS13 returns the digest of the content covered by the
+// CertificateVerify signature. See RFC 8446, Section 4.4.3.
+func signedMessageTLS13(sigHash crypto.Hash, context string, transcript hash.Hash) []byte {
+	h := sigHash.New()
+	h.Write(bytes.Repeat([]byte{0x20}, 64))
+	io.WriteString(h, context)
+	h.Write(transcript.Sum(nil))
+	return h.Sum(nil)
+}
+
+// signHandshakeTLS13 signs the handshake transcript for CertificateVerify.
+func signHandshakeTLS13(rand io.Reader, priv crypto.PrivateKey, sigAndHash signatureAndHash,
+	context string, transcript hash.Hash) ([]byte, error) {
+	signer, ok := priv.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("private key does not implement crypto.Signer")
+	}
+	sigHash, ok := hashForSignatureSchemeTLS13(sigAndHash)
+	if !ok {
+		return nil, errors.New("unsupported signature algorithm")
+	}
+
+	var opts crypto.SignerOpts = sigHash
+	if isPSSSignatureScheme(sigAndHash) {
+		opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: sigHash}
+	}
+	return signer.Sign(rand, signedMessageTLS13(sigHash, context, transcript), opts)
+}
+
+// verifyHandshakeSignatureTLS13 verifies the signature in CertificateVerify.
+func verifyHandshakeSignatureTLS13(pub crypto.PublicKey, sigAndHash signatureAndHash,
+	context string, transcript hash.Hash, sig []byte) error {
+	sigHash, ok := hashForSignatureSchemeTLS13(sigAndHash)
+	if !ok {
+		return errors.New("unsupported signature algorithm")
+	}
+	signed := signedMessageTLS13(sigHash, context, transcript)
+
+	if isPSSSignatureScheme(sigAndHash) {
+		pubKey, ok := pub.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("expected an RSA public key, got %T", pub)
+		}
+		opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}
+		return rsa.VerifyPSS(pubKey, sigHash, signed, sig, opts)
+	}
+
+	pubKey, ok := pub.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("expected an ECDSA public key, got %T", pub)
+	}
+	if !ecdsa.VerifyASN1(pubKey, signed, sig) {
+		return errors.New("ECDSA verification failure")
+	}
+	return nil
+}
+
+func isSupportedSignatureAlgorithm(sigAndHash signatureAndHash, supported []signatureAndHash) bool {
+	for _, s := range supported {
+		if s == sigAndHash {
+			return true
+		}
+	}
+	return false
+}
+
+func containsUint16(list []uint16, v uint16) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,647 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bfe_tls
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/cloudflare/circl/kem/mlkem/mlkem768"
+)
+
+type tls13TestRule struct {
+	rule Rule
+}
+
+func (r *tls13TestRule) Get(c *Conn) *Rule {
+	return &r.rule
+}
+
+type tls13TestNextProtos []string
+
+func (p tls13TestNextProtos) Get(c *Conn) []string {
+	return p
+}
+
+func testCertValidTime() time.Time {
+	return time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func newTLS13ServerConfig() *Config {
+	config := &Config{
+		Certificates: make([]Certificate, 2),
+		MinVersion:   VersionTLS10,
+		MaxVersion:   VersionTLS13,
+	}
+	config.Certificates[0].Certificate = [][]byte{testRSACertificate}
+	config.Certificates[0].PrivateKey = testRSAPrivateKey
+	config.Certificates[1].Certificate = [][]byte{testECDSACertificate}
+	config.Certificates[1].PrivateKey = testECDSAPrivateKey
+	return config
+}
+
+// localPipe returns a pair of connected TCP connections. Unlike net.Pipe,
+// writes are buffered, since both peers may write at the same time in TLS 1.3.
+func localPipe() (net.Conn, net.Conn, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer ln.Close()
+
+	c, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		return nil, nil, err
+	}
+	s, err := ln.Accept()
+	if err != nil {
+		c.Close()
+		return nil, nil, err
+	}
+	return c, s, nil
+}
+
+// testTLS13Handshake runs a handshake between a crypto/tls client and a
+// bfe_tls server, and exchanges some application data.
+func testTLS13Handshake(clientConfig *tls.Config, serverConfig *Config) (ConnectionState, tls.ConnectionState, error) {
+	c, s, err := localPipe()
+	if err != nil {
+		return ConnectionState{}, tls.ConnectionState{}, err
+	}
+	defer c.Close()
+	defer s.Close()
+
+	type result struct {
+		state tls.ConnectionState
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		cli := tls.Client(c, clientConfig)
+		err := cli.Handshake()
+		if err == nil {
+			buf := make([]byte, 4)
+			if _, err = io.ReadFull(cli, buf); err == nil && string(buf) != "ping" {
+				err = fmt.Errorf("unexpected data from server: %q", buf)
+			}
+			if err == nil {
+				_, err = cli.Write([]byte("pong"))
+			}
+		}
+		done <- result{cli.ConnectionState(), err}
+		if err != nil {
+			c.Close()
+		}
+	}()
+
+	server := Server(s, serverConfig)
+	err = server.Handshake()
+	if err == nil {
+		_, err = server.Write([]byte("ping"))
+	}
+	if err == nil {
+		buf := make([]byte, 4)
+		if _, err = io.ReadFull(server, buf); err == nil && string(buf) != "pong" {
+			err = fmt.Errorf("unexpected data from client: %q", buf)
+		}
+	}
+	if err != nil {
+		s.Close()
+	}
+
+	res := <-done
+	if err == nil {
+		err = res.err
+	}
+	return server.ConnectionState(), res.state, err
+}
+
+func TestTLS13Handshake(t *testing.T) {
+	serverConfig := newTLS13ServerConfig()
+	serverConfig.Certificates = serverConfig.Certificates[:1]
+
+	var keyLog bytes.Buffer
+	clientConfig := &tls.Config{
+		InsecureSkipVerify: true,
+		KeyLogWriter:       &keyLog,
+	}
+	state, clientState, err := testTLS13Handshake(clientConfig, serverConfig)
+	if err != nil {
+		t.Fatalf("handshake failed: %s", err)
+	}
+	if state.Version != VersionTLS13 || clientState.Version != tls.VersionTLS13 {
+		t.Fatalf("unexpected version: server %x, client %x", state.Version, clientState.Version)
+	}
+	if state.CipherSuite != clientState.CipherSuite {
+		t.Errorf("cipher suite mismatch: server %x, client %x", state.CipherSuite, clientState.CipherSuite)
+	}
+	if !isSupportedKeyShareGroup(state.CurveID) {
+		t.Errorf("unexpected curve %s", CurveText(state.CurveID))
+	}
+	if state.DidResume {
+		t.Errorf("unexpected resumption")
+	}
+	if len(state.JA3Raw) == 0 || len(state.JA3Hash) != 32 {
+		t.Errorf("JA3 fingerprint not recorded: %q %q", state.JA3Raw, state.JA3Hash)
+	}
+
+	// traffic secrets should match the ones logged by client
+	clientRandom := hex.EncodeToString(state.ClientRandom)
+	for _, label := range []string{KeyLogLabelClientHandshake, KeyLogLabelServerHandshake,
+		KeyLogLabelClientTraffic, KeyLogLabelServerTraffic} {
+		line := fmt.Sprintf("%s %s %x\n", label, clientRandom, state.TrafficSecrets[label])
+		if !strings.Contains(keyLog.String(), line) {
+			t.Errorf("traffic secret %s mismatch, key log:\n%s", label, keyLog.String())
+		}
+	}
+}
+
+func TestTLS13CipherSuites(t *testing.T) {
+	serverConfig := newTLS13ServerConfig()
+	serverConfig.ServerRule = &tls13TestRule{Rule{NextProtos: tls13TestNextProtos{"http/1.1"}, Chacha20: true}}
+
+	for _, suite := range []uint16{TLS_AES_128_GCM_SHA256, TLS_AES_256_GCM_SHA384, TLS_CHACHA20_POLY1305_SHA256} {
+		clientConfig := &tls.Config{
+			InsecureSkipVerify: true,
+			ServerName:         "example.golang",
+		}
+		// crypto/tls doesn't allow to configure TLS 1.3 cipher suites,
+		// so restrict them in server side
+		serverConfig.PreferServerCipherSuites = true
+		saved := cipherSuitesTLS13
+		cipherSuitesTLS13 = []*cipherSuiteTLS13{cipherSuiteTLS13ByID(suite)}
+		state, _, err := testTLS13Handshake(clientConfig, serverConfig)
+		cipherSuitesTLS13 = saved
+		if err != nil {
+			t.Fatalf("handshake with %x failed: %s", suite, err)
+		}
+		if state.CipherSuite != suite {
+			t.Errorf("unexpected cipher suite %x, expect %x", state.CipherSuite, suite)
+		}
+	}
+}
+
+func TestTLS13Chacha20DisabledByRule(t *testing.T) {
+	serverConfig := newTLS13ServerConfig()
+	serverConfig.ServerRule = &tls13TestRule{Rule{NextProtos: tls13TestNextProtos{"http/1.1"}, Chacha20: false}}
+
+	saved := cipherSuitesTLS13
+	cipherSuitesTLS13 = []*cipherSuiteTLS13{cipherSuiteTLS13ByID(TLS_CHACHA20_POLY1305_SHA256)}
+	defer func() { cipherSuitesTLS13 = saved }()
+
+	clientConfig := &tls.Config{InsecureSkipVerify: true}
+	if _, _, err := testTLS13Handshake(clientConfig, serverConfig); err == nil {
+		t.Fatalf("handshake should fail without shared cipher suite")
+	}
+}
+
+func TestTLS13ECDSACertificate(t *testing.T) {
+	serverConfig := newTLS13ServerConfig()
+	serverConfig.Certificates = serverConfig.Certificates[1:]
+
+	clientConfig := &tls.Config{InsecureSkipVerify: true}
+	state, _, err := testTLS13Handshake(clientConfig, serverConfig)
+	if err != nil {
+		t.Fatalf("handshake failed: %s", err)
+	}
+	if state.Version != VersionTLS13 {
+		t.Fatalf("unexpected version %x", state.Version)
+	}
+}
+
+func TestTLS13HelloRetryRequest(t *testing.T) {
+	serverConfig := newTLS13ServerConfig()
+	serverConfig.CurvePreferences = []CurveID{CurveP256}
+
+	clientConfig := &tls.Config{
+		InsecureSkipVerify: true,
+		CurvePreferences:   []tls.CurveID{tls.X25519, tls.CurveP256},
+	}
+	state, _, err := testTLS13Handshake(clientConfig, serverConfig)
+	if err != nil {
+		t.Fatalf("handshake failed: %s", err)
+	}
+	if state.Version != VersionTLS13 {
+		t.Fatalf("unexpected version %x", state.Version)
+	}
+	if state.CurveID != CurveP256 {
+		t.Errorf("unexpected curve %s", CurveText(state.CurveID))
+	}
+}
+
+func TestTLS13X25519MLKEM768KeyShare(t *testing.T) {
+	// client key share: ML-KEM-768 encapsulation key || X25519 public key
+	encapKey, decapKey, err := mlkem768.GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %s", err)
+	}
+	x25519Key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %s", err)
+	}
+	clientShare := make([]byte, mlkem768.PublicKeySize)
+	encapKey.Pack(clientShare)
+	clientShare = append(clientShare, x25519Key.PublicKey().Bytes()...)
+
+	serverShare, sharedKey, err := generateServerKeyShare(rand.Reader, X25519MLKEM768, clientShare)
+	if err != nil {
+		t.Fatalf("generateServerKeyShare failed: %s", err)
+	}
+	if len(serverShare) != mlkem768.CiphertextSize+x25519PublicKeySize {
+		t.Fatalf("unexpected server key share size %d", len(serverShare))
+	}
+
+	// shared key: ML-KEM-768 shared key || X25519 shared key
+	expectKey := make([]byte, mlkem768.SharedKeySize)
+	decapKey.DecapsulateTo(expectKey, serverShare[:mlkem768.CiphertextSize])
+	serverKey, err := ecdh.X25519().NewPublicKey(serverShare[mlkem768.CiphertextSize:])
+	if err != nil {
+		t.Fatalf("invalid server X25519 key share: %s", err)
+	}
+	x25519SharedKey, err := x25519Key.ECDH(serverKey)
+	if err != nil {
+		t.Fatalf("ECDH failed: %s", err)
+	}
+	expectKey = append(expectKey, x25519SharedKey...)
+	if !bytes.Equal(sharedKey, expectKey) {
+		t.Errorf("shared key mismatch")
+	}
+
+	// malformed client key share
+	if _, _, err := generateServerKeyShare(rand.Reader, X25519MLKEM768, clientShare[1:]); err == nil {
+		t.Errorf("malformed key share should be rejected")
+	}
+}
+
+func TestTLS13CurvePreferencesByRule(t *testing.T) {
+	serverConfig := newTLS13ServerConfig()
+	serverConfig.ServerRule = &tls13TestRule{Rule{
+		NextProtos:       tls13TestNextProtos{"http/1.1"},
+		CurvePreferences: []CurveID{CurveP384},
+	}}
+
+	clientConfig := &tls.Config{
+		InsecureSkipVerify: true,
+		CurvePreferences:   []tls.CurveID{tls.X25519, tls.CurveP384},
+	}
+	state, _, err := testTLS13Handshake(clientConfig, serverConfig)
+	if err != nil {
+		t.Fatalf("handshake failed: %s", err)
+	}
+	if state.CurveID != CurveP384 {
+		t.Errorf("unexpected curve %s", CurveText(state.CurveID))
+	}
+
+	// rule curve preferences also apply to TLS 1.2
+	clientConfig.MaxVersion = tls.VersionTLS12
+	clientConfig.CipherSuites = []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}
+	state, _, err = testTLS13Handshake(clientConfig, serverConfig)
+	if err != nil {
+		t.Fatalf("handshake failed: %s", err)
+	}
+	if state.Version != VersionTLS12 || state.CurveID != CurveP384 {
+		t.Errorf("unexpected version %x curve %s", state.Version, CurveText(state.CurveID))
+	}
+}
+
+func TestTLS13Resumption(t *testing.T) {
+	serverConfig := newTLS13ServerConfig()
+	clientConfig := &tls.Config{
+		InsecureSkipVerify: true,
+		ServerName:         "example.golang", // key of client session cache
+		ClientSessionCache: tls.NewLRUClientSessionCache(1),
+		Time:               testCertValidTime, // sessions with expired certificate are not resumed
+	}
+
+	state, _, err := testTLS13Handshake(clientConfig, serverConfig)
+	if err != nil {
+		t.Fatalf("handshake failed: %s", err)
+	}
+	if state.DidResume {
+		t.Fatalf("unexpected resumption")
+	}
+
+	state, clientState, err := testTLS13Handshake(clientConfig, serverConfig)
+	if err != nil {
+		t.Fatalf("resumption handshake failed: %s", err)
+	}
+	if !state.DidResume || !clientState.DidResume {
+		t.Fatalf("session should be resumed")
+	}
+
+	// tickets encrypted by other keys are ignored
+	serverConfig = newTLS13ServerConfig()
+	state, _, err = testTLS13Handshake(clientConfig, serverConfig)
+	if err != nil {
+		t.Fatalf("handshake failed: %s", err)
+	}
+	if state.DidResume {
+		t.Fatalf("session should not be resumed with another ticket key")
+	}
+
+	// no ticket issued if disabled
+	serverConfig = newTLS13ServerConfig()
+	serverConfig.SessionTicketsDisabled = true
+	clientConfig.ClientSessionCache = tls.NewLRUClientSessionCache(1)
+	for i := 0; i < 2; i++ {
+		state, _, err = testTLS13Handshake(clientConfig, serverConfig)
+		if err != nil {
+			t.Fatalf("handshake failed: %s", err)
+		}
+		if state.DidResume {
+			t.Fatalf("session should not be resumed if ticket disabled")
+		}
+	}
+}
+
+func TestTLS13ServerRule(t *testing.T) {
+	serverConfig := newTLS13ServerConfig()
+	serverConfig.ServerRule = &tls13TestRule{Rule{
+		NextProtos: tls13TestNextProtos{"h2", "http/1.1"},
+		Grade:      GradeAPlus,
+	}}
+
+	clientConfig := &tls.Config{
+		InsecureSkipVerify: true,
+		NextProtos:         []string{"h2", "http/1.1"},
+	}
+	state, clientState, err := testTLS13Handshake(clientConfig, serverConfig)
+	if err != nil {
+		t.Fatalf("handshake failed: %s", err)
+	}
+	if state.NegotiatedProtocol != "h2" || clientState.NegotiatedProtocol != "h2" {
+		t.Errorf("unexpected protocol: server %q, client %q", state.NegotiatedProtocol,
+			clientState.NegotiatedProtocol)
+	}
+
+	// legacy client is rejected for grade A+
+	clientConfig.MaxVersion = tls.VersionTLS11
+	clientConfig.MinVersion = tls.VersionTLS10
+	if _, _, err := testTLS13Handshake(clientConfig, serverConfig); err == nil {
+		t.Errorf("TLS 1.1 handshake should fail for grade A+")
+	}
+}
+
+func TestTLS13ClientAuth(t *testing.T) {
+	serverConfig := newTLS13ServerConfig()
+	serverConfig.ClientAuth = RequireAnyClientCert
+
+	clientConfig := &tls.Config{
+		InsecureSkipVerify: true,
+		ServerName:         "example.golang", // key of client session cache
+		ClientSessionCache: tls.NewLRUClientSessionCache(1),
+		Time:               testCertValidTime, // sessions with expired certificate are not resumed
+	}
+	if _, _, err := testTLS13Handshake(clientConfig, serverConfig); err == nil {
+		t.Fatalf("handshake should fail without client certificate")
+	}
+
+	for _, cert := range []tls.Certificate{
+		{Certificate: [][]byte{testRSACertificate}, PrivateKey: testRSAPrivateKey},
+		{Certificate: [][]byte{testECDSACertificate}, PrivateKey: testECDSAPrivateKey},
+	} {
+		clientConfig.Certificates = []tls.Certificate{cert}
+		state, _, err := testTLS13Handshake(clientConfig, serverConfig)
+		if err != nil {
+			t.Fatalf("handshake failed: %s", err)
+		}
+		if len(state.PeerCertificates) != 1 {
+			t.Fatalf("unexpected peer certificates: %d", len(state.PeerCertificates))
+		}
+	}
+
+	// client certificates are restored from session ticket
+	state, _, err := testTLS13Handshake(clientConfig, serverConfig)
+	if err != nil {
+		t.Fatalf("handshake failed: %s", err)
+	}
+	if !state.DidResume || len(state.PeerCertificates) != 1 {
+		t.Fatalf("unexpected resumed state: %v, %d", state.DidResume, len(state.PeerCertificates))
+	}
+}
+
+func TestTLS13VersionFallback(t *testing.T) {
+	serverConfig := newTLS13ServerConfig()
+	serverConfig.MaxVersion = VersionTLS12
+
+	clientConfig := &tls.Config{InsecureSkipVerify: true}
+	state, _, err := testTLS13Handshake(clientConfig, serverConfig)
+	if err != nil {
+		t.Fatalf("handshake failed: %s", err)
+	}
+	if state.Version != VersionTLS12 {
+		t.Fatalf("unexpected version %x", state.Version)
+	}
+
+	// downgrade protection sentinel is set by TLS 1.3 server
+	serverConfig.MaxVersion = VersionTLS13
+	clientConfig.MaxVersion = tls.VersionTLS12
+	state, _, err = testTLS13Handshake(clientConfig, serverConfig)
+	if err != nil {
+		t.Fatalf("handshake failed: %s", err)
+	}
+	if !bytes.Equal(state.ServerRandom[24:], []byte(downgradeCanaryTLS12)) {
+		t.Errorf("downgrade canary not set: %x", state.ServerRandom)
+	}
+}
+
+func TestGetCertificateACMETLS(t *testing.T) {
+	serverConfig := newTLS13ServerConfig()
+	challengeCert := &serverConfig.Certificates[1]
+	serverConfig.Certificates = serverConfig.Certificates[:1]
+	serverConfig.ServerRule = &tls13TestRule{Rule{
+		NextProtos: tls13TestNextProtos{"h2", "http/1.1"},
+		ClientAuth: true,
+	}}
+	serverConfig.GetCertificate = func(hello *ClientHelloInfo) (*Certificate, error) {
+		if len(hello.SupportedProtos) == 1 && hello.SupportedProtos[0] == ACMETLSProtocol {
+			return challengeCert, nil
+		}
+		return nil, nil
+	}
+
+	for _, vers := range []uint16{tls.VersionTLS12, tls.VersionTLS13} {
+		// validation connection from ACME server
+		clientConfig := &tls.Config{
+			InsecureSkipVerify: true,
+			NextProtos:         []string{ACMETLSProtocol},
+			MaxVersion:         vers,
+		}
+		state, clientState, err := testTLS13Handshake(clientConfig, serverConfig)
+		if err != nil {
+			t.Fatalf("handshake failed: %s", err)
+		}
+		if state.NegotiatedProtocol != ACMETLSProtocol || clientState.NegotiatedProtocol != ACMETLSProtocol {
+			t.Errorf("unexpected protocol: server %q, client %q", state.NegotiatedProtocol,
+				clientState.NegotiatedProtocol)
+		}
+		if !bytes.Equal(clientState.PeerCertificates[0].Raw, challengeCert.Certificate[0]) {
+			t.Errorf("challenge certificate should be used")
+		}
+		if state.ClientAuth {
+			t.Errorf("client auth should be disabled for validation connection")
+		}
+
+		// normal connection
+		clientConfig.NextProtos = []string{"h2", ACMETLSProtocol}
+		clientConfig.Certificates = []tls.Certificate{{
+			Certificate: serverConfig.Certificates[0].Certificate,
+			PrivateKey:  serverConfig.Certificates[0].PrivateKey,
+		}}
+		serverConfig.ServerRule = &tls13TestRule{Rule{
+			NextProtos: tls13TestNextProtos{"h2", "http/1.1"},
+		}}
+		state, clientState, err = testTLS13Handshake(clientConfig, serverConfig)
+		if err != nil {
+			t.Fatalf("handshake failed: %s", err)
+		}
+		if state.NegotiatedProtocol != "h2" {
+			t.Errorf("unexpected protocol %q", state.NegotiatedProtocol)
+		}
+		if !bytes.Equal(clientState.PeerCertificates[0].Raw, serverConfig.Certificates[0].Certificate[0]) {
+			t.Errorf("default certificate should be used")
+		}
+		serverConfig.ServerRule = &tls13TestRule{Rule{
+			NextProtos: tls13TestNextProtos{"h2", "http/1.1"},
+			ClientAuth: true,
+		}}
+	}
+}
+
+// opaqueKey hides the concrete type of private key, as remote key of
+// keyless tls does.
+type opaqueKey struct {
+	crypto.Signer
+}
+
+func (k opaqueKey) Decrypt(rand io.Reader, msg []byte, opts crypto.DecrypterOpts) ([]byte, error) {
+	return k.Signer.(crypto.Decrypter).Decrypt(rand, msg, opts)
+}
+
+func TestHandshakeServerOpaqueKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		index  int // index of certificate in server config
+		vers   uint16
+		suites []uint16
+	}{
+		{"TLS12-RSA", 0, tls.VersionTLS12, []uint16{tls.TLS_RSA_WITH_AES_128_CBC_SHA}},
+		{"TLS12-ECDHE-RSA", 0, tls.VersionTLS12, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}},
+		{"TLS12-ECDHE-ECDSA", 1, tls.VersionTLS12, []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}},
+		{"TLS13-RSA", 0, tls.VersionTLS13, nil},
+		{"TLS13-ECDSA", 1, tls.VersionTLS13, nil},
+	}
+
+	for _, test := range tests {
+		serverConfig := newTLS13ServerConfig()
+		cert := serverConfig.Certificates[test.index]
+		cert.PrivateKey = opaqueKey{cert.PrivateKey.(crypto.Signer)}
+		serverConfig.Certificates = []Certificate{cert}
+
+		clientConfig := &tls.Config{
+			InsecureSkipVerify: true,
+			MaxVersion:         test.vers,
+			CipherSuites:       test.suites,
+		}
+		state, _, err := testTLS13Handshake(clientConfig, serverConfig)
+		if err != nil {
+			t.Errorf("%s: handshake failed: %s", test.name, err)
+			continue
+		}
+		if state.Version != test.vers {
+			t.Errorf("%s: unexpected version %x", test.name, state.Version)
+		}
+	}
+}
+
+// readTestAlert reads a plaintext alert record from conn.
+func readTestAlert(conn net.Conn) (alert, error) {
+	record := make([]byte, recordHeaderLen+2)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := io.ReadFull(conn, record); err != nil {
+		return 0, err
+	}
+	if recordType(record[0]) != recordTypeAlert {
+		return 0, fmt.Errorf("unexpected record type %d", record[0])
+	}
+	return alert(record[recordHeaderLen+1]), nil
+}
+
+func TestTLS13SkipEarlyDataLimit(t *testing.T) {
+	client, server, err := localPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	defer server.Close()
+
+	c := &Conn{conn: server, config: newTLS13ServerConfig(), vers: VersionTLS13, haveVers: true,
+		skipEarlyData: true}
+
+	// records of 0-RTT data are skipped until maxEarlyDataSize is exceeded
+	n := 1000
+	record := make([]byte, recordHeaderLen+n)
+	record[0], record[1], record[2] = byte(recordTypeApplicationData), 3, 3
+	record[3], record[4] = byte(n>>8), byte(n)
+	go func() {
+		for i := 0; i <= maxEarlyDataSize/n; i++ {
+			client.Write(record)
+		}
+	}()
+
+	if err := c.readRecord(recordTypeHandshake); err == nil {
+		t.Fatalf("readRecord() should fail if too much early data skipped")
+	}
+	if c.earlyDataSkipped <= maxEarlyDataSize {
+		t.Errorf("unexpected bytes of early data skipped: %d", c.earlyDataSkipped)
+	}
+	if a, err := readTestAlert(client); err != nil || a != alertUnexpectedMessage {
+		t.Errorf("unexpected_message alert should be sent: %v %v", a, err)
+	}
+}
+
+func TestTLS13KeyUpdateNotAtRecordEnd(t *testing.T) {
+	client, server, err := localPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	defer server.Close()
+
+	c := &Conn{conn: server, config: newTLS13ServerConfig(), vers: VersionTLS13, haveVers: true}
+
+	// KeyUpdate followed by another message in the same record
+	msg := new(keyUpdateMsg).marshal()
+	c.hand.Write(msg)
+	c.hand.Write(msg)
+	if err := c.handlePostHandshakeMessage(); err == nil {
+		t.Fatalf("handlePostHandshakeMessage() should fail for KeyUpdate not at end of record")
+	}
+	if a, err := readTestAlert(client); err != nil || a != alertUnexpectedMessage {
+		t.Errorf("unexpected_message alert should be sent: %v %v", a, err)
+	}
+}
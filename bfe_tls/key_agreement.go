@@ -20,6 +20,7 @@ package bfe_tls
 
 import (
 	"crypto"
+	"crypto/ecdh"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/md5"
@@ -33,6 +34,13 @@ import (
 	"math/big"
 )
 
+import (
+	"github.com/cloudflare/circl/kem/mlkem/mlkem768"
+)
+
+// x25519PublicKeySize is the size of X25519 public key. See RFC 7748
+const x25519PublicKeySize = 32
+
 var errClientKeyExchange = errors.New("tls: invalid ClientKeyExchange message")
 var errServerKeyExchange = errors.New("tls: invalid ServerKeyExchange message")
 
@@ -45,12 +53,6 @@ func (ka rsaKeyAgreement) generateServerKeyExchange(config *Config, cert *Certif
 }
 
 func (ka rsaKeyAgreement) processClientKeyExchange(config *Config, cert *Certificate, ckx *clientKeyExchangeMsg, version uint16) ([]byte, error) {
-	preMasterSecret := make([]byte, 48)
-	_, err := io.ReadFull(config.rand(), preMasterSecret[2:])
-	if err != nil {
-		return nil, err
-	}
-
 	if len(ckx.ciphertext) < 2 {
 		return nil, errClientKeyExchange
 	}
@@ -70,7 +72,15 @@ func (ka rsaKeyAgreement) processClientKeyExchange(config *Config, cert *Certifi
 		return nil, errClientKeyExchange
 	}
 
-	err = rsa.DecryptPKCS1v15SessionKey(config.rand(), cert.PrivateKey.(*rsa.PrivateKey), ciphertext, preMasterSecret)
+	// Note: private key may be remote (eg. keyless). With SessionKeyLen set,
+	// a random pre-master secret is returned for invalid padding instead of
+	// an error, as rsa.DecryptPKCS1v15SessionKey does
+	decrypter, ok := cert.PrivateKey.(crypto.Decrypter)
+	if !ok {
+		return nil, errors.New("tls: certificate private key does not implement crypto.Decrypter")
+	}
+	preMasterSecret, err := decrypter.Decrypt(config.rand(), ciphertext,
+		&rsa.PKCS1v15DecryptOptions{SessionKeyLen: 48})
 	if err != nil {
 		return nil, err
 	}
@@ -201,6 +211,92 @@ func CurveForCurveID(id CurveID) (elliptic.Curve, bool) {
 	return curveForCurveID(id)
 }
 
+// ecdhCurveForCurveID returns the ECDH curve for TLS 1.3 key exchange.
+func ecdhCurveForCurveID(id CurveID) (ecdh.Curve, bool) {
+	switch id {
+	case X25519:
+		return ecdh.X25519(), true
+	case CurveP256:
+		return ecdh.P256(), true
+	case CurveP384:
+		return ecdh.P384(), true
+	case CurveP521:
+		return ecdh.P521(), true
+	default:
+		return nil, false
+	}
+}
+
+// generateServerKeyShare generates the server key share and the shared
+// secret for TLS 1.3 key exchange, given the key share of the client.
+// See RFC 8446, Section 4.2.8
+func generateServerKeyShare(rand io.Reader, group CurveID, clientShare []byte) (serverShare, sharedKey []byte, err error) {
+	if group == X25519MLKEM768 {
+		return generateHybridServerKeyShare(rand, clientShare)
+	}
+
+	curve, ok := ecdhCurveForCurveID(group)
+	if !ok {
+		return nil, nil, errors.New("tls: unsupported key share group")
+	}
+	peerKey, err := curve.NewPublicKey(clientShare)
+	if err != nil {
+		return nil, nil, errors.New("tls: invalid client key share")
+	}
+	key, err := curve.GenerateKey(rand)
+	if err != nil {
+		return nil, nil, err
+	}
+	sharedKey, err = key.ECDH(peerKey)
+	if err != nil {
+		return nil, nil, errors.New("tls: invalid client key share")
+	}
+	return key.PublicKey().Bytes(), sharedKey, nil
+}
+
+// generateHybridServerKeyShare generates the server key share and the shared
+// secret for X25519MLKEM768. The client key share is the ML-KEM-768
+// encapsulation key followed by the X25519 public key, and the server key
+// share is the ML-KEM-768 ciphertext followed by the X25519 public key.
+// The shared secret is the ML-KEM-768 shared secret followed by the X25519
+// shared secret.
+// See https://datatracker.ietf.org/doc/draft-ietf-tls-ecdhe-mlkem/
+func generateHybridServerKeyShare(rand io.Reader, clientShare []byte) (serverShare, sharedKey []byte, err error) {
+	if len(clientShare) != mlkem768.PublicKeySize+x25519PublicKeySize {
+		return nil, nil, errors.New("tls: invalid client key share")
+	}
+
+	encapKey := new(mlkem768.PublicKey)
+	if err := encapKey.Unpack(clientShare[:mlkem768.PublicKeySize]); err != nil {
+		return nil, nil, errors.New("tls: invalid client key share")
+	}
+	seed := make([]byte, mlkem768.EncapsulationSeedSize)
+	if _, err := io.ReadFull(rand, seed); err != nil {
+		return nil, nil, err
+	}
+	ciphertext := make([]byte, mlkem768.CiphertextSize)
+	mlkemSharedKey := make([]byte, mlkem768.SharedKeySize)
+	encapKey.EncapsulateTo(ciphertext, mlkemSharedKey, seed)
+
+	x25519Share, x25519SharedKey, err := generateServerKeyShare(rand, X25519, clientShare[mlkem768.PublicKeySize:])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serverShare = append(ciphertext, x25519Share...)
+	sharedKey = append(mlkemSharedKey, x25519SharedKey...)
+	return serverShare, sharedKey, nil
+}
+
+// isSupportedKeyShareGroup reports whether group could be used for TLS 1.3 key exchange.
+func isSupportedKeyShareGroup(group CurveID) bool {
+	if group == X25519MLKEM768 {
+		return true
+	}
+	_, ok := ecdhCurveForCurveID(group)
+	return ok
+}
+
 // ecdheRSAKeyAgreement implements a TLS key agreement where the server
 // generates a ephemeral EC public/private key pair and signs it. The
 // pre-master secret is then calculated using ECDH. The signature may
@@ -211,11 +307,17 @@ type ecdheKeyAgreement struct {
 	privateKey []byte
 	curve      elliptic.Curve
 	x, y       *big.Int
+
+	preferredCurves []CurveID // curves in preference order (in server side)
+	curveid         CurveID   // selected curve (in server side)
 }
 
 func (ka *ecdheKeyAgreement) generateServerKeyExchange(config *Config, cert *Certificate, clientHello *clientHelloMsg, hello *serverHelloMsg) (*serverKeyExchangeMsg, error) {
 	var curveid CurveID
-	preferredCurves := config.curvePreferences()
+	preferredCurves := ka.preferredCurves
+	if len(preferredCurves) == 0 {
+		preferredCurves = config.legacyCurvePreferences()
+	}
 
 NextCandidate:
 	for _, candidate := range preferredCurves {
@@ -235,6 +337,7 @@ NextCandidate:
 	if ka.curve, ok = curveForCurveID(curveid); !ok {
 		return nil, errors.New("tls: preferredCurves includes unsupported curve")
 	}
+	ka.curveid = curveid
 
 	var x, y *big.Int
 	var err error
@@ -263,33 +366,28 @@ NextCandidate:
 	if err != nil {
 		return nil, err
 	}
-	var sig []byte
+	// Note: private key may be remote (eg. keyless), so sign by crypto.Signer
+	signer, ok := cert.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("tls: certificate private key does not implement crypto.Signer")
+	}
 	switch ka.sigType {
 	case signatureECDSA:
-		privKey, ok := cert.PrivateKey.(*ecdsa.PrivateKey)
-		if !ok {
+		if _, ok := signer.Public().(*ecdsa.PublicKey); !ok {
 			return nil, errors.New("ECDHE ECDSA requires an ECDSA server private key")
 		}
-		r, s, err := ecdsa.Sign(config.rand(), privKey, digest)
-		if err != nil {
-			return nil, errors.New("failed to sign ECDHE parameters: " + err.Error())
-		}
-		sig, err = asn1.Marshal(ecdsaSignature{r, s})
-		if err != nil {
-			return nil, errors.New("failed to sign ECDHE asn1 marshal: " + err.Error())
-		}
 	case signatureRSA:
-		privKey, ok := cert.PrivateKey.(*rsa.PrivateKey)
-		if !ok {
+		if _, ok := signer.Public().(*rsa.PublicKey); !ok {
 			return nil, errors.New("ECDHE RSA requires a RSA server private key")
 		}
-		sig, err = rsa.SignPKCS1v15(config.rand(), privKey, hashFunc, digest)
-		if err != nil {
-			return nil, errors.New("failed to sign ECDHE parameters: " + err.Error())
-		}
 	default:
 		return nil, errors.New("unknown ECDHE signature algorithm")
 	}
+	// Note: signature of ecdsa signer is asn1 encoded ecdsaSignature
+	sig, err := signer.Sign(config.rand(), digest, hashFunc)
+	if err != nil {
+		return nil, errors.New("failed to sign ECDHE parameters: " + err.Error())
+	}
 
 	skx := new(serverKeyExchangeMsg)
 	sigAndHashLen := 0
@@ -0,0 +1,419 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// online revocation checking of client certificates
+//
+// Revocation status of certificate is checked by ocsp responder (RFC 6960)
+// or crl distribution point (RFC 5280) in certificate. Results are cached
+// until next update of ocsp response or crl, and failures are cached for a
+// short time to avoid adding latency to every handshake during outage.
+//
+// Results in use are refreshed in background halfway before they expire, so
+// only the first check of a certificate (or of an issuer for crl) waits for
+// fetching in handshake. If refreshing fails, the last valid result is kept.
+//
+// Note: Only leaf certificate is checked online. Intermediate certificates
+// could be revoked by crl files of client CA. Resumed sessions are not
+// checked again until session ticket or session cache expires.
+
+package bfe_tls
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+import (
+	"github.com/bfenetworks/go-lib/log"
+	"golang.org/x/crypto/ocsp"
+)
+
+const (
+	// max size of ocsp response or crl
+	maxRevocationResponseSize = 10 * 1024 * 1024
+
+	// max number of cached results
+	maxRevocationCacheSize = 100000
+
+	// cache time for results without next update
+	revocationCacheTime = time.Hour
+
+	// cache time for failures
+	revocationFailureCacheTime = time.Minute
+
+	// interval for checking results to refresh
+	revocationRefreshInterval = 10 * time.Second
+)
+
+// ErrCertRevoked is returned if certificate is revoked.
+var ErrCertRevoked = errors.New("certificate revoked")
+
+var errNoRevocationSource = errors.New("no ocsp responder or crl distribution point")
+
+// revocationEntry is cached result of ocsp response or crl.
+type revocationEntry struct {
+	revoked map[string]bool // serial numbers (hex) of revoked certificates
+	err     error           // error if status unknown
+	expire  time.Time       // time when entry expires
+
+	fetched time.Time                            // time when entry is fetched
+	used    bool                                 // used by checks since fetched or not
+	refetch func(now time.Time) *revocationEntry // fetches entry again
+}
+
+// refreshTime returns time for refreshing entry in background.
+func (e *revocationEntry) refreshTime() time.Time {
+	return e.fetched.Add(e.expire.Sub(e.fetched) / 2)
+}
+
+// RevocationFetcher fetches and caches ocsp responses and crls. It is
+// shared by revocation checkers.
+type RevocationFetcher struct {
+	client *http.Client
+
+	lock     sync.Mutex
+	cache    map[string]*revocationEntry
+	inflight map[string]chan struct{} // fetches in progress
+}
+
+// NewRevocationFetcher creates fetcher with given timeout for fetching.
+func NewRevocationFetcher(timeout time.Duration) *RevocationFetcher {
+	return &RevocationFetcher{
+		client:   &http.Client{Timeout: timeout},
+		cache:    make(map[string]*revocationEntry),
+		inflight: make(map[string]chan struct{}),
+	}
+}
+
+// load returns cached entry for key, or fetches it. Concurrent fetches for
+// the same key are merged.
+func (f *RevocationFetcher) load(key string, fetch func(now time.Time) *revocationEntry) *revocationEntry {
+	for {
+		now := time.Now()
+
+		f.lock.Lock()
+		if e, ok := f.cache[key]; ok && now.Before(e.expire) {
+			e.used = true
+			f.lock.Unlock()
+			state.TlsRevocationCacheHit.Inc(1)
+			return e
+		}
+		if wait, ok := f.inflight[key]; ok {
+			f.lock.Unlock()
+			<-wait
+			continue
+		}
+		wait := make(chan struct{})
+		f.inflight[key] = wait
+		f.lock.Unlock()
+
+		e := fetch(now)
+		e.fetched = now
+		e.refetch = fetch
+
+		f.lock.Lock()
+		if len(f.cache) >= maxRevocationCacheSize {
+			f.pruneLocked(now)
+		}
+		f.cache[key] = e
+		delete(f.inflight, key)
+		f.lock.Unlock()
+		close(wait)
+
+		return e
+	}
+}
+
+// refresh fetches entries which are used since fetched and due to refresh.
+// The last valid entry is kept if fetch fails.
+func (f *RevocationFetcher) refresh(now time.Time) {
+	f.lock.Lock()
+	dues := make(map[string]*revocationEntry)
+	for key, e := range f.cache {
+		if _, ok := f.inflight[key]; ok {
+			continue
+		}
+		if e.used && !now.Before(e.refreshTime()) {
+			dues[key] = e
+		}
+	}
+	f.lock.Unlock()
+
+	for key, e := range dues {
+		newEntry := e.refetch(now)
+		newEntry.fetched = now
+		newEntry.refetch = e.refetch
+
+		f.lock.Lock()
+		if f.cache[key] == e {
+			if newEntry.err != nil && e.err == nil && now.Before(e.expire) {
+				// retry later, halfway before last valid entry expires
+				e.fetched = now
+			} else {
+				f.cache[key] = newEntry
+			}
+		}
+		f.lock.Unlock()
+	}
+}
+
+// Start starts to refresh results in use in background.
+func (f *RevocationFetcher) Start() {
+	go func() {
+		ticker := time.NewTicker(revocationRefreshInterval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			f.refresh(now)
+		}
+	}()
+}
+
+// pruneLocked removes expired entries, or all entries if none expired.
+func (f *RevocationFetcher) pruneLocked(now time.Time) {
+	for key, e := range f.cache {
+		if !now.Before(e.expire) {
+			delete(f.cache, key)
+		}
+	}
+	if len(f.cache) >= maxRevocationCacheSize {
+		f.cache = make(map[string]*revocationEntry)
+	}
+}
+
+// expireTime returns time when result with given next update expires.
+func expireTime(nextUpdate time.Time, now time.Time) time.Time {
+	if nextUpdate.IsZero() {
+		return now.Add(revocationCacheTime)
+	}
+	return nextUpdate
+}
+
+func failureEntry(err error, now time.Time) *revocationEntry {
+	return &revocationEntry{err: err, expire: now.Add(revocationFailureCacheTime)}
+}
+
+func (f *RevocationFetcher) get(req *http.Request) ([]byte, error) {
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxRevocationResponseSize))
+}
+
+// checkOcsp checks certificate by ocsp responder.
+func (f *RevocationFetcher) checkOcsp(cert, issuer *x509.Certificate) error {
+	responder := cert.OCSPServer[0]
+	key := "ocsp_" + responder + "_" + certIssuerKey(issuer) + "_" + cert.SerialNumber.Text(16)
+	e := f.load(key, func(now time.Time) *revocationEntry {
+		state.TlsRevocationOcspFetch.Inc(1)
+		e, err := f.fetchOcsp(responder, cert, issuer, now)
+		if err != nil {
+			state.TlsRevocationOcspFetchErr.Inc(1)
+			log.Logger.Warn("tls: fetch ocsp response from %s: %v", responder, err)
+			return failureEntry(err, now)
+		}
+		return e
+	})
+
+	return e.check(cert)
+}
+
+func (f *RevocationFetcher) fetchOcsp(responder string, cert, issuer *x509.Certificate,
+	now time.Time) (*revocationEntry, error) {
+	reqData, err := ocsp.CreateRequest(cert, issuer, nil)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, responder, bytes.NewReader(reqData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/ocsp-request")
+	req.Header.Set("Accept", "application/ocsp-response")
+
+	data, err := f.get(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// check signature and serial number
+	resp, err := ocsp.ParseResponseForCert(data, cert, issuer)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.NextUpdate.IsZero() && !now.Before(resp.NextUpdate) {
+		return nil, fmt.Errorf("ocsp response expired at %s", resp.NextUpdate)
+	}
+
+	e := &revocationEntry{
+		revoked: make(map[string]bool),
+		expire:  expireTime(resp.NextUpdate, now),
+	}
+	switch resp.Status {
+	case ocsp.Good:
+	case ocsp.Revoked:
+		e.revoked[cert.SerialNumber.Text(16)] = true
+	default:
+		e.err = fmt.Errorf("ocsp status is unknown")
+	}
+	return e, nil
+}
+
+// checkCrl checks certificate by crl distribution point.
+func (f *RevocationFetcher) checkCrl(url string, cert, issuer *x509.Certificate) error {
+	key := "crl_" + url + "_" + certIssuerKey(issuer)
+	e := f.load(key, func(now time.Time) *revocationEntry {
+		state.TlsRevocationCrlFetch.Inc(1)
+		e, err := f.fetchCrl(url, issuer, now)
+		if err != nil {
+			state.TlsRevocationCrlFetchErr.Inc(1)
+			log.Logger.Warn("tls: fetch crl from %s: %v", url, err)
+			return failureEntry(err, now)
+		}
+		return e
+	})
+
+	return e.check(cert)
+}
+
+func (f *RevocationFetcher) fetchCrl(url string, issuer *x509.Certificate,
+	now time.Time) (*revocationEntry, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := f.get(req)
+	if err != nil {
+		return nil, err
+	}
+	if block, _ := pem.Decode(data); block != nil {
+		data = block.Bytes
+	}
+
+	crl, err := x509.ParseRevocationList(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := crl.CheckSignatureFrom(issuer); err != nil {
+		return nil, err
+	}
+	if !crl.NextUpdate.IsZero() && !now.Before(crl.NextUpdate) {
+		return nil, fmt.Errorf("crl expired at %s", crl.NextUpdate)
+	}
+
+	e := &revocationEntry{
+		revoked: make(map[string]bool, len(crl.RevokedCertificateEntries)),
+		expire:  expireTime(crl.NextUpdate, now),
+	}
+	for _, entry := range crl.RevokedCertificateEntries {
+		e.revoked[entry.SerialNumber.Text(16)] = true
+	}
+	return e, nil
+}
+
+func (e *revocationEntry) check(cert *x509.Certificate) error {
+	if e.err != nil {
+		return e.err
+	}
+	if e.revoked[cert.SerialNumber.Text(16)] {
+		return ErrCertRevoked
+	}
+	return nil
+}
+
+// certIssuerKey returns key for issuer in cache.
+func certIssuerKey(issuer *x509.Certificate) string {
+	digest := sha256.Sum256(issuer.Raw)
+	return hex.EncodeToString(digest[:])
+}
+
+// crlDistributionPoint returns the first http url of crl distribution
+// points in certificate.
+func crlDistributionPoint(cert *x509.Certificate) string {
+	for _, url := range cert.CRLDistributionPoints {
+		if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
+			return url
+		}
+	}
+	return ""
+}
+
+// RevocationChecker checks revocation status of client certificate online.
+type RevocationChecker struct {
+	Fetcher *RevocationFetcher
+
+	// check by ocsp responder in certificate
+	OCSP bool
+
+	// check by crl distribution point in certificate. If OCSP is also
+	// enabled, crl is used only if ocsp status is unknown
+	CRL bool
+
+	// reject certificate if revocation status is unknown (hard-fail), or
+	// accept it (soft-fail)
+	HardFail bool
+}
+
+// Check checks revocation status of cert issued by issuer. It returns
+// ErrCertRevoked if cert is revoked, or other error if revocation status
+// is unknown for hard-fail policy.
+func (c *RevocationChecker) Check(cert, issuer *x509.Certificate) error {
+	err := c.check(cert, issuer)
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, ErrCertRevoked) {
+		state.TlsRevocationCertRevoked.Inc(1)
+		return err
+	}
+	if c.HardFail {
+		state.TlsRevocationHardFail.Inc(1)
+		return err
+	}
+	state.TlsRevocationSoftFail.Inc(1)
+	return nil
+}
+
+func (c *RevocationChecker) check(cert, issuer *x509.Certificate) error {
+	err := errNoRevocationSource
+	if c.OCSP && len(cert.OCSPServer) > 0 {
+		err = c.Fetcher.checkOcsp(cert, issuer)
+		if err == nil || errors.Is(err, ErrCertRevoked) {
+			return err
+		}
+	}
+	if c.CRL {
+		if url := crlDistributionPoint(cert); len(url) > 0 {
+			err = c.Fetcher.checkCrl(url, cert, issuer)
+		}
+	}
+	return err
+}
@@ -0,0 +1,350 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bfe_tls
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+import (
+	"golang.org/x/crypto/ocsp"
+)
+
+// testRevocationServer is ocsp responder and crl distribution point for testing.
+type testRevocationServer struct {
+	*httptest.Server
+
+	ca    *x509.Certificate
+	caKey crypto.Signer
+
+	lock         sync.Mutex
+	revoked      map[string]bool // serial numbers (hex) of revoked certificates
+	ocspFail     bool
+	crlFail      bool
+	ocspRequests int
+	crlRequests  int
+}
+
+func newTestRevocationServer(t *testing.T) *testRevocationServer {
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test client ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, caKey.Public(), caKey)
+	if err != nil {
+		t.Fatalf("create ca certificate: %v", err)
+	}
+	ca, _ := x509.ParseCertificate(der)
+
+	s := &testRevocationServer{ca: ca, caKey: caKey, revoked: make(map[string]bool)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ocsp", s.serveOcsp)
+	mux.HandleFunc("/crl", s.serveCrl)
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *testRevocationServer) serveOcsp(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.ocspRequests++
+
+	data, _ := io.ReadAll(r.Body)
+	req, err := ocsp.ParseRequest(data)
+	if s.ocspFail || err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	template := ocsp.Response{
+		Status:       ocsp.Good,
+		SerialNumber: req.SerialNumber,
+		ThisUpdate:   time.Now().Add(-time.Hour),
+		NextUpdate:   time.Now().Add(time.Hour),
+	}
+	if s.revoked[req.SerialNumber.Text(16)] {
+		template.Status = ocsp.Revoked
+		template.RevokedAt = time.Now().Add(-time.Hour)
+	}
+	resp, _ := ocsp.CreateResponse(s.ca, s.ca, template, s.caKey)
+	w.Write(resp)
+}
+
+func (s *testRevocationServer) serveCrl(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.crlRequests++
+
+	if s.crlFail {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	template := &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Now().Add(-time.Hour),
+		NextUpdate: time.Now().Add(time.Hour),
+	}
+	for serial := range s.revoked {
+		n, _ := new(big.Int).SetString(serial, 16)
+		template.RevokedCertificateEntries = append(template.RevokedCertificateEntries,
+			x509.RevocationListEntry{SerialNumber: n, RevocationTime: time.Now().Add(-time.Hour)})
+	}
+	crl, _ := x509.CreateRevocationList(rand.Reader, template, s.ca, s.caKey)
+	w.Write(crl)
+}
+
+func (s *testRevocationServer) set(f func(s *testRevocationServer)) {
+	s.lock.Lock()
+	f(s)
+	s.lock.Unlock()
+}
+
+func (s *testRevocationServer) requests() (int, int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.ocspRequests, s.crlRequests
+}
+
+// issue issues client certificate, with ocsp responder and crl
+// distribution point if enabled.
+func (s *testRevocationServer) issue(t *testing.T, serial int64, withOcsp, withCrl bool) tls.Certificate {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if withOcsp {
+		template.OCSPServer = []string{s.URL + "/ocsp"}
+	}
+	if withCrl {
+		template.CRLDistributionPoints = []string{s.URL + "/crl"}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, s.ca, key.Public(), s.caKey)
+	if err != nil {
+		t.Fatalf("create client certificate: %v", err)
+	}
+	leaf, _ := x509.ParseCertificate(der)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func TestRevocationCheckerOCSP(t *testing.T) {
+	s := newTestRevocationServer(t)
+	s.revoked["3"] = true
+	checker := &RevocationChecker{Fetcher: NewRevocationFetcher(time.Second), OCSP: true}
+
+	good := s.issue(t, 2, true, true)
+	for i := 0; i < 2; i++ {
+		if err := checker.Check(good.Leaf, s.ca); err != nil {
+			t.Fatalf("good certificate should be accepted: %v", err)
+		}
+	}
+	if ocspRequests, crlRequests := s.requests(); ocspRequests != 1 || crlRequests != 0 {
+		t.Errorf("result should be cached: %d ocsp requests, %d crl requests", ocspRequests, crlRequests)
+	}
+
+	revoked := s.issue(t, 3, true, true)
+	if err := checker.Check(revoked.Leaf, s.ca); !errors.Is(err, ErrCertRevoked) {
+		t.Errorf("revoked certificate should be rejected: %v", err)
+	}
+}
+
+func TestRevocationCheckerCRL(t *testing.T) {
+	s := newTestRevocationServer(t)
+	s.revoked["3"] = true
+	checker := &RevocationChecker{Fetcher: NewRevocationFetcher(time.Second), CRL: true}
+
+	good := s.issue(t, 2, true, true)
+	if err := checker.Check(good.Leaf, s.ca); err != nil {
+		t.Fatalf("good certificate should be accepted: %v", err)
+	}
+	revoked := s.issue(t, 3, true, true)
+	if err := checker.Check(revoked.Leaf, s.ca); !errors.Is(err, ErrCertRevoked) {
+		t.Errorf("revoked certificate should be rejected: %v", err)
+	}
+	if ocspRequests, crlRequests := s.requests(); ocspRequests != 0 || crlRequests != 1 {
+		t.Errorf("crl should be cached: %d ocsp requests, %d crl requests", ocspRequests, crlRequests)
+	}
+
+	// crl signed by other ca is rejected
+	other := newTestRevocationServer(t)
+	checker.HardFail = true
+	if err := checker.Check(good.Leaf, other.ca); err == nil || errors.Is(err, ErrCertRevoked) {
+		t.Errorf("crl with invalid signature should be rejected: %v", err)
+	}
+}
+
+func TestRevocationCheckerOCSPFallbackToCRL(t *testing.T) {
+	s := newTestRevocationServer(t)
+	s.revoked["3"] = true
+	s.ocspFail = true
+	checker := &RevocationChecker{Fetcher: NewRevocationFetcher(time.Second), OCSP: true, CRL: true,
+		HardFail: true}
+
+	good := s.issue(t, 2, true, true)
+	if err := checker.Check(good.Leaf, s.ca); err != nil {
+		t.Errorf("good certificate should be accepted: %v", err)
+	}
+	revoked := s.issue(t, 3, true, true)
+	if err := checker.Check(revoked.Leaf, s.ca); !errors.Is(err, ErrCertRevoked) {
+		t.Errorf("revoked certificate should be rejected: %v", err)
+	}
+}
+
+func TestRevocationCheckerFailPolicy(t *testing.T) {
+	s := newTestRevocationServer(t)
+	s.ocspFail = true
+	fetcher := NewRevocationFetcher(time.Second)
+	soft := &RevocationChecker{Fetcher: fetcher, OCSP: true}
+	hard := &RevocationChecker{Fetcher: fetcher, OCSP: true, HardFail: true}
+
+	cert := s.issue(t, 2, true, false)
+	if err := soft.Check(cert.Leaf, s.ca); err != nil {
+		t.Errorf("soft-fail should accept certificate: %v", err)
+	}
+	if err := hard.Check(cert.Leaf, s.ca); err == nil || errors.Is(err, ErrCertRevoked) {
+		t.Errorf("hard-fail should reject certificate: %v", err)
+	}
+	if ocspRequests, _ := s.requests(); ocspRequests != 1 {
+		t.Errorf("failure should be cached: %d ocsp requests", ocspRequests)
+	}
+
+	// certificate without ocsp responder or crl distribution point
+	cert = s.issue(t, 3, false, false)
+	if err := soft.Check(cert.Leaf, s.ca); err != nil {
+		t.Errorf("soft-fail should accept certificate: %v", err)
+	}
+	if err := hard.Check(cert.Leaf, s.ca); err == nil {
+		t.Errorf("hard-fail should reject certificate")
+	}
+}
+
+func TestRevocationFetcherRefresh(t *testing.T) {
+	s := newTestRevocationServer(t)
+	fetcher := NewRevocationFetcher(time.Second)
+	checker := &RevocationChecker{Fetcher: fetcher, OCSP: true, HardFail: true}
+	cert := s.issue(t, 2, true, false)
+
+	// result not used since fetched is not refreshed
+	if err := checker.Check(cert.Leaf, s.ca); err != nil {
+		t.Fatalf("good certificate should be accepted: %v", err)
+	}
+	fetcher.refresh(time.Now().Add(45 * time.Minute))
+	if ocspRequests, _ := s.requests(); ocspRequests != 1 {
+		t.Errorf("unused result should not be refreshed: %d ocsp requests", ocspRequests)
+	}
+
+	// result in use is refreshed halfway before it expires
+	checker.Check(cert.Leaf, s.ca)
+	fetcher.refresh(time.Now())
+	if ocspRequests, _ := s.requests(); ocspRequests != 1 {
+		t.Errorf("result should not be refreshed early: %d ocsp requests", ocspRequests)
+	}
+	fetcher.refresh(time.Now().Add(45 * time.Minute))
+	if ocspRequests, _ := s.requests(); ocspRequests != 2 {
+		t.Errorf("result should be refreshed: %d ocsp requests", ocspRequests)
+	}
+
+	// last valid result is kept if refreshing fails
+	s.set(func(s *testRevocationServer) { s.ocspFail = true })
+	checker.Check(cert.Leaf, s.ca)
+	fetcher.refresh(time.Now().Add(53 * time.Minute))
+	if err := checker.Check(cert.Leaf, s.ca); err != nil {
+		t.Errorf("last valid result should be kept: %v", err)
+	}
+	if ocspRequests, _ := s.requests(); ocspRequests != 3 {
+		t.Errorf("result should be refreshed: %d ocsp requests", ocspRequests)
+	}
+
+	// revocation is found in background, without fetching in check
+	s.set(func(s *testRevocationServer) {
+		s.ocspFail = false
+		s.revoked["2"] = true
+	})
+	fetcher.refresh(time.Now().Add(58 * time.Minute))
+	ocspRequests, _ := s.requests()
+	if err := checker.Check(cert.Leaf, s.ca); !errors.Is(err, ErrCertRevoked) {
+		t.Errorf("revoked certificate should be rejected: %v", err)
+	}
+	if n, _ := s.requests(); n != ocspRequests || n != 4 {
+		t.Errorf("check should use refreshed result: %d/%d ocsp requests", n, ocspRequests)
+	}
+}
+
+func TestRevocationCheckerHandshake(t *testing.T) {
+	s := newTestRevocationServer(t)
+	s.revoked["3"] = true
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(s.ca)
+	serverConfig := newTLS13ServerConfig()
+	serverConfig.Certificates = serverConfig.Certificates[:1]
+	serverConfig.ServerRule = &tls13TestRule{Rule{
+		NextProtos:   tls13TestNextProtos{"http/1.1"},
+		ClientAuth:   true,
+		ClientCAs:    clientCAs,
+		ClientCAName: "test",
+		ClientRevocation: &RevocationChecker{
+			Fetcher:  NewRevocationFetcher(time.Second),
+			OCSP:     true,
+			HardFail: true,
+		},
+	}}
+
+	good := s.issue(t, 2, true, false)
+	revoked := s.issue(t, 3, true, false)
+	for _, vers := range []uint16{tls.VersionTLS12, tls.VersionTLS13} {
+		clientConfig := &tls.Config{
+			InsecureSkipVerify: true,
+			MaxVersion:         vers,
+			Certificates:       []tls.Certificate{good},
+		}
+		if _, _, err := testTLS13Handshake(clientConfig, serverConfig); err != nil {
+			t.Errorf("handshake with good certificate failed: %v", err)
+		}
+
+		clientConfig.Certificates = []tls.Certificate{revoked}
+		_, _, err := testTLS13Handshake(clientConfig, serverConfig)
+		if err == nil || !strings.Contains(err.Error(), "revoked client certificate") {
+			t.Errorf("handshake with revoked certificate should fail: %v", err)
+		}
+	}
+}
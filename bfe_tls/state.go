@@ -35,6 +35,18 @@ type TlsState struct {
 	TlsHandshakeOcspTimeErr               *metrics.Counter
 	TlsStatusRequestExtCount              *metrics.Counter
 	TlsHandshakeZeroData                  *metrics.Counter
+	TlsHandshakeTLS13                     *metrics.Counter
+	TlsHandshakeHelloRetryRequest         *metrics.Counter
+
+	// online revocation checking of client certificates
+	TlsRevocationOcspFetch    *metrics.Counter
+	TlsRevocationOcspFetchErr *metrics.Counter
+	TlsRevocationCrlFetch     *metrics.Counter
+	TlsRevocationCrlFetchErr  *metrics.Counter
+	TlsRevocationCacheHit     *metrics.Counter
+	TlsRevocationCertRevoked  *metrics.Counter
+	TlsRevocationSoftFail     *metrics.Counter
+	TlsRevocationHardFail     *metrics.Counter
 }
 
 var state TlsState
@@ -41,6 +41,13 @@ type sessionState struct {
 
 	// Note: SessionId will not be serialized if ticket format is TicketFormatRaw
 	sessionId []byte // session id
+
+	// Note: createdAt is only serialized for TLS 1.3, in which masterSecret
+	// is the resumption PSK
+	createdAt uint64 // seconds since UNIX epoch
+
+	// Note: usedOldKey is not serialized
+	usedOldKey bool // ticket is encrypted by key older than the active key
 }
 
 func (s *sessionState) equal(i interface{}) bool {
@@ -51,7 +58,8 @@ func (s *sessionState) equal(i interface{}) bool {
 
 	if s.vers != s1.vers ||
 		s.cipherSuite != s1.cipherSuite ||
-		!bytes.Equal(s.masterSecret, s1.masterSecret) {
+		!bytes.Equal(s.masterSecret, s1.masterSecret) ||
+		s.createdAt != s1.createdAt {
 		return false
 	}
 
@@ -73,6 +81,9 @@ func (s *sessionState) marshal() []byte {
 	for _, cert := range s.certificates {
 		length += 4 + len(cert)
 	}
+	if s.vers >= VersionTLS13 {
+		length += 8
+	}
 
 	ret := make([]byte, length)
 	x := ret
@@ -99,6 +110,12 @@ func (s *sessionState) marshal() []byte {
 		x = x[4+len(cert):]
 	}
 
+	if s.vers >= VersionTLS13 {
+		for i := 0; i < 8; i++ {
+			x[i] = byte(s.createdAt >> uint(56-8*i))
+		}
+	}
+
 	return ret
 }
 
@@ -142,54 +159,104 @@ func (s *sessionState) unmarshal(data []byte) bool {
 		data = data[certLen:]
 	}
 
+	s.createdAt = 0
+	if s.vers >= VersionTLS13 {
+		if len(data) < 8 {
+			return false
+		}
+		for i := 0; i < 8; i++ {
+			s.createdAt = s.createdAt<<8 | uint64(data[i])
+		}
+		data = data[8:]
+	}
+
 	return len(data) <= 0
 }
 
 func (c *Conn) encryptTicket(state *sessionState) ([]byte, error) {
 	serialized := state.marshal()
-	encrypted := make([]byte, aes.BlockSize+len(serialized)+sha256.Size)
-	iv := encrypted[:aes.BlockSize]
+	encrypted := make([]byte, ticketKeyNameLen+aes.BlockSize+len(serialized)+sha256.Size)
+	keyName := encrypted[:ticketKeyNameLen]
+	iv := encrypted[ticketKeyNameLen : ticketKeyNameLen+aes.BlockSize]
 	macBytes := encrypted[len(encrypted)-sha256.Size:]
 
 	if _, err := io.ReadFull(c.config.rand(), iv); err != nil {
 		return nil, err
 	}
-	block, err := aes.NewCipher(c.config.SessionTicketKey[:16])
+	ticketKey := c.config.ticketKeys()[0]
+	copy(keyName, ticketKey.Name[:])
+	key := ticketKey.Key
+	block, err := aes.NewCipher(key[:16])
 	if err != nil {
 		return nil, errors.New("tls: failed to create cipher while encrypting ticket: " + err.Error())
 	}
-	cipher.NewCTR(block, iv).XORKeyStream(encrypted[aes.BlockSize:], serialized)
+	cipher.NewCTR(block, iv).XORKeyStream(encrypted[ticketKeyNameLen+aes.BlockSize:], serialized)
 
-	mac := hmac.New(sha256.New, c.config.SessionTicketKey[16:32])
+	mac := hmac.New(sha256.New, key[16:32])
 	mac.Write(encrypted[:len(encrypted)-sha256.Size])
 	mac.Sum(macBytes[:0])
 
 	return encrypted, nil
 }
 
+// decryptTicket decrypts a ticket in layout keyName|iv|ciphertext|mac.
+// Tickets in legacy layout iv|ciphertext|mac (issued before key name was
+// added) are still accepted while their key is in the key list.
 func (c *Conn) decryptTicket(encrypted []byte) (*sessionState, bool) {
-	if len(encrypted) < aes.BlockSize+sha256.Size {
-		return nil, false
+	keys := c.config.ticketKeys()
+
+	// find key by key name in ticket
+	keyIndex := -1
+	if len(encrypted) >= ticketKeyNameLen+aes.BlockSize+sha256.Size {
+		keyName := encrypted[:ticketKeyNameLen]
+		for i := range keys {
+			if bytes.Equal(keyName, keys[i].Name[:]) && checkTicketMac(keys[i].Key, encrypted) {
+				keyIndex = i
+				break
+			}
+		}
 	}
 
-	iv := encrypted[:aes.BlockSize]
-	macBytes := encrypted[len(encrypted)-sha256.Size:]
-
-	mac := hmac.New(sha256.New, c.config.SessionTicketKey[16:32])
-	mac.Write(encrypted[:len(encrypted)-sha256.Size])
-	expected := mac.Sum(nil)
+	var state *sessionState
+	ok := false
+	if keyIndex >= 0 {
+		state, ok = decryptTicketWithKey(keys[keyIndex].Key, encrypted, ticketKeyNameLen)
+	}
 
-	if subtle.ConstantTimeCompare(macBytes, expected) != 1 {
+	// Note: legacy ticket has no key name, so try mac of each key instead.
+	// The mac covers the same bytes in both layouts, and iv of legacy ticket
+	// may happen to equal a key name
+	if !ok && keyIndex < 0 && len(encrypted) >= aes.BlockSize+sha256.Size {
+		for i := range keys {
+			if checkTicketMac(keys[i].Key, encrypted) {
+				keyIndex = i
+				break
+			}
+		}
+	}
+	if !ok && keyIndex >= 0 {
+		state, ok = decryptTicketWithKey(keys[keyIndex].Key, encrypted, 0)
+	}
+	if !ok {
 		return nil, false
 	}
 
-	block, err := aes.NewCipher(c.config.SessionTicketKey[:16])
+	// Note: key of next interval (SEED rotation) may precede older keys, so
+	// key is checked by created time instead of index
+	state.usedOldKey = keyIndex > 0 && !keys[keyIndex].Created.After(keys[0].Created)
+	return state, true
+}
+
+// decryptTicketWithKey decrypts ticket whose iv starts at offset.
+func decryptTicketWithKey(key [32]byte, encrypted []byte, offset int) (*sessionState, bool) {
+	block, err := aes.NewCipher(key[:16])
 	if err != nil {
 		return nil, false
 	}
 
-	ciphertext := encrypted[aes.BlockSize : len(encrypted)-sha256.Size]
-	plaintext := ciphertext
+	iv := encrypted[offset : offset+aes.BlockSize]
+	ciphertext := encrypted[offset+aes.BlockSize : len(encrypted)-sha256.Size]
+	plaintext := make([]byte, len(ciphertext))
 	cipher.NewCTR(block, iv).XORKeyStream(plaintext, ciphertext)
 
 	state := new(sessionState)
@@ -197,6 +264,17 @@ func (c *Conn) decryptTicket(encrypted []byte) (*sessionState, bool) {
 	return state, ok
 }
 
+// checkTicketMac checks mac at the end of ticket, which covers all bytes before it.
+func checkTicketMac(key [32]byte, encrypted []byte) bool {
+	macBytes := encrypted[len(encrypted)-sha256.Size:]
+
+	mac := hmac.New(sha256.New, key[16:32])
+	mac.Write(encrypted[:len(encrypted)-sha256.Size])
+	expected := mac.Sum(nil)
+
+	return subtle.ConstantTimeCompare(macBytes, expected) == 1
+}
+
 func PKCS5Padding(ciphertext []byte, blockSize int) []byte {
 	padding := blockSize - len(ciphertext)%blockSize
 	padtext := bytes.Repeat([]byte{byte(padding)}, padding)
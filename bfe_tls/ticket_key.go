@@ -0,0 +1,93 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bfe_tls
+
+import (
+	"sync"
+	"time"
+)
+
+// TicketKey is a key for encrypting and decrypting session tickets.
+type TicketKey struct {
+	Name    [16]byte  // identifier of key
+	Key     [32]byte  // aes key (first 16 bytes) and hmac key (last 16 bytes)
+	Created time.Time // time when key is created
+}
+
+// NewTicketKey creates ticket key from raw key (48 bytes), in the same
+// format as session ticket key file.
+func NewTicketKey(raw []byte, created time.Time) TicketKey {
+	var key TicketKey
+	copy(key.Name[:], raw[:16])
+	copy(key.Key[:], raw[16:])
+	key.Created = created
+	return key
+}
+
+// SessionTicketKeys is an ordered set of session ticket keys, which may be
+// shared by configs. The first key is the active key for encrypting new
+// tickets, and all keys are accepted for decrypting tickets.
+type SessionTicketKeys struct {
+	lock sync.RWMutex
+	keys []TicketKey
+}
+
+// NewSessionTicketKeys creates set of session ticket keys.
+func NewSessionTicketKeys(keys ...TicketKey) *SessionTicketKeys {
+	k := new(SessionTicketKeys)
+	k.Set(keys)
+	return k
+}
+
+// Set replaces all keys. The first key is the active key.
+func (k *SessionTicketKeys) Set(keys []TicketKey) {
+	keys = append([]TicketKey(nil), keys...)
+
+	k.lock.Lock()
+	k.keys = keys
+	k.lock.Unlock()
+}
+
+// Rotate adds key as the active key. The previous keys are kept as
+// decrypt-only keys, with at most n keys in total.
+func (k *SessionTicketKeys) Rotate(key TicketKey, n int) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	keys := make([]TicketKey, 0, n)
+	keys = append(keys, key)
+	for _, old := range k.keys {
+		if len(keys) >= n {
+			break
+		}
+		if old.Name == key.Name && old.Key == key.Key {
+			continue
+		}
+		keys = append(keys, old)
+	}
+	k.keys = keys
+}
+
+// Keys returns a copy of keys.
+func (k *SessionTicketKeys) Keys() []TicketKey {
+	return append([]TicketKey(nil), k.get()...)
+}
+
+// get returns keys, which should not be modified.
+func (k *SessionTicketKeys) get() []TicketKey {
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+	return k.keys
+}
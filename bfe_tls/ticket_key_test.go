@@ -0,0 +1,192 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bfe_tls
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"testing"
+	"time"
+)
+
+func testTicketKey(b byte) TicketKey {
+	raw := make([]byte, 48)
+	for i := range raw {
+		raw[i] = b
+	}
+	return NewTicketKey(raw, time.Unix(int64(b), 0))
+}
+
+func TestSessionTicketKeysRotate(t *testing.T) {
+	keys := NewSessionTicketKeys(testTicketKey(1))
+	keys.Rotate(testTicketKey(2), 3)
+	keys.Rotate(testTicketKey(3), 3)
+	keys.Rotate(testTicketKey(4), 3)
+	keys.Rotate(testTicketKey(4), 3) // duplicated key is ignored
+
+	got := keys.Keys()
+	if len(got) != 3 {
+		t.Fatalf("unexpected number of keys: %d", len(got))
+	}
+	for i, b := range []byte{4, 3, 2} {
+		if got[i] != testTicketKey(b) {
+			t.Errorf("unexpected key %d: %v", i, got[i].Name)
+		}
+	}
+
+	// returned keys are copied
+	got[0] = testTicketKey(5)
+	if keys.Keys()[0] != testTicketKey(4) {
+		t.Errorf("keys should not be modified")
+	}
+}
+
+func TestSessionTicketKeysResumption(t *testing.T) {
+	for _, vers := range []uint16{VersionTLS12, VersionTLS13} {
+		keys := NewSessionTicketKeys(testTicketKey(1))
+		serverConfig := newTLS13ServerConfig()
+		serverConfig.SessionTicketKeys = keys
+		clientConfig := &tls.Config{
+			InsecureSkipVerify: true,
+			ServerName:         "example.golang",
+			ClientSessionCache: tls.NewLRUClientSessionCache(1),
+			Time:               testCertValidTime,
+			MaxVersion:         vers,
+		}
+
+		handshake := func() bool {
+			state, _, err := testTLS13Handshake(clientConfig, serverConfig)
+			if err != nil {
+				t.Fatalf("handshake failed: %s", err)
+			}
+			return state.DidResume
+		}
+
+		if handshake() {
+			t.Fatalf("unexpected resumption")
+		}
+
+		// ticket encrypted by decrypt-only key is accepted
+		keys.Rotate(testTicketKey(2), 2)
+		if !handshake() {
+			t.Fatalf("session should be resumed with decrypt-only key (version %x)", vers)
+		}
+
+		// ticket is reissued with active key
+		keys.Rotate(testTicketKey(3), 2)
+		if !handshake() {
+			t.Fatalf("session should be resumed with reissued ticket (version %x)", vers)
+		}
+
+		// ticket encrypted by removed key is ignored
+		keys.Set([]TicketKey{testTicketKey(4)})
+		if handshake() {
+			t.Fatalf("session should not be resumed with removed key (version %x)", vers)
+		}
+	}
+}
+
+func TestTicketKeyNameAndOldKey(t *testing.T) {
+	active, next, old := testTicketKey(2), testTicketKey(3), testTicketKey(1)
+	state := &sessionState{vers: VersionTLS12, cipherSuite: TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		masterSecret: make([]byte, 48)}
+
+	encrypt := func(key TicketKey) []byte {
+		c := &Conn{config: &Config{SessionTicketKeys: NewSessionTicketKeys(key)}}
+		ticket, err := c.encryptTicket(state)
+		if err != nil {
+			t.Fatalf("encryptTicket(): %s", err)
+		}
+		if string(ticket[:ticketKeyNameLen]) != string(key.Name[:]) {
+			t.Errorf("ticket should be prefixed with key name")
+		}
+		return ticket
+	}
+
+	// keys of SEED rotation: active key, key of next interval, and older keys
+	c := &Conn{config: &Config{SessionTicketKeys: NewSessionTicketKeys(active, next, old)}}
+	cases := []struct {
+		key        TicketKey
+		usedOldKey bool
+	}{
+		{active, false},
+		{next, false},
+		{old, true},
+	}
+	for _, cs := range cases {
+		s, ok := c.decryptTicket(encrypt(cs.key))
+		if !ok || !s.equal(state) {
+			t.Fatalf("decryptTicket() with key %d failed", cs.key.Name[0])
+		}
+		if s.usedOldKey != cs.usedOldKey {
+			t.Errorf("usedOldKey with key %d should be %v", cs.key.Name[0], cs.usedOldKey)
+		}
+	}
+
+	// ticket with unknown key name or invalid mac is rejected
+	ticket := encrypt(testTicketKey(4))
+	if _, ok := c.decryptTicket(ticket); ok {
+		t.Errorf("ticket with unknown key name should be rejected")
+	}
+	ticket = encrypt(active)
+	ticket[ticketKeyNameLen] ^= 0xff
+	if _, ok := c.decryptTicket(ticket); ok {
+		t.Errorf("ticket with invalid mac should be rejected")
+	}
+}
+
+func TestTicketLegacyLayout(t *testing.T) {
+	active, old := testTicketKey(2), testTicketKey(1)
+	state := &sessionState{vers: VersionTLS12, cipherSuite: TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		masterSecret: make([]byte, 48)}
+
+	// legacy layout: iv|ciphertext|mac, without key name
+	legacy := func(key TicketKey) []byte {
+		c := &Conn{config: &Config{SessionTicketKeys: NewSessionTicketKeys(key)}}
+		ticket, err := c.encryptTicket(state)
+		if err != nil {
+			t.Fatalf("encryptTicket(): %s", err)
+		}
+		ticket = ticket[ticketKeyNameLen:]
+		mac := hmac.New(sha256.New, key.Key[16:32])
+		mac.Write(ticket[:len(ticket)-sha256.Size])
+		mac.Sum(ticket[:len(ticket)-sha256.Size])
+		return ticket
+	}
+
+	c := &Conn{config: &Config{SessionTicketKeys: NewSessionTicketKeys(active, old)}}
+	cases := []struct {
+		key        TicketKey
+		usedOldKey bool
+	}{
+		{active, false},
+		{old, true},
+	}
+	for _, cs := range cases {
+		s, ok := c.decryptTicket(legacy(cs.key))
+		if !ok || !s.equal(state) {
+			t.Fatalf("decryptTicket() of legacy ticket with key %d failed", cs.key.Name[0])
+		}
+		if s.usedOldKey != cs.usedOldKey {
+			t.Errorf("usedOldKey with key %d should be %v", cs.key.Name[0], cs.usedOldKey)
+		}
+	}
+
+	// legacy ticket with unknown key is rejected
+	if _, ok := c.decryptTicket(legacy(testTicketKey(3))); ok {
+		t.Errorf("legacy ticket with unknown key should be rejected")
+	}
+}
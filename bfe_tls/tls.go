@@ -239,36 +239,13 @@ func LoadX509KeyPair(certFile, keyFile string) (cert Certificate, err error) {
 // X509KeyPair parses a public/private key pair from a pair of
 // PEM encoded data.
 func X509KeyPair(certPEMBlock, keyPEMBlock []byte) (cert Certificate, err error) {
-	var certDERBlock *pem.Block
-	for {
-		certDERBlock, certPEMBlock = pem.Decode(certPEMBlock)
-		if certDERBlock == nil {
-			break
-		}
-		if certDERBlock.Type == "CERTIFICATE" {
-			cert.Certificate = append(cert.Certificate, certDERBlock.Bytes)
-		}
-	}
-
-	if len(cert.Certificate) == 0 {
-		err = errors.New("crypto/tls: failed to parse certificate PEM data")
+	cert.Certificate, err = parseCertPEM(certPEMBlock)
+	if err != nil {
 		return
 	}
 	cert.buildCertMsg() // prebuild cert message for tls handshake
 
-	var keyDERBlock *pem.Block
-	for {
-		keyDERBlock, keyPEMBlock = pem.Decode(keyPEMBlock)
-		if keyDERBlock == nil {
-			err = errors.New("crypto/tls: failed to parse key PEM data")
-			return
-		}
-		if keyDERBlock.Type == "PRIVATE KEY" || strings.HasSuffix(keyDERBlock.Type, " PRIVATE KEY") {
-			break
-		}
-	}
-
-	cert.PrivateKey, err = parsePrivateKey(keyDERBlock.Bytes)
+	cert.PrivateKey, err = ParsePrivateKeyPEM(keyPEMBlock)
 	if err != nil {
 		return
 	}
@@ -309,6 +286,75 @@ func X509KeyPair(certPEMBlock, keyPEMBlock []byte) (cert Certificate, err error)
 	return
 }
 
+// X509CertWithKey parses a certificate chain from PEM encoded data, and
+// pairs it with the given private key, which may be one whose private part
+// is not available locally (eg. remote key of keyless tls).
+func X509CertWithKey(certPEMBlock []byte, key crypto.Signer) (cert Certificate, err error) {
+	cert.Certificate, err = parseCertPEM(certPEMBlock)
+	if err != nil {
+		return
+	}
+	cert.buildCertMsg() // prebuild cert message for tls handshake
+
+	x509Cert, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return
+	}
+	pub, ok := x509Cert.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok {
+		err = errors.New("crypto/tls: unknown public key algorithm")
+		return
+	}
+	if !pub.Equal(key.Public()) {
+		err = errors.New("crypto/tls: private key does not match public key")
+		return
+	}
+	cert.PrivateKey = key
+
+	return
+}
+
+// parseCertPEM returns DER blocks of certificates in PEM encoded data.
+func parseCertPEM(certPEMBlock []byte) ([][]byte, error) {
+	var certs [][]byte
+	var certDERBlock *pem.Block
+	for {
+		certDERBlock, certPEMBlock = pem.Decode(certPEMBlock)
+		if certDERBlock == nil {
+			break
+		}
+		if certDERBlock.Type == "CERTIFICATE" {
+			certs = append(certs, certDERBlock.Bytes)
+		}
+	}
+
+	if len(certs) == 0 {
+		return nil, errors.New("crypto/tls: failed to parse certificate PEM data")
+	}
+	return certs, nil
+}
+
+// ParsePrivateKeyPEM parses a private key from PEM encoded data. The key is
+// *rsa.PrivateKey or *ecdsa.PrivateKey.
+func ParsePrivateKeyPEM(keyPEMBlock []byte) (crypto.Signer, error) {
+	var keyDERBlock *pem.Block
+	for {
+		keyDERBlock, keyPEMBlock = pem.Decode(keyPEMBlock)
+		if keyDERBlock == nil {
+			return nil, errors.New("crypto/tls: failed to parse key PEM data")
+		}
+		if keyDERBlock.Type == "PRIVATE KEY" || strings.HasSuffix(keyDERBlock.Type, " PRIVATE KEY") {
+			break
+		}
+	}
+
+	key, err := parsePrivateKey(keyDERBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	return key.(crypto.Signer), nil
+}
+
 // Attempt to parse the given private key DER block. OpenSSL 0.9.8 generates
 // PKCS#1 private keys by default, while OpenSSL 1.0.0 generates PKCS#8 keys.
 // OpenSSL ecparam generates SEC1 EC private keys for ECDSA. We try all three.
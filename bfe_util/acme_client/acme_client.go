@@ -0,0 +1,565 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// client for obtaining certificates from acme server (RFC 8555)
+//
+// The client obtains one certificate for each configured host, with http-01
+// or tls-alpn-01 challenge. Account key, certificates and keys are persisted
+// in storage dir, and certificates are renewed before expiration.
+
+package acme_client
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+import (
+	"github.com/bfenetworks/go-lib/log"
+	"golang.org/x/crypto/acme"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_tls"
+)
+
+const (
+	ChallengeHTTP01    = "http-01"
+	ChallengeTLSALPN01 = "tls-alpn-01"
+
+	// path prefix of http-01 challenge request
+	HTTP01PathPrefix = "/.well-known/acme-challenge/"
+)
+
+const (
+	accountKeyFile = "account.key"  // private key of account
+	accountFile    = "account.json" // url of account
+	certFileSuffix = ".crt"         // certificate chain of host
+	keyFileSuffix  = ".key"         // private key of host
+
+	obtainTimeout = 5 * time.Minute // timeout for obtaining one certificate
+)
+
+// Config is config of acme client.
+type Config struct {
+	DirectoryURL   string        // directory url of acme server
+	Email          string        // contact email of account
+	HTTPClient     *http.Client  // http client for acme server, nil for default
+	StorageDir     string        // dir for account, certificates and keys
+	ChallengeTypes []string      // types of challenges, in order of preference
+	RenewBefore    time.Duration // renew certificate when it expires within RenewBefore
+	CheckInterval  time.Duration // interval for checking certificates
+}
+
+// CertState is state of certificate of one host.
+type CertState struct {
+	NotBefore     time.Time // validity of current certificate
+	NotAfter      time.Time
+	ObtainCount   int64 // number of certificates obtained
+	LastObtain    time.Time
+	ErrorCount    int64 // number of failures
+	LastError     string
+	LastErrorTime time.Time
+}
+
+// ClientState is state of acme client.
+type ClientState struct {
+	Account string                // url of account
+	Hosts   map[string]*CertState // host => state of certificate
+}
+
+type accountInfo struct {
+	URI string // url of account
+}
+
+type Client struct {
+	conf    Config
+	client  *acme.Client
+	trigger chan struct{}
+
+	lock      sync.Mutex
+	hosts     []string                        // hosts to obtain certificates for
+	certs     map[string]*bfe_tls.Certificate // host => certificate
+	http01    map[string]string               // token => key authorization
+	tlsALPN01 map[string]*bfe_tls.Certificate // host => challenge certificate
+	state     ClientState
+}
+
+// NewClient creates acme client, with account key loaded from storage dir
+// (or generated if not exist).
+func NewClient(conf Config) (*Client, error) {
+	if err := os.MkdirAll(conf.StorageDir, 0700); err != nil {
+		return nil, err
+	}
+
+	c := new(Client)
+	c.conf = conf
+	c.trigger = make(chan struct{}, 1)
+	c.certs = make(map[string]*bfe_tls.Certificate)
+	c.http01 = make(map[string]string)
+	c.tlsALPN01 = make(map[string]*bfe_tls.Certificate)
+	c.state.Hosts = make(map[string]*CertState)
+
+	key, err := c.loadAccountKey()
+	if err != nil {
+		return nil, fmt.Errorf("load account key: %s", err)
+	}
+	c.client = &acme.Client{
+		Key:          key,
+		DirectoryURL: conf.DirectoryURL,
+		HTTPClient:   conf.HTTPClient,
+		UserAgent:    "bfe",
+	}
+
+	// reuse registered account
+	var account accountInfo
+	if err := c.readJSON(accountFile, &account); err == nil && len(account.URI) > 0 {
+		c.client.KID = acme.KeyID(account.URI)
+		c.state.Account = account.URI
+	}
+
+	return c, nil
+}
+
+// SetHosts sets hosts to obtain certificates for. Certificates of new hosts
+// are loaded from storage dir, and missing ones are obtained in background.
+func (c *Client) SetHosts(hosts []string) {
+	c.lock.Lock()
+	hostMap := make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		hostMap[host] = true
+		if _, ok := c.state.Hosts[host]; ok {
+			continue
+		}
+
+		state := new(CertState)
+		c.state.Hosts[host] = state
+		cert, err := c.loadCert(host)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				log.Logger.Warn("acme client: load certificate for %s: %v", host, err)
+			}
+			continue
+		}
+		c.certs[host] = cert
+		state.NotBefore, state.NotAfter = cert.Leaf.NotBefore, cert.Leaf.NotAfter
+	}
+
+	// certificates of removed hosts are not served any more
+	for host := range c.state.Hosts {
+		if !hostMap[host] {
+			delete(c.state.Hosts, host)
+			delete(c.certs, host)
+		}
+	}
+	c.hosts = append([]string(nil), hosts...)
+	c.lock.Unlock()
+
+	select {
+	case c.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Start starts to check certificates periodically, and obtains certificates
+// which are missing or about to expire.
+func (c *Client) Start() {
+	go func() {
+		for {
+			c.CheckCerts()
+
+			select {
+			case <-time.After(c.conf.CheckInterval):
+			case <-c.trigger:
+			}
+		}
+	}()
+}
+
+// CheckCerts obtains certificates which are missing or about to expire.
+func (c *Client) CheckCerts() {
+	for _, host := range c.renewHosts() {
+		err := c.obtainCert(host)
+		if err != nil {
+			log.Logger.Warn("acme client: obtain certificate for %s: %v", host, err)
+		} else {
+			log.Logger.Info("acme client: obtain certificate for %s success", host)
+		}
+		c.setObtainResult(host, err)
+	}
+}
+
+// renewHosts returns hosts whose certificate should be obtained.
+func (c *Client) renewHosts() []string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var hosts []string
+	for _, host := range c.hosts {
+		cert, ok := c.certs[host]
+		if !ok || time.Until(cert.Leaf.NotAfter) < c.conf.RenewBefore {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+func (c *Client) obtainCert(host string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), obtainTimeout)
+	defer cancel()
+
+	if err := c.register(ctx); err != nil {
+		return fmt.Errorf("register account: %s", err)
+	}
+
+	order, err := c.client.AuthorizeOrder(ctx, acme.DomainIDs(host))
+	if err != nil {
+		return fmt.Errorf("create order: %s", err)
+	}
+	for _, url := range order.AuthzURLs {
+		if err := c.authorize(ctx, url); err != nil {
+			return err
+		}
+	}
+	order, err = c.client.WaitOrder(ctx, order.URI)
+	if err != nil {
+		return fmt.Errorf("wait order: %s", err)
+	}
+
+	// finalize order with new key
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	req := &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: host},
+		DNSNames: []string{host},
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, req, key)
+	if err != nil {
+		return err
+	}
+	der, _, err := c.client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return fmt.Errorf("finalize order: %s", err)
+	}
+
+	certPEM, keyPEM, err := encodeCert(der, key)
+	if err != nil {
+		return err
+	}
+	cert, err := parseCert(certPEM, keyPEM)
+	if err != nil {
+		return err
+	}
+	if err := cert.Leaf.VerifyHostname(host); err != nil {
+		return err
+	}
+
+	// save to storage dir before serving
+	if err := c.writeFile(host+keyFileSuffix, keyPEM); err != nil {
+		return err
+	}
+	if err := c.writeFile(host+certFileSuffix, certPEM); err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	if _, ok := c.state.Hosts[host]; ok {
+		c.certs[host] = cert
+	}
+	c.lock.Unlock()
+	return nil
+}
+
+// register registers account on acme server if not registered yet.
+func (c *Client) register(ctx context.Context) error {
+	if len(c.client.KID) > 0 {
+		return nil
+	}
+
+	account := new(acme.Account)
+	if len(c.conf.Email) > 0 {
+		account.Contact = []string{"mailto:" + c.conf.Email}
+	}
+	_, err := c.client.Register(ctx, account, acme.AcceptTOS)
+	if err != nil && err != acme.ErrAccountAlreadyExists {
+		return err
+	}
+
+	uri := string(c.client.KID)
+	if err := c.writeJSON(accountFile, accountInfo{URI: uri}); err != nil {
+		log.Logger.Warn("acme client: save account: %v", err)
+	}
+	c.lock.Lock()
+	c.state.Account = uri
+	c.lock.Unlock()
+	return nil
+}
+
+// authorize fulfills challenge of authorization, and waits until it is valid.
+func (c *Client) authorize(ctx context.Context, url string) error {
+	authz, err := c.client.GetAuthorization(ctx, url)
+	if err != nil {
+		return fmt.Errorf("get authorization: %s", err)
+	}
+	if authz.Status == acme.StatusValid {
+		return nil
+	}
+
+	chal := c.pickChallenge(authz.Challenges)
+	if chal == nil {
+		return fmt.Errorf("no supported challenge for %s", authz.Identifier.Value)
+	}
+
+	switch chal.Type {
+	case ChallengeHTTP01:
+		resp, err := c.client.HTTP01ChallengeResponse(chal.Token)
+		if err != nil {
+			return err
+		}
+		c.setHTTP01(chal.Token, resp)
+		defer c.setHTTP01(chal.Token, "")
+
+	case ChallengeTLSALPN01:
+		cert, err := c.client.TLSALPN01ChallengeCert(chal.Token, authz.Identifier.Value)
+		if err != nil {
+			return err
+		}
+		c.setTLSALPN01(authz.Identifier.Value, &bfe_tls.Certificate{
+			Certificate: cert.Certificate,
+			PrivateKey:  cert.PrivateKey,
+		})
+		defer c.setTLSALPN01(authz.Identifier.Value, nil)
+	}
+
+	if _, err := c.client.Accept(ctx, chal); err != nil {
+		return fmt.Errorf("accept %s challenge: %s", chal.Type, err)
+	}
+	if _, err := c.client.WaitAuthorization(ctx, url); err != nil {
+		return fmt.Errorf("wait authorization: %s", err)
+	}
+	return nil
+}
+
+func (c *Client) pickChallenge(chals []*acme.Challenge) *acme.Challenge {
+	for _, typ := range c.conf.ChallengeTypes {
+		for _, chal := range chals {
+			if chal.Type == typ {
+				return chal
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Client) setHTTP01(token string, resp string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if len(resp) == 0 {
+		delete(c.http01, token)
+		return
+	}
+	c.http01[token] = resp
+}
+
+func (c *Client) setTLSALPN01(host string, cert *bfe_tls.Certificate) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if cert == nil {
+		delete(c.tlsALPN01, host)
+		return
+	}
+	c.tlsALPN01[host] = cert
+}
+
+func (c *Client) setObtainResult(host string, err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	state, ok := c.state.Hosts[host]
+	if !ok {
+		return
+	}
+	if err != nil {
+		state.ErrorCount++
+		state.LastError = err.Error()
+		state.LastErrorTime = time.Now()
+		return
+	}
+
+	if cert, ok := c.certs[host]; ok {
+		state.NotBefore, state.NotAfter = cert.Leaf.NotBefore, cert.Leaf.NotAfter
+	}
+	state.ObtainCount++
+	state.LastObtain = time.Now()
+}
+
+// GetCertificate returns certificate for tls handshake. It returns challenge
+// certificate for tls-alpn-01 validation, certificate of the host if
+// obtained, or nil otherwise.
+func (c *Client) GetCertificate(hello *bfe_tls.ClientHelloInfo) (*bfe_tls.Certificate, error) {
+	host := strings.ToLower(hello.ServerName)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if len(hello.SupportedProtos) == 1 && hello.SupportedProtos[0] == bfe_tls.ACMETLSProtocol {
+		cert, ok := c.tlsALPN01[host]
+		if !ok {
+			return nil, fmt.Errorf("acme client: no tls-alpn-01 challenge for %s", host)
+		}
+		return cert, nil
+	}
+
+	return c.certs[host], nil
+}
+
+// HTTP01Response returns key authorization for http-01 challenge request.
+func (c *Client) HTTP01Response(path string) (string, bool) {
+	if !strings.HasPrefix(path, HTTP01PathPrefix) {
+		return "", false
+	}
+	token := strings.TrimPrefix(path, HTTP01PathPrefix)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	resp, ok := c.http01[token]
+	return resp, ok
+}
+
+// GetState returns state of acme client.
+func (c *Client) GetState() ClientState {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	state := c.state
+	state.Hosts = make(map[string]*CertState, len(c.state.Hosts))
+	for host, certState := range c.state.Hosts {
+		s := *certState
+		state.Hosts[host] = &s
+	}
+	return state
+}
+
+func (c *Client) loadAccountKey() (crypto.Signer, error) {
+	data, err := os.ReadFile(filepath.Join(c.conf.StorageDir, accountKeyFile))
+	if err == nil {
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("invalid pem data")
+		}
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	// generate new account key
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	data = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+	if err := c.writeFile(accountKeyFile, data); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (c *Client) loadCert(host string) (*bfe_tls.Certificate, error) {
+	certPEM, err := os.ReadFile(filepath.Join(c.conf.StorageDir, host+certFileSuffix))
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := os.ReadFile(filepath.Join(c.conf.StorageDir, host+keyFileSuffix))
+	if err != nil {
+		return nil, err
+	}
+	return parseCert(certPEM, keyPEM)
+}
+
+func (c *Client) readJSON(name string, v interface{}) error {
+	data, err := os.ReadFile(filepath.Join(c.conf.StorageDir, name))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (c *Client) writeJSON(name string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.writeFile(name, data)
+}
+
+// writeFile writes file in storage dir atomically.
+func (c *Client) writeFile(name string, data []byte) error {
+	path := filepath.Join(c.conf.StorageDir, name)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+func encodeCert(der [][]byte, key *ecdsa.PrivateKey) ([]byte, []byte, error) {
+	var certPEM []byte
+	for _, b := range der {
+		certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: b})...)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
+
+func parseCert(certPEM, keyPEM []byte) (*bfe_tls.Certificate, error) {
+	cert, err := bfe_tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
@@ -0,0 +1,611 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acme_client
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+import (
+	"golang.org/x/crypto/acme"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_tls"
+)
+
+// oid of acmeIdentifier extension, see RFC 8737, Section 6.1
+var idPeAcmeIdentifier = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 31}
+
+type fakeChallenge struct {
+	Type   string `json:"type"`
+	URL    string `json:"url"`
+	Token  string `json:"token"`
+	Status string `json:"status"`
+
+	authz *fakeAuthz
+}
+
+type fakeIdentifier struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type fakeAuthz struct {
+	Identifier fakeIdentifier   `json:"identifier"`
+	Status     string           `json:"status"`
+	Challenges []*fakeChallenge `json:"challenges"`
+
+	order *fakeOrder
+}
+
+type fakeOrder struct {
+	Status         string           `json:"status"`
+	Identifiers    []fakeIdentifier `json:"identifiers"`
+	Authorizations []string         `json:"authorizations"`
+	Finalize       string           `json:"finalize"`
+	Certificate    string           `json:"certificate,omitempty"`
+
+	url    string
+	authzs []*fakeAuthz
+	chain  []byte
+}
+
+// fakeACMEServer is a minimal acme server (RFC 8555) for tests. Challenges
+// are validated against given http and tls addresses.
+type fakeACMEServer struct {
+	*httptest.Server
+	httpAddr string // address for http-01 validation
+	tlsAddr  string // address for tls-alpn-01 validation
+
+	caKey  *ecdsa.PrivateKey
+	caCert *x509.Certificate
+
+	lock       sync.Mutex
+	nextID     int
+	accounts   map[string]*ecdsa.PublicKey // kid => account key
+	authzs     map[string]*fakeAuthz
+	challenges map[string]*fakeChallenge
+	orders     map[string]*fakeOrder
+	validated  []string // types of validated challenges
+}
+
+func newFakeACMEServer(t *testing.T) *fakeACMEServer {
+	s := &fakeACMEServer{
+		accounts:   make(map[string]*ecdsa.PublicKey),
+		authzs:     make(map[string]*fakeAuthz),
+		challenges: make(map[string]*fakeChallenge),
+		orders:     make(map[string]*fakeOrder),
+	}
+
+	var err error
+	s.caKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %s", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fake acme ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &s.caKey.PublicKey, s.caKey)
+	if err != nil {
+		t.Fatalf("create ca certificate: %s", err)
+	}
+	s.caCert, _ = x509.ParseCertificate(der)
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *fakeACMEServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Replay-Nonce", fmt.Sprintf("nonce-%d", time.Now().UnixNano()))
+
+	switch r.URL.Path {
+	case "/dir":
+		s.writeJSON(w, http.StatusOK, map[string]string{
+			"newNonce":   s.URL + "/nonce",
+			"newAccount": s.URL + "/new-account",
+			"newOrder":   s.URL + "/new-order",
+			"revokeCert": s.URL + "/revoke-cert",
+			"keyChange":  s.URL + "/key-change",
+		})
+		return
+	case "/nonce":
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	kid, payload, err := s.parseJWS(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "malformed", err.Error())
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	switch {
+	case r.URL.Path == "/new-account":
+		w.Header().Set("Location", kid)
+		s.writeJSON(w, http.StatusCreated, map[string]string{"status": acme.StatusValid})
+
+	case r.URL.Path == "/new-order":
+		var req struct {
+			Identifiers []fakeIdentifier
+		}
+		json.Unmarshal(payload, &req)
+		order := s.newOrder(req.Identifiers)
+		w.Header().Set("Location", order.url)
+		s.writeJSON(w, http.StatusCreated, order)
+
+	case strings.HasPrefix(r.URL.Path, "/authz/"):
+		s.writeJSON(w, http.StatusOK, s.authzs[r.URL.Path])
+
+	case strings.HasPrefix(r.URL.Path, "/chal/"):
+		chal := s.challenges[r.URL.Path]
+		if chal.Status == acme.StatusPending {
+			s.validate(chal, s.accounts[kid])
+		}
+		s.writeJSON(w, http.StatusOK, chal)
+
+	case strings.HasPrefix(r.URL.Path, "/order/"):
+		s.writeJSON(w, http.StatusOK, s.orders[r.URL.Path])
+
+	case strings.HasPrefix(r.URL.Path, "/finalize/"):
+		order := s.orders["/order/"+strings.TrimPrefix(r.URL.Path, "/finalize/")]
+		if order.Status != acme.StatusReady {
+			s.writeError(w, http.StatusForbidden, "orderNotReady", "order not ready")
+			return
+		}
+		var req struct {
+			CSR string
+		}
+		json.Unmarshal(payload, &req)
+		if err := s.issue(order, req.CSR); err != nil {
+			s.writeError(w, http.StatusBadRequest, "badCSR", err.Error())
+			return
+		}
+		w.Header().Set("Location", order.url)
+		s.writeJSON(w, http.StatusOK, order)
+
+	case strings.HasPrefix(r.URL.Path, "/cert/"):
+		order := s.orders["/order/"+strings.TrimPrefix(r.URL.Path, "/cert/")]
+		w.Header().Set("Content-Type", "application/pem-certificate-chain")
+		w.Write(order.chain)
+
+	default:
+		s.writeError(w, http.StatusNotFound, "malformed", "not found")
+	}
+}
+
+// parseJWS verifies request signed by account key, and returns kid and payload.
+func (s *fakeACMEServer) parseJWS(r *http.Request) (string, []byte, error) {
+	var jws struct {
+		Protected string
+		Payload   string
+		Signature string
+	}
+	if err := json.NewDecoder(r.Body).Decode(&jws); err != nil {
+		return "", nil, err
+	}
+
+	var header struct {
+		Alg string
+		Kid string
+		JWK *struct {
+			Crv string
+			X   string
+			Y   string
+		}
+	}
+	data, err := base64.RawURLEncoding.DecodeString(jws.Protected)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return "", nil, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	kid := header.Kid
+	var pub *ecdsa.PublicKey
+	if header.JWK != nil {
+		x, _ := base64.RawURLEncoding.DecodeString(header.JWK.X)
+		y, _ := base64.RawURLEncoding.DecodeString(header.JWK.Y)
+		pub = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		thumbprint, _ := acme.JWKThumbprint(pub)
+		kid = s.URL + "/account/" + thumbprint
+		s.accounts[kid] = pub
+	} else {
+		pub = s.accounts[kid]
+	}
+	if pub == nil || header.Alg != "ES256" {
+		return "", nil, fmt.Errorf("unknown account %s", kid)
+	}
+
+	sig, _ := base64.RawURLEncoding.DecodeString(jws.Signature)
+	if len(sig) != 64 {
+		return "", nil, fmt.Errorf("invalid signature")
+	}
+	digest := sha256.Sum256([]byte(jws.Protected + "." + jws.Payload))
+	rs, ss := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+	if !ecdsa.Verify(pub, digest[:], rs, ss) {
+		return "", nil, fmt.Errorf("invalid signature")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(jws.Payload)
+	return kid, payload, err
+}
+
+func (s *fakeACMEServer) newOrder(ids []fakeIdentifier) *fakeOrder {
+	s.nextID++
+	order := &fakeOrder{
+		Status:      acme.StatusPending,
+		Identifiers: ids,
+		Finalize:    fmt.Sprintf("%s/finalize/%d", s.URL, s.nextID),
+		url:         fmt.Sprintf("%s/order/%d", s.URL, s.nextID),
+	}
+	s.orders[fmt.Sprintf("/order/%d", s.nextID)] = order
+
+	for _, id := range ids {
+		s.nextID++
+		authz := &fakeAuthz{Identifier: id, Status: acme.StatusPending, order: order}
+		for _, typ := range []string{ChallengeHTTP01, ChallengeTLSALPN01} {
+			s.nextID++
+			chal := &fakeChallenge{
+				Type:   typ,
+				URL:    fmt.Sprintf("%s/chal/%d", s.URL, s.nextID),
+				Token:  fmt.Sprintf("token-%d", s.nextID),
+				Status: acme.StatusPending,
+				authz:  authz,
+			}
+			s.challenges[fmt.Sprintf("/chal/%d", s.nextID)] = chal
+			authz.Challenges = append(authz.Challenges, chal)
+		}
+		s.authzs[fmt.Sprintf("/authz/%d", s.nextID)] = authz
+		order.Authorizations = append(order.Authorizations, fmt.Sprintf("%s/authz/%d", s.URL, s.nextID))
+		order.authzs = append(order.authzs, authz)
+	}
+	return order
+}
+
+func (s *fakeACMEServer) validate(chal *fakeChallenge, pub *ecdsa.PublicKey) {
+	thumbprint, _ := acme.JWKThumbprint(pub)
+	keyAuth := chal.Token + "." + thumbprint
+	host := chal.authz.Identifier.Value
+
+	var err error
+	switch chal.Type {
+	case ChallengeHTTP01:
+		err = validateHTTP01(s.httpAddr, host, chal.Token, keyAuth)
+	case ChallengeTLSALPN01:
+		err = validateTLSALPN01(s.tlsAddr, host, keyAuth)
+	}
+
+	if err != nil {
+		chal.Status = acme.StatusInvalid
+		chal.authz.Status = acme.StatusInvalid
+		chal.authz.order.Status = acme.StatusInvalid
+		return
+	}
+	s.validated = append(s.validated, chal.Type)
+	chal.Status = acme.StatusValid
+	chal.authz.Status = acme.StatusValid
+
+	order := chal.authz.order
+	for _, authz := range order.authzs {
+		if authz.Status != acme.StatusValid {
+			return
+		}
+	}
+	order.Status = acme.StatusReady
+}
+
+func validateHTTP01(addr, host, token, keyAuth string) error {
+	req, _ := http.NewRequest("GET", "http://"+addr+HTTP01PathPrefix+token, nil)
+	req.Host = host
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != keyAuth {
+		return fmt.Errorf("unexpected response %d %q", resp.StatusCode, body)
+	}
+	return nil
+}
+
+func validateTLSALPN01(addr, host, keyAuth string) error {
+	conn, err := tls.Dial("tcp", addr, &tls.Config{
+		ServerName:         host,
+		NextProtos:         []string{bfe_tls.ACMETLSProtocol},
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	state := conn.ConnectionState()
+	if state.NegotiatedProtocol != bfe_tls.ACMETLSProtocol {
+		return fmt.Errorf("unexpected protocol %q", state.NegotiatedProtocol)
+	}
+	cert := state.PeerCertificates[0]
+	if len(cert.DNSNames) != 1 || cert.DNSNames[0] != host {
+		return fmt.Errorf("unexpected names %v", cert.DNSNames)
+	}
+
+	digest := sha256.Sum256([]byte(keyAuth))
+	expect, _ := asn1.Marshal(digest[:])
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(idPeAcmeIdentifier) && ext.Critical && bytes.Equal(ext.Value, expect) {
+			return nil
+		}
+	}
+	return fmt.Errorf("no valid acmeIdentifier extension")
+}
+
+func (s *fakeACMEServer) issue(order *fakeOrder, csrB64 string) error {
+	der, err := base64.RawURLEncoding.DecodeString(csrB64)
+	if err != nil {
+		return err
+	}
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		return err
+	}
+	if len(csr.DNSNames) != 1 || csr.DNSNames[0] != order.Identifiers[0].Value {
+		return fmt.Errorf("unexpected names %v", csr.DNSNames)
+	}
+
+	s.nextID++
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(int64(s.nextID)),
+		Subject:      pkix.Name{CommonName: csr.DNSNames[0]},
+		DNSNames:     csr.DNSNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(90 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, tmpl, s.caCert, csr.PublicKey, s.caKey)
+	if err != nil {
+		return err
+	}
+
+	order.chain = append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.caCert.Raw})...)
+	order.Status = acme.StatusValid
+	order.Certificate = fmt.Sprintf("%s/cert/%s", s.URL, strings.TrimPrefix(order.url, s.URL+"/order/"))
+	return nil
+}
+
+func (s *fakeACMEServer) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (s *fakeACMEServer) writeError(w http.ResponseWriter, status int, typ, detail string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"type":   "urn:ietf:params:acme:error:" + typ,
+		"detail": detail,
+	})
+}
+
+// startChallengeServers starts http and tls servers which answer challenges
+// with the client.
+func startChallengeServers(t *testing.T, s *fakeACMEServer, client *Client) {
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp, ok := client.HTTP01Response(r.URL.Path)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, resp)
+	}))
+	t.Cleanup(httpServer.Close)
+	s.httpAddr = httpServer.Listener.Addr().String()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %s", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	s.tlsAddr = ln.Addr().String()
+
+	tlsListener := bfe_tls.NewListener(ln, &bfe_tls.Config{GetCertificate: client.GetCertificate})
+	go func() {
+		for {
+			conn, err := tlsListener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				conn.(*bfe_tls.Conn).Handshake()
+				conn.Close()
+			}()
+		}
+	}()
+}
+
+func newTestClient(t *testing.T, s *fakeACMEServer, storageDir string, challengeType string) *Client {
+	client, err := NewClient(Config{
+		DirectoryURL:   s.URL + "/dir",
+		Email:          "admin@example.org",
+		StorageDir:     storageDir,
+		ChallengeTypes: []string{challengeType},
+		RenewBefore:    30 * 24 * time.Hour,
+		CheckInterval:  time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("NewClient(): %s", err)
+	}
+	startChallengeServers(t, s, client)
+	return client
+}
+
+func checkHostCert(t *testing.T, client *Client, host string) *bfe_tls.Certificate {
+	cert, err := client.GetCertificate(&bfe_tls.ClientHelloInfo{ServerName: host})
+	if err != nil || cert == nil {
+		t.Fatalf("no certificate for %s: %v", host, err)
+	}
+	if err := cert.Leaf.VerifyHostname(host); err != nil {
+		t.Errorf("unexpected certificate: %s", err)
+	}
+	if len(cert.Certificate) != 2 {
+		t.Errorf("certificate chain should be bundled")
+	}
+	return cert
+}
+
+func TestClientObtainCert(t *testing.T) {
+	for _, typ := range []string{ChallengeHTTP01, ChallengeTLSALPN01} {
+		s := newFakeACMEServer(t)
+		client := newTestClient(t, s, t.TempDir(), typ)
+
+		client.SetHosts([]string{"example.org", "www.example.org"})
+		client.CheckCerts()
+
+		checkHostCert(t, client, "example.org")
+		checkHostCert(t, client, "www.example.org")
+		if len(s.validated) != 2 || s.validated[0] != typ || s.validated[1] != typ {
+			t.Errorf("unexpected validated challenges: %v", s.validated)
+		}
+
+		state := client.GetState()
+		if !strings.HasPrefix(state.Account, s.URL+"/account/") {
+			t.Errorf("unexpected account: %s", state.Account)
+		}
+		for host, certState := range state.Hosts {
+			if certState.ObtainCount != 1 || certState.ErrorCount != 0 {
+				t.Errorf("unexpected state for %s: %+v", host, certState)
+			}
+		}
+
+		// no certificate for other hosts
+		cert, err := client.GetCertificate(&bfe_tls.ClientHelloInfo{ServerName: "other.example.org"})
+		if cert != nil || err != nil {
+			t.Errorf("unexpected certificate for other host: %v", err)
+		}
+
+		// no challenge pending
+		_, err = client.GetCertificate(&bfe_tls.ClientHelloInfo{
+			ServerName:      "example.org",
+			SupportedProtos: []string{bfe_tls.ACMETLSProtocol},
+		})
+		if err == nil {
+			t.Errorf("should fail without pending tls-alpn-01 challenge")
+		}
+	}
+}
+
+func TestClientLoadAndRenewCert(t *testing.T) {
+	s := newFakeACMEServer(t)
+	storageDir := t.TempDir()
+	client := newTestClient(t, s, storageDir, ChallengeHTTP01)
+	client.SetHosts([]string{"example.org"})
+	client.CheckCerts()
+	cert := checkHostCert(t, client, "example.org")
+
+	for _, name := range []string{accountKeyFile, accountFile, "example.org.crt", "example.org.key"} {
+		if _, err := os.Stat(filepath.Join(storageDir, name)); err != nil {
+			t.Errorf("file not persisted: %s", err)
+		}
+	}
+
+	// certificate and account are loaded from storage dir
+	client = newTestClient(t, s, storageDir, ChallengeHTTP01)
+	if client.GetState().Account == "" {
+		t.Errorf("account should be loaded")
+	}
+	client.SetHosts([]string{"example.org"})
+	if hosts := client.renewHosts(); len(hosts) != 0 {
+		t.Errorf("unexpected hosts to renew: %v", hosts)
+	}
+	if !bytes.Equal(checkHostCert(t, client, "example.org").Certificate[0], cert.Certificate[0]) {
+		t.Errorf("certificate should be loaded from storage dir")
+	}
+
+	// certificate is renewed before expiration
+	client.conf.RenewBefore = 100 * 24 * time.Hour
+	client.CheckCerts()
+	if bytes.Equal(checkHostCert(t, client, "example.org").Certificate[0], cert.Certificate[0]) {
+		t.Errorf("certificate should be renewed")
+	}
+	if state := client.GetState().Hosts["example.org"]; state.ObtainCount != 1 {
+		t.Errorf("unexpected state: %+v", state)
+	}
+
+	// certificate of removed host is not served
+	client.SetHosts(nil)
+	cert, _ = client.GetCertificate(&bfe_tls.ClientHelloInfo{ServerName: "example.org"})
+	if cert != nil {
+		t.Errorf("certificate of removed host should not be served")
+	}
+}
+
+func TestClientObtainCertFail(t *testing.T) {
+	s := newFakeACMEServer(t)
+	client := newTestClient(t, s, t.TempDir(), ChallengeHTTP01)
+	s.httpAddr = s.tlsAddr // validation will fail
+
+	client.SetHosts([]string{"example.org"})
+	client.CheckCerts()
+
+	cert, _ := client.GetCertificate(&bfe_tls.ClientHelloInfo{ServerName: "example.org"})
+	if cert != nil {
+		t.Errorf("unexpected certificate")
+	}
+	state := client.GetState().Hosts["example.org"]
+	if state.ErrorCount != 1 || state.LastError == "" {
+		t.Errorf("unexpected state: %+v", state)
+	}
+}
@@ -0,0 +1,270 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// resolve backend instances by dns
+
+package bns
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"time"
+)
+
+import (
+	"github.com/miekg/dns"
+)
+
+const (
+	DNSTypeA   = "A"   // A and AAAA records
+	DNSTypeSRV = "SRV" // SRV records
+)
+
+const (
+	DefaultResolvConf = "/etc/resolv.conf"
+	DefaultDNSTimeout = 2 * time.Second
+)
+
+// DNSResolver resolves domain to instances by querying name servers.
+type DNSResolver struct {
+	servers []string // address of name servers, e.g., "8.8.8.8:53"
+	client  dns.Client
+}
+
+// NewDNSResolver creates resolver with given name servers.
+func NewDNSResolver(servers []string, timeout time.Duration) *DNSResolver {
+	r := new(DNSResolver)
+	r.servers = servers
+	r.client = dns.Client{
+		Net:     "udp",
+		Timeout: timeout,
+		UDPSize: dns.DefaultMsgSize,
+	}
+	return r
+}
+
+// NewDNSResolverFromConf creates resolver with name servers in resolv.conf.
+func NewDNSResolverFromConf(filename string) (*DNSResolver, error) {
+	conf, err := dns.ClientConfigFromFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if len(conf.Servers) == 0 {
+		return nil, fmt.Errorf("no name server in %s", filename)
+	}
+
+	servers := make([]string, 0, len(conf.Servers))
+	for _, server := range conf.Servers {
+		servers = append(servers, net.JoinHostPort(server, conf.Port))
+	}
+
+	timeout := DefaultDNSTimeout
+	if conf.Timeout > 0 {
+		timeout = time.Duration(conf.Timeout) * time.Second
+	}
+	return NewDNSResolver(servers, timeout), nil
+}
+
+// Resolve resolves domain to instances. For DNSTypeA, all instances use the
+// given port and weight 0. For DNSTypeSRV, port and weight of instances come
+// from SRV records with the lowest priority. It also returns the minimum TTL
+// of records.
+func (r *DNSResolver) Resolve(domain string, dnsType string, port int) ([]Instance, time.Duration, error) {
+	var instances []Instance
+	var ttl uint32
+	var err error
+
+	switch dnsType {
+	case DNSTypeA:
+		instances, ttl, err = r.resolveA(domain, port)
+	case DNSTypeSRV:
+		instances, ttl, err = r.resolveSRV(domain)
+	default:
+		return nil, 0, fmt.Errorf("unknown dns type: %s", dnsType)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sort.Slice(instances, func(i, j int) bool {
+		if instances[i].Host != instances[j].Host {
+			return instances[i].Host < instances[j].Host
+		}
+		return instances[i].Port < instances[j].Port
+	})
+	return instances, time.Duration(ttl) * time.Second, nil
+}
+
+func (r *DNSResolver) resolveA(domain string, port int) ([]Instance, uint32, error) {
+	addrs, ttl, err := r.lookupAddr(domain)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	instances := make([]Instance, 0, len(addrs))
+	for _, addr := range addrs {
+		instances = append(instances, Instance{Host: addr, Port: port})
+	}
+	return instances, ttl, nil
+}
+
+func (r *DNSResolver) resolveSRV(domain string) ([]Instance, uint32, error) {
+	reply, err := r.exchange(domain, dns.TypeSRV)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// only records with the lowest priority are used
+	var records []*dns.SRV
+	for _, rr := range reply.Answer {
+		srv, ok := rr.(*dns.SRV)
+		if !ok {
+			continue
+		}
+		if len(records) > 0 && srv.Priority > records[0].Priority {
+			continue
+		}
+		if len(records) > 0 && srv.Priority < records[0].Priority {
+			records = records[:0]
+		}
+		records = append(records, srv)
+	}
+	if len(records) == 0 {
+		return nil, 0, fmt.Errorf("no SRV record for %s", domain)
+	}
+
+	ttl := uint32(math.MaxUint32)
+	var instances []Instance
+	for _, srv := range records {
+		ttl = minTTL(ttl, srv.Hdr.Ttl)
+
+		// addresses of target may be provided in additional section
+		addrs, addrTTL := addrsOf(reply.Extra, srv.Target)
+		if len(addrs) == 0 {
+			addrs, addrTTL, err = r.lookupAddr(srv.Target)
+			if err != nil {
+				return nil, 0, err
+			}
+		}
+		ttl = minTTL(ttl, addrTTL)
+
+		for _, addr := range addrs {
+			instances = append(instances, Instance{
+				Host:   addr,
+				Port:   int(srv.Port),
+				Weight: int(srv.Weight),
+			})
+		}
+	}
+	return instances, ttl, nil
+}
+
+// lookupAddr queries both A and AAAA records of name.
+func (r *DNSResolver) lookupAddr(name string) ([]string, uint32, error) {
+	var addrs []string
+	ttl := uint32(math.MaxUint32)
+
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		reply, err := r.exchange(name, qtype)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		for _, rr := range reply.Answer {
+			switch v := rr.(type) {
+			case *dns.A:
+				addrs = append(addrs, v.A.String())
+			case *dns.AAAA:
+				addrs = append(addrs, v.AAAA.String())
+			default:
+				continue
+			}
+			ttl = minTTL(ttl, rr.Header().Ttl)
+		}
+	}
+
+	if len(addrs) == 0 {
+		return nil, 0, fmt.Errorf("no address record for %s", name)
+	}
+	return addrs, ttl, nil
+}
+
+// exchange sends query to name servers in order, until one of them succeeds.
+func (r *DNSResolver) exchange(name string, qtype uint16) (*dns.Msg, error) {
+	if len(r.servers) == 0 {
+		return nil, fmt.Errorf("no name server")
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), qtype)
+	msg.SetEdns0(dns.DefaultMsgSize, false)
+
+	var err error
+	for _, server := range r.servers {
+		var reply *dns.Msg
+		reply, _, err = r.client.Exchange(msg, server)
+		if err != nil {
+			continue
+		}
+
+		switch reply.Rcode {
+		case dns.RcodeSuccess:
+			return reply, nil
+		case dns.RcodeNameError:
+			// authoritative answer, no need to ask others
+			return nil, fmt.Errorf("%s %s: %s", name, dns.TypeToString[qtype],
+				dns.RcodeToString[reply.Rcode])
+		default:
+			err = fmt.Errorf("%s %s: %s", name, dns.TypeToString[qtype],
+				dns.RcodeToString[reply.Rcode])
+		}
+	}
+
+	return nil, err
+}
+
+// addrsOf returns addresses of name in A and AAAA records.
+func addrsOf(rrs []dns.RR, name string) ([]string, uint32) {
+	var addrs []string
+	ttl := uint32(math.MaxUint32)
+
+	for _, rr := range rrs {
+		if !sameName(rr.Header().Name, name) {
+			continue
+		}
+		switch v := rr.(type) {
+		case *dns.A:
+			addrs = append(addrs, v.A.String())
+		case *dns.AAAA:
+			addrs = append(addrs, v.AAAA.String())
+		default:
+			continue
+		}
+		ttl = minTTL(ttl, rr.Header().Ttl)
+	}
+	return addrs, ttl
+}
+
+func sameName(a, b string) bool {
+	return dns.CanonicalName(a) == dns.CanonicalName(b)
+}
+
+func minTTL(a, b uint32) uint32 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bns
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/miekg/dns"
+)
+
+func startTestDNSServer(t *testing.T, records map[string][]string) string {
+	zone := make(map[string][]dns.RR)
+	for name, rrs := range records {
+		for _, s := range rrs {
+			rr, err := dns.NewRR(s)
+			if err != nil {
+				t.Fatalf("dns.NewRR(%s): %s", s, err)
+			}
+			zone[name] = append(zone[name], rr)
+		}
+	}
+
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		msg := new(dns.Msg)
+		msg.SetReply(req)
+
+		q := req.Question[0]
+		rrs, ok := zone[q.Name]
+		if !ok {
+			msg.Rcode = dns.RcodeNameError
+		}
+		for _, rr := range rrs {
+			if rr.Header().Rrtype == q.Qtype {
+				msg.Answer = append(msg.Answer, rr)
+			}
+		}
+		w.WriteMsg(msg)
+	})
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket: %s", err)
+	}
+	server := &dns.Server{PacketConn: conn, Handler: handler}
+	go server.ActivateAndServe()
+	t.Cleanup(func() { server.Shutdown() })
+
+	return conn.LocalAddr().String()
+}
+
+func TestDNSResolverResolve(t *testing.T) {
+	addr := startTestDNSServer(t, map[string][]string{
+		"app.example.org.": {
+			"app.example.org. 30 IN A 10.0.0.2",
+			"app.example.org. 60 IN A 10.0.0.1",
+			"app.example.org. 20 IN AAAA ::1",
+		},
+		"_http._tcp.example.org.": {
+			"_http._tcp.example.org. 10 IN SRV 10 5 8081 app.example.org.",
+			"_http._tcp.example.org. 10 IN SRV 10 0 8082 b.example.org.",
+			"_http._tcp.example.org. 10 IN SRV 20 5 8083 c.example.org.",
+		},
+		"b.example.org.": {
+			"b.example.org. 5 IN A 10.0.1.1",
+		},
+		"empty.example.org.": {
+			"empty.example.org. 30 IN TXT \"none\"",
+		},
+	})
+	resolver := NewDNSResolver([]string{addr}, time.Second)
+
+	tests := []struct {
+		Domain    string
+		Type      string
+		Port      int
+		Instances []Instance
+		TTL       time.Duration
+		Err       bool
+	}{
+		{
+			Domain: "app.example.org",
+			Type:   DNSTypeA,
+			Port:   8080,
+			Instances: []Instance{
+				{"10.0.0.1", 8080, 0},
+				{"10.0.0.2", 8080, 0},
+				{"::1", 8080, 0},
+			},
+			TTL: 20 * time.Second,
+		},
+		{
+			Domain: "_http._tcp.example.org",
+			Type:   DNSTypeSRV,
+			Instances: []Instance{
+				{"10.0.0.1", 8081, 5},
+				{"10.0.0.2", 8081, 5},
+				{"10.0.1.1", 8082, 0},
+				{"::1", 8081, 5},
+			},
+			TTL: 5 * time.Second,
+		},
+		{Domain: "empty.example.org", Type: DNSTypeA, Port: 8080, Err: true},
+		{Domain: "unknown.example.org", Type: DNSTypeA, Port: 8080, Err: true},
+		{Domain: "app.example.org", Type: "MX", Err: true},
+	}
+
+	for i, tt := range tests {
+		instances, ttl, err := resolver.Resolve(tt.Domain, tt.Type, tt.Port)
+		if tt.Err {
+			if err == nil {
+				t.Errorf("case %d expect error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("case %d unexpected error: %s", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(instances, tt.Instances) {
+			t.Errorf("case %d expect %v, got %v", i, tt.Instances, instances)
+		}
+		if ttl != tt.TTL {
+			t.Errorf("case %d expect ttl %s, got %s", i, tt.TTL, ttl)
+		}
+	}
+}
+
+func TestNewDNSResolverFromConf(t *testing.T) {
+	resolver, err := NewDNSResolverFromConf("./testdata/resolv.conf")
+	if err != nil {
+		t.Fatalf("NewDNSResolverFromConf: %s", err)
+	}
+
+	expect := []string{"10.0.0.53:53", "[2001:db8::53]:53"}
+	if !reflect.DeepEqual(resolver.servers, expect) {
+		t.Errorf("expect servers %v, got %v", expect, resolver.servers)
+	}
+	if resolver.client.Timeout != 3*time.Second {
+		t.Errorf("expect timeout 3s, got %s", resolver.client.Timeout)
+	}
+
+	if _, err := NewDNSResolverFromConf("./testdata/no_exist.conf"); err == nil {
+		t.Errorf("expect error for nonexistent file")
+	}
+}
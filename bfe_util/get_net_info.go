@@ -81,6 +81,18 @@ func GetConnFile(conn net.Conn) (*os.File, error) {
 	return nil, fmt.Errorf("GetConnFd(): conn type not support %s", reflect.TypeOf(conn))
 }
 
+// GetLocalIP returns local ip of given conn (over tcp or udp).
+func GetLocalIP(conn net.Conn) net.IP {
+	switch addr := conn.LocalAddr().(type) {
+	case *net.TCPAddr:
+		return addr.IP
+	case *net.UDPAddr:
+		return addr.IP
+	default:
+		return nil
+	}
+}
+
 // ParseIpAndPort return parsed ip address
 func ParseIpAndPort(addr string) (net.IP, int, error) {
 	taddr, err := net.ResolveTCPAddr("tcp", addr)
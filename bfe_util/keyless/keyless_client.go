@@ -0,0 +1,385 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// client of key server for keyless tls
+//
+// Private keys of server certificates are kept by key server, and bfe only
+// holds key references. Signing and RSA decryption in tls handshake are
+// delegated to key server, over rpc on mutually authenticated tls
+// connections. Connections are pooled and reused; a connection is closed
+// on transport error or timeout, and redialed on next call.
+//
+// A local key may be used as fallback according to fallback policy. Note:
+// Errors returned by key server (eg. unknown key) never fall back to local
+// key, so that misconfiguration is not hidden.
+
+package keyless
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/rpc"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+import (
+	"github.com/bfenetworks/go-lib/log"
+)
+
+const (
+	// name of rpc service of key server
+	serviceName = "KeyServer"
+
+	methodSign    = serviceName + ".Sign"
+	methodDecrypt = serviceName + ".Decrypt"
+)
+
+// fallback policy of remote keys
+const (
+	FallbackDisabled       = "Disabled"       // never use local key
+	FallbackTransportError = "TransportError" // use local key on transport error (eg. dial failure, timeout)
+)
+
+var (
+	ErrTimeout         = errors.New("keyless: key server timeout")
+	ErrNoSessionKeyLen = errors.New("keyless: SessionKeyLen is required for decryption")
+	ErrUnsupportedOpts = errors.New("keyless: unsupported decrypter opts")
+)
+
+// transportError is error of connection to key server, while request may not
+// be processed by key server.
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string {
+	return e.err.Error()
+}
+
+func (e *transportError) Unwrap() error {
+	return e.err
+}
+
+// IsTransportError returns true if err is error of connection to key server,
+// instead of error returned by key server.
+func IsTransportError(err error) bool {
+	var e *transportError
+	return errors.As(err, &e)
+}
+
+// SignArgs is request of signing digest with a key.
+type SignArgs struct {
+	KeyID      string      // reference of key
+	Digest     []byte      // digest to sign
+	Hash       crypto.Hash // hash function of digest
+	PSS        bool        // sign with RSA-PSS or not
+	SaltLength int         // salt length of RSA-PSS
+}
+
+// SignReply is response of signing.
+type SignReply struct {
+	Signature []byte
+}
+
+// DecryptArgs is request of RSA decryption (PKCS #1 v1.5) with a key.
+type DecryptArgs struct {
+	KeyID         string // reference of key
+	Ciphertext    []byte
+	SessionKeyLen int // length of session key, random key is returned for invalid padding
+}
+
+// DecryptReply is response of decryption.
+type DecryptReply struct {
+	Plaintext []byte
+}
+
+// Config is config of key server client.
+type Config struct {
+	Address   string        // address of key server, eg. "keyserver.example.org:8443"
+	TLSConfig *tls.Config   // client certificate and ca certificates for key server
+	Timeout   time.Duration // timeout for one operation, including dialing
+	PoolSize  int           // max number of connections to key server
+
+	FallbackPolicy string // when local fallback key is used, FallbackDisabled by default
+}
+
+// ClientState is state of key server client.
+type ClientState struct {
+	Address       string
+	Conns         int   // number of open connections
+	Dial          int64 // number of connections dialed
+	DialErr       int64
+	Sign          int64 // number of signing requests
+	SignErr       int64
+	Decrypt       int64 // number of decryption requests
+	DecryptErr    int64
+	Fallback      int64 // number of operations done by local fallback key
+	LastError     string
+	LastErrorTime time.Time
+}
+
+// connSlot holds one pooled connection.
+type connSlot struct {
+	lock sync.Mutex
+	conn *rpc.Client
+}
+
+type Client struct {
+	conf  Config
+	slots []*connSlot
+	next  uint32 // index of next slot to use
+
+	lock  sync.Mutex
+	state ClientState
+}
+
+// NewClient creates client of key server. Connections are dialed on demand.
+func NewClient(conf Config) *Client {
+	if conf.PoolSize <= 0 {
+		conf.PoolSize = 1
+	}
+
+	c := new(Client)
+	c.conf = conf
+	c.slots = make([]*connSlot, conf.PoolSize)
+	for i := range c.slots {
+		c.slots[i] = new(connSlot)
+	}
+	c.state.Address = conf.Address
+	return c
+}
+
+// NewKey creates a remote key referenced by keyID, with public key pub.
+// If fallback is not nil, it is used on transport error if allowed by
+// fallback policy.
+func (c *Client) NewKey(keyID string, pub crypto.PublicKey, fallback crypto.Signer) *RemoteKey {
+	return &RemoteKey{
+		client:   c,
+		keyID:    keyID,
+		pub:      pub,
+		fallback: fallback,
+	}
+}
+
+// Close closes all connections to key server.
+func (c *Client) Close() {
+	for _, slot := range c.slots {
+		slot.lock.Lock()
+		if slot.conn != nil {
+			slot.conn.Close()
+			slot.conn = nil
+		}
+		slot.lock.Unlock()
+	}
+}
+
+// GetState returns state of client.
+func (c *Client) GetState() ClientState {
+	conns := 0
+	for _, slot := range c.slots {
+		slot.lock.Lock()
+		if slot.conn != nil {
+			conns++
+		}
+		slot.lock.Unlock()
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	state := c.state
+	state.Conns = conns
+	return state
+}
+
+func (c *Client) incState(counter *int64, err error) {
+	c.lock.Lock()
+	*counter++
+	if err != nil {
+		c.state.LastError = err.Error()
+		c.state.LastErrorTime = time.Now()
+	}
+	c.lock.Unlock()
+}
+
+// getConn returns connection of slot, and dials one if not exist.
+func (c *Client) getConn(slot *connSlot, deadline time.Time) (*rpc.Client, error) {
+	slot.lock.Lock()
+	defer slot.lock.Unlock()
+	if slot.conn != nil {
+		return slot.conn, nil
+	}
+
+	dialer := &net.Dialer{Deadline: deadline}
+	conn, err := tls.DialWithDialer(dialer, "tcp", c.conf.Address, c.conf.TLSConfig)
+	if err != nil {
+		err = &transportError{fmt.Errorf("keyless: dial key server: %s", err)}
+		c.incState(&c.state.DialErr, err)
+		return nil, err
+	}
+	c.incState(&c.state.Dial, nil)
+
+	slot.conn = rpc.NewClient(conn)
+	return slot.conn, nil
+}
+
+// closeConn closes connection of slot, if it is still conn.
+func (c *Client) closeConn(slot *connSlot, conn *rpc.Client) {
+	slot.lock.Lock()
+	if slot.conn == conn {
+		slot.conn = nil
+	}
+	slot.lock.Unlock()
+	conn.Close()
+}
+
+// call invokes method of key server, on one of pooled connections.
+func (c *Client) call(method string, args interface{}, reply interface{}) error {
+	deadline := time.Now().Add(c.conf.Timeout)
+	slot := c.slots[atomic.AddUint32(&c.next, 1)%uint32(len(c.slots))]
+
+	conn, err := c.getConn(slot, deadline)
+	if err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	call := conn.Go(method, args, reply, make(chan *rpc.Call, 1))
+	select {
+	case <-call.Done:
+		if call.Error == nil {
+			return nil
+		}
+		// Note: error returned by key server does not affect connection
+		if _, ok := call.Error.(rpc.ServerError); ok {
+			return fmt.Errorf("keyless: %s: %w", method, call.Error)
+		}
+		c.closeConn(slot, conn)
+		return &transportError{fmt.Errorf("keyless: %s: %w", method, call.Error)}
+	case <-timer.C:
+		// connection may be stuck, drop it
+		c.closeConn(slot, conn)
+		return &transportError{ErrTimeout}
+	}
+}
+
+func (c *Client) sign(keyID string, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	args := SignArgs{
+		KeyID:  keyID,
+		Digest: digest,
+		Hash:   opts.HashFunc(),
+	}
+	if pssOpts, ok := opts.(*rsa.PSSOptions); ok {
+		args.PSS = true
+		args.SaltLength = pssOpts.SaltLength
+	}
+
+	var reply SignReply
+	err := c.call(methodSign, &args, &reply)
+	if err != nil {
+		c.incState(&c.state.SignErr, err)
+		return nil, err
+	}
+	c.incState(&c.state.Sign, nil)
+	return reply.Signature, nil
+}
+
+func (c *Client) decrypt(keyID string, ciphertext []byte, opts crypto.DecrypterOpts) ([]byte, error) {
+	args := DecryptArgs{
+		KeyID:      keyID,
+		Ciphertext: ciphertext,
+	}
+	// Note: errors of PKCS #1 v1.5 padding are not returned, to avoid
+	// Bleichenbacher oracle, so SessionKeyLen is required
+	pkcs1Opts, ok := opts.(*rsa.PKCS1v15DecryptOptions)
+	if !ok {
+		return nil, ErrUnsupportedOpts
+	}
+	if pkcs1Opts.SessionKeyLen <= 0 {
+		return nil, ErrNoSessionKeyLen
+	}
+	args.SessionKeyLen = pkcs1Opts.SessionKeyLen
+
+	var reply DecryptReply
+	err := c.call(methodDecrypt, &args, &reply)
+	if err != nil {
+		c.incState(&c.state.DecryptErr, err)
+		return nil, err
+	}
+	c.incState(&c.state.Decrypt, nil)
+	return reply.Plaintext, nil
+}
+
+// RemoteKey is a private key kept by key server. It implements
+// crypto.Signer and crypto.Decrypter.
+type RemoteKey struct {
+	client   *Client
+	keyID    string           // reference of key
+	pub      crypto.PublicKey // public key
+	fallback crypto.Signer    // local key used on transport error, may be nil
+}
+
+// KeyID returns reference of key.
+func (k *RemoteKey) KeyID() string {
+	return k.keyID
+}
+
+// Public returns public key.
+func (k *RemoteKey) Public() crypto.PublicKey {
+	return k.pub
+}
+
+// canFallback returns true if local fallback key should be used for err.
+func (k *RemoteKey) canFallback(err error) bool {
+	return k.fallback != nil && k.client.conf.FallbackPolicy == FallbackTransportError &&
+		IsTransportError(err)
+}
+
+// Sign signs digest by key server, or by local fallback key on transport
+// error if allowed by fallback policy.
+func (k *RemoteKey) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	sig, err := k.client.sign(k.keyID, digest, opts)
+	if err == nil || !k.canFallback(err) {
+		return sig, err
+	}
+
+	log.Logger.Warn("keyless: sign with local key for %s: %s", k.keyID, err)
+	k.client.incState(&k.client.state.Fallback, nil)
+	return k.fallback.Sign(rand, digest, opts)
+}
+
+// Decrypt decrypts msg by key server, or by local fallback key on transport
+// error if allowed by fallback policy.
+func (k *RemoteKey) Decrypt(rand io.Reader, msg []byte, opts crypto.DecrypterOpts) ([]byte, error) {
+	plaintext, err := k.client.decrypt(k.keyID, msg, opts)
+	if err == nil || !k.canFallback(err) {
+		return plaintext, err
+	}
+
+	decrypter, ok := k.fallback.(crypto.Decrypter)
+	if !ok {
+		return nil, err
+	}
+	log.Logger.Warn("keyless: decrypt with local key for %s: %s", k.keyID, err)
+	k.client.incState(&k.client.state.Fallback, nil)
+	return decrypter.Decrypt(rand, msg, opts)
+}
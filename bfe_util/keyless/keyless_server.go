@@ -0,0 +1,169 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// reference implementation of key server for keyless tls
+//
+// Key server keeps private keys, and serves signing and RSA decryption
+// requests from bfe over mutually authenticated tls connections. Keys are
+// referenced by key id, eg. file name of key in key dir.
+
+package keyless
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+import (
+	"github.com/bfenetworks/go-lib/log"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_tls"
+)
+
+// suffix of key file in key dir
+const KeyFileSuffix = ".key"
+
+// Server is key server.
+type Server struct {
+	lock sync.RWMutex
+	keys map[string]crypto.Signer // key id => private key
+}
+
+// NewServer creates key server with no key.
+func NewServer() *Server {
+	s := new(Server)
+	s.keys = make(map[string]crypto.Signer)
+	return s
+}
+
+// AddKey adds private key referenced by keyID.
+func (s *Server) AddKey(keyID string, key crypto.Signer) {
+	s.lock.Lock()
+	s.keys[keyID] = key
+	s.lock.Unlock()
+}
+
+// LoadKeyDir adds PEM encoded private keys in dir, with file name (without
+// suffix) as key id, eg. key of "example.org.key" is referenced by
+// "example.org".
+func (s *Server) LoadKeyDir(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+KeyFileSuffix))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		key, err := bfe_tls.ParsePrivateKeyPEM(data)
+		if err != nil {
+			return fmt.Errorf("%s: %s", file, err)
+		}
+		s.AddKey(strings.TrimSuffix(filepath.Base(file), KeyFileSuffix), key)
+	}
+	return nil
+}
+
+func (s *Server) getKey(keyID string) (crypto.Signer, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	key, ok := s.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %s", keyID)
+	}
+	return key, nil
+}
+
+// Serve accepts connections on ln and serves requests. Clients must present
+// certificates verified by config.ClientCAs.
+func (s *Server) Serve(ln net.Listener, config *tls.Config) error {
+	config = config.Clone()
+	config.ClientAuth = tls.RequireAndVerifyClientCert
+
+	rpcServer := rpc.NewServer()
+	if err := rpcServer.RegisterName(serviceName, &keyService{s}); err != nil {
+		return err
+	}
+
+	tlsLn := tls.NewListener(ln, config)
+	for {
+		conn, err := tlsLn.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			// complete handshake, so that unauthenticated clients are logged
+			tlsConn := conn.(*tls.Conn)
+			if err := tlsConn.Handshake(); err != nil {
+				log.Logger.Info("keyless: handshake with %s: %s", conn.RemoteAddr(), err)
+				conn.Close()
+				return
+			}
+			rpcServer.ServeConn(conn)
+		}()
+	}
+}
+
+// keyService is rpc service of key server.
+type keyService struct {
+	server *Server
+}
+
+func (ks *keyService) Sign(args *SignArgs, reply *SignReply) error {
+	key, err := ks.server.getKey(args.KeyID)
+	if err != nil {
+		return err
+	}
+
+	var opts crypto.SignerOpts = args.Hash
+	if args.PSS {
+		opts = &rsa.PSSOptions{SaltLength: args.SaltLength, Hash: args.Hash}
+	}
+	reply.Signature, err = key.Sign(rand.Reader, args.Digest, opts)
+	return err
+}
+
+func (ks *keyService) Decrypt(args *DecryptArgs, reply *DecryptReply) error {
+	key, err := ks.server.getKey(args.KeyID)
+	if err != nil {
+		return err
+	}
+	decrypter, ok := key.(crypto.Decrypter)
+	if !ok {
+		return fmt.Errorf("key %s does not support decryption", args.KeyID)
+	}
+
+	// Note: errors of PKCS #1 v1.5 padding should never be returned to
+	// client, which is a Bleichenbacher oracle. With SessionKeyLen, random
+	// key is returned for invalid padding instead
+	if args.SessionKeyLen <= 0 {
+		return fmt.Errorf("SessionKeyLen is required for decryption")
+	}
+	opts := &rsa.PKCS1v15DecryptOptions{SessionKeyLen: args.SessionKeyLen}
+	reply.Plaintext, err = decrypter.Decrypt(rand.Reader, args.Ciphertext, opts)
+	return err
+}
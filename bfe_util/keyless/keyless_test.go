@@ -0,0 +1,454 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keyless
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/bfenetworks/bfe/bfe_tls"
+)
+
+// testPKI issues certificates for key server and its clients.
+type testPKI struct {
+	ca    *x509.Certificate
+	caKey *ecdsa.PrivateKey
+	pool  *x509.CertPool
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "keyless test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &testPKI{ca: ca, caKey: key, pool: x509.NewCertPool()}
+	p.pool.AddCert(ca)
+	return p
+}
+
+func (p *testPKI) issue(t *testing.T, name string, key crypto.Signer, usage x509.ExtKeyUsage) []byte {
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, p.ca, key.Public(), p.caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func (p *testPKI) tlsCert(t *testing.T, name string, usage x509.ExtKeyUsage) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{
+		Certificate: [][]byte{p.issue(t, name, key, usage)},
+		PrivateKey:  key,
+	}
+}
+
+type testKeys struct {
+	rsa   *rsa.PrivateKey
+	ecdsa *ecdsa.PrivateKey
+}
+
+func newTestKeys(t *testing.T) *testKeys {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testKeys{rsa: rsaKey, ecdsa: ecdsaKey}
+}
+
+// startTestServer starts key server with keys "rsa" and "ecdsa".
+func startTestServer(t *testing.T, p *testPKI, keys *testKeys) string {
+	s := NewServer()
+	s.AddKey("rsa", keys.rsa)
+	s.AddKey("ecdsa", keys.ecdsa)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{p.tlsCert(t, "keyserver", x509.ExtKeyUsageServerAuth)},
+		ClientCAs:    p.pool,
+	}
+	go s.Serve(ln, config)
+	return ln.Addr().String()
+}
+
+func newTestClient(t *testing.T, p *testPKI, addr string) *Client {
+	c := NewClient(Config{
+		Address: addr,
+		TLSConfig: &tls.Config{
+			Certificates: []tls.Certificate{p.tlsCert(t, "bfe", x509.ExtKeyUsageClientAuth)},
+			RootCAs:      p.pool,
+		},
+		Timeout:  time.Second,
+		PoolSize: 2,
+	})
+	t.Cleanup(c.Close)
+	return c
+}
+
+func TestRemoteKeySign(t *testing.T) {
+	p := newTestPKI(t)
+	keys := newTestKeys(t)
+	c := newTestClient(t, p, startTestServer(t, p, keys))
+
+	digest := sha256.Sum256([]byte("hello"))
+
+	// RSA PKCS #1 v1.5
+	key := c.NewKey("rsa", keys.rsa.Public(), nil)
+	sig, err := key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		t.Fatalf("Sign(): %s", err)
+	}
+	if err := rsa.VerifyPKCS1v15(&keys.rsa.PublicKey, crypto.SHA256, digest[:], sig); err != nil {
+		t.Errorf("invalid PKCS #1 v1.5 signature: %s", err)
+	}
+
+	// RSA-PSS
+	opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}
+	sig, err = key.Sign(rand.Reader, digest[:], opts)
+	if err != nil {
+		t.Fatalf("Sign(): %s", err)
+	}
+	if err := rsa.VerifyPSS(&keys.rsa.PublicKey, crypto.SHA256, digest[:], sig, opts); err != nil {
+		t.Errorf("invalid PSS signature: %s", err)
+	}
+
+	// ECDSA
+	key = c.NewKey("ecdsa", keys.ecdsa.Public(), nil)
+	sig, err = key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		t.Fatalf("Sign(): %s", err)
+	}
+	if !ecdsa.VerifyASN1(&keys.ecdsa.PublicKey, digest[:], sig) {
+		t.Errorf("invalid ECDSA signature")
+	}
+
+	// unknown key
+	key = c.NewKey("unknown", keys.ecdsa.Public(), nil)
+	if _, err := key.Sign(rand.Reader, digest[:], crypto.SHA256); err == nil ||
+		!strings.Contains(err.Error(), "unknown key") {
+		t.Errorf("Sign() with unknown key should fail: %v", err)
+	}
+
+	// Note: connections are reused, also after error of key server
+	state := c.GetState()
+	if state.Sign != 3 || state.SignErr != 1 {
+		t.Errorf("unexpected sign state %d/%d", state.Sign, state.SignErr)
+	}
+	if state.Dial != 2 || state.Conns != 2 {
+		t.Errorf("unexpected conn state %d/%d", state.Dial, state.Conns)
+	}
+}
+
+func TestRemoteKeyDecrypt(t *testing.T) {
+	p := newTestPKI(t)
+	keys := newTestKeys(t)
+	c := newTestClient(t, p, startTestServer(t, p, keys))
+	key := c.NewKey("rsa", keys.rsa.Public(), nil)
+
+	secret := make([]byte, 48)
+	rand.Read(secret)
+	ciphertext, err := rsa.EncryptPKCS1v15(rand.Reader, &keys.rsa.PublicKey, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := &rsa.PKCS1v15DecryptOptions{SessionKeyLen: 48}
+	plaintext, err := key.Decrypt(rand.Reader, ciphertext, opts)
+	if err != nil {
+		t.Fatalf("Decrypt(): %s", err)
+	}
+	if string(plaintext) != string(secret) {
+		t.Errorf("unexpected plaintext")
+	}
+
+	// random session key for invalid ciphertext, instead of error
+	ciphertext[len(ciphertext)-1] ^= 0xff
+	plaintext, err = key.Decrypt(rand.Reader, ciphertext, opts)
+	if err != nil || len(plaintext) != 48 || string(plaintext) == string(secret) {
+		t.Errorf("unexpected result for invalid ciphertext: %v", err)
+	}
+
+	// ECDSA key does not support decryption
+	key = c.NewKey("ecdsa", keys.ecdsa.Public(), nil)
+	if _, err := key.Decrypt(rand.Reader, ciphertext, opts); err == nil {
+		t.Errorf("Decrypt() with ECDSA key should fail")
+	}
+
+	// decryption without SessionKeyLen is refused, by client and key server
+	key = c.NewKey("rsa", keys.rsa.Public(), nil)
+	if _, err := key.Decrypt(rand.Reader, ciphertext, nil); err != ErrUnsupportedOpts {
+		t.Errorf("Decrypt() without opts should fail: %v", err)
+	}
+	if _, err := key.Decrypt(rand.Reader, ciphertext, &rsa.PKCS1v15DecryptOptions{}); err != ErrNoSessionKeyLen {
+		t.Errorf("Decrypt() without SessionKeyLen should fail: %v", err)
+	}
+	args := DecryptArgs{KeyID: "rsa", Ciphertext: ciphertext}
+	if err := c.call(methodDecrypt, &args, new(DecryptReply)); err == nil ||
+		!strings.Contains(err.Error(), "SessionKeyLen") {
+		t.Errorf("key server should refuse decryption without SessionKeyLen: %v", err)
+	}
+}
+
+func TestClientRequireClientCert(t *testing.T) {
+	p := newTestPKI(t)
+	keys := newTestKeys(t)
+	addr := startTestServer(t, p, keys)
+
+	// client certificate issued by another ca
+	c := newTestClient(t, newTestPKI(t), addr)
+	c.conf.TLSConfig.RootCAs = p.pool
+
+	digest := sha256.Sum256([]byte("hello"))
+	key := c.NewKey("ecdsa", keys.ecdsa.Public(), nil)
+	if _, err := key.Sign(rand.Reader, digest[:], crypto.SHA256); err == nil {
+		t.Errorf("Sign() should fail without trusted client certificate")
+	}
+	if state := c.GetState(); state.Conns != 0 {
+		t.Errorf("failed connection should be closed")
+	}
+}
+
+func TestClientTimeoutAndFallback(t *testing.T) {
+	p := newTestPKI(t)
+	keys := newTestKeys(t)
+
+	// key server which never completes handshake
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	c := newTestClient(t, p, ln.Addr().String())
+	c.conf.Timeout = 100 * time.Millisecond
+	digest := sha256.Sum256([]byte("hello"))
+
+	start := time.Now()
+	key := c.NewKey("ecdsa", keys.ecdsa.Public(), nil)
+	if _, err := key.Sign(rand.Reader, digest[:], crypto.SHA256); err == nil {
+		t.Errorf("Sign() should fail with unavailable key server")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Sign() should fail in timeout, elapsed %s", elapsed)
+	}
+
+	// local key is not used by default
+	key = c.NewKey("ecdsa", keys.ecdsa.Public(), keys.ecdsa)
+	if _, err := key.Sign(rand.Reader, digest[:], crypto.SHA256); !IsTransportError(err) {
+		t.Errorf("Sign() should fail with transport error: %v", err)
+	}
+
+	// sign by local key
+	c.conf.FallbackPolicy = FallbackTransportError
+	sig, err := key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		t.Fatalf("Sign() with fallback key: %s", err)
+	}
+	if !ecdsa.VerifyASN1(&keys.ecdsa.PublicKey, digest[:], sig) {
+		t.Errorf("invalid ECDSA signature")
+	}
+	if state := c.GetState(); state.Fallback != 1 || state.DialErr != 3 {
+		t.Errorf("unexpected state %d/%d", state.Fallback, state.DialErr)
+	}
+}
+
+func TestRemoteKeyNoFallbackOnServerError(t *testing.T) {
+	p := newTestPKI(t)
+	keys := newTestKeys(t)
+	c := newTestClient(t, p, startTestServer(t, p, keys))
+	c.conf.FallbackPolicy = FallbackTransportError
+
+	// error returned by key server is not a transport error
+	digest := sha256.Sum256([]byte("hello"))
+	key := c.NewKey("unknown", keys.ecdsa.Public(), keys.ecdsa)
+	_, err := key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err == nil || IsTransportError(err) {
+		t.Errorf("Sign() with unknown key should fail with server error: %v", err)
+	}
+	if state := c.GetState(); state.Fallback != 0 {
+		t.Errorf("local key should not be used for server error")
+	}
+}
+
+func TestServerLoadKeyDir(t *testing.T) {
+	keys := newTestKeys(t)
+	dir := t.TempDir()
+	der, err := x509.MarshalPKCS8PrivateKey(keys.ecdsa)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, "example.org.key"), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "example.org.crt"), []byte("ignored"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewServer()
+	if err := s.LoadKeyDir(dir); err != nil {
+		t.Fatalf("LoadKeyDir(): %s", err)
+	}
+	if _, err := s.getKey("example.org"); err != nil {
+		t.Errorf("key should be loaded: %s", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "bad.key"), []byte("bad"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.LoadKeyDir(dir); err == nil {
+		t.Errorf("LoadKeyDir() should fail with invalid key")
+	}
+}
+
+func TestRemoteKeyHandshake(t *testing.T) {
+	p := newTestPKI(t)
+	keys := newTestKeys(t)
+	c := newTestClient(t, p, startTestServer(t, p, keys))
+
+	tests := []struct {
+		keyID  string
+		pub    crypto.PublicKey
+		vers   uint16
+		suites []uint16
+	}{
+		{"rsa", keys.rsa.Public(), tls.VersionTLS12, []uint16{tls.TLS_RSA_WITH_AES_128_CBC_SHA}},
+		{"rsa", keys.rsa.Public(), tls.VersionTLS12, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}},
+		{"ecdsa", keys.ecdsa.Public(), tls.VersionTLS12, []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}},
+		{"rsa", keys.rsa.Public(), tls.VersionTLS13, nil},
+		{"ecdsa", keys.ecdsa.Public(), tls.VersionTLS13, nil},
+	}
+	for _, test := range tests {
+		key := c.NewKey(test.keyID, test.pub, nil)
+		certPEM := pem.EncodeToMemory(&pem.Block{
+			Type:  "CERTIFICATE",
+			Bytes: p.issue(t, "example.org", key, x509.ExtKeyUsageServerAuth),
+		})
+		cert, err := bfe_tls.X509CertWithKey(certPEM, key)
+		if err != nil {
+			t.Fatalf("X509CertWithKey(): %s", err)
+		}
+		serverConfig := &bfe_tls.Config{
+			Certificates: []bfe_tls.Certificate{cert},
+			MaxVersion:   bfe_tls.VersionTLS13,
+		}
+		clientConfig := &tls.Config{
+			RootCAs:      p.pool,
+			ServerName:   "example.org",
+			MaxVersion:   test.vers,
+			CipherSuites: test.suites,
+		}
+
+		cliConn, srvConn := net.Pipe()
+		done := make(chan error, 1)
+		go func() {
+			cli := tls.Client(cliConn, clientConfig)
+			err := cli.Handshake()
+			if err == nil {
+				_, err = io.ReadFull(cli, make([]byte, 4))
+			}
+			cliConn.Close()
+			done <- err
+		}()
+
+		srv := bfe_tls.Server(srvConn, serverConfig)
+		if err := srv.Handshake(); err != nil {
+			t.Errorf("%s %x: server handshake failed: %s", test.keyID, test.vers, err)
+		} else {
+			srv.Write([]byte("ping"))
+		}
+		srvConn.Close()
+		if err := <-done; err != nil {
+			t.Errorf("%s %x: client failed: %s", test.keyID, test.vers, err)
+		}
+	}
+
+	// mismatched key
+	key := c.NewKey("ecdsa", keys.ecdsa.Public(), nil)
+	other := c.NewKey("rsa", keys.rsa.Public(), nil)
+	certPEM := pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: p.issue(t, "example.org", key, x509.ExtKeyUsageServerAuth),
+	})
+	if _, err := bfe_tls.X509CertWithKey(certPEM, other); err == nil {
+		t.Errorf("X509CertWithKey() should fail with mismatched key")
+	}
+}
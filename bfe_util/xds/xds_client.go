@@ -0,0 +1,270 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// client for incremental (delta) xds protocol
+//
+// The client subscribes resources of given types on an aggregated stream.
+// Each response is passed to handler, and is ACKed if handler returns nil,
+// or NACKed with the error otherwise.
+
+package xds
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"sync"
+	"time"
+)
+
+import (
+	"github.com/bfenetworks/go-lib/log"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	RetryIntervalMin = time.Second      // min interval to reconnect xds server
+	RetryIntervalMax = 30 * time.Second // max interval to reconnect xds server
+)
+
+// Handler handles response from xds server. Response is NACKed if error
+// returned.
+type Handler func(resp *discoveryv3.DeltaDiscoveryResponse) error
+
+// Config is config of xds client.
+type Config struct {
+	Address        string        // address of xds server
+	NodeId         string        // id of node
+	NodeCluster    string        // cluster of node
+	TLSConfig      *tls.Config   // tls config, nil for plaintext
+	ConnectTimeout time.Duration // timeout for each attempt to connect xds server
+	TypeURLs       []string      // type urls of resources to subscribe
+}
+
+// TypeState is state of resources of one type.
+type TypeState struct {
+	SystemVersion string            // system version of last ACKed response
+	Versions      map[string]string // resource name => version of ACKed resources
+	AckCount      int64
+	NackCount     int64
+	LastNack      string // error of last NACKed response
+	LastNackTime  time.Time
+}
+
+// ClientState is state of xds client.
+type ClientState struct {
+	Connected   bool
+	LastConnect time.Time
+	LastError   string
+	Types       map[string]*TypeState
+}
+
+type Client struct {
+	conf    Config
+	handler Handler
+
+	lock  sync.Mutex
+	state ClientState
+}
+
+func NewClient(conf Config, handler Handler) *Client {
+	c := new(Client)
+	c.conf = conf
+	c.handler = handler
+	c.state.Types = make(map[string]*TypeState)
+	for _, typeURL := range conf.TypeURLs {
+		c.state.Types[typeURL] = &TypeState{Versions: make(map[string]string)}
+	}
+	return c
+}
+
+// Start starts to subscribe resources from xds server, and reconnects if
+// stream is broken.
+func (c *Client) Start() {
+	go func() {
+		interval := RetryIntervalMin
+		for {
+			start := time.Now()
+			err := c.subscribe()
+			c.setDisconnected(err)
+			log.Logger.Warn("xds client: stream with %s broken: %v", c.conf.Address, err)
+
+			// reset backoff if stream lasted long enough
+			if time.Since(start) > RetryIntervalMax {
+				interval = RetryIntervalMin
+			}
+			time.Sleep(interval)
+			interval *= 2
+			if interval > RetryIntervalMax {
+				interval = RetryIntervalMax
+			}
+		}
+	}()
+}
+
+// dial creates connection to xds server. The connection is established in
+// background, and each attempt is limited by ConnectTimeout.
+func (c *Client) dial() (*grpc.ClientConn, error) {
+	creds := insecure.NewCredentials()
+	if c.conf.TLSConfig != nil {
+		creds = credentials.NewTLS(c.conf.TLSConfig)
+	}
+	return grpc.NewClient(c.conf.Address,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff:           backoff.DefaultConfig,
+			MinConnectTimeout: c.conf.ConnectTimeout,
+		}),
+	)
+}
+
+// subscribe subscribes resources on one stream, until the stream is broken.
+func (c *Client) subscribe() error {
+	conn, err := c.dial()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := discoveryv3.NewAggregatedDiscoveryServiceClient(conn)
+	stream, err := client.DeltaAggregatedResources(ctx)
+	if err != nil {
+		return err
+	}
+
+	// subscribe all resources of each type, with versions of resources known
+	node := &corev3.Node{Id: c.conf.NodeId, Cluster: c.conf.NodeCluster}
+	for _, typeURL := range c.conf.TypeURLs {
+		req := &discoveryv3.DeltaDiscoveryRequest{
+			Node:                    node,
+			TypeUrl:                 typeURL,
+			InitialResourceVersions: c.getVersions(typeURL),
+		}
+		if err := stream.Send(req); err != nil {
+			return err
+		}
+	}
+	c.setConnected()
+	log.Logger.Info("xds client: stream with %s established", c.conf.Address)
+
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		if err := stream.Send(c.handleResponse(resp)); err != nil {
+			return err
+		}
+	}
+}
+
+// handleResponse handles response and returns ACK or NACK for it.
+func (c *Client) handleResponse(resp *discoveryv3.DeltaDiscoveryResponse) *discoveryv3.DeltaDiscoveryRequest {
+	req := &discoveryv3.DeltaDiscoveryRequest{
+		TypeUrl:       resp.TypeUrl,
+		ResponseNonce: resp.Nonce,
+	}
+
+	err := c.handler(resp)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	state, ok := c.state.Types[resp.TypeUrl]
+	if !ok {
+		err = fmt.Errorf("unknown type url: %s", resp.TypeUrl)
+		state = &TypeState{Versions: make(map[string]string)}
+	}
+
+	if err != nil {
+		log.Logger.Warn("xds client: NACK %s version %s nonce %s: %v",
+			resp.TypeUrl, resp.SystemVersionInfo, resp.Nonce, err)
+		state.NackCount++
+		state.LastNack = err.Error()
+		state.LastNackTime = time.Now()
+		req.ErrorDetail = status.New(codes.InvalidArgument, err.Error()).Proto()
+		return req
+	}
+
+	state.AckCount++
+	state.SystemVersion = resp.SystemVersionInfo
+	for _, res := range resp.Resources {
+		state.Versions[res.Name] = res.Version
+	}
+	for _, name := range resp.RemovedResources {
+		delete(state.Versions, name)
+	}
+	return req
+}
+
+func (c *Client) getVersions(typeURL string) map[string]string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	state, ok := c.state.Types[typeURL]
+	if !ok || len(state.Versions) == 0 {
+		return nil
+	}
+
+	versions := make(map[string]string, len(state.Versions))
+	for name, version := range state.Versions {
+		versions[name] = version
+	}
+	return versions
+}
+
+func (c *Client) setConnected() {
+	c.lock.Lock()
+	c.state.Connected = true
+	c.state.LastConnect = time.Now()
+	c.lock.Unlock()
+}
+
+func (c *Client) setDisconnected(err error) {
+	c.lock.Lock()
+	c.state.Connected = false
+	if err != nil {
+		c.state.LastError = err.Error()
+	}
+	c.lock.Unlock()
+}
+
+// GetState returns state of xds client.
+func (c *Client) GetState() ClientState {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	state := c.state
+	state.Types = make(map[string]*TypeState, len(c.state.Types))
+	for typeURL, typeState := range c.state.Types {
+		s := *typeState
+		s.Versions = make(map[string]string, len(typeState.Versions))
+		for name, version := range typeState.Versions {
+			s.Versions[name] = version
+		}
+		state.Types[typeURL] = &s
+	}
+	return state
+}
@@ -0,0 +1,175 @@
+// Copyright (c) 2026 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+import (
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+const testTypeURL = "type.example.org/test"
+
+type testADSServer struct {
+	discoveryv3.UnimplementedAggregatedDiscoveryServiceServer
+
+	responses []*discoveryv3.DeltaDiscoveryResponse
+	requests  chan *discoveryv3.DeltaDiscoveryRequest
+}
+
+func (s *testADSServer) DeltaAggregatedResources(
+	stream discoveryv3.AggregatedDiscoveryService_DeltaAggregatedResourcesServer) error {
+	// subscribe request
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	s.requests <- req
+
+	for _, resp := range s.responses {
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+		req, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		s.requests <- req
+	}
+
+	<-stream.Context().Done()
+	return nil
+}
+
+func startTestADSServer(t *testing.T, server *testADSServer) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %s", err)
+	}
+	s := grpc.NewServer()
+	discoveryv3.RegisterAggregatedDiscoveryServiceServer(s, server)
+	go s.Serve(ln)
+	t.Cleanup(s.Stop)
+	return ln.Addr().String()
+}
+
+func newTestResponse(nonce, version string, names ...string) *discoveryv3.DeltaDiscoveryResponse {
+	resp := &discoveryv3.DeltaDiscoveryResponse{
+		TypeUrl:           testTypeURL,
+		SystemVersionInfo: version,
+		Nonce:             nonce,
+	}
+	for _, name := range names {
+		resp.Resources = append(resp.Resources, &discoveryv3.Resource{
+			Name:     name,
+			Version:  version,
+			Resource: &anypb.Any{TypeUrl: testTypeURL, Value: []byte(name)},
+		})
+	}
+	return resp
+}
+
+func recvRequest(t *testing.T, server *testADSServer) *discoveryv3.DeltaDiscoveryRequest {
+	select {
+	case req := <-server.requests:
+		return req
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no request received")
+	}
+	return nil
+}
+
+func TestClientAckNack(t *testing.T) {
+	server := &testADSServer{
+		responses: []*discoveryv3.DeltaDiscoveryResponse{
+			newTestResponse("n1", "v1", "a", "b"),
+			newTestResponse("n2", "v2", "bad"),
+		},
+		requests: make(chan *discoveryv3.DeltaDiscoveryRequest, 10),
+	}
+	addr := startTestADSServer(t, server)
+
+	handler := func(resp *discoveryv3.DeltaDiscoveryResponse) error {
+		for _, res := range resp.Resources {
+			if res.Name == "bad" {
+				return errors.New("invalid resource")
+			}
+		}
+		return nil
+	}
+	client := NewClient(Config{
+		Address:        addr,
+		NodeId:         "node1",
+		ConnectTimeout: 5 * time.Second,
+		TypeURLs:       []string{testTypeURL},
+	}, handler)
+	client.Start()
+
+	// subscribe
+	req := recvRequest(t, server)
+	if req.TypeUrl != testTypeURL || req.Node.GetId() != "node1" {
+		t.Errorf("unexpected subscribe request: %v", req)
+	}
+
+	// ACK
+	req = recvRequest(t, server)
+	if req.ResponseNonce != "n1" || req.ErrorDetail != nil {
+		t.Errorf("expect ACK for n1, got %v", req)
+	}
+
+	// NACK
+	req = recvRequest(t, server)
+	if req.ResponseNonce != "n2" || req.ErrorDetail.GetMessage() != "invalid resource" {
+		t.Errorf("expect NACK for n2, got %v", req)
+	}
+
+	state := client.GetState()
+	typeState := state.Types[testTypeURL]
+	if !state.Connected || typeState.AckCount != 1 || typeState.NackCount != 1 {
+		t.Errorf("unexpected state: %+v %+v", state, typeState)
+	}
+	if typeState.SystemVersion != "v1" || len(typeState.Versions) != 2 {
+		t.Errorf("versions of NACKed response should not be recorded: %+v", typeState)
+	}
+}
+
+func TestClientHandleRemoved(t *testing.T) {
+	client := NewClient(Config{TypeURLs: []string{testTypeURL}},
+		func(resp *discoveryv3.DeltaDiscoveryResponse) error { return nil })
+
+	client.handleResponse(newTestResponse("n1", "v1", "a", "b"))
+	resp := newTestResponse("n2", "v2")
+	resp.RemovedResources = []string{"a"}
+	client.handleResponse(resp)
+
+	versions := client.getVersions(testTypeURL)
+	if len(versions) != 1 || versions["b"] != "v1" {
+		t.Errorf("unexpected versions: %v", versions)
+	}
+
+	// response of unknown type is NACKed
+	resp = newTestResponse("n3", "v3", "c")
+	resp.TypeUrl = "type.example.org/unknown"
+	if req := client.handleResponse(resp); req.ErrorDetail == nil {
+		t.Errorf("response of unknown type should be NACKed")
+	}
+}